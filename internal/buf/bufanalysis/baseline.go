@@ -0,0 +1,118 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type baseline struct {
+	keyToCount map[baselineKey]int
+}
+
+func readBaseline(reader io.Reader) (*baseline, error) {
+	baseline := &baseline{
+		keyToCount: make(map[baselineKey]int),
+	}
+	scanner := bufio.NewScanner(reader)
+	// messages can be long, so do not limit lines to the default 64KB
+	scanner.Buffer(nil, 1<<24)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var key baselineKey
+		if err := json.Unmarshal([]byte(line), &key); err != nil {
+			return nil, fmt.Errorf("invalid baseline line %d: %v", lineNumber, err)
+		}
+		baseline.keyToCount[key]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+func (b *baseline) Filter(fileAnnotations []FileAnnotation) []FileAnnotation {
+	keyToCount := make(map[baselineKey]int, len(b.keyToCount))
+	for key, count := range b.keyToCount {
+		keyToCount[key] = count
+	}
+	var filtered []FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		key := newBaselineKey(fileAnnotation)
+		if keyToCount[key] > 0 {
+			keyToCount[key]--
+			continue
+		}
+		filtered = append(filtered, fileAnnotation)
+	}
+	return filtered
+}
+
+func writeBaseline(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	keys := make([]baselineKey, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		keys[i] = newBaselineKey(fileAnnotation)
+	}
+	sort.Slice(
+		keys,
+		func(i int, j int) bool {
+			if keys[i].Path != keys[j].Path {
+				return keys[i].Path < keys[j].Path
+			}
+			if keys[i].Type != keys[j].Type {
+				return keys[i].Type < keys[j].Type
+			}
+			return keys[i].Message < keys[j].Message
+		},
+	)
+	for _, key := range keys {
+		data, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// baselineKey is the part of a FileAnnotation that is matched against
+// a Baseline, and the JSON object of a line of a Baseline.
+type baselineKey struct {
+	Path    string `json:"path,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func newBaselineKey(fileAnnotation FileAnnotation) baselineKey {
+	var path string
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.Path()
+	}
+	return baselineKey{
+		Path:    path,
+		Type:    fileAnnotation.Type(),
+		Message: fileAnnotation.Message(),
+	}
+}
@@ -16,6 +16,7 @@ package bufanalysis
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"sort"
@@ -27,9 +28,87 @@ const (
 	// FormatText is the text format for FileAnnotations.
 	FormatText = iota + 1
 	// FormatJSON is the JSON format for FileAnnotations.
+	//
+	// Each FileAnnotation is printed as a JSON object on its own line. The
+	// format is stable, fields will only be added and never removed or
+	// renamed. The fields are:
+	//
+	//   path: The external path of the file, omitted if not known.
+	//   start_line: The 1-indexed starting line, omitted if not known.
+	//   start_column: The 1-indexed starting column, omitted if not known.
+	//   end_line: The 1-indexed ending line, omitted if not known.
+	//   end_column: The 1-indexed ending column, omitted if not known.
+	//   type: The type of the annotation, such as the ID of a lint or
+	//     breaking change rule, omitted for build errors.
+	//   message: The message of the annotation.
 	FormatJSON
 	// FormatMSVS is the MSVS format for FileAnnotations.
 	FormatMSVS
+	// FormatGitLab is the GitLab Code Quality format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single JSON array of all FileAnnotations.
+	//
+	// https://docs.gitlab.com/ee/user/project/merge_requests/code_quality.html#implementing-a-custom-tool
+	FormatGitLab
+	// FormatGerrit is the Gerrit robot comments format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single JSON ReviewInput object with
+	// the robot comments of all FileAnnotations keyed by path, which can be
+	// posted to the set review REST API endpoint as-is.
+	//
+	// https://gerrit-review.googlesource.com/Documentation/rest-api-changes.html#robot-comment-input
+	FormatGerrit
+	// FormatCheckstyle is the Checkstyle XML format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single XML document of all FileAnnotations.
+	FormatCheckstyle
+	// FormatMarkdown is the Markdown format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single Markdown table of all
+	// FileAnnotations grouped by type, suitable for posting as a comment.
+	FormatMarkdown
+	// FormatJUnit is the JUnit XML format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single XML document of all
+	// FileAnnotations, with a test suite for each file and a failed test
+	// case for each FileAnnotation.
+	FormatJUnit
+	// FormatGitHubActions is the GitHub Actions workflow command format for
+	// FileAnnotations.
+	//
+	// Each FileAnnotation is printed as an error or warning command depending
+	// on its severity, which GitHub Actions shows as an annotation on the file.
+	//
+	// https://docs.github.com/en/actions/reference/workflow-commands-for-github-actions#setting-an-error-message
+	FormatGitHubActions
+	// FormatGitHubReview is the GitHub pull request review format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single JSON object with a review
+	// comment for each FileAnnotation, which can be posted to the create a review
+	// REST API endpoint as-is. FileAnnotations without a file or line are listed
+	// in the body of the review.
+	//
+	// https://docs.github.com/en/rest/reference/pulls#create-a-review-for-a-pull-request
+	FormatGitHubReview
+	// FormatGitLabReview is the GitLab merge request discussion format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single JSON array with a discussion for
+	// each FileAnnotation, each of which can be posted to the create a merge
+	// request thread REST API endpoint. The base_sha, start_sha, and head_sha of
+	// the position must be added from the diff refs of the merge request, as they
+	// are not known. FileAnnotations without a file or line have no position.
+	//
+	// https://docs.gitlab.com/ee/api/discussions.html#create-new-merge-request-thread
+	FormatGitLabReview
+	// FormatSARIF is the SARIF 2.1.0 format for FileAnnotations.
+	//
+	// Unlike the other formats, this is a single JSON SARIF log with a result
+	// for each FileAnnotation, which can be uploaded to GitHub code scanning
+	// and other SARIF consumers. The rules given with PrintWithRules are
+	// included as the rule metadata.
+	//
+	// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+	FormatSARIF
 )
 
 var (
@@ -40,6 +119,15 @@ var (
 		"text",
 		"json",
 		"msvs",
+		"gitlab",
+		"gerrit",
+		"checkstyle",
+		"markdown",
+		"junit",
+		"github-actions",
+		"github-review",
+		"gitlab-review",
+		"sarif",
 	}
 	// AllFormatStringsWithAliases is all format strings with aliases.
 	//
@@ -49,19 +137,46 @@ var (
 		"gcc",
 		"json",
 		"msvs",
+		"gitlab",
+		"gerrit",
+		"checkstyle",
+		"markdown",
+		"junit",
+		"github-actions",
+		"github-review",
+		"gitlab-review",
+		"sarif",
 	}
 
 	stringToFormat = map[string]Format{
 		"text": FormatText,
 		// alias for text
-		"gcc":  FormatText,
-		"json": FormatJSON,
-		"msvs": FormatMSVS,
+		"gcc":            FormatText,
+		"json":           FormatJSON,
+		"msvs":           FormatMSVS,
+		"gitlab":         FormatGitLab,
+		"gerrit":         FormatGerrit,
+		"checkstyle":     FormatCheckstyle,
+		"markdown":       FormatMarkdown,
+		"junit":          FormatJUnit,
+		"github-actions": FormatGitHubActions,
+		"github-review":  FormatGitHubReview,
+		"gitlab-review":  FormatGitLabReview,
+		"sarif":          FormatSARIF,
 	}
 	formatToString = map[Format]string{
-		FormatText: "text",
-		FormatJSON: "json",
-		FormatMSVS: "msvs",
+		FormatText:          "text",
+		FormatJSON:          "json",
+		FormatMSVS:          "msvs",
+		FormatGitLab:        "gitlab",
+		FormatGerrit:        "gerrit",
+		FormatCheckstyle:    "checkstyle",
+		FormatMarkdown:      "markdown",
+		FormatJUnit:         "junit",
+		FormatGitHubActions: "github-actions",
+		FormatGitHubReview:  "github-review",
+		FormatGitLabReview:  "gitlab-review",
+		FormatSARIF:         "sarif",
 	}
 )
 
@@ -92,6 +207,31 @@ func ParseFormat(s string) (Format, error) {
 	return 0, fmt.Errorf("unknown format: %q", s)
 }
 
+const (
+	// SeverityError is the severity of FileAnnotations that fail the command.
+	//
+	// This is the default severity.
+	SeverityError Severity = iota + 1
+	// SeverityWarning is the severity of FileAnnotations that are reported
+	// but do not fail the command.
+	SeverityWarning
+)
+
+// Severity is the severity of a FileAnnotation.
+type Severity int
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
 // FileInfo is a minimal FileInfo interface.
 type FileInfo interface {
 	Path() string
@@ -138,6 +278,14 @@ type FileAnnotation interface {
 	Type() string
 	// Message is the message of the annotation.
 	Message() string
+	// Edits are the edits to the file that fix the annotation.
+	//
+	// This will be empty if the annotation cannot be fixed mechanically.
+	Edits() []Edit
+	// Severity is the severity of the annotation.
+	//
+	// This is SeverityError unless set with FileAnnotationWithSeverity.
+	Severity() Severity
 }
 
 // NewFileAnnotation returns a new FileAnnotation.
@@ -149,6 +297,7 @@ func NewFileAnnotation(
 	endColumn int,
 	typeString string,
 	message string,
+	options ...FileAnnotationOption,
 ) FileAnnotation {
 	return newFileAnnotation(
 		fileInfo,
@@ -158,9 +307,71 @@ func NewFileAnnotation(
 		endColumn,
 		typeString,
 		message,
+		options...,
 	)
 }
 
+// FileAnnotationOption is an option for a new FileAnnotation.
+type FileAnnotationOption func(*fileAnnotation)
+
+// FileAnnotationWithEdits returns a new FileAnnotationOption that sets the
+// edits that fix the FileAnnotation.
+func FileAnnotationWithEdits(edits ...Edit) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.edits = edits
+	}
+}
+
+// FileAnnotationWithSeverity returns a new FileAnnotationOption that sets the
+// severity of the FileAnnotation.
+//
+// The default is SeverityError.
+func FileAnnotationWithSeverity(severity Severity) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.severity = severity
+	}
+}
+
+// Baseline is a set of recorded FileAnnotations, used to suppress the
+// FileAnnotations that already existed when the Baseline was written.
+type Baseline interface {
+	// Filter returns the FileAnnotations that are not in the Baseline.
+	//
+	// FileAnnotations match if they have the same type, path, and message,
+	// which names the element of the annotation, so that FileAnnotations still
+	// match if lines are added to or removed from the file. Each FileAnnotation
+	// in the Baseline matches at most one FileAnnotation.
+	Filter(fileAnnotations []FileAnnotation) []FileAnnotation
+}
+
+// ReadBaseline reads a Baseline written by WriteBaseline.
+func ReadBaseline(reader io.Reader) (Baseline, error) {
+	return readBaseline(reader)
+}
+
+// WriteBaseline writes the FileAnnotations as a Baseline.
+//
+// Each FileAnnotation is written as a JSON object with its type, path, and
+// message on its own line, sorted so that the Baseline can be diffed. The path
+// is relative to the root of the input, so the Baseline does not depend on
+// how the input is specified.
+func WriteBaseline(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	return writeBaseline(writer, fileAnnotations)
+}
+
+// Edit is an edit to a file that replaces the text between the start and end
+// positions with NewText.
+//
+// Lines and columns are 1-indexed, and the end position is exclusive, as with
+// the positions of FileAnnotations.
+type Edit struct {
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+	NewText     string
+}
+
 // SortFileAnnotations sorts the FileAnnotations.
 //
 // The order of sorting is:
@@ -177,11 +388,46 @@ func SortFileAnnotations(fileAnnotations []FileAnnotation) {
 }
 
 // PrintFileAnnotations prints the file annotations separated by newlines.
-func PrintFileAnnotations(writer io.Writer, fileAnnotations []FileAnnotation, formatString string) error {
+//
+// For FormatGitLab, FormatGerrit, FormatGitHubReview, FormatGitLabReview, and
+// FormatSARIF, the file annotations are printed as a single JSON value, for FormatCheckstyle
+// and FormatJUnit, as a single XML document, and for FormatMarkdown, as a single
+// Markdown table.
+func PrintFileAnnotations(
+	writer io.Writer,
+	fileAnnotations []FileAnnotation,
+	formatString string,
+	options ...PrintOption,
+) error {
 	format, err := ParseFormat(formatString)
 	if err != nil {
 		return err
 	}
+	printOptions := newPrintOptions()
+	for _, option := range options {
+		option(printOptions)
+	}
+	if printOptions.groupByFile && format != FormatGitHubReview && format != FormatGitLabReview {
+		return fmt.Errorf("grouping by file is only supported for the github-review and gitlab-review formats but the format was %s", format.String())
+	}
+	switch format {
+	case FormatGitHubReview:
+		return printFileAnnotationsGitHubReview(writer, fileAnnotations, printOptions.groupByFile)
+	case FormatGitLabReview:
+		return printFileAnnotationsGitLabReview(writer, fileAnnotations, printOptions.groupByFile)
+	case FormatGitLab:
+		return printFileAnnotationsGitLab(writer, fileAnnotations)
+	case FormatGerrit:
+		return printFileAnnotationsGerrit(writer, fileAnnotations)
+	case FormatCheckstyle:
+		return printFileAnnotationsCheckstyle(writer, fileAnnotations)
+	case FormatMarkdown:
+		return printFileAnnotationsMarkdown(writer, fileAnnotations)
+	case FormatJUnit:
+		return printFileAnnotationsJUnit(writer, fileAnnotations)
+	case FormatSARIF:
+		return printFileAnnotationsSARIF(writer, fileAnnotations, printOptions.rules)
+	}
 	for _, fileAnnotation := range fileAnnotations {
 		s, err := FormatFileAnnotation(fileAnnotation, format)
 		if err != nil {
@@ -194,6 +440,47 @@ func PrintFileAnnotations(writer io.Writer, fileAnnotations []FileAnnotation, fo
 	return nil
 }
 
+// PrintOption is an option for PrintFileAnnotations.
+type PrintOption func(*printOptions)
+
+// PrintWithGroupByFile returns a new PrintOption that groups the file
+// annotations of each file into a single comment with the number of file
+// annotations, and adds the number of file annotations of each file to the
+// summary.
+//
+// This is only supported for FormatGitHubReview and FormatGitLabReview.
+func PrintWithGroupByFile() PrintOption {
+	return func(printOptions *printOptions) {
+		printOptions.groupByFile = true
+	}
+}
+
+// PrintWithRules returns a new PrintOption that adds the metadata of the rules
+// that were run, for the formats that include rule metadata.
+//
+// The rules of any other types of the FileAnnotations are included without
+// metadata. This is ignored for formats that do not include rule metadata.
+func PrintWithRules(rules ...Rule) PrintOption {
+	return func(printOptions *printOptions) {
+		printOptions.rules = append(printOptions.rules, rules...)
+	}
+}
+
+// Rule is the metadata of a rule, which produces FileAnnotations with
+// the ID of the rule as their type.
+type Rule struct {
+	// ID is the ID of the rule.
+	ID string
+	// Purpose is a one-line description of what the rule checks.
+	//
+	// May be empty.
+	Purpose string
+	// Help is the documentation of the rule as Markdown.
+	//
+	// May be empty.
+	Help string
+}
+
 // FormatFileAnnotation formats the FileAnnotation.
 func FormatFileAnnotation(fileAnnotation FileAnnotation, format Format) (string, error) {
 	switch format {
@@ -207,11 +494,66 @@ func FormatFileAnnotation(fileAnnotation FileAnnotation, format Format) (string,
 		return string(data), nil
 	case FormatMSVS:
 		return fileAnnotation.MSVSString(), nil
+	case FormatGitLab:
+		data, err := json.Marshal(newExternalGitLabIssue(fileAnnotation, ""))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatGerrit:
+		data, err := json.Marshal(newExternalGerritRobotComment(fileAnnotation, ""))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatCheckstyle:
+		data, err := xml.Marshal(newExternalCheckstyleError(fileAnnotation))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatMarkdown:
+		return getMarkdownRow(fileAnnotation, true), nil
+	case FormatJUnit:
+		data, err := xml.Marshal(newExternalJUnitTestCase(fileAnnotation))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatGitHubActions:
+		return getGitHubActionsCommand(fileAnnotation), nil
+	case FormatGitHubReview:
+		data, err := json.Marshal(newExternalGitHubReviewComment(fileAnnotation))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatGitLabReview:
+		data, err := json.Marshal(newExternalGitLabDiscussion(fileAnnotation))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case FormatSARIF:
+		data, err := json.Marshal(newExternalSARIFResult(fileAnnotation))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
 	default:
 		return "", fmt.Errorf("unknown FileAnnotation Format: %v", format)
 	}
 }
 
+type printOptions struct {
+	groupByFile bool
+	rules       []Rule
+}
+
+func newPrintOptions() *printOptions {
+	return &printOptions{}
+}
+
 type sortFileAnnotations []FileAnnotation
 
 func (a sortFileAnnotations) Len() int               { return len(a) }
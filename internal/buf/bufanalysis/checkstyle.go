@@ -0,0 +1,113 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+const (
+	// checkstyleVersion is the Checkstyle version the output is compatible with.
+	checkstyleVersion = "8.0"
+	// checkstyleSourcePrefix is prefixed to the type of each FileAnnotation to
+	// get the source of the error.
+	checkstyleSourcePrefix = "buf."
+)
+
+func printFileAnnotationsCheckstyle(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	externalCheckstyle := externalCheckstyle{
+		Version: checkstyleVersion,
+	}
+	// files are printed in the order they are first seen
+	pathToIndex := make(map[string]int)
+	for _, fileAnnotation := range fileAnnotations {
+		path := getCheckstylePath(fileAnnotation)
+		index, ok := pathToIndex[path]
+		if !ok {
+			index = len(externalCheckstyle.Files)
+			pathToIndex[path] = index
+			externalCheckstyle.Files = append(
+				externalCheckstyle.Files,
+				externalCheckstyleFile{
+					Name: path,
+				},
+			)
+		}
+		externalCheckstyle.Files[index].Errors = append(
+			externalCheckstyle.Files[index].Errors,
+			newExternalCheckstyleError(fileAnnotation),
+		)
+	}
+	data, err := xml.MarshalIndent(externalCheckstyle, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func newExternalCheckstyleError(fileAnnotation FileAnnotation) externalCheckstyleError {
+	line := fileAnnotation.StartLine()
+	if line == 0 {
+		line = 1
+	}
+	typeString := fileAnnotation.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = typeString
+	}
+	return externalCheckstyleError{
+		Line:     line,
+		Column:   fileAnnotation.StartColumn(),
+		Severity: fileAnnotation.Severity().String(),
+		Message:  message,
+		Source:   checkstyleSourcePrefix + typeString,
+	}
+}
+
+func getCheckstylePath(fileAnnotation FileAnnotation) string {
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		return fileInfo.ExternalPath()
+	}
+	return "<input>"
+}
+
+type externalCheckstyle struct {
+	XMLName xml.Name                 `xml:"checkstyle"`
+	Version string                   `xml:"version,attr"`
+	Files   []externalCheckstyleFile `xml:"file"`
+}
+
+type externalCheckstyleFile struct {
+	Name   string                    `xml:"name,attr"`
+	Errors []externalCheckstyleError `xml:"error"`
+}
+
+type externalCheckstyleError struct {
+	XMLName  xml.Name `xml:"error"`
+	Line     int      `xml:"line,attr"`
+	Column   int      `xml:"column,attr,omitempty"`
+	Severity string   `xml:"severity,attr"`
+	Message  string   `xml:"message,attr"`
+	Source   string   `xml:"source,attr"`
+}
@@ -28,6 +28,8 @@ type fileAnnotation struct {
 	endColumn   int
 	typeString  string
 	message     string
+	edits       []Edit
+	severity    Severity
 }
 
 func newFileAnnotation(
@@ -38,8 +40,9 @@ func newFileAnnotation(
 	endColumn int,
 	typeString string,
 	message string,
+	options ...FileAnnotationOption,
 ) *fileAnnotation {
-	return &fileAnnotation{
+	fileAnnotation := &fileAnnotation{
 		fileInfo:    fileInfo,
 		startLine:   startLine,
 		startColumn: startColumn,
@@ -47,7 +50,12 @@ func newFileAnnotation(
 		endColumn:   endColumn,
 		typeString:  typeString,
 		message:     message,
+		severity:    SeverityError,
 	}
+	for _, option := range options {
+		option(fileAnnotation)
+	}
+	return fileAnnotation
 }
 
 func (f *fileAnnotation) FileInfo() FileInfo {
@@ -78,6 +86,14 @@ func (f *fileAnnotation) Message() string {
 	return f.message
 }
 
+func (f *fileAnnotation) Edits() []Edit {
+	return f.edits
+}
+
+func (f *fileAnnotation) Severity() Severity {
+	return f.severity
+}
+
 func (f *fileAnnotation) String() string {
 	if f == nil {
 		return ""
@@ -154,7 +170,9 @@ func (f *fileAnnotation) MSVSString() string {
 		_, _ = buffer.WriteRune(',')
 		_, _ = buffer.WriteString(strconv.Itoa(int(column)))
 	}
-	_, _ = buffer.WriteString(") : error ")
+	_, _ = buffer.WriteString(") : ")
+	_, _ = buffer.WriteString(f.severity.String())
+	_, _ = buffer.WriteRune(' ')
 	_, _ = buffer.WriteString(typeString)
 	_, _ = buffer.WriteString(" : ")
 	_, _ = buffer.WriteString(message)
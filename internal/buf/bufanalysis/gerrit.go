@@ -0,0 +1,133 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+)
+
+const (
+	// gerritRobotID is the robot ID of all robot comments.
+	gerritRobotID = "buf"
+	// gerritPatchsetLevelPath is the special path Gerrit uses for comments
+	// that are not attached to a file.
+	gerritPatchsetLevelPath = "/PATCHSET_LEVEL"
+)
+
+func printFileAnnotationsGerrit(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	// the run ID is derived from the FileAnnotations so that the same
+	// results always have the same run ID
+	robotRunID, err := getGerritRobotRunID(fileAnnotations)
+	if err != nil {
+		return err
+	}
+	pathToRobotComments := make(map[string][]externalGerritRobotComment)
+	for _, fileAnnotation := range fileAnnotations {
+		robotComment := newExternalGerritRobotComment(fileAnnotation, robotRunID)
+		pathToRobotComments[robotComment.Path] = append(pathToRobotComments[robotComment.Path], robotComment)
+	}
+	data, err := json.Marshal(
+		externalGerritReviewInput{
+			RobotComments: pathToRobotComments,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// newExternalGerritRobotComment returns a new externalGerritRobotComment.
+//
+// If robotRunID is empty, the run ID is computed from the FileAnnotation alone.
+func newExternalGerritRobotComment(fileAnnotation FileAnnotation, robotRunID string) externalGerritRobotComment {
+	if robotRunID == "" {
+		// this cannot error for a single valid FileAnnotation
+		robotRunID, _ = getGerritRobotRunID([]FileAnnotation{fileAnnotation})
+	}
+	path := gerritPatchsetLevelPath
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.ExternalPath()
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = fileAnnotation.Type()
+	}
+	robotComment := externalGerritRobotComment{
+		RobotID:    gerritRobotID,
+		RobotRunID: robotRunID,
+		Path:       path,
+		Line:       fileAnnotation.StartLine(),
+		Message:    message,
+	}
+	if typeString := fileAnnotation.Type(); typeString != "" {
+		robotComment.Properties = map[string]string{
+			"type": typeString,
+		}
+	}
+	// Gerrit ranges use 0-based characters with an exclusive end character,
+	// while FileAnnotations use 1-based columns
+	if fileAnnotation.StartLine() > 0 &&
+		fileAnnotation.StartColumn() > 0 &&
+		fileAnnotation.EndLine() > 0 &&
+		fileAnnotation.EndColumn() > 0 {
+		robotComment.Range = &externalGerritCommentRange{
+			StartLine:      fileAnnotation.StartLine(),
+			StartCharacter: fileAnnotation.StartColumn() - 1,
+			EndLine:        fileAnnotation.EndLine(),
+			EndCharacter:   fileAnnotation.EndColumn() - 1,
+		}
+	}
+	return robotComment
+}
+
+func getGerritRobotRunID(fileAnnotations []FileAnnotation) (string, error) {
+	hash := sha256.New()
+	for _, fileAnnotation := range fileAnnotations {
+		data, err := fileAnnotation.MarshalJSON()
+		if err != nil {
+			return "", err
+		}
+		if _, err := hash.Write(append(data, '\n')); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+type externalGerritReviewInput struct {
+	RobotComments map[string][]externalGerritRobotComment `json:"robot_comments"`
+}
+
+type externalGerritRobotComment struct {
+	RobotID    string                      `json:"robot_id"`
+	RobotRunID string                      `json:"robot_run_id"`
+	Properties map[string]string           `json:"properties,omitempty"`
+	Path       string                      `json:"path"`
+	Line       int                         `json:"line,omitempty"`
+	Range      *externalGerritCommentRange `json:"range,omitempty"`
+	Message    string                      `json:"message"`
+}
+
+type externalGerritCommentRange struct {
+	StartLine      int `json:"start_line"`
+	StartCharacter int `json:"start_character"`
+	EndLine        int `json:"end_line"`
+	EndCharacter   int `json:"end_character"`
+}
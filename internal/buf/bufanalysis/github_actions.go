@@ -0,0 +1,84 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+var (
+	// https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+	gitHubActionsDataEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	gitHubActionsPropertyEscaper = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+)
+
+func getGitHubActionsCommand(fileAnnotation FileAnnotation) string {
+	typeString := fileAnnotation.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = typeString
+	}
+	buffer := bytes.NewBuffer(nil)
+	// the workflow commands are named after the severity
+	_, _ = buffer.WriteString("::")
+	_, _ = buffer.WriteString(fileAnnotation.Severity().String())
+	_, _ = buffer.WriteRune(' ')
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		_, _ = buffer.WriteString("file=")
+		_, _ = buffer.WriteString(gitHubActionsPropertyEscaper.Replace(fileInfo.ExternalPath()))
+		_, _ = buffer.WriteRune(',')
+		if startLine := fileAnnotation.StartLine(); startLine != 0 {
+			writeGitHubActionsIntProperty(buffer, "line", startLine)
+			if endLine := fileAnnotation.EndLine(); endLine != 0 {
+				writeGitHubActionsIntProperty(buffer, "endLine", endLine)
+			}
+			if startColumn := fileAnnotation.StartColumn(); startColumn != 0 {
+				writeGitHubActionsIntProperty(buffer, "col", startColumn)
+				// GitHub only uses the ending column if the annotation is on a single line
+				if endColumn := fileAnnotation.EndColumn(); endColumn != 0 && fileAnnotation.EndLine() == startLine {
+					writeGitHubActionsIntProperty(buffer, "endColumn", endColumn)
+				}
+			}
+		}
+	}
+	_, _ = buffer.WriteString("title=")
+	_, _ = buffer.WriteString(gitHubActionsPropertyEscaper.Replace(typeString))
+	_, _ = buffer.WriteString("::")
+	_, _ = buffer.WriteString(gitHubActionsDataEscaper.Replace(message))
+	return buffer.String()
+}
+
+func writeGitHubActionsIntProperty(buffer *bytes.Buffer, key string, value int) {
+	_, _ = buffer.WriteString(key)
+	_, _ = buffer.WriteRune('=')
+	_, _ = buffer.WriteString(strconv.Itoa(value))
+	_, _ = buffer.WriteRune(',')
+}
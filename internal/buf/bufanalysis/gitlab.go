@@ -0,0 +1,125 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+func printFileAnnotationsGitLab(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	externalGitLabIssues := make([]externalGitLabIssue, 0, len(fileAnnotations))
+	// the fingerprint is used by GitLab to track issues across commits, so we do
+	// not include the line and column, as otherwise moving code would result
+	// in new issues. Instead, we disambiguate otherwise-equal FileAnnotations
+	// by the number of times we have seen them.
+	keyToCount := make(map[string]int)
+	for _, fileAnnotation := range fileAnnotations {
+		key := getGitLabFingerprintKey(fileAnnotation)
+		count := keyToCount[key]
+		keyToCount[key] = count + 1
+		if count > 0 {
+			key = key + "\x00" + strconv.Itoa(count)
+		}
+		externalGitLabIssues = append(externalGitLabIssues, newExternalGitLabIssue(fileAnnotation, key))
+	}
+	data, err := json.Marshal(externalGitLabIssues)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// newExternalGitLabIssue returns a new externalGitLabIssue.
+//
+// If fingerprintKey is empty, the key is computed from the FileAnnotation.
+func newExternalGitLabIssue(fileAnnotation FileAnnotation, fingerprintKey string) externalGitLabIssue {
+	if fingerprintKey == "" {
+		fingerprintKey = getGitLabFingerprintKey(fileAnnotation)
+	}
+	fingerprint := sha256.Sum256([]byte(fingerprintKey))
+	path := "<input>"
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.ExternalPath()
+	}
+	description := fileAnnotation.Message()
+	if description == "" {
+		description = fileAnnotation.Type()
+	}
+	checkName := fileAnnotation.Type()
+	if checkName == "" {
+		// should never happen but just in case
+		checkName = "FAILURE"
+	}
+	beginLine := fileAnnotation.StartLine()
+	if beginLine == 0 {
+		beginLine = 1
+	}
+	endLine := fileAnnotation.EndLine()
+	if endLine < beginLine {
+		endLine = beginLine
+	}
+	return externalGitLabIssue{
+		Description: description,
+		CheckName:   checkName,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		Severity:    getGitLabSeverity(fileAnnotation.Severity()),
+		Location: externalGitLabLocation{
+			Path: path,
+			Lines: externalGitLabLines{
+				Begin: beginLine,
+				End:   endLine,
+			},
+		},
+	}
+}
+
+// getGitLabSeverity returns the GitLab Code Quality severity for the Severity.
+func getGitLabSeverity(severity Severity) string {
+	if severity == SeverityWarning {
+		return "minor"
+	}
+	return "major"
+}
+
+func getGitLabFingerprintKey(fileAnnotation FileAnnotation) string {
+	path := ""
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.Path()
+	}
+	return path + "\x00" + fileAnnotation.Type() + "\x00" + fileAnnotation.Message()
+}
+
+type externalGitLabIssue struct {
+	Description string                 `json:"description"`
+	CheckName   string                 `json:"check_name"`
+	Fingerprint string                 `json:"fingerprint"`
+	Severity    string                 `json:"severity"`
+	Location    externalGitLabLocation `json:"location"`
+}
+
+type externalGitLabLocation struct {
+	Path  string              `json:"path"`
+	Lines externalGitLabLines `json:"lines"`
+}
+
+type externalGitLabLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
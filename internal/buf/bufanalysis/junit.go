@@ -0,0 +1,117 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+func printFileAnnotationsJUnit(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	externalJUnitTestSuites := externalJUnitTestSuites{}
+	// files are printed in the order they are first seen
+	pathToIndex := make(map[string]int)
+	for _, fileAnnotation := range fileAnnotations {
+		path := getCheckstylePath(fileAnnotation)
+		index, ok := pathToIndex[path]
+		if !ok {
+			index = len(externalJUnitTestSuites.TestSuites)
+			pathToIndex[path] = index
+			externalJUnitTestSuites.TestSuites = append(
+				externalJUnitTestSuites.TestSuites,
+				externalJUnitTestSuite{
+					Name: path,
+				},
+			)
+		}
+		externalJUnitTestSuite := &externalJUnitTestSuites.TestSuites[index]
+		externalJUnitTestSuite.Tests++
+		externalJUnitTestSuite.Failures++
+		externalJUnitTestSuite.TestCases = append(
+			externalJUnitTestSuite.TestCases,
+			newExternalJUnitTestCase(fileAnnotation),
+		)
+		externalJUnitTestSuites.Tests++
+		externalJUnitTestSuites.Failures++
+	}
+	data, err := xml.MarshalIndent(externalJUnitTestSuites, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func newExternalJUnitTestCase(fileAnnotation FileAnnotation) externalJUnitTestCase {
+	path := getCheckstylePath(fileAnnotation)
+	line := fileAnnotation.StartLine()
+	if line == 0 {
+		line = 1
+	}
+	column := fileAnnotation.StartColumn()
+	if column == 0 {
+		column = 1
+	}
+	typeString := fileAnnotation.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = typeString
+	}
+	return externalJUnitTestCase{
+		// test cases are named by location so that they are unique within the file
+		Name:      path + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(column) + ":" + typeString,
+		ClassName: path,
+		Failure: externalJUnitFailure{
+			Message:  message,
+			Type:     typeString,
+			Contents: fileAnnotation.String(),
+		},
+	}
+}
+
+type externalJUnitTestSuites struct {
+	XMLName    xml.Name                 `xml:"testsuites"`
+	Tests      int                      `xml:"tests,attr"`
+	Failures   int                      `xml:"failures,attr"`
+	TestSuites []externalJUnitTestSuite `xml:"testsuite"`
+}
+
+type externalJUnitTestSuite struct {
+	Name      string                  `xml:"name,attr"`
+	Tests     int                     `xml:"tests,attr"`
+	Failures  int                     `xml:"failures,attr"`
+	TestCases []externalJUnitTestCase `xml:"testcase"`
+}
+
+type externalJUnitTestCase struct {
+	XMLName   xml.Name             `xml:"testcase"`
+	Name      string               `xml:"name,attr"`
+	ClassName string               `xml:"classname,attr"`
+	Failure   externalJUnitFailure `xml:"failure"`
+}
+
+type externalJUnitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"\r\n", " ",
+	"\n", " ",
+)
+
+func printFileAnnotationsMarkdown(writer io.Writer, fileAnnotations []FileAnnotation) error {
+	// group by type, keeping the existing order within each type
+	sortedFileAnnotations := make([]FileAnnotation, len(fileAnnotations))
+	copy(sortedFileAnnotations, fileAnnotations)
+	sort.SliceStable(
+		sortedFileAnnotations,
+		func(i int, j int) bool {
+			return sortedFileAnnotations[i].Type() < sortedFileAnnotations[j].Type()
+		},
+	)
+	buffer := bytes.NewBuffer(nil)
+	_, _ = buffer.WriteString("**")
+	_, _ = buffer.WriteString(strconv.Itoa(len(fileAnnotations)))
+	if len(fileAnnotations) == 1 {
+		_, _ = buffer.WriteString(" failure")
+	} else {
+		_, _ = buffer.WriteString(" failures")
+	}
+	_, _ = buffer.WriteString("**\n\n")
+	_, _ = buffer.WriteString("| Rule | Location | Message |\n")
+	_, _ = buffer.WriteString("| --- | --- | --- |\n")
+	for i, fileAnnotation := range sortedFileAnnotations {
+		// only print the type on the first row of each group
+		printType := i == 0 || sortedFileAnnotations[i-1].Type() != fileAnnotation.Type()
+		_, _ = buffer.WriteString(getMarkdownRow(fileAnnotation, printType))
+		_, _ = buffer.WriteRune('\n')
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func getMarkdownRow(fileAnnotation FileAnnotation, printType bool) string {
+	path := "<input>"
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.ExternalPath()
+	}
+	line := fileAnnotation.StartLine()
+	if line == 0 {
+		line = 1
+	}
+	column := fileAnnotation.StartColumn()
+	if column == 0 {
+		column = 1
+	}
+	typeString := fileAnnotation.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = typeString
+	}
+	buffer := bytes.NewBuffer(nil)
+	_, _ = buffer.WriteString("| ")
+	if printType {
+		_, _ = buffer.WriteRune('`')
+		_, _ = buffer.WriteString(typeString)
+		_, _ = buffer.WriteRune('`')
+	}
+	_, _ = buffer.WriteString(" | `")
+	_, _ = buffer.WriteString(markdownEscaper.Replace(path))
+	_, _ = buffer.WriteRune(':')
+	_, _ = buffer.WriteString(strconv.Itoa(line))
+	_, _ = buffer.WriteRune(':')
+	_, _ = buffer.WriteString(strconv.Itoa(column))
+	_, _ = buffer.WriteString("` | ")
+	_, _ = buffer.WriteString(markdownEscaper.Replace(message))
+	_, _ = buffer.WriteString(" |")
+	return buffer.String()
+}
@@ -0,0 +1,299 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const (
+	// gitHubReviewEvent is the event of GitHub reviews, which only comments
+	// so that the review does not block merging on its own.
+	gitHubReviewEvent = "COMMENT"
+	// gitHubReviewSide is the side of the diff of GitHub review comments,
+	// which is always the new version of the file.
+	gitHubReviewSide = "RIGHT"
+	// gitLabReviewPositionType is the position type of GitLab discussions.
+	gitLabReviewPositionType = "text"
+)
+
+func printFileAnnotationsGitHubReview(writer io.Writer, fileAnnotations []FileAnnotation, groupByFile bool) error {
+	review := externalGitHubReview{
+		Event:    gitHubReviewEvent,
+		Comments: []externalGitHubReviewComment{},
+	}
+	// FileAnnotations without a location cannot be comments, so they are
+	// listed in the body instead
+	var bodyFileAnnotations []FileAnnotation
+	var commentFileAnnotations []FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		if hasReviewLocation(fileAnnotation) {
+			commentFileAnnotations = append(commentFileAnnotations, fileAnnotation)
+		} else {
+			bodyFileAnnotations = append(bodyFileAnnotations, fileAnnotation)
+		}
+	}
+	if groupByFile {
+		for _, pathFileAnnotations := range groupReviewFileAnnotationsByPath(commentFileAnnotations) {
+			review.Comments = append(
+				review.Comments,
+				externalGitHubReviewComment{
+					Path: pathFileAnnotations[0].FileInfo().ExternalPath(),
+					Line: pathFileAnnotations[0].StartLine(),
+					Side: gitHubReviewSide,
+					Body: getGroupedReviewBody(pathFileAnnotations),
+				},
+			)
+		}
+	} else {
+		for _, fileAnnotation := range commentFileAnnotations {
+			review.Comments = append(review.Comments, newExternalGitHubReviewComment(fileAnnotation))
+		}
+	}
+	review.Body = getReviewSummary(fileAnnotations, bodyFileAnnotations, groupByFile)
+	data, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func newExternalGitHubReviewComment(fileAnnotation FileAnnotation) externalGitHubReviewComment {
+	comment := externalGitHubReviewComment{
+		Line: fileAnnotation.StartLine(),
+		Side: gitHubReviewSide,
+		Body: getReviewBody(fileAnnotation),
+	}
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		comment.Path = fileInfo.ExternalPath()
+	}
+	// multi-line comments are set with start_line, and line is the last line
+	if endLine := fileAnnotation.EndLine(); endLine > comment.Line && comment.Line > 0 {
+		comment.StartLine = comment.Line
+		comment.Line = endLine
+	}
+	return comment
+}
+
+func printFileAnnotationsGitLabReview(writer io.Writer, fileAnnotations []FileAnnotation, groupByFile bool) error {
+	discussions := make([]externalGitLabDiscussion, 0, len(fileAnnotations))
+	if groupByFile {
+		var commentFileAnnotations []FileAnnotation
+		var bodyFileAnnotations []FileAnnotation
+		for _, fileAnnotation := range fileAnnotations {
+			if hasReviewLocation(fileAnnotation) {
+				commentFileAnnotations = append(commentFileAnnotations, fileAnnotation)
+			} else {
+				bodyFileAnnotations = append(bodyFileAnnotations, fileAnnotation)
+			}
+		}
+		// GitLab has no review body, so the summary is its own discussion
+		discussions = append(
+			discussions,
+			externalGitLabDiscussion{
+				Body: getReviewSummary(fileAnnotations, bodyFileAnnotations, true),
+			},
+		)
+		for _, pathFileAnnotations := range groupReviewFileAnnotationsByPath(commentFileAnnotations) {
+			discussions = append(
+				discussions,
+				externalGitLabDiscussion{
+					Body:     getGroupedReviewBody(pathFileAnnotations),
+					Position: newExternalGitLabPosition(pathFileAnnotations[0]),
+				},
+			)
+		}
+	} else {
+		for _, fileAnnotation := range fileAnnotations {
+			discussions = append(discussions, newExternalGitLabDiscussion(fileAnnotation))
+		}
+	}
+	data, err := json.Marshal(discussions)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func newExternalGitLabDiscussion(fileAnnotation FileAnnotation) externalGitLabDiscussion {
+	discussion := externalGitLabDiscussion{
+		Body: getReviewBody(fileAnnotation),
+	}
+	if hasReviewLocation(fileAnnotation) {
+		discussion.Position = newExternalGitLabPosition(fileAnnotation)
+	}
+	return discussion
+}
+
+func newExternalGitLabPosition(fileAnnotation FileAnnotation) *externalGitLabPosition {
+	path := fileAnnotation.FileInfo().ExternalPath()
+	return &externalGitLabPosition{
+		PositionType: gitLabReviewPositionType,
+		NewPath:      path,
+		OldPath:      path,
+		NewLine:      fileAnnotation.StartLine(),
+	}
+}
+
+// hasReviewLocation returns true if the FileAnnotation has a file and line,
+// which review comments must have.
+func hasReviewLocation(fileAnnotation FileAnnotation) bool {
+	return fileAnnotation.FileInfo() != nil && fileAnnotation.StartLine() > 0
+}
+
+// groupReviewFileAnnotationsByPath groups the FileAnnotations by external
+// path, sorted by path, with the FileAnnotations of each path sorted by line.
+//
+// All FileAnnotations must have a review location.
+func groupReviewFileAnnotationsByPath(fileAnnotations []FileAnnotation) [][]FileAnnotation {
+	pathToFileAnnotations := make(map[string][]FileAnnotation)
+	for _, fileAnnotation := range fileAnnotations {
+		path := fileAnnotation.FileInfo().ExternalPath()
+		pathToFileAnnotations[path] = append(pathToFileAnnotations[path], fileAnnotation)
+	}
+	paths := make([]string, 0, len(pathToFileAnnotations))
+	for path := range pathToFileAnnotations {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	groups := make([][]FileAnnotation, len(paths))
+	for i, path := range paths {
+		pathFileAnnotations := pathToFileAnnotations[path]
+		sort.SliceStable(
+			pathFileAnnotations,
+			func(i int, j int) bool {
+				return pathFileAnnotations[i].StartLine() < pathFileAnnotations[j].StartLine()
+			},
+		)
+		groups[i] = pathFileAnnotations
+	}
+	return groups
+}
+
+// getReviewBody returns the Markdown body of the review comment for the
+// FileAnnotation, with the suggested severity and the type.
+func getReviewBody(fileAnnotation FileAnnotation) string {
+	typeString := fileAnnotation.Type()
+	if typeString == "" {
+		// should never happen but just in case
+		typeString = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = typeString
+	}
+	return "**" + fileAnnotation.Severity().String() + "** `" + typeString + "`: " + message
+}
+
+// getGroupedReviewBody returns the Markdown body of the review comment for
+// the FileAnnotations of a single file, which are listed with their lines.
+func getGroupedReviewBody(fileAnnotations []FileAnnotation) string {
+	buffer := bytes.NewBuffer(nil)
+	writeReviewCount(buffer, len(fileAnnotations))
+	_, _ = buffer.WriteString(" in this file\n")
+	for _, fileAnnotation := range fileAnnotations {
+		_, _ = buffer.WriteString("\n- Line ")
+		_, _ = buffer.WriteString(strconv.Itoa(fileAnnotation.StartLine()))
+		_, _ = buffer.WriteString(": ")
+		_, _ = buffer.WriteString(getReviewBody(fileAnnotation))
+	}
+	return buffer.String()
+}
+
+// getReviewSummary returns the Markdown summary of the review, which lists
+// the FileAnnotations without a review location, and if groupByFile is set,
+// the number of FileAnnotations of each file.
+func getReviewSummary(
+	fileAnnotations []FileAnnotation,
+	bodyFileAnnotations []FileAnnotation,
+	groupByFile bool,
+) string {
+	buffer := bytes.NewBuffer(nil)
+	writeReviewCount(buffer, len(fileAnnotations))
+	if groupByFile {
+		pathToCount := make(map[string]int)
+		var paths []string
+		for _, fileAnnotation := range fileAnnotations {
+			path := "<input>"
+			if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+				path = fileInfo.ExternalPath()
+			}
+			if pathToCount[path] == 0 {
+				paths = append(paths, path)
+			}
+			pathToCount[path]++
+		}
+		sort.Strings(paths)
+		_, _ = buffer.WriteString("\n\n| File | Failures |\n| --- | --- |")
+		for _, path := range paths {
+			_, _ = buffer.WriteString("\n| `")
+			_, _ = buffer.WriteString(markdownEscaper.Replace(path))
+			_, _ = buffer.WriteString("` | ")
+			_, _ = buffer.WriteString(strconv.Itoa(pathToCount[path]))
+			_, _ = buffer.WriteString(" |")
+		}
+	}
+	if len(bodyFileAnnotations) > 0 {
+		_, _ = buffer.WriteRune('\n')
+		for _, fileAnnotation := range bodyFileAnnotations {
+			_, _ = buffer.WriteString("\n- ")
+			_, _ = buffer.WriteString(getReviewBody(fileAnnotation))
+		}
+	}
+	return buffer.String()
+}
+
+func writeReviewCount(buffer *bytes.Buffer, count int) {
+	_, _ = buffer.WriteString("**")
+	_, _ = buffer.WriteString(strconv.Itoa(count))
+	if count == 1 {
+		_, _ = buffer.WriteString(" failure")
+	} else {
+		_, _ = buffer.WriteString(" failures")
+	}
+	_, _ = buffer.WriteString("**")
+}
+
+type externalGitHubReview struct {
+	Body     string                        `json:"body,omitempty"`
+	Event    string                        `json:"event"`
+	Comments []externalGitHubReviewComment `json:"comments"`
+}
+
+type externalGitHubReviewComment struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Side      string `json:"side"`
+	Body      string `json:"body"`
+}
+
+type externalGitLabDiscussion struct {
+	Body     string                  `json:"body"`
+	Position *externalGitLabPosition `json:"position,omitempty"`
+}
+
+type externalGitLabPosition struct {
+	PositionType string `json:"position_type"`
+	NewPath      string `json:"new_path"`
+	OldPath      string `json:"old_path"`
+	NewLine      int    `json:"new_line"`
+}
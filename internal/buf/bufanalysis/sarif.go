@@ -0,0 +1,283 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+const (
+	// sarifVersion is the SARIF version of the output.
+	sarifVersion = "2.1.0"
+	// sarifSchema is the JSON schema of sarifVersion.
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifToolName is the name of the tool that produced the results.
+	sarifToolName = "buf"
+	// sarifToolInformationURI is the URI of the tool that produced the results.
+	sarifToolInformationURI = "https://github.com/bufbuild/buf"
+)
+
+func printFileAnnotationsSARIF(writer io.Writer, fileAnnotations []FileAnnotation, rules []Rule) error {
+	run := externalSARIFRun{
+		Tool: externalSARIFTool{
+			Driver: externalSARIFDriver{
+				Name:           sarifToolName,
+				InformationURI: sarifToolInformationURI,
+				// rules must be an array, even if empty
+				Rules: make([]externalSARIFRule, 0, len(rules)),
+			},
+		},
+		Results: make([]externalSARIFResult, 0, len(fileAnnotations)),
+	}
+	// the given rules are listed first in the order given, followed by
+	// any other types of the FileAnnotations, such as compile errors,
+	// in the order they are first seen
+	ruleIDToIndex := make(map[string]int)
+	addRule := func(rule Rule) int {
+		index, ok := ruleIDToIndex[rule.ID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIDToIndex[rule.ID] = index
+			run.Tool.Driver.Rules = append(
+				run.Tool.Driver.Rules,
+				newExternalSARIFRule(rule),
+			)
+		}
+		return index
+	}
+	for _, rule := range rules {
+		addRule(rule)
+	}
+	for _, fileAnnotation := range fileAnnotations {
+		result := newExternalSARIFResult(fileAnnotation)
+		ruleIndex := addRule(Rule{ID: result.RuleID})
+		result.RuleIndex = &ruleIndex
+		run.Results = append(run.Results, result)
+	}
+	data, err := json.Marshal(
+		externalSARIF{
+			Schema:  sarifSchema,
+			Version: sarifVersion,
+			Runs:    []externalSARIFRun{run},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func newExternalSARIFRule(rule Rule) externalSARIFRule {
+	externalSARIFRule := externalSARIFRule{
+		ID: rule.ID,
+	}
+	if rule.Purpose != "" {
+		externalSARIFRule.ShortDescription = &externalSARIFMessage{
+			Text: rule.Purpose,
+		}
+	}
+	if rule.Help != "" {
+		externalSARIFRule.Help = &externalSARIFMessage{
+			Text:     rule.Help,
+			Markdown: rule.Help,
+		}
+	}
+	return externalSARIFRule
+}
+
+// newExternalSARIFResult returns a new externalSARIFResult without a rule index.
+func newExternalSARIFResult(fileAnnotation FileAnnotation) externalSARIFResult {
+	ruleID := fileAnnotation.Type()
+	if ruleID == "" {
+		// should never happen but just in case
+		ruleID = "FAILURE"
+	}
+	message := fileAnnotation.Message()
+	if message == "" {
+		message = ruleID
+	}
+	externalSARIFResult := externalSARIFResult{
+		RuleID: ruleID,
+		Level:  getSARIFLevel(fileAnnotation.Severity()),
+		Message: externalSARIFMessage{
+			Text: message,
+		},
+	}
+	fileInfo := fileAnnotation.FileInfo()
+	if fileInfo == nil {
+		return externalSARIFResult
+	}
+	artifactLocation := externalSARIFArtifactLocation{
+		URI: filepath.ToSlash(fileInfo.ExternalPath()),
+	}
+	externalSARIFResult.Locations = []externalSARIFLocation{
+		{
+			PhysicalLocation: externalSARIFPhysicalLocation{
+				ArtifactLocation: artifactLocation,
+				Region: newExternalSARIFRegion(
+					fileAnnotation.StartLine(),
+					fileAnnotation.StartColumn(),
+					fileAnnotation.EndLine(),
+					fileAnnotation.EndColumn(),
+				),
+			},
+		},
+	}
+	if edits := fileAnnotation.Edits(); len(edits) > 0 {
+		replacements := make([]externalSARIFReplacement, 0, len(edits))
+		for _, edit := range edits {
+			replacements = append(
+				replacements,
+				externalSARIFReplacement{
+					DeletedRegion: newExternalSARIFRegion(
+						edit.StartLine,
+						edit.StartColumn,
+						edit.EndLine,
+						edit.EndColumn,
+					),
+					InsertedContent: &externalSARIFArtifactContent{
+						Text: edit.NewText,
+					},
+				},
+			)
+		}
+		externalSARIFResult.Fixes = []externalSARIFFix{
+			{
+				ArtifactChanges: []externalSARIFArtifactChange{
+					{
+						ArtifactLocation: artifactLocation,
+						Replacements:     replacements,
+					},
+				},
+			},
+		}
+	}
+	return externalSARIFResult
+}
+
+// getSARIFLevel returns the SARIF result level for the Severity.
+func getSARIFLevel(severity Severity) string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// newExternalSARIFRegion returns a new externalSARIFRegion, or nil if the
+// start line is not known.
+//
+// SARIF lines and columns are 1-indexed and the end column is exclusive,
+// as with FileAnnotations, so only unknown values have to be omitted.
+func newExternalSARIFRegion(startLine int, startColumn int, endLine int, endColumn int) *externalSARIFRegion {
+	if startLine == 0 {
+		return nil
+	}
+	externalSARIFRegion := &externalSARIFRegion{
+		StartLine: startLine,
+	}
+	if startColumn != 0 {
+		externalSARIFRegion.StartColumn = startColumn
+	}
+	if endLine >= startLine {
+		externalSARIFRegion.EndLine = endLine
+		if endColumn != 0 && startColumn != 0 {
+			externalSARIFRegion.EndColumn = endColumn
+		}
+	}
+	return externalSARIFRegion
+}
+
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type externalSARIF struct {
+	Schema  string             `json:"$schema"`
+	Version string             `json:"version"`
+	Runs    []externalSARIFRun `json:"runs"`
+}
+
+type externalSARIFRun struct {
+	Tool    externalSARIFTool     `json:"tool"`
+	Results []externalSARIFResult `json:"results"`
+}
+
+type externalSARIFTool struct {
+	Driver externalSARIFDriver `json:"driver"`
+}
+
+type externalSARIFDriver struct {
+	Name           string              `json:"name"`
+	InformationURI string              `json:"informationUri"`
+	Rules          []externalSARIFRule `json:"rules"`
+}
+
+type externalSARIFRule struct {
+	ID               string                `json:"id"`
+	ShortDescription *externalSARIFMessage `json:"shortDescription,omitempty"`
+	Help             *externalSARIFMessage `json:"help,omitempty"`
+}
+
+type externalSARIFResult struct {
+	RuleID    string                  `json:"ruleId"`
+	RuleIndex *int                    `json:"ruleIndex,omitempty"`
+	Level     string                  `json:"level"`
+	Message   externalSARIFMessage    `json:"message"`
+	Locations []externalSARIFLocation `json:"locations,omitempty"`
+	Fixes     []externalSARIFFix      `json:"fixes,omitempty"`
+}
+
+type externalSARIFMessage struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown,omitempty"`
+}
+
+type externalSARIFLocation struct {
+	PhysicalLocation externalSARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type externalSARIFPhysicalLocation struct {
+	ArtifactLocation externalSARIFArtifactLocation `json:"artifactLocation"`
+	Region           *externalSARIFRegion          `json:"region,omitempty"`
+}
+
+type externalSARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type externalSARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type externalSARIFFix struct {
+	ArtifactChanges []externalSARIFArtifactChange `json:"artifactChanges"`
+}
+
+type externalSARIFArtifactChange struct {
+	ArtifactLocation externalSARIFArtifactLocation `json:"artifactLocation"`
+	Replacements     []externalSARIFReplacement    `json:"replacements"`
+}
+
+type externalSARIFReplacement struct {
+	DeletedRegion   *externalSARIFRegion          `json:"deletedRegion"`
+	InsertedContent *externalSARIFArtifactContent `json:"insertedContent,omitempty"`
+}
+
+type externalSARIFArtifactContent struct {
+	Text string `json:"text"`
+}
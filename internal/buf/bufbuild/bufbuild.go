@@ -30,7 +30,8 @@ type Builder interface {
 	// they are unique relative to the roots.
 	//
 	// If an error is returned, it is a system error.
-	// Only one of Image and FileAnnotations will be returned.
+	// Only one of Image and FileAnnotations will be returned, unless WithPartial
+	// is given, in which case both may be returned.
 	//
 	// FileAnnotations will use external file paths.
 	Build(
@@ -54,3 +55,38 @@ func WithExcludeSourceCodeInfo() BuildOption {
 		buildOptions.excludeSourceCodeInfo = true
 	}
 }
+
+// WithPartial returns a BuildOption that returns an Image of the files that
+// compile when some files fail to compile, along with the FileAnnotations for
+// the files that failed.
+//
+// Files that import a file that fails to compile will also fail to compile.
+// If no files compile, only FileAnnotations are returned.
+func WithPartial() BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.partial = true
+	}
+}
+
+// WithParallelism returns a BuildOption that compiles files on at most
+// parallelism workers.
+//
+// Files are split into one chunk per worker, and each chunk is parsed and linked
+// independently. If parallelism < 1, the default of runtime.GOMAXPROCS(0) is used.
+func WithParallelism(parallelism int) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.parallelism = parallelism
+	}
+}
+
+// WithCacheDirPath returns a BuildOption that caches built files in the
+// directory at dirPath, creating the directory if it does not exist.
+//
+// Files are only rebuilt if their content or the content of any file they
+// import has changed since they were cached.
+// Cached files that have not been used for 30 days are removed.
+func WithCacheDirPath(dirPath string) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.cacheDirPath = dirPath
+	}
+}
@@ -0,0 +1,507 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbuild
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+	"github.com/jhump/protoreflect/desc"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// buildCacheVersion is part of the key of every entry, and must be
+	// changed if the output of the builder changes for the same input.
+	buildCacheVersion            = "1"
+	buildCacheDataFileSuffix     = ".data"
+	buildCacheMetadataFileSuffix = ".json"
+	// buildCachePruneFileName is the name of the file whose modification time
+	// is the last time the cache was pruned.
+	buildCachePruneFileName = "pruned"
+	// buildCacheTempFilePrefix is the prefix of the temporary files that
+	// entries are written to before they are renamed.
+	buildCacheTempFilePrefix = "tmp"
+	// buildCacheMaxUnusedDuration is how long an entry can go unused before
+	// it is pruned.
+	buildCacheMaxUnusedDuration = 30 * 24 * time.Hour
+	// buildCachePruneInterval is how often the cache is pruned. This is also
+	// how often the modification times of used entries are updated.
+	buildCachePruneInterval = 24 * time.Hour
+	// buildCacheMemoryMaxEntries is the maximum number of entries that are
+	// also kept in memory.
+	buildCacheMemoryMaxEntries = 10000
+)
+
+// globalBuildCacheMemory keeps the entries read from or written to a cache
+// directory in memory, so that builds in the same process, such as the
+// requests to a persistent worker, do not read and unmarshal them again.
+var globalBuildCacheMemory = newBuildCacheMemory(buildCacheMemoryMaxEntries)
+
+// buildCache caches the FileDescriptorProtos of built files on disk, keyed by
+// the path and content digest of each file.
+//
+// An entry is only used if the content digests of all of the transitive
+// dependencies of the file are unchanged, so that a file is rebuilt if it
+// or any file it imports changes.
+//
+// The modification time of an entry is the last time it was used, up to
+// buildCachePruneInterval. Entries that have not been used for
+// buildCacheMaxUnusedDuration are removed when the cache is pruned, which is
+// done at most once every buildCachePruneInterval when entries are added.
+type buildCache struct {
+	dirPath               string
+	excludeSourceCodeInfo bool
+	parserAccessorHandler *parserAccessorHandler
+	memory                *buildCacheMemory
+	pathToDigest          map[string]string
+	lock                  sync.Mutex
+}
+
+// buildCacheMetadata is the metadata stored alongside the cached FileDescriptorProto.
+type buildCacheMetadata struct {
+	Path   string `json:"path,omitempty"`
+	Digest string `json:"digest,omitempty"`
+	// Dependencies is a map from the path of each transitive dependency
+	// to the content digest of the dependency when the file was built.
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+func newBuildCache(
+	dirPath string,
+	excludeSourceCodeInfo bool,
+	parserAccessorHandler *parserAccessorHandler,
+) *buildCache {
+	return &buildCache{
+		dirPath:               dirPath,
+		excludeSourceCodeInfo: excludeSourceCodeInfo,
+		parserAccessorHandler: parserAccessorHandler,
+		memory:                globalBuildCacheMemory,
+		pathToDigest:          make(map[string]string),
+	}
+}
+
+// Get returns the FileDescriptors for the paths that have valid entries,
+// and the paths that do not, in the order of paths.
+//
+// Entries that cannot be read are treated as missing.
+func (b *buildCache) Get(paths []string) (map[string]*desc.FileDescriptor, []string) {
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	seenPaths := make(map[string]struct{})
+	var cachedPaths []string
+	var uncachedPaths []string
+	for _, path := range paths {
+		pathFileDescriptorProtos, ok := b.getFileDescriptorProtos(path)
+		if !ok {
+			uncachedPaths = append(uncachedPaths, path)
+			continue
+		}
+		cachedPaths = append(cachedPaths, path)
+		for _, fileDescriptorProto := range pathFileDescriptorProtos {
+			if _, ok := seenPaths[fileDescriptorProto.GetName()]; ok {
+				continue
+			}
+			seenPaths[fileDescriptorProto.GetName()] = struct{}{}
+			fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+		}
+	}
+	if len(cachedPaths) == 0 {
+		return nil, uncachedPaths
+	}
+	nameToDescFileDescriptor, err := desc.CreateFileDescriptors(fileDescriptorProtos)
+	if err != nil {
+		// the cache is inconsistent, so build everything
+		return nil, paths
+	}
+	pathToDescFileDescriptor := make(map[string]*desc.FileDescriptor, len(cachedPaths))
+	for _, path := range cachedPaths {
+		pathToDescFileDescriptor[path] = nameToDescFileDescriptor[path]
+	}
+	return pathToDescFileDescriptor, uncachedPaths
+}
+
+// Put adds entries for the FileDescriptors and all of their transitive dependencies.
+func (b *buildCache) Put(descFileDescriptors []*desc.FileDescriptor) error {
+	if err := os.MkdirAll(b.dirPath, 0755); err != nil {
+		return err
+	}
+	alreadySeen := make(map[string]struct{})
+	for _, descFileDescriptor := range descFileDescriptors {
+		if err := b.putRec(descFileDescriptor, alreadySeen); err != nil {
+			return err
+		}
+	}
+	return b.pruneIfDue(time.Now())
+}
+
+func (b *buildCache) putRec(descFileDescriptor *desc.FileDescriptor, alreadySeen map[string]struct{}) error {
+	path := descFileDescriptor.GetName()
+	if _, ok := alreadySeen[path]; ok {
+		return nil
+	}
+	alreadySeen[path] = struct{}{}
+	for _, dependency := range descFileDescriptor.GetDependencies() {
+		if err := b.putRec(dependency, alreadySeen); err != nil {
+			return err
+		}
+	}
+	digest, err := b.getDigest(path)
+	if err != nil {
+		return err
+	}
+	filePathPrefix := b.getFilePathPrefix(path, digest)
+	dependencies := make(map[string]string)
+	if err := b.addDependencyDigestsRec(descFileDescriptor, dependencies); err != nil {
+		return err
+	}
+	metadata := &buildCacheMetadata{
+		Path:         path,
+		Digest:       digest,
+		Dependencies: dependencies,
+	}
+	fileDescriptorProto := descFileDescriptor.AsFileDescriptorProto()
+	b.memory.put(filePathPrefix, metadata, proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto))
+	// the key does not include the dependencies, so an existing entry is
+	// overwritten if it was stored for different dependency digests
+	if existingMetadata, err := b.getMetadata(path, digest); err == nil && stringMapsEqual(existingMetadata.Dependencies, dependencies) {
+		if _, err := os.Stat(filePathPrefix + buildCacheDataFileSuffix); err == nil {
+			return nil
+		}
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fileDescriptorProto)
+	if err != nil {
+		return err
+	}
+	if err := ioutilextended.WriteFileAtomic(filePathPrefix+buildCacheDataFileSuffix, bytes.NewReader(data), buildCacheTempFilePrefix); err != nil {
+		return err
+	}
+	data, err = json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	// the metadata is written last so that entries are only valid once the data is written
+	return ioutilextended.WriteFileAtomic(filePathPrefix+buildCacheMetadataFileSuffix, bytes.NewReader(data), buildCacheTempFilePrefix)
+}
+
+func (b *buildCache) addDependencyDigestsRec(descFileDescriptor *desc.FileDescriptor, dependencies map[string]string) error {
+	for _, dependency := range descFileDescriptor.GetDependencies() {
+		path := dependency.GetName()
+		if _, ok := dependencies[path]; ok {
+			continue
+		}
+		digest, err := b.getDigest(path)
+		if err != nil {
+			return err
+		}
+		dependencies[path] = digest
+		if err := b.addDependencyDigestsRec(dependency, dependencies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getFileDescriptorProtos returns the FileDescriptorProtos of the path and
+// all of its transitive dependencies, or false if there is no valid entry.
+func (b *buildCache) getFileDescriptorProtos(path string) ([]*descriptorpb.FileDescriptorProto, bool) {
+	digest, err := b.getDigest(path)
+	if err != nil {
+		return nil, false
+	}
+	metadata, fileDescriptorProto, err := b.getEntry(path, digest)
+	if err != nil {
+		return nil, false
+	}
+	fileDescriptorProtos := []*descriptorpb.FileDescriptorProto{fileDescriptorProto}
+	for dependencyPath, dependencyDigest := range metadata.Dependencies {
+		currentDependencyDigest, err := b.getDigest(dependencyPath)
+		if err != nil || currentDependencyDigest != dependencyDigest {
+			return nil, false
+		}
+		_, dependencyFileDescriptorProto, err := b.getEntry(dependencyPath, dependencyDigest)
+		if err != nil {
+			return nil, false
+		}
+		fileDescriptorProtos = append(fileDescriptorProtos, dependencyFileDescriptorProto)
+	}
+	return fileDescriptorProtos, true
+}
+
+// getEntry returns the metadata and FileDescriptorProto of the entry for
+// the path and digest, from memory if possible.
+//
+// The FileDescriptorProto is a copy that the caller can modify.
+func (b *buildCache) getEntry(path string, digest string) (*buildCacheMetadata, *descriptorpb.FileDescriptorProto, error) {
+	filePathPrefix := b.getFilePathPrefix(path, digest)
+	if metadata, fileDescriptorProto, ok := b.memory.get(filePathPrefix); ok {
+		return metadata, proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto), nil
+	}
+	metadata, err := b.getMetadata(path, digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileDescriptorProto, err := b.getFileDescriptorProto(path, digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.touch(path, digest)
+	b.memory.put(filePathPrefix, metadata, proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto))
+	return metadata, fileDescriptorProto, nil
+}
+
+func (b *buildCache) getMetadata(path string, digest string) (*buildCacheMetadata, error) {
+	data, err := ioutil.ReadFile(b.getFilePathPrefix(path, digest) + buildCacheMetadataFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &buildCacheMetadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, err
+	}
+	// guard against hash collisions
+	if metadata.Path != path || metadata.Digest != digest {
+		return nil, os.ErrNotExist
+	}
+	return metadata, nil
+}
+
+func (b *buildCache) getFileDescriptorProto(path string, digest string) (*descriptorpb.FileDescriptorProto, error) {
+	data, err := ioutil.ReadFile(b.getFilePathPrefix(path, digest) + buildCacheDataFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(data, fileDescriptorProto); err != nil {
+		return nil, err
+	}
+	if fileDescriptorProto.GetName() != path {
+		return nil, os.ErrNotExist
+	}
+	return fileDescriptorProto, nil
+}
+
+// getDigest returns the content digest of the file at the path.
+//
+// The file is read with the parserAccessorHandler so that the file is
+// resolved the same way the parser resolves it.
+func (b *buildCache) getDigest(path string) (_ string, retErr error) {
+	b.lock.Lock()
+	digest, ok := b.pathToDigest[path]
+	b.lock.Unlock()
+	if ok {
+		return digest, nil
+	}
+	readCloser, err := b.parserAccessorHandler.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, readCloser); err != nil {
+		return "", err
+	}
+	digest = hex.EncodeToString(hash.Sum(nil))
+	b.lock.Lock()
+	b.pathToDigest[path] = digest
+	b.lock.Unlock()
+	return digest, nil
+}
+
+// touch marks the entry for the path and digest as used so that it is not pruned.
+//
+// The modification times are only updated once every buildCachePruneInterval
+// so that reading from the cache does not usually write to it. Errors are
+// ignored, as an entry that is pruned while in use is rebuilt.
+func (b *buildCache) touch(path string, digest string) {
+	filePathPrefix := b.getFilePathPrefix(path, digest)
+	fileInfo, err := os.Stat(filePathPrefix + buildCacheMetadataFileSuffix)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(fileInfo.ModTime()) < buildCachePruneInterval {
+		return
+	}
+	_ = os.Chtimes(filePathPrefix+buildCacheDataFileSuffix, now, now)
+	_ = os.Chtimes(filePathPrefix+buildCacheMetadataFileSuffix, now, now)
+}
+
+// pruneIfDue prunes the cache if it has not been pruned for buildCachePruneInterval.
+func (b *buildCache) pruneIfDue(now time.Time) error {
+	pruneFilePath := filepath.Join(b.dirPath, buildCachePruneFileName)
+	fileInfo, err := os.Stat(pruneFilePath)
+	if err == nil && now.Sub(fileInfo.ModTime()) < buildCachePruneInterval {
+		return nil
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := ioutil.WriteFile(pruneFilePath, nil, 0644); err != nil {
+			return err
+		}
+	}
+	// this is done before pruning so that concurrent builds do not all prune
+	if err := os.Chtimes(pruneFilePath, now, now); err != nil {
+		return err
+	}
+	return b.prune(now)
+}
+
+// prune removes the entries and temporary files that have not been modified
+// for buildCacheMaxUnusedDuration.
+//
+// The metadata file of an entry is removed before the data file so that
+// the entry is never valid without its data.
+func (b *buildCache) prune(now time.Time) error {
+	fileInfos, err := ioutil.ReadDir(b.dirPath)
+	if err != nil {
+		return err
+	}
+	var metadataFilePaths []string
+	var otherFilePaths []string
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		if fileInfo.IsDir() || name == buildCachePruneFileName {
+			continue
+		}
+		if now.Sub(fileInfo.ModTime()) < buildCacheMaxUnusedDuration {
+			continue
+		}
+		filePath := filepath.Join(b.dirPath, name)
+		switch {
+		case strings.HasSuffix(name, buildCacheMetadataFileSuffix):
+			metadataFilePaths = append(metadataFilePaths, filePath)
+		case strings.HasSuffix(name, buildCacheDataFileSuffix), strings.HasPrefix(name, buildCacheTempFilePrefix):
+			otherFilePaths = append(otherFilePaths, filePath)
+		}
+	}
+	for _, filePath := range append(metadataFilePaths, otherFilePaths...) {
+		// another build may have pruned the file already
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *buildCache) getFilePathPrefix(path string, digest string) string {
+	hash := sha256.New()
+	for _, value := range []string{
+		buildCacheVersion,
+		strconv.FormatBool(b.excludeSourceCodeInfo),
+		path,
+		digest,
+	} {
+		// each value is terminated so that values cannot run into each other
+		_, _ = hash.Write([]byte(value))
+		_, _ = hash.Write([]byte{0})
+	}
+	return filepath.Join(b.dirPath, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// buildCacheMemory is a bounded set of entries kept in memory, keyed by the
+// file path prefix of the entry. The least recently used entry is evicted
+// when the set is full.
+type buildCacheMemory struct {
+	maxEntries   int
+	list         *list.List
+	keyToElement map[string]*list.Element
+	lock         sync.Mutex
+}
+
+type buildCacheMemoryEntry struct {
+	key                 string
+	metadata            *buildCacheMetadata
+	fileDescriptorProto *descriptorpb.FileDescriptorProto
+}
+
+func newBuildCacheMemory(maxEntries int) *buildCacheMemory {
+	return &buildCacheMemory{
+		maxEntries:   maxEntries,
+		list:         list.New(),
+		keyToElement: make(map[string]*list.Element),
+	}
+}
+
+// get returns the entry for the key. The returned values must not be modified.
+func (m *buildCacheMemory) get(key string) (*buildCacheMetadata, *descriptorpb.FileDescriptorProto, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	element, ok := m.keyToElement[key]
+	if !ok {
+		return nil, nil, false
+	}
+	m.list.MoveToFront(element)
+	entry := element.Value.(*buildCacheMemoryEntry)
+	return entry.metadata, entry.fileDescriptorProto, true
+}
+
+// put adds or replaces the entry for the key. The values must not be modified afterwards.
+func (m *buildCacheMemory) put(
+	key string,
+	metadata *buildCacheMetadata,
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if element, ok := m.keyToElement[key]; ok {
+		entry := element.Value.(*buildCacheMemoryEntry)
+		entry.metadata = metadata
+		entry.fileDescriptorProto = fileDescriptorProto
+		m.list.MoveToFront(element)
+		return
+	}
+	m.keyToElement[key] = m.list.PushFront(
+		&buildCacheMemoryEntry{
+			key:                 key,
+			metadata:            metadata,
+			fileDescriptorProto: fileDescriptorProto,
+		},
+	)
+	for m.list.Len() > m.maxEntries {
+		element := m.list.Back()
+		m.list.Remove(element)
+		delete(m.keyToElement, element.Value.(*buildCacheMemoryEntry).key)
+	}
+}
+
+func stringMapsEqual(one map[string]string, two map[string]string) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for key, value := range one {
+		if otherValue, ok := two[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
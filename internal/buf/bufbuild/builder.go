@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
 	"runtime/debug"
 	"sync"
 
@@ -25,12 +26,13 @@ import (
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
-	"github.com/bufbuild/buf/internal/pkg/thread"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type builder struct {
@@ -57,6 +59,9 @@ func (b *builder) Build(
 		ctx,
 		module,
 		buildOptions.excludeSourceCodeInfo,
+		buildOptions.partial,
+		buildOptions.parallelism,
+		buildOptions.cacheDirPath,
 	)
 }
 
@@ -64,9 +69,15 @@ func (b *builder) build(
 	ctx context.Context,
 	module bufcore.Module,
 	excludeSourceCodeInfo bool,
+	partial bool,
+	parallelism int,
+	cacheDirPath string,
 ) (bufcore.Image, []bufanalysis.FileAnnotation, error) {
 	defer instrument.Start(b.logger, "build").End()
 
+	if parallelism < 1 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
 	parserAccessorHandler := newParserAccessorHandler(ctx, module)
 	targetFileInfos, err := module.TargetFileInfos(ctx)
 	if err != nil {
@@ -80,11 +91,95 @@ func (b *builder) build(
 		paths[i] = targetFileInfo.Path()
 	}
 
+	var buildCache *buildCache
+	var cachedDescFileDescriptors map[string]*desc.FileDescriptor
+	buildPaths := paths
+	if cacheDirPath != "" {
+		buildCache = newBuildCache(cacheDirPath, excludeSourceCodeInfo, parserAccessorHandler)
+		cachedDescFileDescriptors, buildPaths = buildCache.Get(paths)
+		b.logger.Debug(
+			"build_cache",
+			zap.Int("cached", len(cachedDescFileDescriptors)),
+			zap.Int("uncached", len(buildPaths)),
+		)
+	}
+	instrument.Progress(
+		b.logger,
+		"compiling",
+		zap.Int("num_files", len(buildPaths)),
+		zap.Int("num_cached_files", len(paths)-len(buildPaths)),
+	)
+	builtDescFileDescriptors, fileAnnotations, err := b.compile(
+		ctx,
+		parserAccessorHandler,
+		buildPaths,
+		excludeSourceCodeInfo,
+		partial,
+		parallelism,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fileAnnotations) > 0 && !partial {
+		return nil, fileAnnotations, nil
+	}
+	if buildCache != nil && len(builtDescFileDescriptors) > 0 {
+		if err := buildCache.Put(builtDescFileDescriptors); err != nil {
+			// the cache is only an optimization, so we do not fail the build
+			b.logger.Debug("build_cache_put", zap.Error(err))
+		}
+	}
+
+	nameToBuiltDescFileDescriptor := make(map[string]*desc.FileDescriptor, len(builtDescFileDescriptors))
+	for _, builtDescFileDescriptor := range builtDescFileDescriptors {
+		nameToBuiltDescFileDescriptor[builtDescFileDescriptor.GetName()] = builtDescFileDescriptor
+	}
+	// put the FileDescriptors back in the order of paths, skipping
+	// the paths that did not compile with a partial build
+	var descFileDescriptors []*desc.FileDescriptor
+	for _, path := range paths {
+		if descFileDescriptor, ok := cachedDescFileDescriptors[path]; ok {
+			descFileDescriptors = append(descFileDescriptors, descFileDescriptor)
+		} else if descFileDescriptor, ok := nameToBuiltDescFileDescriptor[path]; ok {
+			descFileDescriptors = append(descFileDescriptors, descFileDescriptor)
+		}
+	}
+	if len(descFileDescriptors) == 0 {
+		return nil, fileAnnotations, nil
+	}
+	image, err := b.getImage(
+		ctx,
+		excludeSourceCodeInfo,
+		descFileDescriptors,
+		parserAccessorHandler,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return image, fileAnnotations, nil
+}
+
+// compile compiles the paths, and returns the FileDescriptors in the order of paths.
+//
+// If there are FileAnnotations and partial is false, no FileDescriptors are returned.
+// If partial is true, the FileDescriptors of the paths that compile are returned.
+func (b *builder) compile(
+	ctx context.Context,
+	parserAccessorHandler *parserAccessorHandler,
+	paths []string,
+	excludeSourceCodeInfo bool,
+	partial bool,
+	parallelism int,
+) ([]*desc.FileDescriptor, []bufanalysis.FileAnnotation, error) {
+	if len(paths) == 0 {
+		return nil, nil, nil
+	}
 	buildResults := b.getBuildResults(
 		ctx,
 		parserAccessorHandler,
 		paths,
 		excludeSourceCodeInfo,
+		parallelism,
 	)
 	var buildResultErr error
 	for _, buildResult := range buildResults {
@@ -99,23 +194,68 @@ func (b *builder) build(
 	}
 	if len(fileAnnotations) > 0 {
 		bufanalysis.SortFileAnnotations(fileAnnotations)
-		return nil, fileAnnotations, nil
+		if !partial {
+			return nil, fileAnnotations, nil
+		}
+		// the build results contain no FileDescriptors for any chunk with an error,
+		// so we rebuild each file on its own to find the files that compile
+		var err error
+		buildResults, paths, err = b.getPartialBuildResults(
+			ctx,
+			parserAccessorHandler,
+			paths,
+			excludeSourceCodeInfo,
+			parallelism,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fileAnnotations, nil
+		}
 	}
-
 	descFileDescriptors, err := getDescFileDescriptorsFromBuildResults(buildResults, paths)
 	if err != nil {
 		return nil, nil, err
 	}
-	image, err := b.getImage(
+	return descFileDescriptors, fileAnnotations, nil
+}
+
+// getPartialBuildResults builds each path on its own, and returns the
+// build results and paths for the paths that compile.
+func (b *builder) getPartialBuildResults(
+	ctx context.Context,
+	parserAccessorHandler *parserAccessorHandler,
+	paths []string,
+	excludeSourceCodeInfo bool,
+	parallelism int,
+) ([]*buildResult, []string, error) {
+	defer instrument.Start(b.logger, "partial").End()
+
+	chunks := make([][]string, len(paths))
+	for i, path := range paths {
+		chunks[i] = []string{path}
+	}
+	pathBuildResults := getBuildResultsForChunks(
 		ctx,
-		excludeSourceCodeInfo,
-		descFileDescriptors,
 		parserAccessorHandler,
+		chunks,
+		excludeSourceCodeInfo,
+		parallelism,
 	)
-	if err != nil {
-		return nil, nil, err
+	var buildResults []*buildResult
+	var successfulPaths []string
+	for i, pathBuildResult := range pathBuildResults {
+		if pathBuildResult.Err != nil {
+			return nil, nil, pathBuildResult.Err
+		}
+		if len(pathBuildResult.FileAnnotations) > 0 {
+			continue
+		}
+		buildResults = append(buildResults, pathBuildResult)
+		successfulPaths = append(successfulPaths, paths[i])
 	}
-	return image, nil, nil
+	return buildResults, successfulPaths, nil
 }
 
 func (b *builder) getBuildResults(
@@ -123,38 +263,65 @@ func (b *builder) getBuildResults(
 	parserAccessorHandler *parserAccessorHandler,
 	paths []string,
 	excludeSourceCodeInfo bool,
+	parallelism int,
 ) []*buildResult {
 	defer instrument.Start(b.logger, "parse").End()
 
-	var buildResults []*buildResult
+	// each chunk is parsed by its own parser, and imports are parsed once per
+	// chunk, so we use one chunk per worker
 	chunkSize := 0
-	if parallelism := thread.Parallelism(); parallelism > 1 {
-		chunkSize = len(paths) / parallelism
+	if parallelism > 1 {
+		chunkSize = (len(paths) + parallelism - 1) / parallelism
 	}
-	chunks := stringutil.SliceToChunks(paths, chunkSize)
-	buildResultC := make(chan *buildResult, len(chunks))
-	for _, iPaths := range chunks {
+	return getBuildResultsForChunks(
+		ctx,
+		parserAccessorHandler,
+		stringutil.SliceToChunks(paths, chunkSize),
+		excludeSourceCodeInfo,
+		parallelism,
+	)
+}
+
+// getBuildResultsForChunks builds each chunk of paths on a pool of at most
+// parallelism workers, and returns the build results in the order of the chunks.
+func getBuildResultsForChunks(
+	ctx context.Context,
+	parserAccessorHandler *parserAccessorHandler,
+	chunks [][]string,
+	excludeSourceCodeInfo bool,
+	parallelism int,
+) []*buildResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	buildResults := make([]*buildResult, len(chunks))
+	semaphoreC := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, iPaths := range chunks {
+		i := i
 		iPaths := iPaths
+		wg.Add(1)
 		go func() {
-			var buildResult *buildResult
-			defer func() {
-				select {
-				case buildResultC <- buildResult:
-				case <-ctx.Done():
-				}
-			}()
+			defer wg.Done()
+			select {
+			case semaphoreC <- struct{}{}:
+			case <-ctx.Done():
+				buildResults[i] = newBuildResult(nil, nil, ctx.Err())
+				return
+			}
+			defer func() { <-semaphoreC }()
 			defer func() {
 				// Recover any panics here since we run in a goroutine
 				v := recover()
 				if v != nil {
-					buildResult = newBuildResult(
+					buildResults[i] = newBuildResult(
 						nil,
 						nil,
 						fmt.Errorf("panic: %v, stack:\n%s", v, string(debug.Stack())),
 					)
 				}
 			}()
-			buildResult = getBuildResult(
+			buildResults[i] = getBuildResult(
 				ctx,
 				parserAccessorHandler,
 				iPaths,
@@ -162,15 +329,17 @@ func (b *builder) getBuildResults(
 			)
 		}()
 	}
-	for i := 0; i < len(chunks); i++ {
-		select {
-		case <-ctx.Done():
-			return []*buildResult{newBuildResult(nil, nil, ctx.Err())}
-		case buildResult := <-buildResultC:
-			buildResults = append(buildResults, buildResult)
-		}
+	doneC := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneC)
+	}()
+	select {
+	case <-ctx.Done():
+		return []*buildResult{newBuildResult(nil, nil, ctx.Err())}
+	case <-doneC:
+		return buildResults
 	}
-	return buildResults
 }
 
 func getBuildResult(
@@ -418,6 +587,15 @@ func (b *builder) getImage(
 			return nil, err
 		}
 	}
+	if len(imageFiles) >= protodescriptor.InternMinFileDescriptorProtos {
+		// large images repeat the same names across files, so we share the
+		// backing memory of equal strings to reduce what we hold onto
+		fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(imageFiles))
+		for i, imageFile := range imageFiles {
+			fileDescriptorProtos[i] = imageFile.Proto()
+		}
+		protodescriptor.InternFileDescriptorProtoStrings(fileDescriptorProtos...)
+	}
 	return bufcore.NewImage(imageFiles)
 }
 
@@ -496,6 +674,9 @@ func newBuildResult(
 
 type buildOptions struct {
 	excludeSourceCodeInfo bool
+	partial               bool
+	parallelism           int
+	cacheDirPath          string
 }
 
 func newBuildOptions() *buildOptions {
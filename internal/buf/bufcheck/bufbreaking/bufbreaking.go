@@ -19,6 +19,8 @@ package bufbreaking
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
@@ -27,6 +29,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// AllConfigVersions are the config versions that the checkers returned by
+// GetAllCheckers are available in.
+var AllConfigVersions = []string{
+	"v1",
+}
+
 // Handler handles the main breaking functionality.
 type Handler interface {
 	// Check runs the breaking checks.
@@ -63,7 +71,23 @@ type Config struct {
 	// created from this package, i.e. created wth ConfigBuilder.NewConfig.
 	Checkers            []Checker
 	IgnoreIDToRootPaths map[string]map[string]struct{}
+	IgnoreIDToSymbols   map[string]map[string]struct{}
 	IgnoreRootPaths     map[string]struct{}
+	// IgnoreRootPathGlobs and IgnoreIDToRootPathGlobs are the ignore paths
+	// that are glob patterns.
+	IgnoreRootPathGlobs     []string
+	IgnoreIDToRootPathGlobs map[string][]string
+	// IgnoreUnstablePackages says to not run the Checkers on files in packages
+	// with alpha, beta, or test version suffixes, or in packages matching
+	// UnstablePackageRegexp.
+	IgnoreUnstablePackages bool
+	// UnstablePackageRegexp matches the packages to consider unstable in
+	// addition to those with unstable version suffixes.
+	//
+	// Only used if IgnoreUnstablePackages is set. Can be nil.
+	UnstablePackageRegexp *regexp.Regexp
+	// Plugins are the check plugins to run in addition to the Checkers.
+	Plugins []*bufcheck.Plugin
 }
 
 // GetCheckers returns the checkers for the given categories.
@@ -80,8 +104,13 @@ func NewConfig(externalConfig ExternalConfig) (*Config, error) {
 	internalConfig, err := internal.ConfigBuilder{
 		Use:                           externalConfig.Use,
 		Except:                        externalConfig.Except,
+		Categories:                    externalConfig.Categories,
 		IgnoreRootPaths:               externalConfig.Ignore,
 		IgnoreIDOrCategoryToRootPaths: externalConfig.IgnoreOnly,
+		IgnoreIDOrCategoryToSymbols:   externalConfig.IgnoreSymbols,
+		MessageSameOptionExtensions:   externalConfig.MessageSameOptionExtensions,
+		ServiceSameOptionExtensions:   externalConfig.ServiceSameOptionExtensions,
+		RPCSameOptionExtensions:       externalConfig.RPCSameOptionExtensions,
 	}.NewConfig(
 		v1CheckerBuilders,
 		v1IDToCategories,
@@ -90,7 +119,26 @@ func NewConfig(externalConfig ExternalConfig) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return internalConfigToConfig(internalConfig), nil
+	plugins, err := bufcheck.NewPlugins(externalConfig.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	internalConfig.Plugins = plugins
+	config := internalConfigToConfig(internalConfig)
+	config.IgnoreUnstablePackages = externalConfig.IgnoreUnstablePackages
+	if externalConfig.UnstablePackagePattern != "" {
+		unstablePackageRegexp, err := regexp.Compile(externalConfig.UnstablePackagePattern)
+		if err != nil {
+			return nil, fmt.Errorf("unstable_package_pattern: %v", err)
+		}
+		config.UnstablePackageRegexp = unstablePackageRegexp
+	}
+	return config, nil
+}
+
+// GetAllCategories gets all known categories.
+func GetAllCategories() []string {
+	return append([]string{}, v1AllCategories...)
 }
 
 // GetAllCheckers gets all known checkers for the given categories.
@@ -106,29 +154,62 @@ func GetAllCheckers(categories ...string) ([]bufcheck.Checker, error) {
 	return checkersToBufcheckCheckers(config.Checkers, categories)
 }
 
+// GetDoc gets the Doc for the breaking checker with the given ID.
+//
+// Returns false if there is no breaking checker with the given ID.
+func GetDoc(id string) (*bufcheck.Doc, bool) {
+	doc, ok := v1IDToDoc[id]
+	return doc, ok
+}
+
 // ExternalConfig is an external config.
 type ExternalConfig struct {
 	Use    []string `json:"use,omitempty" yaml:"use,omitempty"`
 	Except []string `json:"except,omitempty" yaml:"except,omitempty"`
-	// IgnoreRootPaths
+	// Categories are user-defined categories, such as
+	// ACME_WIRE: WIRE + FILE_SAME_PACKAGE
+	Categories map[string]string `json:"categories,omitempty" yaml:"categories,omitempty"`
+	// IgnoreRootPaths, which can be paths to files or directories, or glob
+	// patterns such as "**/vendor/**".
 	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
 	// IgnoreIDOrCategoryToRootPaths
 	IgnoreOnly map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
+	// IgnoreIDOrCategoryToSymbols
+	IgnoreSymbols               map[string][]string `json:"ignore_symbols,omitempty" yaml:"ignore_symbols,omitempty"`
+	MessageSameOptionExtensions []string            `json:"message_same_option_extensions,omitempty" yaml:"message_same_option_extensions,omitempty"`
+	ServiceSameOptionExtensions []string            `json:"service_same_option_extensions,omitempty" yaml:"service_same_option_extensions,omitempty"`
+	RPCSameOptionExtensions     []string            `json:"rpc_same_option_extensions,omitempty" yaml:"rpc_same_option_extensions,omitempty"`
+	// IgnoreUnstablePackages says to skip packages with alpha, beta, or test
+	// version suffixes, such as foo.v1alpha1, foo.v1beta1, and foo.v1test.
+	IgnoreUnstablePackages bool `json:"ignore_unstable_packages,omitempty" yaml:"ignore_unstable_packages,omitempty"`
+	// UnstablePackagePattern is a regular expression for additional packages
+	// to skip if IgnoreUnstablePackages is set.
+	UnstablePackagePattern string `json:"unstable_package_pattern,omitempty" yaml:"unstable_package_pattern,omitempty"`
+	// Plugins are the check plugins to run in addition to the configured checkers.
+	Plugins []bufcheck.ExternalPluginConfig `json:"plugins,omitempty" yaml:"plugins,omitempty"`
 }
 
 func internalConfigToConfig(internalConfig *internal.Config) *Config {
 	return &Config{
-		Checkers:            internalCheckersToCheckers(internalConfig.Checkers),
-		IgnoreIDToRootPaths: internalConfig.IgnoreIDToRootPaths,
-		IgnoreRootPaths:     internalConfig.IgnoreRootPaths,
+		Checkers:                internalCheckersToCheckers(internalConfig.Checkers),
+		IgnoreIDToRootPaths:     internalConfig.IgnoreIDToRootPaths,
+		IgnoreIDToRootPathGlobs: internalConfig.IgnoreIDToRootPathGlobs,
+		IgnoreIDToSymbols:       internalConfig.IgnoreIDToSymbols,
+		IgnoreRootPaths:         internalConfig.IgnoreRootPaths,
+		IgnoreRootPathGlobs:     internalConfig.IgnoreRootPathGlobs,
+		Plugins:                 internalConfig.Plugins,
 	}
 }
 
 func configToInternalConfig(config *Config) *internal.Config {
 	return &internal.Config{
-		Checkers:            checkersToInternalCheckers(config.Checkers),
-		IgnoreIDToRootPaths: config.IgnoreIDToRootPaths,
-		IgnoreRootPaths:     config.IgnoreRootPaths,
+		Checkers:                checkersToInternalCheckers(config.Checkers),
+		IgnoreIDToRootPaths:     config.IgnoreIDToRootPaths,
+		IgnoreIDToRootPathGlobs: config.IgnoreIDToRootPathGlobs,
+		IgnoreIDToSymbols:       config.IgnoreIDToSymbols,
+		IgnoreRootPaths:         config.IgnoreRootPaths,
+		IgnoreRootPathGlobs:     config.IgnoreRootPathGlobs,
+		Plugins:                 config.Plugins,
 	}
 }
 
@@ -274,6 +274,19 @@ func TestRunBreakingFieldSameOneof(t *testing.T) {
 	)
 }
 
+func TestRunBreakingFieldSameProto3Optional(t *testing.T) {
+	// the synthetic oneofs of proto3 optional fields are not oneofs, so
+	// FIELD_SAME_ONEOF and ONEOF_NO_DELETE do not fail
+	testBreaking(
+		t,
+		"breaking_field_same_proto3_optional",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 6, 3, 6, 26, "FIELD_SAME_PROTO3_OPTIONAL"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 7, 3, 7, 17, "FIELD_SAME_PROTO3_OPTIONAL"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 16, 5, 16, 28, "FIELD_SAME_PROTO3_OPTIONAL"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 17, 5, 17, 19, "FIELD_SAME_PROTO3_OPTIONAL"),
+	)
+}
+
 func TestRunBreakingFieldSameType(t *testing.T) {
 	// TODO: double check all this
 	testBreaking(
@@ -299,6 +312,28 @@ func TestRunBreakingFieldSameType(t *testing.T) {
 	)
 }
 
+func TestRunBreakingFieldWireCompatibleType(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_field_wire_compatible_type",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 19, 3, 19, 9, "FIELD_WIRE_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 24, 3, 24, 6, "FIELD_WIRE_COMPATIBLE_TYPE"),
+	)
+}
+
+func TestRunBreakingFieldWireJSONCompatibleType(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_field_wire_json_compatible_type",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 18, 3, 18, 8, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 19, 3, 19, 9, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 21, 3, 21, 8, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 22, 3, 22, 10, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 23, 3, 23, 8, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 24, 3, 24, 6, "FIELD_WIRE_JSON_COMPATIBLE_TYPE"),
+	)
+}
+
 func TestRunBreakingFileNoDelete(t *testing.T) {
 	testBreaking(
 		t,
@@ -400,6 +435,36 @@ func TestRunBreakingMessageSameValues(t *testing.T) {
 	)
 }
 
+func TestRunBreakingMessageOptionExtensions(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_message_option_extensions",
+		bufanalysistesting.NewFileAnnotationNoLocation(t, "1.proto", "MESSAGE_SAME_MAP_ENTRY"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 9, 3, 9, 55, "MESSAGE_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 10, 3, 13, 4, "MESSAGE_SAME_MAP_ENTRY"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 18, 3, 18, 41, "MESSAGE_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 22, 9, 22, 12, "MESSAGE_SAME_OPTION_EXTENSIONS"),
+	)
+}
+
+func TestRunBreakingIgnoreSymbols(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_ignore_symbols",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 7, 3, 7, 9, "FIELD_SAME_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 11, 3, 11, 9, "FIELD_SAME_TYPE"),
+	)
+}
+
+func TestRunBreakingIgnoreUnstablePackages(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_ignore_unstable_packages",
+		bufanalysistesting.NewFileAnnotationNoLocationOrPath(t, "FILE_NO_DELETE"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 6, 3, 6, 9, "FIELD_SAME_TYPE"),
+	)
+}
+
 func TestRunBreakingOneofNoDelete(t *testing.T) {
 	testBreaking(
 		t,
@@ -411,6 +476,18 @@ func TestRunBreakingOneofNoDelete(t *testing.T) {
 	)
 }
 
+func TestRunBreakingOptionExtensions(t *testing.T) {
+	testBreaking(
+		t,
+		"breaking_option_extensions",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 10, 3, 10, 57, "SERVICE_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 12, 3, 12, 38, "SERVICE_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 13, 7, 13, 13, "RPC_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 16, 7, 16, 16, "RPC_SAME_OPTION_EXTENSIONS"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 24, 9, 24, 19, "SERVICE_SAME_OPTION_EXTENSIONS"),
+	)
+}
+
 func TestRunBreakingPackageNoDelete(t *testing.T) {
 	testBreaking(
 		t,
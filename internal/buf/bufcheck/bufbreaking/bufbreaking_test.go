@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal/internaltesting"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultConfigBuilder(t *testing.T) {
@@ -39,3 +40,19 @@ func TestCheckerBuilders(t *testing.T) {
 		v1AllCategories,
 	)
 }
+
+func TestDocs(t *testing.T) {
+	t.Parallel()
+	for id := range v1IDToCategories {
+		doc, ok := v1IDToDoc[id]
+		require.True(t, ok, id)
+		require.NotEmpty(t, doc.Rationale, id)
+		require.NotEmpty(t, doc.PreviousExample, id)
+		require.NotEmpty(t, doc.FailingExample, id)
+		require.NotEmpty(t, doc.PassingExample, id)
+	}
+	for id := range v1IDToDoc {
+		_, ok := v1IDToCategories[id]
+		require.True(t, ok, id)
+	}
+}
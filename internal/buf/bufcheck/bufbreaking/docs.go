@@ -0,0 +1,789 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreaking
+
+import "github.com/bufbuild/buf/internal/buf/bufcheck"
+
+// v1IDToDoc is the documentation for all v1 checkers.
+//
+// Every ID in v1IDToCategories must have an entry.
+var v1IDToDoc = map[string]*bufcheck.Doc{
+	"ENUM_NO_DELETE": newNoDeleteDoc(
+		"an enum",
+		"file",
+		`// foo/v1/foo.proto
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}
+enum Bar {
+  BAR_UNSPECIFIED = 0;
+}`,
+		`// foo/v1/foo.proto
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+		`// foo/v1/foo.proto
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}
+// Deprecated: use Foo instead.
+enum Bar {
+  option deprecated = true;
+  BAR_UNSPECIFIED = 0;
+}`,
+	),
+	"ENUM_VALUE_NO_DELETE": newNoDeleteDoc(
+		"an enum value",
+		"enum",
+		`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+		`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+		`enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1 [deprecated = true];
+}`,
+	),
+	"ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED": {
+		Rationale: `Enum values are serialized as their names in JSON. If a deleted enum value's
+name is not reserved, it can later be reused for a value with a different meaning,
+and JSON written before the deletion is then read as the new value. Reserving the
+name prevents this, while still allowing the value to be deleted.`,
+		PreviousExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+		FailingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+		PassingExample: `enum Foo {
+  reserved "FOO_ONE";
+  FOO_UNSPECIFIED = 0;
+}`,
+	},
+	"ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED": {
+		Rationale: `Enum values are serialized as their numbers on the wire. If a deleted enum
+value's number is not reserved, it can later be reused for a value with a different
+meaning, and data written before the deletion is then read as the new value.
+Reserving the number prevents this, while still allowing the value to be deleted.`,
+		PreviousExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+		FailingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+		PassingExample: `enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+	},
+	"ENUM_VALUE_SAME_NAME": {
+		Rationale: `Enum values are serialized as their names in JSON, and generated code refers
+to them by name. Renaming a value breaks JSON written with the old name, and
+breaks code that refers to the value.`,
+		PreviousExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+		FailingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_FIRST = 1;
+}`,
+		PassingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+	},
+	"EXTENSION_MESSAGE_NO_DELETE": {
+		Rationale: `Extensions declared in other files depend on the extension ranges of the
+message they extend. Deleting an extension range makes these extensions fail to
+compile.`,
+		PreviousExample: `message Foo {
+  extensions 100 to 199;
+}`,
+		FailingExample: `message Foo {}`,
+		PassingExample: `message Foo {
+  extensions 100 to 199;
+}`,
+	},
+	"FIELD_NO_DELETE": newNoDeleteDoc(
+		"a field",
+		"message",
+		`message Foo {
+  string name = 1;
+  string title = 2;
+}`,
+		`message Foo {
+  string name = 1;
+}`,
+		`message Foo {
+  string name = 1;
+  string title = 2 [deprecated = true];
+}`,
+	),
+	"FIELD_NO_DELETE_UNLESS_NAME_RESERVED": {
+		Rationale: `Fields are serialized by their names in JSON. If a deleted field's name is
+not reserved, it can later be reused for a field with a different type or meaning,
+and JSON written before the deletion is then read into the new field. Reserving
+the name prevents this, while still allowing the field to be deleted.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+  string title = 2;
+}`,
+		FailingExample: `message Foo {
+  string name = 1;
+}`,
+		PassingExample: `message Foo {
+  reserved "title";
+  string name = 1;
+}`,
+	},
+	"FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED": {
+		Rationale: `Fields are serialized by their numbers on the wire. If a deleted field's
+number is not reserved, it can later be reused for a field with a different type or
+meaning, and data written before the deletion is then read into the new field.
+Reserving the number prevents this, while still allowing the field to be deleted.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+  string title = 2;
+}`,
+		FailingExample: `message Foo {
+  string name = 1;
+}`,
+		PassingExample: `message Foo {
+  reserved 2;
+  string name = 1;
+}`,
+	},
+	"FIELD_SAME_CTYPE": newFieldSameOptionDoc(
+		`The ctype option changes the type of string fields in code generated for C++.
+Changing it breaks C++ code that uses the field.`,
+		"[ctype = CORD]",
+		"",
+	),
+	"FIELD_SAME_JSON_NAME": newFieldSameOptionDoc(
+		`Fields are serialized by their JSON names in JSON. Changing the json_name
+option breaks JSON written with the old name.`,
+		`[json_name = "fooName"]`,
+		`[json_name = "foo_name"]`,
+	),
+	"FIELD_SAME_JSTYPE": newFieldSameOptionDoc(
+		`The jstype option changes the type of 64-bit integer fields in code generated for
+JavaScript, for example from number to string. Changing it breaks JavaScript code
+that uses the field.`,
+		"[jstype = JS_STRING]",
+		"",
+	),
+	"FIELD_SAME_LABEL": {
+		Rationale: `Changing a field between optional, required, and repeated changes how the
+field is encoded, and how it is represented in generated code. Data written with
+the old label may fail to parse or be read incorrectly, and code that uses the field
+breaks.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+}`,
+		FailingExample: `message Foo {
+  repeated string name = 1;
+}`,
+		PassingExample: `message Foo {
+  string name = 1;
+}`,
+	},
+	"FIELD_SAME_NAME": {
+		Rationale: `Fields are serialized by their names in JSON, and generated code refers to
+them by name. Renaming a field breaks JSON written with the old name, and breaks
+code that refers to the field.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+}`,
+		FailingExample: `message Foo {
+  string title = 1;
+}`,
+		PassingExample: `message Foo {
+  string name = 1;
+}`,
+	},
+	"FIELD_SAME_ONEOF": {
+		Rationale: `Only one field of a oneof can be set at a time. Moving a field into or out of
+a oneof changes which fields are cleared when another is set, and changes the
+generated code for the field.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+}`,
+		FailingExample: `message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+		PassingExample: `message Foo {
+  string name = 1;
+}`,
+	},
+	"FIELD_SAME_PROTO3_OPTIONAL": {
+		Rationale: `A proto3 optional field tracks whether it was set, while a proto3 field without
+the optional label does not. The encoding is the same, but adding or removing the
+optional label changes the generated code for the field, such as pointer types in
+Go and has methods in other languages.`,
+		PreviousExample: `message Foo {
+  string name = 1;
+}`,
+		FailingExample: `message Foo {
+  optional string name = 1;
+}`,
+		PassingExample: `message Foo {
+  string name = 1;
+}`,
+	},
+	"FIELD_SAME_TYPE": {
+		Rationale: `The type of a field determines how it is encoded, and how it is represented in
+generated code. Changing it breaks code that uses the field, even where the
+encodings are compatible.`,
+		PreviousExample: `message Foo {
+  int32 count = 1;
+}`,
+		FailingExample: `message Foo {
+  int64 count = 1;
+}`,
+		PassingExample: `message Foo {
+  int32 count = 1;
+}`,
+	},
+	"FIELD_WIRE_COMPATIBLE_TYPE": {
+		Rationale: `Some types share an encoding on the wire, for example int32 and int64, so data
+written with one can be read with the other. Changing a field to a type with an
+incompatible encoding means data written before the change fails to parse or is
+read incorrectly.`,
+		PreviousExample: `message Foo {
+  int32 count = 1;
+}`,
+		FailingExample: `message Foo {
+  string count = 1;
+}`,
+		PassingExample: `message Foo {
+  int64 count = 1;
+}`,
+	},
+	"FIELD_WIRE_JSON_COMPATIBLE_TYPE": {
+		Rationale: `Some types that share an encoding on the wire differ in JSON, for example int32
+and int64, as 64-bit integers are encoded as strings in JSON. Changing a field to a
+type with an incompatible encoding on the wire or in JSON means data written before
+the change fails to parse or is read incorrectly.`,
+		PreviousExample: `message Foo {
+  int32 count = 1;
+}`,
+		FailingExample: `message Foo {
+  int64 count = 1;
+}`,
+		PassingExample: `message Foo {
+  int32 count = 1;
+}`,
+	},
+	"FILE_NO_DELETE": {
+		Rationale: `Other files import files by path, and generated code is written to paths
+derived from them. Deleting a file breaks the files that import it, and code that
+uses its generated code.`,
+		PreviousExample: `// foo/v1/foo.proto
+// foo/v1/bar.proto`,
+		FailingExample: `// foo/v1/foo.proto`,
+		PassingExample: `// foo/v1/foo.proto
+// foo/v1/bar.proto`,
+	},
+	"FILE_SAME_CC_ENABLE_ARENAS": newFileSameOptionDoc(
+		`The cc_enable_arenas option controls whether code generated for C++ supports arena
+allocation. Disabling it breaks C++ code that allocates messages on arenas.`,
+		"option cc_enable_arenas = true;",
+		"option cc_enable_arenas = false;",
+	),
+	"FILE_SAME_CC_GENERIC_SERVICES": newFileSameOptionDoc(
+		`The cc_generic_services option controls whether generic service code is generated
+for C++. Changing it adds or removes generated classes that C++ code may use.`,
+		"option cc_generic_services = true;",
+		"option cc_generic_services = false;",
+	),
+	"FILE_SAME_CSHARP_NAMESPACE": newFileSameOptionDoc(
+		`The csharp_namespace option is the namespace of the code generated for C#.
+Changing it breaks C# code that refers to the generated types.`,
+		`option csharp_namespace = "Acme.Foo.V1";`,
+		`option csharp_namespace = "Acme.Bar.V1";`,
+	),
+	"FILE_SAME_GO_PACKAGE": newFileSameOptionDoc(
+		`The go_package option is the import path and package name of the code generated
+for Go. Changing it breaks Go code that imports the generated package.`,
+		`option go_package = "github.com/acme/foo/v1;foov1";`,
+		`option go_package = "github.com/acme/bar/v1;barv1";`,
+	),
+	"FILE_SAME_JAVA_GENERIC_SERVICES": newFileSameOptionDoc(
+		`The java_generic_services option controls whether generic service code is
+generated for Java. Changing it adds or removes generated classes that Java code
+may use.`,
+		"option java_generic_services = true;",
+		"option java_generic_services = false;",
+	),
+	"FILE_SAME_JAVA_MULTIPLE_FILES": newFileSameOptionDoc(
+		`The java_multiple_files option controls whether each type is generated into its
+own Java class, or nested in the outer class of the file. Changing it moves the
+generated classes, which breaks Java code that refers to them.`,
+		"option java_multiple_files = true;",
+		"option java_multiple_files = false;",
+	),
+	"FILE_SAME_JAVA_OUTER_CLASSNAME": newFileSameOptionDoc(
+		`The java_outer_classname option is the name of the outer Java class generated for
+the file. Changing it breaks Java code that refers to the class or the types nested
+in it.`,
+		`option java_outer_classname = "FooProto";`,
+		`option java_outer_classname = "BarProto";`,
+	),
+	"FILE_SAME_JAVA_PACKAGE": newFileSameOptionDoc(
+		`The java_package option is the package of the code generated for Java. Changing
+it breaks Java code that imports the generated classes.`,
+		`option java_package = "com.acme.foo.v1";`,
+		`option java_package = "com.acme.bar.v1";`,
+	),
+	"FILE_SAME_JAVA_STRING_CHECK_UTF8": newFileSameOptionDoc(
+		`The java_string_check_utf8 option controls whether code generated for Java
+rejects string fields that are not valid UTF-8. Enabling it makes data that
+previously parsed fail to parse.`,
+		"option java_string_check_utf8 = false;",
+		"option java_string_check_utf8 = true;",
+	),
+	"FILE_SAME_OBJC_CLASS_PREFIX": newFileSameOptionDoc(
+		`The objc_class_prefix option is prefixed to the names of the classes generated for
+Objective-C. Changing it breaks Objective-C code that refers to the classes.`,
+		`option objc_class_prefix = "AFX";`,
+		`option objc_class_prefix = "ABX";`,
+	),
+	"FILE_SAME_OPTIMIZE_FOR": newFileSameOptionDoc(
+		`The optimize_for option controls which code is generated for some languages. For
+example, with LITE_RUNTIME, code generated for C++ and Java depends on the lite
+runtime and lacks reflection, which breaks code that uses either.`,
+		"option optimize_for = SPEED;",
+		"option optimize_for = LITE_RUNTIME;",
+	),
+	"FILE_SAME_PACKAGE": {
+		Rationale: `The package is part of the fully-qualified name of every type in a file, and
+the path of every RPC. Changing it breaks code that refers to the types, and
+clients that call the RPCs.`,
+		PreviousExample: `// foo/v1/foo.proto
+package foo.v1;`,
+		FailingExample: `// foo/v1/foo.proto
+package bar.v1;`,
+		PassingExample: `// foo/v1/foo.proto
+package foo.v1;`,
+	},
+	"FILE_SAME_PHP_CLASS_PREFIX": newFileSameOptionDoc(
+		`The php_class_prefix option is prefixed to the names of the classes generated for
+PHP. Changing it breaks PHP code that refers to the classes.`,
+		`option php_class_prefix = "Afx";`,
+		`option php_class_prefix = "Abx";`,
+	),
+	"FILE_SAME_PHP_GENERIC_SERVICES": newFileSameOptionDoc(
+		`The php_generic_services option controls whether generic service code is
+generated for PHP. Changing it adds or removes generated classes that PHP code may
+use.`,
+		"option php_generic_services = true;",
+		"option php_generic_services = false;",
+	),
+	"FILE_SAME_PHP_METADATA_NAMESPACE": newFileSameOptionDoc(
+		`The php_metadata_namespace option is the namespace of the metadata classes
+generated for PHP. Changing it breaks PHP code that refers to the metadata classes.`,
+		`option php_metadata_namespace = "Acme\\Foo\\V1\\Metadata";`,
+		`option php_metadata_namespace = "Acme\\Bar\\V1\\Metadata";`,
+	),
+	"FILE_SAME_PHP_NAMESPACE": newFileSameOptionDoc(
+		`The php_namespace option is the namespace of the code generated for PHP.
+Changing it breaks PHP code that refers to the generated classes.`,
+		`option php_namespace = "Acme\\Foo\\V1";`,
+		`option php_namespace = "Acme\\Bar\\V1";`,
+	),
+	"FILE_SAME_PY_GENERIC_SERVICES": newFileSameOptionDoc(
+		`The py_generic_services option controls whether generic service code is
+generated for Python. Changing it adds or removes generated classes that Python
+code may use.`,
+		"option py_generic_services = true;",
+		"option py_generic_services = false;",
+	),
+	"FILE_SAME_RUBY_PACKAGE": newFileSameOptionDoc(
+		`The ruby_package option is the module of the code generated for Ruby. Changing it
+breaks Ruby code that refers to the generated classes.`,
+		`option ruby_package = "Acme::Foo::V1";`,
+		`option ruby_package = "Acme::Bar::V1";`,
+	),
+	"FILE_SAME_SWIFT_PREFIX": newFileSameOptionDoc(
+		`The swift_prefix option is prefixed to the names of the types generated for
+Swift. Changing it breaks Swift code that refers to the types.`,
+		`option swift_prefix = "Afx";`,
+		`option swift_prefix = "Abx";`,
+	),
+	"FILE_SAME_SYNTAX": {
+		Rationale: `Proto2 and proto3 differ in field presence, default values, and the handling
+of unknown enum values, and code generators generate different code for each.
+Changing the syntax of a file changes the behavior of its generated code.
+
+Files that use editions express this behavior as features instead. If either file
+uses editions, the syntax or edition can change as long as the file-level features,
+such as field_presence and enum_type, stay the same.`,
+		PreviousExample: `syntax = "proto2";`,
+		FailingExample:  `syntax = "proto3";`,
+		PassingExample:  `syntax = "proto2";`,
+	},
+	"MESSAGE_NO_DELETE": newNoDeleteDoc(
+		"a message",
+		"file",
+		`// foo/v1/foo.proto
+message Foo {}
+message Bar {}`,
+		`// foo/v1/foo.proto
+message Foo {}`,
+		`// foo/v1/foo.proto
+message Foo {}
+// Deprecated: use Foo instead.
+message Bar {
+  option deprecated = true;
+}`,
+	),
+	"MESSAGE_NO_REMOVE_STANDARD_DESCRIPTOR_ACCESSOR": {
+		Rationale: `The no_standard_descriptor_accessor option removes the descriptor accessor from
+the code generated for some languages. Setting it breaks code that uses the
+accessor, while unsetting it only adds an accessor.`,
+		PreviousExample: `message Foo {}`,
+		FailingExample: `message Foo {
+  option no_standard_descriptor_accessor = true;
+}`,
+		PassingExample: `message Foo {}`,
+	},
+	"MESSAGE_SAME_MAP_ENTRY": {
+		Rationale: `Map fields are represented by messages with the map_entry option set. Changing
+the option changes a field between a map and a repeated message, which differ in
+JSON and in generated code.`,
+		PreviousExample: `message Foo {
+  map<string, string> labels = 1;
+}`,
+		FailingExample: `message Foo {
+  message LabelsEntry {
+    string key = 1;
+    string value = 2;
+  }
+  repeated LabelsEntry labels = 1;
+}`,
+		PassingExample: `message Foo {
+  map<string, string> labels = 1;
+}`,
+	},
+	"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT": {
+		Rationale: `The message_set_wire_format option changes how the extensions of a message are
+encoded on the wire. Changing it means data written before the change fails to
+parse.`,
+		PreviousExample: `message Foo {
+  option message_set_wire_format = true;
+  extensions 4 to max;
+}`,
+		FailingExample: `message Foo {
+  extensions 4 to max;
+}`,
+		PassingExample: `message Foo {
+  option message_set_wire_format = true;
+  extensions 4 to max;
+}`,
+	},
+	"MESSAGE_SAME_OPTION_EXTENSIONS": newSameOptionExtensionsDoc(
+		"message",
+		"message_option_extensions",
+		`message Foo {
+  option (google.api.resource) = {
+    type: "acme.com/Foo"
+    pattern: "foos/{foo}"
+  };
+}`,
+		`message Foo {
+  option (google.api.resource) = {
+    type: "acme.com/Foo"
+    pattern: "projects/{project}/foos/{foo}"
+  };
+}`,
+	),
+	"ONEOF_NO_DELETE": {
+		Rationale: `Generated code refers to oneofs by name, for example to determine which field
+of the oneof is set. Deleting a oneof breaks code that uses it.`,
+		PreviousExample: `message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+		FailingExample: `message Foo {
+  string name = 1;
+}`,
+		PassingExample: `message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`,
+	},
+	"PACKAGE_ENUM_NO_DELETE": newPackageNoDeleteDoc(
+		"an enum",
+		`// foo/v1/a.proto
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+		`// foo/v1/b.proto
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+	),
+	"PACKAGE_MESSAGE_NO_DELETE": newPackageNoDeleteDoc(
+		"a message",
+		`// foo/v1/a.proto
+message Foo {}`,
+		`// foo/v1/b.proto
+message Foo {}`,
+	),
+	"PACKAGE_NO_DELETE": {
+		Rationale: `Code that uses generated code imports it by package. Deleting every file of a
+package breaks all such code, even if the files are only moved to another package.`,
+		PreviousExample: `// foo/v1/foo.proto
+package foo.v1;`,
+		FailingExample: `// bar/v1/foo.proto
+package bar.v1;`,
+		PassingExample: `// foo/v1/foo.proto
+package foo.v1;`,
+	},
+	"PACKAGE_SERVICE_NO_DELETE": newPackageNoDeleteDoc(
+		"a service",
+		`// foo/v1/a.proto
+service FooService {}`,
+		`// foo/v1/b.proto
+service FooService {}`,
+	),
+	"RESERVED_ENUM_NO_DELETE": newReservedNoDeleteDoc(
+		"enum",
+		"value",
+		`enum Foo {
+  reserved 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+		`enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`,
+	),
+	"RESERVED_MESSAGE_NO_DELETE": newReservedNoDeleteDoc(
+		"message",
+		"field",
+		`message Foo {
+  reserved 2;
+  string name = 1;
+}`,
+		`message Foo {
+  string name = 1;
+}`,
+	),
+	"RPC_NO_DELETE": newNoDeleteDoc(
+		"an RPC",
+		"service",
+		`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+  rpc ListFoos(ListFoosRequest) returns (ListFoosResponse);
+}`,
+		`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+		`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+  rpc ListFoos(ListFoosRequest) returns (ListFoosResponse) {
+    option deprecated = true;
+  }
+}`,
+	),
+	"RPC_SAME_CLIENT_STREAMING": newRPCSameStreamingDoc(
+		"client",
+		"rpc GetFoo(stream GetFooRequest) returns (GetFooResponse);",
+	),
+	"RPC_SAME_IDEMPOTENCY_LEVEL": {
+		Rationale: `The idempotency_level option tells clients and servers whether an RPC can be
+retried, or be called with HTTP GET. Changing it changes how existing clients call
+the RPC.`,
+		PreviousExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}`,
+	},
+	"RPC_SAME_OPTION_EXTENSIONS": newSameOptionExtensionsDoc(
+		"RPC",
+		"rpc_option_extensions",
+		`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+    option (google.api.http) = { get: "/v1/foos/{name}" };
+  }
+}`,
+		`service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+    option (google.api.http) = { post: "/v1/foos:get" body: "*" };
+  }
+}`,
+	),
+	"RPC_SAME_REQUEST_TYPE": newRPCSameTypeDoc(
+		"request",
+		"rpc GetFoo(GetFooRequest) returns (GetFooResponse);",
+		"rpc GetFoo(GetBarRequest) returns (GetFooResponse);",
+	),
+	"RPC_SAME_RESPONSE_TYPE": newRPCSameTypeDoc(
+		"response",
+		"rpc GetFoo(GetFooRequest) returns (GetFooResponse);",
+		"rpc GetFoo(GetFooRequest) returns (GetBarResponse);",
+	),
+	"RPC_SAME_SERVER_STREAMING": newRPCSameStreamingDoc(
+		"server",
+		"rpc GetFoo(GetFooRequest) returns (stream GetFooResponse);",
+	),
+	"SERVICE_NO_DELETE": newNoDeleteDoc(
+		"a service",
+		"file",
+		`// foo/v1/foo.proto
+service FooService {}
+service BarService {}`,
+		`// foo/v1/foo.proto
+service FooService {}`,
+		`// foo/v1/foo.proto
+service FooService {}
+// Deprecated: use FooService instead.
+service BarService {
+  option deprecated = true;
+}`,
+	),
+	"SERVICE_SAME_OPTION_EXTENSIONS": newSameOptionExtensionsDoc(
+		"service",
+		"service_option_extensions",
+		`service FooService {
+  option (google.api.default_host) = "foo.acme.com";
+}`,
+		`service FooService {
+  option (google.api.default_host) = "bar.acme.com";
+}`,
+	),
+}
+
+func newNoDeleteDoc(elementName string, parentName string, previousExample string, failingExample string, passingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Generated code refers to ` + elementName + ` by its name in the ` + parentName + `.
+Deleting it breaks code that uses it, and clients that still send or expect it.
+Deprecate it instead, and delete it once it is no longer used.`,
+		PreviousExample: previousExample,
+		FailingExample:  failingExample,
+		PassingExample:  passingExample,
+	}
+}
+
+func newPackageNoDeleteDoc(elementName string, previousExample string, movedExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Code that uses generated code refers to ` + elementName + ` by its
+fully-qualified name, which includes its package but not its file. Deleting it from
+the package breaks this code, while moving it to another file of the same package
+does not.`,
+		PreviousExample: previousExample,
+		FailingExample:  "// foo/v1/a.proto",
+		PassingExample:  movedExample,
+	}
+}
+
+func newReservedNoDeleteDoc(elementName string, childName string, previousExample string, failingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Reserved numbers and names prevent a deleted ` + childName + ` from being reused for a
+` + childName + ` with a different meaning, which would cause data written before the
+deletion to be read incorrectly. Deleting a reservation from the ` + elementName + ` allows
+this again.`,
+		PreviousExample: previousExample,
+		FailingExample:  failingExample,
+		PassingExample:  previousExample,
+	}
+}
+
+func newFieldSameOptionDoc(rationale string, previousOption string, failingOption string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale:       rationale,
+		PreviousExample: newFieldOptionExample(previousOption),
+		FailingExample:  newFieldOptionExample(failingOption),
+		PassingExample:  newFieldOptionExample(previousOption),
+	}
+}
+
+func newFieldOptionExample(option string) string {
+	if option != "" {
+		option = " " + option
+	}
+	return `message Foo {
+  string name = 1` + option + `;
+}`
+}
+
+func newFileSameOptionDoc(rationale string, previousOption string, failingOption string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale:       rationale,
+		PreviousExample: previousOption,
+		FailingExample:  failingOption,
+		PassingExample:  previousOption,
+	}
+}
+
+func newSameOptionExtensionsDoc(elementName string, configKey string, previousExample string, failingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Some custom options on a ` + elementName + ` are part of its API, for example
+those that code generators or API gateways read to generate clients or route
+requests. Changing them breaks these clients. The options are configured with
+` + configKey + `.`,
+		PreviousExample: previousExample,
+		FailingExample:  failingExample,
+		PassingExample:  previousExample,
+	}
+}
+
+func newRPCSameTypeDoc(typeName string, previousRPC string, failingRPC string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Clients and servers encode and decode the ` + typeName + ` of an RPC with its
+` + typeName + ` type. Changing the type means existing clients and servers no longer
+agree on the ` + typeName + `, and breaks code that calls or implements the RPC.`,
+		PreviousExample: newServiceExample(previousRPC),
+		FailingExample:  newServiceExample(failingRPC),
+		PassingExample:  newServiceExample(previousRPC),
+	}
+}
+
+func newRPCSameStreamingDoc(sideName string, streamingRPC string) *bufcheck.Doc {
+	unaryRPC := "rpc GetFoo(GetFooRequest) returns (GetFooResponse);"
+	return &bufcheck.Doc{
+		Rationale: `Streaming and unary RPCs are called differently, and have different signatures in
+generated code. Changing whether the ` + sideName + ` streams breaks existing clients and
+servers, and code that calls or implements the RPC.`,
+		PreviousExample: newServiceExample(unaryRPC),
+		FailingExample:  newServiceExample(streamingRPC),
+		PassingExample:  newServiceExample(unaryRPC),
+	}
+}
+
+func newServiceExample(rpc string) string {
+	return `service FooService {
+  ` + rpc + `
+}`
+}
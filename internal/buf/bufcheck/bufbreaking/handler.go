@@ -16,9 +16,11 @@ package bufbreaking
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
-	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking/internal"
+	bufcheckinternal "github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoreutil"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
@@ -27,7 +29,7 @@ import (
 
 type handler struct {
 	logger *zap.Logger
-	runner *internal.Runner
+	runner *bufcheckinternal.Runner
 }
 
 func newHandler(
@@ -35,7 +37,7 @@ func newHandler(
 ) *handler {
 	return &handler{
 		logger: logger,
-		runner: internal.NewRunner(logger, ""),
+		runner: bufcheckinternal.NewRunner(logger, ""),
 	}
 }
 
@@ -53,5 +55,35 @@ func (h *handler) Check(
 	if err != nil {
 		return nil, err
 	}
-	return h.runner.Check(ctx, configToInternalConfig(config), previousFiles, files)
+	if config.IgnoreUnstablePackages {
+		// filter both sides so that deleting an unstable package or file
+		// is not reported either
+		previousFiles = filterStableFiles(previousFiles, config.UnstablePackageRegexp)
+		files = filterStableFiles(files, config.UnstablePackageRegexp)
+	}
+	internalConfig := configToInternalConfig(config)
+	fileAnnotations, err := h.runner.Check(ctx, internalConfig, previousFiles, files)
+	if err != nil {
+		return nil, err
+	}
+	pluginFileAnnotations, err := h.runner.CheckPlugins(ctx, internalConfig, previousImage, image)
+	if err != nil {
+		return nil, err
+	}
+	if len(pluginFileAnnotations) == 0 {
+		return fileAnnotations, nil
+	}
+	fileAnnotations = append(fileAnnotations, pluginFileAnnotations...)
+	bufanalysis.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, nil
+}
+
+func filterStableFiles(files []protosource.File, unstablePackageRegexp *regexp.Regexp) []protosource.File {
+	stableFiles := make([]protosource.File, 0, len(files))
+	for _, file := range files {
+		if !internal.PackageIsUnstable(file.Package(), unstablePackageRegexp) {
+			stableFiles = append(stableFiles, file)
+		}
+	}
+	return stableFiles
 }
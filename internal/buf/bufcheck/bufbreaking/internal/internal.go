@@ -20,6 +20,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
@@ -310,6 +312,23 @@ func checkFieldSameOneof(add addFunc, previousField protosource.Field, field pro
 	return nil
 }
 
+// CheckFieldSameProto3Optional is a check function.
+var CheckFieldSameProto3Optional = newFieldPairCheckFunc(checkFieldSameProto3Optional)
+
+func checkFieldSameProto3Optional(add addFunc, previousField protosource.Field, field protosource.Field) error {
+	if previousField.Proto3Optional() != field.Proto3Optional() {
+		// otherwise prints as hex
+		numberString := strconv.FormatInt(int64(field.Number()), 10)
+		// TODO: specific label location
+		if field.Proto3Optional() {
+			add(field, field.Location(), `Field %q on message %q changed to be proto3 optional.`, numberString, field.Message().Name())
+		} else {
+			add(field, field.Location(), `Field %q on message %q changed to no longer be proto3 optional.`, numberString, field.Message().Name())
+		}
+	}
+	return nil
+}
+
 // CheckFieldSameType is a check function.
 var CheckFieldSameType = newFieldPairCheckFunc(checkFieldSameType)
 
@@ -360,6 +379,82 @@ func checkFieldSameType(add addFunc, previousField protosource.Field, field prot
 	return nil
 }
 
+// CheckFieldWireCompatibleType is a check function.
+var CheckFieldWireCompatibleType = newFieldPairCheckFunc(checkFieldWireCompatibleType)
+
+func checkFieldWireCompatibleType(add addFunc, previousField protosource.Field, field protosource.Field) error {
+	return checkFieldCompatibleType(
+		add,
+		previousField,
+		field,
+		fieldTypeToWireGroup,
+		false,
+		"See https://developers.google.com/protocol-buffers/docs/proto3#updating for wire compatibility rules.",
+	)
+}
+
+// CheckFieldWireJSONCompatibleType is a check function.
+var CheckFieldWireJSONCompatibleType = newFieldPairCheckFunc(checkFieldWireJSONCompatibleType)
+
+func checkFieldWireJSONCompatibleType(add addFunc, previousField protosource.Field, field protosource.Field) error {
+	return checkFieldCompatibleType(
+		add,
+		previousField,
+		field,
+		fieldTypeToWireJSONGroup,
+		true,
+		"See https://developers.google.com/protocol-buffers/docs/proto3#updating for wire compatibility rules and https://developers.google.com/protocol-buffers/docs/proto3#json for JSON compatibility rules.",
+	)
+}
+
+// checkFieldCompatibleType checks that the type of the field only changed
+// within its group in typeToGroup. Types not in typeToGroup cannot change.
+//
+// Messages and groups must have the same type name, and so must enums if
+// checkEnumTypeName is set.
+func checkFieldCompatibleType(
+	add addFunc,
+	previousField protosource.Field,
+	field protosource.Field,
+	typeToGroup map[protosource.FieldDescriptorProtoType]int,
+	checkEnumTypeName bool,
+	rulesMessage string,
+) error {
+	// otherwise prints as hex
+	numberString := strconv.FormatInt(int64(previousField.Number()), 10)
+	if previousField.Type() != field.Type() {
+		previousGroup, ok := typeToGroup[previousField.Type()]
+		if ok && previousGroup == typeToGroup[field.Type()] {
+			return nil
+		}
+		add(field, field.TypeLocation(), `Field %q on message %q changed type from %q to %q. %s`, numberString, field.Message().Name(), previousField.Type().String(), field.Type().String(), rulesMessage)
+		return nil
+	}
+
+	switch field.Type() {
+	case protosource.FieldDescriptorProtoTypeEnum:
+		if !checkEnumTypeName {
+			return nil
+		}
+	case protosource.FieldDescriptorProtoTypeGroup, protosource.FieldDescriptorProtoTypeMessage:
+	default:
+		return nil
+	}
+	if previousField.TypeName() != field.TypeName() {
+		add(
+			field,
+			field.TypeNameLocation(),
+			`Field %q on message %q changed type from %q to %q. %s`,
+			numberString,
+			field.Message().Name(),
+			strings.TrimPrefix(previousField.TypeName(), "."),
+			strings.TrimPrefix(field.TypeName(), "."),
+			rulesMessage,
+		)
+	}
+	return nil
+}
+
 // CheckFileNoDelete is a check function.
 var CheckFileNoDelete = newFilesCheckFunc(checkFileNoDelete)
 
@@ -517,7 +612,32 @@ func checkFileSameCcEnableArenas(add addFunc, previousFile protosource.File, fil
 var CheckFileSameSyntax = newFilePairCheckFunc(checkFileSameSyntax)
 
 func checkFileSameSyntax(add addFunc, previousFile protosource.File, file protosource.File) error {
-	return checkFileSameValue(add, previousFile.Syntax().String(), file.Syntax().String(), file, file.SyntaxLocation(), `syntax`)
+	if previousFile.Syntax() != protosource.SyntaxEditions && file.Syntax() != protosource.SyntaxEditions {
+		return checkFileSameValue(add, previousFile.Syntax().String(), file.Syntax().String(), file, file.SyntaxLocation(), `syntax`)
+	}
+	// with editions, the behavior of a file is determined by its features
+	// instead of its syntax, so the syntax or edition can change as long as
+	// the resolved file-level features stay the same
+	previousFeatures := previousFile.Features()
+	features := file.Features()
+	for i, feature := range features {
+		if i >= len(previousFeatures) {
+			break
+		}
+		if previousFeature := previousFeatures[i]; previousFeature.Value != feature.Value {
+			add(
+				file,
+				file.SyntaxLocation(),
+				`File changed from the %s to the %s, which changed feature %q from %q to %q.`,
+				getSyntaxOrEditionString(previousFile),
+				getSyntaxOrEditionString(file),
+				feature.Name,
+				previousFeature.Value,
+				feature.Value,
+			)
+		}
+	}
+	return nil
 }
 
 func checkFileSameValue(add addFunc, previousValue interface{}, value interface{}, file protosource.File, location protosource.Location, name string) error {
@@ -560,6 +680,23 @@ func checkMessageNoRemoveStandardDescriptorAccessor(add addFunc, previousMessage
 	return nil
 }
 
+// CheckMessageSameMapEntry is a check function.
+var CheckMessageSameMapEntry = newMessagePairCheckFunc(checkMessageSameMapEntry)
+
+func checkMessageSameMapEntry(add addFunc, previousMessage protosource.Message, message protosource.Message) error {
+	previous := strconv.FormatBool(previousMessage.IsMapEntry())
+	current := strconv.FormatBool(message.IsMapEntry())
+	if previous != current {
+		// map entries are synthesized by the compiler and have no meaningful location
+		var location protosource.Location
+		if !message.IsMapEntry() {
+			location = message.Location()
+		}
+		add(message, location, `Message option "map_entry" changed from %q to %q.`, previous, current)
+	}
+	return nil
+}
+
 // CheckMessageSameMessageSetWireFormat is a check function.
 var CheckMessageSameMessageSetWireFormat = newMessagePairCheckFunc(checkMessageSameMessageSetWireFormat)
 
@@ -572,6 +709,30 @@ func checkMessageSameMessageSetWireFormat(add addFunc, previousMessage protosour
 	return nil
 }
 
+// CheckMessageSameOptionExtensions is a check function.
+var CheckMessageSameOptionExtensions = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	previousFiles []protosource.File,
+	files []protosource.File,
+	optionExtensions []OptionExtension,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMessagePairCheckFunc(
+		func(add addFunc, previousMessage protosource.Message, message protosource.Message) error {
+			return checkMessageSameOptionExtensions(add, previousMessage, message, optionExtensions)
+		},
+	)(id, ignoreFunc, previousFiles, files)
+}
+
+func checkMessageSameOptionExtensions(add addFunc, previousMessage protosource.Message, message protosource.Message, optionExtensions []OptionExtension) error {
+	for _, optionExtension := range optionExtensions {
+		if !optionExtensionIsSame(previousMessage, message, optionExtension.Number) {
+			add(message, getOptionExtensionLocation(message, optionExtension.Number), `Message %q changed option %q.`, message.Name(), optionExtension.Name)
+		}
+	}
+	return nil
+}
+
 // CheckOneofNoDelete is a check function.
 var CheckOneofNoDelete = newMessagePairCheckFunc(checkOneofNoDelete)
 
@@ -831,6 +992,30 @@ func checkRPCSameIdempotencyLevel(add addFunc, previousMethod protosource.Method
 	return nil
 }
 
+// CheckRPCSameOptionExtensions is a check function.
+var CheckRPCSameOptionExtensions = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	previousFiles []protosource.File,
+	files []protosource.File,
+	optionExtensions []OptionExtension,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMethodPairCheckFunc(
+		func(add addFunc, previousMethod protosource.Method, method protosource.Method) error {
+			return checkRPCSameOptionExtensions(add, previousMethod, method, optionExtensions)
+		},
+	)(id, ignoreFunc, previousFiles, files)
+}
+
+func checkRPCSameOptionExtensions(add addFunc, previousMethod protosource.Method, method protosource.Method, optionExtensions []OptionExtension) error {
+	for _, optionExtension := range optionExtensions {
+		if !optionExtensionIsSame(previousMethod, method, optionExtension.Number) {
+			add(method, getOptionExtensionLocation(method, optionExtension.Number), `RPC %q on service %q changed option %q.`, method.Name(), method.Service().Name(), optionExtension.Name)
+		}
+	}
+	return nil
+}
+
 // CheckRPCSameRequestType is a check function.
 var CheckRPCSameRequestType = newMethodPairCheckFunc(checkRPCSameRequestType)
 
@@ -886,3 +1071,27 @@ func checkServiceNoDelete(add addFunc, previousFile protosource.File, file proto
 	}
 	return nil
 }
+
+// CheckServiceSameOptionExtensions is a check function.
+var CheckServiceSameOptionExtensions = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	previousFiles []protosource.File,
+	files []protosource.File,
+	optionExtensions []OptionExtension,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newServicePairCheckFunc(
+		func(add addFunc, previousService protosource.Service, service protosource.Service) error {
+			return checkServiceSameOptionExtensions(add, previousService, service, optionExtensions)
+		},
+	)(id, ignoreFunc, previousFiles, files)
+}
+
+func checkServiceSameOptionExtensions(add addFunc, previousService protosource.Service, service protosource.Service, optionExtensions []OptionExtension) error {
+	for _, optionExtension := range optionExtensions {
+		if !optionExtensionIsSame(previousService, service, optionExtension.Number) {
+			add(service, getOptionExtensionLocation(service, optionExtension.Number), `Service %q changed option %q.`, service.Name(), optionExtension.Name)
+		}
+	}
+	return nil
+}
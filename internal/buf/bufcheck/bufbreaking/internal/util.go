@@ -15,14 +15,122 @@
 package internal
 
 import (
+	"bytes"
+	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
 
+var (
+	// MessageOptionExtensionNameToNumber maps the names of well-known message
+	// option extensions to their field numbers.
+	MessageOptionExtensionNameToNumber = map[string]int32{
+		"google.api.resource": 1053,
+	}
+	// ServiceOptionExtensionNameToNumber maps the names of well-known service
+	// option extensions to their field numbers.
+	ServiceOptionExtensionNameToNumber = map[string]int32{
+		"google.api.default_host": 1049,
+		"google.api.oauth_scopes": 1050,
+	}
+	// RPCOptionExtensionNameToNumber maps the names of well-known method option
+	// extensions to their field numbers.
+	RPCOptionExtensionNameToNumber = map[string]int32{
+		"google.api.http":             72295728,
+		"google.api.method_signature": 1051,
+		"google.api.routing":          72295729,
+	}
+)
+
+// unstablePackageVersionRegexp matches the alpha, beta, and test package
+// versions, such as v1alpha1, v1p1beta1, and v1test.
+var unstablePackageVersionRegexp = regexp.MustCompile(`^v[1-9][0-9]*(p[1-9][0-9]*)?(alpha|beta)[1-9][0-9]*$|^v[1-9][0-9]*test`)
+
+// PackageIsUnstable returns true if the last component of the package is an
+// alpha, beta, or test version, or if the package matches the pattern.
+//
+// The pattern can be nil.
+func PackageIsUnstable(pkg string, pattern *regexp.Regexp) bool {
+	if pattern != nil && pattern.MatchString(pkg) {
+		return true
+	}
+	index := strings.LastIndexByte(pkg, '.')
+	if index < 0 {
+		return false
+	}
+	return unstablePackageVersionRegexp.MatchString(pkg[index+1:])
+}
+
+var (
+	// fieldTypeToWireGroup groups the field types that have the same
+	// encoding on the wire.
+	fieldTypeToWireGroup = map[protosource.FieldDescriptorProtoType]int{
+		protosource.FieldDescriptorProtoTypeInt32:    1,
+		protosource.FieldDescriptorProtoTypeUint32:   1,
+		protosource.FieldDescriptorProtoTypeInt64:    1,
+		protosource.FieldDescriptorProtoTypeUint64:   1,
+		protosource.FieldDescriptorProtoTypeBool:     1,
+		protosource.FieldDescriptorProtoTypeEnum:     1,
+		protosource.FieldDescriptorProtoTypeSint32:   2,
+		protosource.FieldDescriptorProtoTypeSint64:   2,
+		protosource.FieldDescriptorProtoTypeFixed32:  3,
+		protosource.FieldDescriptorProtoTypeSfixed32: 3,
+		protosource.FieldDescriptorProtoTypeFixed64:  4,
+		protosource.FieldDescriptorProtoTypeSfixed64: 4,
+		protosource.FieldDescriptorProtoTypeString:   5,
+		protosource.FieldDescriptorProtoTypeBytes:    5,
+	}
+	// fieldTypeToWireJSONGroup groups the field types that have the same
+	// encoding on the wire and in JSON.
+	//
+	// 64-bit integers are strings in JSON while 32-bit integers are numbers,
+	// bytes are base64-encoded, and enums are their value names.
+	fieldTypeToWireJSONGroup = map[protosource.FieldDescriptorProtoType]int{
+		protosource.FieldDescriptorProtoTypeInt32:    1,
+		protosource.FieldDescriptorProtoTypeUint32:   1,
+		protosource.FieldDescriptorProtoTypeInt64:    2,
+		protosource.FieldDescriptorProtoTypeUint64:   2,
+		protosource.FieldDescriptorProtoTypeFixed32:  3,
+		protosource.FieldDescriptorProtoTypeSfixed32: 3,
+		protosource.FieldDescriptorProtoTypeFixed64:  4,
+		protosource.FieldDescriptorProtoTypeSfixed64: 4,
+	}
+)
+
+// OptionExtension is an option extension to check.
+type OptionExtension struct {
+	// Name is the name used in messages.
+	Name string
+	// Number is the field number of the extension.
+	Number int32
+}
+
+// NewOptionExtensions returns new OptionExtensions for the values.
+//
+// Each value is either a name in nameToNumber or a field number.
+func NewOptionExtensions(values []string, nameToNumber map[string]int32) ([]OptionExtension, error) {
+	optionExtensions := make([]OptionExtension, 0, len(values))
+	for _, value := range values {
+		if number, ok := nameToNumber[value]; ok {
+			optionExtensions = append(optionExtensions, OptionExtension{Name: value, Number: number})
+			continue
+		}
+		number, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || number < 1 {
+			return nil, fmt.Errorf("%q is not a known option or a positive field number, known options are %s", value, stringutil.SliceToString(getSortedOptionExtensionNames(nameToNumber)))
+		}
+		optionExtensions = append(optionExtensions, OptionExtension{Name: value, Number: int32(number)})
+	}
+	return optionExtensions, nil
+}
+
 // addFunc adds a FileAnnotation.
 //
 // Both the Descriptor and Location can be nil.
@@ -260,3 +368,39 @@ func withBackupLocation(primary protosource.Location, secondary protosource.Loca
 	}
 	return secondary
 }
+
+func optionExtensionIsSame(previous protosource.OptionExtensionDescriptor, current protosource.OptionExtensionDescriptor, fieldNumber int32) bool {
+	previousValue, previousOK := previous.OptionExtension(fieldNumber)
+	value, ok := current.OptionExtension(fieldNumber)
+	return previousOK == ok && bytes.Equal(previousValue, value)
+}
+
+// getOptionExtensionLocation returns the location of the option extension, or the
+// location of the descriptor if the option extension was removed.
+func getOptionExtensionLocation(namedDescriptor interface {
+	protosource.NamedDescriptor
+	protosource.OptionExtensionDescriptor
+}, fieldNumber int32) protosource.Location {
+	if location := namedDescriptor.OptionExtensionLocation(fieldNumber); location != nil {
+		return location
+	}
+	return namedDescriptor.NameLocation()
+}
+
+func getSortedOptionExtensionNames(nameToNumber map[string]int32) []string {
+	names := make([]string, 0, len(nameToNumber))
+	for name := range nameToNumber {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getSyntaxOrEditionString returns the syntax or edition of the file for
+// messages, such as syntax "proto3" or edition "2023".
+func getSyntaxOrEditionString(file protosource.File) string {
+	if file.Syntax() == protosource.SyntaxEditions {
+		return strconv.Quote(file.Edition()) + " edition"
+	}
+	return strconv.Quote(file.Syntax().String()) + " syntax"
+}
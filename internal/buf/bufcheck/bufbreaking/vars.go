@@ -15,8 +15,13 @@
 package bufbreaking
 
 import (
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking/internal"
 	bufcheckinternal "github.com/bufbuild/buf/internal/buf/bufcheck/internal"
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
 
 var (
@@ -37,7 +42,10 @@ var (
 		v1FieldSameLabelCheckerBuilder,
 		v1FieldSameNameCheckerBuilder,
 		v1FieldSameOneofCheckerBuilder,
+		v1FieldSameProto3OptionalCheckerBuilder,
 		v1FieldSameTypeCheckerBuilder,
+		v1FieldWireCompatibleTypeCheckerBuilder,
+		v1FieldWireJSONCompatibleTypeCheckerBuilder,
 		v1FileNoDeleteCheckerBuilder,
 		v1FileSameCsharpNamespaceCheckerBuilder,
 		v1FileSameGoPackageCheckerBuilder,
@@ -61,7 +69,9 @@ var (
 		v1FileSameSyntaxCheckerBuilder,
 		v1MessageNoDeleteCheckerBuilder,
 		v1MessageNoRemoveStandardDescriptorAccessorCheckerBuilder,
+		v1MessageSameMapEntryCheckerBuilder,
 		v1MessageSameMessageSetWireFormatCheckerBuilder,
+		v1MessageSameOptionExtensionsCheckerBuilder,
 		v1OneofNoDeleteCheckerBuilder,
 		v1PackageEnumNoDeleteCheckerBuilder,
 		v1PackageMessageNoDeleteCheckerBuilder,
@@ -72,10 +82,12 @@ var (
 		v1RPCNoDeleteCheckerBuilder,
 		v1RPCSameClientStreamingCheckerBuilder,
 		v1RPCSameIdempotencyLevelCheckerBuilder,
+		v1RPCSameOptionExtensionsCheckerBuilder,
 		v1RPCSameRequestTypeCheckerBuilder,
 		v1RPCSameResponseTypeCheckerBuilder,
 		v1RPCSameServerStreamingCheckerBuilder,
 		v1ServiceNoDeleteCheckerBuilder,
+		v1ServiceSameOptionExtensionsCheckerBuilder,
 	}
 
 	// v1DefaultCategories are the default categories.
@@ -155,12 +167,20 @@ var (
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"FIELD_SAME_PROTO3_OPTIONAL": {
+			"FILE",
+			"PACKAGE",
+		},
 		"FIELD_SAME_TYPE": {
 			"FILE",
 			"PACKAGE",
-			"WIRE_JSON",
+		},
+		"FIELD_WIRE_COMPATIBLE_TYPE": {
 			"WIRE",
 		},
+		"FIELD_WIRE_JSON_COMPATIBLE_TYPE": {
+			"WIRE_JSON",
+		},
 		"FILE_NO_DELETE": {
 			"FILE",
 		},
@@ -250,12 +270,21 @@ var (
 			"FILE",
 			"PACKAGE",
 		},
+		"MESSAGE_SAME_MAP_ENTRY": {
+			"FILE",
+			"PACKAGE",
+			"WIRE_JSON",
+		},
 		"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT": {
 			"FILE",
 			"PACKAGE",
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"MESSAGE_SAME_OPTION_EXTENSIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"ONEOF_NO_DELETE": {
 			"FILE",
 			"PACKAGE",
@@ -300,6 +329,10 @@ var (
 			"WIRE_JSON",
 			"WIRE",
 		},
+		"RPC_SAME_OPTION_EXTENSIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 		"RPC_SAME_REQUEST_TYPE": {
 			"FILE",
 			"PACKAGE",
@@ -321,6 +354,10 @@ var (
 		"SERVICE_NO_DELETE": {
 			"FILE",
 		},
+		"SERVICE_SAME_OPTION_EXTENSIONS": {
+			"FILE",
+			"PACKAGE",
+		},
 	}
 
 	v1EnumNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
@@ -398,11 +435,26 @@ var (
 		"fields have the same oneofs in a given message",
 		internal.CheckFieldSameOneof,
 	)
+	v1FieldSameProto3OptionalCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_SAME_PROTO3_OPTIONAL",
+		"fields are either proto3 optional or not in a given message",
+		internal.CheckFieldSameProto3Optional,
+	)
 	v1FieldSameTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FIELD_SAME_TYPE",
 		"fields have the same types in a given message",
 		internal.CheckFieldSameType,
 	)
+	v1FieldWireCompatibleTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_WIRE_COMPATIBLE_TYPE",
+		"fields only change types in ways that are compatible on the wire",
+		internal.CheckFieldWireCompatibleType,
+	)
+	v1FieldWireJSONCompatibleTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_WIRE_JSON_COMPATIBLE_TYPE",
+		"fields only change types in ways that are compatible on the wire and in JSON",
+		internal.CheckFieldWireJSONCompatibleType,
+	)
 	v1FileNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FILE_NO_DELETE",
 		"files are not deleted",
@@ -518,11 +570,35 @@ var (
 		"messages do not change the no_standard_descriptor_accessor option from false or unset to true",
 		internal.CheckMessageNoRemoveStandardDescriptorAccessor,
 	)
+	v1MessageSameMapEntryCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"MESSAGE_SAME_MAP_ENTRY",
+		"messages have the same value for the map_entry option",
+		internal.CheckMessageSameMapEntry,
+	)
 	v1MessageSameMessageSetWireFormatCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT",
 		"messages have the same value for the message_set_wire_format option",
 		internal.CheckMessageSameMessageSetWireFormat,
 	)
+	v1MessageSameOptionExtensionsCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"MESSAGE_SAME_OPTION_EXTENSIONS",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if _, err := internal.NewOptionExtensions(configBuilder.MessageSameOptionExtensions, internal.MessageOptionExtensionNameToNumber); err != nil {
+				return "", fmt.Errorf("message_same_option_extensions: %v", err)
+			}
+			return "messages have the same values for the options " + stringutil.SliceToString(configBuilder.MessageSameOptionExtensions) + " (options are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			optionExtensions, err := internal.NewOptionExtensions(configBuilder.MessageSameOptionExtensions, internal.MessageOptionExtensionNameToNumber)
+			if err != nil {
+				return nil, fmt.Errorf("message_same_option_extensions: %v", err)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckMessageSameOptionExtensions(id, ignoreFunc, previousFiles, files, optionExtensions)
+			}), nil
+		},
+		"message_same_option_extensions",
+	)
 	v1OneofNoDeleteCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"ONEOF_NO_DELETE",
 		"oneofs are not deleted from a given message",
@@ -573,6 +649,25 @@ var (
 		"rpcs have the same value for the idempotency_level option",
 		internal.CheckRPCSameIdempotencyLevel,
 	)
+	v1RPCSameOptionExtensionsCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"RPC_SAME_OPTION_EXTENSIONS",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if _, err := internal.NewOptionExtensions(configBuilder.RPCSameOptionExtensions, internal.RPCOptionExtensionNameToNumber); err != nil {
+				return "", fmt.Errorf("rpc_same_option_extensions: %v", err)
+			}
+			return "rpcs have the same values for the options " + stringutil.SliceToString(configBuilder.RPCSameOptionExtensions) + " (options are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			optionExtensions, err := internal.NewOptionExtensions(configBuilder.RPCSameOptionExtensions, internal.RPCOptionExtensionNameToNumber)
+			if err != nil {
+				return nil, fmt.Errorf("rpc_same_option_extensions: %v", err)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckRPCSameOptionExtensions(id, ignoreFunc, previousFiles, files, optionExtensions)
+			}), nil
+		},
+		"rpc_same_option_extensions",
+	)
 	v1RPCSameRequestTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_SAME_REQUEST_TYPE",
 		"rpcs are have the same request type",
@@ -593,4 +688,23 @@ var (
 		"services are not deleted from a given file",
 		internal.CheckServiceNoDelete,
 	)
+	v1ServiceSameOptionExtensionsCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"SERVICE_SAME_OPTION_EXTENSIONS",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if _, err := internal.NewOptionExtensions(configBuilder.ServiceSameOptionExtensions, internal.ServiceOptionExtensionNameToNumber); err != nil {
+				return "", fmt.Errorf("service_same_option_extensions: %v", err)
+			}
+			return "services have the same values for the options " + stringutil.SliceToString(configBuilder.ServiceSameOptionExtensions) + " (options are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			optionExtensions, err := internal.NewOptionExtensions(configBuilder.ServiceSameOptionExtensions, internal.ServiceOptionExtensionNameToNumber)
+			if err != nil {
+				return nil, fmt.Errorf("service_same_option_extensions: %v", err)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckServiceSameOptionExtensions(id, ignoreFunc, previousFiles, files, optionExtensions)
+			}), nil
+		},
+		"service_same_option_extensions",
+	)
 )
@@ -21,6 +21,7 @@ package bufcheck
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -29,6 +30,8 @@ import (
 	"go.uber.org/multierr"
 )
 
+const pluginBinaryPrefix = "buf-check-plugin-"
+
 // AllCheckerFormatStrings is all checker format strings.
 var AllCheckerFormatStrings = []string{
 	"text",
@@ -52,45 +55,100 @@ type Checker interface {
 	//
 	// Full sentence.
 	Purpose() string
+	// IsDefault returns true if the Checker is used when no checkers or
+	// categories are configured.
+	IsDefault() bool
+	// IsFixable returns true if the FileAnnotations of the Checker have edits
+	// that fix them, as applied by buf check lint --fix.
+	IsFixable() bool
+	// ConfigKeys returns the config keys that configure the Checker.
+	//
+	// lower_snake_case.
+	// Sorted.
+	ConfigKeys() []string
+}
+
+// Doc is the documentation for a checker.
+type Doc struct {
+	// Rationale is why the checker exists.
+	Rationale string
+	// PreviousExample is Protobuf source that FailingExample and PassingExample
+	// are compared against.
+	//
+	// Only set for breaking checkers.
+	PreviousExample string
+	// FailingExample is Protobuf source that the checker fails on.
+	FailingExample string
+	// PassingExample is Protobuf source that the checker passes on.
+	PassingExample string
+}
+
+// PrintOption is an option for PrintCheckers and PrintRules.
+type PrintOption func(*printOptions)
+
+// PrintWithDocs returns a new PrintOption that also prints the Doc of
+// each checker, as returned by getDoc.
+//
+// In text format, each checker is printed as a paragraph instead of as a
+// row of a table.
+func PrintWithDocs(getDoc func(id string) (*Doc, bool)) PrintOption {
+	return func(printOptions *printOptions) {
+		printOptions.getDoc = getDoc
+	}
 }
 
 // PrintCheckers prints the checkers to the writer.
 //
 // The empty string defaults to text.
-func PrintCheckers(writer io.Writer, checkers []Checker, formatString string) (retErr error) {
+func PrintCheckers(writer io.Writer, checkers []Checker, formatString string, options ...PrintOption) (retErr error) {
 	if len(checkers) == 0 {
 		return nil
 	}
-	asJSON := false
-	switch s := strings.ToLower(strings.TrimSpace(formatString)); s {
-	case "", "text":
-		asJSON = false
-	case "json":
-		asJSON = true
-	default:
-		return fmt.Errorf("unknown format: %q", s)
+	printOptions := newPrintOptions()
+	for _, option := range options {
+		option(printOptions)
 	}
-	if !asJSON {
+	asJSON, err := parseFormatString(formatString)
+	if err != nil {
+		return err
+	}
+	if !asJSON && printOptions.getDoc == nil {
 		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
 		defer func() {
 			retErr = multierr.Append(retErr, tabWriter.Flush())
 		}()
 		writer = tabWriter
-		if _, err := fmt.Fprintln(writer, "ID\tCATEGORIES\tPURPOSE"); err != nil {
+		if _, err := fmt.Fprintln(writer, "ID\tCATEGORIES\tFIXABLE\tPURPOSE"); err != nil {
 			return err
 		}
 	}
-	for _, checker := range checkers {
-		if err := printChecker(writer, checker, asJSON); err != nil {
+	for i, checker := range checkers {
+		if err := printChecker(writer, checker, asJSON, printOptions.getDoc, i == 0); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func printChecker(writer io.Writer, checker Checker, asJSON bool) error {
+func printChecker(writer io.Writer, checker Checker, asJSON bool, getDoc func(string) (*Doc, bool), first bool) error {
 	if asJSON {
-		data, err := json.Marshal(checker)
+		var data []byte
+		var err error
+		if getDoc == nil {
+			data, err = json.Marshal(checker)
+		} else {
+			data, err = json.Marshal(
+				checkerDocJSON{
+					ID:         checker.ID(),
+					Categories: checker.Categories(),
+					Purpose:    checker.Purpose(),
+					Default:    checker.IsDefault(),
+					Fixable:    checker.IsFixable(),
+					ConfigKeys: checker.ConfigKeys(),
+					docJSON:    newDocJSON(checker.ID(), getDoc),
+				},
+			)
+		}
 		if err != nil {
 			return err
 		}
@@ -99,8 +157,269 @@ func printChecker(writer io.Writer, checker Checker, asJSON bool) error {
 		}
 		return nil
 	}
-	if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\n", checker.ID(), strings.Join(checker.Categories(), ", "), checker.Purpose()); err != nil {
+	if getDoc != nil {
+		return printCheckerDoc(writer, "", checker, getDoc, first)
+	}
+	if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", checker.ID(), strings.Join(checker.Categories(), ", "), fixableString(checker), checker.Purpose()); err != nil {
 		return err
 	}
 	return nil
 }
+
+// RuleSet is a set of checkers of the same type.
+type RuleSet struct {
+	// Type is the type of the checkers, such as lint or breaking.
+	Type string
+	// ConfigVersions are the config versions that the checkers are available in.
+	ConfigVersions []string
+	// Checkers are the checkers.
+	Checkers []Checker
+}
+
+// PrintRules prints the checkers of the rule sets to the writer, along with
+// the type and config versions of each checker.
+//
+// The empty string defaults to text.
+func PrintRules(writer io.Writer, ruleSets []RuleSet, formatString string, options ...PrintOption) (retErr error) {
+	printOptions := newPrintOptions()
+	for _, option := range options {
+		option(printOptions)
+	}
+	asJSON, err := parseFormatString(formatString)
+	if err != nil {
+		return err
+	}
+	if !asJSON && printOptions.getDoc == nil {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "TYPE\tID\tCATEGORIES\tFIXABLE\tPURPOSE"); err != nil {
+			return err
+		}
+	}
+	first := true
+	for _, ruleSet := range ruleSets {
+		for _, checker := range ruleSet.Checkers {
+			if err := printRule(writer, ruleSet, checker, asJSON, printOptions.getDoc, first); err != nil {
+				return err
+			}
+			first = false
+		}
+	}
+	return nil
+}
+
+func printRule(writer io.Writer, ruleSet RuleSet, checker Checker, asJSON bool, getDoc func(string) (*Doc, bool), first bool) error {
+	if asJSON {
+		data, err := json.Marshal(
+			ruleJSON{
+				Type:           ruleSet.Type,
+				ID:             checker.ID(),
+				Categories:     checker.Categories(),
+				Purpose:        checker.Purpose(),
+				Default:        checker.IsDefault(),
+				Fixable:        checker.IsFixable(),
+				ConfigKeys:     checker.ConfigKeys(),
+				ConfigVersions: ruleSet.ConfigVersions,
+				docJSON:        newDocJSON(checker.ID(), getDoc),
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+			return err
+		}
+		return nil
+	}
+	if getDoc != nil {
+		return printCheckerDoc(writer, ruleSet.Type, checker, getDoc, first)
+	}
+	if _, err := fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", ruleSet.Type, checker.ID(), strings.Join(checker.Categories(), ", "), fixableString(checker), checker.Purpose()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// printCheckerDoc prints the checker as a paragraph, separated from the
+// previous checker by a blank line unless first is true.
+//
+// The type is omitted if empty.
+func printCheckerDoc(writer io.Writer, checkerType string, checker Checker, getDoc func(string) (*Doc, bool), first bool) error {
+	if !first {
+		if _, err := fmt.Fprintln(writer); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(writer, "%s\n\n%s\n\n", checker.ID(), checker.Purpose()); err != nil {
+		return err
+	}
+	if checkerType != "" {
+		if _, err := fmt.Fprintf(writer, "Type: %s\n", checkerType); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(writer, "Categories: %s\n", strings.Join(checker.Categories(), ", ")); err != nil {
+		return err
+	}
+	if checker.IsFixable() {
+		if _, err := fmt.Fprintln(writer, "Fixable: yes"); err != nil {
+			return err
+		}
+	}
+	doc, ok := getDoc(checker.ID())
+	if !ok {
+		return nil
+	}
+	if _, err := fmt.Fprintf(writer, "\n%s\n", doc.Rationale); err != nil {
+		return err
+	}
+	if doc.PreviousExample != "" {
+		if _, err := fmt.Fprintf(writer, "\nPrevious example:\n\n%s\n", indent(doc.PreviousExample)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(
+		writer,
+		"\nFailing example:\n\n%s\n\nPassing example:\n\n%s\n",
+		indent(doc.FailingExample),
+		indent(doc.PassingExample),
+	)
+	return err
+}
+
+func fixableString(checker Checker) string {
+	if checker.IsFixable() {
+		return "yes"
+	}
+	return "no"
+}
+
+func parseFormatString(formatString string) (bool, error) {
+	switch s := strings.ToLower(strings.TrimSpace(formatString)); s {
+	case "", "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown format: %q", s)
+	}
+}
+
+func indent(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type printOptions struct {
+	getDoc func(string) (*Doc, bool)
+}
+
+func newPrintOptions() *printOptions {
+	return &printOptions{}
+}
+
+type checkerDocJSON struct {
+	ID         string   `json:"id" yaml:"id"`
+	Categories []string `json:"categories" yaml:"categories"`
+	Purpose    string   `json:"purpose" yaml:"purpose"`
+	Default    bool     `json:"default" yaml:"default"`
+	Fixable    bool     `json:"fixable" yaml:"fixable"`
+	ConfigKeys []string `json:"config_keys,omitempty" yaml:"config_keys,omitempty"`
+	docJSON
+}
+
+type ruleJSON struct {
+	Type           string   `json:"type" yaml:"type"`
+	ID             string   `json:"id" yaml:"id"`
+	Categories     []string `json:"categories" yaml:"categories"`
+	Purpose        string   `json:"purpose" yaml:"purpose"`
+	Default        bool     `json:"default" yaml:"default"`
+	Fixable        bool     `json:"fixable" yaml:"fixable"`
+	ConfigKeys     []string `json:"config_keys,omitempty" yaml:"config_keys,omitempty"`
+	ConfigVersions []string `json:"config_versions" yaml:"config_versions"`
+	docJSON
+}
+
+type docJSON struct {
+	Explanation     string `json:"explanation,omitempty" yaml:"explanation,omitempty"`
+	PreviousExample string `json:"previous_example,omitempty" yaml:"previous_example,omitempty"`
+	FailingExample  string `json:"failing_example,omitempty" yaml:"failing_example,omitempty"`
+	PassingExample  string `json:"passing_example,omitempty" yaml:"passing_example,omitempty"`
+}
+
+func newDocJSON(id string, getDoc func(string) (*Doc, bool)) docJSON {
+	if getDoc == nil {
+		return docJSON{}
+	}
+	doc, ok := getDoc(id)
+	if !ok {
+		return docJSON{}
+	}
+	return docJSON{
+		Explanation:     doc.Rationale,
+		PreviousExample: doc.PreviousExample,
+		FailingExample:  doc.FailingExample,
+		PassingExample:  doc.PassingExample,
+	}
+}
+
+// ExternalPluginConfig is an external config for a check plugin.
+type ExternalPluginConfig struct {
+	// Name is the name of the plugin.
+	//
+	// This field must be set.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Path is the path to the plugin binary.
+	//
+	// If not set, this defaults to buf-check-plugin-NAME on the PATH.
+	Path      string `json:"path,omitempty" yaml:"path,omitempty"`
+	Parameter string `json:"parameter,omitempty" yaml:"parameter,omitempty"`
+}
+
+// Plugin is a check plugin.
+//
+// Check plugins are executables that read a bufbuild.buf.check.v1.CheckRequest
+// from stdin, and write a bufbuild.buf.check.v1.CheckResponse to stdout,
+// analogous to protoc plugins.
+type Plugin struct {
+	Name      string
+	Path      string
+	Parameter string
+}
+
+// NewPlugins returns new Plugins for the ExternalPluginConfigs.
+func NewPlugins(externalPluginConfigs []ExternalPluginConfig) ([]*Plugin, error) {
+	if len(externalPluginConfigs) == 0 {
+		return nil, nil
+	}
+	names := make(map[string]struct{}, len(externalPluginConfigs))
+	plugins := make([]*Plugin, len(externalPluginConfigs))
+	for i, externalPluginConfig := range externalPluginConfigs {
+		name := strings.TrimSpace(externalPluginConfig.Name)
+		if name == "" {
+			return nil, errors.New("plugin name is required")
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("duplicate plugin name: %q", name)
+		}
+		names[name] = struct{}{}
+		path := strings.TrimSpace(externalPluginConfig.Path)
+		if path == "" {
+			path = pluginBinaryPrefix + name
+		}
+		plugins[i] = &Plugin{
+			Name:      name,
+			Path:      path,
+			Parameter: externalPluginConfig.Parameter,
+		}
+	}
+	return plugins, nil
+}
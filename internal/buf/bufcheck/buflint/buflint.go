@@ -37,6 +37,12 @@ var AllFormatStrings = append(
 	"config-ignore-yaml",
 )
 
+// AllConfigVersions are the config versions that the checkers returned by
+// GetAllCheckers are available in.
+var AllConfigVersions = []string{
+	"v1",
+}
+
 // Handler handles the main lint functionality.
 type Handler interface {
 	// Check runs the lint checks.
@@ -71,8 +77,18 @@ type Config struct {
 	// created from this package, i.e. created wth ConfigBuilder.NewConfig.
 	Checkers            []Checker
 	IgnoreIDToRootPaths map[string]map[string]struct{}
+	IgnoreIDToSymbols   map[string]map[string]struct{}
 	IgnoreRootPaths     map[string]struct{}
+	// IgnoreRootPathGlobs and IgnoreIDToRootPathGlobs are the ignore paths
+	// that are glob patterns.
+	IgnoreRootPathGlobs     []string
+	IgnoreIDToRootPathGlobs map[string][]string
+	// WarnIDs are the IDs of the checkers whose failures are warnings, see
+	// SplitWarnings.
+	WarnIDs             map[string]struct{}
 	AllowCommentIgnores bool
+	// Plugins are the check plugins to run in addition to the Checkers.
+	Plugins []*bufcheck.Plugin
 }
 
 // GetCheckers returns the checkers for the given categories.
@@ -86,17 +102,41 @@ func (c *Config) GetCheckers(categories ...string) ([]bufcheck.Checker, error) {
 
 // NewConfig returns a new Config.
 func NewConfig(externalConfig ExternalConfig) (*Config, error) {
+	importRules := make([]internal.ImportRule, len(externalConfig.ImportRules))
+	for i, externalImportRule := range externalConfig.ImportRules {
+		importRules[i] = internal.ImportRule{
+			From:  externalImportRule.From,
+			Allow: externalImportRule.Allow,
+			Deny:  externalImportRule.Deny,
+		}
+	}
 	internalConfig, err := internal.ConfigBuilder{
 		Use:                                  externalConfig.Use,
 		Except:                               externalConfig.Except,
+		Categories:                           externalConfig.Categories,
 		IgnoreRootPaths:                      externalConfig.Ignore,
 		IgnoreIDOrCategoryToRootPaths:        externalConfig.IgnoreOnly,
+		IgnoreIDOrCategoryToSymbols:          externalConfig.IgnoreSymbols,
+		Warn:                                 externalConfig.Warn,
 		AllowCommentIgnores:                  externalConfig.AllowCommentIgnores,
 		EnumZeroValueSuffix:                  externalConfig.EnumZeroValueSuffix,
 		RPCAllowSameRequestResponse:          externalConfig.RPCAllowSameRequestResponse,
 		RPCAllowGoogleProtobufEmptyRequests:  externalConfig.RPCAllowGoogleProtobufEmptyRequests,
 		RPCAllowGoogleProtobufEmptyResponses: externalConfig.RPCAllowGoogleProtobufEmptyResponses,
 		ServiceSuffix:                        externalConfig.ServiceSuffix,
+		MessageNamePattern:                   externalConfig.MessageNamePattern,
+		FieldNamePattern:                     externalConfig.FieldNamePattern,
+		ServiceNamePattern:                   externalConfig.ServiceNamePattern,
+		RPCVerbs:                             externalConfig.RPCVerbs,
+		PackageVersionSuffixForms:            externalConfig.PackageVersionSuffixForms,
+		PackageVersionSuffixAllowUnversioned: externalConfig.PackageVersionSuffixAllowUnversioned,
+		MessageMaxNestingDepth:               externalConfig.MessageMaxNestingDepth,
+		MessageMaxFields:                     externalConfig.MessageMaxFields,
+		EnumMaxValues:                        externalConfig.EnumMaxValues,
+		ServiceMaxRPCs:                       externalConfig.ServiceMaxRPCs,
+		ImportRules:                          importRules,
+		RPCIdempotencyLevelDisallowUnknown:   externalConfig.RPCIdempotencyLevelDisallowUnknown,
+		UnusedEntryPoints:                    externalConfig.UnusedEntryPoints,
 	}.NewConfig(
 		v1CheckerBuilders,
 		v1IDToCategories,
@@ -105,9 +145,19 @@ func NewConfig(externalConfig ExternalConfig) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	plugins, err := bufcheck.NewPlugins(externalConfig.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	internalConfig.Plugins = plugins
 	return internalConfigToConfig(internalConfig), nil
 }
 
+// GetAllCategories gets all known categories.
+func GetAllCategories() []string {
+	return append([]string{}, v1AllCategories...)
+}
+
 // GetAllCheckers gets all known checkers for the given categories.
 //
 // If categories is empty, this returns all checkers as bufcheck.Checkers.
@@ -121,35 +171,82 @@ func GetAllCheckers(categories ...string) ([]bufcheck.Checker, error) {
 	return checkersToBufcheckCheckers(config.Checkers, categories)
 }
 
+// GetDoc gets the Doc for the lint checker with the given ID.
+//
+// Returns false if there is no lint checker with the given ID.
+func GetDoc(id string) (*bufcheck.Doc, bool) {
+	doc, ok := v1IDToDoc[id]
+	return doc, ok
+}
+
 // ExternalConfig is an external config.
 type ExternalConfig struct {
 	Use    []string `json:"use,omitempty" yaml:"use,omitempty"`
 	Except []string `json:"except,omitempty" yaml:"except,omitempty"`
-	// IgnoreRootPaths
+	// Categories are user-defined categories, such as
+	// ACME_DEFAULT: DEFAULT + COMMENTS - PACKAGE_VERSION_SUFFIX
+	Categories map[string]string `json:"categories,omitempty" yaml:"categories,omitempty"`
+	// IgnoreRootPaths, which can be paths to files or directories, or glob
+	// patterns such as "**/vendor/**".
 	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
 	// IgnoreIDOrCategoryToRootPaths
-	IgnoreOnly                           map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
-	EnumZeroValueSuffix                  string              `json:"enum_zero_value_suffix,omitempty" yaml:"enum_zero_value_suffix,omitempty"`
-	RPCAllowSameRequestResponse          bool                `json:"rpc_allow_same_request_response,omitempty" yaml:"rpc_allow_same_request_response,omitempty"`
-	RPCAllowGoogleProtobufEmptyRequests  bool                `json:"rpc_allow_google_protobuf_empty_requests,omitempty" yaml:"rpc_allow_google_protobuf_empty_requests,omitempty"`
-	RPCAllowGoogleProtobufEmptyResponses bool                `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
-	ServiceSuffix                        string              `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
-	AllowCommentIgnores                  bool                `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
+	IgnoreOnly map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
+	// IgnoreIDOrCategoryToSymbols
+	IgnoreSymbols map[string][]string `json:"ignore_symbols,omitempty" yaml:"ignore_symbols,omitempty"`
+	// WarnIDsOrCategories
+	Warn                                 []string             `json:"warn,omitempty" yaml:"warn,omitempty"`
+	EnumZeroValueSuffix                  string               `json:"enum_zero_value_suffix,omitempty" yaml:"enum_zero_value_suffix,omitempty"`
+	RPCAllowSameRequestResponse          bool                 `json:"rpc_allow_same_request_response,omitempty" yaml:"rpc_allow_same_request_response,omitempty"`
+	RPCAllowGoogleProtobufEmptyRequests  bool                 `json:"rpc_allow_google_protobuf_empty_requests,omitempty" yaml:"rpc_allow_google_protobuf_empty_requests,omitempty"`
+	RPCAllowGoogleProtobufEmptyResponses bool                 `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
+	ServiceSuffix                        string               `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
+	MessageNamePattern                   string               `json:"message_name_pattern,omitempty" yaml:"message_name_pattern,omitempty"`
+	FieldNamePattern                     string               `json:"field_name_pattern,omitempty" yaml:"field_name_pattern,omitempty"`
+	ServiceNamePattern                   string               `json:"service_name_pattern,omitempty" yaml:"service_name_pattern,omitempty"`
+	RPCVerbs                             []string             `json:"rpc_verbs,omitempty" yaml:"rpc_verbs,omitempty"`
+	PackageVersionSuffixForms            []string             `json:"package_version_suffix_forms,omitempty" yaml:"package_version_suffix_forms,omitempty"`
+	PackageVersionSuffixAllowUnversioned []string             `json:"package_version_suffix_allow_unversioned,omitempty" yaml:"package_version_suffix_allow_unversioned,omitempty"`
+	MessageMaxNestingDepth               int                  `json:"message_max_nesting_depth,omitempty" yaml:"message_max_nesting_depth,omitempty"`
+	MessageMaxFields                     int                  `json:"message_max_fields,omitempty" yaml:"message_max_fields,omitempty"`
+	EnumMaxValues                        int                  `json:"enum_max_values,omitempty" yaml:"enum_max_values,omitempty"`
+	ServiceMaxRPCs                       int                  `json:"service_max_rpcs,omitempty" yaml:"service_max_rpcs,omitempty"`
+	ImportRules                          []ExternalImportRule `json:"import_rules,omitempty" yaml:"import_rules,omitempty"`
+	RPCIdempotencyLevelDisallowUnknown   bool                 `json:"rpc_idempotency_level_disallow_unknown,omitempty" yaml:"rpc_idempotency_level_disallow_unknown,omitempty"`
+	AllowCommentIgnores                  bool                 `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
+	// UnusedEntryPoints are the fully-qualified names of the messages and enums,
+	// or package patterns such as "acme.events.*", that are used outside of RPCs
+	// and are not reported by the UNUSED checkers.
+	UnusedEntryPoints []string `json:"unused_entry_points,omitempty" yaml:"unused_entry_points,omitempty"`
+	// Plugins are the check plugins to run in addition to the configured checkers.
+	Plugins []bufcheck.ExternalPluginConfig `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+}
+
+// ExternalImportRule is an external import rule.
+//
+// Files matching From can only import files that match Allow, if set, and do not
+// match Deny. Patterns are either a package, a package followed by ".*" to match
+// the package and all of its sub-packages, "*", or a path pattern that contains
+// a "/", such as "foo/internal/**", which matches files and directories.
+type ExternalImportRule struct {
+	From  string   `json:"from,omitempty" yaml:"from,omitempty"`
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`
 }
 
 // PrintFileAnnotations prints the FileAnnotations to the Writer.
 //
-// Also accepts config-ignore-yaml.
+// Also accepts config-ignore-yaml, for which the PrintOptions are ignored.
 func PrintFileAnnotations(
 	writer io.Writer,
 	fileAnnotations []bufanalysis.FileAnnotation,
 	formatString string,
+	options ...bufanalysis.PrintOption,
 ) error {
 	switch s := strings.ToLower(strings.TrimSpace(formatString)); s {
 	case "config-ignore-yaml":
 		return printFileAnnotationsConfigIgnoreYAML(writer, fileAnnotations)
 	default:
-		return bufanalysis.PrintFileAnnotations(writer, fileAnnotations, s)
+		return bufanalysis.PrintFileAnnotations(writer, fileAnnotations, s, options...)
 	}
 }
 
@@ -208,21 +305,67 @@ func printFileAnnotationsConfigIgnoreYAML(
 	return err
 }
 
+// SplitWarnings splits the FileAnnotations into errors and warnings as
+// configured by the WarnIDs of the Config.
+//
+// The warnings have SeverityWarning. The order of the FileAnnotations is preserved.
+func SplitWarnings(
+	config *Config,
+	fileAnnotations []bufanalysis.FileAnnotation,
+) ([]bufanalysis.FileAnnotation, []bufanalysis.FileAnnotation) {
+	if len(config.WarnIDs) == 0 {
+		return fileAnnotations, nil
+	}
+	var errorFileAnnotations []bufanalysis.FileAnnotation
+	var warningFileAnnotations []bufanalysis.FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		if _, ok := config.WarnIDs[fileAnnotation.Type()]; ok {
+			warningFileAnnotations = append(
+				warningFileAnnotations,
+				bufanalysis.NewFileAnnotation(
+					fileAnnotation.FileInfo(),
+					fileAnnotation.StartLine(),
+					fileAnnotation.StartColumn(),
+					fileAnnotation.EndLine(),
+					fileAnnotation.EndColumn(),
+					fileAnnotation.Type(),
+					fileAnnotation.Message(),
+					bufanalysis.FileAnnotationWithEdits(fileAnnotation.Edits()...),
+					bufanalysis.FileAnnotationWithSeverity(bufanalysis.SeverityWarning),
+				),
+			)
+		} else {
+			errorFileAnnotations = append(errorFileAnnotations, fileAnnotation)
+		}
+	}
+	return errorFileAnnotations, warningFileAnnotations
+}
+
 func internalConfigToConfig(internalConfig *internal.Config) *Config {
 	return &Config{
-		Checkers:            internalCheckersToCheckers(internalConfig.Checkers),
-		IgnoreIDToRootPaths: internalConfig.IgnoreIDToRootPaths,
-		IgnoreRootPaths:     internalConfig.IgnoreRootPaths,
-		AllowCommentIgnores: internalConfig.AllowCommentIgnores,
+		Checkers:                internalCheckersToCheckers(internalConfig.Checkers),
+		IgnoreIDToRootPaths:     internalConfig.IgnoreIDToRootPaths,
+		IgnoreIDToRootPathGlobs: internalConfig.IgnoreIDToRootPathGlobs,
+		IgnoreIDToSymbols:       internalConfig.IgnoreIDToSymbols,
+		IgnoreRootPaths:         internalConfig.IgnoreRootPaths,
+		IgnoreRootPathGlobs:     internalConfig.IgnoreRootPathGlobs,
+		WarnIDs:                 internalConfig.WarnIDs,
+		Plugins:                 internalConfig.Plugins,
+		AllowCommentIgnores:     internalConfig.AllowCommentIgnores,
 	}
 }
 
 func configToInternalConfig(config *Config) *internal.Config {
 	return &internal.Config{
-		Checkers:            checkersToInternalCheckers(config.Checkers),
-		IgnoreIDToRootPaths: config.IgnoreIDToRootPaths,
-		IgnoreRootPaths:     config.IgnoreRootPaths,
-		AllowCommentIgnores: config.AllowCommentIgnores,
+		Checkers:                checkersToInternalCheckers(config.Checkers),
+		IgnoreIDToRootPaths:     config.IgnoreIDToRootPaths,
+		IgnoreIDToRootPathGlobs: config.IgnoreIDToRootPathGlobs,
+		IgnoreIDToSymbols:       config.IgnoreIDToSymbols,
+		IgnoreRootPaths:         config.IgnoreRootPaths,
+		IgnoreRootPathGlobs:     config.IgnoreRootPathGlobs,
+		WarnIDs:                 config.WarnIDs,
+		Plugins:                 config.Plugins,
+		AllowCommentIgnores:     config.AllowCommentIgnores,
 	}
 }
 
@@ -16,22 +16,29 @@ package buflint_test
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufanalysis/bufanalysistesting"
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/bufmod"
+	checkv1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/check/v1"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 // Hint on how to get these:
@@ -142,6 +149,20 @@ func TestRunComments(t *testing.T) {
 	)
 }
 
+func TestRunDeprecated(t *testing.T) {
+	testLint(
+		t,
+		"deprecated",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 5, 9, 5, 12, "DEPRECATED_COMMENT"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 15, 6, 15, 13, "DEPRECATED_COMMENT"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 23, 10, 23, 13, "DEPRECATED_COMMENT"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 24, 3, 24, 6, "FIELD_DEPRECATED_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 25, 3, 25, 10, "FIELD_DEPRECATED_TYPE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 31, 9, 31, 19, "DEPRECATED_COMMENT"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 33, 7, 33, 10, "DEPRECATED_COMMENT"),
+	)
+}
+
 func TestRunDirectorySamePackage(t *testing.T) {
 	testLint(
 		t,
@@ -153,6 +174,24 @@ func TestRunDirectorySamePackage(t *testing.T) {
 	)
 }
 
+func TestRunImportAllowed(t *testing.T) {
+	testLint(
+		t,
+		"import_allowed",
+		bufanalysistesting.NewFileAnnotation(t, "acme/internal/v1/a.proto", 6, 1, 6, 32, "IMPORT_ALLOWED"),
+		bufanalysistesting.NewFileAnnotation(t, "acme/public/v1/a.proto", 5, 1, 5, 35, "IMPORT_ALLOWED"),
+	)
+}
+
+func TestRunImportTransitiveAllowed(t *testing.T) {
+	testLint(
+		t,
+		"import_transitive_allowed",
+		bufanalysistesting.NewFileAnnotation(t, "acme/public/v1/a.proto", 5, 1, 5, 33, "IMPORT_TRANSITIVE_ALLOWED"),
+		bufanalysistesting.NewFileAnnotation(t, "acme/public/v1/b.proto", 5, 1, 5, 35, "IMPORT_ALLOWED"),
+	)
+}
+
 func TestRunImportNoPublic(t *testing.T) {
 	testLint(
 		t,
@@ -354,6 +393,17 @@ func TestRunFileLowerSnakeCase(t *testing.T) {
 	)
 }
 
+func TestRunMaxLimits(t *testing.T) {
+	testLint(
+		t,
+		"max_limits",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 10, 9, 10, 12, "MESSAGE_MAX_FIELDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 15, 13, 15, 25, "MESSAGE_MAX_NESTING_DEPTH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 32, 6, 32, 10, "ENUM_MAX_VALUES"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 42, 9, 42, 19, "SERVICE_MAX_RPCS"),
+	)
+}
+
 func TestRunMessagePascalCase(t *testing.T) {
 	testLint(
 		t,
@@ -371,6 +421,26 @@ func TestRunMessagePascalCase(t *testing.T) {
 	)
 }
 
+func TestRunNamePattern(t *testing.T) {
+	testLint(
+		t,
+		"name_pattern",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 10, 7, 17, "FIELD_NAME_PATTERN"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 11, 9, 11, 16, "MESSAGE_NAME_PATTERN"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 14, 9, 14, 20, "SERVICE_NAME_PATTERN"),
+	)
+}
+
+func TestRunNamePatternCustom(t *testing.T) {
+	testLint(
+		t,
+		"name_pattern_custom",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 10, 7, 18, "FIELD_NAME_PATTERN"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 10, 9, 10, 20, "MESSAGE_NAME_PATTERN"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 13, 9, 13, 20, "SERVICE_NAME_PATTERN"),
+	)
+}
+
 func TestRunOneofLowerSnakeCase(t *testing.T) {
 	testLint(
 		t,
@@ -475,6 +545,17 @@ func TestRunPackageSameOptionValue(t *testing.T) {
 	)
 }
 
+func TestRunPackageVersion(t *testing.T) {
+	testLint(
+		t,
+		"package_version",
+		bufanalysistesting.NewFileAnnotation(t, "bar/a.proto", 3, 1, 3, 16, "PACKAGE_VERSION_DIRECTORY_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "foo/v1/a.proto", 3, 1, 3, 16, "DIRECTORY_SAME_PACKAGE"),
+		bufanalysistesting.NewFileAnnotation(t, "foo/v1/b.proto", 3, 1, 3, 16, "DIRECTORY_SAME_PACKAGE"),
+		bufanalysistesting.NewFileAnnotation(t, "foo/v1/b.proto", 3, 1, 3, 16, "PACKAGE_VERSION_DIRECTORY_MATCH"),
+	)
+}
+
 func TestRunPackageVersionSuffix(t *testing.T) {
 	testLint(
 		t,
@@ -489,6 +570,51 @@ func TestRunPackageVersionSuffix(t *testing.T) {
 	)
 }
 
+func TestRunPackageVersionSuffixCustom(t *testing.T) {
+	testLint(
+		t,
+		"package_version_suffix_custom",
+		bufanalysistesting.NewFileAnnotation(t, "c.proto", 3, 1, 3, 19, "PACKAGE_VERSION_SUFFIX"),
+		bufanalysistesting.NewFileAnnotation(t, "d.proto", 3, 1, 3, 11, "PACKAGE_VERSION_SUFFIX"),
+		bufanalysistesting.NewFileAnnotation(t, "internal/b.proto", 3, 1, 3, 23, "PACKAGE_VERSION_SUFFIX"),
+	)
+}
+
+func TestRunRPCIdempotencyLevelDefined(t *testing.T) {
+	testLint(
+		t,
+		"rpc_idempotency_level_defined",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 8, 7, 8, 10, "RPC_IDEMPOTENCY_LEVEL_DEFINED"),
+	)
+}
+
+func TestRunRPCIdempotencyLevelDefinedDisallowUnknown(t *testing.T) {
+	testLint(
+		t,
+		"rpc_idempotency_level_defined_disallow_unknown",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 8, 7, 8, 10, "RPC_IDEMPOTENCY_LEVEL_DEFINED"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 10, 5, 10, 52, "RPC_IDEMPOTENCY_LEVEL_DEFINED"),
+	)
+}
+
+func TestRunRPCNameVerb(t *testing.T) {
+	testLint(
+		t,
+		"rpc_name_verb",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 10, 7, 10, 15, "RPC_NAME_VERB"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 11, 7, 11, 10, "RPC_NAME_VERB"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 12, 7, 12, 13, "RPC_NAME_VERB"),
+	)
+}
+
+func TestRunRPCNameVerbCustom(t *testing.T) {
+	testLint(
+		t,
+		"rpc_name_verb_custom",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 10, 7, 10, 13, "RPC_NAME_VERB"),
+	)
+}
+
 func TestRunRPCNoStreaming(t *testing.T) {
 	testLint(
 		t,
@@ -674,6 +800,46 @@ func TestRunServiceSuffixCustom(t *testing.T) {
 	)
 }
 
+func TestRunUnused(t *testing.T) {
+	testLint(
+		t,
+		"unused",
+		bufanalysistesting.NewFileAnnotation(t, "a/v1/a.proto", 24, 11, 24, 21, "MESSAGE_UNUSED"),
+		bufanalysistesting.NewFileAnnotation(t, "a/v1/a.proto", 33, 6, 33, 14, "ENUM_UNUSED"),
+		bufanalysistesting.NewFileAnnotation(t, "a/v1/a.proto", 52, 19, 52, 28, "EXTENSION_UNUSED"),
+		bufanalysistesting.NewFileAnnotation(t, "a/v1/a.proto", 55, 9, 55, 15, "MESSAGE_UNUSED"),
+	)
+}
+
+func TestRunValidateRules(t *testing.T) {
+	testLint(
+		t,
+		"validate_rules",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 27, 3, 27, 50, "VALIDATE_RULES_TYPE_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 28, 3, 28, 60, "VALIDATE_RULES_TYPE_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 29, 3, 29, 67, "VALIDATE_RULES_TYPE_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 30, 3, 30, 80, "VALIDATE_RULES_TYPE_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 31, 3, 31, 75, "VALIDATE_RULES_TYPE_MATCH"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 32, 3, 32, 63, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 33, 3, 33, 74, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 34, 3, 34, 69, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 35, 3, 35, 70, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 36, 3, 36, 86, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 37, 3, 37, 65, "VALIDATE_RULES_SYNTAX"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 38, 3, 38, 68, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 39, 3, 39, 74, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 40, 3, 40, 73, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 41, 3, 41, 69, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 42, 3, 42, 78, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 43, 3, 43, 84, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 44, 3, 44, 73, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 45, 3, 45, 82, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 46, 3, 46, 79, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 47, 3, 47, 83, "VALIDATE_RULES_BOUNDS"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 48, 3, 48, 73, "VALIDATE_RULES_BOUNDS"),
+	)
+}
+
 func TestRunIgnores1(t *testing.T) {
 	testLint(
 		t,
@@ -753,6 +919,64 @@ func TestRunIgnores3(t *testing.T) {
 	)
 }
 
+func TestRunIgnores4(t *testing.T) {
+	testLintExternalConfigModifier(
+		t,
+		"ignores",
+		func(externalConfig *bufconfig.ExternalConfig) {
+			externalConfig.Lint.Ignore = []string{
+				"**/bar",
+				"buf/foo/*.proto",
+			}
+		},
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 13, 6, 13, 9, "ENUM_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/baz/baz.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/baz/baz.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/baz/baz.proto", 13, 6, 13, 9, "ENUM_PASCAL_CASE"),
+	)
+}
+
+func TestRunIgnores5(t *testing.T) {
+	testLintExternalConfigModifier(
+		t,
+		"ignores",
+		func(externalConfig *bufconfig.ExternalConfig) {
+			externalConfig.Lint.IgnoreOnly = map[string][]string{
+				"ENUM_PASCAL_CASE": {
+					"bar*.proto",
+				},
+				"STYLE_BASIC": {
+					"buf/foo/**/baz.proto",
+				},
+			}
+		},
+		bufanalysistesting.NewFileAnnotation(t, "buf/bar/bar.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/bar/bar.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/bar/bar2.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/bar/bar2.proto", 9, 9, 9, 13, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/buf.proto", 13, 6, 13, 9, "ENUM_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/bar/bar.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/bar/bar.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/buf.proto", 6, 9, 6, 15, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/buf.proto", 9, 9, 9, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "buf/foo/buf.proto", 13, 6, 13, 9, "ENUM_PASCAL_CASE"),
+	)
+}
+
+func TestIgnoreSymbols(t *testing.T) {
+	testLint(
+		t,
+		"ignore_symbols",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 9, 7, 19, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 17, 9, 17, 12, "MESSAGE_PASCAL_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 18, 9, 18, 17, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
 func TestCommentIgnoresOff(t *testing.T) {
 	testLint(
 		t,
@@ -802,6 +1026,58 @@ func TestCommentIgnoresOn(t *testing.T) {
 	)
 }
 
+func TestRunPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script requires a shell")
+	}
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	responseData, err := protoencoding.NewWireMarshaler().Marshal(
+		&checkv1.CheckResponse{
+			FileAnnotations: []*checkv1.FileAnnotation{
+				{
+					Path:        proto.String("a.proto"),
+					StartLine:   proto.Uint32(3),
+					StartColumn: proto.Uint32(1),
+					EndLine:     proto.Uint32(3),
+					EndColumn:   proto.Uint32(11),
+					Type:        proto.String("ACME_PACKAGE"),
+				},
+				{
+					Path: proto.String("b/b.proto"),
+					Type: proto.String("ACME_PACKAGE"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	responseFilePath := filepath.Join(tmpDirPath, "response.bin")
+	require.NoError(t, ioutil.WriteFile(responseFilePath, responseData, 0600))
+	pluginFilePath := filepath.Join(tmpDirPath, "buf-check-plugin-acme")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			pluginFilePath,
+			[]byte("#!/bin/sh\ncat > /dev/null\ncat "+responseFilePath+"\n"),
+			0700,
+		),
+	)
+	testLintExternalConfigModifier(
+		t,
+		"plugins",
+		func(externalConfig *bufconfig.ExternalConfig) {
+			externalConfig.Lint.Plugins = []bufcheck.ExternalPluginConfig{
+				{
+					Name: "acme",
+					Path: pluginFilePath,
+				},
+			}
+		},
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 3, 1, 3, 11, "ACME_PACKAGE"),
+	)
+}
+
 func testLint(
 	t *testing.T,
 	relDirPath string,
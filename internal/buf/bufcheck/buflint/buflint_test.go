@@ -17,7 +17,9 @@ package buflint
 import (
 	"testing"
 
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal/internaltesting"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultConfigBuilder(t *testing.T) {
@@ -39,3 +41,101 @@ func TestCheckerBuilders(t *testing.T) {
 		v1AllCategories,
 	)
 }
+
+func TestDocs(t *testing.T) {
+	t.Parallel()
+	for id := range v1IDToCategories {
+		doc, ok := v1IDToDoc[id]
+		require.True(t, ok, id)
+		require.NotEmpty(t, doc.Rationale, id)
+		require.NotEmpty(t, doc.FailingExample, id)
+		require.NotEmpty(t, doc.PassingExample, id)
+	}
+	for id := range v1IDToDoc {
+		_, ok := v1IDToCategories[id]
+		require.True(t, ok, id)
+	}
+}
+
+func TestCustomCategories(t *testing.T) {
+	t.Parallel()
+	config, err := NewConfig(
+		ExternalConfig{
+			Use: []string{"ACME"},
+			Categories: map[string]string{
+				"ACME":      "ACME_BASE + COMMENTS - COMMENT_FIELD",
+				"ACME_BASE": "MINIMAL-PACKAGE_DIRECTORY_MATCH",
+			},
+		},
+	)
+	require.NoError(t, err)
+	expectedIDs, err := getIDs("MINIMAL", "COMMENTS")
+	require.NoError(t, err)
+	delete(expectedIDs, "PACKAGE_DIRECTORY_MATCH")
+	delete(expectedIDs, "COMMENT_FIELD")
+	ids := make(map[string]struct{}, len(config.Checkers))
+	for _, checker := range config.Checkers {
+		ids[checker.ID()] = struct{}{}
+	}
+	require.Equal(t, expectedIDs, ids)
+
+	for _, categories := range []map[string]string{
+		{"ACME": ""},
+		{"ACME": "DEFAULT +"},
+		{"ACME": "DEFAULT COMMENTS"},
+		{"ACME": "DEFAULT + UNKNOWN"},
+		{"ACME": "DEFAULT + ACME"},
+		{"ACME": "ACME_OTHER", "ACME_OTHER": "ACME"},
+		{"DEFAULT": "MINIMAL"},
+		{"COMMENT_FIELD": "MINIMAL"},
+	} {
+		_, err := NewConfig(ExternalConfig{Categories: categories})
+		require.Error(t, err, categories)
+	}
+}
+
+func TestWarn(t *testing.T) {
+	t.Parallel()
+	config, err := NewConfig(
+		ExternalConfig{
+			Use:  []string{"DEFAULT"},
+			Warn: []string{"COMMENTS", "PACKAGE_DIRECTORY_MATCH"},
+		},
+	)
+	require.NoError(t, err)
+	expectedIDs, err := getIDs("COMMENTS")
+	require.NoError(t, err)
+	expectedIDs["PACKAGE_DIRECTORY_MATCH"] = struct{}{}
+	require.Equal(t, expectedIDs, config.WarnIDs)
+
+	fileAnnotations := []bufanalysis.FileAnnotation{
+		bufanalysis.NewFileAnnotation(nil, 1, 1, 1, 1, "PACKAGE_DIRECTORY_MATCH", "one"),
+		bufanalysis.NewFileAnnotation(nil, 2, 1, 2, 1, "FIELD_LOWER_SNAKE_CASE", "two"),
+		bufanalysis.NewFileAnnotation(nil, 3, 1, 3, 1, "COMMENT_FIELD", "three"),
+	}
+	errorFileAnnotations, warningFileAnnotations := SplitWarnings(config, fileAnnotations)
+	require.Equal(t, fileAnnotations[1:2], errorFileAnnotations)
+	require.Equal(
+		t,
+		[]bufanalysis.FileAnnotation{
+			bufanalysis.NewFileAnnotation(nil, 1, 1, 1, 1, "PACKAGE_DIRECTORY_MATCH", "one", bufanalysis.FileAnnotationWithSeverity(bufanalysis.SeverityWarning)),
+			bufanalysis.NewFileAnnotation(nil, 3, 1, 3, 1, "COMMENT_FIELD", "three", bufanalysis.FileAnnotationWithSeverity(bufanalysis.SeverityWarning)),
+		},
+		warningFileAnnotations,
+	)
+
+	_, err = NewConfig(ExternalConfig{Warn: []string{"UNKNOWN"}})
+	require.Error(t, err)
+}
+
+func getIDs(categories ...string) (map[string]struct{}, error) {
+	checkers, err := GetAllCheckers(categories...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(checkers))
+	for _, checker := range checkers {
+		ids[checker.ID()] = struct{}{}
+	}
+	return ids, nil
+}
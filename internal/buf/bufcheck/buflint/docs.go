@@ -0,0 +1,635 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflint
+
+import "github.com/bufbuild/buf/internal/buf/bufcheck"
+
+// v1IDToDoc is the documentation for all v1 checkers.
+//
+// Every ID in v1IDToCategories must have an entry.
+var v1IDToDoc = map[string]*bufcheck.Doc{
+	"COMMENT_ENUM": newCommentDoc("enum", `enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`, `// Foo is a foo.
+enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`),
+	"COMMENT_ENUM_VALUE": newCommentDoc("enum value", `enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`, `enum Foo {
+  // FOO_UNSPECIFIED is the default value.
+  FOO_UNSPECIFIED = 0;
+}`),
+	"COMMENT_FIELD": newCommentDoc("field", `message Foo {
+  string name = 1;
+}`, `message Foo {
+  // name is the name of the foo.
+  string name = 1;
+}`),
+	"COMMENT_MESSAGE": newCommentDoc("message", `message Foo {}`, `// Foo is a foo.
+message Foo {}`),
+	"COMMENT_ONEOF": newCommentDoc("oneof", `message Foo {
+  oneof value {
+    string name = 1;
+  }
+}`, `message Foo {
+  // value is the value of the foo.
+  oneof value {
+    string name = 1;
+  }
+}`),
+	"COMMENT_RPC": newCommentDoc("RPC", `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`, `service FooService {
+  // GetFoo gets a foo.
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`),
+	"COMMENT_SERVICE": newCommentDoc("service", `service FooService {}`, `// FooService manages foos.
+service FooService {}`),
+	"DEPRECATED_COMMENT": {
+		Rationale: `Deprecations without guidance are rarely acted on, as consumers do not know
+what to migrate to. A comment that mentions the deprecation, such as one starting with
+"Deprecated:", should explain what to use instead. This follows the Go convention for
+deprecation comments, which many tools recognize.`,
+		FailingExample: `message Foo {
+  string name = 1 [deprecated = true];
+}`,
+		PassingExample: `message Foo {
+  // Deprecated: use display_name instead.
+  string name = 1 [deprecated = true];
+  string display_name = 2;
+}`,
+	},
+	"DIRECTORY_SAME_PACKAGE": {
+		Rationale: `A directory is the unit that most code generators map to a single generated
+package. If files in the same directory have different packages, the generated
+code either fails to compile or ends up in unexpected places.`,
+		FailingExample: `// foo/v1/a.proto
+package foo.v1;
+
+// foo/v1/b.proto
+package bar.v1;`,
+		PassingExample: `// foo/v1/a.proto
+package foo.v1;
+
+// foo/v1/b.proto
+package foo.v1;`,
+	},
+	"ENUM_FIRST_VALUE_ZERO": {
+		Rationale: `In proto3, the first enum value must be zero, and it is the value used when a
+field is not set. Applying the same rule in proto2 keeps the default value of an
+enum explicit and consistent across syntaxes.`,
+		FailingExample: `enum Foo {
+  FOO_ONE = 1;
+  FOO_UNSPECIFIED = 0;
+}`,
+		PassingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+	},
+	"ENUM_MAX_VALUES": {
+		Rationale: `Enums with a very large number of values are hard to review, and generate
+large amounts of code that some languages and clients handle poorly. Such enums are
+often better modeled as strings or split up. The maximum is configured with
+enum_max_values, and defaults to 250. The examples use a maximum of 2.`,
+		FailingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+  FOO_TWO = 2;
+}`,
+		PassingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+	},
+	"ENUM_NO_ALLOW_ALIAS": {
+		Rationale: `Aliased enum values share a number, so the name that is used for a value in
+JSON and text output is ambiguous, and renaming a value can silently break
+consumers.`,
+		FailingExample: `enum Foo {
+  option allow_alias = true;
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+  FOO_UNO = 1;
+}`,
+		PassingExample: `enum Foo {
+  FOO_UNSPECIFIED = 0;
+  FOO_ONE = 1;
+}`,
+	},
+	"ENUM_PASCAL_CASE": newCaseDoc("enum", "PascalCase", `enum foo_bar {}`, `enum FooBar {}`),
+	"ENUM_UNUSED": {
+		Rationale: `Enums that are not used by any RPC are usually dead schema that is left
+behind after a refactor. An enum is used if it is reachable from the request or response
+type of an RPC, from a message that is extended by a custom option, or from one of the
+unused_entry_points, which are fully-qualified names or package patterns such as
+acme.events.* for types that are used outside of RPCs. Only references from the files
+that are checked are known. The examples have no entry points.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+enum Bar {
+  BAR_UNSPECIFIED = 0;
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+message GetFooRequest {
+  Bar bar = 1;
+}`,
+	},
+	"ENUM_VALUE_UPPER_SNAKE_CASE": newCaseDoc("enum value", "UPPER_SNAKE_CASE", `enum Foo {
+  fooUnspecified = 0;
+}`, `enum Foo {
+  FOO_UNSPECIFIED = 0;
+}`),
+	"ENUM_VALUE_PREFIX": {
+		Rationale: `Enum values use C++ scoping rules, so the values of all enums in a package
+share a single namespace. Prefixing values with the name of their enum avoids
+collisions between enums, and makes values self-describing in generated code.`,
+		FailingExample: `enum Color {
+  UNSPECIFIED = 0;
+  RED = 1;
+}`,
+		PassingExample: `enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+}`,
+	},
+	"ENUM_ZERO_VALUE_SUFFIX": {
+		Rationale: `The zero value is used when an enum field is not set, so it should not carry
+a meaning of its own. A consistent suffix, _UNSPECIFIED by default, makes this
+explicit. The suffix is configurable with enum_zero_value_suffix.`,
+		FailingExample: `enum Color {
+  COLOR_RED = 0;
+}`,
+		PassingExample: `enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+}`,
+	},
+	"EXTENSION_UNUSED": {
+		Rationale: `Extensions are fields of the message that they extend, so an extension of
+a message that is not used is dead schema as well. Extensions of messages that are not
+checked, such as custom options, are always used. See MESSAGE_UNUSED for which messages
+are used. The examples have no entry points.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+message Bar {
+  extensions 100 to 199;
+}
+
+extend Bar {
+  string name = 100;
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+message GetFooRequest {
+  extensions 100 to 199;
+}
+
+extend GetFooRequest {
+  string name = 100;
+}`,
+	},
+	"FIELD_DEPRECATED_TYPE": {
+		Rationale: `A field that uses a deprecated message or enum keeps the deprecated type in use,
+so the deprecation never completes. Such fields should be deprecated along with their
+type. Fields of deprecated messages are not checked, and only types defined in the
+files being checked are known.`,
+		FailingExample: `message Foo {
+  option deprecated = true;
+}
+
+message Bar {
+  Foo foo = 1;
+}`,
+		PassingExample: `message Foo {
+  option deprecated = true;
+}
+
+message Bar {
+  // Deprecated: Foo is deprecated.
+  Foo foo = 1 [deprecated = true];
+}`,
+	},
+	"FIELD_LOWER_SNAKE_CASE": newCaseDoc("field", "lower_snake_case", `message Foo {
+  string fooName = 1;
+}`, `message Foo {
+  string foo_name = 1;
+}`),
+	"FIELD_NAME_PATTERN": newNamePatternDoc("field", "field_name_pattern", "lower_snake_case", `message Foo {
+  string fooName = 1;
+}`, `message Foo {
+  string foo_name = 1;
+}`),
+	"FIELD_NO_DESCRIPTOR": {
+		Rationale: `Some code generators, for example those for Java, generate a method named
+getDescriptor, so a field named descriptor results in generated code that does
+not compile.`,
+		FailingExample: `message Foo {
+  string descriptor = 1;
+}`,
+		PassingExample: `message Foo {
+  string foo_descriptor = 1;
+}`,
+	},
+	"FILE_LOWER_SNAKE_CASE": {
+		Rationale: `Consistent file names make files predictable to find and import, and many
+code generators derive generated file names from them.`,
+		FailingExample: `// foo/v1/FooBar.proto`,
+		PassingExample: `// foo/v1/foo_bar.proto`,
+	},
+	"IMPORT_ALLOWED": {
+		Rationale: `Layering rules between packages, such as public APIs not depending on
+internal packages, are otherwise only enforced by review. The rules are configured
+with import_rules, where each rule restricts the files that files matching from can
+import with allow and deny patterns. Patterns are a package, a package followed by .*
+to also match its sub-packages, or *. Patterns that contain a / are matched against
+file paths instead, for example acme/internal/** or acme/*/internal. Imports within a
+package are always allowed, and only imports of files that are checked are matched.
+The examples use a rule with from acme.public.* and deny acme.internal.*.`,
+		FailingExample: `// acme/public/v1/foo.proto
+package acme.public.v1;
+
+import "acme/internal/v1/bar.proto";`,
+		PassingExample: `// acme/public/v1/foo.proto
+package acme.public.v1;
+
+import "acme/public/v1/bar.proto";`,
+	},
+	"IMPORT_NO_PUBLIC": {
+		Rationale: `Public imports are not supported by all languages, and make it unclear which
+file a type is defined in. Import each file that you depend on directly.`,
+		FailingExample: `import public "foo/v1/foo.proto";`,
+		PassingExample: `import "foo/v1/foo.proto";`,
+	},
+	"IMPORT_NO_WEAK": {
+		Rationale: `Weak imports are not supported by most languages, and allow a file to
+compile without its dependency being present.`,
+		FailingExample: `import weak "foo/v1/foo.proto";`,
+		PassingExample: `import "foo/v1/foo.proto";`,
+	},
+	"IMPORT_TRANSITIVE_ALLOWED": {
+		Rationale: `A layering rule is not enforced if a disallowed package can still be reached
+through an allowed one, as generated code then depends on the disallowed package. This
+applies the import_rules of IMPORT_ALLOWED to the files that are imported transitively,
+and reports each one at the direct import that it is reached through, with the chain
+of imports. Only imports between files that are checked are followed. The examples use
+a rule with from acme.public.* and deny acme.internal.*, where acme/shared/v1/bar.proto
+imports acme/internal/v1/baz.proto.`,
+		FailingExample: `// acme/public/v1/foo.proto
+package acme.public.v1;
+
+import "acme/shared/v1/bar.proto";`,
+		PassingExample: `// acme/public/v1/foo.proto
+package acme.public.v1;
+
+import "acme/public/v1/bar.proto";`,
+	},
+	"MESSAGE_MAX_FIELDS": {
+		Rationale: `Messages with a very large number of fields generate large amounts of code,
+which some languages and clients cannot compile or handle efficiently, and usually
+indicate that the message should be split up. The maximum is configured with
+message_max_fields, and defaults to 100. The examples use a maximum of 2.`,
+		FailingExample: `message Foo {
+  string one = 1;
+  string two = 2;
+  string three = 3;
+}`,
+		PassingExample: `message Foo {
+  string one = 1;
+  Bar bar = 2;
+}
+
+message Bar {
+  string two = 1;
+  string three = 2;
+}`,
+	},
+	"MESSAGE_MAX_NESTING_DEPTH": {
+		Rationale: `Deeply nested messages result in long generated type names that are hard to
+use in most languages. Only the first message past the maximum depth is reported.
+The maximum is configured with message_max_nesting_depth, and defaults to 4, where
+top-level messages are one level deep. The examples use a maximum of 2.`,
+		FailingExample: `message Foo {
+  message Bar {
+    message Baz {}
+  }
+}`,
+		PassingExample: `message Foo {
+  message Bar {}
+}
+
+message Baz {}`,
+	},
+	"MESSAGE_UNUSED": {
+		Rationale: `Messages that are not used by any RPC are usually dead schema that is left
+behind after a refactor. A message is used if it is the request or response type of an
+RPC, is reachable from one through fields and extensions, is the type of a custom option,
+contains a nested type that is used, or is one of the unused_entry_points, which are
+fully-qualified names or package patterns such as acme.events.* for types that are used
+outside of RPCs. Only references from the files that are checked are known. The examples
+have no entry points.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+message Bar {}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+message GetFooRequest {
+  Bar bar = 1;
+}`,
+	},
+	"MESSAGE_NAME_PATTERN": newNamePatternDoc("message", "message_name_pattern", "PascalCase that allows acronyms", `message foo_bar {}`, `message HTTPRequest {}`),
+	"MESSAGE_PASCAL_CASE":  newCaseDoc("message", "PascalCase", `message foo_bar {}`, `message FooBar {}`),
+	"ONEOF_LOWER_SNAKE_CASE": newCaseDoc("oneof", "lower_snake_case", `message Foo {
+  oneof fooValue {
+    string name = 1;
+  }
+}`, `message Foo {
+  oneof foo_value {
+    string name = 1;
+  }
+}`),
+	"PACKAGE_DEFINED": {
+		Rationale: `Files without a package put all of their types in the global namespace, where
+they can collide with types from any other file.`,
+		FailingExample: `syntax = "proto3";
+
+message Foo {}`,
+		PassingExample: `syntax = "proto3";
+
+package foo.v1;
+
+message Foo {}`,
+	},
+	"PACKAGE_DIRECTORY_MATCH": {
+		Rationale: `Placing files in a directory that matches their package makes it possible to
+find the files for a package, and matches how most languages lay out generated
+code.`,
+		FailingExample: `// bar/a.proto
+package foo.v1;`,
+		PassingExample: `// foo/v1/a.proto
+package foo.v1;`,
+	},
+	"PACKAGE_LOWER_SNAKE_CASE":      newCaseDoc("package", "lower_snake_case", `package fooBar.v1;`, `package foo_bar.v1;`),
+	"PACKAGE_SAME_CSHARP_NAMESPACE": newPackageSameOptionDoc("csharp_namespace", `option csharp_namespace = "Foo.V1";`, `option csharp_namespace = "Foo.Bar.V1";`),
+	"PACKAGE_SAME_DIRECTORY": {
+		Rationale: `Splitting a package across directories means that generated code for the
+package is split across packages in languages that map directories to packages.`,
+		FailingExample: `// foo/v1/a.proto
+package foo.v1;
+
+// bar/b.proto
+package foo.v1;`,
+		PassingExample: `// foo/v1/a.proto
+package foo.v1;
+
+// foo/v1/b.proto
+package foo.v1;`,
+	},
+	"PACKAGE_SAME_GO_PACKAGE":          newPackageSameOptionDoc("go_package", `option go_package = "foov1";`, `option go_package = "barv1";`),
+	"PACKAGE_SAME_JAVA_MULTIPLE_FILES": newPackageSameOptionDoc("java_multiple_files", `option java_multiple_files = true;`, `option java_multiple_files = false;`),
+	"PACKAGE_SAME_JAVA_PACKAGE":        newPackageSameOptionDoc("java_package", `option java_package = "com.foo.v1";`, `option java_package = "com.bar.v1";`),
+	"PACKAGE_SAME_PHP_NAMESPACE":       newPackageSameOptionDoc("php_namespace", `option php_namespace = "Foo\\V1";`, `option php_namespace = "Bar\\V1";`),
+	"PACKAGE_SAME_RUBY_PACKAGE":        newPackageSameOptionDoc("ruby_package", `option ruby_package = "Foo::V1";`, `option ruby_package = "Bar::V1";`),
+	"PACKAGE_SAME_SWIFT_PREFIX":        newPackageSameOptionDoc("swift_prefix", `option swift_prefix = "FOO";`, `option swift_prefix = "BAR";`),
+	"PACKAGE_VERSION_DIRECTORY_MATCH": {
+		Rationale: `Placing each version of a package in its own directory, such as foo/v1 and
+foo/v2, keeps the versions from being mixed in generated code. This is the same
+as PACKAGE_DIRECTORY_MATCH, but does not apply to unversioned packages.`,
+		FailingExample: `// foo/a.proto
+package foo.v1;`,
+		PassingExample: `// foo/v1/a.proto
+package foo.v1;`,
+	},
+	"PACKAGE_VERSION_SUFFIX": {
+		Rationale: `Versioned packages allow breaking changes to be made in a new package, such as
+foo.v2, while existing consumers continue to use foo.v1. The allowed version
+forms are configurable with package_version_suffix_forms, and unversioned
+packages can be allowed under specific directories with
+package_version_suffix_allow_unversioned.`,
+		FailingExample: `package foo;`,
+		PassingExample: `package foo.v1;`,
+	},
+	"RPC_IDEMPOTENCY_LEVEL_DEFINED": {
+		Rationale: `Clients and proxies use the idempotency_level option to decide whether an
+RPC can be retried, or sent as an HTTP GET. RPCs without the option default to
+IDEMPOTENCY_UNKNOWN, which is often not what was intended. Set
+rpc_idempotency_level_disallow_unknown to also require a value other than
+IDEMPOTENCY_UNKNOWN.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}`,
+	},
+	"RPC_NAME_VERB": {
+		Rationale: `RPC names that start with a verb from an approved list, such as Get, List,
+Create, Update, and Delete, make APIs predictable and avoid sprawl of
+near-synonyms such as Fetch, Retrieve, and Read. The verbs are configurable
+with rpc_verbs.`,
+		FailingExample: `service FooService {
+  rpc FetchFoo(FetchFooRequest) returns (FetchFooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+	},
+	"RPC_NO_CLIENT_STREAMING": {
+		Rationale: `Streaming RPCs are not supported by all RPC frameworks and proxies, and are
+harder to retry, load balance, and debug than unary RPCs.`,
+		FailingExample: `service FooService {
+  rpc UploadFoo(stream UploadFooRequest) returns (UploadFooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc UploadFoo(UploadFooRequest) returns (UploadFooResponse);
+}`,
+	},
+	"RPC_NO_SERVER_STREAMING": {
+		Rationale: `Streaming RPCs are not supported by all RPC frameworks and proxies, and are
+harder to retry, load balance, and debug than unary RPCs.`,
+		FailingExample: `service FooService {
+  rpc ListFoos(ListFoosRequest) returns (stream ListFoosResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc ListFoos(ListFoosRequest) returns (ListFoosResponse);
+}`,
+	},
+	"RPC_PASCAL_CASE": newCaseDoc("RPC", "PascalCase", `service FooService {
+  rpc get_foo(GetFooRequest) returns (GetFooResponse);
+}`, `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`),
+	"RPC_REQUEST_RESPONSE_UNIQUE": {
+		Rationale: `Sharing request or response messages between RPCs means that a field cannot
+be added for one RPC without affecting the others. Use a dedicated message for
+each. This is configurable with rpc_allow_same_request_response,
+rpc_allow_google_protobuf_empty_requests, and
+rpc_allow_google_protobuf_empty_responses.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(FooRequest) returns (FooResponse);
+  rpc DeleteFoo(FooRequest) returns (FooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+  rpc DeleteFoo(DeleteFooRequest) returns (DeleteFooResponse);
+}`,
+	},
+	"RPC_REQUEST_STANDARD_NAME": {
+		Rationale: `Naming request messages after their RPC makes it obvious which RPC a message
+belongs to. Using google.protobuf.Empty as a request is configurable with
+rpc_allow_google_protobuf_empty_requests.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(Foo) returns (GetFooResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+	},
+	"RPC_RESPONSE_STANDARD_NAME": {
+		Rationale: `Naming response messages after their RPC makes it obvious which RPC a message
+belongs to. Using google.protobuf.Empty as a response is configurable with
+rpc_allow_google_protobuf_empty_responses.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (Foo);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}`,
+	},
+	"SERVICE_MAX_RPCS": {
+		Rationale: `Services with a very large number of RPCs are hard to navigate, and generate
+large clients and servers. Such services are usually better split up by resource.
+The maximum is configured with service_max_rpcs, and defaults to 50. The examples
+use a maximum of 1.`,
+		FailingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+  rpc GetBar(GetBarRequest) returns (GetBarResponse);
+}`,
+		PassingExample: `service FooService {
+  rpc GetFoo(GetFooRequest) returns (GetFooResponse);
+}
+
+service BarService {
+  rpc GetBar(GetBarRequest) returns (GetBarResponse);
+}`,
+	},
+	"SERVICE_NAME_PATTERN": newNamePatternDoc("service", "service_name_pattern", "PascalCase that allows acronyms", `service foo_service {}`, `service HTTPService {}`),
+	"SERVICE_PASCAL_CASE":  newCaseDoc("service", "PascalCase", `service foo_service {}`, `service FooService {}`),
+	"SERVICE_SUFFIX": {
+		Rationale: `A consistent suffix, Service by default, distinguishes services from messages
+in generated code and documentation. The suffix is configurable with
+service_suffix.`,
+		FailingExample: `service Foo {}`,
+		PassingExample: `service FooService {}`,
+	},
+	"VALIDATE_RULES_BOUNDS": {
+		Rationale: `protoc-gen-validate constraints with contradictory bounds can never be
+satisfied, such as a const outside of gt and lt, so every message would fail validation.`,
+		FailingExample: `message Foo {
+  int32 count = 1 [(validate.rules).int32 = {const: 20, lt: 10}];
+}`,
+		PassingExample: `message Foo {
+  int32 count = 1 [(validate.rules).int32 = {gt: 5, lt: 10}];
+}`,
+	},
+	"VALIDATE_RULES_SYNTAX": {
+		Rationale: `protoc-gen-validate constraints that are malformed, such as patterns that are
+not valid RE2 regular expressions, fail at generation time or at runtime.`,
+		FailingExample: `message Foo {
+  string name = 1 [(validate.rules).string.pattern = "^[a-z+$"];
+}`,
+		PassingExample: `message Foo {
+  string name = 1 [(validate.rules).string.pattern = "^[a-z]+$"];
+}`,
+	},
+	"VALIDATE_RULES_TYPE_MATCH": {
+		Rationale: `protoc-gen-validate constraints for one type on a field of another type are
+rejected by the plugin at generation time.`,
+		FailingExample: `message Foo {
+  int32 count = 1 [(validate.rules).string.min_len = 1];
+}`,
+		PassingExample: `message Foo {
+  string name = 1 [(validate.rules).string.min_len = 1];
+}`,
+	},
+}
+
+func newCommentDoc(elementName string, failingExample string, passingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Comments on each ` + elementName + ` are carried into generated code and
+documentation, where they are often the only explanation of what the ` + elementName + `
+is for.`,
+		FailingExample: failingExample,
+		PassingExample: passingExample,
+	}
+}
+
+func newCaseDoc(elementName string, caseName string, failingExample string, passingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Consistent ` + caseName + ` names for each ` + elementName + ` follow the Protobuf
+style guide, and allow code generators to produce idiomatic names in every language.`,
+		FailingExample: failingExample,
+		PassingExample: passingExample,
+	}
+}
+
+func newNamePatternDoc(elementName string, configKey string, defaultName string, failingExample string, passingExample string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `Organizations whose naming conventions differ from the Protobuf style guide,
+for example by allowing acronyms, can still enforce consistent ` + elementName + ` names. The
+pattern is a regular expression configured with ` + configKey + `, and defaults to
+` + defaultName + `.`,
+		FailingExample: failingExample,
+		PassingExample: passingExample,
+	}
+}
+
+func newPackageSameOptionDoc(optionName string, option string, otherOption string) *bufcheck.Doc {
+	return &bufcheck.Doc{
+		Rationale: `All files in a package should generate code into the same place. If the
+` + optionName + ` option differs between files of a package, the generated code for
+the package is split or does not compile.`,
+		FailingExample: `// foo/v1/a.proto
+package foo.v1;
+` + option + `
+
+// foo/v1/b.proto
+package foo.v1;
+` + otherOption,
+		PassingExample: `// foo/v1/a.proto
+package foo.v1;
+` + option + `
+
+// foo/v1/b.proto
+package foo.v1;
+` + option,
+	}
+}
@@ -48,5 +48,19 @@ func (h *handler) Check(
 	if err != nil {
 		return nil, err
 	}
-	return h.runner.Check(ctx, configToInternalConfig(config), nil, files)
+	internalConfig := configToInternalConfig(config)
+	fileAnnotations, err := h.runner.Check(ctx, internalConfig, nil, files)
+	if err != nil {
+		return nil, err
+	}
+	pluginFileAnnotations, err := h.runner.CheckPlugins(ctx, internalConfig, nil, image)
+	if err != nil {
+		return nil, err
+	}
+	if len(pluginFileAnnotations) == 0 {
+		return fileAnnotations, nil
+	}
+	fileAnnotations = append(fileAnnotations, pluginFileAnnotations...)
+	bufanalysis.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, nil
 }
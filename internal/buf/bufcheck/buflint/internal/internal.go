@@ -16,8 +16,10 @@ package internal
 
 import (
 	"errors"
+	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
@@ -87,6 +89,65 @@ func checkCommentNamedDescriptor(
 	return nil
 }
 
+// CheckDeprecatedComment is a check function.
+var CheckDeprecatedComment = newFileCheckFunc(checkDeprecatedComment)
+
+func checkDeprecatedComment(add addFunc, file protosource.File) error {
+	if err := protosource.ForEachEnum(
+		func(enum protosource.Enum) error {
+			checkDeprecatedCommentNamedDescriptor(add, enum, enum.Deprecated(), "Enum")
+			for _, enumValue := range enum.Values() {
+				checkDeprecatedCommentNamedDescriptor(add, enumValue, enumValue.Deprecated(), "Enum value")
+			}
+			return nil
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	if err := protosource.ForEachMessage(
+		func(message protosource.Message) error {
+			checkDeprecatedCommentNamedDescriptor(add, message, message.Deprecated(), "Message")
+			for _, field := range message.Fields() {
+				checkDeprecatedCommentNamedDescriptor(add, field, field.Deprecated(), "Field")
+			}
+			for _, field := range message.Extensions() {
+				checkDeprecatedCommentNamedDescriptor(add, field, field.Deprecated(), "Extension")
+			}
+			return nil
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	for _, service := range file.Services() {
+		checkDeprecatedCommentNamedDescriptor(add, service, service.Deprecated(), "Service")
+		for _, method := range service.Methods() {
+			checkDeprecatedCommentNamedDescriptor(add, method, method.Deprecated(), "RPC")
+		}
+	}
+	return nil
+}
+
+func checkDeprecatedCommentNamedDescriptor(
+	add addFunc,
+	namedDescriptor protosource.NamedDescriptor,
+	deprecated bool,
+	typeName string,
+) {
+	if !deprecated {
+		return
+	}
+	location := namedDescriptor.Location()
+	if location == nil {
+		return
+	}
+	comments := location.LeadingComments() + location.TrailingComments()
+	if !strings.Contains(strings.ToLower(comments), "deprecated") {
+		add(namedDescriptor, namedDescriptor.NameLocation(), "%s %q is deprecated and should have a comment that starts with \"Deprecated:\" and explains what to use instead.", typeName, namedDescriptor.Name())
+	}
+}
+
 // CheckDirectorySamePackage is a check function.
 var CheckDirectorySamePackage = newDirToFilesCheckFunc(checkDirectorySamePackage)
 
@@ -105,6 +166,27 @@ func checkDirectorySamePackage(add addFunc, dirPath string, files []protosource.
 	return nil
 }
 
+// CheckEnumMaxValues is a check function.
+var CheckEnumMaxValues = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	maxValues int,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newEnumCheckFunc(
+		func(add addFunc, enum protosource.Enum) error {
+			return checkEnumMaxValues(add, enum, maxValues)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkEnumMaxValues(add addFunc, enum protosource.Enum, maxValues int) error {
+	if numValues := len(enum.Values()); numValues > maxValues {
+		add(enum, enum.NameLocation(), "Enum %q has %d values, which is more than the maximum of %d.", enum.Name(), numValues, maxValues)
+	}
+	return nil
+}
+
 // CheckEnumNoAllowAlias is a check function.
 var CheckEnumNoAllowAlias = newEnumCheckFunc(checkEnumNoAllowAlias)
 
@@ -127,6 +209,41 @@ func checkEnumPascalCase(add addFunc, enum protosource.Enum) error {
 	return nil
 }
 
+// CheckEnumUnused is a check function.
+var CheckEnumUnused = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	entryPoints []string,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protosource.File) error {
+			return checkEnumUnused(add, files, entryPoints)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkEnumUnused(add addFunc, files []protosource.File, entryPoints []string) error {
+	reachableTypeNames, err := getReachableTypeNames(files, entryPoints)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := protosource.ForEachEnum(
+			func(enum protosource.Enum) error {
+				if _, ok := reachableTypeNames[enum.FullName()]; !ok {
+					add(enum, enum.NameLocation(), "Enum %q is not reachable from any RPC or entry point.", enum.FullName())
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CheckEnumFirstValueZero is a check function.
 var CheckEnumFirstValueZero = newEnumCheckFunc(checkEnumFirstValueZero)
 
@@ -141,25 +258,25 @@ func checkEnumFirstValueZero(add addFunc, enum protosource.Enum) error {
 }
 
 // CheckEnumValuePrefix is a check function.
-var CheckEnumValuePrefix = newEnumValueCheckFunc(checkEnumValuePrefix)
+var CheckEnumValuePrefix = newEnumValueFixCheckFunc(checkEnumValuePrefix)
 
-func checkEnumValuePrefix(add addFunc, enumValue protosource.EnumValue) error {
+func checkEnumValuePrefix(addFix addFixFunc, enumValue protosource.EnumValue) error {
 	name := enumValue.Name()
 	expectedPrefix := fieldToUpperSnakeCase(enumValue.Enum().Name()) + "_"
 	if !strings.HasPrefix(name, expectedPrefix) {
-		add(enumValue, enumValue.NameLocation(), "Enum value name %q should be prefixed with %q.", name, expectedPrefix)
+		addFix(enumValue, enumValue.NameLocation(), expectedPrefix+name, "Enum value name %q should be prefixed with %q.", name, expectedPrefix)
 	}
 	return nil
 }
 
 // CheckEnumValueUpperSnakeCase is a check function.
-var CheckEnumValueUpperSnakeCase = newEnumValueCheckFunc(checkEnumValueUpperSnakeCase)
+var CheckEnumValueUpperSnakeCase = newEnumValueFixCheckFunc(checkEnumValueUpperSnakeCase)
 
-func checkEnumValueUpperSnakeCase(add addFunc, enumValue protosource.EnumValue) error {
+func checkEnumValueUpperSnakeCase(addFix addFixFunc, enumValue protosource.EnumValue) error {
 	name := enumValue.Name()
 	expectedName := fieldToUpperSnakeCase(name)
 	if name != expectedName {
-		add(enumValue, enumValue.NameLocation(), "Enum value name %q should be UPPER_SNAKE_CASE, such as %q.", name, expectedName)
+		addFix(enumValue, enumValue.NameLocation(), expectedName, "Enum value name %q should be UPPER_SNAKE_CASE, such as %q.", name, expectedName)
 	}
 	return nil
 }
@@ -171,28 +288,70 @@ var CheckEnumZeroValueSuffix = func(
 	files []protosource.File,
 	suffix string,
 ) ([]bufanalysis.FileAnnotation, error) {
-	return newEnumValueCheckFunc(
-		func(add addFunc, enumValue protosource.EnumValue) error {
-			return checkEnumZeroValueSuffix(add, enumValue, suffix)
+	return newEnumValueFixCheckFunc(
+		func(addFix addFixFunc, enumValue protosource.EnumValue) error {
+			return checkEnumZeroValueSuffix(addFix, enumValue, suffix)
 		},
 	)(id, ignoreFunc, files)
 }
 
-func checkEnumZeroValueSuffix(add addFunc, enumValue protosource.EnumValue, suffix string) error {
+func checkEnumZeroValueSuffix(addFix addFixFunc, enumValue protosource.EnumValue, suffix string) error {
 	if enumValue.Number() != 0 {
 		return nil
 	}
 	name := enumValue.Name()
 	if !strings.HasSuffix(name, suffix) {
-		add(enumValue, enumValue.NameLocation(), "Enum zero value name %q should be suffixed with %q.", name, suffix)
+		addFix(enumValue, enumValue.NameLocation(), name+suffix, "Enum zero value name %q should be suffixed with %q.", name, suffix)
+	}
+	return nil
+}
+
+// CheckFieldDeprecatedType is a check function.
+var CheckFieldDeprecatedType = newFilesCheckFunc(checkFieldDeprecatedType)
+
+func checkFieldDeprecatedType(add addFunc, files []protosource.File) error {
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return err
+	}
+	fullNameToEnum, err := protosource.FullNameToEnum(files...)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := protosource.ForEachMessage(
+			func(message protosource.Message) error {
+				if message.IsMapEntry() || messageIsDeprecated(message) {
+					// map entries are generated, and all fields of a deprecated
+					// message are deprecated
+					return nil
+				}
+				for _, field := range message.Fields() {
+					if field.Deprecated() {
+						continue
+					}
+					// we only know the types defined in the files being checked
+					typeName := strings.TrimPrefix(field.TypeName(), ".")
+					if typeMessage, ok := fullNameToMessage[typeName]; ok && typeMessage.Deprecated() {
+						add(field, field.TypeNameLocation(), "Field %q uses the deprecated message %q and should be deprecated.", field.Name(), typeName)
+					} else if typeEnum, ok := fullNameToEnum[typeName]; ok && typeEnum.Deprecated() {
+						add(field, field.TypeNameLocation(), "Field %q uses the deprecated enum %q and should be deprecated.", field.Name(), typeName)
+					}
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 // CheckFieldLowerSnakeCase is a check function.
-var CheckFieldLowerSnakeCase = newFieldCheckFunc(checkFieldLowerSnakeCase)
+var CheckFieldLowerSnakeCase = newFieldFixCheckFunc(checkFieldLowerSnakeCase)
 
-func checkFieldLowerSnakeCase(add addFunc, field protosource.Field) error {
+func checkFieldLowerSnakeCase(addFix addFixFunc, field protosource.Field) error {
 	message := field.Message()
 	if message == nil {
 		// just a sanity check
@@ -205,7 +364,38 @@ func checkFieldLowerSnakeCase(add addFunc, field protosource.Field) error {
 	name := field.Name()
 	expectedName := fieldToLowerSnakeCase(name)
 	if name != expectedName {
-		add(field, field.NameLocation(), "Field name %q should be lower_snake_case, such as %q.", name, expectedName)
+		addFix(field, field.NameLocation(), expectedName, "Field name %q should be lower_snake_case, such as %q.", name, expectedName)
+	}
+	return nil
+}
+
+// CheckFieldNamePattern is a check function.
+var CheckFieldNamePattern = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	pattern *regexp.Regexp,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFieldCheckFunc(
+		func(add addFunc, field protosource.Field) error {
+			return checkFieldNamePattern(add, field, pattern)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkFieldNamePattern(add addFunc, field protosource.Field, pattern *regexp.Regexp) error {
+	message := field.Message()
+	if message == nil {
+		// just a sanity check
+		return errors.New("field.Message() was nil")
+	}
+	if message.IsMapEntry() {
+		// map entry fields are always key and value
+		return nil
+	}
+	name := field.Name()
+	if !pattern.MatchString(name) {
+		add(field, field.NameLocation(), "Field name %q should match the pattern %q.", name, pattern.String())
 	}
 	return nil
 }
@@ -236,6 +426,158 @@ func checkFileLowerSnakeCase(add addFunc, file protosource.File) error {
 	return nil
 }
 
+// CheckExtensionUnused is a check function.
+var CheckExtensionUnused = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	entryPoints []string,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protosource.File) error {
+			return checkExtensionUnused(add, files, entryPoints)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkExtensionUnused(add addFunc, files []protosource.File, entryPoints []string) error {
+	reachableTypeNames, err := getReachableTypeNames(files, entryPoints)
+	if err != nil {
+		return err
+	}
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return err
+	}
+	return forEachExtension(
+		func(extension protosource.Field) error {
+			extendee := strings.TrimPrefix(extension.Extendee(), ".")
+			if _, ok := fullNameToMessage[extendee]; !ok {
+				// we only know if the messages in the files being checked are reachable
+				return nil
+			}
+			if _, ok := reachableTypeNames[extendee]; !ok {
+				add(extension, extension.NameLocation(), "Extension %q extends message %q, which is not reachable from any RPC or entry point.", extension.FullName(), extendee)
+			}
+			return nil
+		},
+		files,
+	)
+}
+
+// CheckImportAllowed is a check function.
+var CheckImportAllowed = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	importRules []internal.ImportRule,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protosource.File) error {
+			return checkImportAllowed(add, files, importRules)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkImportAllowed(add addFunc, files []protosource.File, importRules []internal.ImportRule) error {
+	if len(importRules) == 0 {
+		return nil
+	}
+	filePathToFile, err := protosource.FilePathToFile(files...)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		pkg := file.Package()
+		for _, fileImport := range file.FileImports() {
+			importFile, ok := filePathToFile[fileImport.Import()]
+			if !ok {
+				// we only know the packages of the files being checked
+				continue
+			}
+			importPkg := importFile.Package()
+			if importPkg == pkg {
+				continue
+			}
+			if !fileIsAllowedByImportRules(file, importFile, importRules) {
+				add(fileImport, fileImport.Location(), `Import %q of package %q is not allowed from package %q.`, fileImport.Import(), importPkg, pkg)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckImportTransitiveAllowed is a check function.
+var CheckImportTransitiveAllowed = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	importRules []internal.ImportRule,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protosource.File) error {
+			return checkImportTransitiveAllowed(add, files, importRules)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkImportTransitiveAllowed(add addFunc, files []protosource.File, importRules []internal.ImportRule) error {
+	if len(importRules) == 0 {
+		return nil
+	}
+	filePathToFile, err := protosource.FilePathToFile(files...)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		pkg := file.Package()
+		// direct imports are checked by IMPORT_ALLOWED, and each transitive
+		// import is only reported for the first import it is found through
+		seenPaths := map[string]struct{}{
+			file.Path(): {},
+		}
+		for _, fileImport := range file.FileImports() {
+			seenPaths[fileImport.Import()] = struct{}{}
+		}
+		for _, fileImport := range file.FileImports() {
+			importFile, ok := filePathToFile[fileImport.Import()]
+			if !ok {
+				continue
+			}
+			// breadth-first so that the shortest chain of imports is reported
+			chains := [][]protosource.File{{importFile}}
+			for len(chains) > 0 {
+				chain := chains[0]
+				chains = chains[1:]
+				for _, transitiveFileImport := range chain[len(chain)-1].FileImports() {
+					transitiveFile, ok := filePathToFile[transitiveFileImport.Import()]
+					if !ok {
+						continue
+					}
+					if _, ok := seenPaths[transitiveFile.Path()]; ok {
+						continue
+					}
+					seenPaths[transitiveFile.Path()] = struct{}{}
+					transitiveChain := append(append(make([]protosource.File, 0, len(chain)+1), chain...), transitiveFile)
+					if transitivePkg := transitiveFile.Package(); transitivePkg != pkg && !fileIsAllowedByImportRules(file, transitiveFile, importRules) {
+						add(
+							fileImport,
+							fileImport.Location(),
+							`Import %q transitively imports package %q through %s, which is not allowed from package %q.`,
+							fileImport.Import(),
+							transitivePkg,
+							getImportChainString(transitiveChain),
+							pkg,
+						)
+					}
+					chains = append(chains, transitiveChain)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 var (
 	// CheckImportNoPublic is a check function.
 	CheckImportNoPublic = newFileImportCheckFunc(checkImportNoPublic)
@@ -258,6 +600,85 @@ func checkImportNoPublicWeak(add addFunc, fileImport protosource.FileImport, val
 	return nil
 }
 
+// CheckMessageMaxFields is a check function.
+var CheckMessageMaxFields = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	maxFields int,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMessageCheckFunc(
+		func(add addFunc, message protosource.Message) error {
+			return checkMessageMaxFields(add, message, maxFields)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkMessageMaxFields(add addFunc, message protosource.Message, maxFields int) error {
+	if numFields := len(message.Fields()); numFields > maxFields {
+		add(message, message.NameLocation(), "Message %q has %d fields, which is more than the maximum of %d.", message.Name(), numFields, maxFields)
+	}
+	return nil
+}
+
+// CheckMessageMaxNestingDepth is a check function.
+var CheckMessageMaxNestingDepth = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	maxNestingDepth int,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMessageCheckFunc(
+		func(add addFunc, message protosource.Message) error {
+			return checkMessageMaxNestingDepth(add, message, maxNestingDepth)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkMessageMaxNestingDepth(add addFunc, message protosource.Message, maxNestingDepth int) error {
+	if message.IsMapEntry() {
+		// map entries are generated, the map field is what is nested
+		return nil
+	}
+	// top-level messages have a nesting depth of 1
+	nestingDepth := 1
+	for parent := message.Parent(); parent != nil; parent = parent.Parent() {
+		nestingDepth++
+	}
+	// only flag the first message past the limit, the messages nested under it
+	// are fixed along with it
+	if nestingDepth == maxNestingDepth+1 {
+		add(message, message.NameLocation(), "Message %q is nested %d levels deep, which is more than the maximum of %d.", message.NestedName(), nestingDepth, maxNestingDepth)
+	}
+	return nil
+}
+
+// CheckMessageNamePattern is a check function.
+var CheckMessageNamePattern = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	pattern *regexp.Regexp,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMessageCheckFunc(
+		func(add addFunc, message protosource.Message) error {
+			return checkMessageNamePattern(add, message, pattern)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkMessageNamePattern(add addFunc, message protosource.Message, pattern *regexp.Regexp) error {
+	if message.IsMapEntry() {
+		// map entry names are generated
+		return nil
+	}
+	name := message.Name()
+	if !pattern.MatchString(name) {
+		add(message, message.NameLocation(), "Message name %q should match the pattern %q.", name, pattern.String())
+	}
+	return nil
+}
+
 // CheckMessagePascalCase is a check function.
 var CheckMessagePascalCase = newMessageCheckFunc(checkMessagePascalCase)
 
@@ -274,14 +695,59 @@ func checkMessagePascalCase(add addFunc, message protosource.Message) error {
 	return nil
 }
 
+// CheckMessageUnused is a check function.
+var CheckMessageUnused = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	entryPoints []string,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesCheckFunc(
+		func(add addFunc, files []protosource.File) error {
+			return checkMessageUnused(add, files, entryPoints)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkMessageUnused(add addFunc, files []protosource.File, entryPoints []string) error {
+	reachableTypeNames, err := getReachableTypeNames(files, entryPoints)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := protosource.ForEachMessage(
+			func(message protosource.Message) error {
+				if message.IsMapEntry() {
+					// map entries are reachable if the message that contains them is
+					return nil
+				}
+				if _, ok := reachableTypeNames[message.FullName()]; ok {
+					return nil
+				}
+				// a message that only serves as a namespace for reachable nested
+				// types is still used
+				if containerHasReachableType(message, reachableTypeNames) {
+					return nil
+				}
+				add(message, message.NameLocation(), "Message %q is not reachable from any RPC or entry point.", message.FullName())
+				return nil
+			},
+			file,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CheckOneofLowerSnakeCase is a check function.
-var CheckOneofLowerSnakeCase = newOneofCheckFunc(checkOneofLowerSnakeCase)
+var CheckOneofLowerSnakeCase = newOneofFixCheckFunc(checkOneofLowerSnakeCase)
 
-func checkOneofLowerSnakeCase(add addFunc, oneof protosource.Oneof) error {
+func checkOneofLowerSnakeCase(addFix addFixFunc, oneof protosource.Oneof) error {
 	name := oneof.Name()
 	expectedName := fieldToLowerSnakeCase(name)
 	if name != expectedName {
-		add(oneof, oneof.NameLocation(), "Oneof name %q should be lower_snake_case, such as %q.", name, expectedName)
+		addFix(oneof, oneof.NameLocation(), expectedName, "Oneof name %q should be lower_snake_case, such as %q.", name, expectedName)
 	}
 	return nil
 }
@@ -352,32 +818,33 @@ func checkPackageSameDirectory(add addFunc, pkg string, files []protosource.File
 
 var (
 	// CheckPackageSameCsharpNamespace is a check function.
-	CheckPackageSameCsharpNamespace = newPackageToFilesCheckFunc(checkPackageSameCsharpNamespace)
+	CheckPackageSameCsharpNamespace = newPackageToFilesFixCheckFunc(checkPackageSameCsharpNamespace)
 	// CheckPackageSameGoPackage is a check function.
-	CheckPackageSameGoPackage = newPackageToFilesCheckFunc(checkPackageSameGoPackage)
+	CheckPackageSameGoPackage = newPackageToFilesFixCheckFunc(checkPackageSameGoPackage)
 	// CheckPackageSameJavaMultipleFiles is a check function.
-	CheckPackageSameJavaMultipleFiles = newPackageToFilesCheckFunc(checkPackageSameJavaMultipleFiles)
+	CheckPackageSameJavaMultipleFiles = newPackageToFilesFixCheckFunc(checkPackageSameJavaMultipleFiles)
 	// CheckPackageSameJavaPackage is a check function.
-	CheckPackageSameJavaPackage = newPackageToFilesCheckFunc(checkPackageSameJavaPackage)
+	CheckPackageSameJavaPackage = newPackageToFilesFixCheckFunc(checkPackageSameJavaPackage)
 	// CheckPackageSamePhpNamespace is a check function.
-	CheckPackageSamePhpNamespace = newPackageToFilesCheckFunc(checkPackageSamePhpNamespace)
+	CheckPackageSamePhpNamespace = newPackageToFilesFixCheckFunc(checkPackageSamePhpNamespace)
 	// CheckPackageSameRubyPackage is a check function.
-	CheckPackageSameRubyPackage = newPackageToFilesCheckFunc(checkPackageSameRubyPackage)
+	CheckPackageSameRubyPackage = newPackageToFilesFixCheckFunc(checkPackageSameRubyPackage)
 	// CheckPackageSameSwiftPrefix is a check function.
-	CheckPackageSameSwiftPrefix = newPackageToFilesCheckFunc(checkPackageSameSwiftPrefix)
+	CheckPackageSameSwiftPrefix = newPackageToFilesFixCheckFunc(checkPackageSameSwiftPrefix)
 )
 
-func checkPackageSameCsharpNamespace(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.CsharpNamespace, protosource.File.CsharpNamespaceLocation, "csharp_namespace")
+func checkPackageSameCsharpNamespace(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.CsharpNamespace, protosource.File.CsharpNamespaceLocation, "csharp_namespace", strconv.Quote)
 }
 
-func checkPackageSameGoPackage(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.GoPackage, protosource.File.GoPackageLocation, "go_package")
+func checkPackageSameGoPackage(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.GoPackage, protosource.File.GoPackageLocation, "go_package", strconv.Quote)
 }
 
-func checkPackageSameJavaMultipleFiles(add addFunc, pkg string, files []protosource.File) error {
+func checkPackageSameJavaMultipleFiles(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
 	return checkPackageSameOptionValue(
 		add,
+		addFix,
 		pkg,
 		files,
 		func(file protosource.File) string {
@@ -385,46 +852,74 @@ func checkPackageSameJavaMultipleFiles(add addFunc, pkg string, files []protosou
 		},
 		protosource.File.JavaMultipleFilesLocation,
 		"java_multiple_files",
+		func(optionValue string) string {
+			return optionValue
+		},
 	)
 }
 
-func checkPackageSameJavaPackage(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.JavaPackage, protosource.File.JavaPackageLocation, "java_package")
+func checkPackageSameJavaPackage(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.JavaPackage, protosource.File.JavaPackageLocation, "java_package", strconv.Quote)
 }
 
-func checkPackageSamePhpNamespace(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.PhpNamespace, protosource.File.PhpNamespaceLocation, "php_namespace")
+func checkPackageSamePhpNamespace(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.PhpNamespace, protosource.File.PhpNamespaceLocation, "php_namespace", strconv.Quote)
 }
 
-func checkPackageSameRubyPackage(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.RubyPackage, protosource.File.RubyPackageLocation, "ruby_package")
+func checkPackageSameRubyPackage(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.RubyPackage, protosource.File.RubyPackageLocation, "ruby_package", strconv.Quote)
 }
 
-func checkPackageSameSwiftPrefix(add addFunc, pkg string, files []protosource.File) error {
-	return checkPackageSameOptionValue(add, pkg, files, protosource.File.SwiftPrefix, protosource.File.SwiftPrefixLocation, "swift_prefix")
+func checkPackageSameSwiftPrefix(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error {
+	return checkPackageSameOptionValue(add, addFix, pkg, files, protosource.File.SwiftPrefix, protosource.File.SwiftPrefixLocation, "swift_prefix", strconv.Quote)
 }
 
+// checkPackageSameOptionValue checks that all files have the same value for
+// the option.
+//
+// If a strict majority of the files have the same value, the other files that
+// set the option are fixed by setting it to this value, formatted with
+// formatOptionValue.
 func checkPackageSameOptionValue(
 	add addFunc,
+	addFix addFixFunc,
 	pkg string,
 	files []protosource.File,
 	getOptionValue func(protosource.File) string,
 	getOptionLocation func(protosource.File) protosource.Location,
 	name string,
+	formatOptionValue func(string) string,
 ) error {
-	optionValueMap := make(map[string]struct{})
+	optionValueToCount := make(map[string]int)
 	for _, file := range files {
-		optionValueMap[getOptionValue(file)] = struct{}{}
+		optionValueToCount[getOptionValue(file)]++
 	}
-	if len(optionValueMap) > 1 {
-		_, noOptionValue := optionValueMap[""]
-		delete(optionValueMap, "")
+	if len(optionValueToCount) > 1 {
+		majorityOptionValue := ""
+		for optionValue, count := range optionValueToCount {
+			if count > len(files)/2 {
+				majorityOptionValue = optionValue
+			}
+		}
+		_, noOptionValue := optionValueToCount[""]
+		optionValueMap := make(map[string]struct{}, len(optionValueToCount))
+		for optionValue := range optionValueToCount {
+			if optionValue != "" {
+				optionValueMap[optionValue] = struct{}{}
+			}
+		}
 		optionValues := stringutil.MapToSortedSlice(optionValueMap)
 		for _, file := range files {
+			format := "Files in package %q have multiple values %q for option %q and all values must be equal."
 			if noOptionValue {
-				add(file, getOptionLocation(file), "Files in package %q have both values %q and no value for option %q and all values must be equal.", pkg, strings.Join(optionValues, ","), name)
+				format = "Files in package %q have both values %q and no value for option %q and all values must be equal."
+			}
+			optionValue := getOptionValue(file)
+			if majorityOptionValue != "" && optionValue != majorityOptionValue && optionValue != "" {
+				replacement := "option " + name + " = " + formatOptionValue(majorityOptionValue) + ";"
+				addFix(file, getOptionLocation(file), replacement, format, pkg, strings.Join(optionValues, ","), name)
 			} else {
-				add(file, getOptionLocation(file), "Files in package %q have multiple values %q for option %q and all values must be equal.", pkg, strings.Join(optionValues, ","), name)
+				add(file, getOptionLocation(file), format, pkg, strings.Join(optionValues, ","), name)
 			}
 		}
 	}
@@ -432,19 +927,50 @@ func checkPackageSameOptionValue(
 }
 
 // CheckPackageVersionSuffix is a check function.
-var CheckPackageVersionSuffix = newFileCheckFunc(checkPackageVersionSuffix)
+var CheckPackageVersionSuffix = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	forms map[string]struct{},
+	allowUnversionedRootPaths map[string]struct{},
+) ([]bufanalysis.FileAnnotation, error) {
+	return newFileCheckFunc(
+		func(add addFunc, file protosource.File) error {
+			return checkPackageVersionSuffix(add, file, forms, allowUnversionedRootPaths)
+		},
+	)(id, ignoreFunc, files)
+}
 
-func checkPackageVersionSuffix(add addFunc, file protosource.File) error {
+func checkPackageVersionSuffix(
+	add addFunc,
+	file protosource.File,
+	forms map[string]struct{},
+	allowUnversionedRootPaths map[string]struct{},
+) error {
 	pkg := file.Package()
 	if pkg == "" {
 		return nil
 	}
-	if !packageHasVersionSuffix(pkg) {
+	if packageIsUnversioned(pkg) && normalpath.MapHasEqualOrContainingPath(allowUnversionedRootPaths, file.Path(), normalpath.Relative) {
+		return nil
+	}
+	if !packageHasVersionSuffixForForms(pkg, forms) {
 		add(file, file.PackageLocation(), `Package name %q should be suffixed with a correctly formed version, such as %q.`, pkg, pkg+".v1")
 	}
 	return nil
 }
 
+// CheckPackageVersionDirectoryMatch is a check function.
+var CheckPackageVersionDirectoryMatch = newFileCheckFunc(checkPackageVersionDirectoryMatch)
+
+func checkPackageVersionDirectoryMatch(add addFunc, file protosource.File) error {
+	pkg := file.Package()
+	if pkg == "" || packageIsUnversioned(pkg) {
+		return nil
+	}
+	return checkPackageDirectoryMatch(add, file)
+}
+
 // CheckRPCNoClientStreaming is a check function.
 var CheckRPCNoClientStreaming = newMethodCheckFunc(checkRPCNoClientStreaming)
 
@@ -477,6 +1003,57 @@ func checkRPCPascalCase(add addFunc, method protosource.Method) error {
 	return nil
 }
 
+// CheckRPCIdempotencyLevelDefined is a check function.
+var CheckRPCIdempotencyLevelDefined = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	disallowUnknown bool,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMethodCheckFunc(
+		func(add addFunc, method protosource.Method) error {
+			return checkRPCIdempotencyLevelDefined(add, method, disallowUnknown)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkRPCIdempotencyLevelDefined(add addFunc, method protosource.Method, disallowUnknown bool) error {
+	if !method.IdempotencyLevelIsSet() {
+		add(method, method.NameLocation(), "RPC %q should set the idempotency_level option.", method.Name())
+		return nil
+	}
+	if disallowUnknown && method.IdempotencyLevel() == protosource.MethodOptionsIdempotencyLevelIdempotencyUnknown {
+		add(method, method.IdempotencyLevelLocation(), "RPC %q should set the idempotency_level option to a value other than %s.", method.Name(), method.IdempotencyLevel().String())
+	}
+	return nil
+}
+
+// CheckRPCNameVerb is a check function.
+var CheckRPCNameVerb = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	verbs []string,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newMethodCheckFunc(
+		func(add addFunc, method protosource.Method) error {
+			return checkRPCNameVerb(add, method, verbs)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkRPCNameVerb(add addFunc, method protosource.Method, verbs []string) error {
+	name := method.Name()
+	for _, verb := range verbs {
+		// the verb must be followed by a noun that starts a new word
+		if strings.HasPrefix(name, verb) && len(name) > len(verb) && unicode.IsUpper(rune(name[len(verb)])) {
+			return nil
+		}
+	}
+	add(method, method.NameLocation(), "RPC name %q should be a verb followed by a noun, where the verb is one of %s.", name, stringutil.SliceToString(verbs))
+	return nil
+}
+
 // CheckRPCRequestResponseUnique is a check function.
 var CheckRPCRequestResponseUnique = func(
 	id string,
@@ -661,6 +1238,49 @@ func checkServicePascalCase(add addFunc, service protosource.Service) error {
 	return nil
 }
 
+// CheckServiceMaxRPCs is a check function.
+var CheckServiceMaxRPCs = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	maxRPCs int,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newServiceCheckFunc(
+		func(add addFunc, service protosource.Service) error {
+			return checkServiceMaxRPCs(add, service, maxRPCs)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkServiceMaxRPCs(add addFunc, service protosource.Service, maxRPCs int) error {
+	if numRPCs := len(service.Methods()); numRPCs > maxRPCs {
+		add(service, service.NameLocation(), "Service %q has %d RPCs, which is more than the maximum of %d.", service.Name(), numRPCs, maxRPCs)
+	}
+	return nil
+}
+
+// CheckServiceNamePattern is a check function.
+var CheckServiceNamePattern = func(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	pattern *regexp.Regexp,
+) ([]bufanalysis.FileAnnotation, error) {
+	return newServiceCheckFunc(
+		func(add addFunc, service protosource.Service) error {
+			return checkServiceNamePattern(add, service, pattern)
+		},
+	)(id, ignoreFunc, files)
+}
+
+func checkServiceNamePattern(add addFunc, service protosource.Service, pattern *regexp.Regexp) error {
+	name := service.Name()
+	if !pattern.MatchString(name) {
+		add(service, service.NameLocation(), "Service name %q should match the pattern %q.", name, pattern.String())
+	}
+	return nil
+}
+
 // CheckServiceSuffix is a check function.
 var CheckServiceSuffix = func(
 	id string,
@@ -682,3 +1302,37 @@ func checkServiceSuffix(add addFunc, service protosource.Service, suffix string)
 	}
 	return nil
 }
+
+// CheckValidateRulesBounds is a check function.
+var CheckValidateRulesBounds = newFieldCheckFunc(
+	func(add addFunc, field protosource.Field) error {
+		return checkValidateRules(add, field, validateProblemKindBounds)
+	},
+)
+
+// CheckValidateRulesSyntax is a check function.
+var CheckValidateRulesSyntax = newFieldCheckFunc(
+	func(add addFunc, field protosource.Field) error {
+		return checkValidateRules(add, field, validateProblemKindSyntax)
+	},
+)
+
+// CheckValidateRulesTypeMatch is a check function.
+var CheckValidateRulesTypeMatch = newFieldCheckFunc(
+	func(add addFunc, field protosource.Field) error {
+		return checkValidateRules(add, field, validateProblemKindTypeMatch)
+	},
+)
+
+func checkValidateRules(add addFunc, field protosource.Field, kind validateProblemKind) error {
+	location := field.OptionExtensionLocation(validateRulesFieldNumber)
+	if location == nil {
+		location = field.Location()
+	}
+	for _, problem := range getValidateProblems(field) {
+		if problem.kind == kind {
+			add(field, location, "Field %q has invalid validate.rules: %s.", field.Name(), problem.message)
+		}
+	}
+	return nil
+}
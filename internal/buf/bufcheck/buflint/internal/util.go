@@ -17,18 +17,32 @@ package internal
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/internal"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/bufbuild/buf/internal/pkg/thread"
 )
 
+// fileChunkSizeThreshold is the minimum number of files in each chunk for
+// files to be checked concurrently, below which the overhead is not worth it.
+const fileChunkSizeThreshold = 8
+
 // addFunc adds a FileAnnotation.
 //
 // Both the Descriptor and Location can be nil.
 type addFunc func(protosource.Descriptor, protosource.Location, string, ...interface{})
 
+// addFixFunc adds a FileAnnotation that is fixed by replacing the text at the
+// Location with the given replacement.
+//
+// Both the Descriptor and Location can be nil. If the Location is nil, the
+// FileAnnotation cannot be fixed.
+type addFixFunc func(protosource.Descriptor, protosource.Location, string, string, ...interface{})
+
 func fieldToLowerSnakeCase(s string) string {
 	// Try running this on googleapis and watch
 	// We allow both effectively by not passing the option
@@ -43,6 +57,255 @@ func fieldToUpperSnakeCase(s string) string {
 	return stringutil.ToUpperSnakeCase(s)
 }
 
+const (
+	// PackageVersionSuffixFormStable is v\d+.
+	PackageVersionSuffixFormStable = "stable"
+	// PackageVersionSuffixFormTest is v\d+test.*.
+	PackageVersionSuffixFormTest = "test"
+	// PackageVersionSuffixFormAlpha is v\d+alpha\d+.
+	PackageVersionSuffixFormAlpha = "alpha"
+	// PackageVersionSuffixFormBeta is v\d+beta\d+.
+	PackageVersionSuffixFormBeta = "beta"
+	// PackageVersionSuffixFormPatch allows a patch component in alpha and beta
+	// versions, as in v\d+p\d+(alpha|beta)\d+.
+	PackageVersionSuffixFormPatch = "patch"
+	// PackageVersionSuffixFormDate is vYYYYMMDD with a valid date.
+	PackageVersionSuffixFormDate = "date"
+)
+
+var (
+	// AllPackageVersionSuffixForms are all package version suffix forms.
+	AllPackageVersionSuffixForms = []string{
+		PackageVersionSuffixFormStable,
+		PackageVersionSuffixFormTest,
+		PackageVersionSuffixFormAlpha,
+		PackageVersionSuffixFormBeta,
+		PackageVersionSuffixFormPatch,
+		PackageVersionSuffixFormDate,
+	}
+
+	// the forms allowed by packageHasVersionSuffix
+	defaultPackageVersionSuffixForms = map[string]struct{}{
+		PackageVersionSuffixFormStable: {},
+		PackageVersionSuffixFormTest:   {},
+		PackageVersionSuffixFormAlpha:  {},
+		PackageVersionSuffixFormBeta:   {},
+		PackageVersionSuffixFormPatch:  {},
+	}
+)
+
+// messageIsDeprecated returns true if the message or any of the messages it
+// is nested in is deprecated.
+func messageIsDeprecated(message protosource.Message) bool {
+	for ; message != nil; message = message.Parent() {
+		if message.Deprecated() {
+			return true
+		}
+	}
+	return false
+}
+
+// packageMatchesPattern returns true if the package matches the pattern.
+//
+// The pattern is either a package, a package followed by ".*" to match the
+// package and all of its sub-packages, or "*" to match all packages.
+func packageMatchesPattern(pkg string, pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if prefix := strings.TrimSuffix(pattern, ".*"); prefix != pattern {
+		return pkg == prefix || strings.HasPrefix(pkg, prefix+".")
+	}
+	return pkg == pattern
+}
+
+// typeNameMatchesEntryPoint returns true if the message or enum with the given
+// fully-qualified name and package matches the entry point.
+//
+// The entry point is either a fully-qualified name, or a package pattern as
+// accepted by packageMatchesPattern that ends in ".*".
+func typeNameMatchesEntryPoint(fullName string, pkg string, entryPoint string) bool {
+	if strings.HasSuffix(entryPoint, ".*") {
+		return packageMatchesPattern(pkg, entryPoint)
+	}
+	return fullName == entryPoint
+}
+
+// getReachableTypeNames returns the fully-qualified names of the messages and
+// enums in the files that are reachable from the request and response types
+// of the RPCs, the entry points, and the extensions of messages that are not
+// in the files, such as custom options.
+//
+// References from messages that are not in the files are not known, so this is
+// only accurate if the files contain all of the messages that reference them.
+func getReachableTypeNames(files []protosource.File, entryPoints []string) (map[string]struct{}, error) {
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return nil, err
+	}
+	fullNameToEnum, err := protosource.FullNameToEnum(files...)
+	if err != nil {
+		return nil, err
+	}
+	extendeeToExtensions := make(map[string][]protosource.Field)
+	if err := forEachExtension(
+		func(extension protosource.Field) error {
+			extendee := strings.TrimPrefix(extension.Extendee(), ".")
+			extendeeToExtensions[extendee] = append(extendeeToExtensions[extendee], extension)
+			return nil
+		},
+		files,
+	); err != nil {
+		return nil, err
+	}
+	reachableTypeNames := make(map[string]struct{})
+	var messages []protosource.Message
+	addTypeName := func(typeName string) {
+		typeName = strings.TrimPrefix(typeName, ".")
+		if _, ok := reachableTypeNames[typeName]; ok {
+			return
+		}
+		if message, ok := fullNameToMessage[typeName]; ok {
+			reachableTypeNames[typeName] = struct{}{}
+			messages = append(messages, message)
+		} else if _, ok := fullNameToEnum[typeName]; ok {
+			reachableTypeNames[typeName] = struct{}{}
+		}
+	}
+	for _, file := range files {
+		for _, service := range file.Services() {
+			for _, method := range service.Methods() {
+				addTypeName(method.InputTypeName())
+				addTypeName(method.OutputTypeName())
+			}
+		}
+	}
+	for _, entryPoint := range entryPoints {
+		for fullName, message := range fullNameToMessage {
+			if typeNameMatchesEntryPoint(fullName, message.File().Package(), entryPoint) {
+				addTypeName(fullName)
+			}
+		}
+		for fullName, enum := range fullNameToEnum {
+			if typeNameMatchesEntryPoint(fullName, enum.File().Package(), entryPoint) {
+				addTypeName(fullName)
+			}
+		}
+	}
+	for extendee, extensions := range extendeeToExtensions {
+		if _, ok := fullNameToMessage[extendee]; ok {
+			continue
+		}
+		for _, extension := range extensions {
+			addTypeName(extension.TypeName())
+		}
+	}
+	for len(messages) > 0 {
+		message := messages[0]
+		messages = messages[1:]
+		for _, field := range message.Fields() {
+			addTypeName(field.TypeName())
+		}
+		for _, extension := range extendeeToExtensions[message.FullName()] {
+			addTypeName(extension.TypeName())
+		}
+	}
+	return reachableTypeNames, nil
+}
+
+// containerHasReachableType returns true if any of the messages or enums nested
+// in the container, at any depth, are reachable.
+func containerHasReachableType(containerDescriptor protosource.ContainerDescriptor, reachableTypeNames map[string]struct{}) bool {
+	for _, enum := range containerDescriptor.Enums() {
+		if _, ok := reachableTypeNames[enum.FullName()]; ok {
+			return true
+		}
+	}
+	for _, message := range containerDescriptor.Messages() {
+		if _, ok := reachableTypeNames[message.FullName()]; ok {
+			return true
+		}
+		if containerHasReachableType(message, reachableTypeNames) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachExtension calls f for each top-level and nested extension in the files.
+func forEachExtension(f func(protosource.Field) error, files []protosource.File) error {
+	for _, file := range files {
+		for _, extension := range file.Extensions() {
+			if err := f(extension); err != nil {
+				return err
+			}
+		}
+		if err := protosource.ForEachMessage(
+			func(message protosource.Message) error {
+				for _, extension := range message.Extensions() {
+					if err := f(extension); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getImportChainString returns the paths of the files joined by " -> ".
+func getImportChainString(files []protosource.File) string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = strconv.Quote(file.Path())
+	}
+	return strings.Join(paths, " -> ")
+}
+
+// fileMatchesImportRulePattern returns true if the file matches the import
+// rule pattern.
+//
+// Patterns that contain a "/" are matched against the path of the file with
+// normalpath.MatchGlob, and all other patterns against the package of the file.
+func fileMatchesImportRulePattern(file protosource.File, pattern string) bool {
+	if strings.Contains(pattern, "/") {
+		return normalpath.MatchGlob(normalpath.Normalize(pattern), file.Path())
+	}
+	return packageMatchesPattern(file.Package(), pattern)
+}
+
+// fileIsAllowedByImportRules returns true if the import file can be imported
+// by the file according to all of the import rules whose from matches the file.
+func fileIsAllowedByImportRules(file protosource.File, importFile protosource.File, importRules []internal.ImportRule) bool {
+	for _, importRule := range importRules {
+		if fileMatchesImportRulePattern(file, importRule.From) && !fileIsAllowedByImportRule(importFile, importRule) {
+			return false
+		}
+	}
+	return true
+}
+
+func fileIsAllowedByImportRule(importFile protosource.File, importRule internal.ImportRule) bool {
+	for _, pattern := range importRule.Deny {
+		if fileMatchesImportRulePattern(importFile, pattern) {
+			return false
+		}
+	}
+	if len(importRule.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range importRule.Allow {
+		if fileMatchesImportRulePattern(importFile, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
 // https://cloud.google.com/apis/design/versioning
 //
 // All Proto Package values pass.
@@ -50,37 +313,84 @@ func fieldToUpperSnakeCase(s string) string {
 // v1test can be v1test.*
 // v1p1alpha1 is also valid in addition to v1p1beta1
 func packageHasVersionSuffix(pkg string) bool {
-	if pkg == "" {
-		return false
-	}
-	parts := strings.Split(pkg, ".")
-	if len(parts) < 2 {
-		return false
-	}
-	lastPart := parts[len(parts)-1]
-	if len(lastPart) < 2 {
+	return packageHasVersionSuffixForForms(pkg, defaultPackageVersionSuffixForms)
+}
+
+// packageHasVersionSuffixForForms is packageHasVersionSuffix, but only
+// allows the given forms.
+func packageHasVersionSuffixForForms(pkg string, forms map[string]struct{}) bool {
+	version, ok := getPackageVersion(pkg)
+	if !ok {
 		return false
 	}
-	if lastPart[0] != 'v' {
-		return false
+	if _, ok := forms[PackageVersionSuffixFormDate]; ok && versionIsDate(version) {
+		return true
 	}
-	version := lastPart[1:]
 	if strings.Contains(version, "test") {
+		if _, ok := forms[PackageVersionSuffixFormTest]; !ok {
+			return false
+		}
 		split := strings.SplitN(version, "test", 2)
 		if len(split) != 2 {
 			return false
 		}
 		return stringIsPositiveNumber(split[0])
 	}
-	if strings.Contains(version, "alpha") {
-		return packageVersionIsValidAlphaOrBeta(version, "alpha")
+	for _, name := range []string{"alpha", "beta"} {
+		if strings.Contains(version, name) {
+			if _, ok := forms[name]; !ok {
+				return false
+			}
+			if _, ok := forms[PackageVersionSuffixFormPatch]; !ok && strings.Contains(strings.SplitN(version, name, 2)[0], "p") {
+				return false
+			}
+			return packageVersionIsValidAlphaOrBeta(version, name)
+		}
 	}
-	if strings.Contains(version, "beta") {
-		return packageVersionIsValidAlphaOrBeta(version, "beta")
+	if _, ok := forms[PackageVersionSuffixFormStable]; !ok {
+		return false
 	}
 	return stringIsPositiveNumber(version)
 }
 
+// packageIsUnversioned returns true if the last component of the package
+// does not look like a version, that is v followed by a digit.
+//
+// Packages with malformed versions such as foo.v0 are not unversioned.
+func packageIsUnversioned(pkg string) bool {
+	parts := strings.Split(pkg, ".")
+	lastPart := parts[len(parts)-1]
+	return len(parts) < 2 || len(lastPart) < 2 || lastPart[0] != 'v' || lastPart[1] < '0' || lastPart[1] > '9'
+}
+
+// getPackageVersion returns the version of the last component of the package,
+// without the leading v.
+func getPackageVersion(pkg string) (string, bool) {
+	if pkg == "" {
+		return "", false
+	}
+	parts := strings.Split(pkg, ".")
+	if len(parts) < 2 {
+		return "", false
+	}
+	lastPart := parts[len(parts)-1]
+	if len(lastPart) < 2 {
+		return "", false
+	}
+	if lastPart[0] != 'v' {
+		return "", false
+	}
+	return lastPart[1:], true
+}
+
+func versionIsDate(version string) bool {
+	if len(version) != 8 {
+		return false
+	}
+	_, err := time.Parse("20060102", version)
+	return err == nil
+}
+
 func packageVersionIsValidAlphaOrBeta(version string, name string) bool {
 	split := strings.SplitN(version, name, 2)
 	if len(split) != 2 {
@@ -125,6 +435,126 @@ func newFilesCheckFunc(
 	}
 }
 
+// newFilesFixCheckFunc is like newFilesCheckFunc, but also passes an addFixFunc
+// for the FileAnnotations that can be fixed.
+func newFilesFixCheckFunc(
+	f func(addFunc, addFixFunc, []protosource.File) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		helper := internal.NewHelper(id, ignoreFunc)
+		if err := f(helper.AddFileAnnotationf, helper.AddFileAnnotationWithReplacementf, files); err != nil {
+			return nil, err
+		}
+		return helper.FileAnnotations(), nil
+	}
+}
+
+func newPackageToFilesFixCheckFunc(
+	f func(add addFunc, addFix addFixFunc, pkg string, files []protosource.File) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFilesFixCheckFunc(
+		func(add addFunc, addFix addFixFunc, files []protosource.File) error {
+			packageToFiles, err := protosource.PackageToFiles(files...)
+			if err != nil {
+				return err
+			}
+			for pkg, files := range packageToFiles {
+				if err := f(add, addFix, pkg, files); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// newFileFixCheckFunc is like newFileCheckFunc, but passes an addFixFunc for
+// FileAnnotations that can be fixed.
+func newFileFixCheckFunc(
+	f func(addFixFunc, protosource.File) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return func(id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		return checkFilesInChunks(
+			id,
+			ignoreFunc,
+			files,
+			func(helper *internal.Helper, file protosource.File) error {
+				return f(helper.AddFileAnnotationWithReplacementf, file)
+			},
+		)
+	}
+}
+
+func newEnumValueFixCheckFunc(
+	f func(addFixFunc, protosource.EnumValue) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFileFixCheckFunc(
+		func(addFix addFixFunc, file protosource.File) error {
+			return protosource.ForEachEnum(
+				func(enum protosource.Enum) error {
+					for _, enumValue := range enum.Values() {
+						if err := f(addFix, enumValue); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				file,
+			)
+		},
+	)
+}
+
+func newFieldFixCheckFunc(
+	f func(addFixFunc, protosource.Field) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newMessageFixCheckFunc(
+		func(addFix addFixFunc, message protosource.Message) error {
+			for _, field := range message.Fields() {
+				if err := f(addFix, field); err != nil {
+					return err
+				}
+			}
+			for _, field := range message.Extensions() {
+				if err := f(addFix, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func newOneofFixCheckFunc(
+	f func(addFixFunc, protosource.Oneof) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newMessageFixCheckFunc(
+		func(addFix addFixFunc, message protosource.Message) error {
+			for _, oneof := range message.Oneofs() {
+				if err := f(addFix, oneof); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func newMessageFixCheckFunc(
+	f func(addFixFunc, protosource.Message) error,
+) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	return newFileFixCheckFunc(
+		func(addFix addFixFunc, file protosource.File) error {
+			return protosource.ForEachMessage(
+				func(message protosource.Message) error {
+					return f(addFix, message)
+				},
+				file,
+			)
+		},
+	)
+}
+
 func newPackageToFilesCheckFunc(
 	f func(add addFunc, pkg string, files []protosource.File) error,
 ) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
@@ -163,19 +593,21 @@ func newDirToFilesCheckFunc(
 	)
 }
 
+// newFileCheckFunc returns a check function that checks each file on its own,
+// which allows the files to be checked concurrently.
 func newFileCheckFunc(
 	f func(addFunc, protosource.File) error,
 ) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return newFilesCheckFunc(
-		func(add addFunc, files []protosource.File) error {
-			for _, file := range files {
-				if err := f(add, file); err != nil {
-					return err
-				}
-			}
-			return nil
-		},
-	)
+	return func(id string, ignoreFunc internal.IgnoreFunc, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		return checkFilesInChunks(
+			id,
+			ignoreFunc,
+			files,
+			func(helper *internal.Helper, file protosource.File) error {
+				return f(helper.AddFileAnnotationf, file)
+			},
+		)
+	}
 }
 
 func newFileImportCheckFunc(
@@ -238,6 +670,54 @@ func newMessageCheckFunc(
 	)
 }
 
+// checkFilesInChunks calls f for each file.
+//
+// If there are enough files, they are split into thread.Parallelism() chunks
+// that are checked concurrently, each with its own Helper. The FileAnnotations
+// are returned in the order of the files.
+func checkFilesInChunks(
+	id string,
+	ignoreFunc internal.IgnoreFunc,
+	files []protosource.File,
+	f func(*internal.Helper, protosource.File) error,
+) ([]bufanalysis.FileAnnotation, error) {
+	chunks := [][]protosource.File{files}
+	if chunkSize := len(files) / thread.Parallelism(); chunkSize >= fileChunkSizeThreshold {
+		chunks = filesToChunks(files, chunkSize)
+	}
+	helpers := make([]*internal.Helper, len(chunks))
+	jobs := make([]func() error, len(chunks))
+	for i, chunk := range chunks {
+		chunk := chunk
+		helper := internal.NewHelper(id, ignoreFunc)
+		helpers[i] = helper
+		jobs[i] = func() error {
+			for _, file := range chunk {
+				if err := f(helper, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if err := thread.Parallelize(jobs...); err != nil {
+		return nil, err
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, helper := range helpers {
+		fileAnnotations = append(fileAnnotations, helper.FileAnnotations()...)
+	}
+	return fileAnnotations, nil
+}
+
+func filesToChunks(files []protosource.File, chunkSize int) [][]protosource.File {
+	var chunks [][]protosource.File
+	for chunkSize < len(files) {
+		files, chunks = files[chunkSize:], append(chunks, files[0:chunkSize:chunkSize])
+	}
+	return append(chunks, files)
+}
+
 func newFieldCheckFunc(
 	f func(addFunc, protosource.Field) error,
 ) func(string, internal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
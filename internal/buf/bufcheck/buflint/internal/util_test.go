@@ -17,6 +17,7 @@ package internal
 import (
 	"testing"
 
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -74,6 +75,59 @@ func TestPackageHasVersionSuffix(t *testing.T) {
 	testPackageHasVersionSuffix(t, false, "foo.bar.v1aalpha1")
 }
 
+func TestPackageHasVersionSuffixForForms(t *testing.T) {
+	stable := []string{PackageVersionSuffixFormStable}
+	testPackageHasVersionSuffixForForms(t, true, "foo.v1", stable)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1beta1", stable)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1test", stable)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1p1beta1", stable)
+
+	beta := []string{PackageVersionSuffixFormStable, PackageVersionSuffixFormBeta}
+	testPackageHasVersionSuffixForForms(t, true, "foo.v1", beta)
+	testPackageHasVersionSuffixForForms(t, true, "foo.v1beta1", beta)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1alpha1", beta)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1p1beta1", beta)
+
+	patch := []string{PackageVersionSuffixFormAlpha, PackageVersionSuffixFormPatch}
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1", patch)
+	testPackageHasVersionSuffixForForms(t, true, "foo.v1alpha1", patch)
+	testPackageHasVersionSuffixForForms(t, true, "foo.v1p1alpha1", patch)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1p1beta1", patch)
+
+	date := []string{PackageVersionSuffixFormDate}
+	testPackageHasVersionSuffixForForms(t, true, "foo.v20200131", date)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v20200132", date)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v2020013", date)
+	testPackageHasVersionSuffixForForms(t, false, "foo.v1", date)
+	testPackageHasVersionSuffixForForms(t, false, "foo", date)
+}
+
+func TestPackageIsUnversioned(t *testing.T) {
+	assert.True(t, packageIsUnversioned("foo"))
+	assert.True(t, packageIsUnversioned("foo.bar"))
+	assert.True(t, packageIsUnversioned("foo.v"))
+	assert.True(t, packageIsUnversioned("foo.vv1"))
+	assert.False(t, packageIsUnversioned("foo.v0"))
+	assert.False(t, packageIsUnversioned("foo.v1"))
+	assert.False(t, packageIsUnversioned("foo.v1alpha0"))
+}
+
+func TestPackageMatchesPattern(t *testing.T) {
+	assert.True(t, packageMatchesPattern("foo.v1", "*"))
+	assert.True(t, packageMatchesPattern("", "*"))
+	assert.True(t, packageMatchesPattern("foo.v1", "foo.v1"))
+	assert.False(t, packageMatchesPattern("foo.v1", "foo"))
+	assert.True(t, packageMatchesPattern("foo", "foo.*"))
+	assert.True(t, packageMatchesPattern("foo.v1", "foo.*"))
+	assert.True(t, packageMatchesPattern("foo.bar.v1", "foo.*"))
+	assert.False(t, packageMatchesPattern("foobar.v1", "foo.*"))
+	assert.False(t, packageMatchesPattern("bar.foo.v1", "foo.*"))
+}
+
 func testPackageHasVersionSuffix(t *testing.T, expected bool, pkg string) {
 	assert.Equal(t, expected, packageHasVersionSuffix(pkg), pkg)
 }
+
+func testPackageHasVersionSuffixForForms(t *testing.T, expected bool, pkg string, forms []string) {
+	assert.Equal(t, expected, packageHasVersionSuffixForForms(pkg, stringutil.SliceToMap(forms)), pkg)
+}
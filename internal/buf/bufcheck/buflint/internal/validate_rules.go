@@ -0,0 +1,574 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// This file contains helpers for protoc-gen-validate style constraints.
+//
+// https://github.com/envoyproxy/protoc-gen-validate/blob/main/validate/validate.proto
+//
+// We do not depend on the generated code for validate.proto, and the
+// definitions are not required to be in the image, so we read the
+// wire format of the validate.rules field option directly. The field
+// numbers below are stable as they are part of the wire format.
+
+const (
+	// validateRulesFieldNumber is the field number of validate.rules on google.protobuf.FieldOptions.
+	validateRulesFieldNumber = 1071
+
+	validateFieldRulesMessageFieldNumber = 17
+)
+
+// validateRuleType is a member of the type oneof of validate.FieldRules.
+type validateRuleType struct {
+	name string
+	// scalarTypes are the field types the rules apply to if the rules are for a scalar.
+	scalarTypes []protosource.FieldDescriptorProtoType
+	// wrapperTypeName is the name of the google.protobuf wrapper type the rules also apply to.
+	wrapperTypeName string
+	// messageTypeName is the name of the well-known type the rules apply to, if any.
+	messageTypeName string
+	// numeric is whether the rules are validate.*Rules with const, lt, lte, gt, gte, in, not_in.
+	numeric   bool
+	wireType  protowire.Type
+	zigzag    bool
+	isFloat   bool
+	isSigned  bool
+	isMapRule bool
+	isRepRule bool
+}
+
+var validateRuleTypes = map[protowire.Number]*validateRuleType{
+	1:  {name: "float", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeFloat}, wrapperTypeName: "google.protobuf.FloatValue", numeric: true, wireType: protowire.Fixed32Type, isFloat: true},
+	2:  {name: "double", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeDouble}, wrapperTypeName: "google.protobuf.DoubleValue", numeric: true, wireType: protowire.Fixed64Type, isFloat: true},
+	3:  {name: "int32", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeInt32}, wrapperTypeName: "google.protobuf.Int32Value", numeric: true, wireType: protowire.VarintType, isSigned: true},
+	4:  {name: "int64", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeInt64}, wrapperTypeName: "google.protobuf.Int64Value", numeric: true, wireType: protowire.VarintType, isSigned: true},
+	5:  {name: "uint32", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeUint32}, wrapperTypeName: "google.protobuf.UInt32Value", numeric: true, wireType: protowire.VarintType},
+	6:  {name: "uint64", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeUint64}, wrapperTypeName: "google.protobuf.UInt64Value", numeric: true, wireType: protowire.VarintType},
+	7:  {name: "sint32", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeSint32}, numeric: true, wireType: protowire.VarintType, zigzag: true, isSigned: true},
+	8:  {name: "sint64", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeSint64}, numeric: true, wireType: protowire.VarintType, zigzag: true, isSigned: true},
+	9:  {name: "fixed32", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeFixed32}, numeric: true, wireType: protowire.Fixed32Type},
+	10: {name: "fixed64", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeFixed64}, numeric: true, wireType: protowire.Fixed64Type},
+	11: {name: "sfixed32", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeSfixed32}, numeric: true, wireType: protowire.Fixed32Type, isSigned: true},
+	12: {name: "sfixed64", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeSfixed64}, numeric: true, wireType: protowire.Fixed64Type, isSigned: true},
+	13: {name: "bool", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeBool}, wrapperTypeName: "google.protobuf.BoolValue"},
+	14: {name: "string", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeString}, wrapperTypeName: "google.protobuf.StringValue"},
+	15: {name: "bytes", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeBytes}, wrapperTypeName: "google.protobuf.BytesValue"},
+	16: {name: "enum", scalarTypes: []protosource.FieldDescriptorProtoType{protosource.FieldDescriptorProtoTypeEnum}},
+	18: {name: "repeated", isRepRule: true},
+	19: {name: "map", isMapRule: true},
+	20: {name: "any", messageTypeName: "google.protobuf.Any"},
+	21: {name: "duration", messageTypeName: "google.protobuf.Duration"},
+	22: {name: "timestamp", messageTypeName: "google.protobuf.Timestamp"},
+}
+
+// validateFieldRules are the parsed validate.FieldRules for a field.
+type validateFieldRules struct {
+	// ruleType is nil if no type rules are set.
+	ruleType *validateRuleType
+	// ruleData is the wire-encoded rules for ruleType.
+	ruleData []byte
+	// hasMessageRules is whether validate.MessageRules are set.
+	hasMessageRules bool
+}
+
+// rangeWireFields calls f for each field in the wire-encoded message data.
+//
+// For length-delimited fields, value is the contents without the length prefix.
+func rangeWireFields(data []byte, f func(protowire.Number, protowire.Type, []byte) error) error {
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, typ, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		value := data[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		if err := f(number, typ, value); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// decodeValidateNumber decodes a numeric rule value for the rule type as a float64.
+//
+// Precision may be lost for 64-bit integers with a magnitude above 2^53, which is
+// acceptable for comparing bounds.
+func decodeValidateNumber(ruleType *validateRuleType, typ protowire.Type, value []byte) (float64, bool) {
+	if typ != ruleType.wireType {
+		return 0, false
+	}
+	switch typ {
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(value)
+		if n < 0 {
+			return 0, false
+		}
+		switch {
+		case ruleType.zigzag:
+			return float64(protowire.DecodeZigZag(v)), true
+		case ruleType.isSigned:
+			return float64(int64(v)), true
+		default:
+			return float64(v), true
+		}
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(value)
+		if n < 0 {
+			return 0, false
+		}
+		switch {
+		case ruleType.isFloat:
+			return float64(math.Float32frombits(v)), true
+		case ruleType.isSigned:
+			return float64(int32(v)), true
+		default:
+			return float64(v), true
+		}
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(value)
+		if n < 0 {
+			return 0, false
+		}
+		switch {
+		case ruleType.isFloat:
+			return math.Float64frombits(v), true
+		case ruleType.isSigned:
+			return float64(int64(v)), true
+		default:
+			return float64(v), true
+		}
+	default:
+		return 0, false
+	}
+}
+
+type validateProblemKind int
+
+const (
+	validateProblemKindSyntax validateProblemKind = iota + 1
+	validateProblemKindTypeMatch
+	validateProblemKindBounds
+)
+
+type validateProblem struct {
+	kind    validateProblemKind
+	message string
+}
+
+// validateTarget is what a set of validate.FieldRules applies to.
+//
+// This is either a field, or the items, keys, or values of a field.
+type validateTarget struct {
+	description string
+	typ         protosource.FieldDescriptorProtoType
+	typeName    string
+	repeated    bool
+	// mapEntry is non-nil if this is a map field.
+	mapEntry protosource.Message
+}
+
+func newValidateTargetForField(field protosource.Field) *validateTarget {
+	target := &validateTarget{
+		description: "field",
+		typ:         field.Type(),
+		typeName:    field.TypeName(),
+		repeated:    field.Label() == protosource.FieldDescriptorProtoLabelRepeated,
+	}
+	if target.repeated && target.typ == protosource.FieldDescriptorProtoTypeMessage {
+		for _, nestedMessage := range field.Message().Messages() {
+			if "."+nestedMessage.FullName() == field.TypeName() && nestedMessage.IsMapEntry() {
+				target.mapEntry = nestedMessage
+			}
+		}
+	}
+	return target
+}
+
+func (t *validateTarget) typeString() string {
+	switch {
+	case t.mapEntry != nil:
+		return "map"
+	case t.repeated:
+		return "repeated " + t.scalarTypeString()
+	default:
+		return t.scalarTypeString()
+	}
+}
+
+func (t *validateTarget) scalarTypeString() string {
+	if t.typeName != "" {
+		return t.typeName[1:]
+	}
+	return t.typ.String()
+}
+
+// getValidateProblems returns the problems with the validate.rules for the field.
+func getValidateProblems(field protosource.Field) []*validateProblem {
+	data, ok := field.OptionExtension(validateRulesFieldNumber)
+	if !ok {
+		return nil
+	}
+	return getValidateProblemsForTarget(newValidateTargetForField(field), data)
+}
+
+func getValidateProblemsForTarget(target *validateTarget, data []byte) []*validateProblem {
+	fieldRules, err := parseValidateFieldRules(data)
+	if err != nil {
+		return []*validateProblem{
+			{
+				kind:    validateProblemKindSyntax,
+				message: "malformed rules: " + err.Error(),
+			},
+		}
+	}
+	var problems []*validateProblem
+	if fieldRules.hasMessageRules && (target.typ != protosource.FieldDescriptorProtoTypeMessage || target.repeated) {
+		problems = append(problems, &validateProblem{
+			kind:    validateProblemKindTypeMatch,
+			message: fmt.Sprintf("message rules cannot be applied to %s of type %s", target.description, target.typeString()),
+		})
+	}
+	ruleType := fieldRules.ruleType
+	if ruleType == nil {
+		return problems
+	}
+	if !ruleType.matches(target) {
+		// no point in checking the contents of rules for the wrong type
+		return append(problems, &validateProblem{
+			kind:    validateProblemKindTypeMatch,
+			message: fmt.Sprintf("%s rules cannot be applied to %s of type %s", ruleType.name, target.description, target.typeString()),
+		})
+	}
+	switch {
+	case ruleType.numeric:
+		problems = append(problems, getValidateNumericProblems(ruleType, fieldRules.ruleData)...)
+	case ruleType.name == "string":
+		problems = append(problems, getValidateLengthProblems(fieldRules.ruleData, "string", 6, 19, 2, 3)...)
+		problems = append(problems, getValidateLengthProblems(fieldRules.ruleData, "string bytes", 0, 20, 4, 5)...)
+	case ruleType.name == "bytes":
+		problems = append(problems, getValidateLengthProblems(fieldRules.ruleData, "bytes", 4, 13, 2, 3)...)
+	case ruleType.isRepRule:
+		problems = append(problems, getValidateLengthProblems(fieldRules.ruleData, "repeated", 0, 0, 1, 2)...)
+		itemsTarget := *target
+		itemsTarget.description = "items of " + target.description
+		itemsTarget.repeated = false
+		problems = append(problems, getValidateNestedProblems(fieldRules.ruleData, 4, &itemsTarget)...)
+	case ruleType.isMapRule:
+		problems = append(problems, getValidateLengthProblems(fieldRules.ruleData, "map", 0, 0, 1, 2)...)
+		for i, name := range []string{"keys", "values"} {
+			if fields := target.mapEntry.Fields(); len(fields) == 2 {
+				elementTarget := &validateTarget{
+					description: name + " of " + target.description,
+					typ:         fields[i].Type(),
+					typeName:    fields[i].TypeName(),
+				}
+				problems = append(problems, getValidateNestedProblems(fieldRules.ruleData, protowire.Number(4+i), elementTarget)...)
+			}
+		}
+	}
+	return problems
+}
+
+func parseValidateFieldRules(data []byte) (*validateFieldRules, error) {
+	fieldRules := &validateFieldRules{}
+	if err := rangeWireFields(data, func(number protowire.Number, typ protowire.Type, value []byte) error {
+		if number == validateFieldRulesMessageFieldNumber {
+			fieldRules.hasMessageRules = true
+			return nil
+		}
+		ruleType, ok := validateRuleTypes[number]
+		if !ok {
+			return nil
+		}
+		if typ != protowire.BytesType {
+			return fmt.Errorf("%s rules are not a message", ruleType.name)
+		}
+		if fieldRules.ruleType != nil && fieldRules.ruleType != ruleType {
+			return fmt.Errorf("both %s and %s rules set", fieldRules.ruleType.name, ruleType.name)
+		}
+		fieldRules.ruleType = ruleType
+		fieldRules.ruleData = append(fieldRules.ruleData, value...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return fieldRules, nil
+}
+
+func (r *validateRuleType) matches(target *validateTarget) bool {
+	switch {
+	case r.isMapRule:
+		return target.mapEntry != nil
+	case r.isRepRule:
+		return target.repeated && target.mapEntry == nil
+	case target.repeated:
+		return false
+	case r.messageTypeName != "":
+		return target.typeName == "."+r.messageTypeName
+	case r.wrapperTypeName != "" && target.typeName == "."+r.wrapperTypeName:
+		return true
+	default:
+		for _, scalarType := range r.scalarTypes {
+			if target.typ == scalarType {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func getValidateNestedProblems(data []byte, number protowire.Number, target *validateTarget) []*validateProblem {
+	var nestedData []byte
+	found := false
+	_ = rangeWireFields(data, func(fieldNumber protowire.Number, typ protowire.Type, value []byte) error {
+		if fieldNumber == number && typ == protowire.BytesType {
+			found = true
+			nestedData = append(nestedData, value...)
+		}
+		return nil
+	})
+	if !found {
+		return nil
+	}
+	return getValidateProblemsForTarget(target, nestedData)
+}
+
+// getValidateNumericProblems checks that const, lt, lte, gt, gte, in, and not_in
+// can be satisfied together.
+func getValidateNumericProblems(ruleType *validateRuleType, data []byte) []*validateProblem {
+	values := make(map[protowire.Number]float64)
+	var in []float64
+	var notIn []float64
+	var hasIn bool
+	_ = rangeWireFields(data, func(number protowire.Number, typ protowire.Type, value []byte) error {
+		switch number {
+		case 1, 2, 3, 4, 5:
+			if v, ok := decodeValidateNumber(ruleType, typ, value); ok {
+				values[number] = v
+			}
+		case 6:
+			hasIn = true
+			in = append(in, decodeValidateNumbers(ruleType, typ, value)...)
+		case 7:
+			notIn = append(notIn, decodeValidateNumbers(ruleType, typ, value)...)
+		}
+		return nil
+	})
+	var problems []*validateProblem
+	addBounds := func(format string, args ...interface{}) {
+		problems = append(problems, &validateProblem{
+			kind:    validateProblemKindBounds,
+			message: fmt.Sprintf(format, args...),
+		})
+	}
+	numericRange := newValidateNumericRange(values)
+	if c, ok := values[1]; ok {
+		switch {
+		case !numericRange.contains(c):
+			addBounds("%s const %v is not within %s", ruleType.name, c, numericRange.String())
+		case containsValidateNumber(notIn, c):
+			addBounds("%s const %v is listed in not_in", ruleType.name, c)
+		case hasIn && !containsValidateNumber(in, c):
+			addBounds("%s const %v is not listed in in", ruleType.name, c)
+		}
+		return problems
+	}
+	if hasIn {
+		for _, v := range in {
+			if numericRange.contains(v) && !containsValidateNumber(notIn, v) {
+				return problems
+			}
+		}
+		addBounds("%s in values are all excluded by the bounds or not_in, which no value can satisfy", ruleType.name)
+	}
+	return problems
+}
+
+// decodeValidateNumbers decodes the values of a repeated numeric rule, which
+// may be packed.
+func decodeValidateNumbers(ruleType *validateRuleType, typ protowire.Type, value []byte) []float64 {
+	if typ != protowire.BytesType {
+		if v, ok := decodeValidateNumber(ruleType, typ, value); ok {
+			return []float64{v}
+		}
+		return nil
+	}
+	var values []float64
+	for len(value) > 0 {
+		var n int
+		switch ruleType.wireType {
+		case protowire.VarintType:
+			_, n = protowire.ConsumeVarint(value)
+		case protowire.Fixed32Type:
+			_, n = protowire.ConsumeFixed32(value)
+		case protowire.Fixed64Type:
+			_, n = protowire.ConsumeFixed64(value)
+		default:
+			return values
+		}
+		if n < 0 {
+			return values
+		}
+		if v, ok := decodeValidateNumber(ruleType, ruleType.wireType, value[:n]); ok {
+			values = append(values, v)
+		}
+		value = value[n:]
+	}
+	return values
+}
+
+func containsValidateNumber(values []float64, value float64) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNumericRange is the range of values allowed by lt, lte, gt, and gte.
+type validateNumericRange struct {
+	lower          float64
+	lowerOK        bool
+	lowerInclusive bool
+	upper          float64
+	upperOK        bool
+	upperInclusive bool
+}
+
+func newValidateNumericRange(values map[protowire.Number]float64) *validateNumericRange {
+	numericRange := &validateNumericRange{}
+	numericRange.upper, numericRange.upperOK = values[2]
+	if lte, ok := values[3]; ok {
+		numericRange.upper, numericRange.upperOK, numericRange.upperInclusive = lte, true, true
+	}
+	numericRange.lower, numericRange.lowerOK = values[4]
+	if gte, ok := values[5]; ok {
+		numericRange.lower, numericRange.lowerOK, numericRange.lowerInclusive = gte, true, true
+	}
+	return numericRange
+}
+
+// contains returns true if the value is within the range.
+//
+// If the lower bound is not less than the upper bound, protoc-gen-validate
+// treats the range as exclusive, so values either above the lower bound or
+// below the upper bound are within the range.
+func (r *validateNumericRange) contains(value float64) bool {
+	aboveLower := !r.lowerOK || value > r.lower || (r.lowerInclusive && value == r.lower)
+	belowUpper := !r.upperOK || value < r.upper || (r.upperInclusive && value == r.upper)
+	if r.lowerOK && r.upperOK && r.lower >= r.upper {
+		return aboveLower || belowUpper
+	}
+	return aboveLower && belowUpper
+}
+
+func (r *validateNumericRange) String() string {
+	var bounds []string
+	if r.lowerOK {
+		name := "gt"
+		if r.lowerInclusive {
+			name = "gte"
+		}
+		bounds = append(bounds, fmt.Sprintf("%s %v", name, r.lower))
+	}
+	if r.upperOK {
+		name := "lt"
+		if r.upperInclusive {
+			name = "lte"
+		}
+		bounds = append(bounds, fmt.Sprintf("%s %v", name, r.upper))
+	}
+	return strings.Join(bounds, " and ")
+}
+
+// getValidateLengthProblems checks exact, minimum, and maximum length rules.
+//
+// Field numbers of 0 mean the rule does not exist for the type.
+func getValidateLengthProblems(
+	data []byte,
+	name string,
+	patternNumber protowire.Number,
+	exactNumber protowire.Number,
+	minNumber protowire.Number,
+	maxNumber protowire.Number,
+) []*validateProblem {
+	values := make(map[protowire.Number]uint64)
+	var pattern string
+	hasPattern := false
+	_ = rangeWireFields(data, func(number protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case number == patternNumber && typ == protowire.BytesType:
+			pattern = string(value)
+			hasPattern = true
+		case number != 0 && (number == exactNumber || number == minNumber || number == maxNumber) && typ == protowire.VarintType:
+			if v, n := protowire.ConsumeVarint(value); n >= 0 {
+				values[number] = v
+			}
+		}
+		return nil
+	})
+	var problems []*validateProblem
+	if hasPattern {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, &validateProblem{
+				kind:    validateProblemKindSyntax,
+				message: fmt.Sprintf("%s pattern %q is not a valid RE2 regular expression: %v", name, pattern, err),
+			})
+		}
+	}
+	exact, exactOK := values[exactNumber]
+	min, minOK := values[minNumber]
+	max, maxOK := values[maxNumber]
+	if exactOK && exactNumber != 0 {
+		if minOK && exact < min {
+			problems = append(problems, &validateProblem{
+				kind:    validateProblemKindBounds,
+				message: fmt.Sprintf("%s exact length %d is less than minimum %d", name, exact, min),
+			})
+		}
+		if maxOK && exact > max {
+			problems = append(problems, &validateProblem{
+				kind:    validateProblemKindBounds,
+				message: fmt.Sprintf("%s exact length %d is greater than maximum %d", name, exact, max),
+			})
+		}
+	}
+	if minOK && maxOK && min > max {
+		problems = append(problems, &validateProblem{
+			kind:    validateProblemKindBounds,
+			message: fmt.Sprintf("%s minimum %d is greater than maximum %d", name, min, max),
+		})
+	}
+	return problems
+}
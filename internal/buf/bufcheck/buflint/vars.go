@@ -16,11 +16,16 @@ package buflint
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint/internal"
 	bufcheckinternal "github.com/bufbuild/buf/internal/buf/bufcheck/internal"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
 
 var (
@@ -33,19 +38,31 @@ var (
 		v1CommentOneofCheckerBuilder,
 		v1CommentRPCCheckerBuilder,
 		v1CommentServiceCheckerBuilder,
+		v1DeprecatedCommentCheckerBuilder,
 		v1DirectorySamePackageCheckerBuilder,
 		v1EnumFirstValueZeroCheckerBuilder,
+		v1EnumMaxValuesCheckerBuilder,
 		v1EnumNoAllowAliasCheckerBuilder,
 		v1EnumPascalCaseCheckerBuilder,
+		v1EnumUnusedCheckerBuilder,
 		v1EnumValuePrefixCheckerBuilder,
 		v1EnumValueUpperSnakeCaseCheckerBuilder,
 		v1EnumZeroValueSuffixCheckerBuilder,
+		v1ExtensionUnusedCheckerBuilder,
+		v1FieldDeprecatedTypeCheckerBuilder,
 		v1FieldLowerSnakeCaseCheckerBuilder,
+		v1FieldNamePatternCheckerBuilder,
 		v1FieldNoDescriptorCheckerBuilder,
 		v1FileLowerSnakeCaseCheckerBuilder,
+		v1ImportAllowedCheckerBuilder,
 		v1ImportNoPublicCheckerBuilder,
 		v1ImportNoWeakCheckerBuilder,
+		v1ImportTransitiveAllowedCheckerBuilder,
+		v1MessageMaxFieldsCheckerBuilder,
+		v1MessageMaxNestingDepthCheckerBuilder,
+		v1MessageNamePatternCheckerBuilder,
 		v1MessagePascalCaseCheckerBuilder,
+		v1MessageUnusedCheckerBuilder,
 		v1OneofLowerSnakeCaseCheckerBuilder,
 		v1PackageDefinedCheckerBuilder,
 		v1PackageDirectoryMatchCheckerBuilder,
@@ -58,15 +75,23 @@ var (
 		v1PackageSamePhpNamespaceCheckerBuilder,
 		v1PackageSameRubyPackageCheckerBuilder,
 		v1PackageSameSwiftPrefixCheckerBuilder,
+		v1PackageVersionDirectoryMatchCheckerBuilder,
 		v1PackageVersionSuffixCheckerBuilder,
+		v1RPCIdempotencyLevelDefinedCheckerBuilder,
+		v1RPCNameVerbCheckerBuilder,
 		v1RPCNoClientStreamingCheckerBuilder,
 		v1RPCNoServerStreamingCheckerBuilder,
 		v1RPCPascalCaseCheckerBuilder,
 		v1RPCRequestResponseUniqueCheckerBuilder,
 		v1RPCRequestStandardNameCheckerBuilder,
 		v1RPCResponseStandardNameCheckerBuilder,
+		v1ServiceMaxRPCsCheckerBuilder,
+		v1ServiceNamePatternCheckerBuilder,
 		v1ServicePascalCaseCheckerBuilder,
 		v1ServiceSuffixCheckerBuilder,
+		v1ValidateRulesBoundsCheckerBuilder,
+		v1ValidateRulesSyntaxCheckerBuilder,
+		v1ValidateRulesTypeMatchCheckerBuilder,
 	}
 
 	// v1DefaultCategories are the default categories.
@@ -82,9 +107,13 @@ var (
 		"UNARY_RPC",
 		"FILE_LAYOUT",
 		"PACKAGE_AFFINITY",
+		"PACKAGE_VERSION",
 		"SENSIBLE",
 		"STYLE_BASIC",
 		"STYLE_DEFAULT",
+		"VALIDATE",
+		"DEPENDENCY",
+		"UNUSED",
 		"OTHER",
 	}
 	// v1IDToCategories are the ID to categories.
@@ -110,15 +139,22 @@ var (
 		"COMMENT_SERVICE": {
 			"COMMENTS",
 		},
+		"DEPRECATED_COMMENT": {
+			"OTHER",
+		},
 		"DIRECTORY_SAME_PACKAGE": {
 			"MINIMAL",
 			"BASIC",
 			"DEFAULT",
 			"FILE_LAYOUT",
+			"PACKAGE_VERSION",
 		},
 		"ENUM_FIRST_VALUE_ZERO": {
 			"OTHER",
 		},
+		"ENUM_MAX_VALUES": {
+			"OTHER",
+		},
 		"ENUM_NO_ALLOW_ALIAS": {
 			"MINIMAL",
 			"BASIC",
@@ -131,6 +167,9 @@ var (
 			"STYLE_BASIC",
 			"STYLE_DEFAULT",
 		},
+		"ENUM_UNUSED": {
+			"UNUSED",
+		},
 		"ENUM_VALUE_PREFIX": {
 			"DEFAULT",
 			"STYLE_DEFAULT",
@@ -145,12 +184,21 @@ var (
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"EXTENSION_UNUSED": {
+			"UNUSED",
+		},
+		"FIELD_DEPRECATED_TYPE": {
+			"OTHER",
+		},
 		"FIELD_LOWER_SNAKE_CASE": {
 			"BASIC",
 			"DEFAULT",
 			"STYLE_BASIC",
 			"STYLE_DEFAULT",
 		},
+		"FIELD_NAME_PATTERN": {
+			"OTHER",
+		},
 		"FIELD_NO_DESCRIPTOR": {
 			"MINIMAL",
 			"BASIC",
@@ -161,6 +209,9 @@ var (
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"IMPORT_ALLOWED": {
+			"DEPENDENCY",
+		},
 		"IMPORT_NO_PUBLIC": {
 			"MINIMAL",
 			"BASIC",
@@ -173,12 +224,27 @@ var (
 			"DEFAULT",
 			"SENSIBLE",
 		},
+		"IMPORT_TRANSITIVE_ALLOWED": {
+			"DEPENDENCY",
+		},
+		"MESSAGE_MAX_FIELDS": {
+			"OTHER",
+		},
+		"MESSAGE_MAX_NESTING_DEPTH": {
+			"OTHER",
+		},
+		"MESSAGE_NAME_PATTERN": {
+			"OTHER",
+		},
 		"MESSAGE_PASCAL_CASE": {
 			"BASIC",
 			"DEFAULT",
 			"STYLE_BASIC",
 			"STYLE_DEFAULT",
 		},
+		"MESSAGE_UNUSED": {
+			"UNUSED",
+		},
 		"ONEOF_LOWER_SNAKE_CASE": {
 			"BASIC",
 			"DEFAULT",
@@ -251,10 +317,19 @@ var (
 			"DEFAULT",
 			"PACKAGE_AFFINITY",
 		},
+		"PACKAGE_VERSION_DIRECTORY_MATCH": {
+			"PACKAGE_VERSION",
+		},
 		"PACKAGE_VERSION_SUFFIX": {
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"RPC_IDEMPOTENCY_LEVEL_DEFINED": {
+			"OTHER",
+		},
+		"RPC_NAME_VERB": {
+			"OTHER",
+		},
 		"RPC_NO_CLIENT_STREAMING": {
 			"UNARY_RPC",
 		},
@@ -279,6 +354,12 @@ var (
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"SERVICE_MAX_RPCS": {
+			"OTHER",
+		},
+		"SERVICE_NAME_PATTERN": {
+			"OTHER",
+		},
 		"SERVICE_PASCAL_CASE": {
 			"BASIC",
 			"DEFAULT",
@@ -289,6 +370,15 @@ var (
 			"DEFAULT",
 			"STYLE_DEFAULT",
 		},
+		"VALIDATE_RULES_BOUNDS": {
+			"VALIDATE",
+		},
+		"VALIDATE_RULES_SYNTAX": {
+			"VALIDATE",
+		},
+		"VALIDATE_RULES_TYPE_MATCH": {
+			"VALIDATE",
+		},
 	}
 
 	v1CommentEnumCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
@@ -326,6 +416,11 @@ var (
 		"services have non-empty comments",
 		newAdapter(internal.CheckCommentService),
 	)
+	v1DeprecatedCommentCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"DEPRECATED_COMMENT",
+		"deprecated enums, enum values, messages, fields, services, and RPCs have a comment that mentions the deprecation",
+		newAdapter(internal.CheckDeprecatedComment),
+	)
 	v1DirectorySamePackageCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"DIRECTORY_SAME_PACKAGE",
 		"all files in a given directory are in the same package",
@@ -336,6 +431,15 @@ var (
 		"all first values of enums have a numeric value of 0",
 		newAdapter(internal.CheckEnumFirstValueZero),
 	)
+	v1EnumMaxValuesCheckerBuilder = newMaxCheckerBuilder(
+		"ENUM_MAX_VALUES",
+		"enums have at most %d values",
+		"enum_max_values",
+		func(configBuilder bufcheckinternal.ConfigBuilder) int {
+			return configBuilder.EnumMaxValues
+		},
+		internal.CheckEnumMaxValues,
+	)
 	v1EnumNoAllowAliasCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"ENUM_NO_ALLOW_ALIAS",
 		"enums do not have the allow_alias option set",
@@ -346,37 +450,70 @@ var (
 		"enums are PascalCase",
 		newAdapter(internal.CheckEnumPascalCase),
 	)
-	v1EnumValuePrefixCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"ENUM_VALUE_PREFIX",
-		"enum values are prefixed with ENUM_NAME_UPPER_SNAKE_CASE",
-		newAdapter(internal.CheckEnumValuePrefix),
+	v1EnumUnusedCheckerBuilder = newUnusedCheckerBuilder(
+		"ENUM_UNUSED",
+		"enums are reachable from an RPC or entry point (entry points are configurable)",
+		internal.CheckEnumUnused,
 	)
-	v1EnumValueUpperSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"ENUM_VALUE_UPPER_SNAKE_CASE",
-		"enum values are UPPER_SNAKE_CASE",
-		newAdapter(internal.CheckEnumValueUpperSnakeCase),
+	v1EnumValuePrefixCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"ENUM_VALUE_PREFIX",
+			"enum values are prefixed with ENUM_NAME_UPPER_SNAKE_CASE",
+			newAdapter(internal.CheckEnumValuePrefix),
+		),
 	)
-	v1EnumZeroValueSuffixCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
-		"ENUM_ZERO_VALUE_SUFFIX",
-		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
-			if configBuilder.EnumZeroValueSuffix == "" {
-				return "", errors.New("enum_zero_value_suffix is empty")
-			}
-			return "enum zero values are suffixed with " + configBuilder.EnumZeroValueSuffix + " (suffix is configurable)", nil
-		},
-		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
-			if configBuilder.EnumZeroValueSuffix == "" {
-				return nil, errors.New("enum_zero_value_suffix is empty")
-			}
-			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-				return internal.CheckEnumZeroValueSuffix(id, ignoreFunc, files, configBuilder.EnumZeroValueSuffix)
-			}), nil
-		},
+	v1EnumValueUpperSnakeCaseCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"ENUM_VALUE_UPPER_SNAKE_CASE",
+			"enum values are UPPER_SNAKE_CASE",
+			newAdapter(internal.CheckEnumValueUpperSnakeCase),
+		),
+	)
+	v1EnumZeroValueSuffixCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewCheckerBuilder(
+			"ENUM_ZERO_VALUE_SUFFIX",
+			func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+				if configBuilder.EnumZeroValueSuffix == "" {
+					return "", errors.New("enum_zero_value_suffix is empty")
+				}
+				return "enum zero values are suffixed with " + configBuilder.EnumZeroValueSuffix + " (suffix is configurable)", nil
+			},
+			func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+				if configBuilder.EnumZeroValueSuffix == "" {
+					return nil, errors.New("enum_zero_value_suffix is empty")
+				}
+				return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+					return internal.CheckEnumZeroValueSuffix(id, ignoreFunc, files, configBuilder.EnumZeroValueSuffix)
+				}), nil
+			},
+			"enum_zero_value_suffix",
+		),
 	)
-	v1FieldLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"FIELD_LOWER_SNAKE_CASE",
-		"field names are lower_snake_case",
-		newAdapter(internal.CheckFieldLowerSnakeCase),
+	v1ExtensionUnusedCheckerBuilder = newUnusedCheckerBuilder(
+		"EXTENSION_UNUSED",
+		"extensions extend messages that are reachable from an RPC or entry point (entry points are configurable)",
+		internal.CheckExtensionUnused,
+	)
+	v1FieldDeprecatedTypeCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"FIELD_DEPRECATED_TYPE",
+		"fields that use a deprecated message or enum type are deprecated",
+		newAdapter(internal.CheckFieldDeprecatedType),
+	)
+	v1FieldLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"FIELD_LOWER_SNAKE_CASE",
+			"field names are lower_snake_case",
+			newAdapter(internal.CheckFieldLowerSnakeCase),
+		),
+	)
+	v1FieldNamePatternCheckerBuilder = newNamePatternCheckerBuilder(
+		"FIELD_NAME_PATTERN",
+		"field",
+		"field_name_pattern",
+		func(configBuilder bufcheckinternal.ConfigBuilder) string {
+			return configBuilder.FieldNamePattern
+		},
+		internal.CheckFieldNamePattern,
 	)
 	v1FieldNoDescriptorCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"FIELD_NO_DESCRIPTOR",
@@ -388,6 +525,42 @@ var (
 		"filenames are lower_snake_case",
 		newAdapter(internal.CheckFileLowerSnakeCase),
 	)
+	v1ImportAllowedCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"IMPORT_ALLOWED",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if err := validateImportRules(configBuilder.ImportRules); err != nil {
+				return "", err
+			}
+			return "imports are allowed by the import rules (rules are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if err := validateImportRules(configBuilder.ImportRules); err != nil {
+				return nil, err
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckImportAllowed(id, ignoreFunc, files, configBuilder.ImportRules)
+			}), nil
+		},
+		"import_rules",
+	)
+	v1ImportTransitiveAllowedCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"IMPORT_TRANSITIVE_ALLOWED",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if err := validateImportRules(configBuilder.ImportRules); err != nil {
+				return "", err
+			}
+			return "transitive imports are allowed by the import rules (rules are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if err := validateImportRules(configBuilder.ImportRules); err != nil {
+				return nil, err
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckImportTransitiveAllowed(id, ignoreFunc, files, configBuilder.ImportRules)
+			}), nil
+		},
+		"import_rules",
+	)
 	v1ImportNoPublicCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"IMPORT_NO_PUBLIC",
 		"imports are not public",
@@ -398,15 +571,49 @@ var (
 		"imports are not weak",
 		newAdapter(internal.CheckImportNoWeak),
 	)
+	v1MessageMaxFieldsCheckerBuilder = newMaxCheckerBuilder(
+		"MESSAGE_MAX_FIELDS",
+		"messages have at most %d fields",
+		"message_max_fields",
+		func(configBuilder bufcheckinternal.ConfigBuilder) int {
+			return configBuilder.MessageMaxFields
+		},
+		internal.CheckMessageMaxFields,
+	)
+	v1MessageMaxNestingDepthCheckerBuilder = newMaxCheckerBuilder(
+		"MESSAGE_MAX_NESTING_DEPTH",
+		"messages are nested at most %d levels deep, where top-level messages are one level deep",
+		"message_max_nesting_depth",
+		func(configBuilder bufcheckinternal.ConfigBuilder) int {
+			return configBuilder.MessageMaxNestingDepth
+		},
+		internal.CheckMessageMaxNestingDepth,
+	)
+	v1MessageNamePatternCheckerBuilder = newNamePatternCheckerBuilder(
+		"MESSAGE_NAME_PATTERN",
+		"message",
+		"message_name_pattern",
+		func(configBuilder bufcheckinternal.ConfigBuilder) string {
+			return configBuilder.MessageNamePattern
+		},
+		internal.CheckMessageNamePattern,
+	)
 	v1MessagePascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"MESSAGE_PASCAL_CASE",
 		"messages are PascalCase",
 		newAdapter(internal.CheckMessagePascalCase),
 	)
-	v1OneofLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"ONEOF_LOWER_SNAKE_CASE",
-		"oneof names are lower_snake_case",
-		newAdapter(internal.CheckOneofLowerSnakeCase),
+	v1MessageUnusedCheckerBuilder = newUnusedCheckerBuilder(
+		"MESSAGE_UNUSED",
+		"messages are reachable from an RPC or entry point (entry points are configurable)",
+		internal.CheckMessageUnused,
+	)
+	v1OneofLowerSnakeCaseCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"ONEOF_LOWER_SNAKE_CASE",
+			"oneof names are lower_snake_case",
+			newAdapter(internal.CheckOneofLowerSnakeCase),
+		),
 	)
 	v1PackageDefinedCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"PACKAGE_DEFINED",
@@ -423,50 +630,134 @@ var (
 		"packages are lower_snake.case",
 		newAdapter(internal.CheckPackageLowerSnakeCase),
 	)
-	v1PackageSameCsharpNamespaceCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_CSHARP_NAMESPACE",
-		"all files with a given package have the same value for the csharp_namespace option",
-		newAdapter(internal.CheckPackageSameCsharpNamespace),
+	v1PackageSameCsharpNamespaceCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_CSHARP_NAMESPACE",
+			"all files with a given package have the same value for the csharp_namespace option",
+			newAdapter(internal.CheckPackageSameCsharpNamespace),
+		),
 	)
 	v1PackageSameDirectoryCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"PACKAGE_SAME_DIRECTORY",
 		"all files with a given package are in the same directory",
 		newAdapter(internal.CheckPackageSameDirectory),
 	)
-	v1PackageSameGoPackageCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_GO_PACKAGE",
-		"all files with a given package have the same value for the go_package option",
-		newAdapter(internal.CheckPackageSameGoPackage),
-	)
-	v1PackageSameJavaMultipleFilesCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_JAVA_MULTIPLE_FILES",
-		"all files with a given package have the same value for the java_multiple_files option",
-		newAdapter(internal.CheckPackageSameJavaMultipleFiles),
-	)
-	v1PackageSameJavaPackageCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_JAVA_PACKAGE",
-		"all files with a given package have the same value for the java_package option",
-		newAdapter(internal.CheckPackageSameJavaPackage),
-	)
-	v1PackageSamePhpNamespaceCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_PHP_NAMESPACE",
-		"all files with a given package have the same value for the php_namespace option",
-		newAdapter(internal.CheckPackageSamePhpNamespace),
-	)
-	v1PackageSameRubyPackageCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_RUBY_PACKAGE",
-		"all files with a given package have the same value for the ruby_package option",
-		newAdapter(internal.CheckPackageSameRubyPackage),
-	)
-	v1PackageSameSwiftPrefixCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
-		"PACKAGE_SAME_SWIFT_PREFIX",
-		"all files with a given package have the same value for the swift_prefix option",
-		newAdapter(internal.CheckPackageSameSwiftPrefix),
-	)
-	v1PackageVersionSuffixCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+	v1PackageSameGoPackageCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_GO_PACKAGE",
+			"all files with a given package have the same value for the go_package option",
+			newAdapter(internal.CheckPackageSameGoPackage),
+		),
+	)
+	v1PackageSameJavaMultipleFilesCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_JAVA_MULTIPLE_FILES",
+			"all files with a given package have the same value for the java_multiple_files option",
+			newAdapter(internal.CheckPackageSameJavaMultipleFiles),
+		),
+	)
+	v1PackageSameJavaPackageCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_JAVA_PACKAGE",
+			"all files with a given package have the same value for the java_package option",
+			newAdapter(internal.CheckPackageSameJavaPackage),
+		),
+	)
+	v1PackageSamePhpNamespaceCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_PHP_NAMESPACE",
+			"all files with a given package have the same value for the php_namespace option",
+			newAdapter(internal.CheckPackageSamePhpNamespace),
+		),
+	)
+	v1PackageSameRubyPackageCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_RUBY_PACKAGE",
+			"all files with a given package have the same value for the ruby_package option",
+			newAdapter(internal.CheckPackageSameRubyPackage),
+		),
+	)
+	v1PackageSameSwiftPrefixCheckerBuilder = bufcheckinternal.NewFixableCheckerBuilder(
+		bufcheckinternal.NewNopCheckerBuilder(
+			"PACKAGE_SAME_SWIFT_PREFIX",
+			"all files with a given package have the same value for the swift_prefix option",
+			newAdapter(internal.CheckPackageSameSwiftPrefix),
+		),
+	)
+	v1PackageVersionDirectoryMatchCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"PACKAGE_VERSION_DIRECTORY_MATCH",
+		"all files with a versioned package are in a directory that matches their package name",
+		newAdapter(internal.CheckPackageVersionDirectoryMatch),
+	)
+	v1PackageVersionSuffixCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"PACKAGE_VERSION_SUFFIX",
-		`the last component of all packages is a version of the form v\d+, v\d+test.*, v\d+(alpha|beta)\d+, or v\d+p\d+(alpha|beta)\d+, where numbers are >=1`,
-		newAdapter(internal.CheckPackageVersionSuffix),
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if _, err := newPackageVersionSuffixForms(configBuilder.PackageVersionSuffixForms); err != nil {
+				return "", err
+			}
+			purpose := "the last component of all packages is a version of one of the forms " + stringutil.SliceToString(configBuilder.PackageVersionSuffixForms) + " (forms are configurable)"
+			if len(configBuilder.PackageVersionSuffixAllowUnversioned) > 0 {
+				purpose += ", except for unversioned packages in " + stringutil.SliceToString(configBuilder.PackageVersionSuffixAllowUnversioned)
+			}
+			return purpose, nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			forms, err := newPackageVersionSuffixForms(configBuilder.PackageVersionSuffixForms)
+			if err != nil {
+				return nil, err
+			}
+			allowUnversionedRootPaths := make(map[string]struct{}, len(configBuilder.PackageVersionSuffixAllowUnversioned))
+			for _, rootPath := range configBuilder.PackageVersionSuffixAllowUnversioned {
+				rootPath, err := normalpath.NormalizeAndValidate(rootPath)
+				if err != nil {
+					return nil, fmt.Errorf("package_version_suffix_allow_unversioned: %v", err)
+				}
+				allowUnversionedRootPaths[rootPath] = struct{}{}
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckPackageVersionSuffix(id, ignoreFunc, files, forms, allowUnversionedRootPaths)
+			}), nil
+		},
+		"package_version_suffix_forms",
+		"package_version_suffix_allow_unversioned",
+	)
+	v1RPCIdempotencyLevelDefinedCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"RPC_IDEMPOTENCY_LEVEL_DEFINED",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if configBuilder.RPCIdempotencyLevelDisallowUnknown {
+				return "RPCs set the idempotency_level option to a value other than IDEMPOTENCY_UNKNOWN (configurable)", nil
+			}
+			return "RPCs set the idempotency_level option (configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckRPCIdempotencyLevelDefined(
+					id,
+					ignoreFunc,
+					files,
+					configBuilder.RPCIdempotencyLevelDisallowUnknown,
+				)
+			}), nil
+		},
+		"rpc_idempotency_level_disallow_unknown",
+	)
+	v1RPCNameVerbCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
+		"RPC_NAME_VERB",
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if len(configBuilder.RPCVerbs) == 0 {
+				return "", errors.New("rpc_verbs is empty")
+			}
+			return "RPC names are a verb followed by a noun, where the verb is one of " + stringutil.SliceToString(configBuilder.RPCVerbs) + " (verbs are configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if len(configBuilder.RPCVerbs) == 0 {
+				return nil, errors.New("rpc_verbs is empty")
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return internal.CheckRPCNameVerb(id, ignoreFunc, files, configBuilder.RPCVerbs)
+			}), nil
+		},
+		"rpc_verbs",
 	)
 	v1RPCNoClientStreamingCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"RPC_NO_CLIENT_STREAMING",
@@ -500,6 +791,9 @@ var (
 				)
 			}), nil
 		},
+		"rpc_allow_same_request_response",
+		"rpc_allow_google_protobuf_empty_requests",
+		"rpc_allow_google_protobuf_empty_responses",
 	)
 	v1RPCRequestStandardNameCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"RPC_REQUEST_STANDARD_NAME",
@@ -516,6 +810,7 @@ var (
 				)
 			}), nil
 		},
+		"rpc_allow_google_protobuf_empty_requests",
 	)
 	v1RPCResponseStandardNameCheckerBuilder = bufcheckinternal.NewCheckerBuilder(
 		"RPC_RESPONSE_STANDARD_NAME",
@@ -532,6 +827,25 @@ var (
 				)
 			}), nil
 		},
+		"rpc_allow_google_protobuf_empty_responses",
+	)
+	v1ServiceMaxRPCsCheckerBuilder = newMaxCheckerBuilder(
+		"SERVICE_MAX_RPCS",
+		"services have at most %d RPCs",
+		"service_max_rpcs",
+		func(configBuilder bufcheckinternal.ConfigBuilder) int {
+			return configBuilder.ServiceMaxRPCs
+		},
+		internal.CheckServiceMaxRPCs,
+	)
+	v1ServiceNamePatternCheckerBuilder = newNamePatternCheckerBuilder(
+		"SERVICE_NAME_PATTERN",
+		"service",
+		"service_name_pattern",
+		func(configBuilder bufcheckinternal.ConfigBuilder) string {
+			return configBuilder.ServiceNamePattern
+		},
+		internal.CheckServiceNamePattern,
 	)
 	v1ServicePascalCaseCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
 		"SERVICE_PASCAL_CASE",
@@ -554,9 +868,120 @@ var (
 				return internal.CheckServiceSuffix(id, ignoreFunc, files, configBuilder.ServiceSuffix)
 			}), nil
 		},
+		"service_suffix",
+	)
+	v1ValidateRulesBoundsCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"VALIDATE_RULES_BOUNDS",
+		"protoc-gen-validate constraints do not have contradictory bounds",
+		newAdapter(internal.CheckValidateRulesBounds),
+	)
+	v1ValidateRulesSyntaxCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"VALIDATE_RULES_SYNTAX",
+		"protoc-gen-validate constraints are well-formed and have valid regular expressions",
+		newAdapter(internal.CheckValidateRulesSyntax),
+	)
+	v1ValidateRulesTypeMatchCheckerBuilder = bufcheckinternal.NewNopCheckerBuilder(
+		"VALIDATE_RULES_TYPE_MATCH",
+		"protoc-gen-validate constraints match the types of the fields they are applied to",
+		newAdapter(internal.CheckValidateRulesTypeMatch),
 	)
 )
 
+// newMaxCheckerBuilder returns a new CheckerBuilder for a checker that checks
+// that a count is at most the maximum configured with configKey.
+//
+// purposeFormat is formatted with the maximum.
+func newMaxCheckerBuilder(
+	id string,
+	purposeFormat string,
+	configKey string,
+	getMax func(bufcheckinternal.ConfigBuilder) int,
+	check func(string, bufcheckinternal.IgnoreFunc, []protosource.File, int) ([]bufanalysis.FileAnnotation, error),
+) *bufcheckinternal.CheckerBuilder {
+	return bufcheckinternal.NewCheckerBuilder(
+		id,
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			max := getMax(configBuilder)
+			if max < 1 {
+				return "", fmt.Errorf("%s must be at least 1 but was %d", configKey, max)
+			}
+			return fmt.Sprintf(purposeFormat, max) + " (maximum is configurable)", nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			max := getMax(configBuilder)
+			if max < 1 {
+				return nil, fmt.Errorf("%s must be at least 1 but was %d", configKey, max)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return check(id, ignoreFunc, files, max)
+			}), nil
+		},
+		configKey,
+	)
+}
+
+// newNamePatternCheckerBuilder returns a new CheckerBuilder for a checker that
+// checks that names match the regular expression configured with configKey.
+func newNamePatternCheckerBuilder(
+	id string,
+	kind string,
+	configKey string,
+	getPattern func(bufcheckinternal.ConfigBuilder) string,
+	check func(string, bufcheckinternal.IgnoreFunc, []protosource.File, *regexp.Regexp) ([]bufanalysis.FileAnnotation, error),
+) *bufcheckinternal.CheckerBuilder {
+	return bufcheckinternal.NewCheckerBuilder(
+		id,
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			pattern := getPattern(configBuilder)
+			if pattern == "" {
+				return "", fmt.Errorf("%s is empty", configKey)
+			}
+			return fmt.Sprintf("%s names match the pattern %s (pattern is configurable)", kind, pattern), nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			pattern := getPattern(configBuilder)
+			if pattern == "" {
+				return nil, fmt.Errorf("%s is empty", configKey)
+			}
+			regexpPattern, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", configKey, err)
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return check(id, ignoreFunc, files, regexpPattern)
+			}), nil
+		},
+		configKey,
+	)
+}
+
+// newUnusedCheckerBuilder returns a new CheckerBuilder for a checker that
+// checks that types are reachable from the RPCs or the unused_entry_points.
+func newUnusedCheckerBuilder(
+	id string,
+	purpose string,
+	check func(string, bufcheckinternal.IgnoreFunc, []protosource.File, []string) ([]bufanalysis.FileAnnotation, error),
+) *bufcheckinternal.CheckerBuilder {
+	return bufcheckinternal.NewCheckerBuilder(
+		id,
+		func(configBuilder bufcheckinternal.ConfigBuilder) (string, error) {
+			if err := validateUnusedEntryPoints(configBuilder.UnusedEntryPoints); err != nil {
+				return "", err
+			}
+			return purpose, nil
+		},
+		func(configBuilder bufcheckinternal.ConfigBuilder) (bufcheckinternal.CheckFunc, error) {
+			if err := validateUnusedEntryPoints(configBuilder.UnusedEntryPoints); err != nil {
+				return nil, err
+			}
+			return bufcheckinternal.CheckFunc(func(id string, ignoreFunc bufcheckinternal.IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				return check(id, ignoreFunc, files, configBuilder.UnusedEntryPoints)
+			}), nil
+		},
+		"unused_entry_points",
+	)
+}
+
 func newAdapter(
 	f func(string, bufcheckinternal.IgnoreFunc, []protosource.File) ([]bufanalysis.FileAnnotation, error),
 ) func(string, bufcheckinternal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
@@ -564,3 +989,75 @@ func newAdapter(
 		return f(id, ignoreFunc, files)
 	}
 }
+
+func validateUnusedEntryPoints(entryPoints []string) error {
+	for _, entryPoint := range entryPoints {
+		if entryPoint == "" {
+			return errors.New("unused_entry_points contains an empty entry point")
+		}
+		if strings.HasPrefix(entryPoint, ".") || strings.Contains(entryPoint, "/") {
+			return fmt.Errorf("invalid unused_entry_points entry point %q, must be a fully-qualified name or package pattern", entryPoint)
+		}
+		if strings.Contains(strings.TrimSuffix(entryPoint, ".*"), "*") {
+			return fmt.Errorf("invalid unused_entry_points entry point %q, only a trailing \".*\" is allowed", entryPoint)
+		}
+	}
+	return nil
+}
+
+func validateImportRules(importRules []bufcheckinternal.ImportRule) error {
+	for _, importRule := range importRules {
+		if err := validateImportRulePattern(importRule.From); err != nil {
+			return fmt.Errorf("import_rules from: %v", err)
+		}
+		if len(importRule.Allow) == 0 && len(importRule.Deny) == 0 {
+			return fmt.Errorf("import_rules for %q must set allow or deny", importRule.From)
+		}
+		for _, pattern := range importRule.Allow {
+			if err := validateImportRulePattern(pattern); err != nil {
+				return fmt.Errorf("import_rules allow for %q: %v", importRule.From, err)
+			}
+		}
+		for _, pattern := range importRule.Deny {
+			if err := validateImportRulePattern(pattern); err != nil {
+				return fmt.Errorf("import_rules deny for %q: %v", importRule.From, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateImportRulePattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	if pattern == "*" {
+		return nil
+	}
+	if strings.Contains(pattern, "/") {
+		normalizedPattern, err := normalpath.NormalizeAndValidate(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return normalpath.ValidateGlob(normalizedPattern)
+	}
+	if strings.Contains(strings.TrimSuffix(pattern, ".*"), "*") {
+		return fmt.Errorf("invalid pattern %q, only \"*\" or a trailing \".*\" are allowed in package patterns", pattern)
+	}
+	return nil
+}
+
+func newPackageVersionSuffixForms(formStrings []string) (map[string]struct{}, error) {
+	if len(formStrings) == 0 {
+		return nil, errors.New("package_version_suffix_forms is empty")
+	}
+	allForms := stringutil.SliceToMap(internal.AllPackageVersionSuffixForms)
+	forms := make(map[string]struct{}, len(formStrings))
+	for _, form := range formStrings {
+		if _, ok := allForms[form]; !ok {
+			return nil, fmt.Errorf("unknown package_version_suffix_forms value %q, must be one of %s", form, stringutil.SliceToString(internal.AllPackageVersionSuffixForms))
+		}
+		forms[form] = struct{}{}
+	}
+	return forms, nil
+}
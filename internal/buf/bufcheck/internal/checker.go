@@ -20,6 +20,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
 
 // IgnoreFunc is an ignore function.
@@ -33,17 +34,23 @@ type Checker struct {
 	id         string
 	categories []string
 	purpose    string
+	isDefault  bool
+	isFixable  bool
+	configKeys []string
 	checkFunc  CheckFunc
 }
 
 // newChecker returns a new Checker.
 //
 // Categories will be sorted and purpose will have "Checks that "
-// prepended and "." appended.
+// prepended and "." appended. ConfigKeys will be sorted.
 func newChecker(
 	id string,
 	categories []string,
 	purpose string,
+	isDefault bool,
+	isFixable bool,
+	configKeys []string,
 	checkFunc CheckFunc,
 ) *Checker {
 	c := make([]string, len(categories))
@@ -58,6 +65,9 @@ func newChecker(
 		id:         id,
 		categories: c,
 		purpose:    "Checks that " + purpose + ".",
+		isDefault:  isDefault,
+		isFixable:  isFixable,
+		configKeys: stringutil.SliceToUniqueSortedSlice(configKeys),
 		checkFunc:  checkFunc,
 	}
 }
@@ -77,9 +87,33 @@ func (c *Checker) Purpose() string {
 	return c.purpose
 }
 
+// IsDefault implements Checker.
+func (c *Checker) IsDefault() bool {
+	return c.isDefault
+}
+
+// IsFixable implements Checker.
+func (c *Checker) IsFixable() bool {
+	return c.isFixable
+}
+
+// ConfigKeys implements Checker.
+func (c *Checker) ConfigKeys() []string {
+	return c.configKeys
+}
+
 // MarshalJSON implements Checker.
 func (c *Checker) MarshalJSON() ([]byte, error) {
-	return json.Marshal(checkerJSON{ID: c.id, Categories: c.categories, Purpose: c.purpose})
+	return json.Marshal(
+		checkerJSON{
+			ID:         c.id,
+			Categories: c.categories,
+			Purpose:    c.purpose,
+			Default:    c.isDefault,
+			Fixable:    c.isFixable,
+			ConfigKeys: c.configKeys,
+		},
+	)
 }
 
 func (c *Checker) check(ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
@@ -90,4 +124,7 @@ type checkerJSON struct {
 	ID         string   `json:"id" yaml:"id"`
 	Categories []string `json:"categories" yaml:"categories"`
 	Purpose    string   `json:"purpose" yaml:"purpose"`
+	Default    bool     `json:"default" yaml:"default"`
+	Fixable    bool     `json:"fixable" yaml:"fixable"`
+	ConfigKeys []string `json:"config_keys,omitempty" yaml:"config_keys,omitempty"`
 }
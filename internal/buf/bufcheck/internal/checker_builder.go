@@ -24,18 +24,25 @@ type CheckerBuilder struct {
 	id         string
 	newPurpose func(ConfigBuilder) (string, error)
 	newCheck   func(ConfigBuilder) (CheckFunc, error)
+	configKeys []string
+	fixable    bool
 }
 
 // NewCheckerBuilder returns a new CheckerBuilder.
+//
+// The configKeys are the config keys that newPurpose and newCheck read
+// from the ConfigBuilder, such as "enum_zero_value_suffix".
 func NewCheckerBuilder(
 	id string,
 	newPurpose func(ConfigBuilder) (string, error),
 	newCheck func(ConfigBuilder) (CheckFunc, error),
+	configKeys ...string,
 ) *CheckerBuilder {
 	return &CheckerBuilder{
 		id:         id,
 		newPurpose: newPurpose,
 		newCheck:   newCheck,
+		configKeys: configKeys,
 	}
 }
 
@@ -53,13 +60,21 @@ func NewNopCheckerBuilder(
 	)
 }
 
+// NewFixableCheckerBuilder returns a copy of the CheckerBuilder for a checker
+// that attaches edits to its FileAnnotations that fix them.
+func NewFixableCheckerBuilder(checkerBuilder *CheckerBuilder) *CheckerBuilder {
+	fixableCheckerBuilder := *checkerBuilder
+	fixableCheckerBuilder.fixable = true
+	return &fixableCheckerBuilder
+}
+
 // NewChecker returns a new Checker.
 //
 // Categories will be sorted and Purpose will be prepended with "Checks that "
 // and appended with ".".
 //
 // Categories is an actual copy from the checkerBuilder.
-func (c *CheckerBuilder) NewChecker(configBuilder ConfigBuilder, categories []string) (*Checker, error) {
+func (c *CheckerBuilder) NewChecker(configBuilder ConfigBuilder, categories []string, isDefault bool) (*Checker, error) {
 	purpose, err := c.newPurpose(configBuilder)
 	if err != nil {
 		return nil, err
@@ -72,6 +87,9 @@ func (c *CheckerBuilder) NewChecker(configBuilder ConfigBuilder, categories []st
 		c.id,
 		categories,
 		purpose,
+		isDefault,
+		c.fixable,
+		c.configKeys,
 		check,
 	), nil
 }
@@ -15,10 +15,12 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
@@ -26,8 +28,48 @@ import (
 const (
 	defaultEnumZeroValueSuffix = "_UNSPECIFIED"
 	defaultServiceSuffix       = "Service"
+	// PascalCase that allows consecutive capitals, such as HTTPServer
+	defaultMessageNamePattern = "^[A-Z][a-zA-Z0-9]*$"
+	defaultServiceNamePattern = "^[A-Z][a-zA-Z0-9]*$"
+	// lower_snake_case
+	defaultFieldNamePattern = "^[a-z][a-z0-9]*(_[a-z0-9]+)*$"
+
+	defaultMessageMaxNestingDepth = 4
+	defaultMessageMaxFields       = 100
+	defaultEnumMaxValues          = 250
+	defaultServiceMaxRPCs         = 50
 )
 
+var defaultRPCVerbs = []string{
+	"Create",
+	"Delete",
+	"Get",
+	"List",
+	"Update",
+}
+
+var defaultPackageVersionSuffixForms = []string{
+	"alpha",
+	"beta",
+	"patch",
+	"stable",
+	"test",
+}
+
+var defaultMessageSameOptionExtensions = []string{
+	"google.api.resource",
+}
+
+var defaultServiceSameOptionExtensions = []string{
+	"google.api.default_host",
+	"google.api.oauth_scopes",
+}
+
+var defaultRPCSameOptionExtensions = []string{
+	"google.api.http",
+	"google.api.routing",
+}
+
 // Config is the check config.
 type Config struct {
 	// Checkers are the checkers to run.
@@ -38,6 +80,21 @@ type Config struct {
 
 	IgnoreRootPaths     map[string]struct{}
 	IgnoreIDToRootPaths map[string]map[string]struct{}
+	// IgnoreRootPathGlobs and IgnoreIDToRootPathGlobs are the ignore paths
+	// that are glob patterns, see normalpath.MatchGlob.
+	IgnoreRootPathGlobs     []string
+	IgnoreIDToRootPathGlobs map[string][]string
+	// IgnoreIDToSymbols are the fully-qualified names of the symbols to ignore
+	// for each ID, without a leading period.
+	//
+	// Symbols nested within an ignored symbol are also ignored.
+	IgnoreIDToSymbols map[string]map[string]struct{}
+	// WarnIDs are the IDs of the checkers whose failures are warnings.
+	//
+	// These may include IDs of checkers that are not run.
+	WarnIDs map[string]struct{}
+	// Plugins are the check plugins to run in addition to the Checkers.
+	Plugins []*bufcheck.Plugin
 
 	AllowCommentIgnores bool
 }
@@ -47,8 +104,23 @@ type ConfigBuilder struct {
 	Use    []string
 	Except []string
 
+	// Categories are user-defined categories that can be used in Use, Except,
+	// and IgnoreIDOrCategoryToRootPaths.
+	//
+	// Each category is defined by an expression of IDs and categories joined by
+	// "+" for union and "-" for difference, evaluated from left to right, for
+	// example "DEFAULT + COMMENTS - PACKAGE_VERSION_SUFFIX". Expressions can
+	// reference other user-defined categories.
+	Categories map[string]string
+
+	// IgnoreRootPaths and IgnoreIDOrCategoryToRootPaths are paths to files or
+	// directories, or glob patterns such as "**/vendor/**".
 	IgnoreRootPaths               []string
 	IgnoreIDOrCategoryToRootPaths map[string][]string
+	IgnoreIDOrCategoryToSymbols   map[string][]string
+	// Warn are the IDs and categories of the checkers whose failures are
+	// warnings instead of errors.
+	Warn []string
 
 	AllowCommentIgnores bool
 
@@ -57,6 +129,41 @@ type ConfigBuilder struct {
 	RPCAllowGoogleProtobufEmptyRequests  bool
 	RPCAllowGoogleProtobufEmptyResponses bool
 	ServiceSuffix                        string
+	MessageNamePattern                   string
+	FieldNamePattern                     string
+	ServiceNamePattern                   string
+	RPCVerbs                             []string
+	PackageVersionSuffixForms            []string
+	PackageVersionSuffixAllowUnversioned []string
+	MessageMaxNestingDepth               int
+	MessageMaxFields                     int
+	EnumMaxValues                        int
+	ServiceMaxRPCs                       int
+	ImportRules                          []ImportRule
+	RPCIdempotencyLevelDisallowUnknown   bool
+	MessageSameOptionExtensions          []string
+	ServiceSameOptionExtensions          []string
+	RPCSameOptionExtensions              []string
+	UnusedEntryPoints                    []string
+}
+
+// ImportRule restricts the files that files matching From can import.
+//
+// Package patterns are either a package, a package followed by ".*" to match the
+// package and all of its sub-packages, or "*" to match all packages. Patterns
+// that contain a "/" are path patterns, which are matched against the paths of
+// files and their directories, and can be globs such as "foo/internal/**".
+type ImportRule struct {
+	// From is the pattern for the importing files.
+	From string
+	// Allow are the patterns for the files that can be imported.
+	//
+	// If empty, all files not matched by Deny can be imported.
+	Allow []string
+	// Deny are the patterns for the files that cannot be imported.
+	//
+	// Takes precedence over Allow.
+	Deny []string
 }
 
 // NewConfig returns a new Config.
@@ -81,6 +188,7 @@ func newConfig(
 ) (*Config, error) {
 	configBuilder.Use = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.Use)
 	configBuilder.Except = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.Except)
+	configBuilder.Warn = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.Warn)
 	if len(configBuilder.Use) == 0 {
 		// default behavior
 		configBuilder.Use = defaultCategories
@@ -91,10 +199,53 @@ func newConfig(
 	if configBuilder.ServiceSuffix == "" {
 		configBuilder.ServiceSuffix = defaultServiceSuffix
 	}
+	if configBuilder.MessageNamePattern == "" {
+		configBuilder.MessageNamePattern = defaultMessageNamePattern
+	}
+	if configBuilder.FieldNamePattern == "" {
+		configBuilder.FieldNamePattern = defaultFieldNamePattern
+	}
+	if configBuilder.ServiceNamePattern == "" {
+		configBuilder.ServiceNamePattern = defaultServiceNamePattern
+	}
+	configBuilder.RPCVerbs = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.RPCVerbs)
+	if len(configBuilder.RPCVerbs) == 0 {
+		configBuilder.RPCVerbs = defaultRPCVerbs
+	}
+	configBuilder.PackageVersionSuffixForms = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.PackageVersionSuffixForms)
+	if len(configBuilder.PackageVersionSuffixForms) == 0 {
+		configBuilder.PackageVersionSuffixForms = defaultPackageVersionSuffixForms
+	}
+	configBuilder.PackageVersionSuffixAllowUnversioned = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.PackageVersionSuffixAllowUnversioned)
+	if configBuilder.MessageMaxNestingDepth == 0 {
+		configBuilder.MessageMaxNestingDepth = defaultMessageMaxNestingDepth
+	}
+	if configBuilder.MessageMaxFields == 0 {
+		configBuilder.MessageMaxFields = defaultMessageMaxFields
+	}
+	if configBuilder.EnumMaxValues == 0 {
+		configBuilder.EnumMaxValues = defaultEnumMaxValues
+	}
+	if configBuilder.ServiceMaxRPCs == 0 {
+		configBuilder.ServiceMaxRPCs = defaultServiceMaxRPCs
+	}
+	configBuilder.MessageSameOptionExtensions = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.MessageSameOptionExtensions)
+	if len(configBuilder.MessageSameOptionExtensions) == 0 {
+		configBuilder.MessageSameOptionExtensions = defaultMessageSameOptionExtensions
+	}
+	configBuilder.ServiceSameOptionExtensions = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.ServiceSameOptionExtensions)
+	if len(configBuilder.ServiceSameOptionExtensions) == 0 {
+		configBuilder.ServiceSameOptionExtensions = defaultServiceSameOptionExtensions
+	}
+	configBuilder.RPCSameOptionExtensions = stringutil.SliceToUniqueSortedSliceFilterEmptyStrings(configBuilder.RPCSameOptionExtensions)
+	if len(configBuilder.RPCSameOptionExtensions) == 0 {
+		configBuilder.RPCSameOptionExtensions = defaultRPCSameOptionExtensions
+	}
 	return newConfigForCheckerBuilders(
 		configBuilder,
 		checkerBuilders,
 		idToCategories,
+		defaultCategories,
 	)
 }
 
@@ -103,6 +254,7 @@ func newConfigForCheckerBuilders(
 	configBuilder ConfigBuilder,
 	checkerBuilders []*CheckerBuilder,
 	idToCategories map[string][]string,
+	defaultCategories []string,
 ) (*Config, error) {
 	// this checks that there are not duplicate IDs for a given revision
 	// which would be a system error
@@ -111,6 +263,9 @@ func newConfigForCheckerBuilders(
 		return nil, err
 	}
 	categoryToIDs := getCategoryToIDs(idToCategories)
+	if err := addCustomCategories(categoryToIDs, configBuilder.Categories, idToCategories); err != nil {
+		return nil, err
+	}
 	useIDMap, err := transformToIDMap(configBuilder.Use, idToCategories, categoryToIDs)
 	if err != nil {
 		return nil, err
@@ -119,6 +274,14 @@ func newConfigForCheckerBuilders(
 	if err != nil {
 		return nil, err
 	}
+	defaultIDMap, err := transformToIDMap(defaultCategories, idToCategories, categoryToIDs)
+	if err != nil {
+		return nil, err
+	}
+	warnIDMap, err := transformToIDMap(configBuilder.Warn, idToCategories, categoryToIDs)
+	if err != nil {
+		return nil, err
+	}
 
 	// this removes duplicates
 	// we already know that a given checker with the same ID is equivalent
@@ -148,7 +311,8 @@ func newConfigForCheckerBuilders(
 		if err != nil {
 			return nil, err
 		}
-		checker, err := checkerBuilder.NewChecker(configBuilder, categories)
+		_, isDefault := defaultIDMap[checkerBuilder.id]
+		checker, err := checkerBuilder.NewChecker(configBuilder, categories, isDefault)
 		if err != nil {
 			return nil, err
 		}
@@ -161,17 +325,19 @@ func newConfigForCheckerBuilders(
 		return nil, err
 	}
 	ignoreIDToRootPaths := make(map[string]map[string]struct{})
+	ignoreIDToRootPathGlobs := make(map[string][]string)
 	for id, rootPaths := range ignoreIDToRootPathsUnnormalized {
 		for rootPath := range rootPaths {
 			if rootPath == "" {
 				continue
 			}
-			rootPath, err := normalpath.NormalizeAndValidate(rootPath)
+			rootPath, isGlob, err := normalizeIgnoreRootPath(rootPath)
 			if err != nil {
 				return nil, err
 			}
-			if rootPath == "." {
-				return nil, fmt.Errorf("cannot specify %q as an ignore path", rootPath)
+			if isGlob {
+				ignoreIDToRootPathGlobs[id] = append(ignoreIDToRootPathGlobs[id], rootPath)
+				continue
 			}
 			resultRootPathMap, ok := ignoreIDToRootPaths[id]
 			if !ok {
@@ -181,30 +347,81 @@ func newConfigForCheckerBuilders(
 			resultRootPathMap[rootPath] = struct{}{}
 		}
 	}
+	for _, rootPathGlobs := range ignoreIDToRootPathGlobs {
+		sort.Strings(rootPathGlobs)
+	}
+
+	ignoreIDToSymbolsUnnormalized, err := transformToIDToListMap(configBuilder.IgnoreIDOrCategoryToSymbols, idToCategories, categoryToIDs)
+	if err != nil {
+		return nil, err
+	}
+	ignoreIDToSymbols := make(map[string]map[string]struct{})
+	for id, symbols := range ignoreIDToSymbolsUnnormalized {
+		for symbol := range symbols {
+			symbol = strings.TrimPrefix(symbol, ".")
+			if symbol == "" {
+				continue
+			}
+			if strings.ContainsAny(symbol, " /") || strings.HasSuffix(symbol, ".") {
+				return nil, fmt.Errorf("%q is not a valid fully-qualified symbol to ignore", symbol)
+			}
+			resultSymbolMap, ok := ignoreIDToSymbols[id]
+			if !ok {
+				resultSymbolMap = make(map[string]struct{})
+				ignoreIDToSymbols[id] = resultSymbolMap
+			}
+			resultSymbolMap[symbol] = struct{}{}
+		}
+	}
 
 	ignoreRootPaths := make(map[string]struct{}, len(configBuilder.IgnoreRootPaths))
+	var ignoreRootPathGlobs []string
 	for _, rootPath := range configBuilder.IgnoreRootPaths {
 		if rootPath == "" {
 			continue
 		}
-		rootPath, err := normalpath.NormalizeAndValidate(rootPath)
+		rootPath, isGlob, err := normalizeIgnoreRootPath(rootPath)
 		if err != nil {
 			return nil, err
 		}
-		if rootPath == "." {
-			return nil, fmt.Errorf("cannot specify %q as an ignore path", rootPath)
+		if isGlob {
+			ignoreRootPathGlobs = append(ignoreRootPathGlobs, rootPath)
+			continue
 		}
 		ignoreRootPaths[rootPath] = struct{}{}
 	}
 
 	return &Config{
-		Checkers:            resultCheckers,
-		IgnoreIDToRootPaths: ignoreIDToRootPaths,
-		IgnoreRootPaths:     ignoreRootPaths,
-		AllowCommentIgnores: configBuilder.AllowCommentIgnores,
+		Checkers:                resultCheckers,
+		IgnoreIDToRootPaths:     ignoreIDToRootPaths,
+		IgnoreIDToRootPathGlobs: ignoreIDToRootPathGlobs,
+		IgnoreIDToSymbols:       ignoreIDToSymbols,
+		IgnoreRootPaths:         ignoreRootPaths,
+		IgnoreRootPathGlobs:     ignoreRootPathGlobs,
+		WarnIDs:                 warnIDMap,
+		AllowCommentIgnores:     configBuilder.AllowCommentIgnores,
 	}, nil
 }
 
+// normalizeIgnoreRootPath normalizes and validates the ignore path, and
+// returns true if it is a glob pattern.
+func normalizeIgnoreRootPath(rootPath string) (string, bool, error) {
+	rootPath, err := normalpath.NormalizeAndValidate(rootPath)
+	if err != nil {
+		return "", false, err
+	}
+	if rootPath == "." {
+		return "", false, fmt.Errorf("cannot specify %q as an ignore path", rootPath)
+	}
+	if !normalpath.IsGlob(rootPath) {
+		return rootPath, false, nil
+	}
+	if err := normalpath.ValidateGlob(rootPath); err != nil {
+		return "", false, err
+	}
+	return rootPath, true, nil
+}
+
 func transformToIDMap(idsOrCategories []string, idToCategories map[string][]string, categoryToIDs map[string][]string) (map[string]struct{}, error) {
 	if len(idsOrCategories) == 0 {
 		return nil, nil
@@ -271,6 +488,115 @@ func getCategoryToIDs(idToCategories map[string][]string) map[string][]string {
 	return categoryToIDs
 }
 
+// addCustomCategories resolves the custom categories and adds them to categoryToIDs.
+func addCustomCategories(
+	categoryToIDs map[string][]string,
+	customCategoryToExpression map[string]string,
+	idToCategories map[string][]string,
+) error {
+	if len(customCategoryToExpression) == 0 {
+		return nil
+	}
+	customCategories := make([]string, 0, len(customCategoryToExpression))
+	for customCategory := range customCategoryToExpression {
+		if customCategory == "" {
+			return errors.New("category names cannot be empty")
+		}
+		if _, ok := idToCategories[customCategory]; ok {
+			return fmt.Errorf("category %q has the same name as a known id", customCategory)
+		}
+		if _, ok := categoryToIDs[customCategory]; ok {
+			return fmt.Errorf("category %q has the same name as a known category", customCategory)
+		}
+		customCategories = append(customCategories, customCategory)
+	}
+	sort.Strings(customCategories)
+	resolving := make(map[string]struct{})
+	var resolve func(string) ([]string, error)
+	resolve = func(customCategory string) ([]string, error) {
+		if ids, ok := categoryToIDs[customCategory]; ok {
+			return ids, nil
+		}
+		if _, ok := resolving[customCategory]; ok {
+			return nil, fmt.Errorf("category %q is defined in terms of itself", customCategory)
+		}
+		resolving[customCategory] = struct{}{}
+		defer delete(resolving, customCategory)
+		terms, err := parseCategoryExpression(customCategoryToExpression[customCategory])
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %v", customCategory, err)
+		}
+		idMap := make(map[string]struct{})
+		for _, term := range terms {
+			var ids []string
+			if _, ok := idToCategories[term.idOrCategory]; ok {
+				ids = []string{term.idOrCategory}
+			} else if _, ok := customCategoryToExpression[term.idOrCategory]; ok {
+				ids, err = resolve(term.idOrCategory)
+				if err != nil {
+					return nil, err
+				}
+			} else if categoryIDs, ok := categoryToIDs[term.idOrCategory]; ok {
+				ids = categoryIDs
+			} else {
+				return nil, fmt.Errorf("category %q: %q is not a known id or category", customCategory, term.idOrCategory)
+			}
+			for _, id := range ids {
+				if term.subtract {
+					delete(idMap, id)
+				} else {
+					idMap[id] = struct{}{}
+				}
+			}
+		}
+		ids := stringutil.MapToSortedSlice(idMap)
+		categoryToIDs[customCategory] = ids
+		return ids, nil
+	}
+	for _, customCategory := range customCategories {
+		if _, err := resolve(customCategory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type categoryExpressionTerm struct {
+	idOrCategory string
+	subtract     bool
+}
+
+// parseCategoryExpression parses an expression such as "DEFAULT + COMMENTS - PACKAGE_VERSION_SUFFIX".
+func parseCategoryExpression(expression string) ([]categoryExpressionTerm, error) {
+	fields := strings.Fields(strings.NewReplacer("+", " + ", "-", " - ").Replace(expression))
+	if len(fields) == 0 {
+		return nil, errors.New("expression is empty")
+	}
+	var terms []categoryExpressionTerm
+	subtract := false
+	expectTerm := true
+	for _, field := range fields {
+		switch field {
+		case "+", "-":
+			if expectTerm {
+				return nil, fmt.Errorf("expression %q has an unexpected %q", expression, field)
+			}
+			subtract = field == "-"
+			expectTerm = true
+		default:
+			if !expectTerm {
+				return nil, fmt.Errorf("expression %q is missing a \"+\" or \"-\" before %q", expression, field)
+			}
+			terms = append(terms, categoryExpressionTerm{idOrCategory: field, subtract: subtract})
+			expectTerm = false
+		}
+	}
+	if expectTerm {
+		return nil, fmt.Errorf("expression %q ends with an operator", expression)
+	}
+	return terms, nil
+}
+
 func getIDToCheckerBuilder(checkerBuilders []*CheckerBuilder) (map[string]*CheckerBuilder, error) {
 	m := make(map[string]*CheckerBuilder)
 	for _, checkerBuilder := range checkerBuilders {
@@ -56,7 +56,51 @@ func (h *Helper) AddFileAnnotationf(
 			descriptor,
 			location,
 			format,
-			args...,
+			args,
+		),
+	)
+}
+
+// AddFileAnnotationWithReplacementf adds a FileAnnotation with the id as the
+// Type, that is fixed by replacing the text at the location with replacement.
+//
+// If descriptor is nil, no filename information is added.
+// If location is nil, no line or column information will be added, and the
+// FileAnnotation cannot be fixed.
+func (h *Helper) AddFileAnnotationWithReplacementf(
+	descriptor protosource.Descriptor,
+	location protosource.Location,
+	replacement string,
+	format string,
+	args ...interface{},
+) {
+	if h.ignoreFunc != nil && h.ignoreFunc(h.id, descriptor, location) {
+		return
+	}
+	var options []bufanalysis.FileAnnotationOption
+	if location != nil {
+		options = append(
+			options,
+			bufanalysis.FileAnnotationWithEdits(
+				bufanalysis.Edit{
+					StartLine:   location.StartLine(),
+					StartColumn: location.StartColumn(),
+					EndLine:     location.EndLine(),
+					EndColumn:   location.EndColumn(),
+					NewText:     replacement,
+				},
+			),
+		)
+	}
+	h.fileAnnotations = append(
+		h.fileAnnotations,
+		newFileAnnotationf(
+			h.id,
+			descriptor,
+			location,
+			format,
+			args,
+			options...,
 		),
 	)
 }
@@ -75,7 +119,8 @@ func newFileAnnotationf(
 	descriptor protosource.Descriptor,
 	location protosource.Location,
 	format string,
-	args ...interface{},
+	args []interface{},
+	options ...bufanalysis.FileAnnotationOption,
 ) bufanalysis.FileAnnotation {
 	startLine := 0
 	startColumn := 0
@@ -99,5 +144,6 @@ func newFileAnnotationf(
 		endColumn,
 		id,
 		fmt.Sprintf(format, args...),
+		options...,
 	)
 }
@@ -0,0 +1,140 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcheck"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	checkv1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/check/v1"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// CheckPlugins runs the Plugins of the Config.
+//
+// previousImage should be nil for lint.
+//
+// Only path ignores are applied to the FileAnnotations returned by plugins.
+func (r *Runner) CheckPlugins(
+	ctx context.Context,
+	config *Config,
+	previousImage bufcore.Image,
+	image bufcore.Image,
+) ([]bufanalysis.FileAnnotation, error) {
+	if len(config.Plugins) == 0 {
+		return nil, nil
+	}
+	request := &checkv1.CheckRequest{
+		Image: bufcore.ImageToProtoImage(image),
+	}
+	if previousImage != nil {
+		request.AgainstImage = bufcore.ImageToProtoImage(previousImage)
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, plugin := range config.Plugins {
+		if plugin.Parameter != "" {
+			request.Parameter = proto.String(plugin.Parameter)
+		} else {
+			request.Parameter = nil
+		}
+		pluginFileAnnotations, err := r.checkPlugin(ctx, config, plugin, request, image)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", plugin.Name, err)
+		}
+		fileAnnotations = append(fileAnnotations, pluginFileAnnotations...)
+	}
+	bufanalysis.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, nil
+}
+
+func (r *Runner) checkPlugin(
+	ctx context.Context,
+	config *Config,
+	plugin *bufcheck.Plugin,
+	request *checkv1.CheckRequest,
+	image bufcore.Image,
+) ([]bufanalysis.FileAnnotation, error) {
+	defer instrument.Start(r.logger, "check_plugin", zap.String("plugin", plugin.Name)).End()
+	requestData, err := protoencoding.NewWireMarshaler().Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	responseBuffer := bytes.NewBuffer(nil)
+	stderrBuffer := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, plugin.Path)
+	cmd.Stdin = bytes.NewReader(requestData)
+	cmd.Stdout = responseBuffer
+	cmd.Stderr = stderrBuffer
+	if err := cmd.Run(); err != nil {
+		if stderr := strings.TrimSpace(stderrBuffer.String()); stderr != "" {
+			return nil, fmt.Errorf("%v: %s", err, stderr)
+		}
+		return nil, err
+	}
+	response := &checkv1.CheckResponse{}
+	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(responseBuffer.Bytes(), response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, errors.New(response.GetError())
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, protoFileAnnotation := range response.GetFileAnnotations() {
+		if protoFileAnnotation.GetType() == "" {
+			return nil, errors.New("returned a FileAnnotation without a type")
+		}
+		var fileInfo bufanalysis.FileInfo
+		if path := protoFileAnnotation.GetPath(); path != "" {
+			imageFile := image.GetFile(path)
+			if imageFile == nil {
+				return nil, fmt.Errorf("returned a FileAnnotation for unknown file %q", path)
+			}
+			if pathIsIgnored(protoFileAnnotation.GetType(), path, config) {
+				continue
+			}
+			fileInfo = imageFile
+		}
+		fileAnnotations = append(
+			fileAnnotations,
+			bufanalysis.NewFileAnnotation(
+				fileInfo,
+				int(protoFileAnnotation.GetStartLine()),
+				int(protoFileAnnotation.GetStartColumn()),
+				int(protoFileAnnotation.GetEndLine()),
+				int(protoFileAnnotation.GetEndColumn()),
+				protoFileAnnotation.GetType(),
+				protoFileAnnotation.GetMessage(),
+			),
+		)
+	}
+	return fileAnnotations, nil
+}
+
+func pathIsIgnored(id string, path string, config *Config) bool {
+	if rootPathIsIgnored(path, config.IgnoreRootPaths, config.IgnoreRootPathGlobs) {
+		return true
+	}
+	return rootPathIsIgnored(path, config.IgnoreIDToRootPaths[id], config.IgnoreIDToRootPathGlobs[id])
+}
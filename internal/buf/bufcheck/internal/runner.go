@@ -20,10 +20,9 @@ import (
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
-	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/protosource"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
-	"go.uber.org/multierr"
+	"github.com/bufbuild/buf/internal/pkg/thread"
 	"go.uber.org/zap"
 )
 
@@ -50,43 +49,57 @@ func (r *Runner) Check(ctx context.Context, config *Config, previousFiles []prot
 		return nil, nil
 	}
 	defer instrument.Start(r.logger, "check", zap.Int("num_files", len(files)), zap.Int("num_checkers", len(checkers))).End()
+	instrument.Progress(r.logger, "checking", zap.Int("num_files", len(files)), zap.Int("num_checkers", len(checkers)))
 
 	ignoreFunc := r.newIgnoreFunc(config)
-	var fileAnnotations []bufanalysis.FileAnnotation
-	resultC := make(chan *result, len(checkers))
-	for _, checker := range checkers {
+	// the results are stored by the index of the checker instead of in the
+	// order the checkers complete, so that the sort below is deterministic
+	// for FileAnnotations that compare equal
+	checkerFileAnnotations := make([][]bufanalysis.FileAnnotation, len(checkers))
+	jobs := make([]func() error, len(checkers))
+	for i, checker := range checkers {
+		i := i
 		checker := checker
-		go func() {
-			iFileAnnotations, iErr := checker.check(ignoreFunc, previousFiles, files)
-			resultC <- newResult(iFileAnnotations, iErr)
-		}()
-	}
-	var err error
-	for i := 0; i < len(checkers); i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case result := <-resultC:
-			fileAnnotations = append(fileAnnotations, result.FileAnnotations...)
-			err = multierr.Append(err, result.Err)
+		jobs[i] = func() error {
+			// each checker is timed with its number of failures, so that
+			// the checkers that are slow or fail the most can be found
+			timer := instrument.Start(r.logger, "checker", zap.String("checker", checker.ID()))
+			iFileAnnotations, err := checker.check(ignoreFunc, previousFiles, files)
+			timer.End(zap.Int("num_failures", len(iFileAnnotations)))
+			checkerFileAnnotations[i] = iFileAnnotations
+			return err
+		}
+	}
+	// at most thread.Parallelism() checkers are run at once, and checkers
+	// that check each file on its own also split the files between them
+	errC := make(chan error, 1)
+	go func() {
+		errC <- thread.Parallelize(jobs...)
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-errC:
+		if err != nil {
+			return nil, err
 		}
 	}
-	if err != nil {
-		return nil, err
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, iFileAnnotations := range checkerFileAnnotations {
+		fileAnnotations = append(fileAnnotations, iFileAnnotations...)
 	}
 	bufanalysis.SortFileAnnotations(fileAnnotations)
 	return fileAnnotations, nil
-
 }
 
 func (r *Runner) newIgnoreFunc(config *Config) IgnoreFunc {
 	if r.ignorePrefix == "" || !config.AllowCommentIgnores {
 		return func(id string, descriptor protosource.Descriptor, location protosource.Location) bool {
-			return idIsIgnored(id, descriptor, config)
+			return idIsIgnored(id, descriptor, config) || symbolIsIgnored(id, descriptor, config)
 		}
 	}
 	return func(id string, descriptor protosource.Descriptor, location protosource.Location) bool {
-		return locationIsIgnored(id, r.ignorePrefix, location, config) || idIsIgnored(id, descriptor, config)
+		return locationIsIgnored(id, r.ignorePrefix, location, config) || idIsIgnored(id, descriptor, config) || symbolIsIgnored(id, descriptor, config)
 	}
 }
 
@@ -95,17 +108,40 @@ func idIsIgnored(id string, descriptor protosource.Descriptor, config *Config) b
 		return false
 	}
 	path := descriptor.File().Path()
-	if normalpath.MapHasEqualOrContainingPath(config.IgnoreRootPaths, path, normalpath.Relative) {
+	if rootPathIsIgnored(path, config.IgnoreRootPaths, config.IgnoreRootPathGlobs) {
 		return true
 	}
 	if id == "" {
 		return false
 	}
-	ignoreRootPaths, ok := config.IgnoreIDToRootPaths[id]
+	return rootPathIsIgnored(path, config.IgnoreIDToRootPaths[id], config.IgnoreIDToRootPathGlobs[id])
+}
+
+func symbolIsIgnored(id string, descriptor protosource.Descriptor, config *Config) bool {
+	if id == "" {
+		return false
+	}
+	ignoreSymbols, ok := config.IgnoreIDToSymbols[id]
 	if !ok {
 		return false
 	}
-	return normalpath.MapHasEqualOrContainingPath(ignoreRootPaths, path, normalpath.Relative)
+	namedDescriptor, ok := descriptor.(protosource.NamedDescriptor)
+	if !ok {
+		return false
+	}
+	// check the symbol and all of its parents, i.e. a.b.Foo.bar, a.b.Foo, a.b, a
+	symbol := namedDescriptor.FullName()
+	for symbol != "" {
+		if _, ok := ignoreSymbols[symbol]; ok {
+			return true
+		}
+		index := strings.LastIndexByte(symbol, '.')
+		if index < 0 {
+			return false
+		}
+		symbol = symbol[:index]
+	}
+	return false
 }
 
 func locationIsIgnored(id string, ignorePrefix string, location protosource.Location, config *Config) bool {
@@ -127,15 +163,3 @@ func locationIsIgnored(id string, ignorePrefix string, location protosource.Loca
 	}
 	return false
 }
-
-type result struct {
-	FileAnnotations []bufanalysis.FileAnnotation
-	Err             error
-}
-
-func newResult(fileAnnotations []bufanalysis.FileAnnotation, err error) *result {
-	return &result{
-		FileAnnotations: fileAnnotations,
-		Err:             err,
-	}
-}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
 )
 
@@ -76,3 +77,17 @@ func checkerInCategories(checker bufcheck.Checker, categoriesMap map[string]stru
 	}
 	return false
 }
+
+// rootPathIsIgnored returns true if the path is equal to or contained in
+// any of the root paths, or matches any of the globs.
+func rootPathIsIgnored(path string, rootPaths map[string]struct{}, rootPathGlobs []string) bool {
+	if normalpath.MapHasEqualOrContainingPath(rootPaths, path, normalpath.Relative) {
+		return true
+	}
+	for _, rootPathGlob := range rootPathGlobs {
+		if normalpath.MatchGlob(rootPathGlob, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -17,10 +17,15 @@ package bufconfig
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
@@ -30,11 +35,94 @@ import (
 // TODO: make sure copied for git
 const ConfigFilePath = "buf.yaml"
 
+const (
+	// HookCheckLint is the hook check that runs lint.
+	HookCheckLint = "lint"
+	// HookCheckBreaking is the hook check that runs breaking change detection.
+	HookCheckBreaking = "breaking"
+)
+
 // Config is the user config.
 type Config struct {
-	Build    *bufmod.Config
-	Breaking *bufbreaking.Config
-	Lint     *buflint.Config
+	Build      *bufmod.Config
+	Breaking   *bufbreaking.Config
+	Lint       *buflint.Config
+	SourceInfo *SourceInfoConfig
+	// Deps are the remotes of the modules that the module depends on.
+	//
+	// Each remote is a remote source input, such as a git repository, and is
+	// pinned to a digest in the buf.lock file next to the buf.yaml file.
+	//
+	// These are unique and sorted.
+	Deps []string
+	// DefaultInput is the input to use if no input is given and this config
+	// is the closest config to the current directory.
+	//
+	// This is normalized and relative to the directory of the config file.
+	// If empty, the directory of the config file is used.
+	DefaultInput string
+	// Hooks configures the git hooks written by buf install-hooks.
+	Hooks *HooksConfig
+	// NameToConfig are the named configs, which are selected with GetNamedConfig.
+	//
+	// Each named config has the sections of this Config that it does not set.
+	NameToConfig map[string]*Config
+}
+
+// GetNamedConfig gets the named config of the Config.
+//
+// If name is empty, returns config.
+func GetNamedConfig(config *Config, name string) (*Config, error) {
+	if name == "" {
+		return config, nil
+	}
+	namedConfig, ok := config.NameToConfig[name]
+	if !ok {
+		if len(config.NameToConfig) == 0 {
+			return nil, fmt.Errorf("config %q not found, no configs are defined", name)
+		}
+		names := make([]string, 0, len(config.NameToConfig))
+		for name := range config.NameToConfig {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("config %q not found, must be one of %s", name, strings.Join(names, ","))
+	}
+	return namedConfig, nil
+}
+
+// SourceInfoConfig configures whether source code info is included by default
+// for each class of command.
+//
+// Source code info is always included for lint, as lint checks such as the
+// comment checks depend on it.
+type SourceInfoConfig struct {
+	// ExcludeForImageBuild says to exclude source code info when building images.
+	ExcludeForImageBuild bool
+	// ExcludeForBreaking says to exclude source code info for the input of
+	// breaking change detection. Source code info is always excluded for the
+	// against input.
+	//
+	// File annotations will not have accurate line and column information.
+	ExcludeForBreaking bool
+}
+
+// HooksConfig configures the git hooks written by buf install-hooks.
+type HooksConfig struct {
+	// PreCommit are the checks run on the staged .proto files before each
+	// commit, each of which is HookCheckLint or HookCheckBreaking.
+	//
+	// If empty, no pre-commit hook is written.
+	PreCommit []string
+	// PrePush are the checks run on all .proto files before each push,
+	// each of which is HookCheckLint or HookCheckBreaking.
+	//
+	// If empty, no pre-push hook is written.
+	PrePush []string
+	// Against is the git ref to run breaking change detection against.
+	//
+	// If empty, this is the upstream branch of the current branch.
+	Against string
 }
 
 // Provider is a provider.
@@ -69,7 +157,143 @@ func NewProvider(logger *zap.Logger, options ...ProviderOption) Provider {
 
 // ExternalConfig is an external config.
 type ExternalConfig struct {
-	Build    bufmod.ExternalConfig      `json:"build,omitempty" yaml:"build,omitempty"`
-	Breaking bufbreaking.ExternalConfig `json:"breaking,omitempty" yaml:"breaking,omitempty"`
-	Lint     buflint.ExternalConfig     `json:"lint,omitempty" yaml:"lint,omitempty"`
+	Build      bufmod.ExternalConfig      `json:"build,omitempty" yaml:"build,omitempty"`
+	Breaking   bufbreaking.ExternalConfig `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint       buflint.ExternalConfig     `json:"lint,omitempty" yaml:"lint,omitempty"`
+	SourceInfo ExternalSourceInfoConfig   `json:"source_info,omitempty" yaml:"source_info,omitempty"`
+	Deps       []string                   `json:"deps,omitempty" yaml:"deps,omitempty"`
+	// DefaultInput is a directory path relative to the directory of the config file.
+	DefaultInput string              `json:"default_input,omitempty" yaml:"default_input,omitempty"`
+	Hooks        ExternalHooksConfig `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	// Configs are the named configs, keyed by name.
+	Configs map[string]ExternalNamedConfig `json:"configs,omitempty" yaml:"configs,omitempty"`
+}
+
+// ExternalNamedConfig is an external named config.
+//
+// Each section that is not set is the same as in the enclosing config.
+type ExternalNamedConfig struct {
+	Build    *bufmod.ExternalConfig      `json:"build,omitempty" yaml:"build,omitempty"`
+	Breaking *bufbreaking.ExternalConfig `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Lint     *buflint.ExternalConfig     `json:"lint,omitempty" yaml:"lint,omitempty"`
+}
+
+// ExternalHooksConfig is an external hooks config.
+//
+// Each check must be one of "lint" or "breaking". If PreCommit is not set, it
+// defaults to lint, and if PrePush is not set, it defaults to breaking. Set
+// either to an empty list to not write the hook.
+type ExternalHooksConfig struct {
+	PreCommit []string `json:"pre_commit,omitempty" yaml:"pre_commit,omitempty"`
+	PrePush   []string `json:"pre_push,omitempty" yaml:"pre_push,omitempty"`
+	Against   string   `json:"against,omitempty" yaml:"against,omitempty"`
+}
+
+// ExternalSourceInfoConfig is an external source info config.
+//
+// Each value must be one of "include" or "exclude". If empty, source code info is included.
+type ExternalSourceInfoConfig struct {
+	ImageBuild string `json:"image_build,omitempty" yaml:"image_build,omitempty"`
+	Breaking   string `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+}
+
+func newSourceInfoConfig(externalConfig ExternalSourceInfoConfig) (*SourceInfoConfig, error) {
+	excludeForImageBuild, err := parseSourceInfoValue("image_build", externalConfig.ImageBuild)
+	if err != nil {
+		return nil, err
+	}
+	excludeForBreaking, err := parseSourceInfoValue("breaking", externalConfig.Breaking)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceInfoConfig{
+		ExcludeForImageBuild: excludeForImageBuild,
+		ExcludeForBreaking:   excludeForBreaking,
+	}, nil
+}
+
+func newDeps(externalDeps []string) ([]string, error) {
+	if len(externalDeps) == 0 {
+		return nil, nil
+	}
+	deps := make([]string, 0, len(externalDeps))
+	seen := make(map[string]struct{}, len(externalDeps))
+	for _, dep := range externalDeps {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			return nil, errors.New("deps contained an empty value")
+		}
+		// registry paths such as buf.build/acme/weather have no scheme
+		if !strings.Contains(dep, "://") {
+			return nil, fmt.Errorf("dep %s must be a remote input with a scheme such as https://, registry dependencies are not supported", dep)
+		}
+		if _, ok := seen[dep]; ok {
+			return nil, fmt.Errorf("duplicate dep %s", dep)
+		}
+		seen[dep] = struct{}{}
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+func newDefaultInput(externalDefaultInput string) (string, error) {
+	if externalDefaultInput == "" {
+		return "", nil
+	}
+	defaultInput, err := normalpath.NormalizeAndValidate(externalDefaultInput)
+	if err != nil {
+		return "", fmt.Errorf("default_input: %v", err)
+	}
+	return defaultInput, nil
+}
+
+func newHooksConfig(externalConfig ExternalHooksConfig) (*HooksConfig, error) {
+	preCommit := []string{HookCheckLint}
+	if externalConfig.PreCommit != nil {
+		preCommit = externalConfig.PreCommit
+	}
+	prePush := []string{HookCheckBreaking}
+	if externalConfig.PrePush != nil {
+		prePush = externalConfig.PrePush
+	}
+	if err := validateHookChecks("pre_commit", preCommit); err != nil {
+		return nil, err
+	}
+	if err := validateHookChecks("pre_push", prePush); err != nil {
+		return nil, err
+	}
+	return &HooksConfig{
+		PreCommit: preCommit,
+		PrePush:   prePush,
+		Against:   strings.TrimSpace(externalConfig.Against),
+	}, nil
+}
+
+func validateHookChecks(key string, checks []string) error {
+	seen := make(map[string]struct{}, len(checks))
+	for _, check := range checks {
+		switch check {
+		case HookCheckLint, HookCheckBreaking:
+		default:
+			return fmt.Errorf("hooks.%s must only contain %s or %s but contained %q", key, HookCheckLint, HookCheckBreaking, check)
+		}
+		if _, ok := seen[check]; ok {
+			return fmt.Errorf("hooks.%s contained %s more than once", key, check)
+		}
+		seen[check] = struct{}{}
+	}
+	return nil
+}
+
+// parseSourceInfoValue returns true if source code info should be excluded.
+func parseSourceInfoValue(key string, value string) (bool, error) {
+	switch value {
+	case "", "include":
+		return false, nil
+	case "exclude":
+		return true, nil
+	default:
+		return false, fmt.Errorf("source_info.%s must be one of include or exclude but was %q", key, value)
+	}
 }
@@ -16,7 +16,9 @@ package bufconfig
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
+	"sort"
 
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
@@ -95,9 +97,79 @@ func (p *provider) newConfig(externalConfig *ExternalConfig) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Config{
-		Build:    buildConfig,
-		Breaking: breakingConfig,
-		Lint:     lintConfig,
-	}, nil
+	sourceInfoConfig, err := newSourceInfoConfig(externalConfig.SourceInfo)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := newDeps(externalConfig.Deps)
+	if err != nil {
+		return nil, err
+	}
+	defaultInput, err := newDefaultInput(externalConfig.DefaultInput)
+	if err != nil {
+		return nil, err
+	}
+	hooksConfig, err := newHooksConfig(externalConfig.Hooks)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{
+		Build:        buildConfig,
+		Breaking:     breakingConfig,
+		Lint:         lintConfig,
+		SourceInfo:   sourceInfoConfig,
+		Deps:         deps,
+		DefaultInput: defaultInput,
+		Hooks:        hooksConfig,
+	}
+	if len(externalConfig.Configs) == 0 {
+		return config, nil
+	}
+	config.NameToConfig = make(map[string]*Config, len(externalConfig.Configs))
+	names := make([]string, 0, len(externalConfig.Configs))
+	for name := range externalConfig.Configs {
+		names = append(names, name)
+	}
+	// sorted so that the same error is returned for the same config
+	sort.Strings(names)
+	for _, name := range names {
+		namedConfig, err := newNamedConfig(config, externalConfig.Configs[name])
+		if err != nil {
+			return nil, fmt.Errorf("configs.%s: %v", name, err)
+		}
+		config.NameToConfig[name] = namedConfig
+	}
+	return config, nil
+}
+
+func newNamedConfig(config *Config, externalNamedConfig ExternalNamedConfig) (*Config, error) {
+	namedConfig := &Config{
+		Build:        config.Build,
+		Breaking:     config.Breaking,
+		Lint:         config.Lint,
+		SourceInfo:   config.SourceInfo,
+		Deps:         config.Deps,
+		DefaultInput: config.DefaultInput,
+		Hooks:        config.Hooks,
+	}
+	var err error
+	if externalNamedConfig.Build != nil {
+		namedConfig.Build, err = bufmod.NewConfig(*externalNamedConfig.Build)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if externalNamedConfig.Breaking != nil {
+		namedConfig.Breaking, err = bufbreaking.NewConfig(*externalNamedConfig.Breaking)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if externalNamedConfig.Lint != nil {
+		namedConfig.Lint, err = buflint.NewConfig(*externalNamedConfig.Lint)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return namedConfig, nil
 }
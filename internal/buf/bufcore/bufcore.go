@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strconv"
 
 	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
@@ -101,6 +102,41 @@ func NewImageFile(
 	)
 }
 
+const (
+	// ImageFileRoleTarget says the ImageFile is not an import.
+	ImageFileRoleTarget ImageFileRole = iota + 1
+	// ImageFileRoleDirectImport says the ImageFile is an import that is directly
+	// imported by at least one target ImageFile.
+	ImageFileRoleDirectImport
+	// ImageFileRoleTransitiveImport says the ImageFile is an import that is only
+	// imported by other imports.
+	ImageFileRoleTransitiveImport
+	// ImageFileRoleWellKnownType says the ImageFile is an import that is one of the
+	// Well-Known Types shipped with protoc.
+	//
+	// This takes precedence over ImageFileRoleDirectImport and ImageFileRoleTransitiveImport.
+	ImageFileRoleWellKnownType
+)
+
+// ImageFileRole is the role of an ImageFile within an Image.
+type ImageFileRole int
+
+// String returns the string representation of r.
+func (r ImageFileRole) String() string {
+	switch r {
+	case ImageFileRoleTarget:
+		return "target"
+	case ImageFileRoleDirectImport:
+		return "direct_import"
+	case ImageFileRoleTransitiveImport:
+		return "transitive_import"
+	case ImageFileRoleWellKnownType:
+		return "well_known_type"
+	default:
+		return strconv.Itoa(int(r))
+	}
+}
+
 // Image is a buf image.
 type Image interface {
 	// Files are the files that comprise the image.
@@ -144,6 +180,19 @@ func NewMultiImage(images ...Image) (Image, error) {
 	}
 }
 
+// MergeImages returns a new Image that contains the ImageFiles of all of the
+// given Images.
+//
+// Unlike NewMultiImage, the Images may contain the same files, for example
+// common imports. ImageFiles with the same path must have the same content,
+// ignoring SourceCodeInfo, and otherwise this errors. If an ImageFile is an
+// import in some Images and not in others, the non-import is used.
+//
+// Reorders the ImageFiles to be in DAG order.
+func MergeImages(images ...Image) (Image, error) {
+	return mergeImages(images...)
+}
+
 // NewImageForProto returns a new Image for the given proto Image.
 //
 // The input Files are expected to be in correct DAG order!
@@ -204,7 +253,8 @@ type Module interface {
 	// TargetFileInfos gets all FileRefs for the Module that should be built.
 	//
 	// This does not include imports, or if ModuleWithTargetPaths() was used, files
-	// not specified with ModuleWithTargetPaths().
+	// not specified with ModuleWithTargetPaths(), or files excluded with
+	// ModuleWithExcludeTargetPaths().
 	TargetFileInfos(ctx context.Context) ([]FileInfo, error)
 	// GetFile gets the file for the given path.
 	//
@@ -260,20 +310,111 @@ func ModuleWithTargetPathsAllowNotExistOnWalk() ModuleOption {
 	}
 }
 
+// ModuleWithExcludeTargetPaths returns a new ModuleOption that excludes the files
+// equal to or contained within the given file or directory paths from the target files.
+//
+// Excluded files can still be imported by the target files.
+// These paths must be relative to any roots.
+// These paths will be normalized and validated.
+// Multiple calls to this option will override previous calls.
+func ModuleWithExcludeTargetPaths(excludeTargetPaths ...string) ModuleOption {
+	return func(module *module) {
+		module.excludeTargetPaths = excludeTargetPaths
+	}
+}
+
 // ***** Helpers *****
 
 // ImageWithoutImports returns a copy of the Image without imports.
 //
 // The backing Files are not copied.
 func ImageWithoutImports(image Image) Image {
+	return newImageNoValidate(ImageTargetFiles(image))
+}
+
+// ImageTargetFiles returns the ImageFiles of the Image that are not imports.
+//
+// The returned files are in correct DAG order.
+func ImageTargetFiles(image Image) []ImageFile {
 	imageFiles := image.Files()
-	newImageFiles := make([]ImageFile, 0, len(imageFiles))
+	targetImageFiles := make([]ImageFile, 0, len(imageFiles))
 	for _, imageFile := range imageFiles {
 		if !imageFile.IsImport() {
-			newImageFiles = append(newImageFiles, imageFile)
+			targetImageFiles = append(targetImageFiles, imageFile)
 		}
 	}
-	return newImageNoValidate(newImageFiles)
+	return targetImageFiles
+}
+
+// ImagePathToFileRole returns a map from the root relative file path of each
+// ImageFile in the Image to the ImageFileRole of the ImageFile.
+func ImagePathToFileRole(image Image) map[string]ImageFileRole {
+	return getPathToImageFileRole(image)
+}
+
+// ImageWithSourceCodeInfo returns a copy of the Image with the SourceCodeInfo
+// of the matching ImageFiles in sourceImage attached.
+//
+// ImageFiles are matched by path. If a matching ImageFile does not have the same
+// content, ignoring SourceCodeInfo, this errors. ImageFiles without a matching
+// ImageFile in sourceImage, or whose matching ImageFile has no SourceCodeInfo,
+// are left as-is.
+//
+// The backing FileDescriptorProtos of the Image are not modified. The
+// SourceCodeInfo values are shared with sourceImage and are not copied.
+func ImageWithSourceCodeInfo(image Image, sourceImage Image) (Image, error) {
+	return imageWithSourceCodeInfo(image, sourceImage)
+}
+
+// SourceCodeInfoFilterOption is an option for ImageWithFilteredSourceCodeInfo.
+type SourceCodeInfoFilterOption func(*sourceCodeInfoFilter)
+
+// SourceCodeInfoFilterWithOnlyComments returns a new SourceCodeInfoFilterOption
+// that removes all Locations that do not have comments.
+func SourceCodeInfoFilterWithOnlyComments() SourceCodeInfoFilterOption {
+	return func(sourceCodeInfoFilter *sourceCodeInfoFilter) {
+		sourceCodeInfoFilter.onlyComments = true
+	}
+}
+
+// SourceCodeInfoFilterWithoutSpans returns a new SourceCodeInfoFilterOption
+// that removes the spans of all Locations.
+//
+// Note that span is documented as always being set, so consumers that
+// expect spans, such as error reporting, will not have positions.
+func SourceCodeInfoFilterWithoutSpans() SourceCodeInfoFilterOption {
+	return func(sourceCodeInfoFilter *sourceCodeInfoFilter) {
+		sourceCodeInfoFilter.excludeSpans = true
+	}
+}
+
+// SourceCodeInfoFilterWithoutDetachedComments returns a new SourceCodeInfoFilterOption
+// that removes the leading detached comments of all Locations.
+func SourceCodeInfoFilterWithoutDetachedComments() SourceCodeInfoFilterOption {
+	return func(sourceCodeInfoFilter *sourceCodeInfoFilter) {
+		sourceCodeInfoFilter.excludeDetachedComments = true
+	}
+}
+
+// ImageWithFilteredSourceCodeInfo returns a copy of the Image with the
+// SourceCodeInfo of each ImageFile filtered by the options.
+//
+// If no options are given, the Image is returned as-is.
+//
+// The backing FileDescriptorProtos of the Image are not modified.
+func ImageWithFilteredSourceCodeInfo(image Image, options ...SourceCodeInfoFilterOption) Image {
+	return imageWithFilteredSourceCodeInfo(image, options...)
+}
+
+// ImageDigest returns the canonical digest of the Image.
+//
+// The digest is of the form sha256:HEX, and is computed over the deterministic
+// wire encoding of the Image with its ImageFiles sorted by path. The digest does
+// not depend on the DAG order of the ImageFiles, nor on the encoding the Image
+// is written with, so two Images have the same digest if and only if they
+// contain the same ImageFiles with the same import status.
+func ImageDigest(image Image) (string, error) {
+	return imageDigest(image)
 }
 
 // ImageWithOnlyPaths returns a copy of the Image that only includes the Files
@@ -298,6 +439,49 @@ func ImageWithOnlyPathsAllowNotExist(
 	return imageWithOnlyPaths(image, paths, true)
 }
 
+// ImageWithOnlyTypes returns a copy of the Image that only includes the
+// given messages, enums, and services, and the types they transitively
+// reference.
+//
+// Type names are fully-qualified, for example foo.bar.Baz. Messages are kept
+// whole, including their nested types, and a nested type keeps its enclosing
+// messages. Extensions used as custom options are kept along with their types.
+//
+// Files without any of these types are removed, and the dependencies of each
+// File are rewritten to only those still needed. The SourceCodeInfo of each
+// File is rewritten to match. Files keep their import status.
+//
+// If a type name does not exist, this errors.
+func ImageWithOnlyTypes(
+	image Image,
+	typeNames []string,
+) (Image, error) {
+	return imageWithOnlyTypes(image, typeNames)
+}
+
+// ImageUnusedImports returns the imports of each non-import File of the
+// Image that are not used, by path.
+//
+// An import is used if the File references a type, extendee, or custom
+// option defined in the imported file, or in a file that the imported
+// file publicly imports, transitively. Public and weak imports are never
+// returned. Files with no unused imports are not in the returned map.
+func ImageUnusedImports(image Image) (map[string][]string, error) {
+	return imageUnusedImports(image)
+}
+
+// ImageWithoutPaths returns a copy of the Image without the non-import Files
+// equal to or contained within the given root relative file or directory paths.
+//
+// Excluded Files that are still imported by the remaining Files are kept as
+// imports. If all non-import Files are excluded, this errors.
+func ImageWithoutPaths(
+	image Image,
+	paths []string,
+) (Image, error) {
+	return imageWithoutPaths(image, paths)
+}
+
 // ImageByDir returns multiple images that have non-imports split
 // by directory.
 //
@@ -305,12 +489,10 @@ func ImageWithOnlyPathsAllowNotExist(
 // as it's non-imports, along with all required imports for the
 // files in that directory.
 func ImageByDir(image Image) ([]Image, error) {
-	imageFiles := image.Files()
-	paths := make([]string, 0, len(imageFiles))
-	for _, imageFile := range imageFiles {
-		if !imageFile.IsImport() {
-			paths = append(paths, imageFile.Path())
-		}
+	targetImageFiles := ImageTargetFiles(image)
+	paths := make([]string, len(targetImageFiles))
+	for i, targetImageFile := range targetImageFiles {
+		paths[i] = targetImageFile.Path()
 	}
 	dirToPaths := normalpath.ByDir(paths...)
 	newImages := make([]Image, 0, len(dirToPaths))
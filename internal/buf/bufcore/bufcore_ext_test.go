@@ -0,0 +1,459 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcore_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoretesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestImagePathToFileRole(t *testing.T) {
+	t.Parallel()
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(
+				t,
+				bufcoretesting.NewFileDescriptorProto(t, "google/protobuf/timestamp.proto"),
+				"",
+				true,
+			),
+			bufcoretesting.NewImageFile(
+				t,
+				bufcoretesting.NewFileDescriptorProto(t, "c/c.proto"),
+				"",
+				true,
+			),
+			bufcoretesting.NewImageFile(
+				t,
+				bufcoretesting.NewFileDescriptorProto(t, "b/b.proto", "c/c.proto"),
+				"",
+				true,
+			),
+			bufcoretesting.NewImageFile(
+				t,
+				bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "b/b.proto", "google/protobuf/timestamp.proto"),
+				"",
+				false,
+			),
+			bufcoretesting.NewImageFile(
+				t,
+				bufcoretesting.NewFileDescriptorProto(t, "a/a2.proto", "a/a.proto"),
+				"",
+				false,
+			),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]bufcore.ImageFileRole{
+			"google/protobuf/timestamp.proto": bufcore.ImageFileRoleWellKnownType,
+			"c/c.proto":                       bufcore.ImageFileRoleTransitiveImport,
+			"b/b.proto":                       bufcore.ImageFileRoleDirectImport,
+			"a/a.proto":                       bufcore.ImageFileRoleTarget,
+			"a/a2.proto":                      bufcore.ImageFileRoleTarget,
+		},
+		bufcore.ImagePathToFileRole(image),
+	)
+	targetPaths := make([]string, 0)
+	for _, imageFile := range bufcore.ImageTargetFiles(image) {
+		targetPaths = append(targetPaths, imageFile.Path())
+	}
+	assert.Equal(t, []string{"a/a.proto", "a/a2.proto"}, targetPaths)
+	assert.Equal(t, "direct_import", bufcore.ImageFileRoleDirectImport.String())
+}
+
+func TestImageWithSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+	sourceCodeInfo := &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{
+				Path: []int32{4, 0},
+				Span: []int32{2, 0, 10},
+			},
+		},
+	}
+	aFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/a.proto")
+	aFileDescriptorProto.Package = proto.String("a")
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, aFileDescriptorProto, "", false),
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "b/b.proto"), "", false),
+		},
+	)
+	require.NoError(t, err)
+	sourceAFileDescriptorProto := proto.Clone(aFileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	sourceAFileDescriptorProto.SourceCodeInfo = sourceCodeInfo
+	sourceImage, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, sourceAFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+
+	newImage, err := bufcore.ImageWithSourceCodeInfo(image, sourceImage)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(sourceCodeInfo, newImage.GetFile("a/a.proto").Proto().GetSourceCodeInfo()))
+	assert.Nil(t, newImage.GetFile("b/b.proto").Proto().GetSourceCodeInfo())
+	// the original Image is not modified
+	assert.Nil(t, image.GetFile("a/a.proto").Proto().GetSourceCodeInfo())
+
+	sourceAFileDescriptorProto.Package = proto.String("b")
+	_, err = bufcore.ImageWithSourceCodeInfo(image, sourceImage)
+	assert.Error(t, err)
+}
+
+func TestImageWithFilteredSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+	aFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/a.proto")
+	aFileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{
+				Path: []int32{4, 0},
+				Span: []int32{2, 0, 10},
+			},
+			{
+				Path:                    []int32{4, 1},
+				Span:                    []int32{4, 0, 10},
+				LeadingComments:         proto.String(" leading\n"),
+				LeadingDetachedComments: []string{" detached\n"},
+			},
+		},
+	}
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, aFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, image, bufcore.ImageWithFilteredSourceCodeInfo(image))
+	newImage := bufcore.ImageWithFilteredSourceCodeInfo(
+		image,
+		bufcore.SourceCodeInfoFilterWithOnlyComments(),
+		bufcore.SourceCodeInfoFilterWithoutSpans(),
+		bufcore.SourceCodeInfoFilterWithoutDetachedComments(),
+	)
+	assert.True(
+		t,
+		proto.Equal(
+			&descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{
+					{
+						Path:            []int32{4, 1},
+						LeadingComments: proto.String(" leading\n"),
+					},
+				},
+			},
+			newImage.GetFile("a/a.proto").Proto().GetSourceCodeInfo(),
+		),
+	)
+	// the original Image is not modified
+	assert.Len(t, image.GetFile("a/a.proto").Proto().GetSourceCodeInfo().GetLocation(), 2)
+}
+
+func TestImageDigest(t *testing.T) {
+	t.Parallel()
+	aImageFile := bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "a/a.proto"), "", false)
+	bImageFile := bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "b/b.proto"), "", false)
+	bImportImageFile := bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "b/b.proto"), "", true)
+	abImage, err := bufcore.NewImage([]bufcore.ImageFile{aImageFile, bImageFile})
+	require.NoError(t, err)
+	baImage, err := bufcore.NewImage([]bufcore.ImageFile{bImageFile, aImageFile})
+	require.NoError(t, err)
+	abImportImage, err := bufcore.NewImage([]bufcore.ImageFile{aImageFile, bImportImageFile})
+	require.NoError(t, err)
+
+	abDigest, err := bufcore.ImageDigest(abImage)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(abDigest, "sha256:"))
+	// the digest does not depend on the file order
+	baDigest, err := bufcore.ImageDigest(baImage)
+	require.NoError(t, err)
+	assert.Equal(t, abDigest, baDigest)
+	// the digest does depend on which files are imports
+	abImportDigest, err := bufcore.ImageDigest(abImportImage)
+	require.NoError(t, err)
+	assert.NotEqual(t, abDigest, abImportDigest)
+	// the file order of the Image is not modified
+	assert.Equal(t, "b/b.proto", baImage.Files()[0].Path())
+}
+
+func TestMergeImages(t *testing.T) {
+	t.Parallel()
+	cFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "c/c.proto")
+	cImportImageFile := bufcoretesting.NewImageFile(t, cFileDescriptorProto, "", true)
+	cImageFile := bufcoretesting.NewImageFile(t, cFileDescriptorProto, "", false)
+	aImage, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			cImportImageFile,
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "c/c.proto"), "", false),
+		},
+	)
+	require.NoError(t, err)
+	bImage, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "b/b.proto", "c/c.proto"), "", false),
+			cImageFile,
+		},
+	)
+	require.NoError(t, err)
+
+	image, err := bufcore.MergeImages(aImage, bImage)
+	require.NoError(t, err)
+	paths := make([]string, 0)
+	for _, imageFile := range image.Files() {
+		paths = append(paths, imageFile.Path())
+	}
+	// the files are in DAG order and each file is contained once
+	assert.Equal(t, []string{"c/c.proto", "a/a.proto", "b/b.proto"}, paths)
+	// the non-import copy is preferred
+	assert.False(t, image.GetFile("c/c.proto").IsImport())
+
+	// SourceCodeInfo is ignored when comparing copies
+	cSourceFileDescriptorProto := proto.Clone(cFileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	cSourceFileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{}
+	cSourceImage, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, cSourceFileDescriptorProto, "", true),
+		},
+	)
+	require.NoError(t, err)
+	_, err = bufcore.MergeImages(aImage, cSourceImage)
+	require.NoError(t, err)
+
+	cConflictFileDescriptorProto := proto.Clone(cFileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	cConflictFileDescriptorProto.Package = proto.String("c")
+	cConflictImage, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, cConflictFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+	_, err = bufcore.MergeImages(aImage, cConflictImage)
+	assert.EqualError(t, err, "c/c.proto has conflicting definitions in the images")
+}
+
+func TestImageWithOnlyTypes(t *testing.T) {
+	t.Parallel()
+	cFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "c/c.proto")
+	cFileDescriptorProto.Package = proto.String("c")
+	cFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{Name: proto.String("C")},
+		{Name: proto.String("Unused")},
+	}
+	bFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "b/b.proto", "c/c.proto")
+	bFileDescriptorProto.Package = proto.String("b")
+	bFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("B"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("c"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".c.C"),
+				},
+			},
+		},
+		{Name: proto.String("Other")},
+	}
+	bFileDescriptorProto.Service = []*descriptorpb.ServiceDescriptorProto{
+		{
+			Name: proto.String("S"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("M"),
+					InputType:  proto.String(".b.B"),
+					OutputType: proto.String(".b.B"),
+				},
+			},
+		},
+	}
+	bFileDescriptorProto.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{3, 0}, Span: []int32{4, 0, 20}},
+			{Path: []int32{4, 0}, Span: []int32{6, 0, 8}},
+			{Path: []int32{4, 1}, Span: []int32{10, 0, 16}},
+			{Path: []int32{6, 0}, Span: []int32{12, 0, 14}},
+		},
+	}
+	aFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "b/b.proto")
+	aFileDescriptorProto.Package = proto.String("a")
+	aFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{Name: proto.String("A")},
+	}
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, cFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, bFileDescriptorProto, "", false),
+			bufcoretesting.NewImageFile(t, aFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+
+	newImage, err := bufcore.ImageWithOnlyTypes(image, []string{"b.S"})
+	require.NoError(t, err)
+	require.Len(t, newImage.Files(), 2)
+	assert.Nil(t, newImage.GetFile("a/a.proto"))
+	cImageFile := newImage.GetFile("c/c.proto")
+	require.NotNil(t, cImageFile)
+	assert.True(t, cImageFile.IsImport())
+	require.Len(t, cImageFile.Proto().GetMessageType(), 1)
+	assert.Equal(t, "C", cImageFile.Proto().GetMessageType()[0].GetName())
+	bImageFile := newImage.GetFile("b/b.proto")
+	require.NotNil(t, bImageFile)
+	assert.False(t, bImageFile.IsImport())
+	assert.Equal(t, []string{"c/c.proto"}, bImageFile.ImportPaths())
+	require.Len(t, bImageFile.Proto().GetMessageType(), 1)
+	assert.Equal(t, "B", bImageFile.Proto().GetMessageType()[0].GetName())
+	require.Len(t, bImageFile.Proto().GetService(), 1)
+	locationPaths := func(imageFile bufcore.ImageFile) [][]int32 {
+		var paths [][]int32
+		for _, location := range imageFile.Proto().GetSourceCodeInfo().GetLocation() {
+			paths = append(paths, location.GetPath())
+		}
+		return paths
+	}
+	assert.Equal(t, [][]int32{{3, 0}, {4, 0}, {6, 0}}, locationPaths(bImageFile))
+
+	// the unneeded dependency is removed, and the locations are renumbered
+	newImage, err = bufcore.ImageWithOnlyTypes(image, []string{".b.Other"})
+	require.NoError(t, err)
+	require.Len(t, newImage.Files(), 1)
+	bImageFile = newImage.GetFile("b/b.proto")
+	require.NotNil(t, bImageFile)
+	assert.Empty(t, bImageFile.ImportPaths())
+	require.Len(t, bImageFile.Proto().GetMessageType(), 1)
+	assert.Equal(t, "Other", bImageFile.Proto().GetMessageType()[0].GetName())
+	assert.Equal(t, [][]int32{{4, 0}}, locationPaths(bImageFile))
+	assert.Equal(t, []int32{10, 0, 16}, bImageFile.Proto().GetSourceCodeInfo().GetLocation()[0].GetSpan())
+	// the original Image is not modified
+	assert.Len(t, image.GetFile("b/b.proto").Proto().GetMessageType(), 2)
+	assert.Equal(t, []int32{4, 1}, image.GetFile("b/b.proto").Proto().GetSourceCodeInfo().GetLocation()[2].GetPath())
+
+	_, err = bufcore.ImageWithOnlyTypes(image, []string{"b.NotFound"})
+	assert.Error(t, err)
+}
+
+func TestImageUnusedImports(t *testing.T) {
+	t.Parallel()
+	descriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	cFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "c/c.proto")
+	cFileDescriptorProto.Package = proto.String("c")
+	cFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{Name: proto.String("C")},
+	}
+	pFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "p/p.proto", "c/c.proto")
+	pFileDescriptorProto.PublicDependency = []int32{0}
+	oFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "o/o.proto", "google/protobuf/descriptor.proto")
+	oFileDescriptorProto.Package = proto.String("o")
+	oFileDescriptorProto.Extension = []*descriptorpb.FieldDescriptorProto{
+		{
+			Name:     proto.String("opt"),
+			Number:   proto.Int32(50000),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".google.protobuf.MessageOptions"),
+		},
+	}
+	uFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "u/u.proto")
+	uFileDescriptorProto.Package = proto.String("u")
+	uFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{Name: proto.String("U")},
+	}
+	eFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "e/e.proto")
+	// the custom option is an unknown field, as it is when built from source
+	messageOptions := &descriptorpb.MessageOptions{}
+	messageOptions.ProtoReflect().SetUnknown(
+		protowire.AppendVarint(protowire.AppendTag(nil, 50000, protowire.VarintType), 1),
+	)
+	aFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "p/p.proto", "o/o.proto", "u/u.proto", "e/e.proto")
+	aFileDescriptorProto.Package = proto.String("a")
+	aFileDescriptorProto.PublicDependency = []int32{3}
+	aFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name:    proto.String("A"),
+			Options: messageOptions,
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("c"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".c.C"),
+				},
+			},
+		},
+	}
+	bFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "b/b.proto", "u/u.proto")
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, descriptorFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, cFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, pFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, oFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, uFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, eFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, aFileDescriptorProto, "", false),
+			bufcoretesting.NewImageFile(t, bFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+
+	pathToUnusedImports, err := bufcore.ImageUnusedImports(image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string][]string{
+			"a/a.proto": {"u/u.proto"},
+			"b/b.proto": {"u/u.proto"},
+		},
+		pathToUnusedImports,
+	)
+}
+
+func TestImageWithoutPaths(t *testing.T) {
+	t.Parallel()
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "vendor/v.proto"), "", false),
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "gen/g.proto"), "", false),
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "vendor/v.proto"), "", false),
+		},
+	)
+	require.NoError(t, err)
+
+	newImage, err := bufcore.ImageWithoutPaths(image, []string{"vendor", "gen/g.proto"})
+	require.NoError(t, err)
+	bufcoretesting.AssertImageFilesEqual(
+		t,
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "vendor/v.proto"), "", true),
+			bufcoretesting.NewImageFile(t, bufcoretesting.NewFileDescriptorProto(t, "a/a.proto", "vendor/v.proto"), "", false),
+		},
+		newImage.Files(),
+	)
+
+	_, err = bufcore.ImageWithoutPaths(image, []string{"."})
+	assert.Equal(t, bufcore.ErrNoTargetFiles, err)
+}
@@ -31,6 +31,7 @@ type module struct {
 	allReadBucket                  storage.ReadBucket
 	targetPaths                    []string
 	targetPathsAllowNotExistOnWalk bool
+	excludeTargetPaths             []string
 }
 
 func newModule(
@@ -46,7 +47,7 @@ func newModule(
 	for _, option := range options {
 		option(module)
 	}
-	if len(module.targetPaths) > 0 && module.targetPathsAllowNotExistOnWalk {
+	if len(module.targetPaths) == 0 && module.targetPathsAllowNotExistOnWalk {
 		return nil, errors.New("targetPathsAllowNotExistOnWalk set but targetPaths not specified")
 	}
 	for i, targetPath := range module.targetPaths {
@@ -56,6 +57,13 @@ func newModule(
 		}
 		module.targetPaths[i] = normalizedTargetPath
 	}
+	for i, excludeTargetPath := range module.excludeTargetPaths {
+		normalizedExcludeTargetPath, err := normalpath.NormalizeAndValidate(excludeTargetPath)
+		if err != nil {
+			return nil, err
+		}
+		module.excludeTargetPaths[i] = normalizedExcludeTargetPath
+	}
 	if module.importReadBucket != nil {
 		module.importReadBucket = storage.Map(
 			module.importReadBucket,
@@ -97,6 +105,9 @@ func (m *module) TargetFileInfos(ctx context.Context) ([]FileInfo, error) {
 			return nil, err
 		}
 	}
+	if len(m.excludeTargetPaths) > 0 {
+		fileInfos = excludeFileInfos(fileInfos, m.excludeTargetPaths)
+	}
 	if len(fileInfos) == 0 {
 		return nil, ErrNoTargetFiles
 	}
@@ -145,6 +156,22 @@ func (m *module) GetFileInfo(ctx context.Context, path string) (FileInfo, error)
 
 func (*module) isModule() {}
 
+// excludeFileInfos returns the FileInfos whose paths are not equal to or
+// contained within any of the exclude paths.
+func excludeFileInfos(fileInfos []FileInfo, excludePaths []string) []FileInfo {
+	excludePathMap := make(map[string]struct{}, len(excludePaths))
+	for _, excludePath := range excludePaths {
+		excludePathMap[excludePath] = struct{}{}
+	}
+	includedFileInfos := make([]FileInfo, 0, len(fileInfos))
+	for _, fileInfo := range fileInfos {
+		if !normalpath.MapHasEqualOrContainingPath(excludePathMap, fileInfo.Path(), normalpath.Relative) {
+			includedFileInfos = append(includedFileInfos, fileInfo)
+		}
+	}
+	return includedFileInfos
+}
+
 func sortFileInfos(fileInfos []FileInfo) {
 	sort.Slice(
 		fileInfos,
@@ -0,0 +1,607 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// the field numbers of FileDescriptorProto that are filtered, used to
+// rewrite the paths of SourceCodeInfo Locations
+const (
+	fileDependencyFieldNumber       = 3
+	fileMessageTypeFieldNumber      = 4
+	fileEnumTypeFieldNumber         = 5
+	fileServiceFieldNumber          = 6
+	fileExtensionFieldNumber        = 7
+	filePublicDependencyFieldNumber = 10
+	fileWeakDependencyFieldNumber   = 11
+)
+
+func imageWithOnlyTypes(image Image, typeNames []string) (Image, error) {
+	if len(typeNames) == 0 {
+		return nil, errors.New("no types given")
+	}
+	typeFilter := newTypeFilter(image)
+	for _, typeName := range typeNames {
+		if err := typeFilter.require("", typeName); err != nil {
+			return nil, err
+		}
+	}
+	return typeFilter.filter(image)
+}
+
+func imageUnusedImports(image Image) (map[string][]string, error) {
+	typeFilter := newTypeFilter(image)
+	targetImageFiles := ImageTargetFiles(image)
+	for _, imageFile := range targetImageFiles {
+		if err := typeFilter.requireFile(imageFile); err != nil {
+			return nil, err
+		}
+	}
+	pathToPublicDependencies := make(map[string][]string)
+	for _, imageFile := range image.Files() {
+		fileDescriptorProto := imageFile.Proto()
+		for _, index := range fileDescriptorProto.GetPublicDependency() {
+			pathToPublicDependencies[imageFile.Path()] = append(
+				pathToPublicDependencies[imageFile.Path()],
+				fileDescriptorProto.GetDependency()[index],
+			)
+		}
+	}
+	pathToUnusedImports := make(map[string][]string)
+	for _, imageFile := range targetImageFiles {
+		path := imageFile.Path()
+		fileDescriptorProto := imageFile.Proto()
+		referencedPaths := typeFilter.pathToReferencedPaths[path]
+		publicDependencyIndexes := int32Set(fileDescriptorProto.GetPublicDependency())
+		weakDependencyIndexes := int32Set(fileDescriptorProto.GetWeakDependency())
+		for i, dependency := range fileDescriptorProto.GetDependency() {
+			if _, ok := publicDependencyIndexes[int32(i)]; ok {
+				continue
+			}
+			if _, ok := weakDependencyIndexes[int32(i)]; ok {
+				continue
+			}
+			if !isReferencedThroughPublic(dependency, referencedPaths, pathToPublicDependencies, make(map[string]struct{})) {
+				pathToUnusedImports[path] = append(pathToUnusedImports[path], dependency)
+			}
+		}
+	}
+	return pathToUnusedImports, nil
+}
+
+// isReferencedThroughPublic returns true if the path, or a file that the
+// path publicly imports transitively, is in referencedPaths.
+func isReferencedThroughPublic(
+	path string,
+	referencedPaths map[string]struct{},
+	pathToPublicDependencies map[string][]string,
+	seenPaths map[string]struct{},
+) bool {
+	if _, ok := seenPaths[path]; ok {
+		return false
+	}
+	seenPaths[path] = struct{}{}
+	if _, ok := referencedPaths[path]; ok {
+		return true
+	}
+	for _, publicDependency := range pathToPublicDependencies[path] {
+		if isReferencedThroughPublic(publicDependency, referencedPaths, pathToPublicDependencies, seenPaths) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeFilter computes the closure of the types required by a set of types,
+// and filters an Image down to this closure.
+//
+// Messages are kept whole, including their nested types, and nested types
+// require their enclosing messages. Extensions are required by the options
+// that use them, so that custom options keep their definitions.
+type typeFilter struct {
+	nameToElement      map[string]*typeFilterElement
+	extensionKeyToName map[typeFilterExtensionKey]string
+
+	requiredNames map[string]struct{}
+	requiredPaths map[string]struct{}
+	// pathToReferencedPaths are the paths of the other files that
+	// the required types of each file reference.
+	pathToReferencedPaths map[string]map[string]struct{}
+}
+
+// typeFilterElement is a type that can be required.
+//
+// Exactly one of message, enum, service, and extension is set.
+type typeFilterElement struct {
+	imageFile ImageFile
+	// parentName is the full name of the enclosing message, if any.
+	parentName string
+	message    *descriptorpb.DescriptorProto
+	enum       *descriptorpb.EnumDescriptorProto
+	service    *descriptorpb.ServiceDescriptorProto
+	extension  *descriptorpb.FieldDescriptorProto
+}
+
+type typeFilterExtensionKey struct {
+	extendee string
+	number   int32
+}
+
+func newTypeFilter(image Image) *typeFilter {
+	typeFilter := &typeFilter{
+		nameToElement:         make(map[string]*typeFilterElement),
+		extensionKeyToName:    make(map[typeFilterExtensionKey]string),
+		requiredNames:         make(map[string]struct{}),
+		requiredPaths:         make(map[string]struct{}),
+		pathToReferencedPaths: make(map[string]map[string]struct{}),
+	}
+	for _, imageFile := range image.Files() {
+		fileDescriptorProto := imageFile.Proto()
+		packageName := fileDescriptorProto.GetPackage()
+		for _, message := range fileDescriptorProto.GetMessageType() {
+			typeFilter.addMessage(imageFile, packageName, "", message)
+		}
+		for _, enum := range fileDescriptorProto.GetEnumType() {
+			typeFilter.nameToElement[joinTypeName(packageName, enum.GetName())] = &typeFilterElement{
+				imageFile: imageFile,
+				enum:      enum,
+			}
+		}
+		for _, service := range fileDescriptorProto.GetService() {
+			typeFilter.nameToElement[joinTypeName(packageName, service.GetName())] = &typeFilterElement{
+				imageFile: imageFile,
+				service:   service,
+			}
+		}
+		for _, extension := range fileDescriptorProto.GetExtension() {
+			name := joinTypeName(packageName, extension.GetName())
+			typeFilter.nameToElement[name] = &typeFilterElement{
+				imageFile: imageFile,
+				extension: extension,
+			}
+			typeFilter.extensionKeyToName[newTypeFilterExtensionKey(extension)] = name
+		}
+	}
+	return typeFilter
+}
+
+func (t *typeFilter) addMessage(
+	imageFile ImageFile,
+	prefix string,
+	parentName string,
+	message *descriptorpb.DescriptorProto,
+) {
+	name := joinTypeName(prefix, message.GetName())
+	t.nameToElement[name] = &typeFilterElement{
+		imageFile:  imageFile,
+		parentName: parentName,
+		message:    message,
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		t.addMessage(imageFile, name, name, nestedMessage)
+	}
+	for _, nestedEnum := range message.GetEnumType() {
+		t.nameToElement[joinTypeName(name, nestedEnum.GetName())] = &typeFilterElement{
+			imageFile:  imageFile,
+			parentName: name,
+			enum:       nestedEnum,
+		}
+	}
+	// extensions declared within messages are kept with the message
+	for _, nestedExtension := range message.GetExtension() {
+		t.extensionKeyToName[newTypeFilterExtensionKey(nestedExtension)] = name
+	}
+}
+
+// require requires the type with the name, and the types it references.
+//
+// fromPath is the path of the file that references the type, or empty
+// if the type is required directly.
+func (t *typeFilter) require(fromPath string, name string) error {
+	name = strings.TrimPrefix(name, ".")
+	element, ok := t.nameToElement[name]
+	if !ok {
+		return fmt.Errorf("%s is not present in the Image", name)
+	}
+	path := element.imageFile.Path()
+	if fromPath != "" && fromPath != path {
+		referencedPaths, ok := t.pathToReferencedPaths[fromPath]
+		if !ok {
+			referencedPaths = make(map[string]struct{})
+			t.pathToReferencedPaths[fromPath] = referencedPaths
+		}
+		referencedPaths[path] = struct{}{}
+	}
+	if _, ok := t.requiredNames[name]; ok {
+		return nil
+	}
+	t.requiredNames[name] = struct{}{}
+	if _, ok := t.requiredPaths[path]; !ok {
+		t.requiredPaths[path] = struct{}{}
+		if err := t.requireOptions(path, element.imageFile.Proto().GetOptions()); err != nil {
+			return err
+		}
+	}
+	if element.parentName != "" {
+		if err := t.require(path, element.parentName); err != nil {
+			return err
+		}
+	}
+	switch {
+	case element.message != nil:
+		return t.requireMessage(path, element.message)
+	case element.enum != nil:
+		return t.requireEnum(path, element.enum)
+	case element.service != nil:
+		return t.requireService(path, element.service)
+	case element.extension != nil:
+		return t.requireField(path, element.extension)
+	default:
+		return nil
+	}
+}
+
+// requireFile requires all the types of the file, and the extensions set on
+// its options.
+func (t *typeFilter) requireFile(imageFile ImageFile) error {
+	path := imageFile.Path()
+	fileDescriptorProto := imageFile.Proto()
+	if _, ok := t.requiredPaths[path]; !ok {
+		t.requiredPaths[path] = struct{}{}
+		if err := t.requireOptions(path, fileDescriptorProto.GetOptions()); err != nil {
+			return err
+		}
+	}
+	packageName := fileDescriptorProto.GetPackage()
+	var names []string
+	for _, message := range fileDescriptorProto.GetMessageType() {
+		names = append(names, joinTypeName(packageName, message.GetName()))
+	}
+	for _, enum := range fileDescriptorProto.GetEnumType() {
+		names = append(names, joinTypeName(packageName, enum.GetName()))
+	}
+	for _, service := range fileDescriptorProto.GetService() {
+		names = append(names, joinTypeName(packageName, service.GetName()))
+	}
+	for _, extension := range fileDescriptorProto.GetExtension() {
+		names = append(names, joinTypeName(packageName, extension.GetName()))
+	}
+	for _, name := range names {
+		if err := t.require(path, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *typeFilter) requireMessage(path string, message *descriptorpb.DescriptorProto) error {
+	if err := t.requireOptions(path, message.GetOptions()); err != nil {
+		return err
+	}
+	for _, field := range message.GetField() {
+		if err := t.requireField(path, field); err != nil {
+			return err
+		}
+	}
+	for _, extension := range message.GetExtension() {
+		if err := t.requireField(path, extension); err != nil {
+			return err
+		}
+	}
+	for _, oneof := range message.GetOneofDecl() {
+		if err := t.requireOptions(path, oneof.GetOptions()); err != nil {
+			return err
+		}
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		if err := t.requireMessage(path, nestedMessage); err != nil {
+			return err
+		}
+	}
+	for _, nestedEnum := range message.GetEnumType() {
+		if err := t.requireEnum(path, nestedEnum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *typeFilter) requireField(path string, field *descriptorpb.FieldDescriptorProto) error {
+	if typeName := field.GetTypeName(); typeName != "" {
+		if err := t.require(path, typeName); err != nil {
+			return err
+		}
+	}
+	if extendee := field.GetExtendee(); extendee != "" {
+		if err := t.require(path, extendee); err != nil {
+			return err
+		}
+	}
+	return t.requireOptions(path, field.GetOptions())
+}
+
+func (t *typeFilter) requireEnum(path string, enum *descriptorpb.EnumDescriptorProto) error {
+	if err := t.requireOptions(path, enum.GetOptions()); err != nil {
+		return err
+	}
+	for _, value := range enum.GetValue() {
+		if err := t.requireOptions(path, value.GetOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *typeFilter) requireService(path string, service *descriptorpb.ServiceDescriptorProto) error {
+	if err := t.requireOptions(path, service.GetOptions()); err != nil {
+		return err
+	}
+	for _, method := range service.GetMethod() {
+		if err := t.require(path, method.GetInputType()); err != nil {
+			return err
+		}
+		if err := t.require(path, method.GetOutputType()); err != nil {
+			return err
+		}
+		if err := t.requireOptions(path, method.GetOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireOptions requires the extensions that are set on the options.
+//
+// Custom options are usually unknown fields of the options, so both
+// known extensions and unknown fields are matched against the extensions
+// of the Image. Unknown fields that do not match an extension are ignored.
+func (t *typeFilter) requireOptions(path string, options proto.Message) error {
+	if options == nil {
+		return nil
+	}
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return nil
+	}
+	extendee := string(message.Descriptor().FullName())
+	var numbers []int32
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if fieldDescriptor.IsExtension() {
+				numbers = append(numbers, int32(fieldDescriptor.Number()))
+			}
+			return true
+		},
+	)
+	unknown := message.GetUnknown()
+	for len(unknown) > 0 {
+		number, _, n := protowire.ConsumeField(unknown)
+		if n < 0 {
+			break
+		}
+		numbers = append(numbers, int32(number))
+		unknown = unknown[n:]
+	}
+	for _, number := range numbers {
+		name, ok := t.extensionKeyToName[typeFilterExtensionKey{extendee: extendee, number: number}]
+		if !ok {
+			continue
+		}
+		if err := t.require(path, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *typeFilter) filter(image Image) (Image, error) {
+	var newImageFiles []ImageFile
+	newPaths := make(map[string]struct{})
+	// the files are in DAG order, so the dependencies of each file
+	// are filtered before the file
+	for _, imageFile := range image.Files() {
+		path := imageFile.Path()
+		if _, ok := t.requiredPaths[path]; !ok {
+			continue
+		}
+		newImageFiles = append(
+			newImageFiles,
+			newImageFileNoValidate(
+				t.filterFileDescriptorProto(imageFile.Proto(), newPaths),
+				imageFile.ExternalPath(),
+				imageFile.IsImport(),
+			),
+		)
+		newPaths[path] = struct{}{}
+	}
+	return NewImage(newImageFiles)
+}
+
+// filterFileDescriptorProto returns a copy of the FileDescriptorProto with
+// only the required types, and only the dependencies that are still needed.
+//
+// newPaths are the paths of the files that have already been filtered.
+func (t *typeFilter) filterFileDescriptorProto(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	newPaths map[string]struct{},
+) *descriptorpb.FileDescriptorProto {
+	packageName := fileDescriptorProto.GetPackage()
+	newFileDescriptorProto := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+
+	messageIndexes := make(map[int32]int32)
+	newFileDescriptorProto.MessageType = nil
+	for i, message := range fileDescriptorProto.GetMessageType() {
+		if t.isRequired(packageName, message.GetName()) {
+			messageIndexes[int32(i)] = int32(len(newFileDescriptorProto.MessageType))
+			newFileDescriptorProto.MessageType = append(newFileDescriptorProto.MessageType, message)
+		}
+	}
+	enumIndexes := make(map[int32]int32)
+	newFileDescriptorProto.EnumType = nil
+	for i, enum := range fileDescriptorProto.GetEnumType() {
+		if t.isRequired(packageName, enum.GetName()) {
+			enumIndexes[int32(i)] = int32(len(newFileDescriptorProto.EnumType))
+			newFileDescriptorProto.EnumType = append(newFileDescriptorProto.EnumType, enum)
+		}
+	}
+	serviceIndexes := make(map[int32]int32)
+	newFileDescriptorProto.Service = nil
+	for i, service := range fileDescriptorProto.GetService() {
+		if t.isRequired(packageName, service.GetName()) {
+			serviceIndexes[int32(i)] = int32(len(newFileDescriptorProto.Service))
+			newFileDescriptorProto.Service = append(newFileDescriptorProto.Service, service)
+		}
+	}
+	extensionIndexes := make(map[int32]int32)
+	newFileDescriptorProto.Extension = nil
+	for i, extension := range fileDescriptorProto.GetExtension() {
+		if t.isRequired(packageName, extension.GetName()) {
+			extensionIndexes[int32(i)] = int32(len(newFileDescriptorProto.Extension))
+			newFileDescriptorProto.Extension = append(newFileDescriptorProto.Extension, extension)
+		}
+	}
+
+	referencedPaths := t.pathToReferencedPaths[fileDescriptorProto.GetName()]
+	publicDependencyIndexes := int32Set(fileDescriptorProto.GetPublicDependency())
+	weakDependencyIndexes := int32Set(fileDescriptorProto.GetWeakDependency())
+	dependencyIndexes := make(map[int32]int32)
+	newDependencies := make(map[string]struct{})
+	newFileDescriptorProto.Dependency = nil
+	newFileDescriptorProto.PublicDependency = nil
+	newFileDescriptorProto.WeakDependency = nil
+	for i, dependency := range fileDescriptorProto.GetDependency() {
+		if _, ok := newPaths[dependency]; !ok {
+			continue
+		}
+		_, isReferenced := referencedPaths[dependency]
+		_, isPublic := publicDependencyIndexes[int32(i)]
+		// public dependencies are kept as files that import this file may
+		// reference types through them
+		if !isReferenced && !isPublic {
+			continue
+		}
+		newIndex := int32(len(newFileDescriptorProto.Dependency))
+		dependencyIndexes[int32(i)] = newIndex
+		newFileDescriptorProto.Dependency = append(newFileDescriptorProto.Dependency, dependency)
+		newDependencies[dependency] = struct{}{}
+		if isPublic {
+			newFileDescriptorProto.PublicDependency = append(newFileDescriptorProto.PublicDependency, newIndex)
+		}
+		if _, ok := weakDependencyIndexes[int32(i)]; ok {
+			newFileDescriptorProto.WeakDependency = append(newFileDescriptorProto.WeakDependency, newIndex)
+		}
+	}
+	// referenced files that were only imported publicly through a dependency
+	// that was removed are imported directly
+	missingReferencedPaths := make([]string, 0, len(referencedPaths))
+	for referencedPath := range referencedPaths {
+		if _, ok := newDependencies[referencedPath]; !ok {
+			missingReferencedPaths = append(missingReferencedPaths, referencedPath)
+		}
+	}
+	sort.Strings(missingReferencedPaths)
+	newFileDescriptorProto.Dependency = append(newFileDescriptorProto.Dependency, missingReferencedPaths...)
+
+	if sourceCodeInfo := fileDescriptorProto.GetSourceCodeInfo(); sourceCodeInfo != nil {
+		newFileDescriptorProto.SourceCodeInfo = filterSourceCodeInfo(
+			sourceCodeInfo,
+			map[int32]map[int32]int32{
+				fileDependencyFieldNumber:  dependencyIndexes,
+				fileMessageTypeFieldNumber: messageIndexes,
+				fileEnumTypeFieldNumber:    enumIndexes,
+				fileServiceFieldNumber:     serviceIndexes,
+				fileExtensionFieldNumber:   extensionIndexes,
+			},
+		)
+	}
+	return newFileDescriptorProto
+}
+
+func (t *typeFilter) isRequired(prefix string, name string) bool {
+	_, ok := t.requiredNames[joinTypeName(prefix, name)]
+	return ok
+}
+
+// filterSourceCodeInfo returns a copy of the SourceCodeInfo with the Locations
+// of the removed elements removed, and the Locations of the kept elements
+// pointing to their new indexes.
+//
+// fieldNumberToIndexes maps the field numbers of the filtered fields of
+// FileDescriptorProto to the old to new indexes of the kept elements.
+func filterSourceCodeInfo(
+	sourceCodeInfo *descriptorpb.SourceCodeInfo,
+	fieldNumberToIndexes map[int32]map[int32]int32,
+) *descriptorpb.SourceCodeInfo {
+	newSourceCodeInfo := &descriptorpb.SourceCodeInfo{}
+	for _, location := range sourceCodeInfo.GetLocation() {
+		path := location.GetPath()
+		if len(path) > 0 {
+			if path[0] == filePublicDependencyFieldNumber || path[0] == fileWeakDependencyFieldNumber {
+				continue
+			}
+			if indexes, ok := fieldNumberToIndexes[path[0]]; ok {
+				if len(path) == 1 {
+					// for example, the location of an extend block
+					if len(indexes) == 0 {
+						continue
+					}
+				} else {
+					newIndex, ok := indexes[path[1]]
+					if !ok {
+						continue
+					}
+					if newIndex != path[1] {
+						location = proto.Clone(location).(*descriptorpb.SourceCodeInfo_Location)
+						location.Path[1] = newIndex
+					}
+				}
+			}
+		}
+		newSourceCodeInfo.Location = append(newSourceCodeInfo.Location, location)
+	}
+	return newSourceCodeInfo
+}
+
+func newTypeFilterExtensionKey(extension *descriptorpb.FieldDescriptorProto) typeFilterExtensionKey {
+	return typeFilterExtensionKey{
+		extendee: strings.TrimPrefix(extension.GetExtendee(), "."),
+		number:   extension.GetNumber(),
+	}
+}
+
+func joinTypeName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func int32Set(values []int32) map[int32]struct{} {
+	set := make(map[int32]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
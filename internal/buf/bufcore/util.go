@@ -15,12 +15,23 @@
 package bufcore
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 
+	"github.com/bufbuild/buf/internal/gen/data/wkt"
 	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+const imageDigestPrefix = "sha256:"
+
 func getImportFileIndexes(protoImage *imagev1.Image) (map[int]struct{}, error) {
 	imageImportRefs := protoImage.GetBufbuildImageExtension().GetImageImportRefs()
 	importFileIndexes := make(map[int]struct{}, len(imageImportRefs))
@@ -34,6 +45,194 @@ func getImportFileIndexes(protoImage *imagev1.Image) (map[int]struct{}, error) {
 	return importFileIndexes, nil
 }
 
+func getPathToImageFileRole(image Image) map[string]ImageFileRole {
+	imageFiles := image.Files()
+	pathToImageFileRole := make(map[string]ImageFileRole, len(imageFiles))
+	for _, imageFile := range imageFiles {
+		if imageFile.IsImport() {
+			if isWellKnownTypePath(imageFile.Path()) {
+				pathToImageFileRole[imageFile.Path()] = ImageFileRoleWellKnownType
+			} else {
+				pathToImageFileRole[imageFile.Path()] = ImageFileRoleTransitiveImport
+			}
+			continue
+		}
+		pathToImageFileRole[imageFile.Path()] = ImageFileRoleTarget
+	}
+	for _, imageFile := range imageFiles {
+		if imageFile.IsImport() {
+			continue
+		}
+		for _, importPath := range imageFile.ImportPaths() {
+			// imports may not be present in the Image, and well-known types
+			// take precedence over direct imports
+			if pathToImageFileRole[importPath] == ImageFileRoleTransitiveImport {
+				pathToImageFileRole[importPath] = ImageFileRoleDirectImport
+			}
+		}
+	}
+	return pathToImageFileRole
+}
+
+func isWellKnownTypePath(path string) bool {
+	// the well-known type bucket is in-memory, so there is no need for a real context
+	_, err := wkt.ReadBucket.Stat(context.Background(), path)
+	return err == nil
+}
+
+func imageWithSourceCodeInfo(image Image, sourceImage Image) (Image, error) {
+	imageFiles := image.Files()
+	newImageFiles := make([]ImageFile, len(imageFiles))
+	for i, imageFile := range imageFiles {
+		sourceImageFile := sourceImage.GetFile(imageFile.Path())
+		if sourceImageFile == nil || sourceImageFile.Proto().GetSourceCodeInfo() == nil {
+			newImageFiles[i] = imageFile
+			continue
+		}
+		digest, err := getFileDescriptorProtoDigest(imageFile.Proto())
+		if err != nil {
+			return nil, err
+		}
+		sourceDigest, err := getFileDescriptorProtoDigest(sourceImageFile.Proto())
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(digest, sourceDigest) {
+			return nil, fmt.Errorf("%s has different content than the file with the same path in the source", imageFile.Path())
+		}
+		fileDescriptorProto := proto.Clone(imageFile.Proto()).(*descriptorpb.FileDescriptorProto)
+		fileDescriptorProto.SourceCodeInfo = sourceImageFile.Proto().GetSourceCodeInfo()
+		newImageFiles[i] = newImageFileNoValidate(
+			fileDescriptorProto,
+			imageFile.ExternalPath(),
+			imageFile.IsImport(),
+		)
+	}
+	return newImageNoValidate(newImageFiles), nil
+}
+
+func mergeImages(images ...Image) (Image, error) {
+	var imageFiles []ImageFile
+	pathToIndex := make(map[string]int)
+	pathToDigest := make(map[string][]byte)
+	for _, image := range images {
+		for _, imageFile := range image.Files() {
+			path := imageFile.Path()
+			digest, err := getFileDescriptorProtoDigest(imageFile.Proto())
+			if err != nil {
+				return nil, err
+			}
+			index, ok := pathToIndex[path]
+			if !ok {
+				pathToIndex[path] = len(imageFiles)
+				pathToDigest[path] = digest
+				imageFiles = append(imageFiles, imageFile)
+				continue
+			}
+			if !bytes.Equal(digest, pathToDigest[path]) {
+				return nil, fmt.Errorf("%s has conflicting definitions in the images", path)
+			}
+			if imageFiles[index].IsImport() && !imageFile.IsImport() {
+				imageFiles[index] = imageFile
+			}
+		}
+	}
+	return newImage(imageFiles, true)
+}
+
+type sourceCodeInfoFilter struct {
+	onlyComments            bool
+	excludeSpans            bool
+	excludeDetachedComments bool
+}
+
+func imageWithFilteredSourceCodeInfo(image Image, options ...SourceCodeInfoFilterOption) Image {
+	if len(options) == 0 {
+		return image
+	}
+	sourceCodeInfoFilter := &sourceCodeInfoFilter{}
+	for _, option := range options {
+		option(sourceCodeInfoFilter)
+	}
+	imageFiles := image.Files()
+	newImageFiles := make([]ImageFile, len(imageFiles))
+	for i, imageFile := range imageFiles {
+		sourceCodeInfo := imageFile.Proto().GetSourceCodeInfo()
+		if sourceCodeInfo == nil {
+			newImageFiles[i] = imageFile
+			continue
+		}
+		fileDescriptorProto := proto.Clone(imageFile.Proto()).(*descriptorpb.FileDescriptorProto)
+		fileDescriptorProto.SourceCodeInfo = sourceCodeInfoFilter.filter(sourceCodeInfo)
+		newImageFiles[i] = newImageFileNoValidate(
+			fileDescriptorProto,
+			imageFile.ExternalPath(),
+			imageFile.IsImport(),
+		)
+	}
+	return newImageNoValidate(newImageFiles)
+}
+
+func (s *sourceCodeInfoFilter) filter(sourceCodeInfo *descriptorpb.SourceCodeInfo) *descriptorpb.SourceCodeInfo {
+	newSourceCodeInfo := &descriptorpb.SourceCodeInfo{
+		Location: make([]*descriptorpb.SourceCodeInfo_Location, 0, len(sourceCodeInfo.Location)),
+	}
+	for _, location := range sourceCodeInfo.Location {
+		newLocation := &descriptorpb.SourceCodeInfo_Location{
+			Path:                    location.Path,
+			Span:                    location.Span,
+			LeadingComments:         location.LeadingComments,
+			TrailingComments:        location.TrailingComments,
+			LeadingDetachedComments: location.LeadingDetachedComments,
+		}
+		if s.excludeSpans {
+			newLocation.Span = nil
+		}
+		if s.excludeDetachedComments {
+			newLocation.LeadingDetachedComments = nil
+		}
+		if s.onlyComments && newLocation.LeadingComments == nil && newLocation.TrailingComments == nil && len(newLocation.LeadingDetachedComments) == 0 {
+			continue
+		}
+		newSourceCodeInfo.Location = append(newSourceCodeInfo.Location, newLocation)
+	}
+	return newSourceCodeInfo
+}
+
+func imageDigest(image Image) (string, error) {
+	imageFiles := append([]ImageFile{}, image.Files()...)
+	sort.Slice(
+		imageFiles,
+		func(i int, j int) bool {
+			return imageFiles[i].Path() < imageFiles[j].Path()
+		},
+	)
+	// the ImageImportRefs are computed relative to the sorted order
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(
+		ImageToProtoImage(newImageNoValidate(imageFiles)),
+	)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	return imageDigestPrefix + hex.EncodeToString(digest[:]), nil
+}
+
+// getFileDescriptorProtoDigest returns the digest of the FileDescriptorProto
+// without its SourceCodeInfo.
+func getFileDescriptorProtoDigest(fileDescriptorProto *descriptorpb.FileDescriptorProto) ([]byte, error) {
+	if fileDescriptorProto.GetSourceCodeInfo() != nil {
+		fileDescriptorProto = proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+		fileDescriptorProto.SourceCodeInfo = nil
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	return digest[:], nil
+}
+
 func imageWithOnlyPaths(
 	image Image,
 	paths []string,
@@ -69,6 +268,31 @@ func imageWithOnlyPaths(
 	return NewImage(imageFiles)
 }
 
+func imageWithoutPaths(
+	image Image,
+	paths []string,
+) (Image, error) {
+	pathMap := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		// paths may be directories, so these are not validated as file paths
+		normalizedPath, err := normalpath.NormalizeAndValidate(path)
+		if err != nil {
+			return nil, err
+		}
+		pathMap[normalizedPath] = struct{}{}
+	}
+	var includedPaths []string
+	for _, imageFile := range ImageTargetFiles(image) {
+		if !normalpath.MapHasEqualOrContainingPath(pathMap, imageFile.Path(), normalpath.Relative) {
+			includedPaths = append(includedPaths, imageFile.Path())
+		}
+	}
+	if len(includedPaths) == 0 {
+		return nil, ErrNoTargetFiles
+	}
+	return imageWithOnlyPaths(image, includedPaths, false)
+}
+
 // returns accumulated files in correct order
 func addFileWithImports(
 	accumulator []ImageFile,
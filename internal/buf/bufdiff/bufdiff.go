@@ -0,0 +1,190 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdiff computes semantic differences between two images.
+//
+// Unlike bufbreaking, no policy is applied: every addition, removal, and
+// change is reported, regardless of whether it is breaking.
+package bufdiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoreutil"
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+)
+
+const (
+	// ChangeTypeAdded says that an element was added.
+	ChangeTypeAdded ChangeType = iota + 1
+	// ChangeTypeRemoved says that an element was removed.
+	ChangeTypeRemoved
+	// ChangeTypeChanged says that an element exists in both images but is different.
+	ChangeTypeChanged
+)
+
+const (
+	// KindFile is a file.
+	KindFile = "file"
+	// KindMessage is a message.
+	KindMessage = "message"
+	// KindField is a field.
+	KindField = "field"
+	// KindOneof is a oneof.
+	KindOneof = "oneof"
+	// KindEnum is an enum.
+	KindEnum = "enum"
+	// KindEnumValue is an enum value.
+	KindEnumValue = "enum_value"
+	// KindService is a service.
+	KindService = "service"
+	// KindRPC is a RPC.
+	KindRPC = "rpc"
+)
+
+var (
+	changeTypeToString = map[ChangeType]string{
+		ChangeTypeAdded:   "added",
+		ChangeTypeRemoved: "removed",
+		ChangeTypeChanged: "changed",
+	}
+	changeTypeToSymbol = map[ChangeType]string{
+		ChangeTypeAdded:   "+",
+		ChangeTypeRemoved: "-",
+		ChangeTypeChanged: "~",
+	}
+	kindToRank = map[string]int{
+		KindFile:      1,
+		KindMessage:   2,
+		KindField:     3,
+		KindOneof:     4,
+		KindEnum:      5,
+		KindEnumValue: 6,
+		KindService:   7,
+		KindRPC:       8,
+	}
+)
+
+// ChangeType is the type of a change.
+type ChangeType int
+
+// String implements fmt.Stringer.
+func (c ChangeType) String() string {
+	s, ok := changeTypeToString[c]
+	if !ok {
+		return fmt.Sprintf("%d", c)
+	}
+	return s
+}
+
+// Change is a single semantic change between two images.
+type Change interface {
+	// Stringer returns the string representation in text format.
+	fmt.Stringer
+	// Marshaler returns the string representation in JSON format.
+	json.Marshaler
+
+	// Type is the type of change.
+	Type() ChangeType
+	// Kind is the kind of element that changed, such as KindMessage.
+	Kind() string
+	// Name is the fully-qualified name of the element, or the path for files.
+	//
+	// Fields, oneofs, enum values, and RPCs are qualified by their parent, for
+	// example foo.v1.Bar.baz.
+	Name() string
+	// Path is the path of the file that contains the element.
+	//
+	// For removed elements, this is the path in the previous image.
+	Path() string
+	// Description describes the change.
+	//
+	// This is empty for added and removed elements.
+	Description() string
+}
+
+// Diff returns the semantic changes from previousImage to image.
+//
+// Imports are not compared, so images should typically be filtered with
+// bufcore.ImageWithoutImports before passing them to this function.
+//
+// The changes are sorted by path, name, and kind.
+func Diff(ctx context.Context, previousImage bufcore.Image, image bufcore.Image) ([]Change, error) {
+	previousFiles, err := protosource.NewFilesUnstable(ctx, bufcoreutil.NewInputFiles(previousImage.Files())...)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protosource.NewFilesUnstable(ctx, bufcoreutil.NewInputFiles(image.Files())...)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diff(previousFiles, files)
+	if err != nil {
+		return nil, err
+	}
+	sortChanges(changes)
+	return changes, nil
+}
+
+// PrintChanges prints the changes to the writer, one per line.
+//
+// If asJSON is set, each change is printed as a JSON object.
+func PrintChanges(writer io.Writer, changes []Change, asJSON bool) error {
+	for _, change := range changes {
+		s := change.String()
+		if asJSON {
+			data, err := change.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			s = string(data)
+		}
+		if _, err := fmt.Fprintln(writer, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortChanges(changes []Change) {
+	sort.SliceStable(
+		changes,
+		func(i int, j int) bool {
+			one := changes[i]
+			two := changes[j]
+			if one.Path() != two.Path() {
+				return one.Path() < two.Path()
+			}
+			// files sort before the elements they contain
+			if (one.Kind() == KindFile) != (two.Kind() == KindFile) {
+				return one.Kind() == KindFile
+			}
+			if one.Name() != two.Name() {
+				return one.Name() < two.Name()
+			}
+			if one.Kind() != two.Kind() {
+				return kindToRank[one.Kind()] < kindToRank[two.Kind()]
+			}
+			if one.Type() != two.Type() {
+				return one.Type() < two.Type()
+			}
+			return one.Description() < two.Description()
+		},
+	)
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoretesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	previousFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/v1/a.proto")
+	previousFileDescriptorProto.Package = proto.String("a.v1")
+	previousFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Foo"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				newFieldDescriptorProto("one", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				newFieldDescriptorProto("two", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+			},
+		},
+		{
+			Name: proto.String("Removed"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				newFieldDescriptorProto("one", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Nested"),
+				},
+			},
+		},
+	}
+	previousFileDescriptorProto.EnumType = []*descriptorpb.EnumDescriptorProto{
+		{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				newEnumValueDescriptorProto("STATUS_UNSPECIFIED", 0),
+				newEnumValueDescriptorProto("STATUS_OK", 1),
+			},
+		},
+	}
+	previousFileDescriptorProto.Service = []*descriptorpb.ServiceDescriptorProto{
+		{
+			Name: proto.String("FooService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("GetFoo"),
+					InputType:  proto.String(".a.v1.Foo"),
+					OutputType: proto.String(".a.v1.Foo"),
+				},
+			},
+		},
+	}
+	fileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/v1/a.proto")
+	fileDescriptorProto.Package = proto.String("a.v1")
+	fileDescriptorProto.Options = &descriptorpb.FileOptions{
+		GoPackage: proto.String("github.com/acme/a/v1;av1"),
+	}
+	renamedField := newFieldDescriptorProto("uno", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, "")
+	fileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Foo"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				renamedField,
+				newFieldDescriptorProto("three", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".a.v1.Status"),
+			},
+			Options: &descriptorpb.MessageOptions{
+				Deprecated: proto.Bool(true),
+			},
+		},
+	}
+	fileDescriptorProto.EnumType = []*descriptorpb.EnumDescriptorProto{
+		{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				newEnumValueDescriptorProto("STATUS_UNSPECIFIED", 0),
+				newEnumValueDescriptorProto("STATUS_OK", 2),
+				newEnumValueDescriptorProto("STATUS_ERROR", 3),
+			},
+		},
+	}
+	fileDescriptorProto.Service = []*descriptorpb.ServiceDescriptorProto{
+		{
+			Name: proto.String("FooService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:            proto.String("GetFoo"),
+					InputType:       proto.String(".a.v1.Foo"),
+					OutputType:      proto.String(".a.v1.Foo"),
+					ServerStreaming: proto.Bool(true),
+				},
+				{
+					Name:       proto.String("ListFoos"),
+					InputType:  proto.String(".a.v1.Foo"),
+					OutputType: proto.String(".a.v1.Foo"),
+				},
+			},
+		},
+	}
+	addedFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a/v1/b.proto")
+	addedFileDescriptorProto.Package = proto.String("a.v1")
+	addedFileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Bar"),
+		},
+	}
+	previousImage := newImage(t, previousFileDescriptorProto)
+	image := newImage(t, fileDescriptorProto, addedFileDescriptorProto)
+
+	changes, err := Diff(context.Background(), previousImage, image)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintChanges(buffer, changes, false))
+	assert.Equal(
+		t,
+		`~ file a/v1/a.proto: option "go_package" changed from "" to "github.com/acme/a/v1;av1"
+~ message a.v1.Foo: option "deprecated" changed from "false" to "true"
++ field a.v1.Foo.three
+- field a.v1.Foo.two
+~ field a.v1.Foo.uno: name changed from "one" to "uno"
+~ field a.v1.Foo.uno: type changed from "int32" to "int64"
+~ rpc a.v1.FooService.GetFoo: server streaming changed from "false" to "true"
++ rpc a.v1.FooService.ListFoos
+- message a.v1.Removed
++ enum_value a.v1.Status.STATUS_ERROR
+~ enum_value a.v1.Status.STATUS_OK: number changed from "1" to "2"
++ file a/v1/b.proto
++ message a.v1.Bar
+`,
+		buffer.String(),
+	)
+	buffer.Reset()
+	require.NoError(t, PrintChanges(buffer, changes[:1], true))
+	assert.Equal(
+		t,
+		`{"type":"changed","kind":"file","name":"a/v1/a.proto","path":"a/v1/a.proto","description":"option \"go_package\" changed from \"\" to \"github.com/acme/a/v1;av1\""}
+`,
+		buffer.String(),
+	)
+
+	changes, err = Diff(context.Background(), image, image)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDiffMapField(t *testing.T) {
+	t.Parallel()
+	newMapFileDescriptorProto := func(valueType descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FileDescriptorProto {
+		fileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a.proto")
+		fileDescriptorProto.Package = proto.String("a")
+		field := newFieldDescriptorProto("labels", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".a.Foo.LabelsEntry")
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		fileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{field},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("LabelsEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							newFieldDescriptorProto("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+							newFieldDescriptorProto("value", 2, valueType, ""),
+						},
+						Options: &descriptorpb.MessageOptions{
+							MapEntry: proto.Bool(true),
+						},
+					},
+				},
+			},
+		}
+		return fileDescriptorProto
+	}
+	changes, err := Diff(
+		context.Background(),
+		newImage(t, newMapFileDescriptorProto(descriptorpb.FieldDescriptorProto_TYPE_STRING)),
+		newImage(t, newMapFileDescriptorProto(descriptorpb.FieldDescriptorProto_TYPE_BYTES)),
+	)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, `~ field a.Foo.labels: type changed from "map<string, string>" to "map<string, bytes>"`, changes[0].String())
+}
+
+func newImage(t *testing.T, fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) bufcore.Image {
+	imageFiles := make([]bufcore.ImageFile, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		imageFiles[i] = bufcoretesting.NewImageFile(t, fileDescriptorProto, "", false)
+	}
+	image, err := bufcore.NewImage(imageFiles)
+	require.NoError(t, err)
+	return image
+}
+
+func newFieldDescriptorProto(
+	name string,
+	number int32,
+	fieldType descriptorpb.FieldDescriptorProto_Type,
+	typeName string,
+) *descriptorpb.FieldDescriptorProto {
+	fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   fieldType.Enum(),
+	}
+	if typeName != "" {
+		fieldDescriptorProto.TypeName = proto.String(typeName)
+	}
+	return fieldDescriptorProto
+}
+
+func newEnumValueDescriptorProto(name string, number int32) *descriptorpb.EnumValueDescriptorProto {
+	return &descriptorpb.EnumValueDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+	}
+}
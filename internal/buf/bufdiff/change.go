@@ -0,0 +1,98 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type change struct {
+	changeType  ChangeType
+	kind        string
+	name        string
+	path        string
+	description string
+}
+
+func newChange(
+	changeType ChangeType,
+	kind string,
+	name string,
+	path string,
+	description string,
+) *change {
+	return &change{
+		changeType:  changeType,
+		kind:        kind,
+		name:        name,
+		path:        path,
+		description: description,
+	}
+}
+
+func (c *change) Type() ChangeType {
+	return c.changeType
+}
+
+func (c *change) Kind() string {
+	return c.kind
+}
+
+func (c *change) Name() string {
+	return c.name
+}
+
+func (c *change) Path() string {
+	return c.path
+}
+
+func (c *change) Description() string {
+	return c.description
+}
+
+func (c *change) String() string {
+	if c == nil {
+		return ""
+	}
+	s := fmt.Sprintf("%s %s %s", changeTypeToSymbol[c.changeType], c.kind, c.name)
+	if c.description != "" {
+		s += ": " + c.description
+	}
+	return s
+}
+
+func (c *change) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(
+		externalChange{
+			Type:        c.changeType.String(),
+			Kind:        c.kind,
+			Name:        c.name,
+			Path:        c.path,
+			Description: c.description,
+		},
+	)
+}
+
+type externalChange struct {
+	Type        string `json:"type,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Description string `json:"description,omitempty"`
+}
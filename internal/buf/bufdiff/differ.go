@@ -0,0 +1,402 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+)
+
+var fileValueGetters = []*valueGetter{
+	newValueGetter(`package`, func(file protosource.File) string { return file.Package() }),
+	newValueGetter(`syntax`, func(file protosource.File) string { return file.Syntax().String() }),
+	newValueGetter(`edition`, func(file protosource.File) string { return file.Edition() }),
+	newValueGetter(`option "csharp_namespace"`, func(file protosource.File) string { return file.CsharpNamespace() }),
+	newValueGetter(`option "go_package"`, func(file protosource.File) string { return file.GoPackage() }),
+	newValueGetter(`option "java_multiple_files"`, func(file protosource.File) string { return strconv.FormatBool(file.JavaMultipleFiles()) }),
+	newValueGetter(`option "java_outer_classname"`, func(file protosource.File) string { return file.JavaOuterClassname() }),
+	newValueGetter(`option "java_package"`, func(file protosource.File) string { return file.JavaPackage() }),
+	newValueGetter(`option "java_string_check_utf8"`, func(file protosource.File) string { return strconv.FormatBool(file.JavaStringCheckUtf8()) }),
+	newValueGetter(`option "objc_class_prefix"`, func(file protosource.File) string { return file.ObjcClassPrefix() }),
+	newValueGetter(`option "php_class_prefix"`, func(file protosource.File) string { return file.PhpClassPrefix() }),
+	newValueGetter(`option "php_namespace"`, func(file protosource.File) string { return file.PhpNamespace() }),
+	newValueGetter(`option "php_metadata_namespace"`, func(file protosource.File) string { return file.PhpMetadataNamespace() }),
+	newValueGetter(`option "ruby_package"`, func(file protosource.File) string { return file.RubyPackage() }),
+	newValueGetter(`option "swift_prefix"`, func(file protosource.File) string { return file.SwiftPrefix() }),
+	newValueGetter(`option "optimize_for"`, func(file protosource.File) string { return file.OptimizeFor().String() }),
+	newValueGetter(`option "cc_generic_services"`, func(file protosource.File) string { return strconv.FormatBool(file.CcGenericServices()) }),
+	newValueGetter(`option "java_generic_services"`, func(file protosource.File) string { return strconv.FormatBool(file.JavaGenericServices()) }),
+	newValueGetter(`option "py_generic_services"`, func(file protosource.File) string { return strconv.FormatBool(file.PyGenericServices()) }),
+	newValueGetter(`option "php_generic_services"`, func(file protosource.File) string { return strconv.FormatBool(file.PhpGenericServices()) }),
+	newValueGetter(`option "cc_enable_arenas"`, func(file protosource.File) string { return strconv.FormatBool(file.CcEnableArenas()) }),
+}
+
+type valueGetter struct {
+	name string
+	get  func(protosource.File) string
+}
+
+func newValueGetter(name string, get func(protosource.File) string) *valueGetter {
+	return &valueGetter{
+		name: name,
+		get:  get,
+	}
+}
+
+// differ pairs elements the same way as bufbreaking: files by path, messages,
+// enums, and services by fully-qualified name, fields by number, and oneofs,
+// enum values, and RPCs by name.
+type differ struct {
+	previousFullNameToMessage map[string]protosource.Message
+	fullNameToMessage         map[string]protosource.Message
+	changes                   []Change
+}
+
+func diff(previousFiles []protosource.File, files []protosource.File) ([]Change, error) {
+	previousFullNameToMessage, err := protosource.FullNameToMessage(previousFiles...)
+	if err != nil {
+		return nil, err
+	}
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{
+		previousFullNameToMessage: previousFullNameToMessage,
+		fullNameToMessage:         fullNameToMessage,
+	}
+	if err := d.diffFiles(previousFiles, files); err != nil {
+		return nil, err
+	}
+	if err := d.diffMessages(); err != nil {
+		return nil, err
+	}
+	if err := d.diffEnums(previousFiles, files); err != nil {
+		return nil, err
+	}
+	if err := d.diffServices(previousFiles, files); err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+func (d *differ) diffFiles(previousFiles []protosource.File, files []protosource.File) error {
+	previousFilePathToFile, err := protosource.FilePathToFile(previousFiles...)
+	if err != nil {
+		return err
+	}
+	filePathToFile, err := protosource.FilePathToFile(files...)
+	if err != nil {
+		return err
+	}
+	for path, previousFile := range previousFilePathToFile {
+		file, ok := filePathToFile[path]
+		if !ok {
+			d.add(ChangeTypeRemoved, KindFile, path, path, "")
+			continue
+		}
+		for _, valueGetter := range fileValueGetters {
+			d.addIfDifferent(KindFile, path, path, valueGetter.name, valueGetter.get(previousFile), valueGetter.get(file))
+		}
+	}
+	for path := range filePathToFile {
+		if _, ok := previousFilePathToFile[path]; !ok {
+			d.add(ChangeTypeAdded, KindFile, path, path, "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffMessages() error {
+	for fullName, previousMessage := range d.previousFullNameToMessage {
+		if previousMessage.IsMapEntry() {
+			continue
+		}
+		message, ok := d.fullNameToMessage[fullName]
+		if !ok {
+			if !isNestedInUnpairedMessage(fullName, d.previousFullNameToMessage, d.fullNameToMessage) {
+				d.add(ChangeTypeRemoved, KindMessage, fullName, previousMessage.File().Path(), "")
+			}
+			continue
+		}
+		if err := d.diffMessage(previousMessage, message); err != nil {
+			return err
+		}
+	}
+	for fullName, message := range d.fullNameToMessage {
+		if message.IsMapEntry() {
+			continue
+		}
+		if _, ok := d.previousFullNameToMessage[fullName]; ok {
+			continue
+		}
+		if !isNestedInUnpairedMessage(fullName, d.fullNameToMessage, d.previousFullNameToMessage) {
+			d.add(ChangeTypeAdded, KindMessage, fullName, message.File().Path(), "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffMessage(previousMessage protosource.Message, message protosource.Message) error {
+	fullName := message.FullName()
+	path := message.File().Path()
+	d.addIfDifferent(KindMessage, fullName, path, `option "deprecated"`, strconv.FormatBool(previousMessage.Deprecated()), strconv.FormatBool(message.Deprecated()))
+	d.addIfDifferent(KindMessage, fullName, path, `option "message_set_wire_format"`, strconv.FormatBool(previousMessage.MessageSetWireFormat()), strconv.FormatBool(message.MessageSetWireFormat()))
+	d.addIfDifferent(KindMessage, fullName, path, `option "no_standard_descriptor_accessor"`, strconv.FormatBool(previousMessage.NoStandardDescriptorAccessor()), strconv.FormatBool(message.NoStandardDescriptorAccessor()))
+	previousNumberToField, err := protosource.NumberToMessageField(previousMessage)
+	if err != nil {
+		return err
+	}
+	numberToField, err := protosource.NumberToMessageField(message)
+	if err != nil {
+		return err
+	}
+	for number, previousField := range previousNumberToField {
+		field, ok := numberToField[number]
+		if !ok {
+			d.add(ChangeTypeRemoved, KindField, fullName+"."+previousField.Name(), path, "")
+			continue
+		}
+		if err := d.diffField(previousField, field); err != nil {
+			return err
+		}
+	}
+	for number, field := range numberToField {
+		if _, ok := previousNumberToField[number]; !ok {
+			d.add(ChangeTypeAdded, KindField, fullName+"."+field.Name(), path, "")
+		}
+	}
+	previousNameToOneof, err := protosource.NameToMessageOneof(previousMessage)
+	if err != nil {
+		return err
+	}
+	nameToOneof, err := protosource.NameToMessageOneof(message)
+	if err != nil {
+		return err
+	}
+	for name := range previousNameToOneof {
+		if _, ok := nameToOneof[name]; !ok {
+			d.add(ChangeTypeRemoved, KindOneof, fullName+"."+name, path, "")
+		}
+	}
+	for name := range nameToOneof {
+		if _, ok := previousNameToOneof[name]; !ok {
+			d.add(ChangeTypeAdded, KindOneof, fullName+"."+name, path, "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffField(previousField protosource.Field, field protosource.Field) error {
+	fullName := field.Message().FullName() + "." + field.Name()
+	path := field.File().Path()
+	d.addIfDifferent(KindField, fullName, path, `name`, previousField.Name(), field.Name())
+	d.addIfDifferent(KindField, fullName, path, `type`, protosource.FieldTypeString(previousField, d.previousFullNameToMessage), protosource.FieldTypeString(field, d.fullNameToMessage))
+	d.addIfDifferent(KindField, fullName, path, `label`, previousField.Label().String(), field.Label().String())
+	previousOneofName, err := getFieldOneofName(previousField)
+	if err != nil {
+		return err
+	}
+	oneofName, err := getFieldOneofName(field)
+	if err != nil {
+		return err
+	}
+	d.addIfDifferent(KindField, fullName, path, `oneof`, previousOneofName, oneofName)
+	// the json_name follows the name unless set explicitly, so only report
+	// it separately if the name itself did not change
+	if previousField.Name() == field.Name() {
+		d.addIfDifferent(KindField, fullName, path, `json_name`, previousField.JSONName(), field.JSONName())
+	}
+	d.addIfDifferent(KindField, fullName, path, `option "jstype"`, previousField.JSType().String(), field.JSType().String())
+	d.addIfDifferent(KindField, fullName, path, `option "ctype"`, previousField.CType().String(), field.CType().String())
+	d.addIfDifferent(KindField, fullName, path, `option "packed"`, getPackedString(previousField), getPackedString(field))
+	d.addIfDifferent(KindField, fullName, path, `option "deprecated"`, strconv.FormatBool(previousField.Deprecated()), strconv.FormatBool(field.Deprecated()))
+	return nil
+}
+
+func (d *differ) diffEnums(previousFiles []protosource.File, files []protosource.File) error {
+	previousFullNameToEnum, err := protosource.FullNameToEnum(previousFiles...)
+	if err != nil {
+		return err
+	}
+	fullNameToEnum, err := protosource.FullNameToEnum(files...)
+	if err != nil {
+		return err
+	}
+	for fullName, previousEnum := range previousFullNameToEnum {
+		enum, ok := fullNameToEnum[fullName]
+		if !ok {
+			if !isNestedInUnpairedMessage(fullName, d.previousFullNameToMessage, d.fullNameToMessage) {
+				d.add(ChangeTypeRemoved, KindEnum, fullName, previousEnum.File().Path(), "")
+			}
+			continue
+		}
+		if err := d.diffEnum(previousEnum, enum); err != nil {
+			return err
+		}
+	}
+	for fullName, enum := range fullNameToEnum {
+		if _, ok := previousFullNameToEnum[fullName]; ok {
+			continue
+		}
+		if !isNestedInUnpairedMessage(fullName, d.fullNameToMessage, d.previousFullNameToMessage) {
+			d.add(ChangeTypeAdded, KindEnum, fullName, enum.File().Path(), "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffEnum(previousEnum protosource.Enum, enum protosource.Enum) error {
+	fullName := enum.FullName()
+	path := enum.File().Path()
+	d.addIfDifferent(KindEnum, fullName, path, `option "allow_alias"`, strconv.FormatBool(previousEnum.AllowAlias()), strconv.FormatBool(enum.AllowAlias()))
+	d.addIfDifferent(KindEnum, fullName, path, `option "deprecated"`, strconv.FormatBool(previousEnum.Deprecated()), strconv.FormatBool(enum.Deprecated()))
+	previousNameToEnumValue, err := protosource.NameToEnumValue(previousEnum)
+	if err != nil {
+		return err
+	}
+	nameToEnumValue, err := protosource.NameToEnumValue(enum)
+	if err != nil {
+		return err
+	}
+	for name, previousEnumValue := range previousNameToEnumValue {
+		enumValueFullName := fullName + "." + name
+		enumValue, ok := nameToEnumValue[name]
+		if !ok {
+			d.add(ChangeTypeRemoved, KindEnumValue, enumValueFullName, path, "")
+			continue
+		}
+		d.addIfDifferent(KindEnumValue, enumValueFullName, path, `number`, strconv.Itoa(previousEnumValue.Number()), strconv.Itoa(enumValue.Number()))
+		d.addIfDifferent(KindEnumValue, enumValueFullName, path, `option "deprecated"`, strconv.FormatBool(previousEnumValue.Deprecated()), strconv.FormatBool(enumValue.Deprecated()))
+	}
+	for name := range nameToEnumValue {
+		if _, ok := previousNameToEnumValue[name]; !ok {
+			d.add(ChangeTypeAdded, KindEnumValue, fullName+"."+name, path, "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffServices(previousFiles []protosource.File, files []protosource.File) error {
+	previousFullNameToService, err := protosource.FullNameToService(previousFiles...)
+	if err != nil {
+		return err
+	}
+	fullNameToService, err := protosource.FullNameToService(files...)
+	if err != nil {
+		return err
+	}
+	for fullName, previousService := range previousFullNameToService {
+		service, ok := fullNameToService[fullName]
+		if !ok {
+			d.add(ChangeTypeRemoved, KindService, fullName, previousService.File().Path(), "")
+			continue
+		}
+		if err := d.diffService(previousService, service); err != nil {
+			return err
+		}
+	}
+	for fullName, service := range fullNameToService {
+		if _, ok := previousFullNameToService[fullName]; !ok {
+			d.add(ChangeTypeAdded, KindService, fullName, service.File().Path(), "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffService(previousService protosource.Service, service protosource.Service) error {
+	fullName := service.FullName()
+	path := service.File().Path()
+	d.addIfDifferent(KindService, fullName, path, `option "deprecated"`, strconv.FormatBool(previousService.Deprecated()), strconv.FormatBool(service.Deprecated()))
+	previousNameToMethod, err := protosource.NameToMethod(previousService)
+	if err != nil {
+		return err
+	}
+	nameToMethod, err := protosource.NameToMethod(service)
+	if err != nil {
+		return err
+	}
+	for name, previousMethod := range previousNameToMethod {
+		methodFullName := fullName + "." + name
+		method, ok := nameToMethod[name]
+		if !ok {
+			d.add(ChangeTypeRemoved, KindRPC, methodFullName, path, "")
+			continue
+		}
+		d.addIfDifferent(KindRPC, methodFullName, path, `request type`, strings.TrimPrefix(previousMethod.InputTypeName(), "."), strings.TrimPrefix(method.InputTypeName(), "."))
+		d.addIfDifferent(KindRPC, methodFullName, path, `response type`, strings.TrimPrefix(previousMethod.OutputTypeName(), "."), strings.TrimPrefix(method.OutputTypeName(), "."))
+		d.addIfDifferent(KindRPC, methodFullName, path, `client streaming`, strconv.FormatBool(previousMethod.ClientStreaming()), strconv.FormatBool(method.ClientStreaming()))
+		d.addIfDifferent(KindRPC, methodFullName, path, `server streaming`, strconv.FormatBool(previousMethod.ServerStreaming()), strconv.FormatBool(method.ServerStreaming()))
+		d.addIfDifferent(KindRPC, methodFullName, path, `option "idempotency_level"`, previousMethod.IdempotencyLevel().String(), method.IdempotencyLevel().String())
+		d.addIfDifferent(KindRPC, methodFullName, path, `option "deprecated"`, strconv.FormatBool(previousMethod.Deprecated()), strconv.FormatBool(method.Deprecated()))
+	}
+	for name := range nameToMethod {
+		if _, ok := previousNameToMethod[name]; !ok {
+			d.add(ChangeTypeAdded, KindRPC, fullName+"."+name, path, "")
+		}
+	}
+	return nil
+}
+
+func (d *differ) add(changeType ChangeType, kind string, name string, path string, description string) {
+	d.changes = append(d.changes, newChange(changeType, kind, name, path, description))
+}
+
+func (d *differ) addIfDifferent(kind string, name string, path string, valueName string, previousValue string, value string) {
+	if previousValue != value {
+		d.add(ChangeTypeChanged, kind, name, path, fmt.Sprintf("%s changed from %q to %q", valueName, previousValue, value))
+	}
+}
+
+// isNestedInUnpairedMessage returns true if the parent of the element is a message
+// that only exists on one side, in which case only the parent is reported.
+func isNestedInUnpairedMessage(
+	fullName string,
+	fullNameToMessage map[string]protosource.Message,
+	otherFullNameToMessage map[string]protosource.Message,
+) bool {
+	index := strings.LastIndex(fullName, ".")
+	if index < 0 {
+		return false
+	}
+	parentFullName := fullName[:index]
+	if _, ok := fullNameToMessage[parentFullName]; !ok {
+		return false
+	}
+	_, ok := otherFullNameToMessage[parentFullName]
+	return !ok
+}
+
+func getFieldOneofName(field protosource.Field) (string, error) {
+	oneof, err := protosource.FieldOneof(field)
+	if err != nil {
+		return "", err
+	}
+	if oneof == nil {
+		return "", nil
+	}
+	return oneof.Name(), nil
+}
+
+func getPackedString(field protosource.Field) string {
+	packed := field.Packed()
+	if packed == nil {
+		return ""
+	}
+	return strconv.FormatBool(*packed)
+}
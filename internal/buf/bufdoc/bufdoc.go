@@ -0,0 +1,129 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdoc builds a documentation model from images.
+//
+// The model is meant to be serialized as JSON and consumed by documentation
+// generators, so all types have JSON tags.
+package bufdoc
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoreutil"
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+)
+
+// Comments are the comments attached to an element.
+//
+// Comments are only available if the image has source code info. The comment
+// markers are removed, along with a single leading space on each line.
+type Comments struct {
+	LeadingComments  string `json:"leading_comments,omitempty"`
+	TrailingComments string `json:"trailing_comments,omitempty"`
+}
+
+// Package is a documented package.
+type Package struct {
+	// Name is empty for files without a package.
+	Name string `json:"name,omitempty"`
+	// Comments are the comments on the first package statement, ordered by file
+	// path, that has comments.
+	Comments
+	Files []string `json:"files"`
+	// Messages contains all messages in the package, including nested messages,
+	// ordered by fully-qualified name. Map entries are not included.
+	Messages []*Message `json:"messages,omitempty"`
+	// Enums contains all enums in the package, including nested enums,
+	// ordered by fully-qualified name.
+	Enums    []*Enum    `json:"enums,omitempty"`
+	Services []*Service `json:"services,omitempty"`
+}
+
+// Message is a documented message.
+type Message struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	File     string `json:"file"`
+	Comments
+	Deprecated bool     `json:"deprecated,omitempty"`
+	Fields     []*Field `json:"fields,omitempty"`
+}
+
+// Field is a documented field.
+type Field struct {
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+	// Label is empty for map fields.
+	Label string `json:"label,omitempty"`
+	// Type is the scalar type, the fully-qualified name of the message or enum,
+	// or map<K, V> for map fields.
+	Type     string `json:"type"`
+	JSONName string `json:"json_name,omitempty"`
+	Oneof    string `json:"oneof,omitempty"`
+	Comments
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// Enum is a documented enum.
+type Enum struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	File     string `json:"file"`
+	Comments
+	Deprecated bool         `json:"deprecated,omitempty"`
+	Values     []*EnumValue `json:"values,omitempty"`
+}
+
+// EnumValue is a documented enum value.
+type EnumValue struct {
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+	Comments
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// Service is a documented service.
+type Service struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+	File     string `json:"file"`
+	Comments
+	Deprecated bool      `json:"deprecated,omitempty"`
+	Methods    []*Method `json:"methods,omitempty"`
+}
+
+// Method is a documented RPC.
+type Method struct {
+	Name            string `json:"name"`
+	RequestType     string `json:"request_type"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ResponseType    string `json:"response_type"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+	Comments
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// NewPackages returns the documented packages for the non-import files in
+// the image, ordered by package name.
+//
+// Fields, enum values, and RPCs are in declaration order.
+func NewPackages(ctx context.Context, image bufcore.Image) ([]*Package, error) {
+	files, err := protosource.NewFilesUnstable(ctx, bufcoreutil.NewInputFiles(bufcore.ImageWithoutImports(image).Files())...)
+	if err != nil {
+		return nil, err
+	}
+	return newPackages(files)
+}
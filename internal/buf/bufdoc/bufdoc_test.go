@@ -0,0 +1,75 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoretesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewPackagesWithoutSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+	importFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "b.proto")
+	importFileDescriptorProto.Package = proto.String("b")
+	fileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a.proto", "b.proto")
+	fileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Foo"),
+			Options: &descriptorpb.MessageOptions{
+				Deprecated: proto.Bool(true),
+			},
+		},
+	}
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, importFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, fileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+	packages, err := NewPackages(context.Background(), image)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Package{
+			{
+				Files: []string{"a.proto"},
+				Messages: []*Message{
+					{
+						Name:       "Foo",
+						FullName:   "Foo",
+						File:       "a.proto",
+						Deprecated: true,
+					},
+				},
+			},
+		},
+		packages,
+	)
+}
+
+func TestCleanComments(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "", cleanComments(""))
+	assert.Equal(t, "Foo.", cleanComments(" Foo.\n"))
+	assert.Equal(t, "Foo.\n\n  indented", cleanComments(" Foo.\n\n   indented\n"))
+}
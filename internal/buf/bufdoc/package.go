@@ -0,0 +1,216 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdoc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+)
+
+func newPackages(files []protosource.File) ([]*Package, error) {
+	packageToFiles, err := protosource.PackageToFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	packages := make([]*Package, 0, len(packageToFiles))
+	for pkg, packageFiles := range packageToFiles {
+		docPackage, err := newPackage(pkg, packageFiles)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, docPackage)
+	}
+	sort.Slice(
+		packages,
+		func(i int, j int) bool {
+			return packages[i].Name < packages[j].Name
+		},
+	)
+	return packages, nil
+}
+
+// newPackage expects the files to be sorted by path.
+func newPackage(pkg string, files []protosource.File) (*Package, error) {
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return nil, err
+	}
+	docPackage := &Package{
+		Name:  pkg,
+		Files: make([]string, 0, len(files)),
+	}
+	for _, file := range files {
+		docPackage.Files = append(docPackage.Files, file.Path())
+		if docPackage.LeadingComments == "" && docPackage.TrailingComments == "" {
+			docPackage.Comments = newComments(file.PackageLocation())
+		}
+		if err := protosource.ForEachMessage(
+			func(message protosource.Message) error {
+				if message.IsMapEntry() {
+					return nil
+				}
+				docMessage, err := newMessage(message, fullNameToMessage)
+				if err != nil {
+					return err
+				}
+				docPackage.Messages = append(docPackage.Messages, docMessage)
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := protosource.ForEachEnum(
+			func(enum protosource.Enum) error {
+				docPackage.Enums = append(docPackage.Enums, newEnum(enum))
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		for _, service := range file.Services() {
+			docPackage.Services = append(docPackage.Services, newService(service))
+		}
+	}
+	sort.Slice(
+		docPackage.Messages,
+		func(i int, j int) bool {
+			return docPackage.Messages[i].FullName < docPackage.Messages[j].FullName
+		},
+	)
+	sort.Slice(
+		docPackage.Enums,
+		func(i int, j int) bool {
+			return docPackage.Enums[i].FullName < docPackage.Enums[j].FullName
+		},
+	)
+	sort.Slice(
+		docPackage.Services,
+		func(i int, j int) bool {
+			return docPackage.Services[i].FullName < docPackage.Services[j].FullName
+		},
+	)
+	return docPackage, nil
+}
+
+func newMessage(message protosource.Message, fullNameToMessage map[string]protosource.Message) (*Message, error) {
+	docMessage := &Message{
+		Name:       message.Name(),
+		FullName:   message.FullName(),
+		File:       message.File().Path(),
+		Comments:   newComments(message.Location()),
+		Deprecated: message.Deprecated(),
+	}
+	for _, field := range message.Fields() {
+		oneof, err := protosource.FieldOneof(field)
+		if err != nil {
+			return nil, err
+		}
+		oneofName := ""
+		if oneof != nil {
+			oneofName = oneof.Name()
+		}
+		label := field.Label().String()
+		fieldType := protosource.FieldTypeString(field, fullNameToMessage)
+		if strings.HasPrefix(fieldType, "map<") {
+			// map fields are repeated on the wire, but are not documented as such
+			label = ""
+		}
+		docMessage.Fields = append(
+			docMessage.Fields,
+			&Field{
+				Name:       field.Name(),
+				Number:     field.Number(),
+				Label:      label,
+				Type:       fieldType,
+				JSONName:   field.JSONName(),
+				Oneof:      oneofName,
+				Comments:   newComments(field.Location()),
+				Deprecated: field.Deprecated(),
+			},
+		)
+	}
+	return docMessage, nil
+}
+
+func newEnum(enum protosource.Enum) *Enum {
+	docEnum := &Enum{
+		Name:       enum.Name(),
+		FullName:   enum.FullName(),
+		File:       enum.File().Path(),
+		Comments:   newComments(enum.Location()),
+		Deprecated: enum.Deprecated(),
+	}
+	for _, enumValue := range enum.Values() {
+		docEnum.Values = append(
+			docEnum.Values,
+			&EnumValue{
+				Name:       enumValue.Name(),
+				Number:     enumValue.Number(),
+				Comments:   newComments(enumValue.Location()),
+				Deprecated: enumValue.Deprecated(),
+			},
+		)
+	}
+	return docEnum
+}
+
+func newService(service protosource.Service) *Service {
+	docService := &Service{
+		Name:       service.Name(),
+		FullName:   service.FullName(),
+		File:       service.File().Path(),
+		Comments:   newComments(service.Location()),
+		Deprecated: service.Deprecated(),
+	}
+	for _, method := range service.Methods() {
+		docService.Methods = append(
+			docService.Methods,
+			&Method{
+				Name:            method.Name(),
+				RequestType:     strings.TrimPrefix(method.InputTypeName(), "."),
+				ClientStreaming: method.ClientStreaming(),
+				ResponseType:    strings.TrimPrefix(method.OutputTypeName(), "."),
+				ServerStreaming: method.ServerStreaming(),
+				Comments:        newComments(method.Location()),
+				Deprecated:      method.Deprecated(),
+			},
+		)
+	}
+	return docService
+}
+
+func newComments(location protosource.Location) Comments {
+	if location == nil {
+		return Comments{}
+	}
+	return Comments{
+		LeadingComments:  cleanComments(location.LeadingComments()),
+		TrailingComments: cleanComments(location.TrailingComments()),
+	}
+}
+
+// cleanComments removes the trailing newline and the single leading space on
+// each line that typically follows the comment marker.
+func cleanComments(comments string) string {
+	lines := strings.Split(strings.TrimSuffix(comments, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/bufbuild/buf/internal/pkg/fetch"
 	"github.com/bufbuild/buf/internal/pkg/git"
 	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
 	"go.uber.org/zap"
@@ -33,6 +34,8 @@ const (
 	ImageEncodingBin ImageEncoding = iota + 1
 	// ImageEncodingJSON is the JSON image encoding.
 	ImageEncodingJSON
+	// ImageEncodingText is the protobuf text image encoding.
+	ImageEncodingText
 )
 
 var (
@@ -53,6 +56,27 @@ var (
 // ImageEncoding is the encoding of the image.
 type ImageEncoding int
 
+// FormatInfo describes a format and how it is inferred.
+type FormatInfo struct {
+	// Name is the name of the format, as used with the format option.
+	Name string `json:"name,omitempty"`
+	// Image is true for image formats, and false for source formats.
+	Image bool `json:"image,omitempty"`
+	// Extensions are the file extensions the format is inferred from, sorted.
+	//
+	// This includes compressed extensions such as ".json.zst".
+	Extensions []string `json:"extensions,omitempty"`
+	// Compressions are the values of the compression option the format accepts, sorted.
+	Compressions []string `json:"compressions,omitempty"`
+}
+
+// GetFormatInfos gets the FormatInfos for all formats, sorted by name.
+//
+// This does not include deprecated formats.
+func GetFormatInfos() []*FormatInfo {
+	return getFormatInfos()
+}
+
 // PathResolver resolves external paths to paths.
 type PathResolver interface {
 	// PathForExternalPath takes a path external to the asset and converts it to
@@ -83,16 +107,34 @@ type Ref interface {
 type ImageRef interface {
 	Ref
 	ImageEncoding() ImageEncoding
+	// DetectEncoding returns true if the encoding and compression of the
+	// image should be detected from its content instead of ImageEncoding.
+	//
+	// This is the case for stdin if no format or compression is specified.
+	DetectEncoding() bool
 	IsNull() bool
+	// LocalPath returns the path of the image file if it is on the local
+	// filesystem, and empty otherwise.
+	LocalPath() string
 	fetchFileRef() fetch.FileRef
 }
 
 // SourceRef is a source bucket reference.
 type SourceRef interface {
 	Ref
+	// LocalDirPath returns the path of the source directory if it is a
+	// directory on the local filesystem, and empty otherwise.
+	LocalDirPath() string
 	fetchBucketRef() fetch.BucketRef
 }
 
+// IsTarArchiveSourceRef returns true if the SourceRef is a tar archive, such as
+// a .tar or .tgz file, which can be written with Writer.PutSourceArchiveFile.
+func IsTarArchiveSourceRef(sourceRef SourceRef) bool {
+	archiveRef, ok := sourceRef.fetchBucketRef().(fetch.ArchiveRef)
+	return ok && archiveRef.ArchiveType() == fetch.ArchiveTypeTar
+}
+
 // ImageRefParser is an image ref parser for Buf.
 type ImageRefParser interface {
 	// GetImageRef gets the reference for the image file.
@@ -131,6 +173,10 @@ type Reader interface {
 	// GetImageFile gets the image file.
 	//
 	// The returned file will be uncompressed.
+	//
+	// For grpc:// references, the image is built from the FileDescriptorProtos
+	// returned by the reflection service of the server, and the files in the
+	// packages of the services are the targets.
 	GetImageFile(
 		ctx context.Context,
 		container app.EnvStdinContainer,
@@ -147,20 +193,120 @@ type Reader interface {
 }
 
 // NewReader returns a new Reader.
+//
+// The httpClient must support HTTP/2 to read grpc:// references.
 func NewReader(
 	logger *zap.Logger,
 	httpClient *http.Client,
 	httpAuthenticator httpauth.Authenticator,
 	gitCloner git.Cloner,
+	options ...ReaderOption,
 ) Reader {
 	return newReader(
 		logger,
 		httpClient,
 		httpAuthenticator,
 		gitCloner,
+		options...,
 	)
 }
 
+// ReaderOption is an option for a new Reader.
+type ReaderOption func(*reader)
+
+// ReaderWithInsecureHTTP returns a new ReaderOption that allows reading
+// inputs over plain, non-TLS http.
+//
+// By default, only https is allowed for remote inputs.
+func ReaderWithInsecureHTTP() ReaderOption {
+	return func(reader *reader) {
+		reader.insecureHTTP = true
+	}
+}
+
+// ReaderWithKeepTemp returns a new ReaderOption that keeps the temporary
+// directories that archives are extracted to and git repositories are
+// cloned to, and logs their paths.
+//
+// This is used to debug what was read from remote inputs.
+func ReaderWithKeepTemp() ReaderOption {
+	return func(reader *reader) {
+		reader.keepTemp = true
+	}
+}
+
+// ReaderWithoutCache returns a new ReaderOption that does not read from or
+// write to the cache of files read over http and git clones.
+func ReaderWithoutCache() ReaderOption {
+	return func(reader *reader) {
+		reader.noCache = true
+	}
+}
+
+// ReaderWithOffline returns a new ReaderOption that fails to read any input
+// that requires network access, such as https, oci://, grpc:// and remote git
+// inputs, even if they are cached.
+//
+// This is used in air-gapped environments, along with vendored dependencies.
+func ReaderWithOffline() ReaderOption {
+	return func(reader *reader) {
+		reader.offline = true
+	}
+}
+
+// ReaderWithMmap returns a new ReaderOption that memory-maps local image
+// files that are not compressed instead of reading them into memory.
+//
+// The io.ReadCloser returned from GetImageFile then has a Bytes() []byte
+// method that returns the mapped data, which must not be used after the
+// io.ReadCloser is closed. This reduces the memory used to read large
+// images, as the data is paged in from the file as needed.
+func ReaderWithMmap() ReaderOption {
+	return func(reader *reader) {
+		reader.mmap = true
+	}
+}
+
+// ClearCache removes the cache of files read over http and git clones from
+// the cache directory of the user.
+func ClearCache(envContainer app.EnvContainer) error {
+	return clearCache(envContainer)
+}
+
+// ReaderWithNetworkLimiter returns a new ReaderOption that limits remote
+// fetches with the given Limiter.
+//
+// The Limiter can be shared between Readers to limit fetches across them.
+func ReaderWithNetworkLimiter(networkLimiter netlimit.Limiter) ReaderOption {
+	return func(reader *reader) {
+		reader.networkLimiter = networkLimiter
+	}
+}
+
+// FileOpener opens files for a custom scheme.
+type FileOpener interface {
+	// OpenFile opens the file at the URL, which includes the scheme.
+	//
+	// The file may be compressed, in which case it is decompressed according
+	// to the extension of the URL or the compression option of the input.
+	OpenFile(ctx context.Context, container app.EnvContainer, url string) (io.ReadCloser, error)
+}
+
+// ReaderWithFileOpener returns a new ReaderOption that reads inputs with the
+// given custom scheme, such as "artifact" for artifact://path/to/file.tar,
+// with the given FileOpener.
+//
+// This allows internal artifact stores to be read without changes to this
+// package. The built-in schemes, such as https and s3, cannot be overridden.
+func ReaderWithFileOpener(scheme string, fileOpener FileOpener) ReaderOption {
+	return func(reader *reader) {
+		reader.fetchReaderOptions = append(
+			reader.fetchReaderOptions,
+			fetch.WithReaderFileOpener(scheme, fileOpener),
+		)
+	}
+}
+
 // Writer is a writer for Buf.
 type Writer interface {
 	// PutImageFile puts the image file.
@@ -169,13 +315,53 @@ type Writer interface {
 		container app.EnvStdoutContainer,
 		imageRef ImageRef,
 	) (io.WriteCloser, error)
+	// PutSourceArchiveFile puts the tar archive file for the SourceRef.
+	//
+	// Returns an error if the SourceRef is not a tar archive, see
+	// IsTarArchiveSourceRef.
+	PutSourceArchiveFile(
+		ctx context.Context,
+		container app.EnvStdoutContainer,
+		sourceRef SourceRef,
+	) (io.WriteCloser, error)
+	// PutModule pushes the files of the bucket as a module to the oci://
+	// reference, as an artifact with a single layer that is a gzipped tarball.
+	//
+	// Pushed modules are read by source refs for the oci:// reference, so
+	// modules can be used as deps by reference.
+	// Returns the digest of the manifest of the pushed artifact.
+	PutModule(
+		ctx context.Context,
+		container app.EnvContainer,
+		value string,
+		readBucket storage.ReadBucket,
+	) (string, error)
 }
 
 // NewWriter returns a new Writer.
+//
+// The httpClient is used to push images and modules to oci:// references.
 func NewWriter(
 	logger *zap.Logger,
+	httpClient *http.Client,
+	options ...WriterOption,
 ) Writer {
 	return newWriter(
 		logger,
+		httpClient,
+		options...,
 	)
 }
+
+// WriterOption is an option for a new Writer.
+type WriterOption func(*writer)
+
+// WriterWithInsecureHTTP returns a new WriterOption that allows requesting
+// registry tokens over plain, non-TLS http.
+//
+// By default, credentials are only sent to https token realms.
+func WriterWithInsecureHTTP() WriterOption {
+	return func(writer *writer) {
+		writer.insecureHTTP = true
+	}
+}
@@ -14,6 +14,13 @@
 
 package buffetch
 
+import (
+	"sort"
+
+	"github.com/bufbuild/buf/internal/pkg/fetch"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+)
+
 const (
 	// formatBin is the binary format.
 	formatBin = "bin"
@@ -31,6 +38,8 @@ const (
 	formatTar = "tar"
 	// formatTargz is the tar gzipped format.
 	formatTargz = "targz"
+	// formatTxtpb is the protobuf text format.
+	formatTxtpb = "txtpb"
 	// formatZip is the zip format.
 	formatZip = "zip"
 )
@@ -42,10 +51,12 @@ var (
 		formatBingz,
 		formatJSON,
 		formatJSONGZ,
+		formatTxtpb,
 	}
 	imageFormatsNotDeprecated = []string{
 		formatBin,
 		formatJSON,
+		formatTxtpb,
 	}
 	// sorted
 	sourceFormats = []string{
@@ -72,6 +83,7 @@ var (
 		formatJSONGZ,
 		formatTar,
 		formatTargz,
+		formatTxtpb,
 		formatZip,
 	}
 	// sorted
@@ -81,6 +93,7 @@ var (
 		formatGit,
 		formatJSON,
 		formatTar,
+		formatTxtpb,
 		formatZip,
 	}
 
@@ -89,4 +102,90 @@ var (
 		formatJSONGZ: formatJSON,
 		formatTargz:  formatTar,
 	}
+
+	// extensionToFormatInfo is the authoritative mapping from file extensions to
+	// the format and compression type they imply.
+	//
+	// When adding a new encoding or compression, add its extension here, and
+	// inference for inputs and outputs as well as ls-formats will pick it up.
+	extensionToFormatInfo = map[string]extensionFormatInfo{
+		".bin": {
+			format: formatBin,
+		},
+		".git": {
+			format: formatGit,
+		},
+		".json": {
+			format: formatJSON,
+		},
+		".tar": {
+			format: formatTar,
+		},
+		".tgz": {
+			format:          formatTar,
+			compressionType: fetch.CompressionTypeGzip,
+		},
+		".txtpb": {
+			format: formatTxtpb,
+		},
+		".zip": {
+			format: formatZip,
+		},
+	}
+	// compressionExtensionToCompressionType is the mapping from extensions that
+	// can be appended to the extension of a compressible format, such as
+	// ".json.zst", to the compression type they imply.
+	compressionExtensionToCompressionType = map[string]fetch.CompressionType{
+		".gz":  fetch.CompressionTypeGzip,
+		".zst": fetch.CompressionTypeZstd,
+	}
+	// compressibleFormats are the formats that support compression.
+	compressibleFormats = map[string]struct{}{
+		formatBin:   {},
+		formatJSON:  {},
+		formatTar:   {},
+		formatTxtpb: {},
+	}
+	compressionTypeToString = map[fetch.CompressionType]string{
+		fetch.CompressionTypeGzip: "gzip",
+		fetch.CompressionTypeZstd: "zstd",
+	}
 )
+
+type extensionFormatInfo struct {
+	format          string
+	compressionType fetch.CompressionType
+}
+
+func getFormatInfos() []*FormatInfo {
+	imageFormatsMap := stringutil.SliceToMap(imageFormats)
+	formatInfos := make([]*FormatInfo, 0, len(allFormatsNotDeprecated))
+	for _, format := range allFormatsNotDeprecated {
+		_, image := imageFormatsMap[format]
+		formatInfo := &FormatInfo{
+			Name:  format,
+			Image: image,
+		}
+		_, compressible := compressibleFormats[format]
+		for extension, info := range extensionToFormatInfo {
+			if info.format != format {
+				continue
+			}
+			formatInfo.Extensions = append(formatInfo.Extensions, extension)
+			if compressible && info.compressionType == 0 {
+				for compressionExtension := range compressionExtensionToCompressionType {
+					formatInfo.Extensions = append(formatInfo.Extensions, extension+compressionExtension)
+				}
+			}
+		}
+		if compressible {
+			for _, compressionType := range compressionExtensionToCompressionType {
+				formatInfo.Compressions = append(formatInfo.Compressions, compressionTypeToString[compressionType])
+			}
+		}
+		sort.Strings(formatInfo.Extensions)
+		sort.Strings(formatInfo.Compressions)
+		formatInfos = append(formatInfos, formatInfo)
+	}
+	return formatInfos
+}
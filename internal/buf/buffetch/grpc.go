@@ -0,0 +1,75 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffetch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/grpcreflect"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+)
+
+const grpcPrefix = "grpc://"
+
+// getGRPCImageFile builds an image from the reflection service of the gRPC
+// server at the address.
+//
+// The files in the packages of the services are the targets, all other
+// files are imports.
+func getGRPCImageFile(
+	ctx context.Context,
+	grpcreflectClient grpcreflect.Client,
+	address string,
+	imageEncoding ImageEncoding,
+) (io.ReadCloser, error) {
+	if imageEncoding != ImageEncodingBin {
+		return nil, fmt.Errorf("%s references must use format %s", grpcPrefix, formatBin)
+	}
+	fileDescriptorProtos, serviceFilePaths, err := grpcreflectClient.GetFileDescriptorProtos(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("could not get files from %s%s: %v", grpcPrefix, address, err)
+	}
+	servicePathMap := stringutil.SliceToMap(serviceFilePaths)
+	targetPackages := make(map[string]struct{})
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if _, ok := servicePathMap[fileDescriptorProto.GetName()]; ok {
+			targetPackages[fileDescriptorProto.GetPackage()] = struct{}{}
+		}
+	}
+	imageFiles := make([]bufcore.ImageFile, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		_, isTarget := targetPackages[fileDescriptorProto.GetPackage()]
+		imageFile, err := bufcore.NewImageFile(fileDescriptorProto, "", !isTarget)
+		if err != nil {
+			return nil, err
+		}
+		imageFiles[i] = imageFile
+	}
+	image, err := bufcore.NewImage(imageFiles)
+	if err != nil {
+		return nil, err
+	}
+	data, err := protoencoding.NewWireMarshaler().Marshal(bufcore.ImageToProtoImage(image))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -22,17 +22,20 @@ import (
 var _ ImageRef = &imageRef{}
 
 type imageRef struct {
-	fileRef       fetch.FileRef
-	imageEncoding ImageEncoding
+	fileRef        fetch.FileRef
+	imageEncoding  ImageEncoding
+	detectEncoding bool
 }
 
 func newImageRef(
 	fileRef fetch.FileRef,
 	imageEncoding ImageEncoding,
+	detectEncoding bool,
 ) *imageRef {
 	return &imageRef{
-		fileRef:       fileRef,
-		imageEncoding: imageEncoding,
+		fileRef:        fileRef,
+		imageEncoding:  imageEncoding,
+		detectEncoding: detectEncoding,
 	}
 }
 
@@ -44,10 +47,21 @@ func (r *imageRef) ImageEncoding() ImageEncoding {
 	return r.imageEncoding
 }
 
+func (r *imageRef) DetectEncoding() bool {
+	return r.detectEncoding
+}
+
 func (r *imageRef) IsNull() bool {
 	return r.fileRef.FileScheme() == fetch.FileSchemeNull
 }
 
+func (r *imageRef) LocalPath() string {
+	if r.fileRef.FileScheme() == fetch.FileSchemeLocal {
+		return r.fileRef.Path()
+	}
+	return ""
+}
+
 func (r *imageRef) fetchRef() fetch.Ref {
 	return r.fileRef
 }
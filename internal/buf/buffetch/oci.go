@@ -0,0 +1,57 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffetch
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/internal/pkg/oci"
+	"go.uber.org/zap"
+)
+
+const (
+	ociPrefix = "oci://"
+
+	// the media types of images pushed to OCI registries
+	ociConfigMediaType = "application/vnd.buf.image.config.v1+json"
+	ociLayerMediaType  = "application/vnd.buf.image.v1"
+	// the media types of modules pushed to OCI registries
+	ociModuleConfigMediaType = "application/vnd.buf.module.config.v1+json"
+	ociModuleLayerMediaType  = "application/vnd.buf.module.v1.tar+gzip"
+)
+
+func newOCIClient(logger *zap.Logger, httpClient *http.Client, insecureHTTP bool) oci.Client {
+	return oci.NewClient(
+		logger,
+		httpClient,
+		oci.ClientOptions{
+			ConfigMediaType:   ociConfigMediaType,
+			LayerMediaType:    ociLayerMediaType,
+			AllowInsecureHTTP: insecureHTTP,
+		},
+	)
+}
+
+func newOCIModuleClient(logger *zap.Logger, httpClient *http.Client, insecureHTTP bool) oci.Client {
+	return oci.NewClient(
+		logger,
+		httpClient,
+		oci.ClientOptions{
+			ConfigMediaType:   ociModuleConfigMediaType,
+			LayerMediaType:    ociModuleLayerMediaType,
+			AllowInsecureHTTP: insecureHTTP,
+		},
+	)
+}
@@ -16,19 +16,43 @@ package buffetch
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/fetch"
 	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/grpcreflect"
 	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
 
+// httpCacheDirName is the directory within the user cache directory that
+// files read over http are cached in.
+var httpCacheDirName = filepath.Join("buf", "http")
+
+// gitCacheDirName is the directory within the user cache directory that
+// git clones are cached in.
+var gitCacheDirName = filepath.Join("buf", "git")
+
 type reader struct {
-	fetchReader fetch.Reader
+	fetchReader       fetch.Reader
+	grpcreflectClient grpcreflect.Client
+
+	insecureHTTP bool
+	keepTemp     bool
+	noCache      bool
+	offline      bool
+	mmap         bool
+	// may be nil
+	networkLimiter netlimit.Limiter
+	// additional options for the fetch.Reader
+	fetchReaderOptions []fetch.ReaderOption
 }
 
 func newReader(
@@ -36,21 +60,69 @@ func newReader(
 	httpClient *http.Client,
 	httpAuthenticator httpauth.Authenticator,
 	gitCloner git.Cloner,
+	options ...ReaderOption,
 ) *reader {
-	return &reader{
-		fetchReader: fetch.NewReader(
-			logger,
-			fetch.WithReaderHTTP(
-				httpClient,
-				httpAuthenticator,
-			),
-			fetch.WithReaderGit(
-				gitCloner,
-			),
-			fetch.WithReaderLocal(),
-			fetch.WithReaderStdio(),
+	reader := &reader{
+		grpcreflectClient: grpcreflect.NewClient(httpClient),
+	}
+	for _, option := range options {
+		option(reader)
+	}
+	fetchReaderOptions := []fetch.ReaderOption{
+		fetch.WithReaderHTTP(
+			httpClient,
+			httpAuthenticator,
 		),
+		fetch.WithReaderS3(
+			httpauth.NewAWSAuthenticator(httpClient),
+		),
+		fetch.WithReaderGCS(
+			httpauth.NewGoogleAuthenticator(httpClient),
+		),
+		fetch.WithReaderOCI(
+			newOCIClient(logger, httpClient, reader.insecureHTTP),
+		),
+		fetch.WithReaderGit(
+			gitCloner,
+		),
+		fetch.WithReaderLocal(),
+		fetch.WithReaderStdio(),
+	}
+	if !reader.noCache {
+		fetchReaderOptions = append(
+			fetchReaderOptions,
+			fetch.WithReaderHTTPCache(httpCacheDirName),
+			fetch.WithReaderGitCache(gitCacheDirName),
+		)
 	}
+	if reader.insecureHTTP {
+		fetchReaderOptions = append(fetchReaderOptions, fetch.WithReaderInsecureHTTP())
+	}
+	if reader.keepTemp {
+		fetchReaderOptions = append(fetchReaderOptions, fetch.WithReaderKeepTemp())
+	}
+	if reader.offline {
+		fetchReaderOptions = append(fetchReaderOptions, fetch.WithReaderOffline())
+	}
+	if reader.networkLimiter != nil {
+		fetchReaderOptions = append(fetchReaderOptions, fetch.WithReaderNetworkLimiter(reader.networkLimiter))
+	}
+	fetchReaderOptions = append(fetchReaderOptions, reader.fetchReaderOptions...)
+	reader.fetchReader = fetch.NewReader(logger, fetchReaderOptions...)
+	return reader
+}
+
+func clearCache(envContainer app.EnvContainer) error {
+	cacheDirPath, err := app.CacheDirPath(envContainer)
+	if err != nil {
+		return err
+	}
+	for _, dirName := range []string{httpCacheDirName, gitCacheDirName} {
+		if err := os.RemoveAll(filepath.Join(cacheDirPath, dirName)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (a *reader) GetImageFile(
@@ -58,7 +130,25 @@ func (a *reader) GetImageFile(
 	container app.EnvStdinContainer,
 	imageRef ImageRef,
 ) (io.ReadCloser, error) {
-	return a.fetchReader.GetFile(ctx, container, imageRef.fetchFileRef())
+	fileRef := imageRef.fetchFileRef()
+	if fileRef.FileScheme() == fetch.FileSchemeGRPC {
+		if a.offline {
+			return nil, fmt.Errorf("cannot read %s%s, network access is disabled in offline mode", grpcPrefix, fileRef.Path())
+		}
+		if a.networkLimiter != nil {
+			release, err := a.networkLimiter.Acquire(ctx, fileRef.Path())
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		return getGRPCImageFile(ctx, a.grpcreflectClient, fileRef.Path(), imageRef.ImageEncoding())
+	}
+	var getFileOptions []fetch.GetFileOption
+	if a.mmap {
+		getFileOptions = append(getFileOptions, fetch.WithGetFileMmap())
+	}
+	return a.fetchReader.GetFile(ctx, container, fileRef, getFileOptions...)
 }
 
 func (a *reader) GetSourceBucket(
@@ -23,64 +23,75 @@ import (
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/fetch"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 	"go.uber.org/zap"
 )
 
 type refParser struct {
 	logger         *zap.Logger
 	fetchRefParser fetch.RefParser
+	// sourceFetchRefParser is used for source refs, for which oci://
+	// references are modules rather than images
+	sourceFetchRefParser fetch.RefParser
 }
 
 func newRefParser(logger *zap.Logger) *refParser {
-	return newRefParserInternal(logger, processRawRef)
+	return &refParser{
+		logger:               logger.Named("buffetch"),
+		fetchRefParser:       newFetchRefParser(logger, processRawRef),
+		sourceFetchRefParser: newFetchRefParser(logger, processRawRefSource),
+	}
 }
 
 func newImageRefParser(logger *zap.Logger) *refParser {
-	return newRefParserInternal(logger, processRawRefImage)
+	fetchRefParser := newFetchRefParser(logger, processRawRefImage)
+	return &refParser{
+		logger:               logger.Named("buffetch"),
+		fetchRefParser:       fetchRefParser,
+		sourceFetchRefParser: fetchRefParser,
+	}
 }
 
-func newRefParserInternal(
+func newFetchRefParser(
 	logger *zap.Logger,
 	rawRefProcessor func(*fetch.RawRef) error,
-) *refParser {
-	return &refParser{
-		logger: logger.Named("buffetch"),
-		fetchRefParser: fetch.NewRefParser(
-			logger,
-			fetch.WithRawRefProcessor(rawRefProcessor),
-			fetch.WithSingleFormat(formatBin),
-			fetch.WithSingleFormat(formatJSON),
-			fetch.WithSingleFormat(
-				formatBingz,
-				fetch.WithSingleDefaultCompressionType(
-					fetch.CompressionTypeGzip,
-				),
+) fetch.RefParser {
+	return fetch.NewRefParser(
+		logger,
+		fetch.WithRawRefProcessor(rawRefProcessor),
+		fetch.WithSingleFormat(formatBin),
+		fetch.WithSingleFormat(formatJSON),
+		fetch.WithSingleFormat(formatTxtpb),
+		fetch.WithSingleFormat(
+			formatBingz,
+			fetch.WithSingleDefaultCompressionType(
+				fetch.CompressionTypeGzip,
 			),
-			fetch.WithSingleFormat(
-				formatJSONGZ,
-				fetch.WithSingleDefaultCompressionType(
-					fetch.CompressionTypeGzip,
-				),
-			),
-			fetch.WithArchiveFormat(
-				formatTar,
-				fetch.ArchiveTypeTar,
-			),
-			fetch.WithArchiveFormat(
-				formatTargz,
-				fetch.ArchiveTypeTar,
-				fetch.WithArchiveDefaultCompressionType(
-					fetch.CompressionTypeGzip,
-				),
+		),
+		fetch.WithSingleFormat(
+			formatJSONGZ,
+			fetch.WithSingleDefaultCompressionType(
+				fetch.CompressionTypeGzip,
 			),
-			fetch.WithArchiveFormat(
-				formatZip,
-				fetch.ArchiveTypeZip,
+		),
+		fetch.WithArchiveFormat(
+			formatTar,
+			fetch.ArchiveTypeTar,
+		),
+		fetch.WithArchiveFormat(
+			formatTargz,
+			fetch.ArchiveTypeTar,
+			fetch.WithArchiveDefaultCompressionType(
+				fetch.CompressionTypeGzip,
 			),
-			fetch.WithGitFormat(formatGit),
-			fetch.WithDirFormat(formatDir),
 		),
-	}
+		fetch.WithArchiveFormat(
+			formatZip,
+			fetch.ArchiveTypeZip,
+		),
+		fetch.WithGitFormat(formatGit),
+		fetch.WithDirFormat(formatDir),
+	)
 }
 
 func (a *refParser) GetRef(
@@ -88,7 +99,7 @@ func (a *refParser) GetRef(
 	value string,
 ) (Ref, error) {
 	defer instrument.Start(a.logger, "get_ref").End()
-	parsedRef, err := a.getParsedRef(ctx, value, allFormats)
+	parsedRef, err := a.getParsedRef(ctx, a.fetchRefParser, value, allFormats)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +109,7 @@ func (a *refParser) GetRef(
 		if err != nil {
 			return nil, err
 		}
-		return newImageRef(t, imageEncoding), nil
+		return newImageRef(t, imageEncoding, getDetectEncoding(value, t)), nil
 	case fetch.ParsedArchiveRef:
 		return newSourceRef(t), nil
 	case fetch.ParsedDirRef:
@@ -115,7 +126,7 @@ func (a *refParser) GetImageRef(
 	value string,
 ) (ImageRef, error) {
 	defer instrument.Start(a.logger, "get_image_ref").End()
-	parsedRef, err := a.getParsedRef(ctx, value, imageFormats)
+	parsedRef, err := a.getParsedRef(ctx, a.fetchRefParser, value, imageFormats)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +139,7 @@ func (a *refParser) GetImageRef(
 	if err != nil {
 		return nil, err
 	}
-	return newImageRef(parsedSingleRef, imageEncoding), nil
+	return newImageRef(parsedSingleRef, imageEncoding, getDetectEncoding(value, parsedSingleRef)), nil
 }
 
 func (a *refParser) GetSourceRef(
@@ -136,7 +147,7 @@ func (a *refParser) GetSourceRef(
 	value string,
 ) (SourceRef, error) {
 	defer instrument.Start(a.logger, "get_source_ref").End()
-	parsedRef, err := a.getParsedRef(ctx, value, sourceFormats)
+	parsedRef, err := a.getParsedRef(ctx, a.sourceFetchRefParser, value, sourceFormats)
 	if err != nil {
 		return nil, err
 	}
@@ -150,10 +161,11 @@ func (a *refParser) GetSourceRef(
 
 func (a *refParser) getParsedRef(
 	ctx context.Context,
+	fetchRefParser fetch.RefParser,
 	value string,
 	allowedFormats []string,
 ) (fetch.ParsedRef, error) {
-	parsedRef, err := a.fetchRefParser.GetParsedRef(
+	parsedRef, err := fetchRefParser.GetParsedRef(
 		ctx,
 		value,
 		fetch.WithAllowedFormats(allowedFormats...),
@@ -177,52 +189,61 @@ func (a *refParser) checkDeprecated(parsedRef fetch.ParsedRef) {
 }
 
 func processRawRef(rawRef *fetch.RawRef) error {
+	return processRawRefForFormats(rawRef, allFormats, formatDir)
+}
+
+func processRawRefImage(rawRef *fetch.RawRef) error {
+	return processRawRefForFormats(rawRef, imageFormats, formatBin)
+}
+
+// processRawRefSource is processRawRef, except that oci:// references are
+// modules, which are pushed as gzipped tarballs, see PutModule.
+func processRawRefSource(rawRef *fetch.RawRef) error {
+	if strings.HasPrefix(rawRef.Path, ociPrefix) {
+		rawRef.Format = formatTar
+		rawRef.CompressionType = fetch.CompressionTypeGzip
+		return nil
+	}
+	return processRawRef(rawRef)
+}
+
+// processRawRefForFormats infers the format and compression type from the
+// extension of the path using extensionToFormatInfo.
+//
+// Extensions that map to formats not within allowedFormats are ignored and
+// defaultFormat is used, except for compressed extensions, which are an error.
+func processRawRefForFormats(rawRef *fetch.RawRef, allowedFormats []string, defaultFormat string) error {
 	// if format option is not set and path is "-", default to bin
-	var format string
-	var compressionType fetch.CompressionType
+	//
+	// when reading, the actual encoding and compression of stdin are then
+	// detected from its content, see getDetectEncoding
 	if rawRef.Path == "-" || app.IsDevNull(rawRef.Path) || app.IsDevStdin(rawRef.Path) || app.IsDevStdout(rawRef.Path) {
-		format = formatBin
-	} else {
-		switch filepath.Ext(rawRef.Path) {
-		case ".bin":
-			format = formatBin
-		case ".json":
-			format = formatJSON
-		case ".tar":
-			format = formatTar
-		case ".zip":
-			format = formatZip
-		case ".gz":
-			compressionType = fetch.CompressionTypeGzip
-			switch filepath.Ext(strings.TrimSuffix(rawRef.Path, filepath.Ext(rawRef.Path))) {
-			case ".bin":
-				format = formatBin
-			case ".json":
-				format = formatJSON
-			case ".tar":
-				format = formatTar
-			default:
-				return fmt.Errorf("path %q had .gz extension with unknown format", rawRef.Path)
-			}
-		case ".zst":
-			compressionType = fetch.CompressionTypeZstd
-			switch filepath.Ext(strings.TrimSuffix(rawRef.Path, filepath.Ext(rawRef.Path))) {
-			case ".bin":
-				format = formatBin
-			case ".json":
-				format = formatJSON
-			case ".tar":
-				format = formatTar
-			default:
-				return fmt.Errorf("path %q had .zst extension with unknown format", rawRef.Path)
-			}
-		case ".tgz":
-			format = formatTar
-			compressionType = fetch.CompressionTypeGzip
-		case ".git":
-			format = formatGit
-		default:
-			format = formatDir
+		rawRef.Format = formatBin
+		return nil
+	}
+	// references to OCI registries and gRPC servers are always images, and
+	// tags and host names may look like extensions
+	if strings.HasPrefix(rawRef.Path, ociPrefix) || strings.HasPrefix(rawRef.Path, grpcPrefix) {
+		rawRef.Format = formatBin
+		return nil
+	}
+	allowedFormatsMap := stringutil.SliceToMap(allowedFormats)
+	format := defaultFormat
+	var compressionType fetch.CompressionType
+	ext := filepath.Ext(rawRef.Path)
+	if extCompressionType, ok := compressionExtensionToCompressionType[ext]; ok {
+		info, ok := extensionToFormatInfo[filepath.Ext(strings.TrimSuffix(rawRef.Path, ext))]
+		_, compressible := compressibleFormats[info.format]
+		_, allowed := allowedFormatsMap[info.format]
+		if !ok || !compressible || !allowed || info.compressionType != 0 {
+			return fmt.Errorf("path %q had %s extension with unknown format", rawRef.Path, ext)
+		}
+		format = info.format
+		compressionType = extCompressionType
+	} else if info, ok := extensionToFormatInfo[ext]; ok {
+		if _, allowed := allowedFormatsMap[info.format]; allowed {
+			format = info.format
+			compressionType = info.compressionType
 		}
 	}
 	rawRef.Format = format
@@ -230,45 +251,26 @@ func processRawRef(rawRef *fetch.RawRef) error {
 	return nil
 }
 
-func processRawRefImage(rawRef *fetch.RawRef) error {
-	// if format option is not set and path is "-", default to bin
-	var format string
-	var compressionType fetch.CompressionType
-	if rawRef.Path == "-" || app.IsDevNull(rawRef.Path) || app.IsDevStdin(rawRef.Path) || app.IsDevStdout(rawRef.Path) {
-		format = formatBin
-	} else {
-		switch filepath.Ext(rawRef.Path) {
-		case ".bin":
-			format = formatBin
-		case ".json":
-			format = formatJSON
-		case ".gz":
-			compressionType = fetch.CompressionTypeGzip
-			switch filepath.Ext(strings.TrimSuffix(rawRef.Path, filepath.Ext(rawRef.Path))) {
-			case ".bin":
-				format = formatBin
-			case ".json":
-				format = formatJSON
-			default:
-				return fmt.Errorf("path %q had .gz extension with unknown format", rawRef.Path)
-			}
-		case ".zst":
-			compressionType = fetch.CompressionTypeZstd
-			switch filepath.Ext(strings.TrimSuffix(rawRef.Path, filepath.Ext(rawRef.Path))) {
-			case ".bin":
-				format = formatBin
-			case ".json":
-				format = formatJSON
-			default:
-				return fmt.Errorf("path %q had .zst extension with unknown format", rawRef.Path)
-			}
-		default:
-			format = formatBin
+// getDetectEncoding returns true if the ref is for stdin and the value does
+// not specify the format or compression, as there is no extension to infer
+// them from.
+func getDetectEncoding(value string, parsedSingleRef fetch.ParsedSingleRef) bool {
+	switch parsedSingleRef.FileScheme() {
+	case fetch.FileSchemeStdio, fetch.FileSchemeStdin:
+	default:
+		return false
+	}
+	index := strings.IndexByte(value, '#')
+	if index < 0 {
+		return true
+	}
+	for _, option := range strings.Split(value[index+1:], ",") {
+		key := strings.TrimSpace(strings.SplitN(option, "=", 2)[0])
+		if key == "format" || key == "compression" {
+			return false
 		}
 	}
-	rawRef.Format = format
-	rawRef.CompressionType = compressionType
-	return nil
+	return true
 }
 
 func parseImageEncoding(format string) (ImageEncoding, error) {
@@ -277,6 +279,8 @@ func parseImageEncoding(format string) (ImageEncoding, error) {
 		return ImageEncodingBin, nil
 	case formatJSON, formatJSONGZ:
 		return ImageEncodingJSON, nil
+	case formatTxtpb:
+		return ImageEncodingText, nil
 	default:
 		return 0, fmt.Errorf("invalid format for image: %q", format)
 	}
@@ -15,8 +15,6 @@
 package buffetch
 
 import (
-	"path/filepath"
-
 	"github.com/bufbuild/buf/internal/pkg/fetch"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
 )
@@ -43,22 +41,25 @@ func (r *sourceRef) PathForExternalPath(externalPath string) (string, error) {
 	if r.dirPath == "" {
 		return normalpath.NormalizeAndValidate(externalPath)
 	}
-	absDirPath, err := filepath.Abs(normalpath.Unnormalize(r.dirPath))
+	absDirPath, err := normalpath.NormalizeExternalAndAbsolute(r.dirPath)
 	if err != nil {
 		return "", err
 	}
-	// we don't actually need to unnormalize externalPath but we do anyways
-	absExternalPath, err := filepath.Abs(normalpath.Unnormalize(externalPath))
+	absExternalPath, err := normalpath.NormalizeExternalAndAbsolute(externalPath)
 	if err != nil {
 		return "", err
 	}
-	path, err := filepath.Rel(absDirPath, absExternalPath)
+	path, err := normalpath.RelExternal(absDirPath, absExternalPath)
 	if err != nil {
 		return "", err
 	}
 	return normalpath.NormalizeAndValidate(path)
 }
 
+func (r *sourceRef) LocalDirPath() string {
+	return r.dirPath
+}
+
 func (r *sourceRef) fetchRef() fetch.Ref {
 	return r.bucketRef
 }
@@ -15,28 +15,48 @@
 package buffetch
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/fetch"
+	"github.com/bufbuild/buf/internal/pkg/oci"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagearchive"
 	"go.uber.org/zap"
 )
 
 type writer struct {
-	fetchWriter fetch.Writer
+	fetchWriter     fetch.Writer
+	ociModuleClient oci.Client
+
+	insecureHTTP bool
 }
 
 func newWriter(
 	logger *zap.Logger,
+	httpClient *http.Client,
+	options ...WriterOption,
 ) *writer {
-	return &writer{
-		fetchWriter: fetch.NewWriter(
-			logger,
-			fetch.WithWriterLocal(),
-			fetch.WithWriterStdio(),
-		),
+	writer := &writer{}
+	for _, option := range options {
+		option(writer)
 	}
+	writer.fetchWriter = fetch.NewWriter(
+		logger,
+		fetch.WithWriterLocal(),
+		fetch.WithWriterStdio(),
+		fetch.WithWriterOCI(
+			newOCIClient(logger, httpClient, writer.insecureHTTP),
+		),
+	)
+	writer.ociModuleClient = newOCIModuleClient(logger, httpClient, writer.insecureHTTP)
+	return writer
 }
 
 func (w *writer) PutImageFile(
@@ -46,3 +66,38 @@ func (w *writer) PutImageFile(
 ) (io.WriteCloser, error) {
 	return w.fetchWriter.PutFile(ctx, container, imageRef.fetchFileRef())
 }
+
+func (w *writer) PutSourceArchiveFile(
+	ctx context.Context,
+	container app.EnvStdoutContainer,
+	sourceRef SourceRef,
+) (io.WriteCloser, error) {
+	if !IsTarArchiveSourceRef(sourceRef) {
+		return nil, fmt.Errorf("%q is not a tar archive", sourceRef.fetchRef().Path())
+	}
+	return w.fetchWriter.PutFile(ctx, container, sourceRef.fetchBucketRef().(fetch.ArchiveRef))
+}
+
+func (w *writer) PutModule(
+	ctx context.Context,
+	container app.EnvContainer,
+	value string,
+	readBucket storage.ReadBucket,
+) (string, error) {
+	if !strings.HasPrefix(value, ociPrefix) {
+		return "", fmt.Errorf("%q must be an %s reference such as %shost/repository:tag", value, ociPrefix, ociPrefix)
+	}
+	reference, err := oci.ParseReference(strings.TrimPrefix(value, ociPrefix))
+	if err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buffer)
+	if err := storagearchive.Tar(ctx, readBucket, gzipWriter); err != nil {
+		return "", err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", err
+	}
+	return w.ociModuleClient.Push(ctx, container, reference, buffer.Bytes())
+}
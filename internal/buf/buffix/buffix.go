@@ -0,0 +1,231 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buffix applies the edits of FileAnnotations to the files they annotate.
+package buffix
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+)
+
+// tabWidth is the width of a tab stop when counting columns, as done by the
+// Protobuf parser.
+const tabWidth = 8
+
+// File is a file that FileAnnotations were fixed in.
+type File struct {
+	// ExternalPath is the external path of the file.
+	ExternalPath string
+	// Data is the data of the file before the fixes.
+	Data []byte
+	// FixedData is the data of the file after the fixes.
+	FixedData []byte
+}
+
+// Fix applies the edits of the FileAnnotations to the files at their external
+// paths, and returns the fixed files.
+//
+// The files are only read, the fixed data is not written. FileAnnotations
+// without edits, or with edits that overlap the edits of a preceding
+// FileAnnotation of the same file, are not fixed, and are returned in their
+// original order.
+func Fix(fileAnnotations []bufanalysis.FileAnnotation) ([]*File, []bufanalysis.FileAnnotation, error) {
+	var unfixedFileAnnotations []bufanalysis.FileAnnotation
+	var externalPaths []string
+	externalPathToFileAnnotations := make(map[string][]bufanalysis.FileAnnotation)
+	for _, fileAnnotation := range fileAnnotations {
+		if len(fileAnnotation.Edits()) == 0 || fileAnnotation.FileInfo() == nil {
+			continue
+		}
+		externalPath := fileAnnotation.FileInfo().ExternalPath()
+		if _, ok := externalPathToFileAnnotations[externalPath]; !ok {
+			externalPaths = append(externalPaths, externalPath)
+		}
+		externalPathToFileAnnotations[externalPath] = append(externalPathToFileAnnotations[externalPath], fileAnnotation)
+	}
+	fixedFileAnnotations := make(map[bufanalysis.FileAnnotation]struct{})
+	files := make([]*File, 0, len(externalPaths))
+	for _, externalPath := range externalPaths {
+		data, err := ioutil.ReadFile(externalPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		fixedData, fileFixedFileAnnotations, err := fixFile(data, externalPathToFileAnnotations[externalPath])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", externalPath, err)
+		}
+		for _, fileAnnotation := range fileFixedFileAnnotations {
+			fixedFileAnnotations[fileAnnotation] = struct{}{}
+		}
+		files = append(
+			files,
+			&File{
+				ExternalPath: externalPath,
+				Data:         data,
+				FixedData:    fixedData,
+			},
+		)
+	}
+	for _, fileAnnotation := range fileAnnotations {
+		if _, ok := fixedFileAnnotations[fileAnnotation]; !ok {
+			unfixedFileAnnotations = append(unfixedFileAnnotations, fileAnnotation)
+		}
+	}
+	return files, unfixedFileAnnotations, nil
+}
+
+// fixFile applies the edits of the FileAnnotations to the data, and returns the
+// fixed data and the FileAnnotations that were fixed.
+func fixFile(data []byte, fileAnnotations []bufanalysis.FileAnnotation) ([]byte, []bufanalysis.FileAnnotation, error) {
+	lineOffsets := getLineOffsets(data)
+	var replacements []*replacement
+	var fixedFileAnnotations []bufanalysis.FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		fileAnnotationReplacements := make([]*replacement, 0, len(fileAnnotation.Edits()))
+		for _, edit := range fileAnnotation.Edits() {
+			replacement, err := newReplacement(data, lineOffsets, edit)
+			if err != nil {
+				return nil, nil, err
+			}
+			fileAnnotationReplacements = append(fileAnnotationReplacements, replacement)
+		}
+		if replacementsOverlap(fileAnnotationReplacements, fileAnnotationReplacements) ||
+			replacementsOverlap(fileAnnotationReplacements, replacements) {
+			continue
+		}
+		replacements = append(replacements, fileAnnotationReplacements...)
+		fixedFileAnnotations = append(fixedFileAnnotations, fileAnnotation)
+	}
+	sort.Slice(
+		replacements,
+		func(i int, j int) bool {
+			return replacements[i].start < replacements[j].start
+		},
+	)
+	fixedData := make([]byte, 0, len(data))
+	offset := 0
+	for i, replacement := range replacements {
+		if i > 0 && replacement.equal(replacements[i-1]) {
+			// the same edit from multiple FileAnnotations
+			continue
+		}
+		fixedData = append(fixedData, data[offset:replacement.start]...)
+		fixedData = append(fixedData, replacement.newText...)
+		offset = replacement.end
+	}
+	fixedData = append(fixedData, data[offset:]...)
+	return fixedData, fixedFileAnnotations, nil
+}
+
+// replacement replaces the bytes from start to end, exclusive, with newText.
+type replacement struct {
+	start   int
+	end     int
+	newText string
+}
+
+func newReplacement(data []byte, lineOffsets []int, edit bufanalysis.Edit) (*replacement, error) {
+	start, err := getOffset(data, lineOffsets, edit.StartLine, edit.StartColumn)
+	if err != nil {
+		return nil, err
+	}
+	end, err := getOffset(data, lineOffsets, edit.EndLine, edit.EndColumn)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("edit ends at %d:%d before it starts at %d:%d", edit.EndLine, edit.EndColumn, edit.StartLine, edit.StartColumn)
+	}
+	return &replacement{
+		start:   start,
+		end:     end,
+		newText: edit.NewText,
+	}, nil
+}
+
+func (r *replacement) equal(other *replacement) bool {
+	return r.start == other.start && r.end == other.end && r.newText == other.newText
+}
+
+// overlaps returns true if the replacements change any of the same bytes, or
+// insert at the same offset.
+//
+// Equal replacements do not overlap, as applying one applies the other.
+func (r *replacement) overlaps(other *replacement) bool {
+	if r == other || r.equal(other) {
+		return false
+	}
+	if r.start == other.start {
+		return true
+	}
+	return r.start < other.end && other.start < r.end
+}
+
+func replacementsOverlap(one []*replacement, two []*replacement) bool {
+	for _, a := range one {
+		for _, b := range two {
+			if a.overlaps(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getLineOffsets returns the offset of the start of each line.
+func getLineOffsets(data []byte) []int {
+	lineOffsets := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+	return lineOffsets
+}
+
+// getOffset returns the offset of the 1-indexed line and column.
+//
+// Columns are counted as done by the Protobuf parser, where each character
+// is one column, except for tabs, which advance to the next tab stop, and
+// carriage returns, which do not advance.
+func getOffset(data []byte, lineOffsets []int, line int, column int) (int, error) {
+	if line < 1 || line > len(lineOffsets) || column < 1 {
+		return 0, fmt.Errorf("invalid edit position %d:%d", line, column)
+	}
+	offset := lineOffsets[line-1]
+	currentColumn := 1
+	for currentColumn < column {
+		if offset >= len(data) || data[offset] == '\n' {
+			return 0, fmt.Errorf("invalid edit position %d:%d", line, column)
+		}
+		r, size := utf8.DecodeRune(data[offset:])
+		switch r {
+		case '\t':
+			currentColumn += tabWidth - (currentColumn-1)%tabWidth
+		case '\r':
+		default:
+			currentColumn++
+		}
+		offset += size
+	}
+	if currentColumn != column {
+		return 0, fmt.Errorf("invalid edit position %d:%d", line, column)
+	}
+	return offset, nil
+}
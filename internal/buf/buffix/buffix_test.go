@@ -0,0 +1,133 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buffix
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFix(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	externalPath := filepath.Join(dirPath, "a.proto")
+	data := "enum Foo {\n\tnone = 0;\n}\n\nmessage Bar {\n  string fooBar = 1;\n}\n"
+	require.NoError(t, ioutil.WriteFile(externalPath, []byte(data), 0600))
+	fileInfo := newFileInfo(externalPath)
+	prefix := newFileAnnotation(fileInfo, 2, 9, 2, 13, "ENUM_VALUE_PREFIX", "FOO_none")
+	upperSnakeCase := newFileAnnotation(fileInfo, 2, 9, 2, 13, "ENUM_VALUE_UPPER_SNAKE_CASE", "NONE")
+	lowerSnakeCase := newFileAnnotation(fileInfo, 6, 10, 6, 16, "FIELD_LOWER_SNAKE_CASE", "foo_bar")
+	duplicateLowerSnakeCase := newFileAnnotation(fileInfo, 6, 10, 6, 16, "FIELD_NAME_PATTERN", "foo_bar")
+	comment := bufanalysis.NewFileAnnotation(fileInfo, 5, 1, 5, 1, "COMMENT_MESSAGE", "")
+	files, unfixedFileAnnotations, err := Fix(
+		[]bufanalysis.FileAnnotation{
+			prefix,
+			upperSnakeCase,
+			comment,
+			lowerSnakeCase,
+			duplicateLowerSnakeCase,
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]*File{
+			{
+				ExternalPath: externalPath,
+				Data:         []byte(data),
+				FixedData:    []byte("enum Foo {\n\tFOO_none = 0;\n}\n\nmessage Bar {\n  string foo_bar = 1;\n}\n"),
+			},
+		},
+		files,
+	)
+	require.Equal(t, []bufanalysis.FileAnnotation{upperSnakeCase, comment}, unfixedFileAnnotations)
+}
+
+func TestFixInvalidPosition(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	externalPath := filepath.Join(dirPath, "a.proto")
+	// the tab advances from column 1 to column 9, so column 5 is within it
+	require.NoError(t, ioutil.WriteFile(externalPath, []byte("\tfoo\n"), 0600))
+	_, _, err = Fix(
+		[]bufanalysis.FileAnnotation{
+			newFileAnnotation(newFileInfo(externalPath), 1, 5, 1, 12, "FOO", "bar"),
+		},
+	)
+	require.Error(t, err)
+	_, _, err = Fix(
+		[]bufanalysis.FileAnnotation{
+			newFileAnnotation(newFileInfo(externalPath), 1, 9, 1, 13, "FOO", "bar"),
+		},
+	)
+	require.Error(t, err)
+}
+
+func newFileAnnotation(
+	fileInfo bufanalysis.FileInfo,
+	startLine int,
+	startColumn int,
+	endLine int,
+	endColumn int,
+	typeString string,
+	newText string,
+) bufanalysis.FileAnnotation {
+	return bufanalysis.NewFileAnnotation(
+		fileInfo,
+		startLine,
+		startColumn,
+		endLine,
+		endColumn,
+		typeString,
+		"",
+		bufanalysis.FileAnnotationWithEdits(
+			bufanalysis.Edit{
+				StartLine:   startLine,
+				StartColumn: startColumn,
+				EndLine:     endLine,
+				EndColumn:   endColumn,
+				NewText:     newText,
+			},
+		),
+	)
+}
+
+type fileInfo struct {
+	externalPath string
+}
+
+func newFileInfo(externalPath string) *fileInfo {
+	return &fileInfo{
+		externalPath: externalPath,
+	}
+}
+
+func (f *fileInfo) Path() string {
+	return filepath.Base(f.externalPath)
+}
+
+func (f *fileInfo) ExternalPath() string {
+	return f.externalPath
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufformat formats .proto source files.
+//
+// Files are printed with two-space indentation, one statement per line, and
+// normalized spacing between tokens. Runs of imports are sorted by path, and
+// the values of runs of options are aligned. Comments and single blank lines
+// are preserved.
+package bufformat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+// Format formats the .proto source data of the file at the path.
+//
+// The path is only used to identify the file in errors. Returns an error if
+// the data is not valid .proto source.
+func Format(path string, data []byte, options ...FormatOption) ([]byte, error) {
+	formatOptions := newFormatOptions()
+	for _, option := range options {
+		option(formatOptions)
+	}
+	parser := protoparse.Parser{
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filename != path {
+				return nil, storage.NewErrNotExist(filename)
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+	// the file is not linked, so its imports are not read
+	if _, err := parser.ParseFilesButDoNotLink(path); err != nil {
+		return nil, err
+	}
+	tokens, err := lex(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return newPrinter(formatOptions.removeImportPaths).print(tokens), nil
+}
+
+// FormatOption is an option for Format.
+type FormatOption func(*formatOptions)
+
+// FormatWithoutImports returns a new FormatOption that removes the imports
+// of the given paths.
+//
+// The comments directly above a removed import, and its trailing comment,
+// are removed with it. Imports split across lines are not removed.
+func FormatWithoutImports(importPaths ...string) FormatOption {
+	return func(formatOptions *formatOptions) {
+		for _, importPath := range importPaths {
+			formatOptions.removeImportPaths[importPath] = struct{}{}
+		}
+	}
+}
+
+type formatOptions struct {
+	removeImportPaths map[string]struct{}
+}
+
+func newFormatOptions() *formatOptions {
+	return &formatOptions{
+		removeImportPaths: make(map[string]struct{}),
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatIndentation(t *testing.T) {
+	t.Parallel()
+	testFormat(t, "indentation")
+}
+
+func TestFormatImports(t *testing.T) {
+	t.Parallel()
+	testFormat(t, "imports")
+}
+
+func TestFormatOptions(t *testing.T) {
+	t.Parallel()
+	testFormat(t, "options")
+}
+
+func TestFormatComments(t *testing.T) {
+	t.Parallel()
+	testFormat(t, "comments")
+}
+
+func TestFormatWithoutImports(t *testing.T) {
+	t.Parallel()
+	testFormat(t, "without_imports", FormatWithoutImports("b/b.proto", "d/d.proto"))
+}
+
+func TestFormatSyntaxError(t *testing.T) {
+	t.Parallel()
+	_, err := Format("a.proto", []byte("syntax = \"proto3\";\n\nmessage Foo {\n"))
+	assert.Error(t, err)
+}
+
+func testFormat(t *testing.T, name string, options ...FormatOption) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name+".proto"))
+	require.NoError(t, err)
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", name+".golden"))
+	require.NoError(t, err)
+	formatted, err := Format(name+".proto", data, options...)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(formatted))
+	// formatting is idempotent
+	formatted, err = Format(name+".proto", golden, options...)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(formatted))
+}
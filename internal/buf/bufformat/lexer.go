@@ -0,0 +1,205 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenKindIdent tokenKind = iota + 1
+	tokenKindNumber
+	tokenKindString
+	tokenKindPunctuation
+	tokenKindLineComment
+	tokenKindBlockComment
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// newlinesBefore is the number of newlines between the previous token and
+	// this token.
+	newlinesBefore int
+	// spaceBefore is true if there was whitespace between the previous token
+	// and this token.
+	spaceBefore bool
+	// lineIndent is the width of the indentation of the line the token
+	// starts on, as computed by indentWidth.
+	lineIndent int
+}
+
+func (t *token) isComment() bool {
+	return t.kind == tokenKindLineComment || t.kind == tokenKindBlockComment
+}
+
+func (t *token) is(text string) bool {
+	return t.kind == tokenKindPunctuation && t.text == text
+}
+
+// lex splits the .proto source data into tokens.
+//
+// The data is expected to have been validated by the parser, so lex only
+// checks for what it needs to split the data.
+func lex(data []byte) ([]*token, error) {
+	l := &lexer{data: string(data), line: 1}
+	var tokens []*token
+	for {
+		newlinesBefore, spaceBefore := l.skipWhitespace()
+		if l.offset == len(l.data) {
+			return tokens, nil
+		}
+		line, column := l.line, l.column
+		lineIndent := indentWidth(l.data[l.offset-column : l.offset])
+		t, err := l.next()
+		if err != nil {
+			return nil, fmt.Errorf("%d:%d:%v", line, column+1, err)
+		}
+		t.newlinesBefore = newlinesBefore
+		t.spaceBefore = spaceBefore
+		t.lineIndent = lineIndent
+		tokens = append(tokens, t)
+	}
+}
+
+type lexer struct {
+	data   string
+	offset int
+	line   int
+	column int
+}
+
+func (l *lexer) skipWhitespace() (int, bool) {
+	newlines := 0
+	space := false
+	for l.offset < len(l.data) {
+		switch l.data[l.offset] {
+		case '\n':
+			newlines++
+		case ' ', '\t', '\r', '\v', '\f':
+		default:
+			return newlines, space || newlines > 0
+		}
+		space = true
+		l.advance(1)
+	}
+	return newlines, space || newlines > 0
+}
+
+func (l *lexer) next() (*token, error) {
+	c := l.data[l.offset]
+	switch {
+	case strings.HasPrefix(l.data[l.offset:], "//"):
+		end := strings.IndexByte(l.data[l.offset:], '\n')
+		if end < 0 {
+			end = len(l.data) - l.offset
+		}
+		return l.take(tokenKindLineComment, end), nil
+	case strings.HasPrefix(l.data[l.offset:], "/*"):
+		end := strings.Index(l.data[l.offset+2:], "*/")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated block comment")
+		}
+		return l.take(tokenKindBlockComment, end+4), nil
+	case c == '"' || c == '\'':
+		for i := l.offset + 1; i < len(l.data); i++ {
+			switch l.data[i] {
+			case '\\':
+				i++
+			case '\n':
+				return nil, fmt.Errorf("unterminated string literal")
+			case c:
+				return l.take(tokenKindString, i+1-l.offset), nil
+			}
+		}
+		return nil, fmt.Errorf("unterminated string literal")
+	case isDigit(c) || (c == '.' && l.offset+1 < len(l.data) && isDigit(l.data[l.offset+1])):
+		i := l.offset + 1
+		for i < len(l.data) {
+			d := l.data[i]
+			switch {
+			case isIdentChar(d) || d == '.':
+			case (d == '+' || d == '-') && (l.data[i-1] == 'e' || l.data[i-1] == 'E') && !isHex(l.data[l.offset:i]):
+			default:
+				return l.take(tokenKindNumber, i-l.offset), nil
+			}
+			i++
+		}
+		return l.take(tokenKindNumber, i-l.offset), nil
+	case isIdentChar(c):
+		i := l.offset + 1
+		for i < len(l.data) && isIdentChar(l.data[i]) {
+			i++
+		}
+		return l.take(tokenKindIdent, i-l.offset), nil
+	case strings.IndexByte("{}[]()<>;,.=:-+", c) >= 0:
+		return l.take(tokenKindPunctuation, 1), nil
+	default:
+		return nil, fmt.Errorf("unexpected character %q", c)
+	}
+}
+
+func (l *lexer) take(kind tokenKind, length int) *token {
+	t := &token{
+		kind: kind,
+		text: l.data[l.offset : l.offset+length],
+	}
+	l.advance(length)
+	return t
+}
+
+func (l *lexer) advance(length int) {
+	for _, c := range l.data[l.offset : l.offset+length] {
+		if c == '\n' {
+			l.line++
+			l.column = 0
+		} else {
+			l.column++
+		}
+	}
+	l.offset += length
+}
+
+// indentWidth returns the width of the leading whitespace of the line,
+// counting a tab as one level of indentation.
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += len(indent)
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isDigit(c) || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_'
+}
+
+func isHex(number string) bool {
+	return strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0X")
+}
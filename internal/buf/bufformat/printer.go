@@ -0,0 +1,422 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// indent is the indentation for each level of nesting.
+const indent = "  "
+
+type frameKind int
+
+const (
+	// frameKindBody is the body of a message, enum, service, or other
+	// declaration, which contains statements.
+	frameKindBody frameKind = iota + 1
+	// frameKindAggregate is a text format value of an option.
+	frameKindAggregate
+	frameKindBracket
+	frameKindParen
+	frameKindAngle
+)
+
+type frame struct {
+	kind frameKind
+	// depth is the depth of the lines that start with the closer of the
+	// frame. The lines within the frame are one deeper.
+	depth int
+}
+
+type line struct {
+	depth       int
+	blankBefore bool
+	text        string
+	hasCode     bool
+	// importPath is the path of the import if the line is a whole import
+	// statement.
+	importPath string
+	// optionNameEnd is the offset in text after the name of the option if the
+	// line is a whole option statement.
+	optionNameEnd int
+}
+
+type printer struct {
+	removeImportPaths map[string]struct{}
+
+	lines  []*line
+	cur    *line
+	frames []*frame
+	// last is the last token printed, and lastCode is the last token printed
+	// that is not a comment.
+	last     *token
+	lastCode *token
+	// atStatementEnd is true if lastCode ended a statement or opened or
+	// closed a body.
+	atStatementEnd bool
+	// inStatement is true if a statement within a body or the file has been
+	// started but not ended.
+	inStatement      bool
+	statementLine    *line
+	statementKeyword string
+	importPath       string
+	optionNameEnd    int
+}
+
+func newPrinter(removeImportPaths map[string]struct{}) *printer {
+	return &printer{
+		removeImportPaths: removeImportPaths,
+	}
+}
+
+func (p *printer) print(tokens []*token) []byte {
+	for _, t := range tokens {
+		p.printToken(t)
+	}
+	p.lines = removeImports(p.lines, p.removeImportPaths)
+	sortImports(p.lines)
+	alignOptions(p.lines)
+	buffer := bytes.NewBuffer(nil)
+	for i, line := range p.lines {
+		if i > 0 && line.blankBefore {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(strings.Repeat(indent, line.depth))
+		buffer.WriteString(line.text)
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes()
+}
+
+func (p *printer) printToken(t *token) {
+	if newline, blank := p.needsNewline(t); newline {
+		p.cur = &line{
+			depth:       p.depth(t),
+			blankBefore: blank && len(p.lines) > 0,
+		}
+		p.lines = append(p.lines, p.cur)
+	} else if needsSpace(p.last, t) {
+		p.cur.text += " "
+	}
+	if !t.isComment() && !isCloser(t) && p.atBodyLevel() && !p.inStatement {
+		p.inStatement = true
+		p.statementLine = p.cur
+		p.statementKeyword = t.text
+		p.importPath = ""
+		p.optionNameEnd = 0
+	}
+	if t.is("=") && p.atBodyLevel() && p.statementKeyword == "option" && p.statementLine == p.cur && p.optionNameEnd == 0 {
+		p.optionNameEnd = len(strings.TrimRight(p.cur.text, " "))
+	}
+	if t.kind == tokenKindString && p.atBodyLevel() && p.statementKeyword == "import" && p.importPath == "" {
+		p.importPath = t.text[1 : len(t.text)-1]
+	}
+	p.cur.text += p.tokenText(t)
+	p.last = t
+	if t.isComment() {
+		return
+	}
+	p.cur.hasCode = true
+	p.atStatementEnd = false
+	switch {
+	case t.is("{"):
+		if p.opensBody(t) {
+			// the body closes at the depth of the declaration, even if the
+			// declaration is split across lines
+			p.pushFrame(frameKindBody, p.statementLine.depth)
+			p.endStatement()
+		} else {
+			p.pushFrame(frameKindAggregate, p.cur.depth)
+		}
+	case t.is("["):
+		p.pushFrame(frameKindBracket, p.cur.depth)
+	case t.is("("):
+		p.pushFrame(frameKindParen, p.cur.depth)
+	case t.is("<"):
+		p.pushFrame(frameKindAngle, p.cur.depth)
+	case isCloser(t):
+		if len(p.frames) > 0 {
+			frame := p.frames[len(p.frames)-1]
+			p.frames = p.frames[:len(p.frames)-1]
+			if frame.kind == frameKindBody {
+				p.endStatement()
+			}
+		}
+	case t.is(";") && p.atBodyLevel():
+		if p.statementLine == p.cur {
+			switch p.statementKeyword {
+			case "import":
+				p.cur.importPath = p.importPath
+			case "option":
+				p.cur.optionNameEnd = p.optionNameEnd
+			}
+		}
+		p.endStatement()
+	}
+	p.lastCode = t
+}
+
+func (p *printer) pushFrame(kind frameKind, depth int) {
+	p.frames = append(p.frames, &frame{kind: kind, depth: depth})
+}
+
+func (p *printer) endStatement() {
+	p.inStatement = false
+	p.atStatementEnd = true
+}
+
+// needsNewline returns whether the token should start a new line, and if so,
+// whether the line should be preceded by a blank line.
+func (p *printer) needsNewline(t *token) (bool, bool) {
+	switch {
+	case p.cur == nil:
+		return true, false
+	case p.last.kind == tokenKindLineComment:
+		return true, t.newlinesBefore > 1 && p.allowsBlank(t)
+	case isCloser(t) && p.last.kind == tokenKindPunctuation && closerOf(p.last.text) == t.text:
+		// empty bodies and lists stay on one line
+		return false, false
+	case t.is(";"):
+		return false, false
+	case p.opensBody(t):
+		// the body of a declaration starts on the line of the declaration
+		return false, false
+	case !t.isComment() && p.atStatementEnd && p.cur.hasCode:
+		return true, t.newlinesBefore > 1 && p.allowsBlank(t)
+	case t.newlinesBefore > 0:
+		return true, t.newlinesBefore > 1 && p.allowsBlank(t)
+	default:
+		return false, false
+	}
+}
+
+// allowsBlank returns whether a blank line is allowed before the token.
+//
+// Blank lines are removed at the start and end of bodies and lists.
+func (p *printer) allowsBlank(t *token) bool {
+	return !isCloser(t) && !(p.lastCode != nil && p.last == p.lastCode && isOpener(p.lastCode))
+}
+
+// depth returns the depth of a line that starts with the token.
+//
+// Lines are indented one level within each frame opened on a previous line,
+// so that frames opened on the same line only add one level.
+func (p *printer) depth(t *token) int {
+	switch {
+	case p.opensBody(t):
+		return p.statementLine.depth
+	case p.inStatement && p.atBodyLevel() && !isCloser(t):
+		// continuation of a statement split across lines
+		return p.statementLine.depth + 1
+	case len(p.frames) == 0:
+		return 0
+	case isCloser(t):
+		return p.frames[len(p.frames)-1].depth
+	default:
+		return p.frames[len(p.frames)-1].depth + 1
+	}
+}
+
+// opensBody returns true if the token is the { of the body of a
+// declaration, as opposed to the start of a text format value.
+func (p *printer) opensBody(t *token) bool {
+	return t.is("{") && p.atBodyLevel() && p.inStatement && !p.lastCode.is("=") && !p.lastCode.is(":")
+}
+
+// atBodyLevel returns true if the next token is directly within a body or
+// the file, and not within a list or value.
+func (p *printer) atBodyLevel() bool {
+	return len(p.frames) == 0 || p.frames[len(p.frames)-1].kind == frameKindBody
+}
+
+// tokenText returns the text of the token to print at the end of the
+// current line.
+func (p *printer) tokenText(t *token) string {
+	switch t.kind {
+	case tokenKindLineComment:
+		return strings.TrimRight(t.text, " \t\r")
+	case tokenKindBlockComment:
+		commentLines := strings.Split(t.text, "\n")
+		if len(commentLines) == 1 {
+			return t.text
+		}
+		// the lines after the first keep their indentation relative to
+		// the line the comment starts on
+		for i := 1; i < len(commentLines); i++ {
+			commentLine := strings.TrimRight(commentLines[i], " \t\r")
+			if commentLine == "" {
+				commentLines[i] = ""
+				continue
+			}
+			extra := indentWidth(commentLine) - t.lineIndent
+			if extra < 0 {
+				extra = 0
+			}
+			commentLines[i] = strings.Repeat(indent, p.cur.depth) + strings.Repeat(" ", extra) + strings.TrimLeft(commentLine, " \t")
+		}
+		return strings.Join(commentLines, "\n")
+	default:
+		return t.text
+	}
+}
+
+// needsSpace returns whether there should be a space between the tokens on
+// the same line.
+func needsSpace(a *token, b *token) bool {
+	switch {
+	case b.is(";"), b.is(","), b.is(")"), b.is("]"), b.is(">"), b.is(":"):
+		return false
+	case b.is("}"):
+		return !a.is("{")
+	case a.is("("), a.is("["), a.is("<"), a.is("."), a.is("-"), a.is("+"):
+		return false
+	case a.isComment() || b.isComment():
+		return true
+	case b.is("."):
+		// a leading dot of a fully-qualified name, such as after a label
+		return b.spaceBefore && a.kind == tokenKindIdent
+	case b.is("("):
+		// rpc Foo(Request) returns (Response), option (foo) = 1
+		return a.kind != tokenKindIdent || a.text == "option" || a.text == "returns"
+	case b.is("<"):
+		return !(a.kind == tokenKindIdent && a.text == "map")
+	default:
+		return true
+	}
+}
+
+func isOpener(t *token) bool {
+	return t.kind == tokenKindPunctuation && closerOf(t.text) != ""
+}
+
+func isCloser(t *token) bool {
+	return t.is("}") || t.is("]") || t.is(")") || t.is(">")
+}
+
+func closerOf(opener string) string {
+	switch opener {
+	case "{":
+		return "}"
+	case "[":
+		return "]"
+	case "(":
+		return ")"
+	case "<":
+		return ">"
+	default:
+		return ""
+	}
+}
+
+// removeImports removes the import lines of the paths, along with the
+// comment lines directly above them.
+func removeImports(lines []*line, importPaths map[string]struct{}) []*line {
+	if len(importPaths) == 0 {
+		return lines
+	}
+	newLines := make([]*line, 0, len(lines))
+	// the first line of the removed comments and import, if any
+	var removedStart *line
+	for i, line := range lines {
+		if _, ok := importPaths[line.importPath]; ok && line.importPath != "" {
+			// remove the comment lines directly above the import
+			for len(newLines) > 0 && !newLines[len(newLines)-1].hasCode && !line.blankBefore {
+				line = newLines[len(newLines)-1]
+				newLines = newLines[:len(newLines)-1]
+			}
+			if removedStart == nil {
+				removedStart = line
+			}
+			continue
+		}
+		// the blank line before the removed lines is kept
+		if removedStart != nil {
+			line.blankBefore = line.blankBefore || removedStart.blankBefore
+			removedStart = nil
+		}
+		newLines = append(newLines, lines[i])
+	}
+	return newLines
+}
+
+// sortImports sorts each run of import lines by path.
+//
+// A run is broken by a blank line or a line that is not an import or a
+// comment. Comment lines move with the import that follows them.
+func sortImports(lines []*line) {
+	for i := 0; i < len(lines); i++ {
+		end := i
+		for end < len(lines) && lines[end].depth == 0 && (lines[end].importPath != "" || !lines[end].hasCode) && (end == i || !lines[end].blankBefore) {
+			end++
+		}
+		// comment lines at the end of the run belong to the line after it
+		for end > i && lines[end-1].importPath == "" {
+			end--
+		}
+		if end == i {
+			continue
+		}
+		var units [][]*line
+		start := i
+		for j := i; j < end; j++ {
+			if lines[j].importPath != "" {
+				units = append(units, lines[start:j+1])
+				start = j + 1
+			}
+		}
+		sort.SliceStable(units, func(j int, k int) bool {
+			return units[j][len(units[j])-1].importPath < units[k][len(units[k])-1].importPath
+		})
+		blankBefore := lines[i].blankBefore
+		sorted := make([]*line, 0, end-i)
+		for _, unit := range units {
+			sorted = append(sorted, unit...)
+		}
+		for j, line := range sorted {
+			line.blankBefore = j == 0 && blankBefore
+			lines[i+j] = line
+		}
+		i = end - 1
+	}
+}
+
+// alignOptions aligns the = of each run of option lines at the same depth.
+//
+// A run is broken by a blank line or a line that is not an option.
+func alignOptions(lines []*line) {
+	for i := 0; i < len(lines); {
+		if lines[i].optionNameEnd == 0 {
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && lines[end].optionNameEnd != 0 && lines[end].depth == lines[i].depth && !lines[end].blankBefore {
+			end++
+		}
+		maxNameEnd := 0
+		for _, line := range lines[i:end] {
+			if line.optionNameEnd > maxNameEnd {
+				maxNameEnd = line.optionNameEnd
+			}
+		}
+		for _, line := range lines[i:end] {
+			line.text = line.text[:line.optionNameEnd] + strings.Repeat(" ", maxNameEnd-line.optionNameEnd) + line.text[line.optionNameEnd:]
+		}
+		i = end
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufgen does configuration-based generation.
+//
+// It is used by the buf generate command.
+package bufgen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appproto"
+	"go.uber.org/zap"
+)
+
+const (
+	// ExternalConfigFilePath is the default external configuration file path.
+	ExternalConfigFilePath = "buf.gen.yaml"
+	// V1Beta1Version is the string used to identify the v1beta1 version of the generate template.
+	V1Beta1Version = "v1beta1"
+)
+
+const (
+	// StrategyDirectory is the strategy that says to generate per directory.
+	//
+	// This is the default value.
+	StrategyDirectory Strategy = iota + 1
+	// StrategyAll is the strategy that says to generate with all files at once.
+	StrategyAll
+)
+
+// Strategy is a generation strategy.
+type Strategy int
+
+// ParseStrategy parses the Strategy.
+//
+// If the empty string is provided, this is interpreted as StrategyDirectory.
+func ParseStrategy(s string) (Strategy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "directory":
+		return StrategyDirectory, nil
+	case "all":
+		return StrategyAll, nil
+	default:
+		return 0, fmt.Errorf("unknown strategy: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (s Strategy) String() string {
+	switch s {
+	case StrategyDirectory:
+		return "directory"
+	case StrategyAll:
+		return "all"
+	default:
+		return fmt.Sprintf("%d", s)
+	}
+}
+
+// Config is a configuration.
+type Config struct {
+	// Required
+	PluginConfigs []*PluginConfig
+	// Optional
+	//
+	// If set, managed mode is enabled.
+	ManagedConfig *ManagedConfig
+}
+
+// PluginConfig is a plugin configuration.
+type PluginConfig struct {
+	// Required
+	Name string
+	// Required
+	//
+	// If this ends in .zip or .jar, the output is written to an archive
+	// at this path instead of to a directory.
+	Out string
+	// Optional
+	Opt string
+	// Optional
+	//
+	// If not set, the plugin is looked up by name. See NewGenerator.
+	Path string
+	// Required
+	Strategy Strategy
+}
+
+// ManagedConfig is a configuration for managed mode.
+//
+// In managed mode, the file options of the non-import files of the Image are
+// set before the plugins are run, overriding any values in the files.
+// Imports keep their file options, as their code is generated separately.
+type ManagedConfig struct {
+	// Optional
+	//
+	// If set, go_package is set to the prefix joined with the directory of
+	// each file, followed by ;NAME, where NAME is the last element of the
+	// directory, preceded by the element before it if the last element is a
+	// version such as v1.
+	GoPackagePrefix string
+	// Optional
+	//
+	// If set, java_multiple_files is set to the value.
+	JavaMultipleFiles *bool
+	// Optional
+	//
+	// If true, csharp_namespace is set to the package of each file, with
+	// each part of the package in PascalCase. Files without a package are
+	// not changed.
+	CsharpNamespace bool
+}
+
+// ReadConfig reads the configuration from the file path or data.
+//
+// If the value ends in .json, .yaml, or .yml, the value is read as a file.
+// Otherwise, the value is read as JSON or YAML data.
+func ReadConfig(value string) (*Config, error) {
+	return readConfig(value)
+}
+
+// NewConfig returns a new Config for the ExternalConfigV1Beta1.
+func NewConfig(externalConfig ExternalConfigV1Beta1) (*Config, error) {
+	return newConfig(externalConfig)
+}
+
+// ExternalConfigV1Beta1 is an external configuration.
+type ExternalConfigV1Beta1 struct {
+	Version string                        `json:"version,omitempty" yaml:"version,omitempty"`
+	Plugins []ExternalPluginConfigV1Beta1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Managed *ExternalManagedConfigV1Beta1 `json:"managed,omitempty" yaml:"managed,omitempty"`
+}
+
+// ExternalPluginConfigV1Beta1 is an external plugin configuration.
+type ExternalPluginConfigV1Beta1 struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Out  string `json:"out,omitempty" yaml:"out,omitempty"`
+	// Opt is either a single string, or a list of strings that are joined with commas.
+	Opt      interface{} `json:"opt,omitempty" yaml:"opt,omitempty"`
+	Path     string      `json:"path,omitempty" yaml:"path,omitempty"`
+	Strategy string      `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+}
+
+// ExternalManagedConfigV1Beta1 is an external managed mode configuration.
+type ExternalManagedConfigV1Beta1 struct {
+	GoPackagePrefix   string `json:"go_package_prefix,omitempty" yaml:"go_package_prefix,omitempty"`
+	JavaMultipleFiles *bool  `json:"java_multiple_files,omitempty" yaml:"java_multiple_files,omitempty"`
+	CsharpNamespace   bool   `json:"csharp_namespace,omitempty" yaml:"csharp_namespace,omitempty"`
+}
+
+// Generator generates code using plugins.
+type Generator interface {
+	// Generate runs the plugins in the Config against the Image, and writes
+	// the results to the configured outputs.
+	//
+	// Plugins are run in the order they are configured. If the Config has a
+	// ManagedConfig, it is applied to the Image first.
+	Generate(
+		ctx context.Context,
+		container app.EnvStderrContainer,
+		config *Config,
+		image bufcore.Image,
+	) error
+}
+
+// NewGenerator returns a new Generator.
+//
+// Plugins without a path are looked up in the following order:
+//
+// - A binary named protoc-gen-NAME on the PATH.
+// - A builtin plugin with the name, such as the Go plugin.
+// - For the plugins builtin to protoc, such as java, a proxy through protoc.
+func NewGenerator(logger *zap.Logger, options ...GeneratorOption) Generator {
+	return newGenerator(logger, options...)
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithBaseOutDirPath returns a new GeneratorOption that writes all
+// outputs relative to the given directory.
+//
+// The default is to write outputs relative to the current directory.
+func GeneratorWithBaseOutDirPath(baseOutDirPath string) GeneratorOption {
+	return func(generator *generator) {
+		generator.baseOutDirPath = baseOutDirPath
+	}
+}
+
+// GeneratorWithBuiltinHandler returns a new GeneratorOption that adds the
+// Handler as a builtin plugin with the given name.
+//
+// This overrides any default builtin plugin with the same name.
+func GeneratorWithBuiltinHandler(pluginName string, handler appproto.Handler) GeneratorOption {
+	return func(generator *generator) {
+		generator.pluginNameToBuiltinHandler[pluginName] = handler
+	}
+}
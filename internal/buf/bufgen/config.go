@@ -0,0 +1,139 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/encoding"
+)
+
+func readConfig(value string) (*Config, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, errors.New("template value is empty")
+	}
+	var data []byte
+	var err error
+	switch filepath.Ext(value) {
+	case ".json", ".yaml", ".yml":
+		data, err = ioutil.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not read file: %v", err)
+		}
+	default:
+		data = []byte(value)
+	}
+	externalConfig := ExternalConfigV1Beta1{}
+	if err := encoding.UnmarshalJSONOrYAMLStrict(data, &externalConfig); err != nil {
+		return nil, err
+	}
+	return newConfig(externalConfig)
+}
+
+func newConfig(externalConfig ExternalConfigV1Beta1) (*Config, error) {
+	if externalConfig.Version != "" && externalConfig.Version != V1Beta1Version {
+		return nil, fmt.Errorf("unknown version: %q, only %q is supported", externalConfig.Version, V1Beta1Version)
+	}
+	if len(externalConfig.Plugins) == 0 {
+		return nil, errors.New("no plugins set")
+	}
+	pluginConfigs := make([]*PluginConfig, 0, len(externalConfig.Plugins))
+	for _, externalPluginConfig := range externalConfig.Plugins {
+		pluginConfig, err := newPluginConfig(externalPluginConfig)
+		if err != nil {
+			return nil, err
+		}
+		pluginConfigs = append(pluginConfigs, pluginConfig)
+	}
+	var managedConfig *ManagedConfig
+	if externalConfig.Managed != nil {
+		var err error
+		managedConfig, err = newManagedConfig(*externalConfig.Managed)
+		if err != nil {
+			return nil, fmt.Errorf("managed: %v", err)
+		}
+	}
+	return &Config{
+		PluginConfigs: pluginConfigs,
+		ManagedConfig: managedConfig,
+	}, nil
+}
+
+func newManagedConfig(externalManagedConfig ExternalManagedConfigV1Beta1) (*ManagedConfig, error) {
+	if externalManagedConfig.GoPackagePrefix == "" &&
+		externalManagedConfig.JavaMultipleFiles == nil &&
+		!externalManagedConfig.CsharpNamespace {
+		return nil, errors.New("no options set")
+	}
+	goPackagePrefix := strings.TrimSuffix(externalManagedConfig.GoPackagePrefix, "/")
+	if strings.Contains(goPackagePrefix, ";") {
+		return nil, fmt.Errorf("go_package_prefix %q cannot contain a package name", goPackagePrefix)
+	}
+	return &ManagedConfig{
+		GoPackagePrefix:   goPackagePrefix,
+		JavaMultipleFiles: externalManagedConfig.JavaMultipleFiles,
+		CsharpNamespace:   externalManagedConfig.CsharpNamespace,
+	}, nil
+}
+
+func newPluginConfig(externalPluginConfig ExternalPluginConfigV1Beta1) (*PluginConfig, error) {
+	if externalPluginConfig.Name == "" {
+		return nil, errors.New("plugin name is required")
+	}
+	if externalPluginConfig.Out == "" {
+		return nil, fmt.Errorf("plugin %s: out is required", externalPluginConfig.Name)
+	}
+	opt, err := parseOpt(externalPluginConfig.Opt)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", externalPluginConfig.Name, err)
+	}
+	strategy, err := ParseStrategy(externalPluginConfig.Strategy)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", externalPluginConfig.Name, err)
+	}
+	return &PluginConfig{
+		Name:     externalPluginConfig.Name,
+		Out:      externalPluginConfig.Out,
+		Opt:      opt,
+		Path:     externalPluginConfig.Path,
+		Strategy: strategy,
+	}, nil
+}
+
+func parseOpt(opt interface{}) (string, error) {
+	switch t := opt.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case []interface{}:
+		opts := make([]string, len(t))
+		for i, elem := range t {
+			s, ok := elem.(string)
+			if !ok {
+				return "", fmt.Errorf("opt must be a string or a list of strings but had element %v", elem)
+			}
+			opts[i] = s
+		}
+		return strings.Join(opts, ","), nil
+	default:
+		return "", fmt.Errorf("opt must be a string or a list of strings but was %v", opt)
+	}
+}
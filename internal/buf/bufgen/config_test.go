@@ -0,0 +1,131 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	t.Parallel()
+	expectedConfig := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "go",
+				Out:      "gen/go",
+				Opt:      "plugins=grpc,paths=source_relative",
+				Strategy: StrategyDirectory,
+			},
+			{
+				Name:     "java",
+				Out:      "gen/java.jar",
+				Path:     "/usr/local/bin/protoc-gen-java",
+				Strategy: StrategyAll,
+			},
+		},
+	}
+	data := `version: v1beta1
+plugins:
+  - name: go
+    out: gen/go
+    opt:
+      - plugins=grpc
+      - paths=source_relative
+  - name: java
+    out: gen/java.jar
+    path: /usr/local/bin/protoc-gen-java
+    strategy: all
+`
+	config, err := ReadConfig(data)
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, config)
+
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	configFilePath := filepath.Join(tempDirPath, ExternalConfigFilePath)
+	require.NoError(t, ioutil.WriteFile(configFilePath, []byte(data), 0644))
+	config, err = ReadConfig(configFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, expectedConfig, config)
+
+	config, err = ReadConfig(`{"plugins":[{"name":"go","out":"gen/go","opt":"paths=source_relative"}]}`)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Config{
+			PluginConfigs: []*PluginConfig{
+				{
+					Name:     "go",
+					Out:      "gen/go",
+					Opt:      "paths=source_relative",
+					Strategy: StrategyDirectory,
+				},
+			},
+		},
+		config,
+	)
+}
+
+func TestReadConfigManaged(t *testing.T) {
+	t.Parallel()
+	config, err := ReadConfig(`version: v1beta1
+managed:
+  go_package_prefix: github.com/acme/gen/go/
+  java_multiple_files: false
+  csharp_namespace: true
+plugins:
+  - name: go
+    out: gen/go
+`)
+	require.NoError(t, err)
+	javaMultipleFiles := false
+	assert.Equal(
+		t,
+		&ManagedConfig{
+			GoPackagePrefix:   "github.com/acme/gen/go",
+			JavaMultipleFiles: &javaMultipleFiles,
+			CsharpNamespace:   true,
+		},
+		config.ManagedConfig,
+	)
+}
+
+func TestReadConfigError(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		``,
+		`{"version":"v2","plugins":[{"name":"go","out":"gen/go"}]}`,
+		`{"plugins":[]}`,
+		`{"plugins":[{"out":"gen/go"}]}`,
+		`{"plugins":[{"name":"go"}]}`,
+		`{"plugins":[{"name":"go","out":"gen/go","opt":1}]}`,
+		`{"plugins":[{"name":"go","out":"gen/go","opt":[1]}]}`,
+		`{"plugins":[{"name":"go","out":"gen/go","strategy":"foo"}]}`,
+		`{"plugins":[{"name":"go","out":"gen/go","foo":"bar"}]}`,
+		`{"managed":{},"plugins":[{"name":"go","out":"gen/go"}]}`,
+		`{"managed":{"go_package_prefix":"github.com/acme/gen/go;gen"},"plugins":[{"name":"go","out":"gen/go"}]}`,
+		filepath.Join("does", "not", "exist.yaml"),
+	} {
+		_, err := ReadConfig(data)
+		assert.Error(t, err, data)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appproto"
+	"github.com/bufbuild/buf/internal/pkg/app/appproto/appprotoexec"
+	"github.com/bufbuild/buf/internal/pkg/app/appproto/appprotogo"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/bufbuild/buf/internal/pkg/thread"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// jarManifestPath and jarManifestContent are the manifest protoc adds to jar outputs.
+const (
+	jarManifestPath    = "META-INF/MANIFEST.MF"
+	jarManifestContent = "Manifest-Version: 1.0\nCreated-By: 1.6.0 (protoc)\n\n"
+)
+
+type generator struct {
+	logger                     *zap.Logger
+	baseOutDirPath             string
+	pluginNameToBuiltinHandler map[string]appproto.Handler
+}
+
+func newGenerator(logger *zap.Logger, options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger: logger.Named("bufgen"),
+		pluginNameToBuiltinHandler: map[string]appproto.Handler{
+			appprotogo.PluginName: appprotogo.NewHandler(),
+		},
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	config *Config,
+	image bufcore.Image,
+) error {
+	if config.ManagedConfig != nil {
+		var err error
+		image, err = applyManagedConfig(config.ManagedConfig, image)
+		if err != nil {
+			return err
+		}
+	}
+	var imagesByDir []bufcore.Image
+	for _, pluginConfig := range config.PluginConfigs {
+		images := []bufcore.Image{image}
+		if pluginConfig.Strategy == StrategyDirectory {
+			if imagesByDir == nil {
+				var err error
+				timer := instrument.Start(g.logger, "image_by_dir")
+				imagesByDir, err = bufcore.ImageByDir(image)
+				if err != nil {
+					return err
+				}
+				timer.End()
+			}
+			images = imagesByDir
+		}
+		if err := g.generatePlugin(ctx, container, pluginConfig, images); err != nil {
+			return fmt.Errorf("plugin %s: %v", pluginConfig.Name, err)
+		}
+	}
+	return nil
+}
+
+func (g *generator) generatePlugin(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	pluginConfig *PluginConfig,
+	images []bufcore.Image,
+) error {
+	defer instrument.Start(g.logger, "generate_plugin", zap.String("plugin", pluginConfig.Name)).End()
+	instrument.Progress(g.logger, "generating", zap.String("plugin", pluginConfig.Name))
+	handler, err := g.getHandler(pluginConfig)
+	if err != nil {
+		return err
+	}
+	responses := make([]*pluginpb.CodeGeneratorResponse, len(images))
+	jobs := make([]func() error, len(images))
+	for i, image := range images {
+		i := i
+		image := image
+		jobs[i] = func() error {
+			response, err := appproto.Execute(
+				ctx,
+				container,
+				handler,
+				bufcore.ImageToCodeGeneratorRequest(image, pluginConfig.Opt),
+			)
+			if err != nil {
+				return err
+			}
+			if errString := response.GetError(); errString != "" {
+				return fmt.Errorf("%s", errString)
+			}
+			responses[i] = response
+			return nil
+		}
+	}
+	if err := thread.Parallelize(jobs...); err != nil {
+		return err
+	}
+	readBucket, err := newResponsesReadBucket(responses, filepath.Ext(pluginConfig.Out) == ".jar")
+	if err != nil {
+		return err
+	}
+	return g.writeOut(ctx, readBucket, pluginConfig.Out)
+}
+
+func (g *generator) getHandler(pluginConfig *PluginConfig) (appproto.Handler, error) {
+	if pluginConfig.Path != "" {
+		return appprotoexec.NewBinaryHandler(g.logger, pluginConfig.Path)
+	}
+	if pluginPath, err := exec.LookPath("protoc-gen-" + pluginConfig.Name); err == nil {
+		return appprotoexec.NewBinaryHandler(g.logger, pluginPath)
+	}
+	if handler, ok := g.pluginNameToBuiltinHandler[pluginConfig.Name]; ok {
+		return handler, nil
+	}
+	return appprotoexec.NewHandler(g.logger, pluginConfig.Name, "", "")
+}
+
+// writeOut writes the files in the ReadBucket to out, either as a directory
+// or as an archive.
+func (g *generator) writeOut(ctx context.Context, readBucket storage.ReadBucket, out string) (retErr error) {
+	if g.baseOutDirPath != "" && !filepath.IsAbs(out) {
+		out = filepath.Join(g.baseOutDirPath, out)
+	}
+	switch filepath.Ext(out) {
+	case ".jar", ".zip":
+		if dirPath := filepath.Dir(out); dirPath != "." {
+			if err := os.MkdirAll(dirPath, 0755); err != nil {
+				return err
+			}
+		}
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, file.Close())
+		}()
+		return storagearchive.Zip(ctx, readBucket, file)
+	default:
+		if err := os.MkdirAll(out, 0755); err != nil {
+			return err
+		}
+		readWriteBucket, err := storageos.NewReadWriteBucket(out)
+		if err != nil {
+			return err
+		}
+		_, err = storage.Copy(ctx, readBucket, readWriteBucket)
+		return err
+	}
+}
+
+// newResponsesReadBucket returns a new ReadBucket with the files of all the
+// responses, along with a manifest if the output is a jar.
+func newResponsesReadBucket(responses []*pluginpb.CodeGeneratorResponse, isJar bool) (storage.ReadBucket, error) {
+	pathToData := make(map[string][]byte)
+	if isJar {
+		pathToData[jarManifestPath] = []byte(jarManifestContent)
+	}
+	for _, response := range responses {
+		for _, file := range response.File {
+			if file.GetInsertionPoint() != "" {
+				return nil, fmt.Errorf("insertion points not supported but are coming soon: %s", file.GetName())
+			}
+			if _, ok := pathToData[file.GetName()]; ok {
+				return nil, fmt.Errorf("file %s was generated multiple times", file.GetName())
+			}
+			pathToData[file.GetName()] = []byte(file.GetContent())
+		}
+	}
+	return storagemem.NewReadBucket(pathToData)
+}
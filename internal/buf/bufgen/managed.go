@@ -0,0 +1,113 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// packageVersionRegexp matches the versions of packages, such as v1,
+// v1beta1, and v1test.
+var packageVersionRegexp = regexp.MustCompile(`^v[1-9][0-9]*(p[1-9][0-9]*)?((alpha|beta)[1-9][0-9]*|test[a-z0-9]*)?$`)
+
+// applyManagedConfig returns a copy of the Image with the file options of
+// the non-import files set as configured.
+func applyManagedConfig(managedConfig *ManagedConfig, image bufcore.Image) (bufcore.Image, error) {
+	imageFiles := image.Files()
+	newImageFiles := make([]bufcore.ImageFile, len(imageFiles))
+	for i, imageFile := range imageFiles {
+		if imageFile.IsImport() {
+			newImageFiles[i] = imageFile
+			continue
+		}
+		fileDescriptorProto := proto.Clone(imageFile.Proto()).(*descriptorpb.FileDescriptorProto)
+		if fileDescriptorProto.Options == nil {
+			fileDescriptorProto.Options = &descriptorpb.FileOptions{}
+		}
+		if managedConfig.GoPackagePrefix != "" {
+			fileDescriptorProto.Options.GoPackage = proto.String(
+				getManagedGoPackage(managedConfig.GoPackagePrefix, imageFile.Path()),
+			)
+		}
+		if managedConfig.JavaMultipleFiles != nil {
+			fileDescriptorProto.Options.JavaMultipleFiles = proto.Bool(*managedConfig.JavaMultipleFiles)
+		}
+		if managedConfig.CsharpNamespace {
+			if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+				fileDescriptorProto.Options.CsharpNamespace = proto.String(getManagedCsharpNamespace(pkg))
+			}
+		}
+		newImageFile, err := bufcore.NewImageFile(
+			fileDescriptorProto,
+			imageFile.ExternalPath(),
+			imageFile.IsImport(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		newImageFiles[i] = newImageFile
+	}
+	return bufcore.NewImage(newImageFiles)
+}
+
+// getManagedGoPackage returns the go_package for the file at the path.
+//
+// For example, with the prefix github.com/acme/gen/go, the file
+// acme/weather/v1/weather.proto has the go_package
+// github.com/acme/gen/go/acme/weather/v1;weatherv1.
+func getManagedGoPackage(goPackagePrefix string, filePath string) string {
+	importPath := path.Join(goPackagePrefix, path.Dir(filePath))
+	name := path.Base(importPath)
+	if parent := path.Base(path.Dir(importPath)); packageVersionRegexp.MatchString(name) && parent != "." && parent != "/" {
+		name = parent + name
+	}
+	return importPath + ";" + toGoIdentifier(name)
+}
+
+// getManagedCsharpNamespace returns the csharp_namespace for the package.
+//
+// For example, acme.weather_data.v1 has the csharp_namespace
+// Acme.WeatherData.V1.
+func getManagedCsharpNamespace(pkg string) string {
+	parts := strings.Split(pkg, ".")
+	for i, part := range parts {
+		words := strings.Split(part, "_")
+		for j, word := range words {
+			if word != "" {
+				words[j] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		parts[i] = strings.Join(words, "")
+	}
+	return strings.Join(parts, ".")
+}
+
+// toGoIdentifier replaces the characters of the name that cannot be in a Go
+// identifier with underscores.
+func toGoIdentifier(name string) string {
+	identifier := []byte(name)
+	for i, c := range identifier {
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || (i > 0 && '0' <= c && c <= '9')) {
+			identifier[i] = '_'
+		}
+	}
+	return string(identifier)
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoretesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestApplyManagedConfig(t *testing.T) {
+	t.Parallel()
+	importFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "b/b.proto")
+	importFileDescriptorProto.Options = &descriptorpb.FileOptions{
+		GoPackage: proto.String("github.com/b/b"),
+	}
+	fileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "acme/weather/v1/weather.proto", "b/b.proto")
+	fileDescriptorProto.Package = proto.String("acme.weather_data.v1")
+	fileDescriptorProto.Options = &descriptorpb.FileOptions{
+		GoPackage:   proto.String("github.com/acme/weather"),
+		JavaPackage: proto.String("com.acme.weather.v1"),
+	}
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, importFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, fileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+	javaMultipleFiles := true
+	newImage, err := applyManagedConfig(
+		&ManagedConfig{
+			GoPackagePrefix:   "github.com/acme/gen/go",
+			JavaMultipleFiles: &javaMultipleFiles,
+			CsharpNamespace:   true,
+		},
+		image,
+	)
+	require.NoError(t, err)
+	assert.True(
+		t,
+		proto.Equal(
+			&descriptorpb.FileOptions{
+				GoPackage: proto.String("github.com/b/b"),
+			},
+			newImage.GetFile("b/b.proto").Proto().GetOptions(),
+		),
+	)
+	assert.True(
+		t,
+		proto.Equal(
+			&descriptorpb.FileOptions{
+				GoPackage:         proto.String("github.com/acme/gen/go/acme/weather/v1;weatherv1"),
+				JavaPackage:       proto.String("com.acme.weather.v1"),
+				JavaMultipleFiles: proto.Bool(true),
+				CsharpNamespace:   proto.String("Acme.WeatherData.V1"),
+			},
+			newImage.GetFile("acme/weather/v1/weather.proto").Proto().GetOptions(),
+		),
+	)
+	// the Image is not modified
+	assert.Equal(t, "github.com/acme/weather", image.GetFile("acme/weather/v1/weather.proto").Proto().GetOptions().GetGoPackage())
+}
+
+func TestGetManagedGoPackage(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "github.com/acme/gen/go/acme/weather;weather", getManagedGoPackage("github.com/acme/gen/go", "acme/weather/weather.proto"))
+	assert.Equal(t, "github.com/acme/gen/go/acme/v1beta1;acmev1beta1", getManagedGoPackage("github.com/acme/gen/go", "acme/v1beta1/a.proto"))
+	assert.Equal(t, "github.com/acme/gen/go;go", getManagedGoPackage("github.com/acme/gen/go", "a.proto"))
+	assert.Equal(t, "v1;v1", getManagedGoPackage("v1", "a.proto"))
+	assert.Equal(t, "gen/acme-weather;acme_weather", getManagedGoPackage("gen", "acme-weather/a.proto"))
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buflsp implements a language server for Protobuf files.
+package buflsp
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/zap"
+)
+
+// Server is a language server.
+//
+// The server publishes the compile errors and lint failures of the files of
+// the workspace as diagnostics, and supports going to the definition of the
+// types referenced by fields, extensions, and methods.
+//
+// The workspace is built from the files on disk, so diagnostics are updated
+// when files are opened and saved.
+type Server interface {
+	// Serve reads requests from the reader and writes responses to the writer
+	// until the client sends exit, the reader is closed, or the context is done.
+	Serve(ctx context.Context, reader io.Reader, writer io.Writer) error
+}
+
+// NewServer returns a new Server.
+//
+// The container is used to read the workspace with the EnvReader.
+func NewServer(
+	logger *zap.Logger,
+	container app.EnvStdinContainer,
+	envReader bufwire.EnvReader,
+	lintHandler buflint.Handler,
+	options ...ServerOption,
+) Server {
+	return newServer(
+		logger,
+		container,
+		envReader,
+		lintHandler,
+		options...,
+	)
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithConfigOverride returns a new ServerOption that uses the config
+// file or data instead of the buf.yaml of the workspace.
+func ServerWithConfigOverride(configOverride string) ServerOption {
+	return func(server *server) {
+		server.configOverride = configOverride
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+
+	// https://www.jsonrpc.org/specification#error_object
+	jsonrpcParseErrorCode     = -32700
+	jsonrpcInvalidParamsCode  = -32602
+	jsonrpcMethodNotFoundCode = -32601
+	jsonrpcInternalErrorCode  = -32603
+)
+
+// jsonrpcMessage is a request, notification, or response.
+//
+// Notifications have no ID.
+type jsonrpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newJSONRPCError(code int, message string) *jsonrpcError {
+	return &jsonrpcError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// jsonrpcNullResult is used as the Result of responses that have a null result,
+// as a nil Result is omitted.
+var jsonrpcNullResult = json.RawMessage("null")
+
+// readJSONRPCMessage reads a message with the base protocol of the
+// language server protocol, that is a Content-Length header followed
+// by the JSON content.
+//
+// https://microsoft.github.io/language-server-protocol/specifications/specification-current/#baseProtocol
+func readJSONRPCMessage(reader *bufio.Reader) (*jsonrpcMessage, error) {
+	contentLength := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		split := strings.SplitN(line, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("invalid header: %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(split[0]), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(split[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return nil, errors.New("no Content-Length header")
+	}
+	data := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	message := &jsonrpcMessage{}
+	if err := json.Unmarshal(data, message); err != nil {
+		return nil, newJSONRPCParseError(err)
+	}
+	return message, nil
+}
+
+func writeJSONRPCMessage(writer io.Writer, message *jsonrpcMessage) error {
+	message.JSONRPC = jsonrpcVersion
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// jsonrpcParseError is returned by readJSONRPCMessage if the content was read
+// but is not valid JSON, in which case the next message can still be read.
+type jsonrpcParseError struct {
+	err error
+}
+
+func newJSONRPCParseError(err error) *jsonrpcParseError {
+	return &jsonrpcParseError{
+		err: err,
+	}
+}
+
+func (e *jsonrpcParseError) Error() string {
+	return e.err.Error()
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+// The subset of the language server protocol that the server implements.
+//
+// https://microsoft.github.io/language-server-protocol/specifications/specification-current
+
+const (
+	diagnosticSeverityError   = 1
+	diagnosticSeverityWarning = 2
+
+	textDocumentSyncKindNone = 0
+)
+
+type initializeParams struct {
+	RootURI  string `json:"rootUri,omitempty"`
+	RootPath string `json:"rootPath,omitempty"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   *serverInfo        `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync   *textDocumentSyncOptions `json:"textDocumentSync,omitempty"`
+	DefinitionProvider bool                     `json:"definitionProvider,omitempty"`
+}
+
+type textDocumentSyncOptions struct {
+	OpenClose bool         `json:"openClose"`
+	Change    int          `json:"change"`
+	Save      *saveOptions `json:"save,omitempty"`
+}
+
+type saveOptions struct {
+	IncludeText bool `json:"includeText"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+// position is zero-based.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// rangeValue is a range, the end is exclusive.
+type rangeValue struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string     `json:"uri"`
+	Range rangeValue `json:"range"`
+}
+
+type diagnostic struct {
+	Range    rangeValue `json:"range"`
+	Severity int        `json:"severity,omitempty"`
+	Code     string     `json:"code,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Message  string     `json:"message"`
+}
+
+type showMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
@@ -0,0 +1,577 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoreutil"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	serverName       = "buf"
+	diagnosticSource = "buf"
+
+	// https://microsoft.github.io/language-server-protocol/specifications/specification-current/#responseMessage
+	jsonrpcServerNotInitializedCode = -32002
+
+	// https://microsoft.github.io/language-server-protocol/specifications/specification-current/#messageType
+	messageTypeError = 1
+
+	// the field numbers of the references to other types within
+	// FieldDescriptorProto and MethodDescriptorProto
+	fieldExtendeeFieldNumber   = 2
+	fieldTypeNameFieldNumber   = 6
+	methodInputTypeFieldNumber = 2
+	methodOutputTypeNumber     = 3
+)
+
+var (
+	fieldDescriptorProtoFullName  = (&descriptorpb.FieldDescriptorProto{}).ProtoReflect().Descriptor().FullName()
+	methodDescriptorProtoFullName = (&descriptorpb.MethodDescriptorProto{}).ProtoReflect().Descriptor().FullName()
+)
+
+type server struct {
+	logger         *zap.Logger
+	container      app.EnvStdinContainer
+	envReader      bufwire.EnvReader
+	lintHandler    buflint.Handler
+	configOverride string
+
+	// rootDirPath is empty until the server is initialized.
+	rootDirPath string
+	// image is the Image of the last successful build, and is used
+	// for definitions until the next successful build.
+	image                     bufcore.Image
+	fullNameToNamedDescriptor map[string]protosource.NamedDescriptor
+	// diagnosticURIs are the URIs diagnostics were last published for,
+	// so that their diagnostics can be cleared once they are fixed.
+	diagnosticURIs map[string]struct{}
+}
+
+func newServer(
+	logger *zap.Logger,
+	container app.EnvStdinContainer,
+	envReader bufwire.EnvReader,
+	lintHandler buflint.Handler,
+	options ...ServerOption,
+) *server {
+	server := &server{
+		logger:         logger.Named("buflsp"),
+		container:      container,
+		envReader:      envReader,
+		lintHandler:    lintHandler,
+		diagnosticURIs: make(map[string]struct{}),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	return server
+}
+
+func (s *server) Serve(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	bufReader := bufio.NewReader(reader)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		message, err := readJSONRPCMessage(bufReader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			var parseError *jsonrpcParseError
+			if errors.As(err, &parseError) {
+				if err := writeJSONRPCMessage(
+					writer,
+					&jsonrpcMessage{
+						ID:    jsonrpcNullResult,
+						Error: newJSONRPCError(jsonrpcParseErrorCode, parseError.Error()),
+					},
+				); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+		if message.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(ctx, writer, message); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(ctx context.Context, writer io.Writer, message *jsonrpcMessage) error {
+	s.logger.Debug("handle", zap.String("method", message.Method))
+	result, responseErr, err := s.handleMethod(ctx, writer, message.Method, message.Params)
+	if err != nil {
+		return err
+	}
+	// notifications have no response
+	if len(message.ID) == 0 {
+		if responseErr != nil {
+			s.logger.Debug("notification_error", zap.String("method", message.Method), zap.String("error", responseErr.Message))
+		}
+		return nil
+	}
+	response := &jsonrpcMessage{
+		ID:     message.ID,
+		Result: result,
+		Error:  responseErr,
+	}
+	if result == nil && responseErr == nil {
+		response.Result = jsonrpcNullResult
+	}
+	return writeJSONRPCMessage(writer, response)
+}
+
+// handleMethod returns the result or the error to respond with, or an error
+// if the server cannot continue.
+func (s *server) handleMethod(
+	ctx context.Context,
+	writer io.Writer,
+	method string,
+	params json.RawMessage,
+) (interface{}, *jsonrpcError, error) {
+	if method == "initialize" {
+		result, responseErr := s.initialize(params)
+		return result, responseErr, nil
+	}
+	if s.rootDirPath == "" {
+		return nil, newJSONRPCError(jsonrpcServerNotInitializedCode, "server not initialized"), nil
+	}
+	switch method {
+	case "initialized", "textDocument/didOpen", "textDocument/didSave":
+		return nil, nil, s.checkAndPublish(ctx, writer)
+	case "textDocument/definition":
+		result, responseErr := s.definition(params)
+		return result, responseErr, nil
+	case "shutdown", "textDocument/didChange", "textDocument/didClose":
+		return nil, nil, nil
+	default:
+		return nil, newJSONRPCError(jsonrpcMethodNotFoundCode, fmt.Sprintf("method not found: %q", method)), nil
+	}
+}
+
+func (s *server) initialize(params json.RawMessage) (interface{}, *jsonrpcError) {
+	initializeParams := &initializeParams{}
+	if err := json.Unmarshal(params, initializeParams); err != nil {
+		return nil, newJSONRPCError(jsonrpcInvalidParamsCode, err.Error())
+	}
+	rootDirPath := initializeParams.RootPath
+	if initializeParams.RootURI != "" {
+		var err error
+		rootDirPath, err = uriToPath(initializeParams.RootURI)
+		if err != nil {
+			return nil, newJSONRPCError(jsonrpcInvalidParamsCode, err.Error())
+		}
+	}
+	if rootDirPath == "" {
+		return nil, newJSONRPCError(jsonrpcInvalidParamsCode, "no workspace root")
+	}
+	absRootDirPath, err := filepath.Abs(rootDirPath)
+	if err != nil {
+		return nil, newJSONRPCError(jsonrpcInternalErrorCode, err.Error())
+	}
+	s.rootDirPath = absRootDirPath
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync: &textDocumentSyncOptions{
+				OpenClose: true,
+				Change:    textDocumentSyncKindNone,
+				Save:      &saveOptions{},
+			},
+			DefinitionProvider: true,
+		},
+		ServerInfo: &serverInfo{
+			Name: serverName,
+		},
+	}, nil
+}
+
+// checkAndPublish builds and lints the workspace and publishes the diagnostics.
+//
+// If the workspace cannot be read, for example if the configuration is invalid,
+// the error is shown to the user and the previous diagnostics are kept.
+func (s *server) checkAndPublish(ctx context.Context, writer io.Writer) error {
+	uriToDiagnostics, err := s.check(ctx)
+	if err != nil {
+		return writeJSONRPCMessage(
+			writer,
+			&jsonrpcMessage{
+				Method: "window/showMessage",
+				Params: mustMarshalJSON(
+					&showMessageParams{
+						Type:    messageTypeError,
+						Message: err.Error(),
+					},
+				),
+			},
+		)
+	}
+	uris := make([]string, 0, len(uriToDiagnostics))
+	for uri := range uriToDiagnostics {
+		uris = append(uris, uri)
+	}
+	for uri := range s.diagnosticURIs {
+		if _, ok := uriToDiagnostics[uri]; !ok {
+			uris = append(uris, uri)
+		}
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		diagnostics := uriToDiagnostics[uri]
+		if diagnostics == nil {
+			// clients expect an empty array to clear the diagnostics
+			diagnostics = []diagnostic{}
+		}
+		if err := writeJSONRPCMessage(
+			writer,
+			&jsonrpcMessage{
+				Method: "textDocument/publishDiagnostics",
+				Params: mustMarshalJSON(
+					&publishDiagnosticsParams{
+						URI:         uri,
+						Diagnostics: diagnostics,
+					},
+				),
+			},
+		); err != nil {
+			return err
+		}
+	}
+	s.diagnosticURIs = make(map[string]struct{}, len(uriToDiagnostics))
+	for uri := range uriToDiagnostics {
+		s.diagnosticURIs[uri] = struct{}{}
+	}
+	return nil
+}
+
+func (s *server) check(ctx context.Context) (map[string][]diagnostic, error) {
+	env, fileAnnotations, err := s.envReader.GetEnv(
+		ctx,
+		s.container,
+		s.rootDirPath,
+		s.configOverride,
+		nil,
+		false,
+		false, // we need source info for lint and definitions
+	)
+	if err != nil {
+		return nil, err
+	}
+	uriToDiagnostics := make(map[string][]diagnostic)
+	if len(fileAnnotations) > 0 {
+		s.addDiagnostics(uriToDiagnostics, fileAnnotations, diagnosticSeverityError)
+		return uriToDiagnostics, nil
+	}
+	s.setImage(ctx, env.Image())
+	fileAnnotations, err = s.lintHandler.Check(
+		ctx,
+		env.Config().Lint,
+		bufcore.ImageWithoutImports(env.Image()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	fileAnnotations, warningFileAnnotations := buflint.SplitWarnings(env.Config().Lint, fileAnnotations)
+	s.addDiagnostics(uriToDiagnostics, fileAnnotations, diagnosticSeverityError)
+	s.addDiagnostics(uriToDiagnostics, warningFileAnnotations, diagnosticSeverityWarning)
+	return uriToDiagnostics, nil
+}
+
+func (s *server) addDiagnostics(
+	uriToDiagnostics map[string][]diagnostic,
+	fileAnnotations []bufanalysis.FileAnnotation,
+	severity int,
+) {
+	for _, fileAnnotation := range fileAnnotations {
+		fileInfo := fileAnnotation.FileInfo()
+		if fileInfo == nil {
+			s.logger.Debug("file_annotation_without_file", zap.String("message", fileAnnotation.Message()))
+			continue
+		}
+		uri, err := pathToURI(fileInfo.ExternalPath())
+		if err != nil {
+			s.logger.Debug("file_annotation_uri", zap.Error(err))
+			continue
+		}
+		uriToDiagnostics[uri] = append(
+			uriToDiagnostics[uri],
+			diagnostic{
+				Range: newRange(
+					fileAnnotation.StartLine(),
+					fileAnnotation.StartColumn(),
+					fileAnnotation.EndLine(),
+					fileAnnotation.EndColumn(),
+				),
+				Severity: severity,
+				Code:     fileAnnotation.Type(),
+				Source:   diagnosticSource,
+				Message:  fileAnnotation.Message(),
+			},
+		)
+	}
+}
+
+func (s *server) setImage(ctx context.Context, image bufcore.Image) {
+	s.image = image
+	s.fullNameToNamedDescriptor = nil
+	files, err := protosource.NewFilesUnstable(ctx, bufcoreutil.NewInputFiles(image.Files())...)
+	if err != nil {
+		s.logger.Debug("definitions_unavailable", zap.Error(err))
+		return
+	}
+	fullNameToNamedDescriptor, err := protosource.FullNameToNamedDescriptor(files...)
+	if err != nil {
+		s.logger.Debug("definitions_unavailable", zap.Error(err))
+		return
+	}
+	s.fullNameToNamedDescriptor = fullNameToNamedDescriptor
+}
+
+// definition returns the location of the definition of the type referenced
+// at the position, or nil if there is no reference at the position.
+func (s *server) definition(params json.RawMessage) (interface{}, *jsonrpcError) {
+	positionParams := &textDocumentPositionParams{}
+	if err := json.Unmarshal(params, positionParams); err != nil {
+		return nil, newJSONRPCError(jsonrpcInvalidParamsCode, err.Error())
+	}
+	if s.image == nil || s.fullNameToNamedDescriptor == nil {
+		return nil, nil
+	}
+	path, err := uriToPath(positionParams.TextDocument.URI)
+	if err != nil {
+		return nil, newJSONRPCError(jsonrpcInvalidParamsCode, err.Error())
+	}
+	imageFile := s.getImageFileForPath(path)
+	if imageFile == nil {
+		return nil, nil
+	}
+	referenceName := getReferenceName(
+		imageFile.Proto(),
+		positionParams.Position.Line,
+		positionParams.Position.Character,
+	)
+	if referenceName == "" {
+		return nil, nil
+	}
+	namedDescriptor, ok := s.fullNameToNamedDescriptor[strings.TrimPrefix(referenceName, ".")]
+	if !ok {
+		return nil, nil
+	}
+	namedDescriptorLocation := namedDescriptor.NameLocation()
+	if namedDescriptorLocation == nil {
+		return nil, nil
+	}
+	namedDescriptorImageFile := s.image.GetFile(namedDescriptor.File().Path())
+	if namedDescriptorImageFile == nil {
+		return nil, nil
+	}
+	uri, err := pathToURI(namedDescriptorImageFile.ExternalPath())
+	if err != nil {
+		return nil, newJSONRPCError(jsonrpcInternalErrorCode, err.Error())
+	}
+	return []location{
+		{
+			URI: uri,
+			Range: newRange(
+				namedDescriptorLocation.StartLine(),
+				namedDescriptorLocation.StartColumn(),
+				namedDescriptorLocation.EndLine(),
+				namedDescriptorLocation.EndColumn(),
+			),
+		},
+	}, nil
+}
+
+func (s *server) getImageFileForPath(path string) bufcore.ImageFile {
+	for _, imageFile := range s.image.Files() {
+		externalPath, err := filepath.Abs(imageFile.ExternalPath())
+		if err != nil {
+			continue
+		}
+		if externalPath == path {
+			return imageFile
+		}
+	}
+	return nil
+}
+
+// getReferenceName returns the name of the type referenced at the zero-based
+// line and character, or empty if there is no reference at the position.
+//
+// References are the type names and extendees of fields, and the input and
+// output types of methods.
+func getReferenceName(fileDescriptorProto *descriptorpb.FileDescriptorProto, line int, character int) string {
+	for _, sourceCodeInfoLocation := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		if !spanContains(sourceCodeInfoLocation.GetSpan(), line, character) {
+			continue
+		}
+		if referenceName := getReferenceNameForPath(fileDescriptorProto, sourceCodeInfoLocation.GetPath()); referenceName != "" {
+			return referenceName
+		}
+	}
+	return ""
+}
+
+// getReferenceNameForPath returns the value of the field at the SourceCodeInfo
+// path if it is a reference to a type, and empty otherwise.
+func getReferenceNameForPath(fileDescriptorProto *descriptorpb.FileDescriptorProto, path []int32) string {
+	// paths to fields of messages are pairs of field numbers and indexes
+	// followed by the field number
+	if len(path)%2 == 0 {
+		return ""
+	}
+	message := fileDescriptorProto.ProtoReflect()
+	for i := 0; i+1 < len(path); i += 2 {
+		fieldDescriptor := message.Descriptor().Fields().ByNumber(protoreflect.FieldNumber(path[i]))
+		if fieldDescriptor == nil || !fieldDescriptor.IsList() || fieldDescriptor.Message() == nil {
+			return ""
+		}
+		list := message.Get(fieldDescriptor).List()
+		index := int(path[i+1])
+		if index < 0 || index >= list.Len() {
+			return ""
+		}
+		message = list.Get(index).Message()
+	}
+	fieldNumber := path[len(path)-1]
+	switch message.Descriptor().FullName() {
+	case fieldDescriptorProtoFullName:
+		if fieldNumber != fieldExtendeeFieldNumber && fieldNumber != fieldTypeNameFieldNumber {
+			return ""
+		}
+	case methodDescriptorProtoFullName:
+		if fieldNumber != methodInputTypeFieldNumber && fieldNumber != methodOutputTypeNumber {
+			return ""
+		}
+	default:
+		return ""
+	}
+	fieldDescriptor := message.Descriptor().Fields().ByNumber(protoreflect.FieldNumber(fieldNumber))
+	if fieldDescriptor == nil || fieldDescriptor.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return message.Get(fieldDescriptor).String()
+}
+
+// spanContains returns true if the zero-based line and character are within
+// the SourceCodeInfo span, including the end of the span so that a position
+// at the end of a name is within the name.
+func spanContains(span []int32, line int, character int) bool {
+	var startLine, startCharacter, endLine, endCharacter int
+	switch len(span) {
+	case 3:
+		startLine, startCharacter, endLine, endCharacter = int(span[0]), int(span[1]), int(span[0]), int(span[2])
+	case 4:
+		startLine, startCharacter, endLine, endCharacter = int(span[0]), int(span[1]), int(span[2]), int(span[3])
+	default:
+		return false
+	}
+	if line < startLine || (line == startLine && character < startCharacter) {
+		return false
+	}
+	if line > endLine || (line == endLine && character > endCharacter) {
+		return false
+	}
+	return true
+}
+
+// newRange returns a new zero-based range for the one-based lines and columns
+// of FileAnnotations and Locations, where a zero value is unknown.
+func newRange(startLine int, startColumn int, endLine int, endColumn int) rangeValue {
+	start := newPosition(startLine, startColumn)
+	if endLine == 0 {
+		return rangeValue{
+			Start: start,
+			End:   start,
+		}
+	}
+	return rangeValue{
+		Start: start,
+		End:   newPosition(endLine, endColumn),
+	}
+}
+
+func newPosition(line int, column int) position {
+	var p position
+	if line > 0 {
+		p.Line = line - 1
+	}
+	if column > 0 {
+		p.Character = column - 1
+	}
+	return p
+}
+
+func pathToURI(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	uriPath := filepath.ToSlash(absPath)
+	// windows paths such as C:/foo must start with a slash
+	if !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
+	}
+	return (&url.URL{Scheme: "file", Path: uriPath}).String(), nil
+}
+
+func uriToPath(uri string) (string, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsedURI.Scheme != "file" {
+		return "", fmt.Errorf("unsupported uri: %q", uri)
+	}
+	path := parsedURI.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.Abs(filepath.FromSlash(path))
+}
+
+func mustMarshalJSON(value interface{}) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		// all values are our own structs, so this should never happen
+		panic(err)
+	}
+	return data
+}
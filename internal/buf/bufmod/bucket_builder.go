@@ -51,6 +51,8 @@ func (b *bucketBuilder) BuildForBucket(
 		config,
 		buildOptions.paths,
 		buildOptions.pathsAllowNotExistOnWalk,
+		buildOptions.excludePaths,
+		buildOptions.dependencies,
 	)
 }
 
@@ -60,42 +62,10 @@ func (b *bucketBuilder) buildForBucket(
 	config *Config,
 	bucketRelPaths []string,
 	bucketRelPathsAllowNotExistOnWalk bool,
+	excludeBucketRelPaths []string,
+	dependencies []*dependency,
 ) (bufcore.Module, error) {
-	roots := make([]string, 0, len(config.RootToExcludes))
-	var rootBuckets []storage.ReadBucket
-	for root, excludes := range config.RootToExcludes {
-		roots = append(roots, root)
-		mappers := []storage.Mapper{
-			// need to do match extension here
-			// https://github.com/bufbuild/buf/issues/113
-			storage.MatchPathExt(".proto"),
-			storage.MapOnPrefix(root),
-		}
-		if len(excludes) != 0 {
-			var notOrMatchers []storage.Matcher
-			for _, exclude := range excludes {
-				notOrMatchers = append(
-					notOrMatchers,
-					storage.MatchPathContained(exclude),
-				)
-			}
-			mappers = append(
-				mappers,
-				storage.MatchNot(
-					storage.MatchOr(
-						notOrMatchers...,
-					),
-				),
-			)
-		}
-		rootBuckets = append(
-			rootBuckets,
-			storage.Map(
-				readBucket,
-				mappers...,
-			),
-		)
-	}
+	roots := config.Roots()
 	moduleOptions, err := getModuleOptions(
 		roots,
 		bucketRelPaths,
@@ -105,5 +75,83 @@ func (b *bucketBuilder) buildForBucket(
 	if err != nil {
 		return nil, err
 	}
-	return bufcore.NewModule(storage.Multi(rootBuckets...), moduleOptions...)
+	if len(excludeBucketRelPaths) > 0 {
+		excludeBucketRelPaths, err = normalizeAndCheckPaths(
+			excludeBucketRelPaths,
+			"exclude path",
+			normalpath.Relative,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		excludeTargetPaths, err := getExcludeTargetPaths(
+			ctx,
+			roots,
+			func(root string) (storage.ReadBucket, error) {
+				return getRootReadBucket(readBucket, root, config.RootToExcludes[root]), nil
+			},
+			excludeBucketRelPaths,
+			normalpath.Relative,
+		)
+		if err != nil {
+			return nil, err
+		}
+		moduleOptions = append(moduleOptions, bufcore.ModuleWithExcludeTargetPaths(excludeTargetPaths...))
+	}
+	if len(dependencies) > 0 {
+		dependencyReadBuckets := make([]storage.ReadBucket, len(dependencies))
+		for i, dependency := range dependencies {
+			dependencyReadBuckets[i] = getRootsReadBucket(dependency.readBucket, dependency.config)
+		}
+		moduleOptions = append(
+			moduleOptions,
+			bufcore.ModuleWithImports(storage.Multi(dependencyReadBuckets...)),
+		)
+	}
+	return bufcore.NewModule(getRootsReadBucket(readBucket, config), moduleOptions...)
+}
+
+// getRootsReadBucket returns a ReadBucket of the Protobuf files within the
+// roots of the config, with paths relative to the roots.
+func getRootsReadBucket(readBucket storage.ReadBucket, config *Config) storage.ReadBucket {
+	var rootBuckets []storage.ReadBucket
+	for root, excludes := range config.RootToExcludes {
+		rootBuckets = append(rootBuckets, getRootReadBucket(readBucket, root, excludes))
+	}
+	return storage.Multi(rootBuckets...)
+}
+
+// getRootReadBucket returns a ReadBucket of the Protobuf files within the
+// root, except for the excludes and vendored dependencies, with paths
+// relative to the root.
+func getRootReadBucket(readBucket storage.ReadBucket, root string, excludes []string) storage.ReadBucket {
+	mappers := []storage.Mapper{
+		// need to do match extension here
+		// https://github.com/bufbuild/buf/issues/113
+		storage.MatchPathExt(".proto"),
+		storage.MatchNot(storage.MatchPathContained(VendorDirPath)),
+		storage.MapOnPrefix(root),
+	}
+	if len(excludes) != 0 {
+		var notOrMatchers []storage.Matcher
+		for _, exclude := range excludes {
+			notOrMatchers = append(
+				notOrMatchers,
+				storage.MatchPathContained(exclude),
+			)
+		}
+		mappers = append(
+			mappers,
+			storage.MatchNot(
+				storage.MatchOr(
+					notOrMatchers...,
+				),
+			),
+		)
+	}
+	return storage.Map(
+		readBucket,
+		mappers...,
+	)
 }
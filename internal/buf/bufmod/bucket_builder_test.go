@@ -111,6 +111,69 @@ func TestBucketGetFileInfos4(t *testing.T) {
 	)
 }
 
+func TestBucketGetFileInfosExcludePaths(t *testing.T) {
+	t.Parallel()
+	readWriteBucket, err := storageos.NewReadWriteBucket("testdata/1")
+	require.NoError(t, err)
+	config, err := NewConfig(
+		ExternalConfig{
+			Roots: []string{
+				"proto",
+			},
+		},
+	)
+	require.NoError(t, err)
+	module, err := NewBucketBuilder(zap.NewNop()).BuildForBucket(
+		context.Background(),
+		readWriteBucket,
+		config,
+		WithExcludePaths("proto/a/c", "proto/d/1.proto"),
+	)
+	require.NoError(t, err)
+	fileInfos, err := module.TargetFileInfos(context.Background())
+	assert.NoError(t, err)
+	bufcoretesting.AssertFileInfosEqual(
+		t,
+		[]bufcore.FileInfo{
+			bufcoretesting.NewFileInfo(t, "a/1.proto", "testdata/1/proto/a/1.proto", false),
+			bufcoretesting.NewFileInfo(t, "a/2.proto", "testdata/1/proto/a/2.proto", false),
+			bufcoretesting.NewFileInfo(t, "a/3.proto", "testdata/1/proto/a/3.proto", false),
+			bufcoretesting.NewFileInfo(t, "b/1.proto", "testdata/1/proto/b/1.proto", false),
+			bufcoretesting.NewFileInfo(t, "b/2.proto", "testdata/1/proto/b/2.proto", false),
+			bufcoretesting.NewFileInfo(t, "b/3.proto", "testdata/1/proto/b/3.proto", false),
+			bufcoretesting.NewFileInfo(t, "d/2.proto", "testdata/1/proto/d/2.proto", false),
+			bufcoretesting.NewFileInfo(t, "d/3.proto", "testdata/1/proto/d/3.proto", false),
+		},
+		fileInfos,
+	)
+	// excluded files can still be imported
+	_, err = module.GetFileInfo(context.Background(), "a/c/1.proto")
+	assert.NoError(t, err)
+
+	// excluding the root excludes all files
+	module, err = NewBucketBuilder(zap.NewNop()).BuildForBucket(
+		context.Background(),
+		readWriteBucket,
+		config,
+		WithExcludePaths("proto"),
+	)
+	require.NoError(t, err)
+	_, err = module.TargetFileInfos(context.Background())
+	assert.Equal(t, bufcore.ErrNoTargetFiles, err)
+}
+
+func TestBucketGetFileInfosVendor(t *testing.T) {
+	testBucketGetFileInfos(
+		t,
+		"testdata/4",
+		[]string{
+			".",
+		},
+		nil,
+		bufcoretesting.NewFileInfo(t, "a.proto", "testdata/4/a.proto", false),
+	)
+}
+
 func TestBucketGetAllFileInfosError1(t *testing.T) {
 	testBucketGetAllFileInfosError(
 		t,
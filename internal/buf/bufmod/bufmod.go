@@ -17,12 +17,26 @@ package bufmod
 import (
 	"context"
 	"sort"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
 
+const (
+	// LockFilePath is the default lock file path within a bucket.
+	LockFilePath = "buf.lock"
+	// VendorDirPath is the directory within a bucket that dependencies are
+	// vendored to.
+	//
+	// The Protobuf files within this directory are never part of the module.
+	VendorDirPath = "buf.vendor"
+	// V1Beta1Version is the string used to identify the v1beta1 version of the lock file.
+	V1Beta1Version = "v1beta1"
+)
+
 // BucketBuilder builds modules for buckets.
 type BucketBuilder interface {
 	// BuildForBucket builds a module for the given bucket.
@@ -85,6 +99,148 @@ func WithPathsAllowNotExistOnWalk() BuildOption {
 	}
 }
 
+// WithExcludePaths returns a new BuildOption that excludes the files equal to
+// or contained within the given file or directory paths from the files to build.
+//
+// Excluded files can still be imported by the files that are built.
+// These paths must be relative to the bucket or include directory paths.
+// These paths will be normalized.
+// Multiple calls to this option will override previous calls.
+func WithExcludePaths(excludePaths ...string) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.excludePaths = excludePaths
+	}
+}
+
+// WithDependency returns a new BuildOption that adds the Protobuf files of the
+// module in the bucket with the given Config as imports.
+//
+// Multiple calls to this option will add multiple dependencies.
+// The files of the dependencies cannot overlap with each other or with the
+// files of the module being built.
+func WithDependency(readBucket storage.ReadBucket, config *Config) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.dependencies = append(
+			buildOptions.dependencies,
+			&dependency{
+				readBucket: readBucket,
+				config:     config,
+			},
+		)
+	}
+}
+
+// Digest returns the digest of the Protobuf files of the module in the bucket
+// with the given Config.
+//
+// The digest is of the form sha256:HEX, and only changes if the paths relative
+// to the roots or the contents of the Protobuf files change.
+func Digest(ctx context.Context, readBucket storage.ReadBucket, config *Config) (string, error) {
+	return digest(ctx, readBucket, config)
+}
+
+// VendorPath returns the directory within a bucket that the dependency with
+// the given digest is vendored to.
+//
+// This is VendorDirPath joined with the hex of the digest.
+func VendorPath(digest string) string {
+	return normalpath.Join(VendorDirPath, strings.TrimPrefix(digest, digestPrefix))
+}
+
+// Lock pins the dependencies of a module.
+type Lock struct {
+	// Dependencies are the locked dependencies, sorted by remote.
+	Dependencies []*LockedDependency
+}
+
+// GetDependency returns the locked dependency for the remote, or nil if the
+// remote is not locked.
+func (l *Lock) GetDependency(remote string) *LockedDependency {
+	for _, lockedDependency := range l.Dependencies {
+		if lockedDependency.Remote == remote {
+			return lockedDependency
+		}
+	}
+	return nil
+}
+
+// LockedDependency is a dependency pinned to a digest.
+type LockedDependency struct {
+	// Remote is the remote of the dependency, as declared in the config.
+	Remote string
+	// Digest is the digest of the dependency, see Digest.
+	Digest string
+}
+
+// GetLockForBucket gets the Lock from the buf.lock file at the root of the
+// bucket.
+//
+// If the bucket has no buf.lock file, this returns nil.
+func GetLockForBucket(ctx context.Context, readBucket storage.ReadBucket) (*Lock, error) {
+	return getLockForBucket(ctx, readBucket)
+}
+
+// MarshalLock marshals the Lock to the YAML data of a buf.lock file.
+func MarshalLock(lock *Lock) ([]byte, error) {
+	return marshalLock(lock)
+}
+
+// Graph is the dependency graph of a module.
+type Graph struct {
+	// Edges are the edges of the graph, sorted by From and then To.
+	Edges []*GraphEdge
+}
+
+// GraphEdge is an edge from a module to a module that it depends on.
+type GraphEdge struct {
+	// From is the remote of the module, or empty for the root module.
+	From string
+	// To is the remote of the dependency, as declared in the config of From.
+	To string
+	// Digest is the digest that the buf.lock file of From pins To to, or
+	// empty if To is not pinned.
+	Digest string
+}
+
+// GraphConflict is a module that a Graph depends on at more than one version.
+type GraphConflict struct {
+	// Name is the name of the module, see ModuleName.
+	Name string
+	// Edges are the edges to the module, sorted by From and then To.
+	Edges []*GraphEdge
+}
+
+// GetGraphConflicts returns the modules that the Graph depends on at more
+// than one version, sorted by name.
+//
+// Edges to the same module conflict if their remotes differ, for example in
+// their git ref or OCI tag, or if they pin the same remote to different
+// digests. Edges that are not pinned only conflict by remote.
+func GetGraphConflicts(graph *Graph) []*GraphConflict {
+	return getGraphConflicts(graph)
+}
+
+// ModuleName returns the name of the module of the remote, which is the
+// remote without its version.
+//
+// This removes the options after #, such as the git ref, and the tag or
+// digest of an OCI reference.
+func ModuleName(remote string) string {
+	return moduleName(remote)
+}
+
+// ExternalLockV1Beta1 is an external lock file.
+type ExternalLockV1Beta1 struct {
+	Version string                            `json:"version,omitempty" yaml:"version,omitempty"`
+	Deps    []ExternalLockedDependencyV1Beta1 `json:"deps,omitempty" yaml:"deps,omitempty"`
+}
+
+// ExternalLockedDependencyV1Beta1 is an external locked dependency.
+type ExternalLockedDependencyV1Beta1 struct {
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
 // Config is a configuration for build.
 type Config struct {
 	// RootToExcludes contains a map from root to the excludes for that root.
@@ -0,0 +1,146 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmod
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/encoding"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"go.uber.org/multierr"
+)
+
+const digestPrefix = "sha256:"
+
+func digest(ctx context.Context, readBucket storage.ReadBucket, config *Config) (string, error) {
+	rootsReadBucket := getRootsReadBucket(readBucket, config)
+	paths, err := storage.AllPaths(ctx, rootsReadBucket, "")
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+	// the digest is of a listing of the digest and path of each file, in the
+	// same format as the output of sha256sum
+	hash := sha256.New()
+	for _, path := range paths {
+		fileHash := sha256.New()
+		if err := copyFile(ctx, rootsReadBucket, path, fileHash); err != nil {
+			return "", err
+		}
+		if _, err := fmt.Fprintf(hash, "%s  %s\n", hex.EncodeToString(fileHash.Sum(nil)), path); err != nil {
+			return "", err
+		}
+	}
+	return digestPrefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(ctx context.Context, readBucket storage.ReadBucket, path string, writer io.Writer) (retErr error) {
+	readObject, err := readBucket.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readObject.Close())
+	}()
+	_, err = io.Copy(writer, readObject)
+	return err
+}
+
+func getLockForBucket(ctx context.Context, readBucket storage.ReadBucket) (_ *Lock, retErr error) {
+	readObject, err := readBucket.Get(ctx, LockFilePath)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readObject.Close())
+	}()
+	data, err := ioutil.ReadAll(readObject)
+	if err != nil {
+		return nil, err
+	}
+	externalLock := ExternalLockV1Beta1{}
+	if err := encoding.UnmarshalYAMLStrict(data, &externalLock); err != nil {
+		return nil, fmt.Errorf("%s: %v", LockFilePath, err)
+	}
+	lock, err := newLock(externalLock)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", LockFilePath, err)
+	}
+	return lock, nil
+}
+
+func newLock(externalLock ExternalLockV1Beta1) (*Lock, error) {
+	if externalLock.Version != "" && externalLock.Version != V1Beta1Version {
+		return nil, fmt.Errorf("unknown version: %q, only %q is supported", externalLock.Version, V1Beta1Version)
+	}
+	lock := &Lock{
+		Dependencies: make([]*LockedDependency, 0, len(externalLock.Deps)),
+	}
+	for _, externalLockedDependency := range externalLock.Deps {
+		if externalLockedDependency.Remote == "" {
+			return nil, fmt.Errorf("dependency with digest %q has no remote", externalLockedDependency.Digest)
+		}
+		if lock.GetDependency(externalLockedDependency.Remote) != nil {
+			return nil, fmt.Errorf("duplicate dependency %s", externalLockedDependency.Remote)
+		}
+		if !strings.HasPrefix(externalLockedDependency.Digest, digestPrefix) {
+			return nil, fmt.Errorf("dependency %s has invalid digest %q", externalLockedDependency.Remote, externalLockedDependency.Digest)
+		}
+		lock.Dependencies = append(
+			lock.Dependencies,
+			&LockedDependency{
+				Remote: externalLockedDependency.Remote,
+				Digest: externalLockedDependency.Digest,
+			},
+		)
+	}
+	sort.Slice(
+		lock.Dependencies,
+		func(i int, j int) bool {
+			return lock.Dependencies[i].Remote < lock.Dependencies[j].Remote
+		},
+	)
+	return lock, nil
+}
+
+func marshalLock(lock *Lock) ([]byte, error) {
+	externalLock := ExternalLockV1Beta1{
+		Version: V1Beta1Version,
+	}
+	for _, lockedDependency := range lock.Dependencies {
+		externalLock.Deps = append(
+			externalLock.Deps,
+			ExternalLockedDependencyV1Beta1{
+				Remote: lockedDependency.Remote,
+				Digest: lockedDependency.Digest,
+			},
+		)
+	}
+	data, err := encoding.MarshalYAML(externalLock)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("# Generated by buf. DO NOT EDIT.\n"), data...), nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmod
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config, err := NewConfig(ExternalConfig{Roots: []string{"proto"}})
+	require.NoError(t, err)
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"proto/a/a.proto": []byte(`syntax = "proto3";`),
+			"README.md":       []byte("readme"),
+		},
+	)
+	require.NoError(t, err)
+	digest, err := Digest(ctx, readBucket, config)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(digest, "sha256:"), digest)
+
+	// files outside of the roots do not change the digest
+	readBucket, err = storagemem.NewReadBucket(
+		map[string][]byte{
+			"proto/a/a.proto": []byte(`syntax = "proto3";`),
+			"README.md":       []byte("changed"),
+		},
+	)
+	require.NoError(t, err)
+	unchangedDigest, err := Digest(ctx, readBucket, config)
+	require.NoError(t, err)
+	assert.Equal(t, digest, unchangedDigest)
+
+	readBucket, err = storagemem.NewReadBucket(
+		map[string][]byte{
+			"proto/a/a.proto": []byte(`syntax = "proto2";`),
+		},
+	)
+	require.NoError(t, err)
+	changedDigest, err := Digest(ctx, readBucket, config)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changedDigest)
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	t.Parallel()
+	lock := &Lock{
+		Dependencies: []*LockedDependency{
+			{
+				Remote: "https://github.com/acme/a.git",
+				Digest: "sha256:aaaa",
+			},
+			{
+				Remote: "https://github.com/acme/b.git#tag=v1.0.0",
+				Digest: "sha256:bbbb",
+			},
+		},
+	}
+	data, err := MarshalLock(lock)
+	require.NoError(t, err)
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			LockFilePath: data,
+		},
+	)
+	require.NoError(t, err)
+	actualLock, err := GetLockForBucket(context.Background(), readBucket)
+	require.NoError(t, err)
+	assert.Equal(t, lock, actualLock)
+	assert.Equal(t, "sha256:bbbb", actualLock.GetDependency("https://github.com/acme/b.git#tag=v1.0.0").Digest)
+	assert.Nil(t, actualLock.GetDependency("https://github.com/acme/c.git"))
+
+	readBucket, err = storagemem.NewReadBucket(
+		map[string][]byte{
+			LockFilePath: []byte("deps:\n  - remote: https://github.com/acme/a.git\n    digest: aaaa\n"),
+		},
+	)
+	require.NoError(t, err)
+	_, err = GetLockForBucket(context.Background(), readBucket)
+	assert.Error(t, err)
+}
+
+func TestBuildWithDependency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config, err := NewConfig(ExternalConfig{})
+	require.NoError(t, err)
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a/a.proto": []byte(`syntax = "proto3";`),
+		},
+	)
+	require.NoError(t, err)
+	dependencyConfig, err := NewConfig(ExternalConfig{Roots: []string{"proto"}})
+	require.NoError(t, err)
+	dependencyReadBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"proto/b/b.proto": []byte(`syntax = "proto3";`),
+		},
+	)
+	require.NoError(t, err)
+	module, err := NewBucketBuilder(zap.NewNop()).BuildForBucket(
+		ctx,
+		readBucket,
+		config,
+		WithDependency(dependencyReadBucket, dependencyConfig),
+	)
+	require.NoError(t, err)
+	targetFileInfos, err := module.TargetFileInfos(ctx)
+	require.NoError(t, err)
+	require.Len(t, targetFileInfos, 1)
+	assert.Equal(t, "a/a.proto", targetFileInfos[0].Path())
+	fileInfo, err := module.GetFileInfo(ctx, "b/b.proto")
+	require.NoError(t, err)
+	assert.True(t, fileInfo.IsImport())
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmod
+
+import (
+	"sort"
+	"strings"
+)
+
+const ociPrefix = "oci://"
+
+func getGraphConflicts(graph *Graph) []*GraphConflict {
+	nameToEdges := make(map[string][]*GraphEdge)
+	for _, edge := range graph.Edges {
+		name := moduleName(edge.To)
+		nameToEdges[name] = append(nameToEdges[name], edge)
+	}
+	var conflicts []*GraphConflict
+	for name, edges := range nameToEdges {
+		if !isConflict(edges) {
+			continue
+		}
+		conflicts = append(
+			conflicts,
+			&GraphConflict{
+				Name:  name,
+				Edges: edges,
+			},
+		)
+	}
+	sort.Slice(
+		conflicts,
+		func(i int, j int) bool {
+			return conflicts[i].Name < conflicts[j].Name
+		},
+	)
+	return conflicts
+}
+
+// isConflict returns true if the edges to a module depend on it at more
+// than one remote, or pin a remote to more than one digest.
+func isConflict(edges []*GraphEdge) bool {
+	remoteToDigest := make(map[string]string)
+	for _, edge := range edges {
+		digest, ok := remoteToDigest[edge.To]
+		if !ok {
+			if len(remoteToDigest) > 0 {
+				return true
+			}
+			remoteToDigest[edge.To] = edge.Digest
+			continue
+		}
+		if digest == "" {
+			remoteToDigest[edge.To] = edge.Digest
+			continue
+		}
+		if edge.Digest != "" && edge.Digest != digest {
+			return true
+		}
+	}
+	return false
+}
+
+func moduleName(remote string) string {
+	if index := strings.IndexByte(remote, '#'); index >= 0 {
+		remote = remote[:index]
+	}
+	if !strings.HasPrefix(remote, ociPrefix) {
+		return remote
+	}
+	// oci://registry/repository:tag or oci://registry/repository@digest
+	repositoryStart := strings.LastIndexByte(remote, '/') + 1
+	if index := strings.IndexAny(remote[repositoryStart:], ":@"); index >= 0 {
+		remote = remote[:repositoryStart+index]
+	}
+	return remote
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleName(t *testing.T) {
+	t.Parallel()
+	for remote, expectedName := range map[string]string{
+		"https://github.com/acme/weather.git":                   "https://github.com/acme/weather.git",
+		"https://github.com/acme/weather.git#tag=v1":            "https://github.com/acme/weather.git",
+		"https://example.com/weather.tar.gz#strip_components=1": "https://example.com/weather.tar.gz",
+		"oci://ghcr.io/acme/weather:v1":                         "oci://ghcr.io/acme/weather",
+		"oci://ghcr.io/acme/weather@sha256:0123":                "oci://ghcr.io/acme/weather",
+		"oci://localhost:5000/acme/weather:v1":                  "oci://localhost:5000/acme/weather",
+		"oci://localhost:5000/acme/weather":                     "oci://localhost:5000/acme/weather",
+	} {
+		assert.Equal(t, expectedName, ModuleName(remote), remote)
+	}
+}
+
+func TestGetGraphConflicts(t *testing.T) {
+	t.Parallel()
+	graph := &Graph{
+		Edges: []*GraphEdge{
+			{To: "file:///a.git", Digest: "sha256:a"},
+			{To: "file:///b.git#tag=v2", Digest: "sha256:b2"},
+			{To: "file:///c.git", Digest: "sha256:c1"},
+			{To: "file:///d.git", Digest: "sha256:d"},
+			{From: "file:///a.git", To: "file:///b.git#tag=v1", Digest: "sha256:b1"},
+			{From: "file:///a.git", To: "file:///c.git", Digest: "sha256:c2"},
+			// not pinned, so this only conflicts by remote
+			{From: "file:///a.git", To: "file:///d.git"},
+		},
+	}
+	assert.Equal(
+		t,
+		[]*GraphConflict{
+			{
+				Name:  "file:///b.git",
+				Edges: []*GraphEdge{graph.Edges[1], graph.Edges[4]},
+			},
+			{
+				Name:  "file:///c.git",
+				Edges: []*GraphEdge{graph.Edges[2], graph.Edges[5]},
+			},
+		},
+		GetGraphConflicts(graph),
+	)
+	assert.Empty(t, GetGraphConflicts(&Graph{Edges: graph.Edges[:4]}))
+}
@@ -50,6 +50,7 @@ func (b *includeBuilder) BuildForIncludes(
 		includeDirPaths,
 		buildOptions.paths,
 		buildOptions.pathsAllowNotExistOnWalk,
+		buildOptions.excludePaths,
 	)
 }
 
@@ -58,6 +59,7 @@ func (b *includeBuilder) buildForIncludes(
 	includeDirPaths []string,
 	filePaths []string,
 	filePathsAllowNotExistOnWalk bool,
+	excludePaths []string,
 ) (bufcore.Module, error) {
 	if len(includeDirPaths) == 0 {
 		includeDirPaths = []string{"."}
@@ -99,5 +101,33 @@ func (b *includeBuilder) buildForIncludes(
 	if err != nil {
 		return nil, err
 	}
+	if len(excludePaths) > 0 {
+		absExcludePaths, err := normalizeAndCheckPaths(
+			excludePaths,
+			"exclude path",
+			normalpath.Absolute,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		excludeTargetPaths, err := getExcludeTargetPaths(
+			ctx,
+			absIncludeDirPaths,
+			func(root string) (storage.ReadBucket, error) {
+				rootBucket, err := storageos.NewReadWriteBucket(root)
+				if err != nil {
+					return nil, err
+				}
+				return storage.Map(rootBucket, storage.MatchPathExt(".proto")), nil
+			},
+			absExcludePaths,
+			normalpath.Absolute,
+		)
+		if err != nil {
+			return nil, err
+		}
+		moduleOptions = append(moduleOptions, bufcore.ModuleWithExcludeTargetPaths(excludeTargetPaths...))
+	}
 	return bufcore.NewModule(storage.Multi(rootBuckets...), moduleOptions...)
 }
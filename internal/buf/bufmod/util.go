@@ -15,6 +15,7 @@
 package bufmod
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -22,6 +23,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 )
 
 func getModuleOptions(
@@ -37,9 +39,9 @@ func getModuleOptions(
 			return nil, err
 		}
 		moduleOptions = append(moduleOptions, bufcore.ModuleWithTargetPaths(targetPaths...))
-	}
-	if pathsAllowNotExistOnWalk {
-		moduleOptions = append(moduleOptions, bufcore.ModuleWithTargetPathsAllowNotExistOnWalk())
+		if pathsAllowNotExistOnWalk {
+			moduleOptions = append(moduleOptions, bufcore.ModuleWithTargetPathsAllowNotExistOnWalk())
+		}
 	}
 	return moduleOptions, nil
 }
@@ -85,6 +87,55 @@ func pathToTargetPath(roots []string, path string, pathType normalpath.PathType)
 	}
 }
 
+// getExcludeTargetPaths returns the target paths to exclude for the given
+// paths, which are relative to the bucket or include directory paths.
+//
+// Target paths are relative to the roots, so a path that is equal to or
+// contains a root is expanded to all the files of that root, as excluding
+// the root itself would exclude the files of all roots.
+func getExcludeTargetPaths(
+	ctx context.Context,
+	roots []string,
+	getRootReadBucket func(root string) (storage.ReadBucket, error),
+	excludePaths []string,
+	pathType normalpath.PathType,
+) ([]string, error) {
+	var excludeTargetPaths []string
+	for _, excludePath := range excludePaths {
+		var containedRoots []string
+		for _, root := range roots {
+			if normalpath.EqualsOrContainsPath(excludePath, root, pathType) {
+				containedRoots = append(containedRoots, root)
+			}
+		}
+		if len(containedRoots) == 0 {
+			excludeTargetPath, err := pathToTargetPath(roots, excludePath, pathType)
+			if err != nil {
+				return nil, err
+			}
+			excludeTargetPaths = append(excludeTargetPaths, excludeTargetPath)
+			continue
+		}
+		for _, root := range containedRoots {
+			rootReadBucket, err := getRootReadBucket(root)
+			if err != nil {
+				return nil, err
+			}
+			if err := rootReadBucket.Walk(
+				ctx,
+				"",
+				func(objectInfo storage.ObjectInfo) error {
+					excludeTargetPaths = append(excludeTargetPaths, objectInfo.Path())
+					return nil
+				},
+			); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return excludeTargetPaths, nil
+}
+
 // normalizeAndCheckPaths verifies that:
 //
 //   - No paths are empty.
@@ -172,4 +223,11 @@ func sortAndCheckDuplicatePaths(outputs []string, name string, pathType normalpa
 type buildOptions struct {
 	paths                    []string
 	pathsAllowNotExistOnWalk bool
+	excludePaths             []string
+	dependencies             []*dependency
+}
+
+type dependency struct {
+	readBucket storage.ReadBucket
+	config     *Config
 }
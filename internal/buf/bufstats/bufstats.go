@@ -0,0 +1,89 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufstats computes statistics over images.
+package bufstats
+
+import (
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+)
+
+// PackageStats are the statistics for a single package.
+type PackageStats struct {
+	// Package is the package, which is empty for files without a package.
+	Package    string `json:"package,omitempty"`
+	Files      int    `json:"files"`
+	Messages   int    `json:"messages"`
+	Fields     int    `json:"fields"`
+	Extensions int    `json:"extensions"`
+	Oneofs     int    `json:"oneofs"`
+	Enums      int    `json:"enums"`
+	EnumValues int    `json:"enum_values"`
+	Services   int    `json:"services"`
+	Methods    int    `json:"methods"`
+	// Options is the number of times each option is set in the package.
+	//
+	// Options are keyed by name, such as go_package. Custom options are keyed
+	// by their fully-qualified name in parentheses, such as (google.api.http),
+	// or by their number in parentheses if their definition is not in the image.
+	Options map[string]int `json:"options,omitempty"`
+}
+
+// OptionCount is the total number of options set in the package.
+func (p *PackageStats) OptionCount() int {
+	count := 0
+	for _, optionCount := range p.Options {
+		count += optionCount
+	}
+	return count
+}
+
+// GetPackageStats returns the statistics for each package of the non-import
+// files in the image, sorted by package.
+//
+// Map entry messages are not counted, and nested messages and enums are
+// counted the same as top-level ones.
+func GetPackageStats(image bufcore.Image) []*PackageStats {
+	counter := newCounter(image)
+	packageToPackageStats := make(map[string]*PackageStats)
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptorProto := imageFile.Proto()
+		pkg := fileDescriptorProto.GetPackage()
+		packageStats, ok := packageToPackageStats[pkg]
+		if !ok {
+			packageStats = &PackageStats{
+				Package: pkg,
+				Options: make(map[string]int),
+			}
+			packageToPackageStats[pkg] = packageStats
+		}
+		counter.countFile(packageStats, fileDescriptorProto)
+	}
+	packageStatsList := make([]*PackageStats, 0, len(packageToPackageStats))
+	for _, packageStats := range packageToPackageStats {
+		packageStatsList = append(packageStatsList, packageStats)
+	}
+	sort.Slice(
+		packageStatsList,
+		func(i int, j int) bool {
+			return packageStatsList[i].Package < packageStatsList[j].Package
+		},
+	)
+	return packageStatsList
+}
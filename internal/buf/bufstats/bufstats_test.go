@@ -0,0 +1,153 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufstats
+
+import (
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoretesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGetPackageStats(t *testing.T) {
+	t.Parallel()
+	importFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "acme/options/v1/options.proto")
+	importFileDescriptorProto.Package = proto.String("acme.options.v1")
+	importFileDescriptorProto.Extension = []*descriptorpb.FieldDescriptorProto{
+		{
+			Name:     proto.String("internal"),
+			Number:   proto.Int32(50000),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			Extendee: proto.String(".google.protobuf.FieldOptions"),
+		},
+	}
+	fieldOptions := &descriptorpb.FieldOptions{
+		Deprecated: proto.Bool(true),
+	}
+	// custom options are unknown fields in images
+	fieldOptions.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 50000, protowire.VarintType), 1))
+	unknownFieldOptions := &descriptorpb.FieldOptions{}
+	unknownFieldOptions.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 50001, protowire.VarintType), 1))
+	fileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "acme/weather/v1/weather.proto", "acme/options/v1/options.proto")
+	fileDescriptorProto.Package = proto.String("acme.weather.v1")
+	fileDescriptorProto.Options = &descriptorpb.FileOptions{
+		GoPackage: proto.String("github.com/acme/weather/v1;weatherv1"),
+	}
+	fileDescriptorProto.MessageType = []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("Weather"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:    proto.String("one"),
+					Number:  proto.Int32(1),
+					Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:    descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Options: fieldOptions,
+				},
+				{
+					Name:    proto.String("two"),
+					Number:  proto.Int32(2),
+					Label:   descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+					Type:    descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					Options: unknownFieldOptions,
+				},
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("TwoEntry"),
+					Options: &descriptorpb.MessageOptions{
+						MapEntry: proto.Bool(true),
+					},
+				},
+			},
+			EnumType: []*descriptorpb.EnumDescriptorProto{
+				{
+					Name: proto.String("Condition"),
+					Value: []*descriptorpb.EnumValueDescriptorProto{
+						{
+							Name:   proto.String("CONDITION_UNSPECIFIED"),
+							Number: proto.Int32(0),
+						},
+						{
+							Name:   proto.String("CONDITION_SUNNY"),
+							Number: proto.Int32(1),
+							Options: &descriptorpb.EnumValueOptions{
+								Deprecated: proto.Bool(true),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	fileDescriptorProto.Service = []*descriptorpb.ServiceDescriptorProto{
+		{
+			Name: proto.String("WeatherService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("GetWeather"),
+					InputType:  proto.String(".acme.weather.v1.Weather"),
+					OutputType: proto.String(".acme.weather.v1.Weather"),
+				},
+			},
+		},
+	}
+	otherFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "acme/weather/v1/other.proto")
+	otherFileDescriptorProto.Package = proto.String("acme.weather.v1")
+	noPackageFileDescriptorProto := bufcoretesting.NewFileDescriptorProto(t, "a.proto")
+	image, err := bufcore.NewImage(
+		[]bufcore.ImageFile{
+			bufcoretesting.NewImageFile(t, importFileDescriptorProto, "", true),
+			bufcoretesting.NewImageFile(t, fileDescriptorProto, "", false),
+			bufcoretesting.NewImageFile(t, otherFileDescriptorProto, "", false),
+			bufcoretesting.NewImageFile(t, noPackageFileDescriptorProto, "", false),
+		},
+	)
+	require.NoError(t, err)
+	packageStatsList := GetPackageStats(image)
+	assert.Equal(
+		t,
+		[]*PackageStats{
+			{
+				Files:   1,
+				Options: map[string]int{},
+			},
+			{
+				Package:    "acme.weather.v1",
+				Files:      2,
+				Messages:   1,
+				Fields:     2,
+				Enums:      1,
+				EnumValues: 2,
+				Services:   1,
+				Methods:    1,
+				Options: map[string]int{
+					"go_package":                 1,
+					"deprecated":                 2,
+					"(acme.options.v1.internal)": 1,
+					"(50001)":                    1,
+				},
+			},
+		},
+		packageStatsList,
+	)
+	assert.Equal(t, 5, packageStatsList[1].OptionCount())
+}
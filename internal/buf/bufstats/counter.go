@@ -0,0 +1,174 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufstats
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type counter struct {
+	// extendeeToNumberToName contains the extensions defined anywhere in the
+	// image, including imports, to name custom options.
+	extendeeToNumberToName map[string]map[int32]string
+}
+
+func newCounter(image bufcore.Image) *counter {
+	counter := &counter{
+		extendeeToNumberToName: make(map[string]map[int32]string),
+	}
+	for _, imageFile := range image.Files() {
+		fileDescriptorProto := imageFile.Proto()
+		counter.addExtensions(fileDescriptorProto.GetPackage(), fileDescriptorProto.GetExtension())
+		for _, descriptorProto := range fileDescriptorProto.GetMessageType() {
+			counter.addMessageExtensions(fileDescriptorProto.GetPackage(), descriptorProto)
+		}
+	}
+	return counter
+}
+
+func (c *counter) addMessageExtensions(scope string, descriptorProto *descriptorpb.DescriptorProto) {
+	scope = joinName(scope, descriptorProto.GetName())
+	c.addExtensions(scope, descriptorProto.GetExtension())
+	for _, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		c.addMessageExtensions(scope, nestedDescriptorProto)
+	}
+}
+
+func (c *counter) addExtensions(scope string, fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto) {
+	for _, fieldDescriptorProto := range fieldDescriptorProtos {
+		extendee := strings.TrimPrefix(fieldDescriptorProto.GetExtendee(), ".")
+		numberToName, ok := c.extendeeToNumberToName[extendee]
+		if !ok {
+			numberToName = make(map[int32]string)
+			c.extendeeToNumberToName[extendee] = numberToName
+		}
+		numberToName[fieldDescriptorProto.GetNumber()] = joinName(scope, fieldDescriptorProto.GetName())
+	}
+}
+
+func (c *counter) countFile(packageStats *PackageStats, fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	packageStats.Files++
+	packageStats.Extensions += len(fileDescriptorProto.GetExtension())
+	c.countOptions(packageStats, fileDescriptorProto.GetOptions())
+	for _, fieldDescriptorProto := range fileDescriptorProto.GetExtension() {
+		c.countOptions(packageStats, fieldDescriptorProto.GetOptions())
+	}
+	for _, descriptorProto := range fileDescriptorProto.GetMessageType() {
+		c.countMessage(packageStats, descriptorProto)
+	}
+	for _, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+		c.countEnum(packageStats, enumDescriptorProto)
+	}
+	for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		packageStats.Services++
+		c.countOptions(packageStats, serviceDescriptorProto.GetOptions())
+		for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+			packageStats.Methods++
+			c.countOptions(packageStats, methodDescriptorProto.GetOptions())
+		}
+	}
+}
+
+func (c *counter) countMessage(packageStats *PackageStats, descriptorProto *descriptorpb.DescriptorProto) {
+	if descriptorProto.GetOptions().GetMapEntry() {
+		return
+	}
+	packageStats.Messages++
+	packageStats.Fields += len(descriptorProto.GetField())
+	packageStats.Extensions += len(descriptorProto.GetExtension())
+	packageStats.Oneofs += len(descriptorProto.GetOneofDecl())
+	c.countOptions(packageStats, descriptorProto.GetOptions())
+	for _, fieldDescriptorProto := range descriptorProto.GetField() {
+		c.countOptions(packageStats, fieldDescriptorProto.GetOptions())
+	}
+	for _, fieldDescriptorProto := range descriptorProto.GetExtension() {
+		c.countOptions(packageStats, fieldDescriptorProto.GetOptions())
+	}
+	for _, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+		c.countOptions(packageStats, oneofDescriptorProto.GetOptions())
+	}
+	for _, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		c.countMessage(packageStats, nestedDescriptorProto)
+	}
+	for _, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		c.countEnum(packageStats, enumDescriptorProto)
+	}
+}
+
+func (c *counter) countEnum(packageStats *PackageStats, enumDescriptorProto *descriptorpb.EnumDescriptorProto) {
+	packageStats.Enums++
+	packageStats.EnumValues += len(enumDescriptorProto.GetValue())
+	c.countOptions(packageStats, enumDescriptorProto.GetOptions())
+	for _, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		c.countOptions(packageStats, enumValueDescriptorProto.GetOptions())
+	}
+}
+
+// countOptions counts each option set on the options message once.
+//
+// Custom options may either be resolved or be unknown fields depending on how
+// the options were parsed, so we go through the wire format to handle both.
+func (c *counter) countOptions(packageStats *PackageStats, options proto.Message) {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(options)
+	if err != nil {
+		return
+	}
+	messageDescriptor := options.ProtoReflect().Descriptor()
+	seen := make(map[protowire.Number]struct{})
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, typ, data)
+		if n < 0 {
+			return
+		}
+		data = data[n:]
+		if _, ok := seen[number]; ok {
+			continue
+		}
+		seen[number] = struct{}{}
+		packageStats.Options[c.getOptionName(messageDescriptor, number)]++
+	}
+}
+
+func (c *counter) getOptionName(messageDescriptor protoreflect.MessageDescriptor, number protowire.Number) string {
+	if fieldDescriptor := messageDescriptor.Fields().ByNumber(number); fieldDescriptor != nil {
+		return string(fieldDescriptor.Name())
+	}
+	if name, ok := c.extendeeToNumberToName[string(messageDescriptor.FullName())][int32(number)]; ok {
+		return "(" + name + ")"
+	}
+	return "(" + strconv.Itoa(int(number)) + ")"
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
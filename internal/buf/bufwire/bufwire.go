@@ -19,6 +19,7 @@ package bufwire
 
 import (
 	"context"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
@@ -27,6 +28,8 @@ import (
 	"github.com/bufbuild/buf/internal/buf/buffetch"
 	"github.com/bufbuild/buf/internal/buf/bufmod"
 	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
 
@@ -34,6 +37,13 @@ import (
 type Env interface {
 	Image() bufcore.Image
 	Config() *bufconfig.Config
+	// SourceReadBucket returns the contents of the source files of the Image,
+	// by their root relative paths.
+	//
+	// This is nil unless the EnvReader was created with EnvReaderWithSourceReadBucket
+	// and the Image was built from sources. Files of the Image that were not read
+	// from sources, such as the well-known types, are not in the bucket.
+	SourceReadBucket() storage.ReadBucket
 }
 
 // EnvReader is an environment reader.
@@ -41,6 +51,9 @@ type EnvReader interface {
 	// GetEnv gets an environment for the fetch value.
 	//
 	// If externalFilePaths is empty, this builds all files under Buf control.
+	//
+	// If the EnvReader was created with EnvReaderWithPartialBuild, both an Env
+	// and FileAnnotations may be returned when building sources.
 	GetEnv(
 		ctx context.Context,
 		container app.EnvStdinContainer,
@@ -71,12 +84,17 @@ type EnvReader interface {
 		excludeSourceCodeInfo bool,
 	) (Env, []bufanalysis.FileAnnotation, error)
 	// ListFiles lists the files.
+	//
+	// For sources, this lists the target files along with the roots they are
+	// contained within. For images, this lists all files, including imports.
+	//
+	// Source files are parsed but not built to get their package.
 	ListFiles(
 		ctx context.Context,
 		container app.EnvStdinContainer,
 		value string,
 		configOverride string,
-	) ([]bufcore.FileInfo, error)
+	) ([]FileInfo, error)
 	// GetConfig gets the config.
 	GetConfig(
 		ctx context.Context,
@@ -84,6 +102,30 @@ type EnvReader interface {
 	) (*bufconfig.Config, error)
 }
 
+// FileInfo is a file info listed by an EnvReader.
+type FileInfo interface {
+	bufcore.FileInfo
+
+	// RootDirPath returns the root directory that the file is contained within,
+	// relative to the root of the input, as configured in the build roots.
+	//
+	// Empty if the input is an image.
+	RootDirPath() string
+	// Package returns the package of the file.
+	//
+	// Empty if the file has no package, or if the file is a source file
+	// that could not be parsed.
+	Package() string
+	// Syntax returns the syntax of the file, one of proto2, proto3, or editions.
+	//
+	// Empty if the file is a source file that could not be parsed.
+	Syntax() string
+	// Edition returns the edition of the file, such as 2023.
+	//
+	// Empty if the syntax is not editions.
+	Edition() string
+}
+
 // NewEnvReader returns a new EnvReader.
 func NewEnvReader(
 	logger *zap.Logger,
@@ -94,6 +136,7 @@ func NewEnvReader(
 	buildBuilder bufbuild.Builder,
 	valueFlagName string,
 	configOverrideFlagName string,
+	options ...EnvReaderOption,
 ) EnvReader {
 	return newEnvReader(
 		logger,
@@ -104,6 +147,205 @@ func NewEnvReader(
 		buildBuilder,
 		valueFlagName,
 		configOverrideFlagName,
+		options...,
+	)
+}
+
+// NewSharedEnvReader returns a new EnvReader that reads and builds each
+// input once, and returns the same result for all calls to GetEnv with the
+// same arguments.
+//
+// This is used to share one build of an input between multiple checks.
+// The returned Env and FileAnnotations are shared, and must not be modified.
+// All other methods are delegated to the given EnvReader.
+func NewSharedEnvReader(delegate EnvReader) EnvReader {
+	return newSharedEnvReader(delegate)
+}
+
+// EnvReaderOption is an option for a new EnvReader.
+type EnvReaderOption func(*envReader)
+
+// EnvReaderWithPartialBuild returns a new EnvReaderOption that returns an
+// Env with an Image of the files that compile when building sources, along
+// with the FileAnnotations for the files that did not compile.
+func EnvReaderWithPartialBuild() EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.partialBuild = true
+	}
+}
+
+// EnvReaderWithExcludeExternalFilePaths returns a new EnvReaderOption that
+// excludes the files equal to or contained within the given external file or
+// directory paths.
+//
+// Excluded files are not built or checked, but can still be imported by the
+// remaining files. This is applied after the externalFilePaths given to GetEnv,
+// so a file that is both given and excluded is excluded.
+func EnvReaderWithExcludeExternalFilePaths(excludeExternalFilePaths []string) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.excludeExternalFilePaths = excludeExternalFilePaths
+	}
+}
+
+// EnvReaderWithFetchTimeout returns a new EnvReaderOption that times out
+// fetching inputs after the given duration, independent of the timeout of
+// the context.
+//
+// The default is to only use the timeout of the context.
+func EnvReaderWithFetchTimeout(fetchTimeout time.Duration) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.fetchTimeout = fetchTimeout
+	}
+}
+
+// EnvReaderWithBuildTimeout returns a new EnvReaderOption that times out
+// building sources after the given duration, independent of the timeout of
+// the context.
+//
+// The default is to only use the timeout of the context.
+func EnvReaderWithBuildTimeout(buildTimeout time.Duration) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.buildTimeout = buildTimeout
+	}
+}
+
+// EnvReaderWithBuildParallelism returns a new EnvReaderOption that compiles
+// sources on at most the given number of workers.
+//
+// The default is to use the default of the bufbuild.Builder.
+func EnvReaderWithBuildParallelism(buildParallelism int) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.buildParallelism = buildParallelism
+	}
+}
+
+// EnvReaderWithBuildCache returns a new EnvReaderOption that caches built
+// files within the cache directory of the user, see app.CacheDirPath.
+//
+// Files are only rebuilt if their content or the content of any file they
+// import has changed since they were cached.
+// Cached files that have not been used for 30 days are removed.
+func EnvReaderWithBuildCache() EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.buildCache = true
+	}
+}
+
+// ClearBuildCache removes the cache of built files from the cache directory
+// of the user.
+func ClearBuildCache(envContainer app.EnvContainer) error {
+	return clearBuildCache(envContainer)
+}
+
+// EnvReaderWithAllowPlugins returns a new EnvReaderOption that runs the lint
+// and breaking plugins configured in the buf.yaml of local directory inputs,
+// or of the current directory for image inputs.
+//
+// Plugins run arbitrary commands, so by default only the plugins in the config
+// override are run. The plugins configured in the buf.yaml of other inputs,
+// such as archives or git repositories, are never run.
+func EnvReaderWithAllowPlugins() EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.allowPlugins = true
+	}
+}
+
+// EnvReaderWithStrictResolution returns a new EnvReaderOption that fails to
+// read image inputs that are not self-contained, that is that reference
+// imports or types that are not contained in the image.
+//
+// Images are read with unresolvable imports and types by default, in which
+// case custom options that depend on them are left unresolved. This has no
+// effect for sources, as built images are always self-contained.
+func EnvReaderWithStrictResolution() EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.strictResolution = true
+	}
+}
+
+// EnvReaderWithConfigName returns a new EnvReaderOption that uses the named
+// config of the config of the input instead of the config itself, see
+// bufconfig.GetNamedConfig.
+//
+// It is an error if the config does not have a config with this name.
+func EnvReaderWithConfigName(configName string) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.configName = configName
+	}
+}
+
+// EnvReaderWithConfigExcludeSourceCodeInfo returns a new EnvReaderOption that
+// excludes source code info if excludeSourceCodeInfo returns true for the Config
+// of the Env, even if source code info was not explicitly excluded.
+//
+// This is used to apply the per-command source info defaults in the Config.
+func EnvReaderWithConfigExcludeSourceCodeInfo(excludeSourceCodeInfo func(*bufconfig.Config) bool) EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.configExcludeSourceCodeInfo = excludeSourceCodeInfo
+	}
+}
+
+// EnvReaderWithSourceReadBucket returns a new EnvReaderOption that keeps the
+// contents of the source files of the Image in memory when building sources,
+// see Env.SourceReadBucket.
+func EnvReaderWithSourceReadBucket() EnvReaderOption {
+	return func(envReader *envReader) {
+		envReader.sourceReadBucket = true
+	}
+}
+
+// DependencyResolver resolves the dependencies of modules.
+type DependencyResolver interface {
+	// ResolveLock fetches the dependencies in the Config, and the
+	// dependencies declared in the buf.yaml of each dependency, and returns a
+	// Lock that pins each of these transitive dependencies to the digest of
+	// its current files.
+	ResolveLock(
+		ctx context.Context,
+		container app.EnvStdinContainer,
+		config *bufconfig.Config,
+	) (*bufmod.Lock, error)
+	// Vendor fetches the transitive dependencies of the Config, verifies
+	// them against the Lock, and writes the .proto files and buf.yaml of each dependency
+	// to the WriteBucket within bufmod.VendorPath of its digest. The Lock is
+	// written to the buf.lock file within bufmod.VendorDirPath.
+	//
+	// Builds of modules with vendored dependencies do not fetch them.
+	Vendor(
+		ctx context.Context,
+		container app.EnvStdinContainer,
+		config *bufconfig.Config,
+		lock *bufmod.Lock,
+		writeBucket storage.WriteBucket,
+	) error
+	// ResolveGraph fetches the dependencies in the Config, and the
+	// dependencies declared in the buf.yaml of each dependency, and returns
+	// the dependency Graph of the module.
+	//
+	// The digests of the root module are read from the Lock, which may be
+	// nil, and the dependencies in the Lock are verified against them. The
+	// digests of each dependency are read from its own buf.lock file. Each
+	// remote is only fetched once.
+	ResolveGraph(
+		ctx context.Context,
+		container app.EnvStdinContainer,
+		config *bufconfig.Config,
+		lock *bufmod.Lock,
+	) (*bufmod.Graph, error)
+}
+
+// NewDependencyResolver returns a new DependencyResolver.
+func NewDependencyResolver(
+	logger *zap.Logger,
+	fetchRefParser buffetch.RefParser,
+	fetchReader buffetch.Reader,
+	configProvider bufconfig.Provider,
+) DependencyResolver {
+	return newDependencyResolver(
+		logger,
+		fetchRefParser,
+		fetchReader,
+		configProvider,
 	)
 }
 
@@ -118,6 +360,17 @@ type ImageReader interface {
 		externalFileFilePathsAllowNotExist bool,
 		excludeSourceCodeInfo bool,
 	) (bufcore.Image, error)
+	// VerifyImage reads the image from the value and verifies its internal consistency.
+	//
+	// Problems with the image, such as files that cannot be unmarshaled, imports and
+	// types that cannot be resolved, custom options that are not defined in the image,
+	// and source code info that does not refer to an element, are returned as
+	// FileAnnotations. An error is only returned if the image cannot be read at all.
+	VerifyImage(
+		ctx context.Context,
+		container app.EnvStdinContainer,
+		value string,
+	) ([]bufanalysis.FileAnnotation, error)
 }
 
 // NewImageReader returns a new ImageReader.
@@ -126,21 +379,74 @@ func NewImageReader(
 	fetchImageRefParser buffetch.ImageRefParser,
 	fetchReader buffetch.Reader,
 	valueFlagName string,
+	options ...ImageReaderOption,
 ) ImageReader {
 	return newImageReader(
 		logger,
 		fetchImageRefParser,
 		fetchReader,
 		valueFlagName,
+		options...,
 	)
 }
 
+// ImageReaderOption is an option for a new ImageReader.
+type ImageReaderOption func(*imageReader)
+
+// ImageReaderWithJSONUnmarshalerOptions returns a new ImageReaderOption that uses
+// the given options when reading JSON images.
+//
+// These have no effect for binary images.
+func ImageReaderWithJSONUnmarshalerOptions(jsonUnmarshalerOptions ...protoencoding.JSONUnmarshalerOption) ImageReaderOption {
+	return func(imageReader *imageReader) {
+		imageReader.jsonUnmarshalerOptions = append(imageReader.jsonUnmarshalerOptions, jsonUnmarshalerOptions...)
+	}
+}
+
+// ImageReaderWithExcludeExternalFilePaths returns a new ImageReaderOption that
+// excludes the non-import files equal to or contained within the given external
+// file or directory paths.
+//
+// Excluded files that are still imported by the remaining files are kept as
+// imports. This is applied after the externalFilePaths given to GetImage.
+func ImageReaderWithExcludeExternalFilePaths(excludeExternalFilePaths []string) ImageReaderOption {
+	return func(imageReader *imageReader) {
+		imageReader.excludeExternalFilePaths = excludeExternalFilePaths
+	}
+}
+
+// ImageReaderWithFetchTimeout returns a new ImageReaderOption that times out
+// fetching images after the given duration, independent of the timeout of
+// the context.
+//
+// The default is to only use the timeout of the context.
+func ImageReaderWithFetchTimeout(fetchTimeout time.Duration) ImageReaderOption {
+	return func(imageReader *imageReader) {
+		imageReader.fetchTimeout = fetchTimeout
+	}
+}
+
+// ImageReaderWithStrictResolution returns a new ImageReaderOption that fails
+// to read images that are not self-contained, that is that reference imports
+// or types that are not contained in the image.
+//
+// Images are read with unresolvable imports and types by default, in which
+// case custom options that depend on them are left unresolved.
+func ImageReaderWithStrictResolution() ImageReaderOption {
+	return func(imageReader *imageReader) {
+		imageReader.strictResolution = true
+	}
+}
+
 // ImageWriter is an image writer.
 type ImageWriter interface {
 	// PutImage writes the image to the value.
 	//
 	// The file must be an image format.
 	// This is a no-np if value is the equivalent of /dev/null.
+	//
+	// The output is deterministic, that is the same image with the same options
+	// is always written as the same bytes.
 	PutImage(
 		ctx context.Context,
 		container app.EnvStdoutContainer,
@@ -149,6 +455,34 @@ type ImageWriter interface {
 		asFileDescriptorSet bool,
 		excludeImports bool,
 	) error
+	// PutSourceArchive writes the source files of the image in the
+	// sourceReadBucket to the value as a tar archive, such as sources.tgz.
+	//
+	// The files are written by their root relative paths, so the archive can
+	// be built as a source input with the default config. Files of the image
+	// that are not in the sourceReadBucket are skipped.
+	//
+	// The output is deterministic, that is the same files are always written
+	// as the same bytes, independent of their timestamps and permissions.
+	PutSourceArchive(
+		ctx context.Context,
+		container app.EnvStdoutContainer,
+		value string,
+		image bufcore.Image,
+		sourceReadBucket storage.ReadBucket,
+		excludeImports bool,
+	) error
+}
+
+// IsSourceArchiveValue returns true if the value is a tar archive source
+// value, such as sources.tgz, that can be written with ImageWriter.PutSourceArchive.
+func IsSourceArchiveValue(
+	ctx context.Context,
+	logger *zap.Logger,
+	value string,
+) bool {
+	sourceRef, err := buffetch.NewRefParser(logger).GetSourceRef(ctx, value)
+	return err == nil && buffetch.IsTarArchiveSourceRef(sourceRef)
 }
 
 // NewImageWriter returns a new ImageWriter.
@@ -156,10 +490,90 @@ func NewImageWriter(
 	logger *zap.Logger,
 	fetchImageRefParser buffetch.ImageRefParser,
 	fetchWriter buffetch.Writer,
+	options ...ImageWriterOption,
 ) ImageWriter {
 	return newImageWriter(
 		logger,
 		fetchImageRefParser,
 		fetchWriter,
+		options...,
 	)
 }
+
+// ImageWriterOption is an option for a new ImageWriter.
+type ImageWriterOption func(*imageWriter)
+
+// ImageWriterWithCompactSourceCodeInfo returns a new ImageWriterOption that moves
+// the SourceCodeInfo of the files out of the FileDescriptorProtos and into a
+// zstd-compressed field of the image extension when writing images.
+//
+// Consumers that do not need SourceCodeInfo do not need to read it, and the
+// SourceCodeInfo is restored when the image is read by an ImageReader.
+//
+// This cannot be used when writing FileDescriptorSets.
+func ImageWriterWithCompactSourceCodeInfo() ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.compactSourceCodeInfo = true
+	}
+}
+
+// ImageWriterWithSourceCodeInfoFilterOptions returns a new ImageWriterOption that
+// filters the SourceCodeInfo of the files with the given options when writing images.
+//
+// This is applied before ImageWriterWithCompactSourceCodeInfo.
+func ImageWriterWithSourceCodeInfoFilterOptions(sourceCodeInfoFilterOptions ...bufcore.SourceCodeInfoFilterOption) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.sourceCodeInfoFilterOptions = append(imageWriter.sourceCodeInfoFilterOptions, sourceCodeInfoFilterOptions...)
+	}
+}
+
+// ImageWriterWithDigestFunc returns a new ImageWriterOption that calls
+// f with the digest of the image that is written, as returned by bufcore.ImageDigest.
+//
+// The digest is of the image after imports and SourceCodeInfo are excluded or
+// filtered, and is computed even if value is the equivalent of /dev/null.
+func ImageWriterWithDigestFunc(f func(digest string) error) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.digestFunc = f
+	}
+}
+
+// ImageWriterWithOverwriteConfirmer returns a new ImageWriterOption that calls
+// confirm with the path of the image before overwriting an existing local file.
+//
+// If confirm returns false, the image is not written and an error is returned.
+func ImageWriterWithOverwriteConfirmer(confirm func(path string) (bool, error)) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.overwriteConfirmer = confirm
+	}
+}
+
+// ImageWriterWithJSONMarshalerOptions returns a new ImageWriterOption that uses
+// the given options when writing JSON images.
+//
+// These have no effect for binary images.
+func ImageWriterWithJSONMarshalerOptions(jsonMarshalerOptions ...protoencoding.JSONMarshalerOption) ImageWriterOption {
+	return func(imageWriter *imageWriter) {
+		imageWriter.jsonMarshalerOptions = append(imageWriter.jsonMarshalerOptions, jsonMarshalerOptions...)
+	}
+}
+
+// ImageExporter is an image exporter.
+type ImageExporter interface {
+	// ExportImage writes the files of the image to the bucket as .proto files.
+	//
+	// The files are printed from their FileDescriptorProtos, so comments are
+	// only preserved if the image has source code info, and formatting is
+	// not preserved.
+	ExportImage(
+		ctx context.Context,
+		image bufcore.Image,
+		writeBucket storage.WriteBucket,
+		excludeImports bool,
+	) error
+}
+
+// NewImageExporter returns a new ImageExporter.
+func NewImageExporter(logger *zap.Logger) ImageExporter {
+	return newImageExporter(logger)
+}
@@ -0,0 +1,470 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	// updateLockCommand is the command that updates the buf.lock file, used
+	// in error messages.
+	updateLockCommand = "buf beta mod update"
+	// vendorCommand is the command that vendors the dependencies, used in
+	// error messages.
+	vendorCommand = "buf beta mod vendor"
+)
+
+type dependencyResolver struct {
+	logger         *zap.Logger
+	fetchRefParser buffetch.RefParser
+	fetchReader    buffetch.Reader
+	configProvider bufconfig.Provider
+}
+
+func newDependencyResolver(
+	logger *zap.Logger,
+	fetchRefParser buffetch.RefParser,
+	fetchReader buffetch.Reader,
+	configProvider bufconfig.Provider,
+) *dependencyResolver {
+	return &dependencyResolver{
+		logger:         logger.Named("bufwire"),
+		fetchRefParser: fetchRefParser,
+		fetchReader:    fetchReader,
+		configProvider: configProvider,
+	}
+}
+
+func (d *dependencyResolver) ResolveLock(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	config *bufconfig.Config,
+) (*bufmod.Lock, error) {
+	defer instrument.Start(d.logger, "resolve_lock").End()
+	lock := &bufmod.Lock{}
+	if err := walkDependencies(
+		config.Deps,
+		func(dep string) ([]string, error) {
+			digest, deps, err := d.getDigest(ctx, container, dep)
+			if err != nil {
+				return nil, err
+			}
+			lock.Dependencies = append(
+				lock.Dependencies,
+				&bufmod.LockedDependency{
+					Remote: dep,
+					Digest: digest,
+				},
+			)
+			return deps, nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(
+		lock.Dependencies,
+		func(i int, j int) bool {
+			return lock.Dependencies[i].Remote < lock.Dependencies[j].Remote
+		},
+	)
+	return lock, nil
+}
+
+func (d *dependencyResolver) Vendor(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	config *bufconfig.Config,
+	lock *bufmod.Lock,
+	writeBucket storage.WriteBucket,
+) error {
+	defer instrument.Start(d.logger, "vendor").End()
+	if err := walkDependencies(
+		config.Deps,
+		func(dep string) ([]string, error) {
+			lockedDependency := lock.GetDependency(dep)
+			if lockedDependency == nil {
+				return nil, fmt.Errorf("dep %s is not in %s, run %s", dep, bufmod.LockFilePath, updateLockCommand)
+			}
+			return d.vendorDependency(ctx, container, dep, lockedDependency.Digest, writeBucket)
+		},
+	); err != nil {
+		return err
+	}
+	data, err := bufmod.MarshalLock(lock)
+	if err != nil {
+		return err
+	}
+	return storage.PutPath(ctx, writeBucket, normalpath.Join(bufmod.VendorDirPath, bufmod.LockFilePath), data)
+}
+
+func (d *dependencyResolver) ResolveGraph(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	config *bufconfig.Config,
+	lock *bufmod.Lock,
+) (*bufmod.Graph, error) {
+	defer instrument.Start(d.logger, "resolve_graph").End()
+	graph := &bufmod.Graph{}
+	addGraphEdges(graph, "", config.Deps, lock)
+	if err := walkDependencies(
+		config.Deps,
+		func(remote string) ([]string, error) {
+			var lockedDependency *bufmod.LockedDependency
+			if lock != nil {
+				lockedDependency = lock.GetDependency(remote)
+			}
+			dependencyConfig, dependencyLock, err := d.getDependencyConfigAndLock(ctx, container, remote, lockedDependency)
+			if err != nil {
+				return nil, err
+			}
+			addGraphEdges(graph, remote, dependencyConfig.Deps, dependencyLock)
+			return dependencyConfig.Deps, nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(
+		graph.Edges,
+		func(i int, j int) bool {
+			if graph.Edges[i].From != graph.Edges[j].From {
+				return graph.Edges[i].From < graph.Edges[j].From
+			}
+			return graph.Edges[i].To < graph.Edges[j].To
+		},
+	)
+	return graph, nil
+}
+
+// getDependencyConfigAndLock fetches the dependency and returns its config
+// and the Lock from its buf.lock file, which is nil if it has none.
+//
+// If lockedDependency is not nil, the dependency is verified against it.
+func (d *dependencyResolver) getDependencyConfigAndLock(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	dep string,
+	lockedDependency *bufmod.LockedDependency,
+) (_ *bufconfig.Config, _ *bufmod.Lock, retErr error) {
+	readBucketCloser, config, err := d.getDependencyWithConfig(ctx, container, dep)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readBucketCloser.Close())
+	}()
+	if lockedDependency != nil {
+		if err := checkDigest(ctx, dep, readBucketCloser, config.Build, lockedDependency.Digest, updateLockCommand); err != nil {
+			return nil, nil, err
+		}
+	}
+	lock, err := bufmod.GetLockForBucket(ctx, readBucketCloser)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dep %s: %w", dep, err)
+	}
+	return config, lock, nil
+}
+
+// addGraphEdges adds the edges from the remote to the deps, with the digests
+// that the Lock pins them to. The Lock may be nil.
+func addGraphEdges(graph *bufmod.Graph, from string, deps []string, lock *bufmod.Lock) {
+	for _, dep := range deps {
+		edge := &bufmod.GraphEdge{
+			From: from,
+			To:   dep,
+		}
+		if lock != nil {
+			if lockedDependency := lock.GetDependency(dep); lockedDependency != nil {
+				edge.Digest = lockedDependency.Digest
+			}
+		}
+		graph.Edges = append(graph.Edges, edge)
+	}
+}
+
+// vendorDependency writes the dependency to the WriteBucket, and returns the
+// dependencies declared in the buf.yaml of the dependency.
+func (d *dependencyResolver) vendorDependency(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	dep string,
+	lockedDigest string,
+	writeBucket storage.WriteBucket,
+) (_ []string, retErr error) {
+	readBucketCloser, config, err := d.getDependencyWithConfig(ctx, container, dep)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readBucketCloser.Close())
+	}()
+	if err := checkDigest(ctx, dep, readBucketCloser, config.Build, lockedDigest, updateLockCommand); err != nil {
+		return nil, err
+	}
+	vendorPath := bufmod.VendorPath(lockedDigest)
+	if err := storage.WalkReadObjects(
+		ctx,
+		storage.Map(
+			readBucketCloser,
+			storage.MatchOr(
+				storage.MatchPathExt(".proto"),
+				storage.MatchPathEqual(bufconfig.ConfigFilePath),
+			),
+		),
+		"",
+		func(readObject storage.ReadObject) error {
+			data, err := ioutil.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			return storage.PutPath(ctx, writeBucket, normalpath.Join(vendorPath, readObject.Path()), data)
+		},
+	); err != nil {
+		return nil, err
+	}
+	return config.Deps, nil
+}
+
+// getBuildOptions fetches the dependencies in the config and all of their
+// transitive dependencies, and verifies them against the buf.lock file in
+// the bucket.
+//
+// If the bucket has vendored dependencies, these are used instead, and
+// nothing is fetched.
+//
+// The returned function closes the dependencies, and must be called once the
+// module is built.
+func (d *dependencyResolver) getBuildOptions(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	readBucket storage.ReadBucket,
+	config *bufconfig.Config,
+) (_ []bufmod.BuildOption, _ func() error, retErr error) {
+	if len(config.Deps) == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	lock, err := bufmod.GetLockForBucket(ctx, readBucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lock == nil {
+		return nil, nil, fmt.Errorf("deps are declared but there is no %s, run %s", bufmod.LockFilePath, updateLockCommand)
+	}
+	var readBucketClosers []storage.ReadBucketCloser
+	closeDependencies := func() error {
+		var err error
+		for _, readBucketCloser := range readBucketClosers {
+			err = multierr.Append(err, readBucketCloser.Close())
+		}
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, closeDependencies())
+		}
+	}()
+	vendored, err := checkVendorLock(ctx, readBucket, lock)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buildOptions []bufmod.BuildOption
+	if err := walkDependencies(
+		config.Deps,
+		func(dep string) ([]string, error) {
+			lockedDependency := lock.GetDependency(dep)
+			if lockedDependency == nil {
+				return nil, fmt.Errorf("dep %s is not in %s, run %s", dep, bufmod.LockFilePath, updateLockCommand)
+			}
+			if vendored {
+				vendorReadBucket, dependencyConfig, err := d.getVendoredDependency(ctx, readBucket, dep, lockedDependency.Digest)
+				if err != nil {
+					return nil, err
+				}
+				buildOptions = append(buildOptions, bufmod.WithDependency(vendorReadBucket, dependencyConfig.Build))
+				return dependencyConfig.Deps, nil
+			}
+			readBucketCloser, dependencyConfig, err := d.getDependencyWithConfig(ctx, container, dep)
+			if err != nil {
+				return nil, err
+			}
+			readBucketClosers = append(readBucketClosers, readBucketCloser)
+			if err := checkDigest(ctx, dep, readBucketCloser, dependencyConfig.Build, lockedDependency.Digest, updateLockCommand); err != nil {
+				return nil, err
+			}
+			buildOptions = append(buildOptions, bufmod.WithDependency(readBucketCloser, dependencyConfig.Build))
+			return dependencyConfig.Deps, nil
+		},
+	); err != nil {
+		return nil, nil, err
+	}
+	return buildOptions, closeDependencies, nil
+}
+
+// getVendoredDependency returns the dependency vendored within the bucket,
+// and the config from the buf.yaml of the dependency.
+func (d *dependencyResolver) getVendoredDependency(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	dep string,
+	lockedDigest string,
+) (storage.ReadBucket, *bufconfig.Config, error) {
+	vendorReadBucket := storage.Map(readBucket, storage.MapOnPrefix(bufmod.VendorPath(lockedDigest)))
+	config, err := d.configProvider.GetConfig(ctx, vendorReadBucket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dep %s: %w", dep, err)
+	}
+	if err := checkDigest(ctx, dep, vendorReadBucket, config.Build, lockedDigest, vendorCommand); err != nil {
+		return nil, nil, err
+	}
+	return vendorReadBucket, config, nil
+}
+
+// checkVendorLock returns true if the bucket has vendored dependencies.
+//
+// The buf.lock file that the dependencies were vendored for is within the
+// vendor directory, and must match the given Lock.
+func checkVendorLock(ctx context.Context, readBucket storage.ReadBucket, lock *bufmod.Lock) (bool, error) {
+	vendorLock, err := bufmod.GetLockForBucket(ctx, storage.Map(readBucket, storage.MapOnPrefix(bufmod.VendorDirPath)))
+	if err != nil {
+		return false, err
+	}
+	if vendorLock == nil {
+		return false, nil
+	}
+	vendorLockData, err := bufmod.MarshalLock(vendorLock)
+	if err != nil {
+		return false, err
+	}
+	lockData, err := bufmod.MarshalLock(lock)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(vendorLockData, lockData) {
+		return false, fmt.Errorf("%s does not match %s, run %s", bufmod.VendorDirPath, bufmod.LockFilePath, vendorCommand)
+	}
+	return true, nil
+}
+
+// getDigest fetches the dependency and returns its digest, and the
+// dependencies declared in the buf.yaml of the dependency.
+func (d *dependencyResolver) getDigest(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	dep string,
+) (_ string, _ []string, retErr error) {
+	readBucketCloser, config, err := d.getDependencyWithConfig(ctx, container, dep)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readBucketCloser.Close())
+	}()
+	digest, err := bufmod.Digest(ctx, readBucketCloser, config.Build)
+	if err != nil {
+		return "", nil, err
+	}
+	return digest, config.Deps, nil
+}
+
+// checkDigest checks that the digest of the dependency matches the digest
+// in the buf.lock file, suggesting the given command if not.
+func checkDigest(
+	ctx context.Context,
+	dep string,
+	readBucket storage.ReadBucket,
+	buildConfig *bufmod.Config,
+	lockedDigest string,
+	command string,
+) error {
+	digest, err := bufmod.Digest(ctx, readBucket, buildConfig)
+	if err != nil {
+		return err
+	}
+	if digest != lockedDigest {
+		return fmt.Errorf(
+			"dep %s has digest %s but %s has digest %s, run %s if this change is expected",
+			dep,
+			digest,
+			bufmod.LockFilePath,
+			lockedDigest,
+			command,
+		)
+	}
+	return nil
+}
+
+// getDependencyWithConfig fetches the dependency and returns the config from
+// the buf.yaml of the dependency.
+func (d *dependencyResolver) getDependencyWithConfig(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	dep string,
+) (_ storage.ReadBucketCloser, _ *bufconfig.Config, retErr error) {
+	defer func() {
+		if retErr != nil {
+			retErr = fmt.Errorf("dep %s: %w", dep, retErr)
+		}
+	}()
+	sourceRef, err := d.fetchRefParser.GetSourceRef(ctx, dep)
+	if err != nil {
+		return nil, nil, err
+	}
+	readBucketCloser, err := d.fetchReader.GetSourceBucket(ctx, container, sourceRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := d.configProvider.GetConfig(ctx, readBucketCloser)
+	if err != nil {
+		return nil, nil, multierr.Append(err, readBucketCloser.Close())
+	}
+	return readBucketCloser, config, nil
+}
+
+// walkDependencies calls f for each of the deps and all of their transitive
+// dependencies in breadth-first order, where f returns the dependencies
+// declared by the dependency. Each dependency is only visited once.
+func walkDependencies(deps []string, f func(string) ([]string, error)) error {
+	seen := make(map[string]struct{})
+	// copy so that appending does not modify the backing array of deps
+	queue := append([]string{}, deps...)
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[dep]; ok {
+			continue
+		}
+		seen[dep] = struct{}{}
+		dependencyDeps, err := f(dep)
+		if err != nil {
+			return err
+		}
+		queue = append(queue, dependencyDeps...)
+	}
+	return nil
+}
@@ -17,11 +17,13 @@ package bufwire
 import (
 	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 )
 
 type env struct {
-	image  bufcore.Image
-	config *bufconfig.Config
+	image            bufcore.Image
+	config           *bufconfig.Config
+	sourceReadBucket storage.ReadBucket
 }
 
 func newEnv(image bufcore.Image, config *bufconfig.Config) *env {
@@ -31,6 +33,14 @@ func newEnv(image bufcore.Image, config *bufconfig.Config) *env {
 	}
 }
 
+func newSourceEnv(image bufcore.Image, config *bufconfig.Config, sourceReadBucket storage.ReadBucket) *env {
+	return &env{
+		image:            image,
+		config:           config,
+		sourceReadBucket: sourceReadBucket,
+	}
+}
+
 func (e *env) Image() bufcore.Image {
 	return e.image
 }
@@ -38,3 +48,7 @@ func (e *env) Image() bufcore.Image {
 func (e *env) Config() *bufconfig.Config {
 	return e.config
 }
+
+func (e *env) SourceReadBucket() storage.ReadBucket {
+	return e.sourceReadBucket
+}
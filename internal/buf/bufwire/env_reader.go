@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
@@ -29,13 +30,19 @@ import (
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/buffetch"
 	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/bufwork"
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
+// buildCacheDirName is the directory within the user cache directory that
+// built files are cached in.
+var buildCacheDirName = filepath.Join("buf", "build")
+
 type envReader struct {
 	logger                 *zap.Logger
 	fetchRefParser         buffetch.RefParser
@@ -44,8 +51,24 @@ type envReader struct {
 	modBucketBuilder       bufmod.BucketBuilder
 	buildBuilder           bufbuild.Builder
 	imageReader            *imageReader
+	dependencyResolver     *dependencyResolver
 	valueFlagName          string
 	configOverrideFlagName string
+	partialBuild           bool
+	fetchTimeout           time.Duration
+	buildTimeout           time.Duration
+	buildParallelism       int
+	buildCache             bool
+	sourceReadBucket       bool
+	// excludeExternalFilePaths are also set on the imageReader.
+	excludeExternalFilePaths []string
+	// strictResolution is also set on the imageReader.
+	strictResolution bool
+	configName       string
+	allowPlugins     bool
+	// configExcludeSourceCodeInfo returns true if source code info
+	// should be excluded by default for the given config.
+	configExcludeSourceCodeInfo func(*bufconfig.Config) bool
 }
 
 func newEnvReader(
@@ -57,8 +80,9 @@ func newEnvReader(
 	buildBuilder bufbuild.Builder,
 	valueFlagName string,
 	configOverrideFlagName string,
+	options ...EnvReaderOption,
 ) *envReader {
-	return &envReader{
+	envReader := &envReader{
 		logger:           logger.Named("bufwire"),
 		fetchRefParser:   fetchRefParser,
 		fetchReader:      fetchReader,
@@ -71,9 +95,22 @@ func newEnvReader(
 			fetchReader,
 			valueFlagName,
 		),
+		dependencyResolver: newDependencyResolver(
+			logger,
+			fetchRefParser,
+			fetchReader,
+			configProvider,
+		),
 		valueFlagName:          valueFlagName,
 		configOverrideFlagName: configOverrideFlagName,
 	}
+	for _, option := range options {
+		option(envReader)
+	}
+	envReader.imageReader.fetchTimeout = envReader.fetchTimeout
+	envReader.imageReader.excludeExternalFilePaths = envReader.excludeExternalFilePaths
+	envReader.imageReader.strictResolution = envReader.strictResolution
+	return envReader
 }
 
 func (e *envReader) GetEnv(
@@ -190,7 +227,7 @@ func (e *envReader) ListFiles(
 	container app.EnvStdinContainer,
 	value string,
 	configOverride string,
-) (_ []bufcore.FileInfo, retErr error) {
+) (_ []FileInfo, retErr error) {
 	defer func() {
 		if retErr != nil {
 			retErr = fmt.Errorf("%v: %w", e.valueFlagName, retErr)
@@ -215,9 +252,13 @@ func (e *envReader) ListFiles(
 			return nil, err
 		}
 		files := image.Files()
-		fileInfos := make([]bufcore.FileInfo, len(files))
+		fileInfos := make([]FileInfo, len(files))
 		for i, file := range files {
-			fileInfos[i] = file
+			header, err := getImageFileHeader(file)
+			if err != nil {
+				return nil, err
+			}
+			fileInfos[i] = newFileInfo(file, "", header)
 		}
 		return fileInfos, nil
 	case buffetch.SourceRef:
@@ -236,7 +277,30 @@ func (e *envReader) ListFiles(
 		if err != nil {
 			return nil, err
 		}
-		return module.TargetFileInfos(ctx)
+		targetFileInfos, err := module.TargetFileInfos(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roots := config.Build.Roots()
+		fileInfos := make([]FileInfo, len(targetFileInfos))
+		for i, targetFileInfo := range targetFileInfos {
+			rootDirPath, err := getRootDirPath(
+				ctx,
+				readBucketCloser,
+				config.Build.RootToExcludes,
+				roots,
+				targetFileInfo.Path(),
+			)
+			if err != nil {
+				return nil, err
+			}
+			header, err := getModuleFileHeader(ctx, e.logger, module, targetFileInfo.Path())
+			if err != nil {
+				return nil, err
+			}
+			fileInfos[i] = newFileInfo(targetFileInfo, rootDirPath, header)
+		}
+		return fileInfos, nil
 	default:
 		return nil, fmt.Errorf("invalid ref: %T", ref)
 	}
@@ -247,19 +311,34 @@ func (e *envReader) GetConfig(
 	configOverride string,
 ) (*bufconfig.Config, error) {
 	if configOverride != "" {
-		return e.parseConfigOverride(configOverride)
+		config, err := e.parseConfigOverride(configOverride)
+		if err != nil {
+			return nil, newConfigError(err)
+		}
+		return e.getNamedConfig(config)
 	}
 	// if there is no config override, we read the config from the current directory
 	data, err := ioutil.ReadFile(bufconfig.ConfigFilePath)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			return nil, err
+			return nil, newConfigError(err)
 		}
 		// just in case
 		data = nil
 	}
 	// if there was no file, this just returns default config
-	return e.configProvider.GetConfigForData(data)
+	config, err := e.configProvider.GetConfigForData(data)
+	if err != nil {
+		return nil, newConfigError(err)
+	}
+	config, err = e.getNamedConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if !e.allowPlugins {
+		e.removePlugins(config)
+	}
+	return config, nil
 }
 
 func (e *envReader) getEnvFromImage(
@@ -271,21 +350,21 @@ func (e *envReader) getEnvFromImage(
 	excludeSourceCodeInfo bool,
 	imageRef buffetch.ImageRef,
 ) (_ Env, retErr error) {
+	config, err := e.GetConfig(ctx, configOverride)
+	if err != nil {
+		return nil, err
+	}
 	image, err := e.imageReader.getImageForImageRef(
 		ctx,
 		container,
 		externalFilePaths,
 		externalFilePathsAllowNotExist,
-		excludeSourceCodeInfo,
+		e.shouldExcludeSourceCodeInfo(config, excludeSourceCodeInfo),
 		imageRef,
 	)
 	if err != nil {
 		return nil, err
 	}
-	config, err := e.GetConfig(ctx, configOverride)
-	if err != nil {
-		return nil, err
-	}
 	return newEnv(image, config), nil
 }
 
@@ -327,31 +406,119 @@ func (e *envReader) getEnvFromSource(
 			bufmod.WithPathsAllowNotExistOnWalk(),
 		)
 	}
+	if len(e.excludeExternalFilePaths) > 0 {
+		excludeBucketRelPaths := make([]string, len(e.excludeExternalFilePaths))
+		for i, excludeExternalFilePath := range e.excludeExternalFilePaths {
+			excludeBucketRelPath, err := sourceRef.PathForExternalPath(excludeExternalFilePath)
+			if err != nil {
+				return nil, nil, err
+			}
+			excludeBucketRelPaths[i] = excludeBucketRelPath
+		}
+		buildOptions = append(
+			buildOptions,
+			bufmod.WithExcludePaths(excludeBucketRelPaths...),
+		)
+	}
+	fetchCtx, fetchCancel := withPhaseTimeout(ctx, e.fetchTimeout)
+	defer fetchCancel()
+	dependencyBuildOptions, closeDependencies, err := e.dependencyResolver.getBuildOptions(
+		fetchCtx,
+		container,
+		readBucketCloser,
+		config,
+	)
+	if err != nil {
+		return nil, nil, newFetchError(newPhaseTimeoutError(ctx, fetchCtx, fetchPhaseName, e.fetchTimeout, err))
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, closeDependencies())
+	}()
+	buildOptions = append(buildOptions, dependencyBuildOptions...)
+	buildCtx, cancel := withPhaseTimeout(ctx, e.buildTimeout)
+	defer cancel()
 	module, err := e.modBucketBuilder.BuildForBucket(
-		ctx,
+		buildCtx,
 		readBucketCloser,
 		config.Build,
 		buildOptions...,
 	)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, newPhaseTimeoutError(ctx, buildCtx, buildPhaseName, e.buildTimeout, err)
 	}
 	var options []bufbuild.BuildOption
-	if excludeSourceCodeInfo {
+	if e.shouldExcludeSourceCodeInfo(config, excludeSourceCodeInfo) {
 		options = append(options, bufbuild.WithExcludeSourceCodeInfo())
 	}
+	if e.partialBuild {
+		options = append(options, bufbuild.WithPartial())
+	}
+	if e.buildParallelism > 0 {
+		options = append(options, bufbuild.WithParallelism(e.buildParallelism))
+	}
+	if buildCacheDirPath := e.getBuildCacheDirPath(container); buildCacheDirPath != "" {
+		options = append(options, bufbuild.WithCacheDirPath(buildCacheDirPath))
+	}
 	image, fileAnnotations, err := e.buildBuilder.Build(
-		ctx,
+		buildCtx,
 		module,
 		options...,
 	)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, newPhaseTimeoutError(ctx, buildCtx, buildPhaseName, e.buildTimeout, err)
 	}
-	if len(fileAnnotations) > 0 {
+	if image == nil {
 		return nil, fileAnnotations, nil
 	}
-	return newEnv(image, config), nil, nil
+	if !e.sourceReadBucket {
+		return newEnv(image, config), fileAnnotations, nil
+	}
+	// the module reads from the source and dependency buckets, which are
+	// closed once we return, so we copy the files we need now
+	sourceReadBucket, err := getSourceReadBucket(ctx, module, image)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newSourceEnv(image, config, sourceReadBucket), fileAnnotations, nil
+}
+
+// getSourceReadBucket returns a bucket with the contents of the files of the
+// image that are in the module.
+func getSourceReadBucket(
+	ctx context.Context,
+	module bufcore.Module,
+	image bufcore.Image,
+) (storage.ReadBucket, error) {
+	pathToData := make(map[string][]byte)
+	for _, imageFile := range image.Files() {
+		path := imageFile.Path()
+		moduleFile, err := module.GetFile(ctx, path)
+		if err != nil {
+			if storage.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(moduleFile)
+		err = multierr.Append(err, moduleFile.Close())
+		if err != nil {
+			return nil, err
+		}
+		pathToData[path] = data
+	}
+	return storagemem.NewReadBucket(pathToData)
+}
+
+// shouldExcludeSourceCodeInfo returns true if excludeSourceCodeInfo is set, or
+// if the config excludes source code info by default.
+func (e *envReader) shouldExcludeSourceCodeInfo(config *bufconfig.Config, excludeSourceCodeInfo bool) bool {
+	if excludeSourceCodeInfo {
+		return true
+	}
+	if e.configExcludeSourceCodeInfo != nil {
+		return e.configExcludeSourceCodeInfo(config)
+	}
+	return false
 }
 
 func (e *envReader) getSourceBucketAndConfig(
@@ -360,9 +527,11 @@ func (e *envReader) getSourceBucketAndConfig(
 	sourceRef buffetch.SourceRef,
 	configOverride string,
 ) (_ storage.ReadBucketCloser, _ *bufconfig.Config, retErr error) {
-	readBucketCloser, err := e.fetchReader.GetSourceBucket(ctx, container, sourceRef)
+	fetchCtx, cancel := withPhaseTimeout(ctx, e.fetchTimeout)
+	defer cancel()
+	readBucketCloser, err := e.fetchReader.GetSourceBucket(fetchCtx, container, sourceRef)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, newFetchError(newPhaseTimeoutError(ctx, fetchCtx, fetchPhaseName, e.fetchTimeout, err))
 	}
 	defer func() {
 		if retErr != nil {
@@ -377,12 +546,96 @@ func (e *envReader) getSourceBucketAndConfig(
 		// if there was no file, this just returns default config
 		config, err = e.configProvider.GetConfig(ctx, readBucketCloser)
 	}
+	if err != nil {
+		return nil, nil, newConfigError(err)
+	}
+	config, err = e.getNamedConfig(config)
 	if err != nil {
 		return nil, nil, err
 	}
+	// plugins run arbitrary commands, so plugins configured within the input
+	// are only run if allowed and the input is a local directory, and never
+	// for inputs such as archives or git repositories
+	if configOverride == "" && (!e.allowPlugins || sourceRef.LocalDirPath() == "") {
+		e.removePlugins(config)
+	}
+	workspaceBuildConfig, err := e.getWorkspaceBuildConfig(ctx, readBucketCloser)
+	if err != nil {
+		return nil, nil, newConfigError(err)
+	}
+	if workspaceBuildConfig != nil {
+		config.Build = workspaceBuildConfig
+	}
 	return readBucketCloser, config, nil
 }
 
+// removePlugins removes the lint and breaking plugins from the config that
+// was read from the input, and warns if there were any.
+func (e *envReader) removePlugins(config *bufconfig.Config) {
+	var removed bool
+	if config.Lint != nil && len(config.Lint.Plugins) > 0 {
+		config.Lint.Plugins = nil
+		removed = true
+	}
+	if config.Breaking != nil && len(config.Breaking.Plugins) > 0 {
+		config.Breaking.Plugins = nil
+		removed = true
+	}
+	if removed {
+		e.logger.Warn(
+			fmt.Sprintf(
+				"the plugins configured in the %s of the input are not run, as they are only run from the %s of local directory inputs if allowed, or when set with --%s",
+				bufconfig.ConfigFilePath,
+				bufconfig.ConfigFilePath,
+				e.configOverrideFlagName,
+			),
+		)
+	}
+}
+
+// getWorkspaceBuildConfig returns the build config that builds the modules of
+// the workspace together if there is a workspace at the root of the bucket.
+//
+// The build config of each module is read from the buf.yaml within the
+// directory of the module. Only the build configs of the modules are used,
+// the lint and breaking configs are read from the root of the bucket as with
+// any other input.
+//
+// Returns nil if there is no workspace at the root of the bucket.
+func (e *envReader) getWorkspaceBuildConfig(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+) (*bufmod.Config, error) {
+	workConfig, err := bufwork.GetConfigForBucket(ctx, readBucket)
+	if err != nil {
+		return nil, err
+	}
+	if workConfig == nil {
+		return nil, nil
+	}
+	directoryToBuildConfig := make(map[string]*bufmod.Config, len(workConfig.Directories))
+	for _, directory := range workConfig.Directories {
+		moduleConfig, err := e.configProvider.GetConfig(
+			ctx,
+			storage.Map(readBucket, storage.MapOnPrefix(directory)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", directory, err)
+		}
+		directoryToBuildConfig[directory] = moduleConfig.Build
+	}
+	return bufwork.NewBuildConfig(directoryToBuildConfig)
+}
+
+// getNamedConfig returns the config selected with the config name.
+func (e *envReader) getNamedConfig(config *bufconfig.Config) (*bufconfig.Config, error) {
+	config, err := bufconfig.GetNamedConfig(config, e.configName)
+	if err != nil {
+		return nil, newConfigError(err)
+	}
+	return config, nil
+}
+
 func (e *envReader) parseConfigOverride(value string) (*bufconfig.Config, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -391,7 +644,7 @@ func (e *envReader) parseConfigOverride(value string) (*bufconfig.Config, error)
 	var data []byte
 	var err error
 	switch filepath.Ext(value) {
-	case ".json", ".yaml":
+	case ".json", ".yaml", ".yml":
 		data, err = ioutil.ReadFile(value)
 		if err != nil {
 			return nil, fmt.Errorf("%s: could not read file: %v", e.configOverrideFlagName, err)
@@ -405,3 +658,25 @@ func (e *envReader) parseConfigOverride(value string) (*bufconfig.Config, error)
 	}
 	return config, nil
 }
+
+// getBuildCacheDirPath returns empty if the build cache is disabled or the cache
+// directory is not available, in which case built files are not cached.
+func (e *envReader) getBuildCacheDirPath(container app.EnvContainer) string {
+	if !e.buildCache {
+		return ""
+	}
+	cacheDirPath, err := app.CacheDirPath(container)
+	if err != nil {
+		e.logger.Debug("build_cache_disabled", zap.Error(err))
+		return ""
+	}
+	return filepath.Join(cacheDirPath, buildCacheDirName)
+}
+
+func clearBuildCache(envContainer app.EnvContainer) error {
+	cacheDirPath, err := app.CacheDirPath(envContainer)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(cacheDirPath, buildCacheDirName))
+}
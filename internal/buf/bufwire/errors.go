@@ -0,0 +1,79 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"errors"
+)
+
+// IsConfigError returns true for an error that is the result of reading or
+// validating the configuration of an input.
+func IsConfigError(err error) bool {
+	var configError *configError
+	return errors.As(err, &configError)
+}
+
+// IsFetchError returns true for an error that is the result of fetching an
+// input or its dependencies.
+func IsFetchError(err error) bool {
+	var fetchError *fetchError
+	return errors.As(err, &fetchError)
+}
+
+// configError is an error reading or validating the configuration.
+type configError struct {
+	err error
+}
+
+// newConfigError returns a new configError for err, or nil if err is nil.
+func newConfigError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &configError{err: err}
+}
+
+// Error implements error.
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *configError) Unwrap() error {
+	return e.err
+}
+
+// fetchError is an error fetching an input or its dependencies.
+type fetchError struct {
+	err error
+}
+
+// newFetchError returns a new fetchError for err, or nil if err is nil.
+func newFetchError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fetchError{err: err}
+}
+
+// Error implements error.
+func (e *fetchError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
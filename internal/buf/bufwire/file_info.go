@@ -0,0 +1,182 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+var (
+	// editionRegexp matches the edition statement of a file that uses editions.
+	editionRegexp = regexp.MustCompile(`(?m)^\s*edition\s*=\s*"([^"]*)"\s*;`)
+	// packageRegexp matches the package statement of a file.
+	packageRegexp = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+)
+
+type fileInfo struct {
+	bufcore.FileInfo
+
+	rootDirPath string
+	header      *fileHeader
+}
+
+func newFileInfo(bufcoreFileInfo bufcore.FileInfo, rootDirPath string, header *fileHeader) *fileInfo {
+	return &fileInfo{
+		FileInfo:    bufcoreFileInfo,
+		rootDirPath: rootDirPath,
+		header:      header,
+	}
+}
+
+func (f *fileInfo) RootDirPath() string {
+	return f.rootDirPath
+}
+
+func (f *fileInfo) Package() string {
+	return f.header.pkg
+}
+
+func (f *fileInfo) Syntax() string {
+	return f.header.syntax
+}
+
+func (f *fileInfo) Edition() string {
+	return f.header.edition
+}
+
+// fileHeader is the package, syntax, and edition of a file.
+type fileHeader struct {
+	pkg     string
+	syntax  string
+	edition string
+}
+
+// getImageFileHeader gets the header of the image file.
+func getImageFileHeader(imageFile bufcore.ImageFile) (*fileHeader, error) {
+	fileDescriptorProto := imageFile.Proto()
+	edition, err := protodescriptor.GetFileDescriptorProtoEdition(fileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	return &fileHeader{
+		pkg:     fileDescriptorProto.GetPackage(),
+		syntax:  getSyntax(fileDescriptorProto.GetSyntax()),
+		edition: edition,
+	}, nil
+}
+
+// getModuleFileHeader parses the file at the path to get its header.
+//
+// The file is not linked, so its imports are not read. Returns an empty
+// header if the file cannot be parsed.
+func getModuleFileHeader(ctx context.Context, logger *zap.Logger, module bufcore.Module, path string) (*fileHeader, error) {
+	moduleFile, err := module.GetFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(moduleFile)
+	if err != nil {
+		return nil, multierr.Append(err, moduleFile.Close())
+	}
+	if err := moduleFile.Close(); err != nil {
+		return nil, err
+	}
+	parser := protoparse.Parser{
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			if filename != path {
+				return nil, storage.NewErrNotExist(filename)
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+	fileDescriptorProtos, err := parser.ParseFilesButDoNotLink(path)
+	if err != nil {
+		// the parser does not support editions yet, so the header of files
+		// that use editions is read directly
+		if editionMatches := editionRegexp.FindSubmatch(data); editionMatches != nil {
+			header := &fileHeader{
+				syntax:  protodescriptor.SyntaxEditions,
+				edition: string(editionMatches[1]),
+			}
+			if packageMatches := packageRegexp.FindSubmatch(data); packageMatches != nil {
+				header.pkg = string(packageMatches[1])
+			}
+			return header, nil
+		}
+		logger.Debug("parse_package", zap.String("path", path), zap.Error(err))
+		return &fileHeader{}, nil
+	}
+	if len(fileDescriptorProtos) != 1 {
+		return &fileHeader{}, nil
+	}
+	return &fileHeader{
+		pkg:    fileDescriptorProtos[0].GetPackage(),
+		syntax: getSyntax(fileDescriptorProtos[0].GetSyntax()),
+	}, nil
+}
+
+// getSyntax returns the syntax, which is proto2 if it is not set.
+func getSyntax(syntax string) string {
+	if syntax == "" {
+		return "proto2"
+	}
+	return syntax
+}
+
+// getRootDirPath gets the root that contains the path.
+//
+// The roots cannot contain the same paths, so this returns the first
+// root that contains the path and does not exclude it.
+// Returns empty if no root contains the path.
+func getRootDirPath(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	rootToExcludes map[string][]string,
+	roots []string,
+	path string,
+) (string, error) {
+	for _, root := range roots {
+		excluded := false
+		for _, exclude := range rootToExcludes[root] {
+			if normalpath.EqualsOrContainsPath(exclude, path, normalpath.Relative) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+		exists, err := storage.Exists(ctx, readBucket, normalpath.Join(root, path))
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return root, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte("PK\x03\x04")
+	// the keywords that a .proto file can start with, after any comments
+	protoFileKeywords = [][]byte{
+		[]byte("syntax"),
+		[]byte("edition"),
+		[]byte("package"),
+		[]byte("import"),
+		[]byte("option"),
+		[]byte("message"),
+		[]byte("enum"),
+		[]byte("service"),
+		[]byte("//"),
+		[]byte("/*"),
+	}
+	// the field names of an Image that its text encoding can start with
+	imageTextFieldNames = [][]byte{
+		[]byte("file"),
+		[]byte("bufbuild_image_extension"),
+	}
+)
+
+const (
+	// imageExtensionFieldNumber is the field number of Image.bufbuild_image_extension.
+	imageExtensionFieldNumber = 8042
+	// tarMagicOffset is the offset of "ustar" in the header of a tar archive.
+	tarMagicOffset = 257
+)
+
+// readDetectedImageData reads and decompresses all of the image data from
+// the reader, and detects its encoding from the content.
+//
+// This is used for stdin, where there is no extension to infer the format
+// and compression from. Gzip and zstd compression are detected from their
+// magic numbers, JSON from its first byte, the text encoding from the name
+// of its first field, and the binary encoding from the first field of an Image.
+func readDetectedImageData(reader io.Reader) ([]byte, buffetch.ImageEncoding, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		gzipReader, err := pgzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, err
+		}
+		data, err = ioutil.ReadAll(gzipReader)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not decompress gzip data: %v", err)
+		}
+	case bytes.HasPrefix(data, zstdMagic):
+		zstdDecoder, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer zstdDecoder.Close()
+		data, err = zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not decompress zstd data: %v", err)
+		}
+	}
+	imageEncoding, err := detectImageEncoding(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, imageEncoding, nil
+}
+
+// detectImageEncoding detects the encoding of the uncompressed image data.
+func detectImageEncoding(data []byte) (buffetch.ImageEncoding, error) {
+	// an empty binary Image is empty
+	if len(data) == 0 {
+		return buffetch.ImageEncodingBin, nil
+	}
+	trimmedData := bytes.TrimLeft(data, " \t\r\n")
+	if bytes.HasPrefix(trimmedData, []byte("{")) {
+		return buffetch.ImageEncodingJSON, nil
+	}
+	if bytes.HasPrefix(data, zipMagic) ||
+		(len(data) > tarMagicOffset+5 && bytes.Equal(data[tarMagicOffset:tarMagicOffset+5], []byte("ustar"))) {
+		return 0, errors.New(`could not detect the format of stdin: the data is an archive, but only images are detected, set the format explicitly to read a source archive, for example "-#format=tar"`)
+	}
+	for _, keyword := range protoFileKeywords {
+		if bytes.HasPrefix(trimmedData, keyword) {
+			return 0, errors.New("could not detect the format of stdin: the data is a .proto file and not an image, use a directory or archive input to build .proto files")
+		}
+	}
+	for _, fieldName := range imageTextFieldNames {
+		if rest := bytes.TrimPrefix(trimmedData, fieldName); len(rest) < len(trimmedData) {
+			if rest = bytes.TrimLeft(rest, " \t\r\n"); len(rest) > 0 && (rest[0] == ':' || rest[0] == '{') {
+				return buffetch.ImageEncodingText, nil
+			}
+		}
+	}
+	if number, wireType, n := protowire.ConsumeTag(data); n > 0 && wireType == protowire.BytesType {
+		if number == imageFileFieldNumber || number == imageExtensionFieldNumber {
+			return buffetch.ImageEncodingBin, nil
+		}
+	}
+	return 0, errors.New(`could not detect the format of stdin as a binary, JSON, or text image, set the format explicitly, for example "-#format=bin" or "-#format=json"`)
+}
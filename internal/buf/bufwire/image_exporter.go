@@ -0,0 +1,70 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoprint"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type imageExporter struct {
+	logger *zap.Logger
+}
+
+func newImageExporter(logger *zap.Logger) *imageExporter {
+	return &imageExporter{
+		logger: logger.Named("bufwire"),
+	}
+}
+
+func (i *imageExporter) ExportImage(
+	ctx context.Context,
+	image bufcore.Image,
+	writeBucket storage.WriteBucket,
+	excludeImports bool,
+) error {
+	imageFiles := image.Files()
+	defer instrument.Start(i.logger, "export_image", zap.Int("num_files", len(imageFiles))).End()
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(imageFiles))
+	for j, imageFile := range imageFiles {
+		fileDescriptorProtos[j] = imageFile.Proto()
+	}
+	fileDescriptors, err := desc.CreateFileDescriptors(fileDescriptorProtos)
+	if err != nil {
+		return err
+	}
+	printer := &protoprint.Printer{}
+	for _, imageFile := range imageFiles {
+		if excludeImports && imageFile.IsImport() {
+			continue
+		}
+		buffer := bytes.NewBuffer(nil)
+		if err := printer.PrintProtoFile(fileDescriptors[imageFile.Path()], buffer); err != nil {
+			return err
+		}
+		if err := storage.PutPath(ctx, writeBucket, imageFile.Path(), buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
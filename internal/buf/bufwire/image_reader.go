@@ -15,18 +15,25 @@
 package bufwire
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"time"
 
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/buffetch"
 	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
 	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type imageReader struct {
@@ -34,6 +41,12 @@ type imageReader struct {
 	fetchImageRefParser buffetch.ImageRefParser
 	fetchReader         buffetch.Reader
 	valueFlagName       string
+	fetchTimeout        time.Duration
+
+	excludeExternalFilePaths []string
+	strictResolution         bool
+
+	jsonUnmarshalerOptions []protoencoding.JSONUnmarshalerOption
 }
 
 func newImageReader(
@@ -41,13 +54,18 @@ func newImageReader(
 	fetchImageRefParser buffetch.ImageRefParser,
 	fetchReader buffetch.Reader,
 	valueFlagName string,
+	options ...ImageReaderOption,
 ) *imageReader {
-	return &imageReader{
+	imageReader := &imageReader{
 		logger:              logger.Named("bufwire"),
 		fetchImageRefParser: fetchImageRefParser,
 		fetchReader:         fetchReader,
 		valueFlagName:       valueFlagName,
 	}
+	for _, option := range options {
+		option(imageReader)
+	}
+	return imageReader
 }
 
 func (i *imageReader) GetImage(
@@ -78,6 +96,37 @@ func (i *imageReader) GetImage(
 	)
 }
 
+func (i *imageReader) VerifyImage(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	value string,
+) (_ []bufanalysis.FileAnnotation, retErr error) {
+	defer instrument.Start(i.logger, "verify_image").End()
+	defer func() {
+		if retErr != nil {
+			retErr = fmt.Errorf("%v: %w", i.valueFlagName, retErr)
+		}
+	}()
+	imageRef, err := i.fetchImageRefParser.GetImageRef(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	protoImage, err := i.getProtoImage(
+		ctx,
+		container,
+		imageRef,
+		false,
+		func(fileAnnotation bufanalysis.FileAnnotation) {
+			fileAnnotations = append(fileAnnotations, fileAnnotation)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return append(fileAnnotations, verifyProtoImage(protoImage)...), nil
+}
+
 func (i *imageReader) getImageForImageRef(
 	ctx context.Context,
 	container app.EnvStdinContainer,
@@ -86,81 +135,227 @@ func (i *imageReader) getImageForImageRef(
 	excludeSourceCodeInfo bool,
 	imageRef buffetch.ImageRef,
 ) (_ bufcore.Image, retErr error) {
-	readCloser, err := i.fetchReader.GetImageFile(ctx, container, imageRef)
+	protoImage, err := i.getProtoImage(ctx, container, imageRef, excludeSourceCodeInfo, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		retErr = multierr.Append(retErr, readCloser.Close())
-	}()
-	data, err := ioutil.ReadAll(readCloser)
+	if i.strictResolution {
+		// the resolver used to read the image allows unresolvable imports
+		// and types, so that most images can be read, and we check them here
+		if _, err := protoencoding.NewStrictResolver(protoImage.File...); err != nil {
+			return nil, fmt.Errorf("image is not self-contained: %v", err)
+		}
+	}
+	if len(protoImage.File) >= protodescriptor.InternMinFileDescriptorProtos {
+		// the unmarshaled strings of each file are separate allocations
+		protodescriptor.InternFileDescriptorProtoStrings(protoImage.File...)
+	}
+	image, err := bufcore.NewImageForProto(protoImage)
 	if err != nil {
 		return nil, err
 	}
-	protoImage := &imagev1.Image{}
-	switch imageEncoding := imageRef.ImageEncoding(); imageEncoding {
-	// we have to double parse due to custom options
-	// See https://github.com/golang/protobuf/issues/1123
-	// TODO: revisit
-	case buffetch.ImageEncodingBin:
-		firstProtoImage := &imagev1.Image{}
-		timer := instrument.Start(i.logger, "first_wire_unmarshal")
-		if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, firstProtoImage); err != nil {
-			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
-		}
-		timer.End()
-		timer = instrument.Start(i.logger, "new_resolver")
-		// TODO right now, NewResolver sets AllowUnresolvable to true all the time
-		// we want to make this into a check, and we verify if we need this for the individual command
-		resolver, err := protoencoding.NewResolver(
-			firstProtoImage.File...,
-		)
+	if len(externalFilePaths) > 0 {
+		imagePaths, err := getImagePaths(imageRef, externalFilePaths)
 		if err != nil {
 			return nil, err
 		}
-		timer.End()
-		timer = instrument.Start(i.logger, "second_wire_unmarshal")
-		if err := protoencoding.NewWireUnmarshaler(resolver).Unmarshal(data, protoImage); err != nil {
-			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+		if externalFilePathsAllowNotExist {
+			// externalFilePaths have to be targetPaths
+			// TODO: evaluate this
+			image, err = bufcore.ImageWithOnlyPathsAllowNotExist(image, imagePaths)
+		} else {
+			image, err = bufcore.ImageWithOnlyPaths(image, imagePaths)
 		}
-		timer.End()
-	case buffetch.ImageEncodingJSON:
-		firstProtoImage := &imagev1.Image{}
-		timer := instrument.Start(i.logger, "first_json_unmarshal")
-		if err := protoencoding.NewJSONUnmarshaler(nil).Unmarshal(data, firstProtoImage); err != nil {
-			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+		if err != nil {
+			return nil, err
 		}
-		// TODO right now, NewResolver sets AllowUnresolvable to true all the time
-		// we want to make this into a check, and we verify if we need this for the individual command
-		timer.End()
-		timer = instrument.Start(i.logger, "new_resolver")
-		resolver, err := protoencoding.NewResolver(
-			firstProtoImage.File...,
-		)
+	}
+	if len(i.excludeExternalFilePaths) > 0 {
+		excludeImagePaths, err := getImagePaths(imageRef, i.excludeExternalFilePaths)
 		if err != nil {
 			return nil, err
 		}
-		timer.End()
-		timer = instrument.Start(i.logger, "second_json_unmarshal")
-		if err := protoencoding.NewJSONUnmarshaler(resolver).Unmarshal(data, protoImage); err != nil {
-			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+		return bufcore.ImageWithoutPaths(image, excludeImagePaths)
+	}
+	return image, nil
+}
+
+// getProtoImage reads and decodes the image file of the ref.
+//
+// If invalidFileFunc is set, the image is being verified. Binary files that
+// cannot be unmarshaled are passed to invalidFileFunc and skipped, and custom
+// options are not resolved, so that problems with the files are left for the
+// verification to report instead of failing the read.
+func (i *imageReader) getProtoImage(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	imageRef buffetch.ImageRef,
+	excludeSourceCodeInfo bool,
+	invalidFileFunc func(bufanalysis.FileAnnotation),
+) (*imagev1.Image, error) {
+	var protoImage *imagev1.Image
+	imageEncoding := imageRef.ImageEncoding()
+	// if the encoding is detected, the data has already been read
+	var detectedData []byte
+	if imageRef.DetectEncoding() {
+		if err := i.readImageFile(
+			ctx,
+			container,
+			imageRef,
+			func(reader io.Reader) error {
+				var err error
+				detectedData, imageEncoding, err = readDetectedImageData(reader)
+				return err
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	switch imageEncoding {
+	case buffetch.ImageEncodingBin:
+		var binaryInvalidFileFunc func(int, string, error)
+		if invalidFileFunc != nil {
+			binaryInvalidFileFunc = func(index int, name string, err error) {
+				invalidFileFunc(newInvalidFileAnnotation(index, name, err))
+			}
+		}
+		timer := instrument.Start(i.logger, "wire_unmarshal")
+		readImage := func(reader io.Reader) error {
+			var err error
+			protoImage, err = readBinaryProtoImage(reader, excludeSourceCodeInfo, binaryInvalidFileFunc)
+			if err != nil {
+				return fmt.Errorf("could not unmarshal Image: %v", err)
+			}
+			return nil
+		}
+		if imageRef.DetectEncoding() {
+			if err := readImage(bytes.NewReader(detectedData)); err != nil {
+				return nil, err
+			}
+		} else if err := i.readImageFile(ctx, container, imageRef, readImage); err != nil {
+			return nil, err
 		}
 		timer.End()
+	case buffetch.ImageEncodingJSON, buffetch.ImageEncodingText:
+		// custom options in JSON and text images cannot be parsed without
+		// a resolver, so the verification works with what was parsed
+		resolve := invalidFileFunc == nil
+		if imageRef.DetectEncoding() {
+			var err error
+			protoImage, err = i.unmarshalTextProtoImage(detectedData, imageEncoding, resolve)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err := i.readImageFile(
+			ctx,
+			container,
+			imageRef,
+			func(reader io.Reader) error {
+				var data []byte
+				if bytesReader, ok := reader.(interface{ Bytes() []byte }); ok {
+					// the data is memory-mapped, and we unmarshal it before
+					// the mapping is closed, which copies all strings
+					data = bytesReader.Bytes()
+				} else {
+					var err error
+					data, err = ioutil.ReadAll(reader)
+					if err != nil {
+						return err
+					}
+				}
+				var err error
+				protoImage, err = i.unmarshalTextProtoImage(data, imageEncoding, resolve)
+				return err
+			},
+		); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unknown image encoding: %v", imageEncoding)
 	}
+	if compactSourceCodeInfo := protoImage.GetBufbuildImageExtension().GetCompactSourceCodeInfo(); len(compactSourceCodeInfo) > 0 {
+		// no need to decompress if we are excluding it anyways
+		if !excludeSourceCodeInfo {
+			if err := restoreCompactSourceCodeInfo(protoImage.File, compactSourceCodeInfo); err != nil {
+				return nil, fmt.Errorf("could not read compact source code info: %v", err)
+			}
+		}
+		protoImage.BufbuildImageExtension.CompactSourceCodeInfo = nil
+	}
 	if excludeSourceCodeInfo {
 		for _, fileDescriptorProto := range protoImage.File {
 			fileDescriptorProto.SourceCodeInfo = nil
 		}
 	}
-	image, err := bufcore.NewImageForProto(protoImage)
-	if err != nil {
-		return nil, err
+	return protoImage, nil
+}
+
+// unmarshalTextProtoImage unmarshals a JSON or text image.
+//
+// If resolve is false, custom options are not resolved.
+func (i *imageReader) unmarshalTextProtoImage(
+	data []byte,
+	imageEncoding buffetch.ImageEncoding,
+	resolve bool,
+) (*imagev1.Image, error) {
+	newUnmarshaler := func(resolver protoencoding.Resolver, withOptions bool) protoencoding.Unmarshaler {
+		if imageEncoding == buffetch.ImageEncodingText {
+			return protoencoding.NewTextUnmarshaler(resolver)
+		}
+		if withOptions {
+			return protoencoding.NewJSONUnmarshaler(resolver, i.jsonUnmarshalerOptions...)
+		}
+		return protoencoding.NewJSONUnmarshaler(resolver)
+	}
+	if !resolve {
+		protoImage := &imagev1.Image{}
+		if err := newUnmarshaler(nil, false).Unmarshal(data, protoImage); err != nil {
+			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+		}
+		return protoImage, nil
 	}
-	if len(externalFilePaths) == 0 {
-		return image, nil
+	// custom options can only be parsed with a resolver for the files
+	// that define them, which are contained in the image itself, so we
+	// resolve them from the files that have been unmarshaled so far,
+	// which works in a single pass as files come after their imports
+	//
+	// unresolvable imports and types are allowed, strict resolution is
+	// checked separately if the command requires it
+	timer := instrument.Start(i.logger, "unmarshal")
+	protoImage := &imagev1.Image{}
+	lazyResolver := protoencoding.NewLazyResolver(
+		func() []*descriptorpb.FileDescriptorProto {
+			return protoImage.File
+		},
+	)
+	if err := newUnmarshaler(lazyResolver, true).Unmarshal(data, protoImage); err != nil {
+		return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+	}
+	timer.End()
+	if lazyResolver.Incomplete() {
+		// a custom option was used before the file that defines it, such
+		// as in the defining file itself, and was discarded, so we have to
+		// parse again with a resolver for all files
+		// See https://github.com/golang/protobuf/issues/1123
+		timer = instrument.Start(i.logger, "second_unmarshal")
+		resolver, err := protoencoding.NewResolver(
+			protoImage.File...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		protoImage = &imagev1.Image{}
+		if err := newUnmarshaler(resolver, true).Unmarshal(data, protoImage); err != nil {
+			return nil, fmt.Errorf("could not unmarshal Image: %v", err)
+		}
+		timer.End()
 	}
+	return protoImage, nil
+}
+
+func getImagePaths(imageRef buffetch.ImageRef, externalFilePaths []string) ([]string, error) {
 	imagePaths := make([]string, len(externalFilePaths))
 	for i, externalFilePath := range externalFilePaths {
 		imagePath, err := imageRef.PathForExternalPath(externalFilePath)
@@ -169,10 +364,64 @@ func (i *imageReader) getImageForImageRef(
 		}
 		imagePaths[i] = imagePath
 	}
-	if externalFilePathsAllowNotExist {
-		// externalFilePaths have to be targetPaths
-		// TODO: evaluate this
-		return bufcore.ImageWithOnlyPathsAllowNotExist(image, imagePaths)
+	return imagePaths, nil
+}
+
+// restoreCompactSourceCodeInfo sets the SourceCodeInfo of the FileDescriptorProtos
+// from the CompactSourceCodeInfo field of an ImageExtension.
+func restoreCompactSourceCodeInfo(
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+	compactSourceCodeInfo []byte,
+) error {
+	zstdDecoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return err
+	}
+	defer zstdDecoder.Close()
+	data, err := zstdDecoder.DecodeAll(compactSourceCodeInfo, nil)
+	if err != nil {
+		return err
+	}
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, fileDescriptorSet); err != nil {
+		return err
+	}
+	pathToSourceCodeInfo := make(map[string]*descriptorpb.SourceCodeInfo, len(fileDescriptorSet.File))
+	for _, fileDescriptorProto := range fileDescriptorSet.File {
+		pathToSourceCodeInfo[fileDescriptorProto.GetName()] = fileDescriptorProto.GetSourceCodeInfo()
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if sourceCodeInfo, ok := pathToSourceCodeInfo[fileDescriptorProto.GetName()]; ok {
+			fileDescriptorProto.SourceCodeInfo = sourceCodeInfo
+		}
 	}
-	return bufcore.ImageWithOnlyPaths(image, imagePaths)
+	return nil
+}
+
+// readImageFile calls f with the image file of the ref within the fetch timeout.
+//
+// Reading of the file may be interleaved with decoding it, so the fetch
+// timeout applies to the whole of f.
+//
+// The reader may be memory-mapped, in which case it has a Bytes() []byte
+// method. The data must not be used after f returns.
+func (i *imageReader) readImageFile(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	imageRef buffetch.ImageRef,
+	f func(io.Reader) error,
+) (retErr error) {
+	fetchCtx, cancel := withPhaseTimeout(ctx, i.fetchTimeout)
+	defer cancel()
+	defer func() {
+		retErr = newPhaseTimeoutError(ctx, fetchCtx, fetchPhaseName, i.fetchTimeout, retErr)
+	}()
+	readCloser, err := i.fetchReader.GetImageFile(fetchCtx, container, imageRef)
+	if err != nil {
+		return newFetchError(err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	return f(readCloser)
 }
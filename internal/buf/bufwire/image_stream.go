@@ -0,0 +1,283 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// imageFileFieldNumber is the field number of Image.file.
+	imageFileFieldNumber = 1
+	// fileDescriptorProtoNameFieldNumber is the field number of FileDescriptorProto.name.
+	fileDescriptorProtoNameFieldNumber = 1
+)
+
+// readBinaryProtoImage reads a binary Image from the reader in a single pass.
+//
+// Instead of reading the entire Image into memory and unmarshaling it twice,
+// once to build a resolver for custom options and then again with the resolver,
+// each file is unmarshaled as it is read, and custom options are resolved
+// once all files have been read. This means that the raw data of at most one
+// file is held in memory at a time.
+//
+// If the reader is backed by memory-mapped data, as is the case for local
+// files, the files are unmarshaled directly from the mapped data without
+// copying it first. Unmarshaling copies all strings and bytes, so nothing
+// refers to the mapped data once this returns.
+//
+// If excludeSourceCodeInfo is true, SourceCodeInfo is dropped from each file
+// as it is read.
+//
+// If invalidFileFunc is set, files that cannot be unmarshaled are passed to it
+// with their index and name, if the name can be read, and skipped, and custom
+// options are not resolved. Otherwise, an error is returned for such files.
+func readBinaryProtoImage(
+	reader io.Reader,
+	excludeSourceCodeInfo bool,
+	invalidFileFunc func(index int, name string, err error),
+) (*imagev1.Image, error) {
+	var fieldReader binaryFieldReader
+	if bytesReader, ok := reader.(interface{ Bytes() []byte }); ok {
+		fieldReader = newDataBinaryFieldReader(bytesReader.Bytes())
+	} else {
+		fieldReader = newStreamBinaryFieldReader(reader)
+	}
+	wireUnmarshaler := protoencoding.NewWireUnmarshaler(nil)
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	// all fields other than file are small, so we collect their raw data and
+	// unmarshal them into the Image at the end
+	var otherData []byte
+	fileIndex := -1
+	for {
+		number, wireType, value, err := fieldReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if number == imageFileFieldNumber && wireType == protowire.BytesType {
+			fileIndex++
+			fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+			if err := wireUnmarshaler.Unmarshal(value, fileDescriptorProto); err != nil {
+				name := getFileDescriptorProtoName(value)
+				if invalidFileFunc != nil {
+					invalidFileFunc(fileIndex, name, err)
+					continue
+				}
+				if name != "" {
+					return nil, fmt.Errorf("could not unmarshal file %s: %v", name, err)
+				}
+				return nil, fmt.Errorf("could not unmarshal file at index %d: %v", fileIndex, err)
+			}
+			if excludeSourceCodeInfo {
+				fileDescriptorProto.SourceCodeInfo = nil
+			}
+			fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+			continue
+		}
+		otherData = protowire.AppendTag(otherData, number, wireType)
+		if wireType == protowire.BytesType {
+			otherData = protowire.AppendBytes(otherData, value)
+		} else {
+			otherData = append(otherData, value...)
+		}
+	}
+	protoImage := &imagev1.Image{}
+	if err := wireUnmarshaler.Unmarshal(otherData, protoImage); err != nil {
+		return nil, err
+	}
+	protoImage.File = fileDescriptorProtos
+	if invalidFileFunc != nil {
+		return protoImage, nil
+	}
+	// unresolvable imports and types are allowed, strict resolution is
+	// checked separately if the command requires it
+	resolver, err := protoencoding.NewResolver(fileDescriptorProtos...)
+	if err != nil {
+		return nil, err
+	}
+	if resolver != nil {
+		for _, fileDescriptorProto := range fileDescriptorProtos {
+			if err := protoencoding.ResolveUnknownFields(resolver, fileDescriptorProto); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return protoImage, nil
+}
+
+// binaryFieldReader reads the top-level fields of a binary message.
+type binaryFieldReader interface {
+	// Next returns the next field.
+	//
+	// For length-delimited fields, the value is the data without the length.
+	// For all other fields, the value is the encoded value.
+	// The value is only valid until the next call to Next.
+	//
+	// Returns io.EOF if there are no more fields.
+	Next() (protowire.Number, protowire.Type, []byte, error)
+}
+
+type streamBinaryFieldReader struct {
+	bufioReader *bufio.Reader
+	buffer      []byte
+}
+
+func newStreamBinaryFieldReader(reader io.Reader) *streamBinaryFieldReader {
+	return &streamBinaryFieldReader{
+		bufioReader: bufio.NewReader(reader),
+	}
+}
+
+func (r *streamBinaryFieldReader) Next() (protowire.Number, protowire.Type, []byte, error) {
+	tag, err := binary.ReadUvarint(r.bufioReader)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	number, wireType := protowire.DecodeTag(tag)
+	if number < protowire.MinValidNumber {
+		return 0, 0, nil, fmt.Errorf("invalid field number %d", number)
+	}
+	switch wireType {
+	case protowire.VarintType:
+		varint, err := binary.ReadUvarint(r.bufioReader)
+		if err != nil {
+			return 0, 0, nil, unexpectedEOF(err)
+		}
+		return number, wireType, protowire.AppendVarint(nil, varint), nil
+	case protowire.Fixed32Type, protowire.Fixed64Type:
+		size := 4
+		if wireType == protowire.Fixed64Type {
+			size = 8
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(r.bufioReader, value); err != nil {
+			return 0, 0, nil, unexpectedEOF(err)
+		}
+		return number, wireType, value, nil
+	case protowire.BytesType:
+		size, err := binary.ReadUvarint(r.bufioReader)
+		if err != nil {
+			return 0, 0, nil, unexpectedEOF(err)
+		}
+		if size > math.MaxInt32 {
+			return 0, 0, nil, fmt.Errorf("field %d has length %d which exceeds the maximum message size", number, size)
+		}
+		if uint64(cap(r.buffer)) < size {
+			r.buffer = make([]byte, size)
+		}
+		value := r.buffer[:size]
+		if _, err := io.ReadFull(r.bufioReader, value); err != nil {
+			return 0, 0, nil, unexpectedEOF(err)
+		}
+		return number, wireType, value, nil
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported wire type %d for field %d", wireType, number)
+	}
+}
+
+type dataBinaryFieldReader struct {
+	data []byte
+}
+
+func newDataBinaryFieldReader(data []byte) *dataBinaryFieldReader {
+	return &dataBinaryFieldReader{
+		data: data,
+	}
+}
+
+func (r *dataBinaryFieldReader) Next() (protowire.Number, protowire.Type, []byte, error) {
+	if len(r.data) == 0 {
+		return 0, 0, nil, io.EOF
+	}
+	number, wireType, n := protowire.ConsumeTag(r.data)
+	if n < 0 {
+		return 0, 0, nil, protowire.ParseError(n)
+	}
+	r.data = r.data[n:]
+	var value []byte
+	switch wireType {
+	case protowire.VarintType:
+		_, n = protowire.ConsumeVarint(r.data)
+		if n >= 0 {
+			value = r.data[:n]
+		}
+	case protowire.Fixed32Type:
+		_, n = protowire.ConsumeFixed32(r.data)
+		if n >= 0 {
+			value = r.data[:n]
+		}
+	case protowire.Fixed64Type:
+		_, n = protowire.ConsumeFixed64(r.data)
+		if n >= 0 {
+			value = r.data[:n]
+		}
+	case protowire.BytesType:
+		value, n = protowire.ConsumeBytes(r.data)
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported wire type %d for field %d", wireType, number)
+	}
+	if n < 0 {
+		return 0, 0, nil, protowire.ParseError(n)
+	}
+	r.data = r.data[n:]
+	return number, wireType, value, nil
+}
+
+// getFileDescriptorProtoName returns the name of the FileDescriptorProto in
+// the data on a best-effort basis, without unmarshaling the rest of it.
+//
+// Returns empty if the name cannot be read.
+func getFileDescriptorProtoName(data []byte) string {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return ""
+		}
+		data = data[n:]
+		if number == fileDescriptorProtoNameFieldNumber && wireType == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return ""
+			}
+			return string(value)
+		}
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return ""
+		}
+		data = data[n:]
+	}
+	return ""
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,460 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	verifyTypeInvalidFile           = "INVALID_FILE"
+	verifyTypeDuplicateFile         = "DUPLICATE_FILE"
+	verifyTypeUnresolvedImport      = "UNRESOLVED_IMPORT"
+	verifyTypeUnresolvedType        = "UNRESOLVED_TYPE"
+	verifyTypeUnresolvedOption      = "UNRESOLVED_OPTION"
+	verifyTypeInvalidSourceCodeInfo = "INVALID_SOURCE_CODE_INFO"
+	verifyTypeInvalidImage          = "INVALID_IMAGE"
+)
+
+// verifyProtoImage verifies the internal consistency of the Image.
+//
+// The FileDescriptorProtos are expected to not have had their custom options
+// resolved, so that unresolvable custom options are still unknown fields.
+func verifyProtoImage(protoImage *imagev1.Image) []bufanalysis.FileAnnotation {
+	verifier := newImageVerifier(protoImage.File)
+	for _, fileDescriptorProto := range protoImage.File {
+		verifier.verifyFile(fileDescriptorProto)
+	}
+	if len(verifier.fileAnnotations) > 0 {
+		return verifier.fileAnnotations
+	}
+	// the checks above are meant to explain the common problems with
+	// context, this catches anything else that would fail a read
+	if _, err := bufcore.NewImageForProto(protoImage); err != nil {
+		return []bufanalysis.FileAnnotation{newVerifyFileAnnotation(nil, nil, verifyTypeInvalidImage, err.Error())}
+	}
+	if _, err := (protodesc.FileOptions{}).NewFiles(&descriptorpb.FileDescriptorSet{File: protodescriptor.GetResolvableFileDescriptorProtos(protoImage.File...)}); err != nil {
+		return []bufanalysis.FileAnnotation{newVerifyFileAnnotation(nil, nil, verifyTypeInvalidImage, err.Error())}
+	}
+	return nil
+}
+
+func newInvalidFileAnnotation(index int, name string, err error) bufanalysis.FileAnnotation {
+	if name == "" {
+		return newVerifyFileAnnotation(
+			nil,
+			nil,
+			verifyTypeInvalidFile,
+			fmt.Sprintf("File at index %d could not be unmarshaled: %v.", index, err),
+		)
+	}
+	return newVerifyFileAnnotation(
+		newVerifyFileInfo(name),
+		nil,
+		verifyTypeInvalidFile,
+		fmt.Sprintf("File could not be unmarshaled: %v.", err),
+	)
+}
+
+type imageVerifier struct {
+	pathToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto
+	// pathToFullNames contains the messages and enums defined in each file.
+	pathToFullNames map[string]map[string]struct{}
+	// extendeeToNumbers contains the extensions defined anywhere in the image.
+	extendeeToNumbers map[string]map[int32]struct{}
+	fileAnnotations   []bufanalysis.FileAnnotation
+
+	// the state for the file being verified
+	fileInfo          *verifyFileInfo
+	pathKeyToLocation map[string]*descriptorpb.SourceCodeInfo_Location
+	visibleFullNames  map[string]struct{}
+}
+
+func newImageVerifier(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) *imageVerifier {
+	verifier := &imageVerifier{
+		pathToFileDescriptorProto: make(map[string]*descriptorpb.FileDescriptorProto),
+		pathToFullNames:           make(map[string]map[string]struct{}),
+		extendeeToNumbers:         make(map[string]map[int32]struct{}),
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		path := fileDescriptorProto.GetName()
+		if _, ok := verifier.pathToFileDescriptorProto[path]; ok {
+			continue
+		}
+		verifier.pathToFileDescriptorProto[path] = fileDescriptorProto
+		fullNames := make(map[string]struct{})
+		scope := fileDescriptorProto.GetPackage()
+		verifier.addExtensions(fileDescriptorProto.GetExtension())
+		for _, descriptorProto := range fileDescriptorProto.GetMessageType() {
+			verifier.addMessage(fullNames, scope, descriptorProto)
+		}
+		for _, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+			fullNames[joinFullName(scope, enumDescriptorProto.GetName())] = struct{}{}
+		}
+		verifier.pathToFullNames[path] = fullNames
+	}
+	return verifier
+}
+
+func (v *imageVerifier) addMessage(fullNames map[string]struct{}, scope string, descriptorProto *descriptorpb.DescriptorProto) {
+	fullName := joinFullName(scope, descriptorProto.GetName())
+	fullNames[fullName] = struct{}{}
+	v.addExtensions(descriptorProto.GetExtension())
+	for _, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		v.addMessage(fullNames, fullName, nestedDescriptorProto)
+	}
+	for _, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		fullNames[joinFullName(fullName, enumDescriptorProto.GetName())] = struct{}{}
+	}
+}
+
+func (v *imageVerifier) addExtensions(fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto) {
+	for _, fieldDescriptorProto := range fieldDescriptorProtos {
+		extendee := strings.TrimPrefix(fieldDescriptorProto.GetExtendee(), ".")
+		numbers, ok := v.extendeeToNumbers[extendee]
+		if !ok {
+			numbers = make(map[int32]struct{})
+			v.extendeeToNumbers[extendee] = numbers
+		}
+		numbers[fieldDescriptorProto.GetNumber()] = struct{}{}
+	}
+}
+
+func (v *imageVerifier) verifyFile(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	path := fileDescriptorProto.GetName()
+	v.fileInfo = newVerifyFileInfo(path)
+	v.pathKeyToLocation = make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		pathKey := getVerifyPathKey(location.GetPath())
+		if _, ok := v.pathKeyToLocation[pathKey]; !ok {
+			v.pathKeyToLocation[pathKey] = location
+		}
+	}
+	if v.pathToFileDescriptorProto[path] != fileDescriptorProto {
+		v.add(nil, verifyTypeDuplicateFile, "File is contained in the image more than once.")
+		return
+	}
+	if err := protodescriptor.ValidateFileDescriptorProto(fileDescriptorProto); err != nil {
+		v.add(nil, verifyTypeInvalidFile, fmt.Sprintf("File is invalid: %v.", err))
+		return
+	}
+	for i, dependency := range fileDescriptorProto.GetDependency() {
+		if _, ok := v.pathToFileDescriptorProto[dependency]; !ok {
+			v.add(
+				[]int32{3, int32(i)},
+				verifyTypeUnresolvedImport,
+				fmt.Sprintf("Import %q is not contained in the image.", dependency),
+			)
+		}
+	}
+	v.visibleFullNames = v.getVisibleFullNames(fileDescriptorProto)
+	scope := fileDescriptorProto.GetPackage()
+	v.verifyOptions(fileDescriptorProto.GetOptions(), []int32{8}, fmt.Sprintf("file %q", path))
+	for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+		v.verifyMessage(descriptorProto, scope, []int32{4, int32(i)})
+	}
+	for i, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+		v.verifyEnum(enumDescriptorProto, scope, []int32{5, int32(i)})
+	}
+	for i, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		v.verifyService(serviceDescriptorProto, scope, []int32{6, int32(i)})
+	}
+	for i, fieldDescriptorProto := range fileDescriptorProto.GetExtension() {
+		v.verifyField(fieldDescriptorProto, scope, []int32{7, int32(i)})
+	}
+	fileMessage := fileDescriptorProto.ProtoReflect()
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		if !isValidSourceCodeInfoPath(fileMessage, location.GetPath()) {
+			v.addForLocation(
+				location,
+				verifyTypeInvalidSourceCodeInfo,
+				fmt.Sprintf("Source code info location with path %v does not refer to an element of the file.", location.GetPath()),
+			)
+		}
+	}
+}
+
+func (v *imageVerifier) verifyMessage(descriptorProto *descriptorpb.DescriptorProto, scope string, path []int32) {
+	fullName := joinFullName(scope, descriptorProto.GetName())
+	v.verifyOptions(descriptorProto.GetOptions(), appendPath(path, 7), fmt.Sprintf("message %q", fullName))
+	for i, fieldDescriptorProto := range descriptorProto.GetField() {
+		v.verifyField(fieldDescriptorProto, fullName, appendPath(path, 2, int32(i)))
+	}
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		v.verifyMessage(nestedDescriptorProto, fullName, appendPath(path, 3, int32(i)))
+	}
+	for i, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		v.verifyEnum(enumDescriptorProto, fullName, appendPath(path, 4, int32(i)))
+	}
+	for i, fieldDescriptorProto := range descriptorProto.GetExtension() {
+		v.verifyField(fieldDescriptorProto, fullName, appendPath(path, 6, int32(i)))
+	}
+	for i, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+		v.verifyOptions(
+			oneofDescriptorProto.GetOptions(),
+			appendPath(path, 8, int32(i), 2),
+			fmt.Sprintf("oneof %q", joinFullName(fullName, oneofDescriptorProto.GetName())),
+		)
+	}
+}
+
+func (v *imageVerifier) verifyField(fieldDescriptorProto *descriptorpb.FieldDescriptorProto, scope string, path []int32) {
+	description := fmt.Sprintf("field %q", joinFullName(scope, fieldDescriptorProto.GetName()))
+	if typeName := fieldDescriptorProto.GetTypeName(); typeName != "" {
+		v.verifyTypeName(typeName, appendPath(path, 6), description, "type")
+	}
+	if extendee := fieldDescriptorProto.GetExtendee(); extendee != "" {
+		v.verifyTypeName(extendee, appendPath(path, 2), description, "extendee")
+	}
+	v.verifyOptions(fieldDescriptorProto.GetOptions(), appendPath(path, 8), description)
+}
+
+func (v *imageVerifier) verifyEnum(enumDescriptorProto *descriptorpb.EnumDescriptorProto, scope string, path []int32) {
+	fullName := joinFullName(scope, enumDescriptorProto.GetName())
+	v.verifyOptions(enumDescriptorProto.GetOptions(), appendPath(path, 3), fmt.Sprintf("enum %q", fullName))
+	for i, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		v.verifyOptions(
+			enumValueDescriptorProto.GetOptions(),
+			appendPath(path, 2, int32(i), 3),
+			fmt.Sprintf("enum value %q", joinFullName(fullName, enumValueDescriptorProto.GetName())),
+		)
+	}
+}
+
+func (v *imageVerifier) verifyService(serviceDescriptorProto *descriptorpb.ServiceDescriptorProto, scope string, path []int32) {
+	fullName := joinFullName(scope, serviceDescriptorProto.GetName())
+	v.verifyOptions(serviceDescriptorProto.GetOptions(), appendPath(path, 3), fmt.Sprintf("service %q", fullName))
+	for i, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+		methodPath := appendPath(path, 2, int32(i))
+		description := fmt.Sprintf("RPC %q", joinFullName(fullName, methodDescriptorProto.GetName()))
+		v.verifyTypeName(methodDescriptorProto.GetInputType(), appendPath(methodPath, 2), description, "request type")
+		v.verifyTypeName(methodDescriptorProto.GetOutputType(), appendPath(methodPath, 3), description, "response type")
+		v.verifyOptions(methodDescriptorProto.GetOptions(), appendPath(methodPath, 4), description)
+	}
+}
+
+// verifyTypeName verifies that the fully-qualified type name is defined in the
+// file or the files visible through its imports.
+//
+// Relative type names are only valid in unlinked files, and are not verified.
+func (v *imageVerifier) verifyTypeName(typeName string, path []int32, description string, kind string) {
+	if !strings.HasPrefix(typeName, ".") {
+		return
+	}
+	if _, ok := v.visibleFullNames[typeName[1:]]; ok {
+		return
+	}
+	v.add(
+		path,
+		verifyTypeUnresolvedType,
+		fmt.Sprintf("The %s %q of %s is not defined in the file or its imports.", kind, typeName[1:], description),
+	)
+}
+
+// verifyOptions verifies that all unknown fields of the options are custom
+// options defined by an extension in the image.
+func (v *imageVerifier) verifyOptions(options proto.Message, path []int32, description string) {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return
+	}
+	extendee := string(options.ProtoReflect().Descriptor().FullName())
+	numbers := v.extendeeToNumbers[extendee]
+	seen := make(map[protowire.Number]struct{})
+	data := options.ProtoReflect().GetUnknown()
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return
+		}
+		data = data[n:]
+		if _, ok := numbers[int32(number)]; ok {
+			continue
+		}
+		if _, ok := seen[number]; ok {
+			continue
+		}
+		seen[number] = struct{}{}
+		v.add(
+			path,
+			verifyTypeUnresolvedOption,
+			fmt.Sprintf("Option with field number %d on %s is not defined by any extension of %s in the image.", number, description, extendee),
+		)
+	}
+}
+
+// getVisibleFullNames returns the full names of the messages and enums defined in
+// the file, its imports, and the public imports of its imports, transitively.
+func (v *imageVerifier) getVisibleFullNames(fileDescriptorProto *descriptorpb.FileDescriptorProto) map[string]struct{} {
+	visibleFullNames := make(map[string]struct{})
+	seenPaths := make(map[string]struct{})
+	var addPath func(string, bool)
+	addPath = func(path string, publicOnly bool) {
+		if _, ok := seenPaths[path]; ok {
+			return
+		}
+		seenPaths[path] = struct{}{}
+		for fullName := range v.pathToFullNames[path] {
+			visibleFullNames[fullName] = struct{}{}
+		}
+		dependencyFileDescriptorProto, ok := v.pathToFileDescriptorProto[path]
+		if !ok {
+			return
+		}
+		for _, index := range dependencyFileDescriptorProto.GetPublicDependency() {
+			if int(index) < len(dependencyFileDescriptorProto.GetDependency()) {
+				addPath(dependencyFileDescriptorProto.GetDependency()[index], true)
+			}
+		}
+		if !publicOnly {
+			for _, dependency := range dependencyFileDescriptorProto.GetDependency() {
+				addPath(dependency, true)
+			}
+		}
+	}
+	addPath(fileDescriptorProto.GetName(), false)
+	return visibleFullNames
+}
+
+func (v *imageVerifier) add(path []int32, typeString string, message string) {
+	// fall back to the closest enclosing element with a location, as
+	// not every part of an element has its own location
+	var location *descriptorpb.SourceCodeInfo_Location
+	for ; len(path) > 0 && location == nil; path = path[:len(path)-1] {
+		location = v.pathKeyToLocation[getVerifyPathKey(path)]
+	}
+	v.addForLocation(location, typeString, message)
+}
+
+func (v *imageVerifier) addForLocation(location *descriptorpb.SourceCodeInfo_Location, typeString string, message string) {
+	v.fileAnnotations = append(
+		v.fileAnnotations,
+		newVerifyFileAnnotation(v.fileInfo, location, typeString, message),
+	)
+}
+
+// isValidSourceCodeInfoPath returns true if the path refers to an element
+// within the message.
+func isValidSourceCodeInfoPath(message protoreflect.Message, path []int32) bool {
+	for len(path) > 0 {
+		number := protoreflect.FieldNumber(path[0])
+		path = path[1:]
+		fieldDescriptor := message.Descriptor().Fields().ByNumber(number)
+		if fieldDescriptor == nil {
+			// custom options are referred to by their extension number, and
+			// the parts of the path within them cannot be verified
+			return message.Descriptor().ExtensionRanges().Has(number)
+		}
+		if fieldDescriptor.IsList() {
+			if len(path) == 0 {
+				return true
+			}
+			list := message.Get(fieldDescriptor).List()
+			if path[0] < 0 || int(path[0]) >= list.Len() {
+				return false
+			}
+			element := list.Get(int(path[0]))
+			path = path[1:]
+			if fieldDescriptor.Message() == nil {
+				return len(path) == 0
+			}
+			message = element.Message()
+			continue
+		}
+		if fieldDescriptor.Message() == nil {
+			return len(path) == 0
+		}
+		message = message.Get(fieldDescriptor).Message()
+	}
+	return true
+}
+
+func newVerifyFileAnnotation(
+	fileInfo *verifyFileInfo,
+	location *descriptorpb.SourceCodeInfo_Location,
+	typeString string,
+	message string,
+) bufanalysis.FileAnnotation {
+	var annotationFileInfo bufanalysis.FileInfo
+	if fileInfo != nil {
+		annotationFileInfo = fileInfo
+	}
+	var startLine, startColumn, endLine, endColumn int
+	// spans are zero-indexed, and have three elements if the start and end
+	// line are the same
+	switch span := location.GetSpan(); len(span) {
+	case 3:
+		startLine, startColumn, endLine, endColumn = int(span[0])+1, int(span[1])+1, int(span[0])+1, int(span[2])+1
+	case 4:
+		startLine, startColumn, endLine, endColumn = int(span[0])+1, int(span[1])+1, int(span[2])+1, int(span[3])+1
+	}
+	return bufanalysis.NewFileAnnotation(
+		annotationFileInfo,
+		startLine,
+		startColumn,
+		endLine,
+		endColumn,
+		typeString,
+		message,
+	)
+}
+
+type verifyFileInfo struct {
+	path string
+}
+
+func newVerifyFileInfo(path string) *verifyFileInfo {
+	return &verifyFileInfo{
+		path: path,
+	}
+}
+
+func (f *verifyFileInfo) Path() string {
+	return f.path
+}
+
+func (f *verifyFileInfo) ExternalPath() string {
+	return f.path
+}
+
+func getVerifyPathKey(path []int32) string {
+	return fmt.Sprint(path)
+}
+
+func appendPath(path []int32, elements ...int32) []int32 {
+	return append(append(make([]int32, 0, len(path)+len(elements)), path...), elements...)
+}
+
+func joinFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
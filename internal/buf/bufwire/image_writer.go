@@ -16,34 +16,52 @@ package bufwire
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/buffetch"
+	imagev1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type imageWriter struct {
-	logger              *zap.Logger
-	fetchImageRefParser buffetch.ImageRefParser
-	fetchWriter         buffetch.Writer
+	logger                      *zap.Logger
+	fetchImageRefParser         buffetch.ImageRefParser
+	fetchWriter                 buffetch.Writer
+	jsonMarshalerOptions        []protoencoding.JSONMarshalerOption
+	compactSourceCodeInfo       bool
+	sourceCodeInfoFilterOptions []bufcore.SourceCodeInfoFilterOption
+	overwriteConfirmer          func(string) (bool, error)
+	digestFunc                  func(string) error
 }
 
 func newImageWriter(
 	logger *zap.Logger,
 	fetchImageRefParser buffetch.ImageRefParser,
 	fetchWriter buffetch.Writer,
+	options ...ImageWriterOption,
 ) *imageWriter {
-	return &imageWriter{
+	imageWriter := &imageWriter{
 		logger:              logger,
 		fetchImageRefParser: fetchImageRefParser,
 		fetchWriter:         fetchWriter,
 	}
+	for _, option := range options {
+		option(imageWriter)
+	}
+	return imageWriter
 }
 
 func (i *imageWriter) PutImage(
@@ -56,23 +74,49 @@ func (i *imageWriter) PutImage(
 ) (retErr error) {
 	defer instrument.Start(i.logger, "put_image").End()
 
+	if asFileDescriptorSet && i.compactSourceCodeInfo {
+		return errors.New("compact source code info cannot be written to a FileDescriptorSet")
+	}
 	imageRef, err := i.fetchImageRefParser.GetImageRef(ctx, value)
 	if err != nil {
 		return err
 	}
+	writeImage := image
+	if excludeImports {
+		writeImage = bufcore.ImageWithoutImports(image)
+	}
+	writeImage = bufcore.ImageWithFilteredSourceCodeInfo(writeImage, i.sourceCodeInfoFilterOptions...)
+	if i.digestFunc != nil {
+		digest, err := bufcore.ImageDigest(writeImage)
+		if err != nil {
+			return err
+		}
+		if err := i.digestFunc(digest); err != nil {
+			return err
+		}
+	}
 	// stop short for performance
 	if imageRef.IsNull() {
 		return nil
 	}
-	writeImage := image
-	if excludeImports {
-		writeImage = bufcore.ImageWithoutImports(image)
+	if err := i.confirmOverwrite(imageRef); err != nil {
+		return err
 	}
 	var message proto.Message
 	if asFileDescriptorSet {
 		message = bufcore.ImageToFileDescriptorSet(writeImage)
 	} else {
-		message = bufcore.ImageToProtoImage(writeImage)
+		protoImage := bufcore.ImageToProtoImage(writeImage)
+		if i.compactSourceCodeInfo {
+			restore, err := compactSourceCodeInfo(protoImage)
+			if err != nil {
+				return err
+			}
+			// the FileDescriptorProtos are shared with the image, so we must
+			// restore them once we are done
+			defer restore()
+		}
+		message = protoImage
 	}
 	data, err := i.imageMarshal(message, image, imageRef.ImageEncoding())
 	if err != nil {
@@ -89,6 +133,48 @@ func (i *imageWriter) PutImage(
 	return err
 }
 
+func (i *imageWriter) PutSourceArchive(
+	ctx context.Context,
+	container app.EnvStdoutContainer,
+	value string,
+	image bufcore.Image,
+	sourceReadBucket storage.ReadBucket,
+	excludeImports bool,
+) (retErr error) {
+	defer instrument.Start(i.logger, "put_source_archive").End()
+
+	sourceRef, err := buffetch.NewRefParser(i.logger).GetSourceRef(ctx, value)
+	if err != nil {
+		return err
+	}
+	pathToData := make(map[string][]byte)
+	for _, imageFile := range image.Files() {
+		if excludeImports && imageFile.IsImport() {
+			continue
+		}
+		data, err := storage.ReadPath(ctx, sourceReadBucket, imageFile.Path())
+		if err != nil {
+			if storage.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		pathToData[imageFile.Path()] = data
+	}
+	readBucket, err := storagemem.NewReadBucket(pathToData)
+	if err != nil {
+		return err
+	}
+	writeCloser, err := i.fetchWriter.PutSourceArchiveFile(ctx, container, sourceRef)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	return storagearchive.Tar(ctx, readBucket, writeCloser)
+}
+
 func (i *imageWriter) imageMarshal(
 	message proto.Message,
 	image bufcore.Image,
@@ -108,8 +194,86 @@ func (i *imageWriter) imageMarshal(
 		if err != nil {
 			return nil, err
 		}
-		return protoencoding.NewJSONMarshaler(resolver).Marshal(message)
+		return protoencoding.NewJSONMarshaler(resolver, i.jsonMarshalerOptions...).Marshal(message)
+	case buffetch.ImageEncodingText:
+		resolver, err := protoencoding.NewResolver(
+			bufcore.ImageToFileDescriptorProtos(
+				image,
+			)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return protoencoding.NewTextMarshaler(resolver).Marshal(message)
 	default:
 		return nil, fmt.Errorf("unknown image encoding: %v", imageEncoding)
 	}
 }
+
+// compactSourceCodeInfo moves the SourceCodeInfo of the files of the Image into
+// the CompactSourceCodeInfo field of the ImageExtension.
+//
+// This modifies the FileDescriptorProtos in place. The returned function
+// restores the SourceCodeInfo of the FileDescriptorProtos.
+func compactSourceCodeInfo(protoImage *imagev1.Image) (func(), error) {
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	var sourceCodeInfos []*descriptorpb.SourceCodeInfo
+	for _, fileDescriptorProto := range protoImage.File {
+		sourceCodeInfo := fileDescriptorProto.GetSourceCodeInfo()
+		sourceCodeInfos = append(sourceCodeInfos, sourceCodeInfo)
+		if sourceCodeInfo == nil {
+			continue
+		}
+		fileDescriptorSet.File = append(
+			fileDescriptorSet.File,
+			&descriptorpb.FileDescriptorProto{
+				Name:           fileDescriptorProto.Name,
+				SourceCodeInfo: sourceCodeInfo,
+			},
+		)
+	}
+	restore := func() {
+		for i, fileDescriptorProto := range protoImage.File {
+			fileDescriptorProto.SourceCodeInfo = sourceCodeInfos[i]
+		}
+	}
+	if len(fileDescriptorSet.File) == 0 {
+		return restore, nil
+	}
+	data, err := protoencoding.NewWireMarshaler().Marshal(fileDescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	zstdEncoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zstdEncoder.Close()
+	protoImage.BufbuildImageExtension.CompactSourceCodeInfo = zstdEncoder.EncodeAll(data, nil)
+	for _, fileDescriptorProto := range protoImage.File {
+		fileDescriptorProto.SourceCodeInfo = nil
+	}
+	return restore, nil
+}
+
+func (i *imageWriter) confirmOverwrite(imageRef buffetch.ImageRef) error {
+	if i.overwriteConfirmer == nil {
+		return nil
+	}
+	localPath := imageRef.LocalPath()
+	if localPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(localPath); err != nil {
+		// nothing to overwrite, or the write will fail with a better error
+		return nil
+	}
+	confirmed, err := i.overwriteConfirmer(localPath)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return fmt.Errorf("not overwriting %s", localPath)
+	}
+	return nil
+}
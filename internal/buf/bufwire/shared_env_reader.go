@@ -0,0 +1,84 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/pkg/app"
+)
+
+type sharedEnvReader struct {
+	EnvReader
+
+	lock        sync.Mutex
+	keyToResult map[string]*sharedEnvResult
+}
+
+type sharedEnvResult struct {
+	once            sync.Once
+	env             Env
+	fileAnnotations []bufanalysis.FileAnnotation
+	err             error
+}
+
+func newSharedEnvReader(delegate EnvReader) *sharedEnvReader {
+	return &sharedEnvReader{
+		EnvReader:   delegate,
+		keyToResult: make(map[string]*sharedEnvResult),
+	}
+}
+
+func (s *sharedEnvReader) GetEnv(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	value string,
+	configOverride string,
+	externalFilePaths []string,
+	externalFilePathsAllowNotExist bool,
+	excludeSourceCodeInfo bool,
+) (Env, []bufanalysis.FileAnnotation, error) {
+	key := fmt.Sprintf(
+		"%q %q %q %t %t",
+		value,
+		configOverride,
+		externalFilePaths,
+		externalFilePathsAllowNotExist,
+		excludeSourceCodeInfo,
+	)
+	s.lock.Lock()
+	result, ok := s.keyToResult[key]
+	if !ok {
+		result = &sharedEnvResult{}
+		s.keyToResult[key] = result
+	}
+	s.lock.Unlock()
+	// concurrent calls with the same arguments wait for the first call
+	result.once.Do(func() {
+		result.env, result.fileAnnotations, result.err = s.EnvReader.GetEnv(
+			ctx,
+			container,
+			value,
+			configOverride,
+			externalFilePaths,
+			externalFilePathsAllowNotExist,
+			excludeSourceCodeInfo,
+		)
+	})
+	return result.env, result.fileAnnotations, result.err
+}
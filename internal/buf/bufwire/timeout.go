@@ -0,0 +1,59 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	fetchPhaseName = "fetch"
+	buildPhaseName = "build"
+)
+
+// withPhaseTimeout returns a context for the phase that times out after the
+// given timeout.
+//
+// If timeout is 0, the context is not modified.
+func withPhaseTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// newPhaseTimeoutError returns an error that the phase timed out if err is
+// the result of the phase context timing out, and err otherwise.
+//
+// If the parent context is done, err is returned as-is, as the phase did not
+// time out on its own.
+func newPhaseTimeoutError(
+	ctx context.Context,
+	phaseCtx context.Context,
+	phaseName string,
+	timeout time.Duration,
+	err error,
+) error {
+	if err == nil || timeout <= 0 || ctx.Err() != nil {
+		return err
+	}
+	if !errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s timed out after %v: %w", phaseName, timeout, err)
+}
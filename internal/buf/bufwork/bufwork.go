@@ -0,0 +1,76 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufwork contains the workspace functionality.
+//
+// A workspace is a directory with a buf.work.yaml file that lists the
+// directories of the modules within the workspace. The modules of a
+// workspace are built together, so that files in one module can import
+// files in another module.
+package bufwork
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+)
+
+const (
+	// ExternalConfigFilePath is the default external configuration file path.
+	ExternalConfigFilePath = "buf.work.yaml"
+	// V1Beta1Version is the string used to identify the v1beta1 version of the workspace configuration.
+	V1Beta1Version = "v1beta1"
+)
+
+// Config is a workspace configuration.
+type Config struct {
+	// Directories are the directories of the modules within the workspace,
+	// relative to the root of the workspace.
+	//
+	// These are normalized, sorted, and do not overlap.
+	//
+	// Required.
+	Directories []string
+}
+
+// NewConfig returns a new, validated Config for the ExternalConfig.
+func NewConfig(externalConfig ExternalConfigV1Beta1) (*Config, error) {
+	return newConfig(externalConfig)
+}
+
+// GetConfigForBucket gets the Config from the buf.work.yaml file at the root
+// of the bucket.
+//
+// If the bucket has no buf.work.yaml file, this returns nil.
+func GetConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (*Config, error) {
+	return getConfigForBucket(ctx, readBucket)
+}
+
+// NewBuildConfig returns a new build Config that builds the modules of the
+// workspace together.
+//
+// The directoryToBuildConfig map contains the build Config of the module in
+// each directory of the workspace. The roots of each module are relative to
+// the directory of the module, and the roots of the returned Config are
+// relative to the root of the workspace.
+func NewBuildConfig(directoryToBuildConfig map[string]*bufmod.Config) (*bufmod.Config, error) {
+	return newBuildConfig(directoryToBuildConfig)
+}
+
+// ExternalConfigV1Beta1 is an external workspace configuration.
+type ExternalConfigV1Beta1 struct {
+	Version     string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Directories []string `json:"directories,omitempty" yaml:"directories,omitempty"`
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/pkg/encoding"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"go.uber.org/multierr"
+)
+
+func getConfigForBucket(ctx context.Context, readBucket storage.ReadBucket) (_ *Config, retErr error) {
+	readObject, err := readBucket.Get(ctx, ExternalConfigFilePath)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readObject.Close())
+	}()
+	data, err := ioutil.ReadAll(readObject)
+	if err != nil {
+		return nil, err
+	}
+	externalConfig := ExternalConfigV1Beta1{}
+	if err := encoding.UnmarshalYAMLStrict(data, &externalConfig); err != nil {
+		return nil, fmt.Errorf("%s: %v", ExternalConfigFilePath, err)
+	}
+	config, err := newConfig(externalConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ExternalConfigFilePath, err)
+	}
+	return config, nil
+}
+
+func newConfig(externalConfig ExternalConfigV1Beta1) (*Config, error) {
+	if externalConfig.Version != "" && externalConfig.Version != V1Beta1Version {
+		return nil, fmt.Errorf("unknown version: %q, only %q is supported", externalConfig.Version, V1Beta1Version)
+	}
+	if len(externalConfig.Directories) == 0 {
+		return nil, errors.New("no directories set")
+	}
+	directories := make([]string, len(externalConfig.Directories))
+	for i, directory := range externalConfig.Directories {
+		if directory == "" {
+			return nil, errors.New("directories contained an empty path")
+		}
+		normalizedDirectory, err := normalpath.NormalizeAndValidate(directory)
+		if err != nil {
+			return nil, err
+		}
+		if normalizedDirectory == "." {
+			return nil, errors.New("directories cannot contain the root of the workspace")
+		}
+		directories[i] = normalizedDirectory
+	}
+	sort.Strings(directories)
+	for i := 0; i < len(directories); i++ {
+		for j := i + 1; j < len(directories); j++ {
+			if directories[i] == directories[j] {
+				return nil, fmt.Errorf("duplicate directory %s", directories[i])
+			}
+			if normalpath.EqualsOrContainsPath(directories[i], directories[j], normalpath.Relative) {
+				return nil, fmt.Errorf("directory %s is within directory %s which is not allowed", directories[j], directories[i])
+			}
+		}
+	}
+	return &Config{
+		Directories: directories,
+	}, nil
+}
+
+func newBuildConfig(directoryToBuildConfig map[string]*bufmod.Config) (*bufmod.Config, error) {
+	rootToExcludes := make(map[string][]string)
+	for directory, buildConfig := range directoryToBuildConfig {
+		for root, excludes := range buildConfig.RootToExcludes {
+			workspaceRoot := normalpath.Join(directory, root)
+			if _, ok := rootToExcludes[workspaceRoot]; ok {
+				return nil, fmt.Errorf("duplicate root %s", workspaceRoot)
+			}
+			// excludes are relative to the root, so they do not change
+			rootToExcludes[workspaceRoot] = excludes
+		}
+	}
+	roots := make([]string, 0, len(rootToExcludes))
+	for root := range rootToExcludes {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	for i := 0; i < len(roots); i++ {
+		for j := i + 1; j < len(roots); j++ {
+			if normalpath.EqualsOrContainsPath(roots[i], roots[j], normalpath.Relative) {
+				return nil, fmt.Errorf("root %s is within root %s which is not allowed", roots[j], roots[i])
+			}
+		}
+	}
+	return &bufmod.Config{
+		RootToExcludes: rootToExcludes,
+	}, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwork
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfigForBucket(t *testing.T) {
+	t.Parallel()
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			ExternalConfigFilePath: []byte(`version: v1beta1
+directories:
+  - proto/b
+  - ./proto/a
+`),
+		},
+	)
+	require.NoError(t, err)
+	config, err := GetConfigForBucket(context.Background(), readBucket)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Config{
+			Directories: []string{"proto/a", "proto/b"},
+		},
+		config,
+	)
+
+	readBucket, err = storagemem.NewReadBucket(nil)
+	require.NoError(t, err)
+	config, err = GetConfigForBucket(context.Background(), readBucket)
+	require.NoError(t, err)
+	assert.Nil(t, config)
+}
+
+func TestNewConfigError(t *testing.T) {
+	t.Parallel()
+	for _, externalConfig := range []ExternalConfigV1Beta1{
+		{},
+		{Version: "v1", Directories: []string{"a"}},
+		{Directories: []string{""}},
+		{Directories: []string{"."}},
+		{Directories: []string{"../a"}},
+		{Directories: []string{"a", "a"}},
+		{Directories: []string{"a", "a/b"}},
+	} {
+		_, err := NewConfig(externalConfig)
+		assert.Error(t, err, externalConfig)
+	}
+}
+
+func TestNewBuildConfig(t *testing.T) {
+	t.Parallel()
+	buildConfig, err := NewBuildConfig(
+		map[string]*bufmod.Config{
+			"a": {
+				RootToExcludes: map[string][]string{
+					".": {"internal"},
+				},
+			},
+			"b": {
+				RootToExcludes: map[string][]string{
+					"proto":  {},
+					"vendor": {},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&bufmod.Config{
+			RootToExcludes: map[string][]string{
+				"a":        {"internal"},
+				"b/proto":  {},
+				"b/vendor": {},
+			},
+		},
+		buildConfig,
+	)
+}
@@ -16,7 +16,9 @@ package buf
 
 import (
 	"context"
+	"os"
 
+	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
 	"github.com/bufbuild/buf/internal/pkg/app/appflag"
 )
@@ -25,9 +27,35 @@ import (
 const Version = "0.20.2"
 
 // Main is the main.
+//
+// If --persistent_worker is given, this runs as a Bazel persistent worker.
 func Main(use string, options ...RootCommandOption) {
+	if isPersistentWorker(os.Args[1:]) {
+		app.Main(
+			context.Background(),
+			func(ctx context.Context, container app.Container) error {
+				return runPersistentWorker(ctx, container, use, options...)
+			},
+		)
+		return
+	}
 	appcmd.Main(context.Background(), newRootCommand(use, options...))
 }
 
+// NewRootCommand returns a new root Command.
+//
+// This is the Command that Main runs.
+func NewRootCommand(use string, options ...RootCommandOption) *appcmd.Command {
+	return newRootCommand(use, options...)
+}
+
 // RootCommandOption is an option for a root Command.
 type RootCommandOption func(*appcmd.Command, appflag.Builder)
+
+// RootCommandWithInterceptors returns a new RootCommandOption that adds the
+// interceptors to the root Command, which intercept the run of every command.
+func RootCommandWithInterceptors(interceptors ...appcmd.Interceptor) RootCommandOption {
+	return func(rootCommand *appcmd.Command, _ appflag.Builder) {
+		rootCommand.Interceptors = append(rootCommand.Interceptors, interceptors...)
+	}
+}
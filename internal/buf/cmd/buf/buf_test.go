@@ -16,18 +16,31 @@ package buf
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
 	"github.com/bufbuild/buf/internal/pkg/app/appcmd/appcmdtesting"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestSuccess1(t *testing.T) {
@@ -121,7 +134,7 @@ func TestFail5(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
         testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
@@ -135,7 +148,7 @@ func TestFail6(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
         testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
@@ -151,7 +164,7 @@ func TestFail7(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "fail/buf".
         testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
@@ -169,7 +182,7 @@ func TestFail8(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
 		testdata/fail2/buf/buf2.proto:9:9:Field name "oneThree" should be lower_snake_case, such as "one_three".`,
 		"check",
@@ -183,7 +196,7 @@ func TestFail9(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`testdata/fail2/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
 		"check",
 		"lint",
@@ -213,7 +226,7 @@ func TestFail11(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		4,
 		`testdata/fail2/buf/buf2.proto:5:8:buf/buf.proto: does not exist`,
 		"check",
 		"lint",
@@ -228,7 +241,7 @@ func TestFail12(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
+		5,
 		`lint:
   ignore_only:
     FIELD_LOWER_SNAKE_CASE:
@@ -244,291 +257,4755 @@ func TestFail12(t *testing.T) {
 	)
 }
 
-func TestFailCheckBreaking1(t *testing.T) {
+func TestFail13(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		1,
-		`
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
-		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
-		`,
+		5,
+		`[{"description":"Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\".","check_name":"PACKAGE_DIRECTORY_MATCH","fingerprint":"a02d250e671e47914b4a839a727471a762ab1dfcaf28bb0cc5c312670e7460e6","severity":"major","location":{"path":"testdata/fail/buf/buf.proto","lines":{"begin":3,"end":3}}},{"description":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\".","check_name":"FIELD_LOWER_SNAKE_CASE","fingerprint":"3a3c051c3cb757ed431436221a9bd83d5a457c3aa2c581424432a323d13bf236","severity":"major","location":{"path":"testdata/fail/buf/buf.proto","lines":{"begin":6,"end":6}}}]`,
 		"check",
-		"breaking",
+		"lint",
 		"--input",
-		// can't bother right now to filepath.Join this
-		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
-		"--against-input",
-		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"gitlab",
 	)
 }
 
-func TestCheckLsLintCheckers1(t *testing.T) {
+func TestFail14(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		0,
-		`
-		ID                       CATEGORIES  PURPOSE
-		RPC_NO_CLIENT_STREAMING  UNARY_RPC   Checks that RPCs are not client streaming.
-		RPC_NO_SERVER_STREAMING  UNARY_RPC   Checks that RPCs are not server streaming.
-		`,
+		5,
+		`{"robot_comments":{"testdata/fail/buf/buf.proto":[{"robot_id":"buf","robot_run_id":"e77d0452a499a122","properties":{"type":"PACKAGE_DIRECTORY_MATCH"},"path":"testdata/fail/buf/buf.proto","line":3,"range":{"start_line":3,"start_character":0,"end_line":3,"end_character":14},"message":"Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\"."},{"robot_id":"buf","robot_run_id":"e77d0452a499a122","properties":{"type":"FIELD_LOWER_SNAKE_CASE"},"path":"testdata/fail/buf/buf.proto","line":6,"range":{"start_line":6,"start_character":8,"end_line":6,"end_character":14},"message":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}]}}`,
 		"check",
-		"ls-lint-checkers",
-		"--all",
-		"--category",
-		"UNARY_RPC",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"gerrit",
 	)
 }
 
-func TestCheckLsLintCheckers2(t *testing.T) {
+func TestFail15(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		0,
+		5,
 		`
-		ID                       CATEGORIES                            PURPOSE
-		PACKAGE_DIRECTORY_MATCH  MINIMAL, BASIC, DEFAULT, FILE_LAYOUT  Checks that all files with are in a directory that matches their package name.
-		ENUM_NO_ALLOW_ALIAS      MINIMAL, BASIC, DEFAULT, SENSIBLE     Checks that enums do not have the allow_alias option set.
+		<?xml version="1.0" encoding="UTF-8"?>
+		<checkstyle version="8.0">
+		  <file name="testdata/fail/buf/buf.proto">
+		    <error line="3" column="1" severity="error" message="Files with package &#34;other&#34; must be within a directory &#34;other&#34; relative to root but were in directory &#34;buf&#34;." source="buf.PACKAGE_DIRECTORY_MATCH"></error>
+		    <error line="6" column="9" severity="error" message="Field name &#34;oneTwo&#34; should be lower_snake_case, such as &#34;one_two&#34;." source="buf.FIELD_LOWER_SNAKE_CASE"></error>
+		  </file>
+		</checkstyle>
 		`,
 		"check",
-		"ls-lint-checkers",
-		"--config",
-		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"checkstyle",
 	)
 }
 
-func TestCheckLsBreakingCheckers1(t *testing.T) {
+func TestFail16(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		0,
+		5,
 		`
-		ID                                           CATEGORIES                      PURPOSE
-		ENUM_VALUE_SAME_NAME                         FILE, PACKAGE, WIRE_JSON        Checks that enum values have the same name.
-		FIELD_SAME_JSON_NAME                         FILE, PACKAGE, WIRE_JSON        Checks that fields have the same value for the json_name option.
-		FIELD_SAME_NAME                              FILE, PACKAGE, WIRE_JSON        Checks that fields have the same names in a given message.
-		FIELD_SAME_LABEL                             FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same labels in a given message.
-		FIELD_SAME_ONEOF                             FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same oneofs in a given message.
-		FIELD_SAME_TYPE                              FILE, PACKAGE, WIRE_JSON, WIRE  Checks that fields have the same types in a given message.
-		MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT         FILE, PACKAGE, WIRE_JSON, WIRE  Checks that messages have the same value for the message_set_wire_format option.
-		RESERVED_ENUM_NO_DELETE                      FILE, PACKAGE, WIRE_JSON, WIRE  Checks that reserved ranges and names are not deleted from a given enum.
-		RESERVED_MESSAGE_NO_DELETE                   FILE, PACKAGE, WIRE_JSON, WIRE  Checks that reserved ranges and names are not deleted from a given message.
-		RPC_SAME_CLIENT_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same client streaming value.
-		RPC_SAME_IDEMPOTENCY_LEVEL                   FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same value for the idempotency_level option.
-		RPC_SAME_REQUEST_TYPE                        FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs are have the same request type.
-		RPC_SAME_RESPONSE_TYPE                       FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs are have the same response type.
-		RPC_SAME_SERVER_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  Checks that rpcs have the same server streaming value.
-		ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED    WIRE_JSON                       Checks that enum values are not deleted from a given enum unless the name is reserved.
-		FIELD_NO_DELETE_UNLESS_NAME_RESERVED         WIRE_JSON                       Checks that fields are not deleted from a given message unless the name is reserved.
-		ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED  WIRE_JSON, WIRE                 Checks that enum values are not deleted from a given enum unless the number is reserved.
-		FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED       WIRE_JSON, WIRE                 Checks that fields are not deleted from a given message unless the number is reserved.
+		**2 failures**
+
+		| Rule | Location | Message |
+		| --- | --- | --- |
+		| `+"`FIELD_LOWER_SNAKE_CASE` | `testdata/fail/buf/buf.proto:6:9`"+` | Field name "oneTwo" should be lower_snake_case, such as "one_two". |
+		| `+"`PACKAGE_DIRECTORY_MATCH` | `testdata/fail/buf/buf.proto:3:1`"+` | Files with package "other" must be within a directory "other" relative to root but were in directory "buf". |
 		`,
 		"check",
-		"ls-breaking-checkers",
-		"--all",
-		"--category",
-		"WIRE_JSON",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"markdown",
 	)
 }
 
-func TestCheckLsBreakingCheckers2(t *testing.T) {
+func TestFail17(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		0,
+		5,
 		`
-		ID                    CATEGORIES     PURPOSE
-		ENUM_VALUE_NO_DELETE  FILE, PACKAGE  Checks that enum values are not deleted from a given enum.
-		FIELD_SAME_JSTYPE     FILE, PACKAGE  Checks that fields have the same value for the jstype option.
+		<?xml version="1.0" encoding="UTF-8"?>
+		<testsuites tests="2" failures="2">
+		  <testsuite name="testdata/fail/buf/buf.proto" tests="2" failures="2">
+		    <testcase name="testdata/fail/buf/buf.proto:3:1:PACKAGE_DIRECTORY_MATCH" classname="testdata/fail/buf/buf.proto">
+		      <failure message="Files with package &#34;other&#34; must be within a directory &#34;other&#34; relative to root but were in directory &#34;buf&#34;." type="PACKAGE_DIRECTORY_MATCH">testdata/fail/buf/buf.proto:3:1:Files with package &#34;other&#34; must be within a directory &#34;other&#34; relative to root but were in directory &#34;buf&#34;.</failure>
+		    </testcase>
+		    <testcase name="testdata/fail/buf/buf.proto:6:9:FIELD_LOWER_SNAKE_CASE" classname="testdata/fail/buf/buf.proto">
+		      <failure message="Field name &#34;oneTwo&#34; should be lower_snake_case, such as &#34;one_two&#34;." type="FIELD_LOWER_SNAKE_CASE">testdata/fail/buf/buf.proto:6:9:Field name &#34;oneTwo&#34; should be lower_snake_case, such as &#34;one_two&#34;.</failure>
+		    </testcase>
+		  </testsuite>
+		</testsuites>
 		`,
 		"check",
-		"ls-breaking-checkers",
-		"--config",
-		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"junit",
 	)
 }
 
-func TestLsFiles(t *testing.T) {
+func TestFail18(t *testing.T) {
 	t.Parallel()
 	testRunStdout(
 		t,
-		0,
+		5,
 		`
-		testdata/success/buf/buf.proto
+		::error file=testdata/fail/buf/buf.proto,line=3,endLine=3,col=1,endColumn=15,title=PACKAGE_DIRECTORY_MATCH::Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		::error file=testdata/fail/buf/buf.proto,line=6,endLine=6,col=9,endColumn=15,title=FIELD_LOWER_SNAKE_CASE::Field name "oneTwo" should be lower_snake_case, such as "one_two".
 		`,
-		"ls-files",
+		"check",
+		"lint",
 		"--input",
-		filepath.Join("testdata", "success"),
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"github-actions",
 	)
 }
 
-func TestImageConvertRoundtripBinaryJSONBinary(t *testing.T) {
+func TestFailGitHubActionsDefault(t *testing.T) {
 	t.Parallel()
-
-	stdout := bytes.NewBuffer(nil)
-	testRun(
+	env := map[string]string{"GITHUB_ACTIONS": "true"}
+	appcmdtesting.RunCommandExitCodeStdout(
 		t,
-		0,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		5,
+		`
+		::error file=testdata/fail/buf/buf.proto,line=3,endLine=3,col=1,endColumn=15,title=PACKAGE_DIRECTORY_MATCH::Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		::error file=testdata/fail/buf/buf.proto,line=6,endLine=6,col=9,endColumn=15,title=FIELD_LOWER_SNAKE_CASE::Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		env,
 		nil,
-		stdout,
-		"image",
-		"build",
-		"-o",
-		"-",
-		"--source",
-		filepath.Join("testdata", "customoptions1"),
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
 	)
-
-	binary1 := stdout.Bytes()
-	require.NotEmpty(t, binary1)
-
-	stdin := stdout
-	stdout = bytes.NewBuffer(nil)
-	testRun(
+	// an explicit --error-format is not overridden
+	appcmdtesting.RunCommandExitCodeStdout(
 		t,
-		0,
-		stdin,
-		stdout,
-		"experimental",
-		"image",
-		"convert",
-		"-i",
-		"-",
-		"-o",
-		"-#format=json",
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		5,
+		`
+		testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		env,
+		nil,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"text",
 	)
+}
 
-	stdin = stdout
-	stdout = bytes.NewBuffer(nil)
-	testRun(
+func TestFail19(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
 		t,
-		0,
-		stdin,
-		stdout,
-		"experimental",
-		"image",
-		"convert",
-		"-i",
-		"-#format=json",
-		"-o",
-		"-",
+		5,
+		`{"body":"**2 failures**","event":"COMMENT","comments":[{"path":"testdata/fail/buf/buf.proto","line":3,"side":"RIGHT","body":"**error** `+"`"+`PACKAGE_DIRECTORY_MATCH`+"`"+`: Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\"."},{"path":"testdata/fail/buf/buf.proto","line":6,"side":"RIGHT","body":"**error** `+"`"+`FIELD_LOWER_SNAKE_CASE`+"`"+`: Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}]}`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"github-review",
 	)
-
-	require.Equal(t, binary1, stdout.Bytes())
 }
 
-func TestImageConvertRoundtripJSONBinaryJSON(t *testing.T) {
+func TestFail20(t *testing.T) {
 	t.Parallel()
-
-	stdout := bytes.NewBuffer(nil)
-	testRun(
+	testRunStdout(
 		t,
-		0,
-		nil,
-		stdout,
-		"image",
-		"build",
-		"-o",
-		"-#format=json",
-		"--source",
-		filepath.Join("testdata", "customoptions1"),
+		5,
+		`[{"body":"**2 failures**\n\n| File | Failures |\n| --- | --- |\n| `+"`"+`testdata/fail/buf/buf.proto`+"`"+` | 2 |"},{"body":"**2 failures** in this file\n\n- Line 3: **error** `+"`"+`PACKAGE_DIRECTORY_MATCH`+"`"+`: Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\".\n- Line 6: **error** `+"`"+`FIELD_LOWER_SNAKE_CASE`+"`"+`: Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\".","position":{"position_type":"text","new_path":"testdata/fail/buf/buf.proto","old_path":"testdata/fail/buf/buf.proto","new_line":3}}]`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"gitlab-review",
+		"--group-by-file",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`grouping by file is only supported for the github-review and gitlab-review formats but the format was text`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--group-by-file",
 	)
+}
 
-	json1 := stdout.Bytes()
-	require.NotEmpty(t, json1)
+func TestConfigOverride(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	configFilePath := filepath.Join(tempDirPath, "buf.yml")
+	require.NoError(t, ioutil.WriteFile(configFilePath, []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
 
-	stdin := stdout
-	stdout = bytes.NewBuffer(nil)
-	testRun(
+	testRunStdout(
 		t,
-		0,
-		stdin,
-		stdout,
-		"experimental",
-		"image",
-		"convert",
-		"-i",
-		"-#format=json",
-		"-o",
-		"-",
+		5,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		"lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n",
 	)
-
-	stdin = stdout
-	stdout = bytes.NewBuffer(nil)
-	testRun(
+	testRunStdout(
+		t,
+		5,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"all",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--against",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		configFilePath,
+	)
+	testRunStdout(
 		t,
 		0,
-		stdin,
-		stdout,
-		"experimental",
+		``,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--against",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		configFilePath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`source: no .proto target files found`,
 		"image",
-		"convert",
-		"-i",
-		"-",
+		"build",
+		"--source",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		`{"build":{"excludes":["buf"]}}`,
 		"-o",
-		"-#format=json",
+		"-",
 	)
-
-	require.Equal(t, json1, stdout.Bytes())
-}
-
-func testRunStdout(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
-	testRunStdoutInternal(
+	testRunStdoutStderr(
 		t,
-		expectedExitCode,
-		expectedStdout,
-		args...,
+		1,
+		``,
+		`cannot set both --input-config and --config`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		configFilePath,
+		"--config",
+		configFilePath,
 	)
 }
 
-func testRunStdoutProfile(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
-	profileDirPath, err := ioutil.TempDir("", "")
-	require.NoError(t, err)
-	defer func() { assert.NoError(t, os.RemoveAll(profileDirPath)) }()
-	testRunStdoutInternal(
+func TestConfigName(t *testing.T) {
+	t.Parallel()
+	config := `{"lint":{"use":["BASIC"]},"configs":{"relaxed":{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}},"empty":{"build":{"excludes":["buf"]}}}}`
+	testRunStdout(
 		t,
-		0,
+		5,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		config,
+		"--config-name",
+		"relaxed",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
 		``,
-		append(
-			args,
-			"--profile",
-			fmt.Sprintf("--profile-path=%s", profileDirPath),
-			"--profile-loops=1",
-			"--profile-type=cpu",
-		)...,
+		`source: no .proto target files found`,
+		"image",
+		"build",
+		"--source",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		config,
+		"--config-name",
+		"empty",
+		"-o",
+		"-",
+	)
+	testRunStdoutStderr(
+		t,
+		2,
+		``,
+		`input: config "strict" not found, must be one of empty,relaxed`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		config,
+		"--config-name",
+		"strict",
+	)
+	testRunStdoutStderr(
+		t,
+		2,
+		``,
+		`input: config "relaxed" not found, no configs are defined; against: config "relaxed" not found, no configs are defined`,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--against",
+		filepath.Join("testdata", "fail"),
+		"--config-name",
+		"relaxed",
 	)
 }
 
-func testRunStdoutInternal(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
-	appcmdtesting.RunCommandExitCodeStdout(
+func TestErrorExitCode(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		2,
+		``,
+		`input: config: "FOO" is not a known id or category`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		`{"lint":{"use":["FOO"]}}`,
+	)
+	testRunStdoutStderr(
+		t,
+		3,
+		``,
+		filepath.FromSlash(`input: testdata/doesnotexist: does not exist`),
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "doesnotexist"),
+	)
+	testRunStdout(
+		t,
+		100,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+		"--error-exit-code",
+		"100",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`--error-exit-code must be between 0 and 255 but was 256`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-exit-code",
+		"256",
+	)
+}
+
+func TestBaseline(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	require.NoError(t, os.Mkdir(protoDirPath, 0755))
+	baselineFilePath := filepath.Join(tempDirPath, "baseline.jsonl")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string Bad = 1;\n}\n"), 0644))
+
+	testRunStdout(t, 0, ``, "check", "lint", "--input", protoDirPath, "--write-baseline", baselineFilePath)
+	data, err := ioutil.ReadFile(baselineFilePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"path":"a.proto","type":"FIELD_LOWER_SNAKE_CASE","message":"Field name \"Bad\" should be lower_snake_case, such as \"bad\"."}
+`,
+		string(data),
+	)
+	testRunStdout(t, 0, ``, "check", "lint", "--input", protoDirPath, "--baseline", baselineFilePath)
+
+	// the recorded failure moved to another line and a new failure was added
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string Other = 2;\n  string Bad = 1;\n}\n"), 0644))
+	testRunStdout(
+		t,
+		5,
+		filepath.Join(protoDirPath, "a.proto")+`:6:10:Field name "Other" should be lower_snake_case, such as "other".`,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--baseline",
+		baselineFilePath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set both --baseline and --write-baseline`,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--baseline",
+		baselineFilePath,
+		"--write-baseline",
+		baselineFilePath,
+	)
+
+	againstDirPath := filepath.Join(tempDirPath, "against")
+	require.NoError(t, os.Mkdir(againstDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(againstDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  int32 Bad = 1;\n}\n"), 0644))
+	testRun(t, 6, nil, nil, "check", "breaking", "--input", protoDirPath, "--against", againstDirPath)
+	testRunStdout(t, 0, ``, "check", "breaking", "--input", protoDirPath, "--against", againstDirPath, "--write-baseline", baselineFilePath)
+	testRunStdout(t, 0, ``, "check", "breaking", "--input", protoDirPath, "--against", againstDirPath, "--baseline", baselineFilePath)
+}
+
+func TestMetricsOut(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	metricsFilePath := filepath.Join(tempDirPath, "metrics.json")
+	testRunStdout(
+		t,
+		5,
+		`
+		testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--metrics-out",
+		metricsFilePath,
+	)
+	data, err := ioutil.ReadFile(metricsFilePath)
+	require.NoError(t, err)
+	checkerToNumFailures := make(map[string]float64)
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var span struct {
+			Name       string                 `json:"name"`
+			Attributes map[string]interface{} `json:"attributes"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &span))
+		names = append(names, span.Name)
+		if span.Name == "checker" {
+			checkerToNumFailures[span.Attributes["checker"].(string)] = span.Attributes["num_failures"].(float64)
+		}
+	}
+	require.Contains(t, names, "build")
+	require.Equal(t, float64(1), checkerToNumFailures["FIELD_LOWER_SNAKE_CASE"])
+	require.Equal(t, float64(0), checkerToNumFailures["ENUM_PASCAL_CASE"])
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`unknown metrics format: "foo"`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--metrics-out",
+		metricsFilePath,
+		"--metrics-format",
+		"foo",
+	)
+}
+
+func TestDefaultInputEnv(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
 		t,
 		func(use string) *appcmd.Command { return newRootCommand(use) },
-		expectedExitCode,
-		expectedStdout,
+		5,
+		map[string]string{
+			"BUF_INPUT": filepath.Join("testdata", "fail"),
+		},
+		nil,
+		stdout,
+		"check",
+		"lint",
+		"--config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+	)
+	assert.Equal(
+		t,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		strings.TrimSpace(stdout.String()),
+	)
+	// the input flag takes precedence over BUF_INPUT
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		0,
+		map[string]string{
+			"BUF_INPUT": filepath.Join("testdata", "fail"),
+		},
 		nil,
 		nil,
-		args...,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success"),
 	)
 }
 
-func testRun(
-	t *testing.T,
-	expectedExitCode int,
-	stdin io.Reader,
-	stdout io.Writer,
-	args ...string,
-) {
-	appcmdtesting.RunCommandExitCode(
+func TestFailLintWarn(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		5,
+		`testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".`,
+		`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["FIELD_LOWER_SNAKE_CASE"]}}`,
+	)
+	testRunStdoutStderr(
+		t,
+		0,
+		``,
+		`
+		testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+	)
+	testRunStdoutStderr(
+		t,
+		5,
+		`
+		testdata/fail/buf/buf.proto:3:1:Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		``,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--warnings-as-errors",
+	)
+}
+
+func TestFailLintWarnErrorFormat(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		0,
+		``,
+		`
+		::warning file=testdata/fail/buf/buf.proto,line=3,endLine=3,col=1,endColumn=15,title=PACKAGE_DIRECTORY_MATCH::Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		::warning file=testdata/fail/buf/buf.proto,line=6,endLine=6,col=9,endColumn=15,title=FIELD_LOWER_SNAKE_CASE::Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"github-actions",
+	)
+	testRunStdoutStderr(
 		t,
+		0,
+		``,
+		`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<checkstyle version="8.0">
+		  <file name="testdata/fail/buf/buf.proto">
+		    <error line="3" column="1" severity="warning" message="Files with package &#34;other&#34; must be within a directory &#34;other&#34; relative to root but were in directory &#34;buf&#34;." source="buf.PACKAGE_DIRECTORY_MATCH"></error>
+		    <error line="6" column="9" severity="warning" message="Field name &#34;oneTwo&#34; should be lower_snake_case, such as &#34;one_two&#34;." source="buf.FIELD_LOWER_SNAKE_CASE"></error>
+		  </file>
+		</checkstyle>
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"checkstyle",
+	)
+	testRunStdoutStderr(
+		t,
+		0,
+		``,
+		`
+		testdata/fail/buf/buf.proto(3,1) : warning PACKAGE_DIRECTORY_MATCH : Files with package "other" must be within a directory "other" relative to root but were in directory "buf".
+		testdata/fail/buf/buf.proto(6,9) : warning FIELD_LOWER_SNAKE_CASE : Field name "oneTwo" should be lower_snake_case, such as "one_two".
+		`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"msvs",
+	)
+	testRunStdoutStderr(
+		t,
+		0,
+		``,
+		`[{"description":"Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\".","check_name":"PACKAGE_DIRECTORY_MATCH","fingerprint":"a02d250e671e47914b4a839a727471a762ab1dfcaf28bb0cc5c312670e7460e6","severity":"minor","location":{"path":"testdata/fail/buf/buf.proto","lines":{"begin":3,"end":3}}},{"description":"Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\".","check_name":"FIELD_LOWER_SNAKE_CASE","fingerprint":"3a3c051c3cb757ed431436221a9bd83d5a457c3aa2c581424432a323d13bf236","severity":"minor","location":{"path":"testdata/fail/buf/buf.proto","lines":{"begin":6,"end":6}}}]`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"gitlab",
+	)
+	testRunStdoutStderr(
+		t,
+		0,
+		``,
+		`{"body":"**2 failures**","event":"COMMENT","comments":[{"path":"testdata/fail/buf/buf.proto","line":3,"side":"RIGHT","body":"**warning** `+"`"+`PACKAGE_DIRECTORY_MATCH`+"`"+`: Files with package \"other\" must be within a directory \"other\" relative to root but were in directory \"buf\"."},{"path":"testdata/fail/buf/buf.proto","line":6,"side":"RIGHT","body":"**warning** `+"`"+`FIELD_LOWER_SNAKE_CASE`+"`"+`: Field name \"oneTwo\" should be lower_snake_case, such as \"one_two\"."}]}`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"github-review",
+	)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := appcmdtesting.RunCommand(
+		context.Background(),
 		func(use string) *appcmd.Command { return newRootCommand(use) },
-		expectedExitCode,
 		nil,
-		stdin,
-		stdout,
-		args...,
+		nil,
+		ioutil.Discard,
+		stderr,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--input-config",
+		`{"lint":{"use":["BASIC"],"warn":["BASIC"]}}`,
+		"--error-format",
+		"sarif",
+	)
+	require.Equal(t, 0, exitCode, stderr.String())
+	var sarif struct {
+		Runs []struct {
+			Results []struct {
+				Level string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), &sarif))
+	require.Len(t, sarif.Runs, 1)
+	require.Len(t, sarif.Runs[0].Results, 2)
+	for _, result := range sarif.Runs[0].Results {
+		assert.Equal(t, "warning", result.Level)
+	}
+}
+
+func TestCheckLintFix(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	filePath := filepath.Join(tempDirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int32 oneTwo = 1;\n}\n"), 0644))
+
+	testRunStdout(
+		t,
+		5,
+		filePath+`:3:1:Files with package "a" must be within a directory "a" relative to root but were in directory ".".`,
+		"check",
+		"lint",
+		"--input",
+		tempDirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE","PACKAGE_DIRECTORY_MATCH"]}}`,
+		"--fix",
+	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n  int32 one_two = 1;\n}\n", string(data))
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		tempDirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+		"--fix",
+	)
+}
+
+func TestCheckLintFixDryRun(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	filePath := filepath.Join(tempDirPath, "a.proto")
+	fileData := []byte("syntax = \"proto3\";\n\nmessage Foo {\n  int32 oneTwo = 1;\n}\n")
+	require.NoError(t, ioutil.WriteFile(filePath, fileData, 0644))
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		5,
+		nil,
+		stdout,
+		"check",
+		"lint",
+		"--input",
+		tempDirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+		"--fix",
+		"--dry-run",
+	)
+	assert.Contains(t, stdout.String(), "-  int32 oneTwo = 1;\n+  int32 one_two = 1;\n")
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, fileData, data)
+}
+
+func TestCheckLintFixInvalid(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set --dry-run without --fix`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--dry-run",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`--fix requires the input to be a local directory`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "success", "image.bin"),
+		"--fix",
+	)
+}
+
+func TestCheckLintPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin script requires a shell")
+	}
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	// the plugin records that it was run, and returns no FileAnnotations
+	markerFilePath := filepath.Join(tempDirPath, "ran")
+	pluginFilePath := filepath.Join(tempDirPath, "buf-check-plugin-acme")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			pluginFilePath,
+			[]byte("#!/bin/sh\ncat >/dev/null\ntouch '"+markerFilePath+"'\n"),
+			0755,
+		),
+	)
+	lintConfig := "lint:\n  use:\n    - PACKAGE_DEFINED\n  plugins:\n    - name: acme\n      path: " + pluginFilePath + "\n"
+	inputDirPath := filepath.Join(tempDirPath, "input")
+	require.NoError(t, os.MkdirAll(inputDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "buf.yaml"), []byte(lintConfig), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n"), 0644))
+	tarFilePath := filepath.Join(tempDirPath, "input.tar")
+	output, err := exec.Command("tar", "-C", inputDirPath, "-cf", tarFilePath, ".").CombinedOutput()
+	require.NoError(t, err, string(output))
+	assertPluginRun := func(expectedRun bool, args ...string) {
+		t.Helper()
+		testRunStdout(t, 0, ``, append([]string{"check", "lint"}, args...)...)
+		_, err := os.Stat(markerFilePath)
+		if expectedRun {
+			assert.NoError(t, err, args)
+			assert.NoError(t, os.Remove(markerFilePath))
+		} else {
+			assert.True(t, os.IsNotExist(err), args)
+		}
+	}
+
+	// plugins in the buf.yaml of the input are not run unless allowed
+	assertPluginRun(false, "--input", inputDirPath)
+	assertPluginRun(true, "--input", inputDirPath, "--allow-plugins")
+	// plugins in the buf.yaml of inputs that are not local directories are never run
+	assertPluginRun(false, "--input", tarFilePath)
+	assertPluginRun(false, "--input", tarFilePath, "--allow-plugins")
+	// plugins set with --input-config are always run
+	assertPluginRun(true, "--input", tarFilePath, "--input-config", lintConfig)
+}
+
+func TestFailCheckBreaking1(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		6,
+		`
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:5:1:Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:10:1:Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:12:5:Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:22:3:Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:57:1:Previously present field "3" with name "three" on message "Nine" was deleted.
+		`,
+		"check",
+		"breaking",
+		"--input",
+		// can't bother right now to filepath.Join this
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+	)
+}
+
+func TestFailCheckBreakingExcludeSourceInfo(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		6,
+		`
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:1:1:Previously present field "3" with name "three" on message "Five" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:1:1:Previously present field "3" with name "three" on message "Seven" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:1:1:Previously present field "3" with name "three" on message "Three" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/1.proto:1:1:Previously present field "3" with name "three" on message "Two" was deleted.
+		../../bufcheck/bufbreaking/testdata/breaking_field_no_delete/2.proto:1:1:Previously present field "3" with name "three" on message "Nine" was deleted.
+		`,
+		"check",
+		"breaking",
+		"--input",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--input-config",
+		`{"breaking":{"use":["FIELD_NO_DELETE"]},"source_info":{"breaking":"exclude"}}`,
+		"--against-input",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+	)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		~ field a.Three.Eight.changed_name: name changed from "two" to "changed_name"
+		- field a.Three.Four.Five.three
+		- field a.Three.Seven.three
+		- field a.Three.three
+		- field a.Two.three
+		- field a.Nine.three
+		`,
+		"diff",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"type":"changed","kind":"field","name":"a.Three.Eight.changed_name","path":"1.proto","description":"name changed from \"two\" to \"changed_name\""}
+		{"type":"removed","kind":"field","name":"a.Three.Four.Five.three","path":"1.proto"}
+		{"type":"removed","kind":"field","name":"a.Three.Seven.three","path":"1.proto"}
+		{"type":"removed","kind":"field","name":"a.Three.three","path":"1.proto"}
+		{"type":"removed","kind":"field","name":"a.Two.three","path":"1.proto"}
+		{"type":"removed","kind":"field","name":"a.Nine.three","path":"2.proto"}
+		`,
+		"diff",
+		"../../bufcheck/bufbreaking/testdata_previous/breaking_field_no_delete",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"diff",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+		"../../bufcheck/bufbreaking/testdata/breaking_field_no_delete",
+	)
+}
+
+func TestCheckLsLintCheckers1(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		ID                       CATEGORIES  FIXABLE  PURPOSE
+		RPC_NO_CLIENT_STREAMING  UNARY_RPC   no       Checks that RPCs are not client streaming.
+		RPC_NO_SERVER_STREAMING  UNARY_RPC   no       Checks that RPCs are not server streaming.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--category",
+		"UNARY_RPC",
+	)
+}
+
+func TestCheckLsLintCheckers2(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		ID                       CATEGORIES                            FIXABLE  PURPOSE
+		PACKAGE_DIRECTORY_MATCH  MINIMAL, BASIC, DEFAULT, FILE_LAYOUT  no       Checks that all files with are in a directory that matches their package name.
+		ENUM_NO_ALLOW_ALIAS      MINIMAL, BASIC, DEFAULT, SENSIBLE     no       Checks that enums do not have the allow_alias option set.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		ID                   CATEGORIES                         FIXABLE  PURPOSE
+		ENUM_NO_ALLOW_ALIAS  MINIMAL, BASIC, DEFAULT, SENSIBLE  no       Checks that enums do not have the allow_alias option set.
+		ENUM_VALUE_PREFIX    DEFAULT, STYLE_DEFAULT             yes      Checks that enum values are prefixed with ENUM_NAME_UPPER_SNAKE_CASE.
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		`{"lint":{"use":["ENUM_VALUE_PREFIX","ENUM_NO_ALLOW_ALIAS"]}}`,
+	)
+}
+
+func TestCheckLsLintCheckersJSON(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		{"id":"RPC_NO_CLIENT_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not client streaming.","default":false,"fixable":false}
+		{"id":"RPC_NO_SERVER_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not server streaming.","default":false,"fixable":false}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--all",
+		"--category",
+		"UNARY_RPC",
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"id":"RPC_REQUEST_STANDARD_NAME","categories":["DEFAULT","STYLE_DEFAULT"],"purpose":"Checks that RPC request type names are RPCNameRequest or ServiceNameRPCNameRequest (configurable).","default":true,"fixable":false,"config_keys":["rpc_allow_google_protobuf_empty_requests"]}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		`{"lint":{"use":["RPC_REQUEST_STANDARD_NAME"]}}`,
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"id":"ENUM_VALUE_PREFIX","categories":["DEFAULT","STYLE_DEFAULT"],"purpose":"Checks that enum values are prefixed with ENUM_NAME_UPPER_SNAKE_CASE.","default":true,"fixable":true}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		`{"lint":{"use":["ENUM_VALUE_PREFIX"]}}`,
+		"--format",
+		"json",
+	)
+}
+
+func TestCheckLsLintCheckersExplain(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		SERVICE_SUFFIX
+
+		Checks that services are suffixed with Service (suffix is configurable).
+
+		Categories: DEFAULT, STYLE_DEFAULT
+
+		A consistent suffix, Service by default, distinguishes services from messages
+		in generated code and documentation. The suffix is configurable with
+		service_suffix.
+
+		Failing example:
+
+		  service Foo {}
+
+		Passing example:
+
+		  service FooService {}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		`{"lint":{"use":["SERVICE_SUFFIX"]}}`,
+		"--explain",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"id":"SERVICE_SUFFIX","categories":["DEFAULT","STYLE_DEFAULT"],"purpose":"Checks that services are suffixed with Service (suffix is configurable).","default":true,"fixable":false,"config_keys":["service_suffix"],"explanation":"A consistent suffix, Service by default, distinguishes services from messages\nin generated code and documentation. The suffix is configurable with\nservice_suffix.","failing_example":"service Foo {}","passing_example":"service FooService {}"}
+		`,
+		"check",
+		"ls-lint-checkers",
+		"--config",
+		`{"lint":{"use":["SERVICE_SUFFIX"]}}`,
+		"--explain",
+		"--format",
+		"json",
+	)
+}
+
+func TestCheckLsRules(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		TYPE  ID                       CATEGORIES  FIXABLE  PURPOSE
+		lint  RPC_NO_CLIENT_STREAMING  UNARY_RPC   no       Checks that RPCs are not client streaming.
+		lint  RPC_NO_SERVER_STREAMING  UNARY_RPC   no       Checks that RPCs are not server streaming.
+		`,
+		"check",
+		"ls-rules",
+		"--category",
+		"UNARY_RPC",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"type":"lint","id":"RPC_NO_CLIENT_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not client streaming.","default":false,"fixable":false,"config_versions":["v1"]}
+		{"type":"lint","id":"RPC_NO_SERVER_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not server streaming.","default":false,"fixable":false,"config_versions":["v1"]}
+		`,
+		"check",
+		"ls-rules",
+		"--category",
+		"UNARY_RPC",
+		"--format",
+		"json",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`"UNKNOWN" is not a known category`,
+		"check",
+		"ls-rules",
+		"--category",
+		"UNKNOWN",
+	)
+}
+
+func TestCheckLsRulesExplain(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		{"type":"lint","id":"RPC_NO_CLIENT_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not client streaming.","default":false,"fixable":false,"config_versions":["v1"],"explanation":"Streaming RPCs are not supported by all RPC frameworks and proxies, and are\nharder to retry, load balance, and debug than unary RPCs.","failing_example":"service FooService {\n  rpc UploadFoo(stream UploadFooRequest) returns (UploadFooResponse);\n}","passing_example":"service FooService {\n  rpc UploadFoo(UploadFooRequest) returns (UploadFooResponse);\n}"}
+		{"type":"lint","id":"RPC_NO_SERVER_STREAMING","categories":["UNARY_RPC"],"purpose":"Checks that RPCs are not server streaming.","default":false,"fixable":false,"config_versions":["v1"],"explanation":"Streaming RPCs are not supported by all RPC frameworks and proxies, and are\nharder to retry, load balance, and debug than unary RPCs.","failing_example":"service FooService {\n  rpc ListFoos(ListFoosRequest) returns (stream ListFoosResponse);\n}","passing_example":"service FooService {\n  rpc ListFoos(ListFoosRequest) returns (ListFoosResponse);\n}"}
+		`,
+		"check",
+		"ls-rules",
+		"--category",
+		"UNARY_RPC",
+		"--explain",
+		"--format",
+		"json",
+	)
+}
+
+func TestCheckExplain(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		SERVICE_SUFFIX
+
+		Checks that services are suffixed with Service (suffix is configurable).
+
+		Categories: DEFAULT, STYLE_DEFAULT
+
+		A consistent suffix, Service by default, distinguishes services from messages
+		in generated code and documentation. The suffix is configurable with
+		service_suffix.
+
+		Failing example:
+
+		service Foo {}
+
+		Passing example:
+
+		service FooService {}
+		`,
+		"check",
+		"explain",
+		"SERVICE_SUFFIX",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		FIELD_SAME_TYPE
+
+		Checks that fields have the same types in a given message.
+
+		Categories: FILE, PACKAGE
+
+		The type of a field determines how it is encoded, and how it is represented in
+		generated code. Changing it breaks code that uses the field, even where the
+		encodings are compatible.
+
+		Previous example:
+
+		  message Foo {
+		    int32 count = 1;
+		  }
+
+		Failing example:
+
+		  message Foo {
+		    int64 count = 1;
+		  }
+
+		Passing example:
+
+		  message Foo {
+		    int32 count = 1;
+		  }
+		`,
+		"check",
+		"explain",
+		"FIELD_SAME_TYPE",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"check",
+		"explain",
+		"FOO",
+	)
+}
+
+func TestCheckLsBreakingCheckers1(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		ID                                           CATEGORIES                      FIXABLE  PURPOSE
+		ENUM_VALUE_SAME_NAME                         FILE, PACKAGE, WIRE_JSON        no       Checks that enum values have the same name.
+		FIELD_SAME_JSON_NAME                         FILE, PACKAGE, WIRE_JSON        no       Checks that fields have the same value for the json_name option.
+		FIELD_SAME_NAME                              FILE, PACKAGE, WIRE_JSON        no       Checks that fields have the same names in a given message.
+		MESSAGE_SAME_MAP_ENTRY                       FILE, PACKAGE, WIRE_JSON        no       Checks that messages have the same value for the map_entry option.
+		FIELD_SAME_LABEL                             FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that fields have the same labels in a given message.
+		FIELD_SAME_ONEOF                             FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that fields have the same oneofs in a given message.
+		MESSAGE_SAME_MESSAGE_SET_WIRE_FORMAT         FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that messages have the same value for the message_set_wire_format option.
+		RESERVED_ENUM_NO_DELETE                      FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that reserved ranges and names are not deleted from a given enum.
+		RESERVED_MESSAGE_NO_DELETE                   FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that reserved ranges and names are not deleted from a given message.
+		RPC_SAME_CLIENT_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that rpcs have the same client streaming value.
+		RPC_SAME_IDEMPOTENCY_LEVEL                   FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that rpcs have the same value for the idempotency_level option.
+		RPC_SAME_REQUEST_TYPE                        FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that rpcs are have the same request type.
+		RPC_SAME_RESPONSE_TYPE                       FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that rpcs are have the same response type.
+		RPC_SAME_SERVER_STREAMING                    FILE, PACKAGE, WIRE_JSON, WIRE  no       Checks that rpcs have the same server streaming value.
+		ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED    WIRE_JSON                       no       Checks that enum values are not deleted from a given enum unless the name is reserved.
+		FIELD_NO_DELETE_UNLESS_NAME_RESERVED         WIRE_JSON                       no       Checks that fields are not deleted from a given message unless the name is reserved.
+		FIELD_WIRE_JSON_COMPATIBLE_TYPE              WIRE_JSON                       no       Checks that fields only change types in ways that are compatible on the wire and in JSON.
+		ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED  WIRE_JSON, WIRE                 no       Checks that enum values are not deleted from a given enum unless the number is reserved.
+		FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED       WIRE_JSON, WIRE                 no       Checks that fields are not deleted from a given message unless the number is reserved.
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--all",
+		"--category",
+		"WIRE_JSON",
+	)
+}
+
+func TestCheckLsBreakingCheckers2(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		ID                    CATEGORIES     FIXABLE  PURPOSE
+		ENUM_VALUE_NO_DELETE  FILE, PACKAGE  no       Checks that enum values are not deleted from a given enum.
+		FIELD_SAME_JSTYPE     FILE, PACKAGE  no       Checks that fields have the same value for the jstype option.
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--config",
+		filepath.Join("testdata", "small_list_checkers", "buf.yaml"),
+	)
+}
+
+func TestCheckLsBreakingCheckersExplain(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		FIELD_WIRE_COMPATIBLE_TYPE
+
+		Checks that fields only change types in ways that are compatible on the wire.
+
+		Categories: WIRE
+
+		Some types share an encoding on the wire, for example int32 and int64, so data
+		written with one can be read with the other. Changing a field to a type with an
+		incompatible encoding means data written before the change fails to parse or is
+		read incorrectly.
+
+		Previous example:
+
+		  message Foo {
+		    int32 count = 1;
+		  }
+
+		Failing example:
+
+		  message Foo {
+		    string count = 1;
+		  }
+
+		Passing example:
+
+		  message Foo {
+		    int64 count = 1;
+		  }
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--config",
+		`{"breaking":{"use":["FIELD_WIRE_COMPATIBLE_TYPE"]}}`,
+		"--explain",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"id":"FIELD_WIRE_COMPATIBLE_TYPE","categories":["WIRE"],"purpose":"Checks that fields only change types in ways that are compatible on the wire.","default":false,"fixable":false,"explanation":"Some types share an encoding on the wire, for example int32 and int64, so data\nwritten with one can be read with the other. Changing a field to a type with an\nincompatible encoding means data written before the change fails to parse or is\nread incorrectly.","previous_example":"message Foo {\n  int32 count = 1;\n}","failing_example":"message Foo {\n  string count = 1;\n}","passing_example":"message Foo {\n  int64 count = 1;\n}"}
+		`,
+		"check",
+		"ls-breaking-checkers",
+		"--config",
+		`{"breaking":{"use":["FIELD_WIRE_COMPATIBLE_TYPE"]}}`,
+		"--explain",
+		"--format",
+		"json",
+	)
+}
+
+func TestLsFiles(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		testdata/success/buf/buf.proto
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"path":"buf/buf.proto","external_path":"testdata/success/buf/buf.proto","root":".","package":"buf"}
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		PATH                            ROOT  ROLE
+		testdata/success/buf/buf.proto  .     target
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--long",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		buf/buf.proto buf .
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"{{.Path}} {{.Package}} {{.Root}}",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"yaml",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--format",
+		"{{.Path",
+	)
+}
+
+func TestWorkspace(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		PATH                                     ROOT     ROLE
+		testdata/workspace/a/proto/a/v1/a.proto  a/proto  target
+		testdata/workspace/b/b/v1/b.proto        b        target
+		`,
+		"ls-files",
+		"--input",
+		filepath.Join("testdata", "workspace"),
+		"--long",
+	)
+	testRunStdout(
+		t,
+		5,
+		`testdata/workspace/b/b/v1/b.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "workspace"),
+	)
+}
+
+func TestModDeps(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	depDirPath := filepath.Join(tempDirPath, "dep")
+	modDirPath := filepath.Join(tempDirPath, "mod")
+	require.NoError(t, os.MkdirAll(filepath.Join(depDirPath, "proto", "dep", "v1"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(modDirPath, "mod", "v1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "buf.yaml"), []byte("build:\n  roots:\n    - proto\n"), 0644))
+	depFilePath := filepath.Join(depDirPath, "proto", "dep", "v1", "dep.proto")
+	require.NoError(t, ioutil.WriteFile(depFilePath, []byte("syntax = \"proto3\";\n\npackage dep.v1;\n\nmessage Dep {}\n"), 0644))
+	testRunGit(t, depDirPath, "init", "--quiet")
+	testRunGit(t, depDirPath, "add", ".")
+	testRunGit(t, depDirPath, "commit", "--quiet", "-m", "first")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(modDirPath, "buf.yaml"),
+			[]byte(fmt.Sprintf("deps:\n  - file://%s\n", filepath.ToSlash(filepath.Join(depDirPath, ".git")))),
+			0644,
+		),
+	)
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(modDirPath, "mod", "v1", "mod.proto"),
+			[]byte("syntax = \"proto3\";\n\npackage mod.v1;\n\nimport \"dep/v1/dep.proto\";\n\nmessage Mod {\n  dep.v1.Dep dep = 1;\n}\n"),
+			0644,
+		),
+	)
+
+	// no buf.lock
+	testRunStdout(t, 3, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	_, err = os.Stat(filepath.Join(modDirPath, "buf.lock"))
+	require.NoError(t, err)
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "image", "build", "-o", "-#format=json", "--exclude-imports", "--source", modDirPath)
+	assert.Contains(t, stdout.String(), `"name":"mod/v1/mod.proto"`)
+	assert.NotContains(t, stdout.String(), `"name":"dep/v1/dep.proto"`)
+
+	// the dependency changed, so the digest no longer matches buf.lock
+	require.NoError(t, ioutil.WriteFile(depFilePath, []byte("syntax = \"proto3\";\n\npackage dep.v1;\n\nmessage Dep {}\n\nmessage Other {}\n"), 0644))
+	testRunGit(t, depDirPath, "commit", "--quiet", "-a", "-m", "second")
+	testRunStdout(t, 3, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+}
+
+func TestModTransitiveDeps(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	// mod depends on a, which depends on b
+	bDirPath := filepath.Join(tempDirPath, "b")
+	aDirPath := filepath.Join(tempDirPath, "a")
+	modDirPath := filepath.Join(tempDirPath, "mod")
+	for _, dirPath := range []string{bDirPath, aDirPath, modDirPath} {
+		require.NoError(t, os.MkdirAll(dirPath, 0755))
+	}
+	bFilePath := filepath.Join(bDirPath, "b.proto")
+	require.NoError(t, ioutil.WriteFile(bFilePath, []byte("syntax = \"proto3\";\n\nmessage B {}\n"), 0644))
+	testRunGit(t, bDirPath, "init", "--quiet")
+	testRunGit(t, bDirPath, "add", ".")
+	testRunGit(t, bDirPath, "commit", "--quiet", "-m", "first")
+	bRemote := "file://" + filepath.ToSlash(filepath.Join(bDirPath, ".git"))
+	aRemote := "file://" + filepath.ToSlash(filepath.Join(aDirPath, ".git"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "buf.yaml"), []byte("deps:\n  - "+bRemote+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nimport \"b.proto\";\n\nmessage A {\n  B b = 1;\n}\n"), 0644))
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", aDirPath)
+	testRunGit(t, aDirPath, "init", "--quiet")
+	testRunGit(t, aDirPath, "add", ".")
+	testRunGit(t, aDirPath, "commit", "--quiet", "-m", "first")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDirPath, "buf.yaml"), []byte("deps:\n  - "+aRemote+"\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDirPath, "mod.proto"), []byte("syntax = \"proto3\";\n\nimport \"a.proto\";\n\nmessage Mod {\n  A a = 1;\n}\n"), 0644))
+
+	// b is pinned and built even though mod does not declare it
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	data, err := ioutil.ReadFile(filepath.Join(modDirPath, "buf.lock"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "remote: "+aRemote+"\n")
+	assert.Contains(t, string(data), "remote: "+bRemote+"\n")
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "image", "build", "-o", "-#format=json", "--source", modDirPath)
+	assert.Contains(t, stdout.String(), `"name":"b.proto"`)
+
+	// the transitive dependency is verified against buf.lock
+	require.NoError(t, ioutil.WriteFile(bFilePath, []byte("syntax = \"proto3\";\n\nmessage B {}\n\nmessage Other {}\n"), 0644))
+	testRunGit(t, bDirPath, "commit", "--quiet", "-a", "-m", "second")
+	testRunStdout(t, 3, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+
+	// the transitive dependency is vendored as well
+	testRunStdout(t, 0, ``, "beta", "mod", "vendor", "--dir", modDirPath)
+	require.NoError(t, os.RemoveAll(aDirPath))
+	require.NoError(t, os.RemoveAll(bDirPath))
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--offline", "--source", modDirPath)
+}
+
+func TestDepGraph(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	// a depends on x at v1
+	xDirPath := filepath.Join(tempDirPath, "x")
+	aDirPath := filepath.Join(tempDirPath, "a")
+	modDirPath := filepath.Join(tempDirPath, "mod")
+	for _, dirPath := range []string{xDirPath, aDirPath, modDirPath} {
+		require.NoError(t, os.MkdirAll(dirPath, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(xDirPath, "x.proto"), []byte("syntax = \"proto3\";\n\nmessage X {}\n"), 0644))
+	testRunGit(t, xDirPath, "init", "--quiet")
+	testRunGit(t, xDirPath, "add", ".")
+	testRunGit(t, xDirPath, "commit", "--quiet", "-m", "first")
+	testRunGit(t, xDirPath, "tag", "v1")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(xDirPath, "x.proto"), []byte("syntax = \"proto3\";\n\nmessage X {}\n\nmessage Y {}\n"), 0644))
+	testRunGit(t, xDirPath, "commit", "--quiet", "-a", "-m", "second")
+	testRunGit(t, xDirPath, "tag", "v2")
+	xRemote := "file://" + filepath.ToSlash(filepath.Join(xDirPath, ".git"))
+	aRemote := "file://" + filepath.ToSlash(filepath.Join(aDirPath, ".git"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "buf.yaml"), []byte("deps:\n  - "+xRemote+"#tag=v1\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nimport \"x.proto\";\n\nmessage A {\n  X x = 1;\n}\n"), 0644))
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", aDirPath)
+	testRunGit(t, aDirPath, "init", "--quiet")
+	testRunGit(t, aDirPath, "add", ".")
+	testRunGit(t, aDirPath, "commit", "--quiet", "-m", "first")
+	getLockedDigests := func(dirPath string) []string {
+		data, err := ioutil.ReadFile(filepath.Join(dirPath, "buf.lock"))
+		require.NoError(t, err)
+		var digests []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "digest: ") {
+				digests = append(digests, strings.TrimPrefix(line, "digest: "))
+			}
+		}
+		return digests
+	}
+	xV1Digest := getLockedDigests(aDirPath)[0]
+
+	// mod depends on a, and on x at the same version as a
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDirPath, "buf.yaml"), []byte("deps:\n  - "+aRemote+"\n  - "+xRemote+"#tag=v1\n"), 0644))
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	aDigest := getLockedDigests(modDirPath)[0]
+	assert.Equal(t, xV1Digest, getLockedDigests(modDirPath)[1])
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "beta", "dep", "graph", "--dir", modDirPath)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"MODULE DEPENDENCY DIGEST",
+			modDirPath + " " + aRemote + " " + aDigest,
+			modDirPath + " " + xRemote + "#tag=v1 " + xV1Digest,
+			aRemote + " " + xRemote + "#tag=v1 " + xV1Digest,
+		},
+		lines,
+	)
+	testRunStdout(
+		t,
+		0,
+		fmt.Sprintf(
+			`
+			{"dependency":"%s","digest":"%s"}
+			{"dependency":"%s#tag=v1","digest":"%s"}
+			{"module":"%s","dependency":"%s#tag=v1","digest":"%s"}
+			`,
+			aRemote, aDigest,
+			xRemote, xV1Digest,
+			aRemote, xRemote, xV1Digest,
+		),
+		"beta", "dep", "graph", "--dir", modDirPath, "--format", "json",
+	)
+
+	// mod depends on x at v2, which conflicts with a
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDirPath, "buf.yaml"), []byte("deps:\n  - "+aRemote+"\n  - "+xRemote+"#tag=v2\n"), 0644))
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	// x at v1 is pinned as well, as it is a transitive dependency through a
+	require.Len(t, getLockedDigests(modDirPath), 3)
+	assert.Equal(t, xV1Digest, getLockedDigests(modDirPath)[1])
+	xV2Digest := getLockedDigests(modDirPath)[2]
+	assert.NotEqual(t, xV1Digest, xV2Digest)
+	testRunStdoutStderr(
+		t,
+		1,
+		fmt.Sprintf(
+			`
+			digraph deps {
+			"%s" -> "%s" [label="%s"];
+			"%s" -> "%s#tag=v2" [label="%s", color=red];
+			"%s" -> "%s#tag=v1" [label="%s", color=red];
+			}
+			`,
+			modDirPath, aRemote, aDigest,
+			modDirPath, xRemote, xV2Digest,
+			aRemote, xRemote, xV1Digest,
+		),
+		fmt.Sprintf(
+			`
+			%s is depended on at more than one version:
+			%s#tag=v2 (%s) by %s
+			%s#tag=v1 (%s) by %s
+			`,
+			xRemote,
+			xRemote, xV2Digest, modDirPath,
+			xRemote, xV1Digest, aRemote,
+		),
+		"beta", "dep", "graph", "--dir", modDirPath, "--format", "dot",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`--format: unknown format: "yaml"`,
+		"beta", "dep", "graph", "--dir", modDirPath, "--format", "yaml",
+	)
+}
+
+func TestModVendor(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	depDirPath := filepath.Join(tempDirPath, "dep")
+	modDirPath := filepath.Join(tempDirPath, "mod")
+	require.NoError(t, os.MkdirAll(filepath.Join(depDirPath, "proto", "dep", "v1"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(modDirPath, "mod", "v1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "buf.yaml"), []byte("build:\n  roots:\n    - proto\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "proto", "dep", "v1", "dep.proto"), []byte("syntax = \"proto3\";\n\npackage dep.v1;\n\nmessage Dep {}\n"), 0644))
+	testRunGit(t, depDirPath, "init", "--quiet")
+	testRunGit(t, depDirPath, "add", ".")
+	testRunGit(t, depDirPath, "commit", "--quiet", "-m", "first")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(modDirPath, "buf.yaml"),
+			[]byte(fmt.Sprintf("deps:\n  - file://%s\n", filepath.ToSlash(filepath.Join(depDirPath, ".git")))),
+			0644,
+		),
+	)
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(modDirPath, "mod", "v1", "mod.proto"),
+			[]byte("syntax = \"proto3\";\n\npackage mod.v1;\n\nimport \"dep/v1/dep.proto\";\n\nmessage Mod {\n  dep.v1.Dep dep = 1;\n}\n"),
+			0644,
+		),
+	)
+
+	// no buf.lock
+	testRunStdout(t, 1, ``, "beta", "mod", "vendor", "--dir", modDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "vendor", "--dir", modDirPath)
+	// the dependency is no longer available, so the vendored copy is used,
+	// and its files are not part of the module
+	require.NoError(t, os.RemoveAll(depDirPath))
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--offline", "--source", modDirPath)
+	testRunStdout(t, 0, filepath.FromSlash(modDirPath+"/mod/v1/mod.proto"), "ls-files", "--input", modDirPath)
+
+	// the vendored dependency does not match buf.lock
+	vendorFilePaths, err := filepath.Glob(filepath.Join(modDirPath, "buf.vendor", "*", "proto", "dep", "v1", "dep.proto"))
+	require.NoError(t, err)
+	require.Len(t, vendorFilePaths, 1)
+	require.NoError(t, ioutil.WriteFile(vendorFilePaths[0], []byte("syntax = \"proto3\";\n\npackage dep.v1;\n\nmessage Other {}\n"), 0644))
+	testRunStdout(t, 3, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	require.NoError(t, os.RemoveAll(filepath.Join(modDirPath, "buf.vendor")))
+	testRunStdout(t, 3, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+}
+
+func TestOffline(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`input: cannot read https://example.com/protos.tar.gz, network access is disabled in offline mode`,
+		"ls-files",
+		"--offline",
+		"--input",
+		"https://example.com/protos.tar.gz",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`input: cannot read grpc://localhost:50051, network access is disabled in offline mode`,
+		"ls-files",
+		"--offline",
+		"--input",
+		"grpc://localhost:50051",
+	)
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	repoDirPath := filepath.Join(tempDirPath, "repo")
+	cacheDirPath := filepath.Join(tempDirPath, "cache")
+	require.NoError(t, os.MkdirAll(repoDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "a.proto"), []byte(`syntax = "proto3";`), 0644))
+	testRunGit(t, repoDirPath, "init", "--quiet")
+	testRunGit(t, repoDirPath, "add", ".")
+	testRunGit(t, repoDirPath, "commit", "--quiet", "-m", "first")
+	gitCacheDirPath := filepath.Join(cacheDirPath, "buf", "git")
+	otherCacheDirPath := filepath.Join(cacheDirPath, "other")
+	require.NoError(t, os.MkdirAll(otherCacheDirPath, 0755))
+
+	testRunCache := func(args ...string) {
+		appcmdtesting.RunCommandExitCode(
+			t,
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			0,
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+			nil,
+			nil,
+			args...,
+		)
+	}
+	input := "file://" + filepath.ToSlash(filepath.Join(repoDirPath, ".git"))
+	testRunCache("ls-files", "--input", input, "--no-cache")
+	_, err = os.Stat(gitCacheDirPath)
+	assert.True(t, os.IsNotExist(err))
+	testRunCache("ls-files", "--input", input)
+	_, err = os.Stat(gitCacheDirPath)
+	assert.NoError(t, err)
+	// stdin is not a terminal, so this does not prompt, and the answer on stdin is not read
+	appcmdtesting.RunCommandExitCodeStdoutStderr(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		0,
+		``,
+		``,
+		map[string]string{
+			"XDG_CACHE_HOME": cacheDirPath,
+		},
+		strings.NewReader("n\n"),
+		"cache",
+		"clear",
+	)
+	_, err = os.Stat(gitCacheDirPath)
+	assert.True(t, os.IsNotExist(err))
+	// only the caches of buf are cleared
+	_, err = os.Stat(otherCacheDirPath)
+	assert.NoError(t, err)
+	testRunCache("ls-files", "--input", input)
+	_, err = os.Stat(gitCacheDirPath)
+	assert.NoError(t, err)
+	testRunCache("cache", "clear", "--yes")
+	_, err = os.Stat(gitCacheDirPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestKeepTemp is not parallel so that no other test creates temporary
+// directories while the temporary directories left behind are checked.
+func TestKeepTemp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tar is not available on windows")
+	}
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	cacheDirPath := filepath.Join(tempDirPath, "cache")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte(`syntax = "proto3";`), 0644))
+	tarFilePath := filepath.Join(tempDirPath, "proto.tar")
+	output, err := exec.Command("tar", "-C", protoDirPath, "-cf", tarFilePath, ".").CombinedOutput()
+	require.NoError(t, err, string(output))
+	testRunGit(t, protoDirPath, "init", "--quiet")
+	testRunGit(t, protoDirPath, "add", ".")
+	testRunGit(t, protoDirPath, "commit", "--quiet", "-m", "first")
+	gitInput := "file://" + filepath.ToSlash(filepath.Join(protoDirPath, ".git"))
+
+	pathRegexp := regexp.MustCompile(`"path": "([^"]+)"`)
+	getTempDirPaths := func() map[string]struct{} {
+		matches, err := filepath.Glob(filepath.Join(os.TempDir(), "buf-*"))
+		require.NoError(t, err)
+		tempDirPaths := make(map[string]struct{}, len(matches))
+		for _, match := range matches {
+			tempDirPaths[match] = struct{}{}
+		}
+		return tempDirPaths
+	}
+	// testRunKeepTemp returns the paths of the temporary directories that were
+	// logged, and checks that they are exactly the new directories left behind.
+	testRunKeepTemp := func(input string, keepTemp bool) []string {
+		t.Helper()
+		args := []string{"ls-files", "--input", input, "--no-cache"}
+		if keepTemp {
+			args = append(args, "--keep-temp")
+		}
+		before := getTempDirPaths()
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		exitCode := appcmdtesting.RunCommand(
+			context.Background(),
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+			nil,
+			stdout,
+			stderr,
+			args...,
+		)
+		require.Equal(t, 0, exitCode, stderr.String())
+		var loggedPaths []string
+		for _, match := range pathRegexp.FindAllStringSubmatch(stderr.String(), -1) {
+			loggedPaths = append(loggedPaths, match[1])
+		}
+		var newPaths []string
+		for path := range getTempDirPaths() {
+			if _, ok := before[path]; !ok {
+				newPaths = append(newPaths, path)
+			}
+		}
+		assert.ElementsMatch(t, loggedPaths, newPaths, input)
+		return loggedPaths
+	}
+
+	for _, input := range []string{tarFilePath, gitInput} {
+		// nothing is left behind without --keep-temp
+		assert.Empty(t, testRunKeepTemp(input, false), input)
+		// the directory the input was read to is kept and logged with --keep-temp
+		paths := testRunKeepTemp(input, true)
+		if assert.Len(t, paths, 1, input) {
+			data, err := ioutil.ReadFile(filepath.Join(paths[0], "a.proto"))
+			assert.NoError(t, err, input)
+			assert.Equal(t, `syntax = "proto3";`, string(data), input)
+			assert.NoError(t, os.RemoveAll(paths[0]))
+		}
+	}
+}
+
+func TestBuildCache(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	cacheDirPath := filepath.Join(tempDirPath, "cache")
+	buildCacheDirPath := filepath.Join(cacheDirPath, "buf", "build")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "b.proto"), []byte("syntax = \"proto3\";\n\nimport \"a.proto\";\n\nmessage B {\n  A a = 1;\n}\n"), 0644))
+
+	testRunBuildCache := func(expectedExitCode int, args ...string) string {
+		stdout := bytes.NewBuffer(nil)
+		appcmdtesting.RunCommandExitCode(
+			t,
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			expectedExitCode,
+			map[string]string{
+				"XDG_CACHE_HOME": cacheDirPath,
+			},
+			nil,
+			stdout,
+			args...,
+		)
+		return stdout.String()
+	}
+	imageBuildArgs := []string{"image", "build", "-o", "-#format=json", "--exclude-source-info", "--source", protoDirPath}
+	expectedOutput := `{"file":[{"name":"a.proto","messageType":[{"name":"A"}],"syntax":"proto3"},{"name":"b.proto","dependency":["a.proto"],"messageType":[{"name":"B","field":[{"name":"a","number":1,"label":"LABEL_OPTIONAL","type":"TYPE_MESSAGE","typeName":".A","jsonName":"a"}]}],"syntax":"proto3"}],"bufbuildImageExtension":{}}`
+
+	assert.Equal(t, expectedOutput, testRunBuildCache(0, append(imageBuildArgs, "--no-cache")...))
+	_, err = os.Stat(buildCacheDirPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, expectedOutput, testRunBuildCache(0, imageBuildArgs...))
+	_, err = os.Stat(buildCacheDirPath)
+	assert.NoError(t, err)
+	// the second build is read from the cache
+	assert.Equal(t, expectedOutput, testRunBuildCache(0, imageBuildArgs...))
+
+	// b.proto imports a.proto, so it is rebuilt when a.proto changes
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage C {}\n"), 0644))
+	testRunBuildCache(4, imageBuildArgs...)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {}\n"), 0644))
+	assert.Equal(t, expectedOutput, testRunBuildCache(0, imageBuildArgs...))
+
+	// the entry of b.proto is replaced when a.proto changes, so later builds are read from the cache
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {}\n\nmessage D {}\n"), 0644))
+	testRunBuildCache(0, imageBuildArgs...)
+	testRunBuildCache(0, imageBuildArgs...)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := appcmdtesting.RunCommand(
+		context.Background(),
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		map[string]string{
+			"XDG_CACHE_HOME": cacheDirPath,
+		},
+		nil,
+		ioutil.Discard,
+		stderr,
+		append(imageBuildArgs, "--log-level", "debug", "--log-format", "json")...,
+	)
+	require.Equal(t, 0, exitCode, stderr.String())
+	assert.Contains(t, stderr.String(), `"message":"build_cache","cached":2,"uncached":0`)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {}\n"), 0644))
+
+	// entries that have not been used for 30 days are pruned when files are added
+	staleTime := time.Now().Add(-31 * 24 * time.Hour)
+	staleFilePaths := []string{
+		filepath.Join(buildCacheDirPath, "stale.json"),
+		filepath.Join(buildCacheDirPath, "stale.data"),
+	}
+	for _, staleFilePath := range staleFilePaths {
+		require.NoError(t, ioutil.WriteFile(staleFilePath, nil, 0644))
+		require.NoError(t, os.Chtimes(staleFilePath, staleTime, staleTime))
+	}
+	pruneFilePath := filepath.Join(buildCacheDirPath, "pruned")
+	require.NoError(t, os.Chtimes(pruneFilePath, staleTime, staleTime))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "c.proto"), []byte("syntax = \"proto3\";\n\nmessage C {}\n"), 0644))
+	testRunBuildCache(0, imageBuildArgs...)
+	for _, staleFilePath := range staleFilePaths {
+		_, err = os.Stat(staleFilePath)
+		assert.True(t, os.IsNotExist(err))
+	}
+	fileInfo, err := os.Stat(pruneFilePath)
+	require.NoError(t, err)
+	assert.True(t, fileInfo.ModTime().After(staleTime))
+	// the entries of both versions of a.proto, b.proto, and c.proto are kept
+	metadataFilePaths, err := filepath.Glob(filepath.Join(buildCacheDirPath, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, metadataFilePaths, 4)
+
+	testRunBuildCache(0, "cache", "clear")
+	_, err = os.Stat(buildCacheDirPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCheckBreakingAgainstGit(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	oldDirPath := filepath.Join(protoDirPath, "old")
+	require.NoError(t, os.MkdirAll(oldDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {\n  string one = 1;\n  string two = 2;\n}\n"), 0644))
+	// does not compile, so the against input can only be built if it is excluded
+	require.NoError(t, ioutil.WriteFile(filepath.Join(oldDirPath, "old.proto"), []byte("invalid"), 0644))
+	testRunGit(t, tempDirPath, "init", "--quiet")
+	testRunGit(t, tempDirPath, "add", ".")
+	testRunGit(t, tempDirPath, "commit", "--quiet", "-m", "first")
+	testRunGit(t, tempDirPath, "branch", "-M", "main")
+	require.NoError(t, os.RemoveAll(oldDirPath))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\nmessage A {\n  string one = 1;\n}\n"), 0644))
+
+	// the proto directory has no .git, so the repository root is detected and
+	// the against input is the proto directory on the main branch
+	against := filepath.ToSlash(filepath.Join(protoDirPath, ".git")) + "#branch=main"
+	againstConfig := `{"build":{"excludes":["old"]}}`
+	testRunStdout(t, 4, `old/old.proto:1:1:syntax error: unexpected identifier`, "check", "breaking", "--input", protoDirPath, "--against", against)
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 6, nil, stdout, "check", "breaking", "--input", protoDirPath, "--against", against, "--against-config", againstConfig)
+	assert.Contains(t, stdout.String(), `Previously present field "2" with name "two" on message "A" was deleted.`)
+	stdout.Reset()
+	testRun(t, 6, nil, stdout, "check", "breaking", "--input", protoDirPath, "--against-input", against, "--against-input-config", againstConfig)
+	assert.Contains(t, stdout.String(), `Previously present field "2" with name "two" on message "A" was deleted.`)
+	testRunStdout(t, 1, ``, "check", "breaking", "--input", protoDirPath, "--against", against, "--against-input", against)
+}
+
+func testRunGit(t *testing.T, dirPath string, args ...string) {
+	cmd := exec.Command(
+		"git",
+		append(
+			[]string{
+				"-c", "user.name=test",
+				"-c", "user.email=test@test.com",
+				"-c", "commit.gpgsign=false",
+			},
+			args...,
+		)...,
+	)
+	cmd.Dir = dirPath
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestImageConvertRoundtripBinaryJSONBinary(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+
+	binary1 := stdout.Bytes()
+	require.NotEmpty(t, binary1)
+
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-",
+		"-o",
+		"-#format=json",
+	)
+
+	stdin = stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-#format=json",
+		"-o",
+		"-",
+	)
+
+	require.Equal(t, binary1, stdout.Bytes())
+}
+
+func TestImageConvertFIFO(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("named pipes require mkfifo")
+	}
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	binary := stdout.Bytes()
+	require.NotEmpty(t, binary)
+	expectedStdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		bytes.NewReader(binary),
+		expectedStdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-#format=bin",
+		"-o",
+		"-#format=json",
+	)
+
+	// the size of a named pipe is not known until it is read, so it must be
+	// streamed instead of memory-mapped
+	fifoFilePath := filepath.Join(tempDirPath, "image.bin")
+	output, err := exec.Command("mkfifo", fifoFilePath).CombinedOutput()
+	require.NoError(t, err, string(output))
+	errC := make(chan error, 1)
+	go func() {
+		errC <- ioutil.WriteFile(fifoFilePath, binary, 0600)
+	}()
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		fifoFilePath+"#format=bin",
+		"-o",
+		"-#format=json",
+	)
+	require.NoError(t, <-errC)
+	assert.Equal(t, expectedStdout.String(), stdout.String())
+}
+
+func TestStrictResolution(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	imagePath := filepath.Join(tempDirPath, "image.bin")
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		imagePath,
+		"--exclude-imports",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	// unresolvable references are allowed by default
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "customoptions1"),
+		"--against",
+		imagePath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`against: image is not self-contained: could not resolve import "google/protobuf/descriptor.proto", type google.protobuf.FieldOptions`,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "customoptions1"),
+		"--against",
+		imagePath,
+		"--strict-resolution",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"experimental",
+		"image",
+		"convert",
+		"--image",
+		imagePath,
+		"-o",
+		app.DevNullFilePath,
+		"--strict-resolution",
+	)
+	// sources are always built with all of their imports
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		filepath.Join("testdata", "customoptions1"),
+		"--against",
+		filepath.Join("testdata", "customoptions1"),
+		"--strict-resolution",
+	)
+}
+
+func TestImageConvertDetectStdinFormat(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	binary := stdout.Bytes()
+	require.NotEmpty(t, binary)
+
+	for _, format := range []string{"bin", "json", "txtpb", "bin,compression=gzip", "json,compression=zstd", "txtpb,compression=gzip"} {
+		stdin := bytes.NewBuffer(nil)
+		testRun(
+			t,
+			0,
+			nil,
+			stdin,
+			"image",
+			"build",
+			"-o",
+			"-#format="+format,
+			"--source",
+			filepath.Join("testdata", "customoptions1"),
+		)
+		stdout = bytes.NewBuffer(nil)
+		testRun(
+			t,
+			0,
+			stdin,
+			stdout,
+			"experimental",
+			"image",
+			"convert",
+			"-i",
+			"-",
+			"-o",
+			"-",
+		)
+		require.Equal(t, binary, stdout.Bytes(), format)
+	}
+
+	testRunStdin(
+		t,
+		1,
+		`syntax = "proto3";`,
+		``,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-",
+		"-o",
+		"-",
+	)
+}
+
+func TestImageJSONCustomOptions(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+		"--exclude-source-info",
+	)
+	// custom options are rendered by name rather than dropped as unknown fields
+	require.Contains(t, stdout.String(), `"options":{"[baz]":42}`)
+
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+		"--exclude-source-info",
+	)
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-",
+		"-o",
+		"-#format=json",
+	)
+	require.Contains(t, stdout.String(), `"options":{"[baz]":42}`)
+}
+
+func TestImageConvertRoundtripJSONBinaryJSON(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+
+	json1 := stdout.Bytes()
+	require.NotEmpty(t, json1)
+
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-#format=json",
+		"-o",
+		"-",
+	)
+
+	stdin = stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-",
+		"-o",
+		"-#format=json",
+	)
+
+	require.Equal(t, json1, stdout.Bytes())
+}
+
+func TestImageConvertFileDescriptorSetRoundtrip(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+		"--as-file-descriptor-set",
+	)
+	fileDescriptorSet1 := stdout.Bytes()
+	require.NotEmpty(t, fileDescriptorSet1)
+	// a vanilla FileDescriptorSet, without the image extension
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(fileDescriptorSet1, fileDescriptorSet))
+	require.NotEmpty(t, fileDescriptorSet.File)
+	require.Empty(t, fileDescriptorSet.ProtoReflect().GetUnknown())
+	// the image extension is a field of each file, so it would be an unknown
+	// field of the file, not of the set
+	for _, file := range fileDescriptorSet.File {
+		assert.Empty(t, file.ProtoReflect().GetUnknown(), file.GetName())
+	}
+
+	// FileDescriptorSet to Image, marking the dependencies of a.proto as imports
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-",
+		"-o",
+		"-#format=json",
+		"--file",
+		"a.proto",
+	)
+	require.Contains(t, stdout.String(), `"imageImportRefs":[{"fileIndex":0}]`)
+
+	// and back to a FileDescriptorSet
+	stdin = stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-#format=json",
+		"-o",
+		"-",
+		"--as-file-descriptor-set",
+	)
+	require.Equal(t, fileDescriptorSet1, stdout.Bytes())
+}
+
+func TestImageBuildJSONMarshalOptions(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	require.Contains(t, stdout.String(), `"messageType"`)
+	require.NotContains(t, stdout.String(), "\n  ")
+
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+		"--json-use-proto-names",
+		"--json-indent",
+		"2",
+	)
+	require.Contains(t, stdout.String(), `"message_type"`)
+	require.Contains(t, stdout.String(), "\n  ")
+}
+
+func TestImageBuildJSONCanonical(t *testing.T) {
+	t.Parallel()
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--source",
+		filepath.Join("testdata", "customoptions1"),
+		"--json-canonical",
+	)
+	json1 := stdout.Bytes()
+	require.NotEmpty(t, json1)
+
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		"-#format=json",
+		"-o",
+		"-#format=json",
+		"--json-canonical",
+	)
+	require.Equal(t, json1, stdout.Bytes())
+	// keys are sorted, so "bufbuildImageExtension" always precedes "file"
+	require.True(t, bytes.HasPrefix(json1, []byte(`{"bufbuildImageExtension":`)), string(json1[:64]))
+}
+
+func TestBetaValidate(t *testing.T) {
+	t.Parallel()
+	testRunStdin(
+		t,
+		0,
+		`{"id":"foo","items":[{"name":"bar","quantity":"2"}],"card":"baz"}`,
+		``,
+		"beta",
+		"validate",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--payload-format",
+		"json",
+		"-",
+	)
+	testRunStdin(
+		t,
+		1,
+		`{"items":[{"quantity":"2"}]}`,
+		`
+		id: required field not set
+		items[0].name: required field not set
+		`,
+		"beta",
+		"validate",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--payload-format",
+		"json",
+		"-",
+	)
+	testRunStdin(
+		t,
+		1,
+		// items[0] has field number 99 set, which is unknown
+		"\x0a\x03foo\x12\x08\x0a\x03bar\x98\x06\x01",
+		`
+		{"path":"items[0]","message":"unknown fields present"}
+		`,
+		"beta",
+		"validate",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--payload-format",
+		"bin",
+		"--error-format",
+		"json",
+		"-",
+	)
+	testRunStdin(
+		t,
+		1,
+		// card, cash, and then card are set, which are all in the oneof payment
+		"\x0a\x03foo\x1a\x01x\x22\x01y\x1a\x01z",
+		`
+		cash: set after field card of the same oneof payment
+		card: set after field cash of the same oneof payment
+		`,
+		"beta",
+		"validate",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--payload-format",
+		"bin",
+		"-",
+	)
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	testRunStdin(
+		t,
+		0,
+		`{"id":"foo","items":[{"name":"bar","quantity":"2"}]}`,
+		"\x0a\x03foo\x12\x07\x0a\x03bar\x10\x02",
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--from",
+		"-#format=json",
+	)
+	testRunStdin(
+		t,
+		0,
+		"\x0a\x03foo\x12\x07\x0a\x03bar\x10\x02",
+		`{"id":"foo","items":[{"name":"bar","quantity":"2"}]}`,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--to",
+		"-#format=json",
+	)
+	testRunStdin(
+		t,
+		0,
+		`id: "foo" items: { name: "bar" quantity: 2 }`,
+		`{"id":"foo","items":[{"name":"bar","quantity":"2"}]}`,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--from",
+		"-#format=text",
+		"--to",
+		"-#format=json",
+	)
+	testRunStdin(
+		t,
+		1,
+		``,
+		``,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--to",
+		"-#format=yaml",
+	)
+	testRunStdin(
+		t,
+		0,
+		"\x0a\x03foo",
+		`{"id":"foo","items":[]}`,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--to",
+		"-#format=json",
+		"--json-emit-unpopulated",
+	)
+	testRunStdin(
+		t,
+		1,
+		"\x0a\x03foo",
+		``,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--to",
+		"-#format=json",
+		"--json-any-fallback",
+		"unknown",
+	)
+	// the same message in a different field order results in the same canonical output
+	for _, stdin := range []string{
+		`id: "foo" items: { name: "bar" quantity: 2 } card: "baz"`,
+		`card: "baz" items: { quantity: 2 name: "bar" } id: "foo"`,
+	} {
+		testRunStdin(
+			t,
+			0,
+			stdin,
+			`{"card":"baz","id":"foo","items":[{"name":"bar","quantity":"2"}]}`,
+			"convert",
+			"--input",
+			filepath.Join("testdata", "validate"),
+			"--type",
+			"a.v1.Order",
+			"--from",
+			"-#format=text",
+			"--to",
+			"-#format=json",
+			"--json-canonical",
+		)
+	}
+}
+
+func TestConvertStream(t *testing.T) {
+	t.Parallel()
+	delimited := []byte("\x0e\x0a\x03foo\x12\x07\x0a\x03bar\x10\x02\x05\x0a\x03baz\x08\x0a\x03qux\x1a\x01x")
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		bytes.NewReader(delimited),
+		stdout,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--from",
+		"-#format=delimited",
+		"--to",
+		"-#format=jsonl",
+	)
+	require.Equal(
+		t,
+		`{"id":"foo","items":[{"name":"bar","quantity":"2"}]}`+"\n"+
+			`{"id":"baz"}`+"\n"+
+			`{"id":"qux","card":"x"}`+"\n",
+		stdout.String(),
+	)
+	stdin := stdout
+	stdout = bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		stdin,
+		stdout,
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--from",
+		"-#format=jsonl",
+		"--to",
+		"-#format=delimited",
+	)
+	require.Equal(t, delimited, stdout.Bytes())
+	// a stream of more than one message cannot be written as a single message
+	testRun(
+		t,
+		1,
+		bytes.NewReader(delimited),
+		bytes.NewBuffer(nil),
+		"convert",
+		"--input",
+		filepath.Join("testdata", "validate"),
+		"--type",
+		"a.v1.Order",
+		"--from",
+		"-#format=delimited",
+		"--to",
+		"-#format=bin",
+	)
+}
+
+func TestLsStats(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		PACKAGE  FILES  MESSAGES  FIELDS  EXTENSIONS  ONEOFS  ENUMS  ENUM_VALUES  SERVICES  METHODS  OPTIONS
+		-        1      1         1       1           0       0      0            0         0        1
+		`,
+		"ls-stats",
+		"--input",
+		filepath.Join("testdata", "customoptions1"),
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"files":1,"messages":1,"fields":1,"extensions":1,"oneofs":0,"enums":0,"enum_values":0,"services":0,"methods":0,"options":{"(baz)":1}}
+		`,
+		"ls-stats",
+		"--input",
+		filepath.Join("testdata", "customoptions1"),
+		"--format",
+		"json",
+	)
+}
+
+func TestBetaDocs(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		{"packages":[{"name":"acme.v1","leading_comments":"Package acme.v1 is for weather.","files":["a.proto"],"messages":[{"name":"Weather","full_name":"acme.v1.Weather","file":"a.proto","leading_comments":"Weather is the weather.\n\nIt has fields.","fields":[{"name":"temperature","number":1,"label":"optional","type":"double","json_name":"temperature","leading_comments":"The temperature.","trailing_comments":"in celsius"},{"name":"labels","number":2,"type":"map<string, string>","json_name":"labels","deprecated":true},{"name":"name","number":3,"label":"optional","type":"string","json_name":"name","oneof":"kind"}]},{"name":"Nested","full_name":"acme.v1.Weather.Nested","file":"a.proto"}],"enums":[{"name":"Condition","full_name":"acme.v1.Condition","file":"a.proto","values":[{"name":"CONDITION_UNSPECIFIED","number":0},{"name":"CONDITION_SUNNY","number":1,"leading_comments":"Sunny."}]}],"services":[{"name":"WeatherService","full_name":"acme.v1.WeatherService","file":"a.proto","leading_comments":"WeatherService serves weather.","methods":[{"name":"GetWeather","request_type":"acme.v1.Weather","response_type":"acme.v1.Weather","server_streaming":true,"leading_comments":"GetWeather gets weather."}]}]}]}
+		`,
+		"beta",
+		"docs",
+		"--input",
+		filepath.Join("testdata", "docs"),
+	)
+}
+
+func TestEditions(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	// the vendored descriptor.proto predates editions, so the edition and
+	// the features file option are set as unknown fields
+	writeImage := func(name string, syntax string, edition int, fieldPresence int) string {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("a.proto"),
+			Package: proto.String("a"),
+			Syntax:  proto.String(syntax),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:     proto.String("one"),
+							JsonName: proto.String("one"),
+							Number:   proto.Int32(1),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+						},
+					},
+				},
+			},
+		}
+		if edition != 0 {
+			fileDescriptorProto.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 14, protowire.VarintType), uint64(edition)))
+		}
+		if fieldPresence != 0 {
+			featureSet := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), uint64(fieldPresence))
+			fileDescriptorProto.Options = &descriptorpb.FileOptions{}
+			fileDescriptorProto.Options.ProtoReflect().SetUnknown(protowire.AppendBytes(protowire.AppendTag(nil, 50, protowire.BytesType), featureSet))
+		}
+		data, err := proto.Marshal(
+			&descriptorpb.FileDescriptorSet{
+				File: []*descriptorpb.FileDescriptorProto{
+					fileDescriptorProto,
+				},
+			},
+		)
+		require.NoError(t, err)
+		imagePath := filepath.Join(tempDirPath, name)
+		require.NoError(t, ioutil.WriteFile(imagePath, data, 0600))
+		return imagePath
+	}
+	proto3ImagePath := writeImage("proto3.bin", "proto3", 0, 0)
+	editionsImagePath := writeImage("editions.bin", "editions", 1000, 0)
+	// field_presence = IMPLICIT, which is the behavior of proto3
+	editionsImplicitImagePath := writeImage("editions_implicit.bin", "editions", 1000, 2)
+
+	testRunStdout(
+		t,
+		0,
+		`{"path":"a.proto","external_path":"a.proto","package":"a","edition":"2023"}`,
+		"ls-files",
+		"--input",
+		editionsImagePath,
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		a.proto proto3
+		`,
+		"ls-files",
+		"--input",
+		proto3ImagePath,
+		"--format",
+		"{{.Path}} {{.Syntax}}{{.Edition}}",
+	)
+	// the edition is passed through when the image is converted
+	convertedImagePath := filepath.Join(tempDirPath, "converted.bin")
+	testRunStdout(
+		t,
+		0,
+		``,
+		"experimental",
+		"image",
+		"convert",
+		"--image",
+		editionsImplicitImagePath,
+		"-o",
+		convertedImagePath,
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		a.proto editions 2023
+		`,
+		"ls-files",
+		"--input",
+		convertedImagePath,
+		"--format",
+		"{{.Path}} {{.Syntax}} {{.Edition}}",
+	)
+	// editions files are linted like any other file
+	testRunStdout(
+		t,
+		5,
+		`
+		a.proto:1:1:Files with package "a" must be within a directory "a" relative to root but were in directory ".".
+		a.proto:1:1:Package name "a" should be suffixed with a correctly formed version, such as "a.v1".
+		`,
+		"check",
+		"lint",
+		"--input",
+		editionsImagePath,
+	)
+	// moving from proto3 to an edition is only breaking if the features change
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		convertedImagePath,
+		"--against",
+		proto3ImagePath,
+	)
+	testRunStdout(
+		t,
+		6,
+		`a.proto:1:1:File changed from the "proto3" syntax to the "2023" edition, which changed feature "field_presence" from "IMPLICIT" to "EXPLICIT".`,
+		"check",
+		"breaking",
+		"--input",
+		editionsImagePath,
+		"--against",
+		proto3ImagePath,
+	)
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	fieldOptions := &descriptorpb.FieldOptions{}
+	fieldOptions.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 50001, protowire.VarintType), 1))
+	aFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Package:    proto.String("a"),
+		Dependency: []string{"b.proto", "c.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("bar"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".b.Bar"),
+					},
+					{
+						Name:     proto.String("baz"),
+						Number:   proto.Int32(2),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".a.Baz"),
+						Options:  fieldOptions,
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{
+					Path: []int32{4, 0, 2, 1},
+					Span: []int32{5, 2, 20},
+				},
+				{
+					Path: []int32{4, 1},
+					Span: []int32{8, 0, 10, 1},
+				},
+			},
+		},
+	}
+	bFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("b.proto"),
+		Package: proto.String("b"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Bar"),
+			},
+		},
+	}
+	imagePath := filepath.Join(tempDirPath, "image.bin")
+	data, err := proto.Marshal(
+		&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{
+				bFileDescriptorProto,
+				aFileDescriptorProto,
+				bFileDescriptorProto,
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(imagePath, data, 0600))
+	testRunStdout(
+		t,
+		1,
+		`
+		a.proto:1:1:Import "c.proto" is not contained in the image.
+		a.proto:6:3:The type "a.Baz" of field "a.Foo.baz" is not defined in the file or its imports.
+		a.proto:6:3:Option with field number 50001 on field "a.Foo.baz" is not defined by any extension of google.protobuf.FieldOptions in the image.
+		a.proto:9:1:Source code info location with path [4 1] does not refer to an element of the file.
+		b.proto:1:1:File is contained in the image more than once.
+		`,
+		"verify",
+		"--input",
+		imagePath,
+	)
+
+	// a file that cannot be unmarshaled is reported by name, and the rest of
+	// the image is still verified
+	data, err = proto.Marshal(
+		&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{
+				bFileDescriptorProto,
+			},
+		},
+	)
+	require.NoError(t, err)
+	invalidFileData := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "c.proto")
+	// a message_type with a length that exceeds the data
+	invalidFileData = protowire.AppendVarint(protowire.AppendTag(invalidFileData, 4, protowire.BytesType), 100)
+	data = protowire.AppendBytes(protowire.AppendTag(data, 1, protowire.BytesType), invalidFileData)
+	require.NoError(t, ioutil.WriteFile(imagePath, data, 0600))
+	stdout := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		1,
+		nil,
+		nil,
+		stdout,
+		"verify",
+		"--input",
+		imagePath,
+	)
+	assert.True(t, strings.HasPrefix(stdout.String(), "c.proto:1:1:File could not be unmarshaled: "), stdout.String())
+	assert.Equal(t, 1, strings.Count(stdout.String(), "\n"), stdout.String())
+
+	testRunStdout(t, 0, ``, "image", "build", "-o", imagePath, "--source", filepath.Join("testdata", "success"))
+	testRunStdout(t, 0, ``, "verify", "--input", imagePath)
+	testRunStdout(t, 0, ``, "image", "build", "-o", imagePath, "--exclude-source-info", "--source", filepath.Join("testdata", "success"))
+	testRunStdout(t, 0, ``, "verify", "--input", imagePath)
+}
+
+func TestLsFormats(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		`
+		NAME   TYPE    EXTENSIONS                   COMPRESSIONS
+		bin    image   .bin,.bin.gz,.bin.zst        gzip,zstd
+		dir    source
+		git    source  .git
+		json   image   .json,.json.gz,.json.zst     gzip,zstd
+		tar    source  .tar,.tar.gz,.tar.zst,.tgz   gzip,zstd
+		txtpb  image   .txtpb,.txtpb.gz,.txtpb.zst  gzip,zstd
+		zip    source  .zip
+		`,
+		"ls-formats",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"name":"bin","image":true,"extensions":[".bin",".bin.gz",".bin.zst"],"compressions":["gzip","zstd"]}
+		{"name":"dir"}
+		{"name":"git","extensions":[".git"]}
+		{"name":"json","image":true,"extensions":[".json",".json.gz",".json.zst"],"compressions":["gzip","zstd"]}
+		{"name":"tar","extensions":[".tar",".tar.gz",".tar.zst",".tgz"],"compressions":["gzip","zstd"]}
+		{"name":"txtpb","image":true,"extensions":[".txtpb",".txtpb.gz",".txtpb.zst"],"compressions":["gzip","zstd"]}
+		{"name":"zip","extensions":[".zip"]}
+		`,
+		"ls-formats",
+		"--format",
+		"json",
+	)
+}
+
+func TestImageBuildInferCompressedJSON(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	imagePath := filepath.Join(tempDirPath, "image.json.zst")
+
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		imagePath,
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		0,
+		nil,
+		stdout,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		imagePath,
+		"-o",
+		"-#format=json",
+	)
+	require.True(t, strings.HasPrefix(stdout.String(), `{"file":[`), stdout.String())
+}
+
+func TestImageBuildInferCompressedBinary(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	imagePath := filepath.Join(tempDirPath, "image.bin.zst")
+
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		imagePath,
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+	data, err := ioutil.ReadFile(imagePath)
+	require.NoError(t, err)
+	// zstd frame magic number
+	require.True(t, bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}))
+	testRunStdout(
+		t,
+		0,
+		`
+		google/protobuf/descriptor.proto
+		buf/buf.proto
+		`,
+		"ls-files",
+		"--input",
+		imagePath,
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		google/protobuf/descriptor.proto
+		buf/buf.proto
+		`,
+		"ls-files",
+		"--input",
+		imagePath+"#format=bin,compression=zstd",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		google/protobuf/descriptor.proto google.protobuf import
+		buf/buf.proto buf
+		`,
+		"ls-files",
+		"--input",
+		imagePath,
+		"--format",
+		"{{.Path}} {{.Package}}{{if .Import}} import{{end}}",
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		{"path":"google/protobuf/descriptor.proto","external_path":"google/protobuf/descriptor.proto","import":true,"package":"google.protobuf"}
+		{"path":"buf/buf.proto","external_path":"buf/buf.proto","package":"buf"}
+		`,
+		"ls-files",
+		"--input",
+		imagePath,
+		"--format",
+		"json",
+	)
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	imagePath := filepath.Join(tempDirPath, "image.bin")
+	sourceOutputDirPath := filepath.Join(tempDirPath, "source")
+	imageOutputDirPath := filepath.Join(tempDirPath, "image")
+
+	testRunStdout(
+		t,
+		0,
+		``,
+		"export",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"-o",
+		sourceOutputDirPath,
+		"--exclude-imports",
+	)
+	_, err = os.Stat(filepath.Join(sourceOutputDirPath, "buf", "buf.proto"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(sourceOutputDirPath, "google", "protobuf", "descriptor.proto"))
+	require.True(t, os.IsNotExist(err))
+
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		imagePath,
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"export",
+		"--input",
+		imagePath,
+		"-o",
+		imageOutputDirPath,
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		`+filepath.Join(imageOutputDirPath, "buf", "buf.proto")+`
+		`+filepath.Join(imageOutputDirPath, "google", "protobuf", "descriptor.proto")+`
+		`,
+		"ls-files",
+		"--input",
+		imageOutputDirPath,
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		sourceOutputDirPath,
+		"--input-config",
+		`{"lint":{"use":["PACKAGE_DEFINED"]}}`,
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"export",
+		"--input",
+		imagePath,
+	)
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	filePath := filepath.Join(tempDirPath, "a.proto")
+	data := "syntax=\"proto3\";\n\nmessage Foo {\nint32 one=1;\n}\n"
+	formattedData := "syntax = \"proto3\";\n\nmessage Foo {\n  int32 one = 1;\n}\n"
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "format", "--input", tempDirPath)
+	assert.Equal(t, formattedData, stdout.String())
+
+	stdout = bytes.NewBuffer(nil)
+	testRun(t, 1, nil, stdout, "format", "--input", tempDirPath, "--diff")
+	assert.Contains(t, stdout.String(), "-int32 one=1;\n+  int32 one = 1;\n")
+
+	testRunStdout(t, 0, ``, "format", "--input", tempDirPath, "-w")
+	fileData, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, formattedData, string(fileData))
+	testRunStdout(t, 0, ``, "format", "--input", tempDirPath, "-d")
+}
+
+func TestFormatPruneImports(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "b.proto"), []byte("syntax = \"proto3\";\n\nmessage B {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "c.proto"), []byte("syntax = \"proto3\";\n\nmessage C {}\n"), 0644))
+	filePath := filepath.Join(tempDirPath, "a.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("syntax = \"proto3\";\n\n// b is not used\nimport \"b.proto\";\nimport \"c.proto\";\n\nmessage A {\n  C c = 1;\n}\n"), 0644))
+
+	testRunStdout(t, 0, ``, "format", "--input", tempDirPath, "--prune-imports", "-w")
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "syntax = \"proto3\";\n\nimport \"c.proto\";\n\nmessage A {\n  C c = 1;\n}\n", string(data))
+}
+
+func TestFormatInvalid(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set both --write and --diff`,
+		"format",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--write",
+		"--diff",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`--input must be a local directory`,
+		"format",
+		"--input",
+		filepath.Join("testdata", "success", "image.bin"),
+	)
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+
+	testRunStdout(
+		t,
+		0,
+		``,
+		"generate",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--template",
+		`{"version":"v1beta1","plugins":[{"name":"go","out":"gen/go","opt":["paths=source_relative","Mbuf/buf.proto=example.com/buf"]}]}`,
+		"--output",
+		tempDirPath,
+	)
+	data, err := ioutil.ReadFile(filepath.Join(tempDirPath, "gen", "go", "buf", "buf.pb.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "package buf")
+
+	testRunStdout(
+		t,
+		1,
+		``,
+		"generate",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--template",
+		`{"version":"v1beta1","plugins":[{"name":"go","out":"gen/go","strategy":"foo"}]}`,
+		"--output",
+		tempDirPath,
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"generate",
+		"--input",
+		filepath.Join("testdata", "partial"),
+		"--template",
+		`{"version":"v1beta1","plugins":[{"name":"go","out":"gen/go"}]}`,
+		"--output",
+		tempDirPath,
+	)
+}
+
+func testRunStdout(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	testRunStdoutInternal(
+		t,
+		expectedExitCode,
+		expectedStdout,
+		args...,
+	)
+}
+
+func testRunStdoutStderr(t *testing.T, expectedExitCode int, expectedStdout string, expectedStderr string, args ...string) {
+	appcmdtesting.RunCommandExitCodeStdoutStderr(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		expectedExitCode,
+		expectedStdout,
+		expectedStderr,
+		nil,
+		nil,
+		args...,
+	)
+}
+
+func testRunStdoutProfile(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	profileDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(profileDirPath)) }()
+	testRunStdoutInternal(
+		t,
+		0,
+		``,
+		append(
+			args,
+			"--profile",
+			fmt.Sprintf("--profile-path=%s", profileDirPath),
+			"--profile-loops=1",
+			"--profile-type=cpu",
+		)...,
+	)
+}
+
+func testRunStdoutInternal(t *testing.T, expectedExitCode int, expectedStdout string, args ...string) {
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		expectedExitCode,
+		expectedStdout,
+		nil,
+		nil,
+		args...,
+	)
+}
+
+func testRunStdin(t *testing.T, expectedExitCode int, stdin string, expectedStdout string, args ...string) {
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		expectedExitCode,
+		expectedStdout,
+		nil,
+		strings.NewReader(stdin),
+		args...,
+	)
+}
+
+func testRun(
+	t *testing.T,
+	expectedExitCode int,
+	stdin io.Reader,
+	stdout io.Writer,
+	args ...string,
+) {
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		expectedExitCode,
+		nil,
+		stdin,
+		stdout,
+		args...,
+	)
+}
+
+func TestBetaLocation(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		0,
+		filepath.Join("testdata", "success", "buf", "buf.proto")+`:9:35:9:38`,
+		"beta",
+		"location",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"buf.Foo.two",
+	)
+	testRunStdout(
+		t,
+		0,
+		`{"path":"`+filepath.Join("testdata", "success", "buf", "buf.proto")+`","start_line":7,"start_column":1,"end_line":10,"end_column":2}`,
+		"beta",
+		"location",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"--span",
+		"declaration",
+		"--format",
+		"json",
+		".buf.Foo",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"beta",
+		"location",
+		"--input",
+		filepath.Join("testdata", "success"),
+		"buf.Bar",
+	)
+}
+
+func TestImageConvertSourceInfoFrom(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	fullImagePath := filepath.Join(tempDirPath, "full.bin")
+	slimImagePath := filepath.Join(tempDirPath, "slim.bin")
+	reattachedImagePath := filepath.Join(tempDirPath, "reattached.bin")
+
+	testRunStdout(t, 0, ``, "image", "build", "-o", fullImagePath, "--source", filepath.Join("testdata", "success"))
+	testRunStdout(t, 0, ``, "image", "build", "-o", slimImagePath, "--exclude-source-info", "--source", filepath.Join("testdata", "success"))
+	testRunStdout(
+		t,
+		0,
+		``,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		slimImagePath,
+		"--source-info-from",
+		filepath.Join("testdata", "success"),
+		"-o",
+		reattachedImagePath,
+	)
+	fullImageData, err := ioutil.ReadFile(fullImagePath)
+	require.NoError(t, err)
+	reattachedImageData, err := ioutil.ReadFile(reattachedImagePath)
+	require.NoError(t, err)
+	assert.Equal(t, fullImageData, reattachedImageData)
+
+	// different content for buf/buf.proto
+	testRunStdout(
+		t,
+		1,
+		``,
+		"experimental",
+		"image",
+		"convert",
+		"-i",
+		slimImagePath,
+		"--source-info-from",
+		filepath.Join("testdata", "fail"),
+		"-o",
+		app.DevNullFilePath,
+	)
+}
+
+func TestImageConvertAllowInsecureHTTP(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, buffer, "image", "build", "-o", "-", "--source", filepath.Join("testdata", "success"))
+	imageData := buffer.Bytes()
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write(imageData)
+			},
+		),
+	)
+	defer server.Close()
+	imageURL := server.URL + "/image.bin"
+	homeDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(homeDirPath)) }()
+	// the netrc authenticator requires $HOME to be set
+	env := map[string]string{"HOME": homeDirPath}
+
+	for _, expectedExitCodeAndArgs := range []struct {
+		expectedExitCode int
+		args             []string
+	}{
+		{1, []string{"experimental", "image", "convert", "-i", imageURL, "-o", app.DevNullFilePath}},
+		{0, []string{"experimental", "image", "convert", "-i", imageURL, "-o", app.DevNullFilePath, "--allow-insecure-http"}},
+	} {
+		appcmdtesting.RunCommandExitCodeStdout(
+			t,
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			expectedExitCodeAndArgs.expectedExitCode,
+			``,
+			env,
+			nil,
+			expectedExitCodeAndArgs.args...,
+		)
+	}
+}
+
+func TestPhaseTimeouts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating the tar input requires tar")
+	}
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	tarFilePath := filepath.Join(tempDirPath, "input.tar")
+	output, err := exec.Command("tar", "-C", filepath.Join("testdata", "success"), "-cf", tarFilePath, ".").CombinedOutput()
+	require.NoError(t, err, string(output))
+	tarData, err := ioutil.ReadFile(tarFilePath)
+	require.NoError(t, err)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				switch request.URL.Path {
+				case "/blocking.tar", "/blocking.bin":
+					// blocks until the client gives up
+					<-request.Context().Done()
+				case "/slow.tar":
+					time.Sleep(500 * time.Millisecond)
+					_, _ = responseWriter.Write(tarData)
+				default:
+					http.NotFound(responseWriter, request)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	homeDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(homeDirPath)) }()
+	// the netrc authenticator requires $HOME to be set
+	env := map[string]string{"HOME": homeDirPath}
+
+	for _, testCase := range []struct {
+		expectedExitCode int
+		// empty if the command succeeds
+		expectedStderr string
+		args           []string
+	}{
+		{
+			3,
+			"fetch timed out after 100ms",
+			[]string{"image", "build", "-o", app.DevNullFilePath, "--source", server.URL + "/blocking.tar#format=tar", "--fetch-timeout", "100ms", "--build-timeout", "1h"},
+		},
+		{
+			3,
+			"fetch timed out after 100ms",
+			[]string{"check", "lint", "--input", server.URL + "/blocking.bin", "--fetch-timeout", "100ms", "--check-timeout", "1h"},
+		},
+		{
+			1,
+			"build timed out after 1ns",
+			[]string{"image", "build", "-o", app.DevNullFilePath, "--source", filepath.Join("testdata", "success"), "--fetch-timeout", "1h", "--build-timeout", "1ns"},
+		},
+		{
+			1,
+			"check timed out after 1ns",
+			[]string{"check", "lint", "--input", filepath.Join("testdata", "success"), "--fetch-timeout", "1h", "--build-timeout", "1h", "--check-timeout", "1ns"},
+		},
+		{
+			// the build timeout does not start until the input is fetched
+			0,
+			"",
+			[]string{"image", "build", "-o", app.DevNullFilePath, "--source", server.URL + "/slow.tar#format=tar", "--build-timeout", "200ms"},
+		},
+	} {
+		stderr := bytes.NewBuffer(nil)
+		exitCode := appcmdtesting.RunCommand(
+			context.Background(),
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			env,
+			nil,
+			nil,
+			stderr,
+			append(testCase.args, "--allow-insecure-http")...,
+		)
+		assert.Equal(t, testCase.expectedExitCode, exitCode, "%v: %s", testCase.args, stderr.String())
+		if testCase.expectedStderr != "" {
+			assert.Contains(t, stderr.String(), testCase.expectedStderr, testCase.args)
+		} else {
+			assert.Empty(t, stderr.String(), testCase.args)
+		}
+	}
+}
+
+func TestPushOCI(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, buffer, "image", "build", "-o", "-", "--source", filepath.Join("testdata", "success"))
+	imageData := buffer.Bytes()
+	server := newTestOCIRegistryServer(t)
+	defer server.Close()
+	reference := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/test:v1"
+
+	testRunStdout(t, 0, ``, "push", reference, "--input", filepath.Join("testdata", "success"))
+	buffer.Reset()
+	testRun(t, 0, nil, buffer, "experimental", "image", "convert", "-i", reference, "-o", "-")
+	assert.Equal(t, imageData, buffer.Bytes())
+	testRunStdout(t, 1, ``, "push", "ghcr.io/test:v1", "--input", filepath.Join("testdata", "success"))
+}
+
+func TestPushOCIModule(t *testing.T) {
+	t.Parallel()
+	server := newTestOCIRegistryServer(t)
+	defer server.Close()
+	reference := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/test:v1"
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	depDirPath := filepath.Join(tempDirPath, "dep")
+	modDirPath := filepath.Join(tempDirPath, "mod")
+	require.NoError(t, os.MkdirAll(filepath.Join(depDirPath, "proto", "dep", "v1"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(modDirPath, "mod", "v1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "buf.yaml"), []byte("build:\n  roots:\n    - proto\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "proto", "dep", "v1", "dep.proto"), []byte("syntax = \"proto3\";\n\npackage dep.v1;\n\nmessage Dep {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDirPath, "buf.yaml"), []byte("deps:\n  - "+reference+"\n"), 0644))
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(modDirPath, "mod", "v1", "mod.proto"),
+			[]byte("syntax = \"proto3\";\n\npackage mod.v1;\n\nimport \"dep/v1/dep.proto\";\n\nmessage Mod {\n  dep.v1.Dep dep = 1;\n}\n"),
+			0644,
+		),
+	)
+
+	testRunStdout(t, 0, ``, "push", "--module", reference, "--input", depDirPath)
+	testRunStdout(t, 0, ``, "beta", "mod", "update", "--dir", modDirPath)
+	testRunStdout(t, 0, ``, "image", "build", "-o", app.DevNullFilePath, "--source", modDirPath)
+	testRunStdout(
+		t,
+		0,
+		filepath.FromSlash(`proto/dep/v1/dep.proto`),
+		"ls-files",
+		"--input",
+		reference+"#format=tar,compression=gzip",
+	)
+
+	// modules are built before they are pushed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(depDirPath, "proto", "dep", "v1", "dep.proto"), []byte("syntax = \"proto3\";\n\nmessage Dep {\n"), 0644))
+	testRunStdout(t, 1, ``, "push", "--module", reference, "--input", depDirPath)
+	testRunStdout(t, 1, ``, "push", "--module", reference, "--input", filepath.Join("testdata", "success"), "--exclude-imports")
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	netrcFilePath := filepath.Join(tempDirPath, "netrc")
+	env := map[string]string{"NETRC": netrcFilePath}
+
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		func(use string) *appcmd.Command { return newRootCommand(use) },
+		0,
+		``,
+		env,
+		strings.NewReader("token\n"),
+		"login",
+		"ghcr.io",
+		"--username",
+		"acme",
+	)
+	data, err := ioutil.ReadFile(netrcFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "machine ghcr.io\n\tlogin acme\n\tpassword token\n", string(data))
+	for _, stdinAndArgs := range []struct {
+		stdin string
+		args  []string
+	}{
+		{"", []string{"login", "ghcr.io"}},
+		{"token\n", []string{"login", "oci://ghcr.io/acme"}},
+	} {
+		appcmdtesting.RunCommandExitCodeStdout(
+			t,
+			func(use string) *appcmd.Command { return newRootCommand(use) },
+			1,
+			``,
+			env,
+			strings.NewReader(stdinAndArgs.stdin),
+			stdinAndArgs.args...,
+		)
+	}
+}
+
+// newTestOCIRegistryServer returns a server for an OCI registry without
+// authentication that stores everything by path.
+func newTestOCIRegistryServer(t *testing.T) *httptest.Server {
+	var lock sync.Mutex
+	pathToData := make(map[string][]byte)
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+				switch request.Method {
+				case http.MethodPost:
+					responseWriter.Header().Set("Location", "/v2/test/blobs/upload")
+					responseWriter.WriteHeader(http.StatusAccepted)
+				case http.MethodPut:
+					data, err := ioutil.ReadAll(request.Body)
+					require.NoError(t, err)
+					path := request.URL.Path
+					if digest := request.URL.Query().Get("digest"); digest != "" {
+						path = "/v2/test/blobs/" + digest
+					}
+					pathToData[path] = data
+					responseWriter.WriteHeader(http.StatusCreated)
+				default:
+					data, ok := pathToData[request.URL.Path]
+					if !ok {
+						responseWriter.WriteHeader(http.StatusNotFound)
+						return
+					}
+					_, _ = responseWriter.Write(data)
+				}
+			},
+		),
+	)
+}
+
+func TestImageBuildCompactSourceInfo(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	fullImagePath := filepath.Join(tempDirPath, "full.bin")
+	compactImagePath := filepath.Join(tempDirPath, "compact.bin")
+	restoredImagePath := filepath.Join(tempDirPath, "restored.bin")
+
+	testRunStdout(t, 0, ``, "image", "build", "-o", fullImagePath, "--source", filepath.Join("testdata", "success"))
+	testRunStdout(t, 0, ``, "image", "build", "-o", compactImagePath, "--compact-source-info", "--source", filepath.Join("testdata", "success"))
+	testRunStdout(t, 0, ``, "experimental", "image", "convert", "-i", compactImagePath, "-o", restoredImagePath)
+	fullImageData, err := ioutil.ReadFile(fullImagePath)
+	require.NoError(t, err)
+	compactImageData, err := ioutil.ReadFile(compactImagePath)
+	require.NoError(t, err)
+	restoredImageData, err := ioutil.ReadFile(restoredImagePath)
+	require.NoError(t, err)
+	assert.True(t, len(compactImageData) < len(fullImageData))
+	assert.Equal(t, fullImageData, restoredImageData)
+
+	testRunStdout(
+		t,
+		1,
+		``,
+		"image",
+		"build",
+		"-o",
+		app.DevNullFilePath,
+		"--compact-source-info",
+		"--as-file-descriptor-set",
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+}
+
+func TestImageBuildSourceInfoFilter(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	fullImagePath := filepath.Join(tempDirPath, "full.bin")
+	filteredImagePath := filepath.Join(tempDirPath, "filtered.bin")
+
+	testRunStdout(t, 0, ``, "image", "build", "-o", fullImagePath, "--source", filepath.Join("testdata", "success"))
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"-o",
+		filteredImagePath,
+		"--source-info-only-comments",
+		"--source-info-exclude-spans",
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+	fullImageData, err := ioutil.ReadFile(fullImagePath)
+	require.NoError(t, err)
+	filteredImageData, err := ioutil.ReadFile(filteredImagePath)
+	require.NoError(t, err)
+	assert.True(t, len(filteredImageData) < len(fullImageData))
+
+	testRunStdout(
+		t,
+		1,
+		``,
+		"image",
+		"build",
+		"-o",
+		app.DevNullFilePath,
+		"--source-info-only-comments",
+		"--exclude-source-info",
+		"--source",
+		filepath.Join("testdata", "success"),
+	)
+}
+
+func TestImageBuildDigest(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	image1Path := filepath.Join(tempDirPath, "image1.bin")
+	image2Path := filepath.Join(tempDirPath, "image2.bin")
+	jsonImagePath := filepath.Join(tempDirPath, "image.json")
+
+	testImageBuildDigest := func(output string) string {
+		stdout := bytes.NewBuffer(nil)
+		testRun(t, 0, nil, stdout, "image", "build", "-o", output, "--digest", "--source", filepath.Join("testdata", "success"))
+		digest := strings.TrimSpace(stdout.String())
+		assert.True(t, strings.HasPrefix(digest, "sha256:"), digest)
+		return digest
+	}
+	digest1 := testImageBuildDigest(image1Path)
+	digest2 := testImageBuildDigest(image2Path)
+	assert.Equal(t, digest1, digest2)
+	// the digest does not depend on the output format
+	assert.Equal(t, digest1, testImageBuildDigest(jsonImagePath))
+	assert.Equal(t, digest1, testImageBuildDigest(app.DevNullFilePath))
+	// building twice produces the same bytes
+	image1Data, err := ioutil.ReadFile(image1Path)
+	require.NoError(t, err)
+	image2Data, err := ioutil.ReadFile(image2Path)
+	require.NoError(t, err)
+	assert.Equal(t, image1Data, image2Data)
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "image", "build", "-o", app.DevNullFilePath, "--digest", "--exclude-source-info", "--source", filepath.Join("testdata", "success"))
+	assert.NotEqual(t, digest1, strings.TrimSpace(stdout.String()))
+
+	testRunStdout(t, 1, ``, "image", "build", "-o", "-", "--digest", "--source", filepath.Join("testdata", "success"))
+}
+
+func TestImageBuildParallelism(t *testing.T) {
+	t.Parallel()
+	for _, parallelism := range []string{"1", "2", "16"} {
+		testRunStdout(
+			t,
+			4,
+			`{"file":[{"name":"a.proto","package":"a","messageType":[{"name":"A"}],"syntax":"proto3"}],"bufbuildImageExtension":{}}`,
+			"image",
+			"build",
+			"-o",
+			"-#format=json",
+			"--exclude-source-info",
+			"--partial",
+			"--parallelism",
+			parallelism,
+			"--source",
+			filepath.Join("testdata", "partial"),
+		)
+		testRunStdout(
+			t,
+			4,
+			``,
+			"image",
+			"build",
+			"-o",
+			app.DevNullFilePath,
+			"--parallelism",
+			parallelism,
+			"--source",
+			filepath.Join("testdata", "partial"),
+		)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	t.Parallel()
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`--watch requires the input to be a local directory or image file`,
+		"check",
+		"lint",
+		"--input",
+		"https://example.com/foo.tar.gz",
+		"--watch",
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set --watch-clear without --watch`,
+		"image",
+		"build",
+		"-o",
+		app.DevNullFilePath,
+		"--source",
+		filepath.Join("testdata", "success"),
+		"--watch-clear",
+	)
+}
+
+func TestBetaLsp(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string Bad = 1;\n}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "b.proto"), []byte("syntax = \"proto3\";\n\npackage a;\n\nimport \"a.proto\";\n\nmessage B {\n  A a = 1;\n}\n"), 0644))
+	rootURI := "file://" + filepath.ToSlash(tempDirPath)
+	stdin := bytes.NewBuffer(nil)
+	for _, message := range []string{
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"%s"}}`, rootURI),
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"textDocument/definition","params":{"textDocument":{"uri":"%s/b.proto"},"position":{"line":7,"character":2}}}`, rootURI),
+		`{"jsonrpc":"2.0","id":3,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		_, err := fmt.Fprintf(stdin, "Content-Length: %d\r\n\r\n%s", len(message), message)
+		require.NoError(t, err)
+	}
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, stdin, stdout, "beta", "lsp")
+	output := stdout.String()
+	assert.Contains(t, output, `"serverInfo":{"name":"buf"}`)
+	assert.Contains(
+		t,
+		output,
+		fmt.Sprintf(`"method":"textDocument/publishDiagnostics","params":{"uri":"%s/a.proto","diagnostics":[{"range":{"start":{"line":5,"character":9},"end":{"line":5,"character":12}},"severity":1,"code":"FIELD_LOWER_SNAKE_CASE"`, rootURI),
+	)
+	assert.Contains(
+		t,
+		output,
+		fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"result":[{"uri":"%s/a.proto","range":{"start":{"line":4,"character":8},"end":{"line":4,"character":9}}}]}`, rootURI),
+	)
+	assert.Contains(t, output, `{"jsonrpc":"2.0","id":3,"result":null}`)
+}
+
+func TestImageBuildTypes(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "opts.proto"), []byte(`syntax = "proto3";
+package opts;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions {
+  string tag = 50000;
+}
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.proto"), []byte(`syntax = "proto3";
+package a;
+import "opts.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/timestamp.proto";
+message Request {
+  option (opts.tag) = "request";
+  google.protobuf.Timestamp time = 1;
+}
+message Unrelated {
+  google.protobuf.Duration duration = 1;
+}
+service Service {
+  rpc Get(Request) returns (Request);
+}
+service UnrelatedService {
+  rpc Get(Unrelated) returns (Unrelated);
+}
+`), 0644))
+	outputFilePath := filepath.Join(tempDirPath, "image.bin")
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"build",
+		"--source",
+		tempDirPath,
+		"--type",
+		"a.Service",
+		"-o",
+		outputFilePath,
+	)
+	data, err := ioutil.ReadFile(outputFilePath)
+	require.NoError(t, err)
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(data, fileDescriptorSet))
+	var paths []string
+	for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		paths = append(paths, fileDescriptorProto.GetName())
+		if fileDescriptorProto.GetName() == "a.proto" {
+			assert.Equal(t, []string{"opts.proto", "google/protobuf/timestamp.proto"}, fileDescriptorProto.GetDependency())
+			require.Len(t, fileDescriptorProto.GetMessageType(), 1)
+			assert.Equal(t, "Request", fileDescriptorProto.GetMessageType()[0].GetName())
+			require.Len(t, fileDescriptorProto.GetService(), 1)
+			assert.Equal(t, "Service", fileDescriptorProto.GetService()[0].GetName())
+		}
+	}
+	assert.Equal(
+		t,
+		[]string{
+			"google/protobuf/descriptor.proto",
+			"opts.proto",
+			"google/protobuf/timestamp.proto",
+			"a.proto",
+		},
+		paths,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`a.NotFound is not present in the Image`,
+		"image",
+		"build",
+		"--source",
+		tempDirPath,
+		"--type",
+		"a.NotFound",
+		"-o",
+		app.DevNullFilePath,
+	)
+}
+
+func TestCheckLintExcludePath(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDirPath, "vendor"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nimport \"vendor/v.proto\";\nmessage A { v.V v = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "vendor", "v.proto"), []byte("syntax = \"proto3\";\npackage v;\nmessage V { string Bad = 1; }\n"), 0644))
+	testRunStdout(
+		t,
+		5,
+		filepath.Join(tempDirPath, "vendor", "v.proto")+`:3:20:Field name "Bad" should be lower_snake_case, such as "bad".`,
+		"check",
+		"lint",
+		"--input",
+		tempDirPath,
+	)
+	// the excluded file is still available as an import
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		tempDirPath,
+		"--exclude-path",
+		filepath.Join(tempDirPath, "vendor"),
+	)
+}
+
+func TestCheckBreakingLimitToInputFiles(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	againstDirPath := filepath.Join(tempDirPath, "against")
+	inputDirPath := filepath.Join(tempDirPath, "input")
+	require.NoError(t, os.MkdirAll(againstDirPath, 0755))
+	require.NoError(t, os.MkdirAll(inputDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(againstDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int32 x = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(againstDirPath, "b.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage B {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int64 x = 1; }\n"), 0644))
+	testRunStdout(
+		t,
+		6,
+		`
+		<input>:1:1:Previously present file "b.proto" was deleted.
+		`+filepath.Join(inputDirPath, "a.proto")+`:3:13:Field "1" on message "A" changed type from "int32" to "int64".
+		`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against-input",
+		againstDirPath,
+	)
+	// the files are matched by root relative path even though the inputs
+	// are in different directories
+	testRunStdout(
+		t,
+		6,
+		filepath.Join(inputDirPath, "a.proto")+`:3:13:Field "1" on message "A" changed type from "int32" to "int64".`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against-input",
+		againstDirPath,
+		"--limit-to-input-files",
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		againstDirPath,
+		"--against-input",
+		againstDirPath,
+		"--file",
+		filepath.Join(againstDirPath, "b.proto"),
+	)
+}
+
+func TestCheckBreakingMultipleAgainst(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	v1DirPath := filepath.Join(tempDirPath, "v1")
+	v2DirPath := filepath.Join(tempDirPath, "v2")
+	inputDirPath := filepath.Join(tempDirPath, "input")
+	require.NoError(t, os.MkdirAll(v1DirPath, 0755))
+	require.NoError(t, os.MkdirAll(v2DirPath, 0755))
+	require.NoError(t, os.MkdirAll(inputDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(v1DirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int32 x = 1; int32 y = 2; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(v2DirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int32 x = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int64 x = 1; }\n"), 0644))
+	// with a single against input, the failures are not labeled
+	testRunStdout(
+		t,
+		6,
+		filepath.Join(inputDirPath, "a.proto")+`:3:13:Field "1" on message "A" changed type from "int32" to "int64".`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against",
+		v2DirPath,
+	)
+	testRunStdout(
+		t,
+		6,
+		`
+		`+filepath.Join(inputDirPath, "a.proto")+`:3:1:Previously present field "2" with name "y" on message "A" was deleted. (against `+v1DirPath+`)
+		`+filepath.Join(inputDirPath, "a.proto")+`:3:13:Field "1" on message "A" changed type from "int32" to "int64". (against `+v1DirPath+`, `+v2DirPath+`)
+		`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against",
+		v1DirPath,
+		"--against",
+		v2DirPath,
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"breaking",
+		"--input",
+		v2DirPath,
+		"--against",
+		v2DirPath,
+		"--against",
+		v2DirPath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set both --against and --against-input`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against",
+		v1DirPath,
+		"--against-input",
+		v2DirPath,
+	)
+}
+
+func TestImageMerge(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	aDirPath := filepath.Join(tempDirPath, "a")
+	bDirPath := filepath.Join(tempDirPath, "b")
+	cDirPath := filepath.Join(tempDirPath, "c")
+	require.NoError(t, os.MkdirAll(aDirPath, 0755))
+	require.NoError(t, os.MkdirAll(bDirPath, 0755))
+	require.NoError(t, os.MkdirAll(cDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "common.proto"), []byte("syntax = \"proto3\";\npackage common;\nmessage Common {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(aDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nimport \"common.proto\";\nmessage A { common.Common common = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bDirPath, "common.proto"), []byte("syntax = \"proto3\";\npackage common;\nmessage Common {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bDirPath, "b.proto"), []byte("syntax = \"proto3\";\npackage b;\nimport \"common.proto\";\nmessage B { common.Common common = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cDirPath, "common.proto"), []byte("syntax = \"proto3\";\npackage common;\nmessage Common { int32 x = 1; }\n"), 0644))
+	aImagePath := filepath.Join(tempDirPath, "a.bin")
+	bImagePath := filepath.Join(tempDirPath, "b.bin")
+	cImagePath := filepath.Join(tempDirPath, "c.bin")
+	mergedImagePath := filepath.Join(tempDirPath, "merged.bin")
+	testRunStdout(t, 0, ``, "image", "build", "--source", aDirPath, "-o", aImagePath)
+	testRunStdout(t, 0, ``, "image", "build", "--source", bDirPath, "-o", bImagePath)
+	testRunStdout(t, 0, ``, "image", "build", "--source", cDirPath, "-o", cImagePath)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"image",
+		"merge",
+		aImagePath,
+		bImagePath,
+		"-o",
+		mergedImagePath,
+	)
+	testRunStdout(
+		t,
+		0,
+		`
+		common.proto
+		a.proto
+		b.proto
+		`,
+		"ls-files",
+		"--input",
+		mergedImagePath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`common.proto has conflicting definitions in the images`,
+		"image",
+		"merge",
+		aImagePath,
+		cImagePath,
+		"-o",
+		mergedImagePath,
+	)
+}
+
+func TestImageBuildSourceArchive(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	inputDirPath := filepath.Join(tempDirPath, "input")
+	require.NoError(t, os.MkdirAll(filepath.Join(inputDirPath, "a"), 0755))
+	protoFilePath := filepath.Join(inputDirPath, "a", "a.proto")
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\npackage a;\nimport \"google/protobuf/timestamp.proto\";\nmessage A { google.protobuf.Timestamp t = 1; int32 x = 2; }\n"), 0644))
+	firstArchivePath := filepath.Join(tempDirPath, "first.tgz")
+	secondArchivePath := filepath.Join(tempDirPath, "second.tgz")
+	testRunStdout(t, 0, ``, "image", "build", "--source", inputDirPath, "-o", firstArchivePath)
+	// the archive does not depend on the timestamps or permissions of the files
+	modTime := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(protoFilePath, modTime, modTime))
+	require.NoError(t, os.Chmod(protoFilePath, 0600))
+	testRunStdout(t, 0, ``, "image", "build", "--source", inputDirPath, "-o", secondArchivePath)
+	firstData, err := ioutil.ReadFile(firstArchivePath)
+	require.NoError(t, err)
+	secondData, err := ioutil.ReadFile(secondArchivePath)
+	require.NoError(t, err)
+	assert.Equal(t, firstData, secondData)
+	// the well-known types are not source files and are not written
+	testRunStdout(t, 0, `a/a.proto`, "ls-files", "--input", firstArchivePath)
+	testRunStdout(t, 0, ``, "check", "breaking", "--input", inputDirPath, "--against", firstArchivePath)
+	require.NoError(t, ioutil.WriteFile(protoFilePath, []byte("syntax = \"proto3\";\npackage a;\nimport \"google/protobuf/timestamp.proto\";\nmessage A { google.protobuf.Timestamp t = 1; }\n"), 0644))
+	testRunStdout(
+		t,
+		6,
+		protoFilePath+`:4:1:Previously present field "2" with name "x" on message "A" was deleted.`,
+		"check",
+		"breaking",
+		"--input",
+		inputDirPath,
+		"--against",
+		firstArchivePath,
+	)
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`cannot set --as-file-descriptor-set when --output is a tar archive`,
+		"image",
+		"build",
+		"--source",
+		inputDirPath,
+		"-o",
+		firstArchivePath,
+		"--as-file-descriptor-set",
+	)
+}
+
+func TestCheckAll(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	againstDirPath := filepath.Join(tempDirPath, "against")
+	inputDirPath := filepath.Join(tempDirPath, "input")
+	require.NoError(t, os.MkdirAll(againstDirPath, 0755))
+	require.NoError(t, os.MkdirAll(inputDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(againstDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int32 x = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(againstDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { int64 x = 1; string Bad = 2; }\n"), 0644))
+	// both the lint and the breaking failures are printed
+	testRunStdout(
+		t,
+		6,
+		`
+		`+filepath.Join(inputDirPath, "a.proto")+`:3:33:Field name "Bad" should be lower_snake_case, such as "bad".
+		`+filepath.Join(inputDirPath, "a.proto")+`:3:13:Field "1" on message "A" changed type from "int32" to "int64".
+		`,
+		"check",
+		"all",
+		"--input",
+		inputDirPath,
+		"--against",
+		againstDirPath,
+	)
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"all",
+		"--input",
+		againstDirPath,
+		"--against",
+		againstDirPath,
+	)
+	// build failures are only printed once
+	require.NoError(t, ioutil.WriteFile(filepath.Join(inputDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { foo x = 1; }\n"), 0644))
+	testRunStdout(
+		t,
+		4,
+		filepath.Join(inputDirPath, "a.proto")+`:3:13:field a.A.x: unknown type foo`,
+		"check",
+		"all",
+		"--input",
+		inputDirPath,
+		"--against",
+		againstDirPath,
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"check",
+		"all",
+		"--input",
+		inputDirPath,
+	)
+}
+
+func TestCheckLintPathsFromGitDiff(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "buf.yaml"), []byte("lint:\n  use:\n    - FIELD_LOWER_SNAKE_CASE\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte("syntax = \"proto3\";\npackage a;\nmessage A { string Bad = 1; }\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "b.proto"), []byte("syntax = \"proto3\";\npackage b;\nimport \"a.proto\";\nmessage B { a.A a = 1; }\n"), 0644))
+	testRunGit(t, tempDirPath, "init", "--quiet")
+	testRunGit(t, tempDirPath, "add", ".")
+	testRunGit(t, tempDirPath, "commit", "--quiet", "-m", "first")
+	// nothing differs, so there is nothing to check
+	testRunStdout(
+		t,
+		0,
+		``,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--paths-from-git-diff",
+		"HEAD",
+	)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "b.proto"), []byte("syntax = \"proto3\";\npackage b;\nimport \"a.proto\";\nmessage B { a.A a = 1; string AlsoBad = 2; }\n"), 0644))
+	// a.proto is still built as b.proto imports it, but is not checked
+	testRunStdout(
+		t,
+		5,
+		filepath.Join(protoDirPath, "b.proto")+`:4:31:Field name "AlsoBad" should be lower_snake_case, such as "also_bad".`,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--paths-from-git-diff",
+		"HEAD",
+	)
+	testRunStdout(
+		t,
+		1,
+		``,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--paths-from-git-diff",
+		"HEAD",
+		"--file",
+		filepath.Join(protoDirPath, "b.proto"),
+	)
+	// b.proto is not staged yet
+	testRunStdout(t, 0, ``, "check", "lint", "--input", protoDirPath, "--paths-from-git-staged")
+	testRunGit(t, tempDirPath, "add", ".")
+	testRunStdout(
+		t,
+		5,
+		filepath.Join(protoDirPath, "b.proto")+`:4:31:Field name "AlsoBad" should be lower_snake_case, such as "also_bad".`,
+		"check",
+		"lint",
+		"--input",
+		protoDirPath,
+		"--paths-from-git-staged",
+	)
+}
+
+func TestInstallHooks(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	protoDirPath := filepath.Join(tempDirPath, "proto")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	testRunGit(t, tempDirPath, "init", "--quiet")
+	preCommitFilePath := filepath.Join(tempDirPath, ".git", "hooks", "pre-commit")
+	prePushFilePath := filepath.Join(tempDirPath, ".git", "hooks", "pre-push")
+
+	testRunStdout(t, 0, ``, "install-hooks", "--input", protoDirPath)
+	data, err := ioutil.ReadFile(preCommitFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "buf check lint --input 'proto' --paths-from-git-staged\n")
+	assert.NotContains(t, string(data), "buf check breaking")
+	data, err = ioutil.ReadFile(prePushFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `git archive --format=tar -o "${archive}" "${against}:"'proto'`+"\n"+`buf check breaking --input 'proto' --against "${archive}#format=tar"`)
+	assert.NotContains(t, string(data), "buf check lint")
+	assert.Contains(t, string(data), "@{upstream}")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "buf.yaml"), []byte("hooks:\n  pre_commit: [breaking, lint]\n  pre_push: []\n  against: origin/main\n"), 0644))
+	testRunStdout(t, 0, ``, "install-hooks", "--input", protoDirPath)
+	data, err = ioutil.ReadFile(preCommitFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "buf check lint --input 'proto' --paths-from-git-staged\n\nagainst='origin/main'\narchive=")
+	_, err = os.Stat(prePushFilePath)
+	assert.True(t, os.IsNotExist(err))
+	if runtime.GOOS != "windows" {
+		// the hook fails if the against ref cannot be archived, even if buf
+		// itself would succeed on an empty archive
+		binDirPath := filepath.Join(tempDirPath, "bin")
+		require.NoError(t, os.MkdirAll(binDirPath, 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(binDirPath, "buf"), []byte("#!/bin/sh\ncat >/dev/null\n"), 0755))
+		cmd := exec.Command("sh", preCommitFilePath)
+		cmd.Dir = tempDirPath
+		cmd.Env = append(os.Environ(), "PATH="+binDirPath+string(os.PathListSeparator)+os.Getenv("PATH"))
+		output, err := cmd.CombinedOutput()
+		assert.Error(t, err, string(output))
+		assert.Contains(t, string(output), "origin/main")
+	}
+
+	require.NoError(t, ioutil.WriteFile(preCommitFilePath, []byte("#!/bin/sh\n"), 0755))
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		preCommitFilePath+` already exists and was not installed by buf install-hooks, set --force to overwrite it`,
+		"install-hooks",
+		"--input",
+		protoDirPath,
+	)
+	testRunStdout(t, 0, ``, "install-hooks", "--input", protoDirPath, "--force")
+	data, err = ioutil.ReadFile(preCommitFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "buf check lint")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "buf.yaml"), []byte("hooks:\n  pre_commit: [format]\n"), 0644))
+	testRunStdoutStderr(
+		t,
+		1,
+		``,
+		`hooks.pre_commit must only contain lint or breaking but contained "format"`,
+		"install-hooks",
+		"--input",
+		protoDirPath,
+	)
+}
+
+func TestImageBuildPartial(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		4,
+		`{"file":[{"name":"a.proto","package":"a","messageType":[{"name":"A"}],"syntax":"proto3"}],"bufbuildImageExtension":{}}`,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--exclude-source-info",
+		"--partial",
+		"--source",
+		filepath.Join("testdata", "partial"),
+	)
+	testRunStdout(
+		t,
+		4,
+		``,
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--exclude-source-info",
+		"--source",
+		filepath.Join("testdata", "partial"),
+	)
+}
+
+func TestImageBuildSourceInfoConfig(t *testing.T) {
+	t.Parallel()
+	excludeConfig := `{"source_info":{"image_build":"exclude"}}`
+	testImageBuildSourceInfoEqual(
+		t,
+		[]string{"--exclude-source-info"},
+		[]string{"--source-config", excludeConfig},
+	)
+	testImageBuildSourceInfoEqual(
+		t,
+		[]string{},
+		[]string{"--source-config", excludeConfig, "--include-source-info"},
+	)
+	testRunStdout(
+		t,
+		2,
+		``,
+		"image",
+		"build",
+		"-o",
+		app.DevNullFilePath,
+		"--source",
+		filepath.Join("testdata", "success"),
+		"--source-config",
+		`{"source_info":{"image_build":"foo"}}`,
+	)
+}
+
+func testImageBuildSourceInfoEqual(t *testing.T, expectedArgs []string, actualArgs []string) {
+	baseArgs := []string{
+		"image",
+		"build",
+		"-o",
+		"-#format=json",
+		"--exclude-imports",
+		"--source",
+		filepath.Join("testdata", "success"),
+	}
+	expectedStdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, expectedStdout, append(baseArgs, expectedArgs...)...)
+	actualStdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, actualStdout, append(baseArgs, actualArgs...)...)
+	assert.Equal(t, expectedStdout.String(), actualStdout.String())
+}
+
+func TestCheckLintSARIF(t *testing.T) {
+	t.Parallel()
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		5,
+		nil,
+		stdout,
+		"check",
+		"lint",
+		"--input",
+		filepath.Join("testdata", "fail"),
+		"--error-format",
+		"sarif",
 	)
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID               string `json:"id"`
+						ShortDescription struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+						Help struct {
+							Markdown string `json:"markdown"`
+						} `json:"help"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &sarif))
+	assert.Equal(t, "2.1.0", sarif.Version)
+	require.Len(t, sarif.Runs, 1)
+	run := sarif.Runs[0]
+	assert.Equal(t, "buf", run.Tool.Driver.Name)
+	require.Len(t, run.Results, 2)
+	for i, expected := range []struct {
+		ruleID string
+		line   int
+		column int
+	}{
+		{ruleID: "PACKAGE_DIRECTORY_MATCH", line: 3, column: 1},
+		{ruleID: "FIELD_LOWER_SNAKE_CASE", line: 6, column: 9},
+	} {
+		result := run.Results[i]
+		assert.Equal(t, expected.ruleID, result.RuleID)
+		assert.Equal(t, "error", result.Level)
+		assert.NotEmpty(t, result.Message.Text)
+		require.Len(t, result.Locations, 1)
+		assert.Equal(t, "testdata/fail/buf/buf.proto", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+		assert.Equal(t, expected.line, result.Locations[0].PhysicalLocation.Region.StartLine)
+		assert.Equal(t, expected.column, result.Locations[0].PhysicalLocation.Region.StartColumn)
+		require.True(t, result.RuleIndex < len(run.Tool.Driver.Rules))
+		rule := run.Tool.Driver.Rules[result.RuleIndex]
+		assert.Equal(t, expected.ruleID, rule.ID)
+		assert.NotEmpty(t, rule.ShortDescription.Text)
+		assert.Contains(t, rule.Help.Markdown, "```proto")
+	}
 }
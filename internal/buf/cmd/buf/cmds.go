@@ -17,22 +17,105 @@ package buf
 import (
 	"time"
 
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/cacheclear"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/convert"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/depgraph"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/diff"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/docs"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/export"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/format"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/generate"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/imagemerge"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/installhooks"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/location"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/login"
 	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/lsfiles"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/lsformats"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/lsp"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/lsstats"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/modupdate"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/modvendor"
 	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/protoc"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/push"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/validate"
+	"github.com/bufbuild/buf/internal/buf/cmd/buf/internal/verify"
 	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
 	"github.com/bufbuild/buf/internal/pkg/app/appflag"
 	"github.com/spf13/cobra"
 )
 
+const checkLsCheckersLong = `With --format=json, each checker is printed as a JSON object on its own line, with the keys:
+
+  id           The ID of the checker.
+  categories   The categories of the checker.
+  purpose      The purpose of the checker.
+  default      True if the checker is used when no checkers or categories are configured.
+  fixable      True if buf check lint --fix can fix the failures of the checker.
+  config_keys  The config keys that configure the checker. Omitted if there are none.
+
+With --explain, each checker is instead printed as a paragraph in text format, and
+the following keys are added in JSON format:
+
+  explanation       Why the checker exists.
+  previous_example  Protobuf source that the examples are compared against. Only set
+                    for breaking checkers.
+  failing_example   Protobuf source that the checker fails on.
+  passing_example   Protobuf source that the checker passes on.
+
+Use --category to only list the checkers in the given categories.`
+
+const checkLsRulesLong = `Lists all lint and breaking checkers, regardless of the current configuration.
+
+With --format=json, each checker is printed as a JSON object on its own line, with the keys:
+
+  type             The type of the checker, either lint or breaking.
+  id               The ID of the checker.
+  categories       The categories of the checker.
+  purpose          The purpose of the checker.
+  default          True if the checker is used when no checkers or categories are configured.
+  fixable          True if buf check lint --fix can fix the failures of the checker.
+  config_keys      The config keys that configure the checker. Omitted if there are none.
+  config_versions  The config versions that the checker is available in.
+
+With --explain, each checker is instead printed as a paragraph in text format, and
+the following keys are added in JSON format:
+
+  explanation       Why the checker exists.
+  previous_example  Protobuf source that the examples are compared against. Only set
+                    for breaking checkers.
+  failing_example   Protobuf source that the checker fails on.
+  passing_example   Protobuf source that the checker passes on.
+
+Use --category to only list the checkers in the given categories.`
+
 func newRootCommand(use string, options ...RootCommandOption) *appcmd.Command {
-	builder := appflag.NewBuilder(appflag.BuilderWithTimeout(120 * time.Second))
+	builder := appflag.NewBuilder(
+		appflag.BuilderWithTimeout(120*time.Second),
+		appflag.BuilderWithName(use),
+	)
 	rootCommand := &appcmd.Command{
 		Use: use,
 		SubCommands: []*appcmd.Command{
 			newImageCmd(builder),
 			newCheckCmd(builder),
+			generate.NewCommand("generate", builder),
+			diff.NewCommand("diff", builder),
+			export.NewCommand("export", builder),
+			format.NewCommand("format", builder),
+			installhooks.NewCommand("install-hooks", builder),
+			login.NewCommand("login", builder),
 			lsfiles.NewCommand("ls-files", builder),
+			lsformats.NewCommand("ls-formats", builder),
+			lsstats.NewCommand("ls-stats", builder),
+			convert.NewCommand("convert", builder),
 			protoc.NewCommand("protoc", builder),
+			push.NewCommand("push", builder),
+			verify.NewCommand("verify", builder),
+			newCacheCmd(builder),
+			newBetaCmd(builder),
 			newExperimentalCmd(builder),
 		},
 		BindPersistentFlags: builder.BindRoot,
@@ -44,6 +127,52 @@ func newRootCommand(use string, options ...RootCommandOption) *appcmd.Command {
 	return rootCommand
 }
 
+func newCacheCmd(builder appflag.Builder) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   "cache",
+		Short: "Manage the cache of remote inputs.",
+		SubCommands: []*appcmd.Command{
+			cacheclear.NewCommand("clear", builder),
+		},
+	}
+}
+
+func newBetaCmd(builder appflag.Builder) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   "beta",
+		Short: "Beta commands. Feature complete, but may still change.",
+		SubCommands: []*appcmd.Command{
+			validate.NewCommand("validate", builder),
+			location.NewCommand("location", builder),
+			lsp.NewCommand("lsp", builder),
+			docs.NewCommand("docs", builder),
+			newBetaModCmd(builder),
+			newBetaDepCmd(builder),
+		},
+	}
+}
+
+func newBetaModCmd(builder appflag.Builder) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   "mod",
+		Short: "Manage the dependencies of modules.",
+		SubCommands: []*appcmd.Command{
+			modupdate.NewCommand("update", builder),
+			modvendor.NewCommand("vendor", builder),
+		},
+	}
+}
+
+func newBetaDepCmd(builder appflag.Builder) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   "dep",
+		Short: "Inspect the dependencies of modules.",
+		SubCommands: []*appcmd.Command{
+			depgraph.NewCommand("graph", builder),
+		},
+	}
+}
+
 func newExperimentalCmd(builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   "experimental",
@@ -60,6 +189,7 @@ func newImageCmd(builder appflag.Builder) *appcmd.Command {
 		Short: "Work with Images and FileDescriptorSets.",
 		SubCommands: []*appcmd.Command{
 			newImageBuildCmd(builder),
+			imagemerge.NewCommand("merge", builder),
 		},
 	}
 }
@@ -80,18 +210,40 @@ func newImageBuildCmd(builder appflag.Builder) *appcmd.Command {
 		Use:   "build",
 		Short: "Build all files from the input location and output an Image or FileDescriptorSet.",
 		Args:  cobra.NoArgs,
-		Run:   newRunFunc(builder, flags, imageBuild),
+		Run:   newRunFunc(builder, flags, withErrorExitCode(withDefaultInput(withWatch(imageBuild)))),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindImageBuildInput,
 			flags.bindImageBuildConfig,
+			flags.bindConfigName,
 			flags.bindImageBuildFiles,
+			flags.bindExcludePaths,
+			flags.bindTypes,
 			flags.bindImageBuildOutput,
+			flags.bindYes,
 			flags.bindImageBuildAsFileDescriptorSet,
 			flags.bindImageBuildExcludeImports,
 			flags.bindImageBuildExcludeSourceInfo,
+			flags.bindImageBuildIncludeSourceInfo,
+			flags.bindImageBuildPartial,
+			flags.bindCompactSourceInfo,
+			flags.bindSourceInfoFilter,
+			flags.bindDigest,
 			flags.bindImageBuildErrorFormat,
+			flags.bindJSON,
 			flags.bindExperimentalGitClone,
+			flags.bindAllowInsecureHTTP,
+			flags.bindKeepTemp,
+			flags.bindNoCache,
+			flags.bindOffline,
+			flags.bindTLS,
+			flags.bindNetworkLimits,
+			flags.bindFetchTimeout,
+			flags.bindBuildTimeout,
+			flags.bindParallelism,
+			flags.bindWatch,
+			flags.bindErrorExitCode,
 		),
+		FlagCompletions: newFilesErrorFormatFlagCompletions(bufanalysis.AllFormatStrings),
 	}
 }
 
@@ -100,15 +252,42 @@ func newImageConvertCmd(builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   "convert",
 		Short: "Convert the input Image to an output Image with the specified format and filters.",
-		Args:  cobra.NoArgs,
-		Run:   newRunFunc(builder, flags, imageConvert),
+		Long: `Images are wire-compatible with FileDescriptorSets, so this can also be used to convert
+between Images and FileDescriptorSets in either the binary or JSON format.
+
+To convert an Image to a FileDescriptorSet, use --as-file-descriptor-set.
+
+To convert a FileDescriptorSet, such as one produced by protoc --include_imports, to an Image,
+use the FileDescriptorSet as the input. FileDescriptorSets do not record which files are imports,
+so all files are treated as non-imports unless --file is given, in which case only the given
+files are treated as non-imports, and all of their dependencies are marked as imports.`,
+		Args: cobra.NoArgs,
+		Run:  newRunFunc(builder, flags, imageConvert),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindImageConvertInput,
 			flags.bindImageConvertFiles,
+			flags.bindExcludePaths,
+			flags.bindTypes,
 			flags.bindImageConvertOutput,
+			flags.bindYes,
 			flags.bindImageConvertAsFileDescriptorSet,
 			flags.bindImageConvertExcludeImports,
 			flags.bindImageConvertExcludeSourceInfo,
+			flags.bindImageConvertSourceInfoFrom,
+			flags.bindStrictResolution,
+			flags.bindCompactSourceInfo,
+			flags.bindSourceInfoFilter,
+			flags.bindDigest,
+			flags.bindJSON,
+			flags.bindAllowInsecureHTTP,
+			flags.bindKeepTemp,
+			flags.bindNoCache,
+			flags.bindOffline,
+			flags.bindTLS,
+			flags.bindNetworkLimits,
+			flags.bindFetchTimeout,
+			flags.bindBuildTimeout,
+			flags.bindParallelism,
 		),
 	}
 }
@@ -120,8 +299,11 @@ func newCheckCmd(builder appflag.Builder) *appcmd.Command {
 		SubCommands: []*appcmd.Command{
 			newCheckLintCmd(builder),
 			newCheckBreakingCmd(builder),
+			newCheckAllCmd(builder),
 			newCheckLsLintCheckersCmd(builder),
 			newCheckLsBreakingCheckersCmd(builder),
+			newCheckLsRulesCmd(builder),
+			newCheckExplainCmd(builder),
 		},
 	}
 }
@@ -132,14 +314,36 @@ func newCheckLintCmd(builder appflag.Builder) *appcmd.Command {
 		Use:   "lint",
 		Short: "Check that the input location passes lint checks.",
 		Args:  cobra.NoArgs,
-		Run:   newRunFunc(builder, flags, checkLint),
+		Run:   newRunFunc(builder, flags, withErrorExitCode(withDefaultInput(withWatch(checkLint)))),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindCheckLintInput,
 			flags.bindCheckLintConfig,
+			flags.bindConfigName,
 			flags.bindCheckFiles,
+			flags.bindCheckPathsFromGitDiff,
+			flags.bindExcludePaths,
 			flags.bindCheckLintErrorFormat,
+			flags.bindGroupByFile,
+			flags.bindCheckLintWarningsAsErrors,
+			flags.bindBaseline,
+			flags.bindCheckLintFix,
+			flags.bindCheckLintDryRun,
+			flags.bindAllowPlugins,
 			flags.bindExperimentalGitClone,
+			flags.bindAllowInsecureHTTP,
+			flags.bindKeepTemp,
+			flags.bindNoCache,
+			flags.bindOffline,
+			flags.bindTLS,
+			flags.bindNetworkLimits,
+			flags.bindFetchTimeout,
+			flags.bindBuildTimeout,
+			flags.bindParallelism,
+			flags.bindCheckTimeout,
+			flags.bindWatch,
+			flags.bindErrorExitCode,
 		),
+		FlagCompletions: newFilesErrorFormatFlagCompletions(buflint.AllFormatStrings),
 	}
 }
 
@@ -149,18 +353,79 @@ func newCheckBreakingCmd(builder appflag.Builder) *appcmd.Command {
 		Use:   "breaking",
 		Short: "Check that the input location has no breaking changes compared to the against location.",
 		Args:  cobra.NoArgs,
-		Run:   newRunFunc(builder, flags, checkBreaking),
+		Run:   newRunFunc(builder, flags, withErrorExitCode(withDefaultInput(withWatch(checkBreaking)))),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindCheckBreakingInput,
 			flags.bindCheckBreakingConfig,
-			flags.bindCheckBreakingAgainstInput,
+			flags.bindConfigName,
+			flags.bindCheckBreakingAgainst,
 			flags.bindCheckBreakingAgainstConfig,
 			flags.bindCheckBreakingLimitToInputFiles,
 			flags.bindCheckBreakingExcludeImports,
 			flags.bindCheckFiles,
+			flags.bindCheckPathsFromGitDiff,
+			flags.bindExcludePaths,
 			flags.bindCheckBreakingErrorFormat,
+			flags.bindGroupByFile,
+			flags.bindBaseline,
+			flags.bindStrictResolution,
+			flags.bindAllowPlugins,
 			flags.bindExperimentalGitClone,
+			flags.bindAllowInsecureHTTP,
+			flags.bindKeepTemp,
+			flags.bindNoCache,
+			flags.bindOffline,
+			flags.bindTLS,
+			flags.bindNetworkLimits,
+			flags.bindFetchTimeout,
+			flags.bindBuildTimeout,
+			flags.bindParallelism,
+			flags.bindCheckTimeout,
+			flags.bindWatch,
+			flags.bindErrorExitCode,
 		),
+		FlagCompletions: newFilesErrorFormatFlagCompletions(bufanalysis.AllFormatStrings),
+	}
+}
+
+func newCheckAllCmd(builder appflag.Builder) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   "all",
+		Short: "Run both lint and breaking change checks, building the input once.",
+		Args:  cobra.NoArgs,
+		Run:   newRunFunc(builder, flags, withErrorExitCode(withDefaultInput(withWatch(checkAll)))),
+		BindFlags: appcmd.BindMultiple(
+			flags.bindCheckAllInput,
+			flags.bindCheckAllConfig,
+			flags.bindConfigName,
+			flags.bindCheckBreakingAgainst,
+			flags.bindCheckBreakingAgainstConfig,
+			flags.bindCheckBreakingLimitToInputFiles,
+			flags.bindCheckBreakingExcludeImports,
+			flags.bindCheckFiles,
+			flags.bindCheckPathsFromGitDiff,
+			flags.bindExcludePaths,
+			flags.bindCheckBreakingErrorFormat,
+			flags.bindGroupByFile,
+			flags.bindStrictResolution,
+			flags.bindCheckLintWarningsAsErrors,
+			flags.bindAllowPlugins,
+			flags.bindExperimentalGitClone,
+			flags.bindAllowInsecureHTTP,
+			flags.bindKeepTemp,
+			flags.bindNoCache,
+			flags.bindOffline,
+			flags.bindTLS,
+			flags.bindNetworkLimits,
+			flags.bindFetchTimeout,
+			flags.bindBuildTimeout,
+			flags.bindParallelism,
+			flags.bindCheckTimeout,
+			flags.bindWatch,
+			flags.bindErrorExitCode,
+		),
+		FlagCompletions: newFilesErrorFormatFlagCompletions(bufanalysis.AllFormatStrings),
 	}
 }
 
@@ -169,14 +434,48 @@ func newCheckLsLintCheckersCmd(builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   "ls-lint-checkers",
 		Short: "List lint checkers.",
+		Long:  checkLsCheckersLong,
 		Args:  cobra.NoArgs,
 		Run:   newRunFunc(builder, flags, checkLsLintCheckers),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindCheckLsCheckersConfig,
 			flags.bindCheckLsCheckersAll,
 			flags.bindCheckLsCheckersCategories,
+			flags.bindCheckLsCheckersExplain,
+			flags.bindCheckLsCheckersFormat,
+		),
+		FlagCompletions: newCheckLsCheckersFlagCompletions(buflint.GetAllCategories),
+	}
+}
+
+func newCheckLsRulesCmd(builder appflag.Builder) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   "ls-rules",
+		Short: "List all lint and breaking checkers.",
+		Long:  checkLsRulesLong,
+		Args:  cobra.NoArgs,
+		Run:   newRunFunc(builder, flags, checkLsRules),
+		BindFlags: appcmd.BindMultiple(
+			flags.bindCheckLsCheckersCategories,
+			flags.bindCheckLsCheckersExplain,
 			flags.bindCheckLsCheckersFormat,
 		),
+		FlagCompletions: newCheckLsCheckersFlagCompletions(getAllCheckerCategories),
+	}
+}
+
+func newCheckExplainCmd(builder appflag.Builder) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   "explain <checker-id>",
+		Short: "Explain a lint or breaking checker.",
+		Long: `Prints the purpose and categories of the checker, why the checker exists, and
+examples of Protobuf source that fail and pass the checker. For breaking checkers, the
+examples are compared against a previous example.`,
+		Args:           cobra.ExactArgs(1),
+		Run:            newRunFunc(builder, flags, checkExplain),
+		ArgCompletions: []*appcmd.Completion{appcmd.CompleteValuesFunc(getAllCheckerIDs)},
 	}
 }
 
@@ -185,13 +484,16 @@ func newCheckLsBreakingCheckersCmd(builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   "ls-breaking-checkers",
 		Short: "List breaking checkers.",
+		Long:  checkLsCheckersLong,
 		Args:  cobra.NoArgs,
 		Run:   newRunFunc(builder, flags, checkLsBreakingCheckers),
 		BindFlags: appcmd.BindMultiple(
 			flags.bindCheckLsCheckersConfig,
 			flags.bindCheckLsCheckersAll,
 			flags.bindCheckLsCheckersCategories,
+			flags.bindCheckLsCheckersExplain,
 			flags.bindCheckLsCheckersFormat,
 		),
+		FlagCompletions: newCheckLsCheckersFlagCompletions(bufbreaking.GetAllCategories),
 	}
 }
@@ -17,6 +17,7 @@ package buf
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
@@ -24,6 +25,7 @@ import (
 	"github.com/bufbuild/buf/internal/buf/buffetch"
 	"github.com/bufbuild/buf/internal/buf/cmd/internal"
 	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
 	"github.com/bufbuild/buf/internal/pkg/app/appflag"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
 	"github.com/bufbuild/buf/internal/pkg/stringutil"
@@ -31,43 +33,110 @@ import (
 )
 
 const (
-	imageBuildInputFlagName            = "source"
-	imageBuildConfigFlagName           = "source-config"
-	imageBuildOutputFlagName           = "output"
-	imageConvertInputFlagName          = "image"
-	imageConvertOutputFlagName         = "output"
-	checkLintInputFlagName             = "input"
-	checkLintConfigFlagName            = "input-config"
-	checkBreakingInputFlagName         = "input"
-	checkBreakingConfigFlagName        = "input-config"
-	checkBreakingAgainstInputFlagName  = "against-input"
-	checkBreakingAgainstConfigFlagName = "against-input-config"
-	checkLsCheckersConfigFlagName      = "config"
-	checkLsCheckersFormatFlagName      = "format"
-	lsFilesInputFlagName               = "input"
-	lsFilesConfigFlagName              = "input-config"
-	errorFormatFlagName                = "error-format"
-	experimentalGitCloneFlagName       = "experimental-git-clone"
+	imageBuildInputFlagName                 = "source"
+	imageBuildConfigFlagName                = "source-config"
+	imageBuildOutputFlagName                = "output"
+	imageConvertInputFlagName               = "image"
+	imageConvertOutputFlagName              = "output"
+	digestFlagName                          = "digest"
+	watchFlagName                           = "watch"
+	watchClearFlagName                      = "watch-clear"
+	imageConvertSourceInfoFromFlagName      = "source-info-from"
+	checkLintInputFlagName                  = "input"
+	checkLintConfigFlagName                 = "input-config"
+	checkBreakingInputFlagName              = "input"
+	checkBreakingConfigFlagName             = "input-config"
+	checkAllInputFlagName                   = "input"
+	checkAllConfigFlagName                  = "input-config"
+	checkBreakingAgainstFlagName            = "against"
+	checkBreakingAgainstConfigFlagName      = "against-config"
+	checkBreakingAgainstInputFlagName       = "against-input"
+	checkBreakingAgainstInputConfigFlagName = "against-input-config"
+	checkLsCheckersConfigFlagName           = "config"
+	configOverrideFlagName                  = "config"
+	configNameFlagName                      = "config-name"
+	checkLsCheckersFormatFlagName           = "format"
+	lsFilesInputFlagName                    = "input"
+	lsFilesConfigFlagName                   = "input-config"
+	errorFormatFlagName                     = "error-format"
+	groupByFileFlagName                     = "group-by-file"
+	experimentalGitCloneFlagName            = "experimental-git-clone"
+	pathsFromGitDiffFlagName                = "paths-from-git-diff"
+	pathsFromGitStagedFlagName              = "paths-from-git-staged"
+	checkLintFixFlagName                    = "fix"
+	checkLintDryRunFlagName                 = "dry-run"
+	filesFlagName                           = "file"
+	checkLsCheckersCategoriesFlagName       = "category"
+	errorExitCodeFlagName                   = "error-exit-code"
+	baselineFlagName                        = "baseline"
+	writeBaselineFlagName                   = "write-baseline"
 )
 
+// defaultInputUsage is appended to the usage of the input flags that
+// default to internal.GetDefaultInput.
+const defaultInputUsage = `Defaults to $BUF_INPUT if set, or else to the closest directory that contains a buf.yaml,
+starting at the current directory, or the default_input of this buf.yaml if set.
+Defaults to the current directory if no directory contains a buf.yaml.`
+
 // flags are the flags.
 type flags struct {
-	Config               string
-	AgainstConfig        string
-	Input                string
-	AgainstInput         string
-	ConvertInput         string
-	Output               string
-	AsFileDescriptorSet  bool
-	ExcludeImports       bool
-	ExcludeSourceInfo    bool
-	Files                []string
-	LimitToInputFiles    bool
-	CheckerAll           bool
-	CheckerCategories    []string
-	ErrorFormat          string
-	Format               string
-	ExperimentalGitClone bool
+	Config                            string
+	ConfigOverride                    string
+	ConfigName                        string
+	AgainstConfig                     string
+	AgainstInputConfig                string
+	Input                             string
+	Against                           []string
+	AgainstInput                      string
+	ConvertInput                      string
+	SourceInfoFrom                    string
+	Output                            string
+	AsFileDescriptorSet               bool
+	ExcludeImports                    bool
+	ExcludeSourceInfo                 bool
+	IncludeSourceInfo                 bool
+	CompactSourceInfo                 bool
+	SourceInfoOnlyComments            bool
+	SourceInfoExcludeSpans            bool
+	SourceInfoExcludeDetachedComments bool
+	Digest                            bool
+	Partial                           bool
+	Files                             []string
+	ExcludePaths                      []string
+	PathsFromGitDiff                  string
+	PathsFromGitStaged                bool
+	Types                             []string
+	LimitToInputFiles                 bool
+	CheckerAll                        bool
+	CheckerCategories                 []string
+	CheckerExplain                    bool
+	ErrorFormat                       string
+	GroupByFile                       bool
+	Format                            string
+	ExperimentalGitClone              bool
+	AllowInsecureHTTP                 bool
+	KeepTemp                          bool
+	NoCache                           bool
+	Offline                           bool
+	StrictResolution                  bool
+	AllowPlugins                      bool
+	FetchTimeout                      time.Duration
+	BuildTimeout                      time.Duration
+	Parallelism                       int
+	Watch                             bool
+	WatchClear                        bool
+	ErrorExitCode                     int
+	Baseline                          string
+	WriteBaseline                     string
+	CheckTimeout                      time.Duration
+	MaxConcurrentFetches              int
+	FetchHostRate                     float64
+	TLS                               internal.TLSFlags
+	WarningsAsErrors                  bool
+	Fix                               bool
+	DryRun                            bool
+	Yes                               bool
+	JSON                              internal.JSONFlags
 }
 
 func newFlags() *flags {
@@ -86,20 +155,44 @@ func newRunFunc(
 	)
 }
 
+// newFilesErrorFormatFlagCompletions returns the completions of the files
+// flag, and the error format flag with the given formats.
+func newFilesErrorFormatFlagCompletions(errorFormatStrings []string) map[string]*appcmd.Completion {
+	return map[string]*appcmd.Completion{
+		filesFlagName:       appcmd.CompleteFileExtensions("proto"),
+		errorFormatFlagName: appcmd.CompleteValues(errorFormatStrings...),
+	}
+}
+
+// newCheckLsCheckersFlagCompletions returns the completions of the flags of
+// the commands that list checkers, with the categories returned by getCategories.
+func newCheckLsCheckersFlagCompletions(getCategories func() []string) map[string]*appcmd.Completion {
+	return map[string]*appcmd.Completion{
+		checkLsCheckersCategoriesFlagName: appcmd.CompleteValuesFunc(getCategories),
+		checkLsCheckersFormatFlagName:     appcmd.CompleteValues(bufcheck.AllCheckerFormatStrings...),
+	}
+}
+
 func (f *flags) bindImageBuildInput(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.Input, imageBuildInputFlagName, ".", fmt.Sprintf(`The source to build. Must be one of format %s.`, buffetch.SourceFormatsString))
+	flagSet.StringVar(&f.Input, imageBuildInputFlagName, "", fmt.Sprintf(`The source to build. Must be one of format %s.
+%s`, buffetch.SourceFormatsString, defaultInputUsage))
 }
 
 func (f *flags) bindImageBuildConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, imageBuildConfigFlagName, "", `The config file or data to use.`)
+	f.bindConfigOverride(flagSet, imageBuildConfigFlagName)
 }
 
 func (f *flags) bindImageBuildFiles(flagSet *pflag.FlagSet) {
-	flagSet.StringSliceVar(&f.Files, "file", nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
+	flagSet.StringSliceVar(&f.Files, filesFlagName, nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
 }
 
 func (f *flags) bindImageBuildOutput(flagSet *pflag.FlagSet) {
-	flagSet.StringVarP(&f.Output, imageBuildOutputFlagName, "o", "", fmt.Sprintf(`Required. The location to write the image. Must be one of format %s.`, buffetch.ImageFormatsString))
+	flagSet.StringVarP(&f.Output, imageBuildOutputFlagName, "o", "", fmt.Sprintf(`Required. The location to write the image. Must be one of format %s.
+
+If this is a tar archive such as sources.tgz, the source files of the image are written
+instead, with fixed timestamps and permissions, so the same sources are always written
+as the same bytes. The archive can be used as a later input, such as for --against.`, buffetch.ImageFormatsString))
 }
 
 func (f *flags) bindImageBuildAsFileDescriptorSet(flagSet *pflag.FlagSet) {
@@ -117,14 +210,34 @@ func (f *flags) bindImageBuildExcludeSourceInfo(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.ExcludeSourceInfo, "exclude-source-info", false, "Exclude source info.")
 }
 
+func (f *flags) bindImageBuildIncludeSourceInfo(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.IncludeSourceInfo,
+		"include-source-info",
+		false,
+		`Include source info, even if source_info.image_build is set to exclude in the configuration.`,
+	)
+}
+
+func (f *flags) bindImageBuildPartial(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.Partial,
+		"partial",
+		false,
+		`If some files fail to compile, still output an Image of the files that compile.
+Build errors for the files that fail are printed as usual, and the exit code is still non-zero.`,
+	)
+}
+
 func (f *flags) bindImageBuildErrorFormat(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
-		"text",
+		"",
 		fmt.Sprintf(
-			"The format for build errors, printed to stderr. Must be one of %s.",
+			"The format for build errors, printed to stderr. Must be one of %s. %s",
 			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+			internal.ErrorFormatDefaultUsage,
 		),
 	)
 }
@@ -136,7 +249,7 @@ func (f *flags) bindImageConvertInput(flagSet *pflag.FlagSet) {
 }
 
 func (f *flags) bindImageConvertFiles(flagSet *pflag.FlagSet) {
-	flagSet.StringSliceVar(&f.Files, "file", nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
+	flagSet.StringSliceVar(&f.Files, filesFlagName, nil, `Limit to specific files. The dependencies of these files are included and marked as imports.`)
 }
 
 func (f *flags) bindImageConvertOutput(flagSet *pflag.FlagSet) {
@@ -158,34 +271,145 @@ func (f *flags) bindImageConvertExcludeSourceInfo(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.ExcludeSourceInfo, "exclude-source-info", false, "Exclude source info.")
 }
 
+func (f *flags) bindImageConvertSourceInfoFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.SourceInfoFrom,
+		imageConvertSourceInfoFromFlagName,
+		"",
+		fmt.Sprintf(
+			`Reattach source info from the given source, for example to an image built with --exclude-source-info. Must be one of format %s.
+
+The source is built, and each file in the image is given the source info of the file with the same path in the source.
+The files must otherwise be the same, ignoring source info.`,
+			buffetch.SourceFormatsString,
+		),
+	)
+}
+
+func (f *flags) bindCompactSourceInfo(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.CompactSourceInfo,
+		"compact-source-info",
+		false,
+		`Store source info in a single compressed field of the image instead of on each file.
+
+Consumers that do not need source info, such as most plugins, do not have to read it.
+Buf restores the source info when reading the image. Cannot be used with --as-file-descriptor-set.`,
+	)
+}
+
+func (f *flags) bindSourceInfoFilter(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.SourceInfoOnlyComments,
+		"source-info-only-comments",
+		false,
+		`Only keep the source info locations that have comments.`,
+	)
+	flagSet.BoolVar(
+		&f.SourceInfoExcludeSpans,
+		"source-info-exclude-spans",
+		false,
+		`Exclude the spans of source info locations. Consumers will not have line and column information.`,
+	)
+	flagSet.BoolVar(
+		&f.SourceInfoExcludeDetachedComments,
+		"source-info-exclude-detached-comments",
+		false,
+		`Exclude detached comments from source info.`,
+	)
+}
+
+func (f *flags) bindDigest(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Digest, digestFlagName, false, `Print the digest of the written image to stdout.
+
+The digest is of the form sha256:HEX, and does not depend on the output format or file
+ordering, so it only changes if the written image changes. Cannot be used when writing to stdout.`)
+}
+
+func (f *flags) bindExcludePaths(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.ExcludePaths, "exclude-path", nil, `Exclude the files equal to or contained within these file or directory paths, for example generated or vendored directories.
+Excluded files can still be imported by the remaining files. This is applied after --file.`)
+}
+
+func (f *flags) bindTypes(flagSet *pflag.FlagSet) {
+	flagSet.StringSliceVar(&f.Types, "type", nil, `Limit to the given fully-qualified messages, enums, and services, for example foo.bar.Baz.
+The types they transitively reference are included, and files without any of these types are removed.`)
+}
+
+func (f *flags) bindJSON(flagSet *pflag.FlagSet) {
+	internal.BindJSON(flagSet, &f.JSON)
+}
+
 func (f *flags) bindCheckLintInput(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.Input, checkLintInputFlagName, ".", fmt.Sprintf(`The source or image to lint. Must be one of format %s.`, buffetch.AllFormatsString))
+	flagSet.StringVar(&f.Input, checkLintInputFlagName, "", fmt.Sprintf(`The source or image to lint. Must be one of format %s.
+%s`, buffetch.AllFormatsString, defaultInputUsage))
 }
 
 func (f *flags) bindCheckLintConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, checkLintConfigFlagName, "", `The config file or data to use.`)
+	f.bindConfigOverride(flagSet, checkLintConfigFlagName)
 }
 
 func (f *flags) bindCheckBreakingInput(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.Input, checkBreakingInputFlagName, ".", fmt.Sprintf(`The source or image to check for breaking changes. Must be one of format %s.`, buffetch.AllFormatsString))
+	flagSet.StringVar(&f.Input, checkBreakingInputFlagName, "", fmt.Sprintf(`The source or image to check for breaking changes. Must be one of format %s.
+%s`, buffetch.AllFormatsString, defaultInputUsage))
 }
 
 func (f *flags) bindCheckBreakingConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, checkBreakingConfigFlagName, "", `The config file or data to use.`)
+	f.bindConfigOverride(flagSet, checkBreakingConfigFlagName)
+}
+
+func (f *flags) bindCheckAllInput(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Input, checkAllInputFlagName, "", fmt.Sprintf(`The source or image to lint and check for breaking changes. Must be one of format %s.
+%s`, buffetch.AllFormatsString, defaultInputUsage))
+}
+
+func (f *flags) bindCheckAllConfig(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Config, checkAllConfigFlagName, "", `The config file or data to use.`)
+	f.bindConfigOverride(flagSet, checkAllConfigFlagName)
 }
 
-func (f *flags) bindCheckBreakingAgainstInput(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.AgainstInput, checkBreakingAgainstInputFlagName, "", fmt.Sprintf(`Required. The source or image to check against. Must be one of format %s.`, buffetch.AllFormatsString))
+// bindConfigOverride binds --config, which is the same as the given config
+// flag, so that the config can be set with the same flag across commands.
+func (f *flags) bindConfigOverride(flagSet *pflag.FlagSet, configFlagName string) {
+	flagSet.StringVar(&f.ConfigOverride, configOverrideFlagName, "", fmt.Sprintf(`The same as --%s.
+This is either the path to a .json, .yaml, or .yml file, which may be outside of the input,
+or inline JSON or YAML data. The buf.yaml of the input is not read if this is set.`, configFlagName))
+}
+
+func (f *flags) bindConfigName(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.ConfigName, configNameFlagName, "", `The name of the config to use from the configs section of the config, instead of the top-level config.
+Each named config has the build, lint, and breaking sections of the top-level config that it does not set.
+For breaking change detection, this is also used for the config of the against input.`)
+}
+
+func (f *flags) bindCheckBreakingAgainst(flagSet *pflag.FlagSet) {
+	flagSet.StringArrayVar(&f.Against, checkBreakingAgainstFlagName, nil, fmt.Sprintf(`Required. The source or image to check against. Must be one of format %s.
+
+Use .git#branch=main to check against the main branch of the git repository that
+contains the current directory. This works from any directory within the repository.
+
+May be given multiple times to check against multiple inputs, for example the last
+few releases. Each failure is labeled with the inputs that it fails against.`, buffetch.AllFormatsString))
+	// --against-input is the original name of --against
+	flagSet.StringVar(&f.AgainstInput, checkBreakingAgainstInputFlagName, "", fmt.Sprintf(`The same as --%s.`, checkBreakingAgainstFlagName))
+	_ = flagSet.MarkHidden(checkBreakingAgainstInputFlagName)
 }
 
 func (f *flags) bindCheckBreakingAgainstConfig(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.AgainstConfig, checkBreakingAgainstConfigFlagName, "", `The config file or data to use for the against source or image.`)
+	flagSet.StringVar(&f.AgainstConfig, checkBreakingAgainstConfigFlagName, "", `The config file or data to use to build the against source.
+If not set, the config of the against source is used, not the config of the input.`)
+	// --against-input-config is the original name of --against-config
+	flagSet.StringVar(&f.AgainstInputConfig, checkBreakingAgainstInputConfigFlagName, "", fmt.Sprintf(`The same as --%s.`, checkBreakingAgainstConfigFlagName))
+	_ = flagSet.MarkHidden(checkBreakingAgainstInputConfigFlagName)
 }
 
 func (f *flags) bindCheckBreakingLimitToInputFiles(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.LimitToInputFiles, "limit-to-input-files", false, `Only run breaking checks against the files in the input.
-This has the effect of filtering the against input to only contain the files in the input.
-Overrides --file.`)
+This has the effect of filtering the against input to only contain the files in the input,
+matched by their paths relative to the roots, so that files deleted or moved out of the
+input are not reported.`)
 }
 
 func (f *flags) bindCheckBreakingExcludeImports(flagSet *pflag.FlagSet) {
@@ -193,17 +417,26 @@ func (f *flags) bindCheckBreakingExcludeImports(flagSet *pflag.FlagSet) {
 }
 
 func (f *flags) bindCheckFiles(flagSet *pflag.FlagSet) {
-	flagSet.StringSliceVar(&f.Files, "file", nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
+	flagSet.StringSliceVar(&f.Files, filesFlagName, nil, `Limit to specific files. This is an advanced feature and is not recommended.`)
+}
+
+func (f *flags) bindCheckPathsFromGitDiff(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.PathsFromGitDiff, pathsFromGitDiffFlagName, "", `Limit to the .proto files that differ between this git ref and the working tree, for example origin/main.
+All files are still built so that imports resolve. The input must be a local directory within a git repository.
+If no .proto files differ, there is nothing to check. Cannot be used with --file.`)
+	flagSet.BoolVar(&f.PathsFromGitStaged, pathsFromGitStagedFlagName, false, fmt.Sprintf(`Limit to the .proto files that are staged to be committed, as with --%s.
+Cannot be used with --file or --%s.`, pathsFromGitDiffFlagName, pathsFromGitDiffFlagName))
 }
 
 func (f *flags) bindCheckBreakingErrorFormat(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
-		"text",
+		"",
 		fmt.Sprintf(
-			"The format for build errors or check violations, printed to stdout. Must be one of %s.",
+			"The format for build errors or check violations, printed to stdout. Must be one of %s. %s",
 			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+			internal.ErrorFormatDefaultUsage,
 		),
 	)
 }
@@ -212,14 +445,43 @@ func (f *flags) bindCheckLintErrorFormat(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
-		"text",
+		"",
 		fmt.Sprintf(
-			"The format for build errors or check violations, printed to stdout. Must be one of %s.",
+			"The format for build errors or check violations, printed to stdout. Must be one of %s. %s",
 			stringutil.SliceToString(buflint.AllFormatStrings),
+			internal.ErrorFormatDefaultUsage,
 		),
 	)
 }
 
+func (f *flags) bindGroupByFile(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.GroupByFile, groupByFileFlagName, false, fmt.Sprintf(`Group the failures of each file into a single review comment with the number of failures, and add the number of failures of each file to the summary.
+Only supported if --%s is github-review or gitlab-review.`, errorFormatFlagName))
+}
+
+func (f *flags) bindCheckLintWarningsAsErrors(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.WarningsAsErrors, "warnings-as-errors", false, `Treat the failures of the checkers configured as warnings in lint.warn as errors.
+By default, warnings are printed to stderr and do not fail the check.`)
+}
+
+func (f *flags) bindBaseline(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.Baseline, baselineFlagName, "", fmt.Sprintf(`The baseline file written by --%s. Failures recorded in the baseline are not printed and do not fail the check.
+Failures match on their checker, file path, and message, but not their line, so they still match if the file is edited elsewhere.`, writeBaselineFlagName))
+	flagSet.StringVar(&f.WriteBaseline, writeBaselineFlagName, "", fmt.Sprintf(`Write the current failures to this baseline file instead of printing them, to be used with --%s in later runs.
+This allows adopting checks on existing files, while new failures still fail the check.`, baselineFlagName))
+}
+
+func (f *flags) bindCheckLintFix(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Fix, checkLintFixFlagName, false, `Fix the failures of the checkers that can be fixed mechanically, by editing the .proto files.
+The checkers that can be fixed are ENUM_VALUE_PREFIX, ENUM_VALUE_UPPER_SNAKE_CASE, ENUM_ZERO_VALUE_SUFFIX,
+FIELD_LOWER_SNAKE_CASE, ONEOF_LOWER_SNAKE_CASE, and the PACKAGE_SAME checkers for file options, if most files
+in the package have the same value. The remaining failures are printed. The input must be a local directory.`)
+}
+
+func (f *flags) bindCheckLintDryRun(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.DryRun, checkLintDryRunFlagName, false, `With --fix, print the fixes as a diff instead of editing the .proto files.`)
+}
+
 func (f *flags) bindCheckLsCheckersConfig(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.Config, checkLsCheckersConfigFlagName, "", `The config file or data to use. If --all is specified, this is ignored.`)
 }
@@ -229,7 +491,11 @@ func (f *flags) bindCheckLsCheckersAll(flagSet *pflag.FlagSet) {
 }
 
 func (f *flags) bindCheckLsCheckersCategories(flagSet *pflag.FlagSet) {
-	flagSet.StringSliceVar(&f.CheckerCategories, "category", nil, "Only list the checkers in these categories.")
+	flagSet.StringSliceVar(&f.CheckerCategories, checkLsCheckersCategoriesFlagName, nil, "Only list the checkers in these categories.")
+}
+
+func (f *flags) bindCheckLsCheckersExplain(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.CheckerExplain, "explain", false, "Also print the explanation and examples of each checker.")
 }
 
 func (f *flags) bindCheckLsCheckersFormat(flagSet *pflag.FlagSet) {
@@ -247,3 +513,83 @@ func (f *flags) bindCheckLsCheckersFormat(flagSet *pflag.FlagSet) {
 func (f *flags) bindExperimentalGitClone(flagSet *pflag.FlagSet) {
 	internal.BindExperimentalGitClone(flagSet, &f.ExperimentalGitClone)
 }
+
+func (f *flags) bindAllowInsecureHTTP(flagSet *pflag.FlagSet) {
+	internal.BindAllowInsecureHTTP(flagSet, &f.AllowInsecureHTTP)
+}
+
+func (f *flags) bindKeepTemp(flagSet *pflag.FlagSet) {
+	internal.BindKeepTemp(flagSet, &f.KeepTemp)
+}
+
+func (f *flags) bindNoCache(flagSet *pflag.FlagSet) {
+	internal.BindNoCache(flagSet, &f.NoCache)
+}
+
+func (f *flags) bindOffline(flagSet *pflag.FlagSet) {
+	internal.BindOffline(flagSet, &f.Offline)
+}
+
+func (f *flags) bindStrictResolution(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.StrictResolution, "strict-resolution", false, `Fail if an image input references imports or types that are not contained in the image, and list them.
+By default, such references are allowed, and custom options that depend on them are left unresolved.
+This has no effect for sources, as they are always built with all of their imports.`)
+}
+
+func (f *flags) bindAllowPlugins(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.AllowPlugins, "allow-plugins", false, `Run the lint and breaking plugins configured in the buf.yaml of the input if it is a local directory.
+Plugins run arbitrary commands, so by default only the plugins configured with --input-config are run.
+The plugins configured in the buf.yaml of other inputs, such as archives or git repositories, are never run.`)
+}
+
+func (f *flags) bindFetchTimeout(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.FetchTimeout, "fetch-timeout", 0, `The duration until timing out fetching inputs. If 0, only --timeout applies.`)
+}
+
+func (f *flags) bindBuildTimeout(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.BuildTimeout, "build-timeout", 0, `The duration until timing out building sources. If 0, only --timeout applies.`)
+}
+
+func (f *flags) bindParallelism(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.Parallelism, "parallelism", 0, `The maximum number of workers to compile sources with. If 0, defaults to GOMAXPROCS.`)
+}
+
+func (f *flags) bindWatch(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.Watch, watchFlagName, false, `Run again each time a .proto file or configuration file of the input changes, until interrupted.
+
+The input must be a local directory or image file. Hidden directories are not watched,
+and --timeout applies to each run.`)
+	flagSet.BoolVar(&f.WatchClear, watchClearFlagName, false, fmt.Sprintf(`Clear the screen before each run with --%s.`, watchFlagName))
+}
+
+func (f *flags) bindErrorExitCode(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.ErrorExitCode, errorExitCodeFlagName, 0, fmt.Sprintf(`The exit code to use for all errors instead of the default exit codes, which are:
+%d for configuration errors, %d for errors fetching the input or its dependencies, %d for compile errors,
+%d for lint failures, %d for breaking change failures, and %d for all other errors.
+If there are both lint and breaking change failures, the exit code is the one for breaking change failures.
+If 0, the default exit codes are used.`,
+		configErrorExitCode,
+		fetchErrorExitCode,
+		compileErrorExitCode,
+		lintFailureExitCode,
+		breakingFailureExitCode,
+		defaultErrorExitCode,
+	))
+}
+
+func (f *flags) bindCheckTimeout(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.CheckTimeout, "check-timeout", 0, `The duration until timing out running checks. If 0, only --timeout applies.`)
+}
+
+func (f *flags) bindNetworkLimits(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.MaxConcurrentFetches, "max-concurrent-fetches", 0, `The maximum number of remote fetches to run at once. If 0, this is not limited.`)
+	flagSet.Float64Var(&f.FetchHostRate, "fetch-host-rate", 0, `The maximum number of remote fetches to start per second for each host. If 0, this is not limited.`)
+}
+
+func (f *flags) bindTLS(flagSet *pflag.FlagSet) {
+	internal.BindTLS(flagSet, &f.TLS)
+}
+
+func (f *flags) bindYes(flagSet *pflag.FlagSet) {
+	internal.BindYes(flagSet, &f.Yes)
+}
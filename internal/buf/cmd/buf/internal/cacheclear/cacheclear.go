@@ -0,0 +1,86 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheclear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Clear the cache of remote inputs and built files.",
+		Long: `Files read over https, git clones of remote inputs, and built files are cached within
+the cache directory of the user, which is $XDG_CACHE_HOME/buf, or ~/.cache/buf if XDG_CACHE_HOME
+is not set, and %LocalAppData%\buf on Windows. Use --no-cache to not use the cache for a
+single command.
+
+If stdin is a terminal, this prompts for confirmation before clearing the cache, unless --yes is given.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	yes bool
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	internal.BindYes(flagSet, &c.yes)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	if !c.yes {
+		cacheDirPath, err := app.CacheDirPath(container)
+		if err != nil {
+			return err
+		}
+		confirmed, err := app.Confirm(container, fmt.Sprintf("Clear the cache in %s?", cacheDirPath))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("not clearing the cache")
+		}
+	}
+	return multierr.Append(
+		buffetch.ClearCache(container),
+		bufwire.ClearBuildCache(container),
+	)
+}
@@ -0,0 +1,403 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	inputFlagName  = "input"
+	configFlagName = "input-config"
+	typeFlagName   = "type"
+	fromFlagName   = "from"
+	toFlagName     = "to"
+
+	formatBin       = "bin"
+	formatJSON      = "json"
+	formatText      = "text"
+	formatJSONL     = "jsonl"
+	formatDelimited = "delimited"
+)
+
+var allFormatsString = strings.Join([]string{formatBin, formatJSON, formatText, formatJSONL, formatDelimited}, ",")
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Convert message payloads between the binary, JSON, and text formats.",
+		Long: `The message type is resolved from the given input, which can be a source or an image.
+
+The locations given to --from and --to are paths, or "-" for stdin and stdout.
+The format of each location can be set with a "#format=" suffix, for example
+"-#format=json". If not set, this is inferred from the file extension, where
+.json is JSON, .jsonl is JSON lines, .txt and .txtpb are text, and everything else is binary.
+
+The jsonl and delimited formats are streams of messages, and are converted one message
+at a time, so payloads of any size can be converted. jsonl is one JSON message per line,
+and delimited is binary messages each prefixed with their varint length, as written by
+writeDelimitedTo in the Java and C++ protobuf libraries. The other formats contain a single
+message.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	typeName             string
+	from                 string
+	to                   string
+	jsonFlags            internal.JSONFlags
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image that contains the message type. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.typeName,
+		typeFlagName,
+		"",
+		`Required. The fully-qualified name of the message of the payload, for example acme.v1.Order.`,
+	)
+	flagSet.StringVar(
+		&c.from,
+		fromFlagName,
+		"-",
+		fmt.Sprintf(
+			`The location to read the payload from. The format can be set with a "#format=" suffix, and must be one of %s.`,
+			allFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.to,
+		toFlagName,
+		"-",
+		fmt.Sprintf(
+			`The location to write the payload to. The format can be set with a "#format=" suffix, and must be one of %s.`,
+			allFormatsString,
+		),
+	)
+	internal.BindJSON(flagSet, &c.jsonFlags)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
+	if c.typeName == "" {
+		return fmt.Errorf("--%s is required", typeFlagName)
+	}
+	fromPath, fromFormat, err := parseLocation(fromFlagName, c.from)
+	if err != nil {
+		return err
+	}
+	toPath, toFormat, err := parseLocation(toFlagName, c.to)
+	if err != nil {
+		return err
+	}
+	jsonUnmarshalerOptions, err := internal.NewJSONUnmarshalerOptions(c.jsonFlags)
+	if err != nil {
+		return err
+	}
+	jsonMarshalerOptions, err := internal.NewJSONMarshalerOptions(c.jsonFlags)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		true, // no need for source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			"text",
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	resolver, err := protoencoding.NewResolver(
+		bufcore.ImageToFileDescriptorProtos(
+			env.Image(),
+		)...,
+	)
+	if err != nil {
+		return err
+	}
+	if resolver == nil {
+		return fmt.Errorf("--%s: %q not found in input", typeFlagName, c.typeName)
+	}
+	messageType, err := resolver.FindMessageByName(protoreflect.FullName(c.typeName))
+	if err != nil {
+		return fmt.Errorf("--%s: %q not found in input", typeFlagName, c.typeName)
+	}
+	readCloser, err := internal.OpenPayload(container, fromPath, fromFormat)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	writeCloser, err := createPayload(container, toPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeCloser.Close())
+	}()
+	messageReader := newMessageReader(readCloser, resolver, fromFormat, jsonUnmarshalerOptions)
+	messageWriter := newMessageWriter(writeCloser, resolver, toFormat, jsonMarshalerOptions)
+	for i := 0; ; i++ {
+		message := dynamicpb.NewMessage(messageType.Descriptor())
+		if err := messageReader.Read(message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("--%s: could not unmarshal message %d as %s: %v", fromFlagName, i, c.typeName, err)
+		}
+		if err := messageWriter.Write(message); err != nil {
+			return err
+		}
+	}
+}
+
+// parseLocation parses a location of the form path#format=value.
+func parseLocation(flagName string, value string) (string, string, error) {
+	path, options := value, ""
+	if i := strings.IndexByte(value, '#'); i >= 0 {
+		path, options = value[:i], value[i+1:]
+	}
+	if path == "" {
+		return "", "", fmt.Errorf("--%s: path is required", flagName)
+	}
+	var format string
+	if options != "" {
+		for _, option := range strings.Split(options, ",") {
+			split := strings.Split(option, "=")
+			if len(split) != 2 || split[0] != "format" {
+				return "", "", fmt.Errorf("--%s: invalid option: %q", flagName, option)
+			}
+			format = split[1]
+		}
+	}
+	switch format {
+	case formatBin, formatJSON, formatText, formatJSONL, formatDelimited:
+		return path, format, nil
+	case "":
+		switch filepath.Ext(path) {
+		case ".json":
+			return path, formatJSON, nil
+		case ".jsonl":
+			return path, formatJSONL, nil
+		case ".txt", ".txtpb":
+			return path, formatText, nil
+		default:
+			return path, formatBin, nil
+		}
+	default:
+		return "", "", fmt.Errorf("--%s: unknown format: %q, must be one of %s", flagName, format, allFormatsString)
+	}
+}
+
+func createPayload(container app.StdoutContainer, path string) (io.WriteCloser, error) {
+	if path == "-" || app.IsDevStdout(path) {
+		return ioutilextended.NopWriteCloser(container.Stdout()), nil
+	}
+	return os.Create(path)
+}
+
+func newMessageReader(
+	reader io.Reader,
+	resolver protoencoding.Resolver,
+	format string,
+	jsonUnmarshalerOptions []protoencoding.JSONUnmarshalerOption,
+) protoencoding.MessageReader {
+	switch format {
+	case formatJSONL:
+		return protoencoding.NewJSONLinesMessageReader(reader, resolver, jsonUnmarshalerOptions...)
+	case formatDelimited:
+		return protoencoding.NewLengthPrefixedMessageReader(reader, resolver)
+	case formatJSON:
+		return newSingleMessageReader(reader, protoencoding.NewJSONUnmarshaler(resolver, jsonUnmarshalerOptions...))
+	case formatText:
+		return newSingleMessageReader(reader, protoencoding.NewTextUnmarshaler(resolver))
+	default:
+		return newSingleMessageReader(reader, protoencoding.NewWireUnmarshaler(resolver))
+	}
+}
+
+func newMessageWriter(
+	writer io.Writer,
+	resolver protoencoding.Resolver,
+	format string,
+	jsonMarshalerOptions []protoencoding.JSONMarshalerOption,
+) protoencoding.MessageWriter {
+	switch format {
+	case formatJSONL:
+		return protoencoding.NewJSONLinesMessageWriter(writer, resolver, jsonMarshalerOptions...)
+	case formatDelimited:
+		return protoencoding.NewLengthPrefixedMessageWriter(writer)
+	case formatJSON:
+		return newSingleMessageWriter(writer, format, protoencoding.NewJSONMarshaler(resolver, jsonMarshalerOptions...))
+	case formatText:
+		return newSingleMessageWriter(writer, format, protoencoding.NewTextMarshaler(resolver))
+	default:
+		return newSingleMessageWriter(writer, format, protoencoding.NewWireMarshaler())
+	}
+}
+
+// singleMessageReader is a MessageReader for formats that contain a single message.
+type singleMessageReader struct {
+	reader      io.Reader
+	unmarshaler protoencoding.Unmarshaler
+	read        bool
+}
+
+func newSingleMessageReader(reader io.Reader, unmarshaler protoencoding.Unmarshaler) *singleMessageReader {
+	return &singleMessageReader{
+		reader:      reader,
+		unmarshaler: unmarshaler,
+	}
+}
+
+func (r *singleMessageReader) Read(message proto.Message) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	data, err := ioutil.ReadAll(r.reader)
+	if err != nil {
+		return err
+	}
+	return r.unmarshaler.Unmarshal(data, message)
+}
+
+// singleMessageWriter is a MessageWriter for formats that contain a single message.
+type singleMessageWriter struct {
+	writer    io.Writer
+	format    string
+	marshaler protoencoding.Marshaler
+	written   bool
+}
+
+func newSingleMessageWriter(writer io.Writer, format string, marshaler protoencoding.Marshaler) *singleMessageWriter {
+	return &singleMessageWriter{
+		writer:    writer,
+		format:    format,
+		marshaler: marshaler,
+	}
+}
+
+func (w *singleMessageWriter) Write(message proto.Message) error {
+	if w.written {
+		return fmt.Errorf(
+			"--%s: %s can only contain a single message, use %s or %s to write multiple messages",
+			toFlagName,
+			w.format,
+			formatJSONL,
+			formatDelimited,
+		)
+	}
+	w.written = true
+	data, err := w.marshaler.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(data)
+	return err
+}
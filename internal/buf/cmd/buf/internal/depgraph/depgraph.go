@@ -0,0 +1,305 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	dirFlagName    = "dir"
+	formatFlagName = "format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Print the dependency graph of the module.",
+		Long: `Each dependency in buf.yaml is fetched, along with the dependencies declared in its
+own buf.yaml, and each edge of the graph is printed with the digest that the buf.lock
+file of the depending module pins the dependency to. The dependencies in buf.lock are
+verified against their digests.
+
+Builds use the dependencies of dependencies as well, so these must agree with the
+dependencies of the module itself. The graph shows whether they do: if a module is
+depended on at more than one version, such as at different git refs or OCI
+tags, or at different digests, each conflict is printed to stderr and the command fails.
+
+With --format=text, each edge is printed on its own line, with the module, the dependency,
+and the digest. The module in --dir is printed as its directory, and dependencies that
+are not pinned have a digest of -.
+
+With --format=json, each edge is printed as a JSON object on its own line, with the keys:
+
+  module      The remote of the module. Omitted for the module in --dir.
+  dependency  The remote of the dependency.
+  digest      The digest of the dependency. Omitted if the dependency is not pinned.
+  conflict    True if the dependency conflicts with another version of it.
+
+With --format=dot, the graph is printed in the DOT language of Graphviz, with the
+edges to conflicting dependencies in red. For example:
+
+  buf beta dep graph --format=dot | dot -Tsvg > deps.svg`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	dir               string
+	format            string
+	allowInsecureHTTP bool
+	keepTemp          bool
+	noCache           bool
+	tlsFlags          internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.dir,
+		dirFlagName,
+		".",
+		`The directory of the module, containing the buf.yaml file.`,
+	)
+	flagSet.StringVar(
+		&c.format,
+		formatFlagName,
+		"text",
+		`The format to print the graph as. Must be one of [text,json,dot].`,
+	)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	printGraph, err := getPrintGraphFunc(c.format)
+	if err != nil {
+		return err
+	}
+	readBucket, err := storageos.NewReadWriteBucket(c.dir)
+	if err != nil {
+		return err
+	}
+	config, err := bufconfig.NewProvider(container.Logger()).GetConfig(ctx, readBucket)
+	if err != nil {
+		return err
+	}
+	lock, err := bufmod.GetLockForBucket(ctx, readBucket)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	graph, err := internal.NewBufwireDependencyResolver(
+		container.Logger(),
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			TLSConfig:         tlsConfig,
+		},
+	).ResolveGraph(
+		ctx,
+		container,
+		config,
+		lock,
+	)
+	if err != nil {
+		return err
+	}
+	conflicts := bufmod.GetGraphConflicts(graph)
+	conflictEdges := make(map[*bufmod.GraphEdge]struct{})
+	for _, conflict := range conflicts {
+		for _, edge := range conflict.Edges {
+			conflictEdges[edge] = struct{}{}
+		}
+	}
+	if err := printGraph(container.Stdout(), c.dir, graph, conflictEdges); err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if err := printConflicts(container.Stderr(), c.dir, conflicts); err != nil {
+		return err
+	}
+	return errors.New("")
+}
+
+type printGraphFunc func(
+	writer io.Writer,
+	rootName string,
+	graph *bufmod.Graph,
+	conflictEdges map[*bufmod.GraphEdge]struct{},
+) error
+
+func getPrintGraphFunc(format string) (printGraphFunc, error) {
+	switch s := strings.ToLower(strings.TrimSpace(format)); s {
+	case "", "text":
+		return printGraphText, nil
+	case "json":
+		return printGraphJSON, nil
+	case "dot":
+		return printGraphDOT, nil
+	default:
+		return nil, fmt.Errorf("--%s: unknown format: %q", formatFlagName, s)
+	}
+}
+
+func printGraphText(
+	writer io.Writer,
+	rootName string,
+	graph *bufmod.Graph,
+	_ map[*bufmod.GraphEdge]struct{},
+) (retErr error) {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, "MODULE\tDEPENDENCY\tDIGEST"); err != nil {
+		return err
+	}
+	for _, edge := range graph.Edges {
+		digest := edge.Digest
+		if digest == "" {
+			digest = "-"
+		}
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", getModuleName(rootName, edge), edge.To, digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type edgeJSON struct {
+	Module     string `json:"module,omitempty"`
+	Dependency string `json:"dependency,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+	Conflict   bool   `json:"conflict,omitempty"`
+}
+
+func printGraphJSON(
+	writer io.Writer,
+	_ string,
+	graph *bufmod.Graph,
+	conflictEdges map[*bufmod.GraphEdge]struct{},
+) error {
+	for _, edge := range graph.Edges {
+		_, conflict := conflictEdges[edge]
+		data, err := json.Marshal(
+			&edgeJSON{
+				Module:     edge.From,
+				Dependency: edge.To,
+				Digest:     edge.Digest,
+				Conflict:   conflict,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(writer, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printGraphDOT(
+	writer io.Writer,
+	rootName string,
+	graph *bufmod.Graph,
+	conflictEdges map[*bufmod.GraphEdge]struct{},
+) error {
+	if _, err := fmt.Fprintln(writer, "digraph deps {"); err != nil {
+		return err
+	}
+	for _, edge := range graph.Edges {
+		var attributes []string
+		if edge.Digest != "" {
+			attributes = append(attributes, "label="+strconv.Quote(edge.Digest))
+		}
+		if _, ok := conflictEdges[edge]; ok {
+			attributes = append(attributes, "color=red")
+		}
+		line := "  " + strconv.Quote(getModuleName(rootName, edge)) + " -> " + strconv.Quote(edge.To)
+		if len(attributes) > 0 {
+			line += " [" + strings.Join(attributes, ", ") + "]"
+		}
+		if _, err := fmt.Fprintln(writer, line+";"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(writer, "}")
+	return err
+}
+
+func printConflicts(writer io.Writer, rootName string, conflicts []*bufmod.GraphConflict) error {
+	for _, conflict := range conflicts {
+		if _, err := fmt.Fprintf(writer, "%s is depended on at more than one version:\n", conflict.Name); err != nil {
+			return err
+		}
+		for _, edge := range conflict.Edges {
+			digest := edge.Digest
+			if digest == "" {
+				digest = "not pinned"
+			}
+			if _, err := fmt.Fprintf(writer, "  %s (%s) by %s\n", edge.To, digest, getModuleName(rootName, edge)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getModuleName returns the name to print for the module that the edge is from.
+func getModuleName(rootName string, edge *bufmod.GraphEdge) string {
+	if edge.From == "" {
+		return rootName
+	}
+	return edge.From
+}
@@ -0,0 +1,197 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/bufdiff"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/bufbuild/buf/internal/pkg/thread"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatFlagName      = "format"
+	errorFormatFlagName = "error-format"
+
+	// the inputs are arguments, these are used to prefix errors
+	previousName = "previous"
+	currentName  = "current"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <previous> <current>",
+		Short: "Print the semantic changes between two inputs.",
+		Long: fmt.Sprintf(`Both inputs must be one of format %s, and imports are not compared.
+
+Every added, removed, and changed file, message, field, oneof, enum, enum value, service,
+and RPC is printed, regardless of whether the change is breaking. Messages, enums, and
+services are paired by fully-qualified name, fields by number, and all other elements by
+name, the same as for breaking change detection. For example:
+
+  buf diff .git#branch=main .
+
+With --format=json, each change is printed as a JSON object on its own line, with the keys:
+
+  type         One of added, removed, or changed.
+  kind         One of file, message, field, oneof, enum, enum_value, service, or rpc.
+  name         The fully-qualified name of the element, or the path for files.
+  path         The path of the file that contains the element.
+  description  What changed. Omitted for added and removed elements.
+
+The exit code is 0 whether or not there are changes.`,
+			buffetch.AllFormatsString,
+		),
+		Args: cobra.ExactArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	format               string
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.format,
+		formatFlagName,
+		"text",
+		`The format to print changes as. Must be one of [text,json].`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors, printed to stderr. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	asJSON, err := internal.IsLsFormatJSON(c.format)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	fetchOptions := internal.FetchOptions{
+		AllowInsecureHTTP: c.allowInsecureHTTP,
+		KeepTemp:          c.keepTemp,
+		NoCache:           c.noCache,
+		Offline:           c.offline,
+		TLSConfig:         tlsConfig,
+	}
+	var previousEnv bufwire.Env
+	var previousFileAnnotations []bufanalysis.FileAnnotation
+	getPreviousEnv := func() error {
+		var err error
+		previousEnv, previousFileAnnotations, err = getEnv(ctx, container, fetchOptions, previousName, container.Arg(0))
+		return err
+	}
+	var env bufwire.Env
+	var fileAnnotations []bufanalysis.FileAnnotation
+	getCurrentEnv := func() error {
+		var err error
+		env, fileAnnotations, err = getEnv(ctx, container, fetchOptions, currentName, container.Arg(1))
+		return err
+	}
+	if err := thread.Parallelize(getPreviousEnv, getCurrentEnv); err != nil {
+		return err
+	}
+	if fileAnnotations = append(previousFileAnnotations, fileAnnotations...); len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	changes, err := bufdiff.Diff(
+		ctx,
+		bufcore.ImageWithoutImports(previousEnv.Image()),
+		bufcore.ImageWithoutImports(env.Image()),
+	)
+	if err != nil {
+		return err
+	}
+	return bufdiff.PrintChanges(container.Stdout(), changes, asJSON)
+}
+
+func getEnv(
+	ctx context.Context,
+	container applog.Container,
+	fetchOptions internal.FetchOptions,
+	name string,
+	value string,
+) (bufwire.Env, []bufanalysis.FileAnnotation, error) {
+	return internal.NewBufwireEnvReader(
+		container.Logger(),
+		name,
+		"",
+		fetchOptions,
+	).GetEnv(
+		ctx,
+		container,
+		value,
+		"",
+		nil,
+		false,
+		true, // source info is not needed for a diff
+	)
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufdoc"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName       = "input"
+	configFlagName      = "input-config"
+	errorFormatFlagName = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Print a JSON documentation model of the input location.",
+		Long: `The model is a single JSON object with a "packages" key, which is a list of packages
+ordered by name. Each package contains its files, and all of its messages, enums, and services,
+including nested messages and enums, ordered by fully-qualified name. Fields, enum values, and
+RPCs are in declaration order. Imports are not included.
+
+Every element has leading_comments and trailing_comments keys, which are omitted if empty, and
+a deprecated key, which is omitted unless true. Comments are only available for sources and
+images built with source code info. For example:
+
+  buf beta docs | jq '.packages[].messages[] | {full_name, leading_comments}'`,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+type externalModel struct {
+	Packages []*bufdoc.Package `json:"packages"`
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image to document. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors, printed to stderr. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		false, // the comments are in the source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	packages, err := bufdoc.NewPackages(ctx, env.Image())
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(container.Stdout())
+	// map field types such as map<string, string> should stay readable
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(externalModel{Packages: packages})
+}
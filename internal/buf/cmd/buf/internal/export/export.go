@@ -0,0 +1,183 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName          = "input"
+	configFlagName         = "input-config"
+	outputFlagName         = "output"
+	excludeImportsFlagName = "exclude-imports"
+	errorFormatFlagName    = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Export the Protobuf files of the input location as .proto files.",
+		Long: `The files are written to the output directory, preserving their paths relative to their roots.
+
+The files are printed from the built or read image, so comments are preserved but the
+original formatting is not.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	output               string
+	excludeImports       bool
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image to export. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVarP(
+		&c.output,
+		outputFlagName,
+		"o",
+		"",
+		`Required. The output directory to export the files to.`,
+	)
+	flagSet.BoolVar(
+		&c.excludeImports,
+		excludeImportsFlagName,
+		false,
+		`Exclude imports.`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors, printed to stderr. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	if c.output == "" {
+		return fmt.Errorf("--%s is required", outputFlagName)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		false, // comments are printed from source code info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	if err := os.MkdirAll(c.output, 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := storageos.NewReadWriteBucket(c.output)
+	if err != nil {
+		return err
+	}
+	return bufwire.NewImageExporter(container.Logger()).ExportImage(
+		ctx,
+		env.Image(),
+		readWriteBucket,
+		c.excludeImports,
+	)
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufformat"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/diff"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName        = "input"
+	configFlagName       = "input-config"
+	writeFlagName        = "write"
+	diffFlagName         = "diff"
+	pruneImportsFlagName = "prune-imports"
+	errorFormatFlagName  = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Format the .proto files of the input location.",
+		Long: `Files are printed with two-space indentation and one statement per line. Runs of
+imports are sorted by path, and the values of runs of options are aligned. Comments
+and single blank lines are kept.
+
+By default, the formatted files are printed to stdout. With --write, the files that are
+not formatted are rewritten in place. With --diff, the changes are printed as a unified
+diff, and the command fails if any file is not formatted, which is useful in CI.
+
+With --prune-imports, the input is built, and the imports that a file does not use are
+removed, along with the comments directly above them. Public and weak imports are kept.
+
+The input must be a local directory. Without --prune-imports, the files are parsed but
+not built, so imports do not need to be resolvable.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input        string
+	config       string
+	write        bool
+	diff         bool
+	pruneImports bool
+	errorFormat  string
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		`The source directory to format.`,
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.BoolVarP(
+		&c.write,
+		writeFlagName,
+		"w",
+		false,
+		`Rewrite the files that are not formatted instead of printing them to stdout.`,
+	)
+	flagSet.BoolVarP(
+		&c.diff,
+		diffFlagName,
+		"d",
+		false,
+		`Print a diff of the files that are not formatted, and fail if there are any.`,
+	)
+	flagSet.BoolVar(
+		&c.pruneImports,
+		pruneImportsFlagName,
+		false,
+		`Remove unused imports. The input is built to find them.`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors with --%s, printed to stderr. Must be one of %s. %s`,
+			pruneImportsFlagName,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	if c.write && c.diff {
+		return fmt.Errorf("cannot set both --%s and --%s", writeFlagName, diffFlagName)
+	}
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, c.input)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", inputFlagName, err)
+	}
+	if sourceRef, ok := ref.(buffetch.SourceRef); !ok || sourceRef.LocalDirPath() == "" {
+		return fmt.Errorf("--%s must be a local directory", inputFlagName)
+	}
+	envReader := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{},
+	)
+	fileInfos, err := envReader.ListFiles(
+		ctx,
+		container,
+		c.input,
+		c.config,
+	)
+	if err != nil {
+		return err
+	}
+	var pathToUnusedImports map[string][]string
+	if c.pruneImports {
+		env, fileAnnotations, err := envReader.GetEnv(
+			ctx,
+			container,
+			c.input,
+			c.config,
+			nil,
+			false,
+			true, // source code info is not needed to find the unused imports
+		)
+		if err != nil {
+			return err
+		}
+		if len(fileAnnotations) > 0 {
+			if err := bufanalysis.PrintFileAnnotations(
+				container.Stderr(),
+				fileAnnotations,
+				internal.GetErrorFormat(container, c.errorFormat),
+			); err != nil {
+				return err
+			}
+			return errors.New("")
+		}
+		pathToUnusedImports, err = bufcore.ImageUnusedImports(env.Image())
+		if err != nil {
+			return err
+		}
+	}
+	unformatted := false
+	for _, fileInfo := range fileInfos {
+		externalPath := fileInfo.ExternalPath()
+		data, err := ioutil.ReadFile(externalPath)
+		if err != nil {
+			return err
+		}
+		formattedData, err := bufformat.Format(
+			externalPath,
+			data,
+			bufformat.FormatWithoutImports(pathToUnusedImports[fileInfo.Path()]...),
+		)
+		if err != nil {
+			return err
+		}
+		switch {
+		case c.write:
+			if bytes.Equal(data, formattedData) {
+				continue
+			}
+			osFileInfo, err := os.Stat(externalPath)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(externalPath, formattedData, osFileInfo.Mode().Perm()); err != nil {
+				return err
+			}
+		case c.diff:
+			if bytes.Equal(data, formattedData) {
+				continue
+			}
+			unformatted = true
+			diffData, err := diff.Diff(ctx, data, formattedData, externalPath+".orig", externalPath, false)
+			if err != nil {
+				return err
+			}
+			if _, err := container.Stdout().Write(diffData); err != nil {
+				return err
+			}
+		default:
+			if _, err := container.Stdout().Write(formattedData); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted {
+		return errors.New("")
+	}
+	return nil
+}
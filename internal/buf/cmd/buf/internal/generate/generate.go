@@ -0,0 +1,223 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufgen"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName       = "input"
+	configFlagName      = "input-config"
+	templateFlagName    = "template"
+	outputFlagName      = "output"
+	filesFlagName       = "file"
+	errorFormatFlagName = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Generate stubs for protoc plugins using a template.",
+		Long: `The template is a YAML or JSON file or data of the form:
+
+  version: v1beta1
+  plugins:
+    - name: go
+      out: gen/go
+      opt: paths=source_relative
+    - name: java
+      out: gen/java.jar
+      strategy: all
+
+  name      Required. The name of the plugin, as in --NAME_out with protoc.
+  out       Required. The output directory, relative to --output. If this ends in
+            .zip or .jar, the output is written to an archive instead.
+  opt       The options for the plugin, as in --NAME_opt with protoc. Either a string,
+            or a list of strings that are joined with commas.
+  path      The path to the plugin binary. By default, protoc-gen-NAME is looked up
+            on the PATH. If it is not found, the builtin plugin is used for go,
+            and protoc is used for the plugins builtin to protoc, such as java.
+  strategy  Either directory, to invoke the plugin once per directory as protoc
+            does, or all, to invoke the plugin once with all files. Defaults to
+            directory.
+
+The template can also enable managed mode, which sets file options of the files being
+generated before the plugins are run, so that they do not need to be set in each file:
+
+  managed:
+    go_package_prefix: github.com/acme/weather/gen/go
+    java_multiple_files: true
+    csharp_namespace: true
+
+  go_package_prefix    Set go_package to the prefix joined with the directory of the
+                       file, such as github.com/acme/weather/gen/go/acme/weather/v1;weatherv1
+                       for acme/weather/v1/weather.proto.
+  java_multiple_files  Set java_multiple_files to the value.
+  csharp_namespace     If true, set csharp_namespace to the package in PascalCase,
+                       such as Acme.Weather.V1 for acme.weather.v1.
+
+Imports keep their file options. The values set in the files are overridden.
+
+Source code info is always included in the files given to plugins.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	template             string
+	output               string
+	files                []string
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image to generate for. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.template,
+		templateFlagName,
+		bufgen.ExternalConfigFilePath,
+		`The generation template file or data to use. Must be in either YAML or JSON format.`,
+	)
+	flagSet.StringVarP(
+		&c.output,
+		outputFlagName,
+		"o",
+		".",
+		`The base directory to generate to. The out values in the template are relative to this directory.`,
+	)
+	flagSet.StringSliceVar(
+		&c.files,
+		filesFlagName,
+		nil,
+		`Limit to specific files. This is an advanced feature and is not recommended.`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors, printed to stderr. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	config, err := bufgen.ReadConfig(c.template)
+	if err != nil {
+		return fmt.Errorf("--%s: %v", templateFlagName, err)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		c.files,
+		false,
+		false, // plugins always need source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	return bufgen.NewGenerator(
+		container.Logger(),
+		bufgen.GeneratorWithBaseOutDirPath(c.output),
+	).Generate(
+		ctx,
+		container,
+		config,
+		env.Image(),
+	)
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagemerge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/thread"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	outputFlagName              = "output"
+	asFileDescriptorSetFlagName = "as-file-descriptor-set"
+	excludeImportsFlagName      = "exclude-imports"
+	excludeSourceInfoFlagName   = "exclude-source-info"
+
+	imageName = "image"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <image> <image>...",
+		Short: "Merge images or FileDescriptorSets into a single image.",
+		Long: fmt.Sprintf(
+			`Each argument must be one of format %s.
+
+Files contained in more than one image, such as common imports, are contained once in the
+merged image. All copies of a file must have the same content, ignoring source code info,
+and otherwise this fails. If a file is an import in some images and not in others, it is
+not an import in the merged image. For example:
+
+  buf image merge team-a.bin team-b.bin -o gateway.bin`,
+			buffetch.ImageFormatsString,
+		),
+		Args: cobra.MinimumNArgs(2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	output              string
+	asFileDescriptorSet bool
+	excludeImports      bool
+	excludeSourceInfo   bool
+	allowInsecureHTTP   bool
+	keepTemp            bool
+	noCache             bool
+	offline             bool
+	tlsFlags            internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(
+		&c.output,
+		outputFlagName,
+		"o",
+		"",
+		fmt.Sprintf(
+			`Required. The location to write the merged image to. Must be one of format %s.`,
+			buffetch.ImageFormatsString,
+		),
+	)
+	flagSet.BoolVar(
+		&c.asFileDescriptorSet,
+		asFileDescriptorSetFlagName,
+		false,
+		`Output as a google.protobuf.FileDescriptorSet instead of an image.`,
+	)
+	flagSet.BoolVar(
+		&c.excludeImports,
+		excludeImportsFlagName,
+		false,
+		`Exclude imports.`,
+	)
+	flagSet.BoolVar(
+		&c.excludeSourceInfo,
+		excludeSourceInfoFlagName,
+		false,
+		`Exclude source info.`,
+	)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	if c.output == "" {
+		return fmt.Errorf("--%s is required", outputFlagName)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	imageReader := internal.NewBufwireImageReader(
+		container.Logger(),
+		imageName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	)
+	images := make([]bufcore.Image, container.NumArgs())
+	jobs := make([]func() error, container.NumArgs())
+	for i := range jobs {
+		i := i
+		jobs[i] = func() error {
+			var err error
+			images[i], err = imageReader.GetImage(
+				ctx,
+				container,
+				container.Arg(i),
+				nil,
+				false,
+				c.excludeSourceInfo,
+			)
+			return err
+		}
+	}
+	if err := thread.Parallelize(jobs...); err != nil {
+		return err
+	}
+	image, err := bufcore.MergeImages(images...)
+	if err != nil {
+		return err
+	}
+	return internal.NewBufwireImageWriter(
+		container.Logger(),
+	).PutImage(
+		ctx,
+		container,
+		c.output,
+		image,
+		c.asFileDescriptorSet,
+		c.excludeImports,
+	)
+}
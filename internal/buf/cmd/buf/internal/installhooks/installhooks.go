@@ -0,0 +1,315 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package installhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+const (
+	inputFlagName = "input"
+	forceFlagName = "force"
+
+	preCommitHookName = "pre-commit"
+	prePushHookName   = "pre-push"
+
+	// hookMarker is contained in all hooks written by buf install-hooks, so
+	// that they can be overwritten without --force.
+	hookMarker = "# Written by buf install-hooks."
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Install git hooks that run lint and breaking change detection.",
+		Long: `The pre-commit hook runs buf check lint on the staged .proto files, and the pre-push hook
+runs buf check breaking against the upstream branch of the current branch. The hooks are
+configured with the hooks section of the buf.yaml of the input:
+
+  hooks:
+    # The checks of the pre-commit hook, where lint only checks the staged .proto files.
+    # Each must be lint or breaking. Defaults to lint. If empty, no hook is installed.
+    pre_commit:
+      - lint
+    # The checks of the pre-push hook, which checks all .proto files.
+    # Each must be lint or breaking. Defaults to breaking. If empty, no hook is installed.
+    pre_push:
+      - breaking
+    # The git ref to run breaking change detection against.
+    # Defaults to the upstream branch of the current branch, and breaking change
+    # detection is skipped if there is none.
+    against: origin/main
+
+Run buf install-hooks again after changing the hooks section to update the hooks.
+Existing hooks that were not installed by buf install-hooks are not overwritten unless
+--force is set. The hooks run buf from the PATH.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input string
+	force bool
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		"",
+		`The directory of the .proto files to check, which must be within a git repository.
+Defaults to $BUF_INPUT if set, or else to the closest directory that contains a buf.yaml,
+starting at the current directory, or the default_input of this buf.yaml if set.`,
+	)
+	flagSet.BoolVar(
+		&c.force,
+		forceFlagName,
+		false,
+		`Overwrite existing hooks that were not installed by buf install-hooks.`,
+	)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	input := c.input
+	if input == "" {
+		var err error
+		input, err = internal.GetDefaultInput(container.Logger(), container)
+		if err != nil {
+			return err
+		}
+	}
+	if fileInfo, err := os.Stat(input); err != nil || !fileInfo.IsDir() {
+		return fmt.Errorf("--%s must be a local directory but was %q", inputFlagName, input)
+	}
+	hooksConfig, err := getHooksConfig(container, input)
+	if err != nil {
+		return err
+	}
+	inputRepoPath, err := getInputRepoPath(ctx, container, input)
+	if err != nil {
+		return err
+	}
+	hooksDirPath, err := git.HooksDirPath(ctx, container, input)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDirPath, 0755); err != nil {
+		return err
+	}
+	hookFilePaths := []string{
+		filepath.Join(hooksDirPath, preCommitHookName),
+		filepath.Join(hooksDirPath, prePushHookName),
+	}
+	hookDatas := [][]byte{
+		newHook(inputRepoPath, hooksConfig.PreCommit, true, hooksConfig.Against),
+		newHook(inputRepoPath, hooksConfig.PrePush, false, hooksConfig.Against),
+	}
+	// all hooks are checked before any are written, so that either all or
+	// none of the hooks are updated
+	hookExists := make([]bool, len(hookFilePaths))
+	for i, hookFilePath := range hookFilePaths {
+		exists, err := c.checkHook(hookFilePath, hookDatas[i])
+		if err != nil {
+			return err
+		}
+		hookExists[i] = exists
+	}
+	for i, hookFilePath := range hookFilePaths {
+		if err := writeHook(container, hookFilePath, hookDatas[i], hookExists[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHook checks that the hook at hookFilePath can be overwritten, and
+// returns whether it exists and was installed by buf install-hooks.
+//
+// Hooks that were not installed by buf install-hooks are never removed, and
+// only overwritten with --force.
+func (c *controller) checkHook(hookFilePath string, data []byte) (bool, error) {
+	existingData, err := ioutil.ReadFile(hookFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if bytes.Contains(existingData, []byte(hookMarker)) {
+		return true, nil
+	}
+	if len(data) > 0 && !c.force {
+		return false, fmt.Errorf("%s already exists and was not installed by buf install-hooks, set --%s to overwrite it", hookFilePath, forceFlagName)
+	}
+	return false, nil
+}
+
+// writeHook writes the hook to hookFilePath, or if data is empty, removes
+// the hook at hookFilePath if it exists and was installed by buf install-hooks.
+func writeHook(container applog.Container, hookFilePath string, data []byte, exists bool) error {
+	if len(data) == 0 {
+		if exists {
+			container.Logger().Info("removing hook", zap.String("path", hookFilePath))
+			return os.Remove(hookFilePath)
+		}
+		return nil
+	}
+	container.Logger().Info("installing hook", zap.String("path", hookFilePath))
+	if err := ioutil.WriteFile(hookFilePath, data, 0755); err != nil {
+		return err
+	}
+	// WriteFile does not change the permissions of an existing file
+	return os.Chmod(hookFilePath, 0755)
+}
+
+// getHooksConfig gets the hooks config from the buf.yaml in the input
+// directory, or the default hooks config if there is no buf.yaml.
+func getHooksConfig(container applog.Container, input string) (*bufconfig.HooksConfig, error) {
+	data, err := ioutil.ReadFile(filepath.Join(input, bufconfig.ConfigFilePath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	config, err := bufconfig.NewProvider(container.Logger()).GetConfigForData(data)
+	if err != nil {
+		return nil, err
+	}
+	return config.Hooks, nil
+}
+
+// getInputRepoPath returns the slash-separated path of the input directory
+// relative to the top-level directory of its git repository, which is the
+// directory that hooks are run in.
+func getInputRepoPath(ctx context.Context, container applog.Container, input string) (string, error) {
+	topLevelDirPath, err := git.TopLevelDirPath(ctx, container, input)
+	if err != nil {
+		return "", err
+	}
+	// symlinks are resolved on both sides as git returns the resolved path
+	topLevelDirPath, err = filepath.EvalSymlinks(topLevelDirPath)
+	if err != nil {
+		return "", err
+	}
+	absInput, err := filepath.Abs(input)
+	if err != nil {
+		return "", err
+	}
+	absInput, err = filepath.EvalSymlinks(absInput)
+	if err != nil {
+		return "", err
+	}
+	relInput, err := filepath.Rel(topLevelDirPath, absInput)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relInput), nil
+}
+
+// newHook returns a hook that runs the checks on the input, or nil if there
+// are no checks.
+//
+// If staged is true, lint is limited to the staged .proto files. Breaking
+// change detection always checks all files, as the paths of the staged files
+// cannot be matched within the archive of the against ref.
+//
+// Lint is always run before breaking change detection, so that lint still
+// runs if breaking change detection is skipped as there is no upstream branch.
+func newHook(inputRepoPath string, checks []string, staged bool, against string) []byte {
+	if len(checks) == 0 {
+		return nil
+	}
+	var lintArgs string
+	if staged {
+		lintArgs = " --paths-from-git-staged"
+	}
+	buffer := bytes.NewBuffer(nil)
+	_, _ = fmt.Fprintf(buffer, "#!/bin/sh\n%s Run buf install-hooks again to update this hook.\n\nset -e\n", hookMarker)
+	if containsCheck(checks, bufconfig.HookCheckLint) {
+		_, _ = fmt.Fprintf(buffer, "\nbuf check lint --input %s%s\n", shellQuote(inputRepoPath), lintArgs)
+	}
+	if containsCheck(checks, bufconfig.HookCheckBreaking) {
+		if against != "" {
+			_, _ = fmt.Fprintf(buffer, "\nagainst=%s\n", shellQuote(against))
+		} else {
+			_, _ = buffer.WriteString(`
+against="$(git rev-parse --abbrev-ref --symbolic-full-name '@{upstream}' 2>/dev/null)" || {
+  echo 'buf: skipping breaking change detection as the current branch has no upstream branch' >&2
+  exit 0
+}
+`)
+		}
+		// the input at the against ref is read from an archive of the input
+		// directory at the ref, so that no clone is needed
+		//
+		// the archive is written to a temporary file instead of being piped
+		// to buf, as sh has no pipefail to fail the hook if git archive fails
+		againstTreeish := `"${against}:"`
+		if inputRepoPath != "." {
+			againstTreeish += shellQuote(inputRepoPath)
+		}
+		_, _ = fmt.Fprintf(
+			buffer,
+			`archive="$(mktemp)"
+trap 'rm -f "${archive}"' EXIT
+git archive --format=tar -o "${archive}" %s
+buf check breaking --input %s --against "${archive}#format=tar"
+`,
+			againstTreeish,
+			shellQuote(inputRepoPath),
+		)
+	}
+	return buffer.Bytes()
+}
+
+func containsCheck(checks []string, check string) bool {
+	for _, c := range checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes the value for sh.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package location
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore/bufcoreutil"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/protosource"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName  = "input"
+	configFlagName = "input-config"
+	spanFlagName   = "span"
+
+	spanName        = "name"
+	spanDeclaration = "declaration"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <fully-qualified-name>",
+		Short: "Print the source location of a descriptor in the input location.",
+		Long: `The descriptor is given by its fully-qualified name, for example acme.v1.Order for a message,
+acme.v1.Order.id for a field, or acme.v1.OrderService.GetOrder for a method. Enums, enum values,
+messages, fields, oneofs, services, methods, and extensions declared within messages are supported.
+Enum values are named relative to their enum, for example acme.v1.Status.STATUS_ACTIVE.
+
+The location is printed as path:start_line:start_column:end_line:end_column. Lines and columns
+start at 1, and the end column is exclusive. With --format=json, the location is printed as a JSON object with the keys path,
+start_line, start_column, end_line, and end_column.
+
+By default, the location is that of the name of the descriptor. Use --span=declaration for the
+location of the entire declaration.
+
+The input must include source info, so images built with --exclude-source-info are not supported.`,
+		Args: cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	span                 string
+	format               string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image that contains the descriptor. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.span,
+		spanFlagName,
+		spanName,
+		fmt.Sprintf(
+			`The span to print the location of. Must be one of %s.`,
+			strings.Join([]string{spanName, spanDeclaration}, ","),
+		),
+	)
+	internal.BindLsFormat(flagSet, &c.format, "the location")
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
+	fullName := container.Arg(0)
+	if c.span != spanName && c.span != spanDeclaration {
+		return fmt.Errorf("--%s: unknown span: %q", spanFlagName, c.span)
+	}
+	asJSON, err := internal.IsLsFormatJSON(c.format)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		false, // we need source info for locations
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			"text",
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	files, err := protosource.NewFilesUnstable(
+		ctx,
+		bufcoreutil.NewInputFiles(env.Image().Files())...,
+	)
+	if err != nil {
+		return err
+	}
+	fullNameToNamedDescriptor, err := protosource.FullNameToNamedDescriptor(files...)
+	if err != nil {
+		return err
+	}
+	namedDescriptor, ok := fullNameToNamedDescriptor[strings.TrimPrefix(fullName, ".")]
+	if !ok {
+		return fmt.Errorf("%q not found in input", fullName)
+	}
+	var location protosource.Location
+	switch c.span {
+	case spanName:
+		location = namedDescriptor.NameLocation()
+	case spanDeclaration:
+		location = namedDescriptor.Location()
+	}
+	if location == nil {
+		return fmt.Errorf("%q has no source info in input", fullName)
+	}
+	path := namedDescriptor.File().Path()
+	// the external path is more useful as it can be opened directly
+	if imageFile := env.Image().GetFile(path); imageFile != nil {
+		path = imageFile.ExternalPath()
+	}
+	return printLocation(container.Stdout(), newExternalLocation(path, location), asJSON)
+}
+
+type externalLocation struct {
+	Path        string `json:"path"`
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+}
+
+func newExternalLocation(path string, location protosource.Location) *externalLocation {
+	return &externalLocation{
+		Path:        path,
+		StartLine:   location.StartLine(),
+		StartColumn: location.StartColumn(),
+		EndLine:     location.EndLine(),
+		EndColumn:   location.EndColumn(),
+	}
+}
+
+func printLocation(writer io.Writer, externalLocation *externalLocation, asJSON bool) error {
+	if asJSON {
+		data, err := json.Marshal(externalLocation)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	}
+	_, err := fmt.Fprintf(
+		writer,
+		"%s:%d:%d:%d:%d\n",
+		externalLocation.Path,
+		externalLocation.StartLine,
+		externalLocation.StartColumn,
+		externalLocation.EndLine,
+		externalLocation.EndColumn,
+	)
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package login
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/netrc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const usernameFlagName = "username"
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <host>",
+		Short: "Log in to a registry by storing credentials in the netrc file.",
+		Long: `The password or token is read from stdin, for example:
+
+  echo $GITHUB_TOKEN | buf login ghcr.io --username acme
+
+The credentials are stored as the machine for the host in the netrc file, which is
+$NETRC if set, or .netrc in the home directory, and _netrc on Windows. Any existing machine for
+the host is replaced, and the file is created with 0600 permissions if it does not exist.
+
+The credentials are used by buf push and to pull oci:// references for registries that
+have no credentials in the Docker config, and for https remotes, including deps.`,
+		Args: cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	username string
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.username,
+		usernameFlagName,
+		"",
+		`The username to log in with. Some registries accept any username with a token.`,
+	)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	host := container.Arg(0)
+	if host == "" || strings.Contains(host, "/") {
+		return fmt.Errorf("%q must be a host, optionally with a port, such as ghcr.io", host)
+	}
+	data, err := ioutil.ReadAll(container.Stdin())
+	if err != nil {
+		return err
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return errors.New("no password or token was given on stdin")
+	}
+	return netrc.PutMachine(
+		container,
+		netrc.NewMachine(host, c.username, password, ""),
+	)
+}
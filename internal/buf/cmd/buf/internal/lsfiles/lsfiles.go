@@ -16,19 +16,28 @@ package lsfiles
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 
 	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
 	"github.com/bufbuild/buf/internal/buf/cmd/internal"
 	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
 	"github.com/bufbuild/buf/internal/pkg/app/appflag"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 )
 
 const (
 	inputFlagName  = "input"
 	configFlagName = "input-config"
+	formatFlagName = "format"
+	longFlagName   = "long"
 )
 
 // NewCommand returns a new Command
@@ -37,6 +46,26 @@ func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
 	return &appcmd.Command{
 		Use:   use,
 		Short: "List all Protobuf files for the input location.",
+		Long: `With --format=json, each file is printed as a JSON object on its own line, with the keys:
+
+  path           The path of the file relative to its root.
+  external_path  The path that identifies the file externally, such as on disk.
+  root           The configured root that the file is contained within. Omitted for images.
+  import         True if the file is an import. Omitted otherwise.
+  package        The package of the file. Omitted if the file has no package.
+  edition        The edition of the file, such as 2023. Omitted if the file does not use editions.
+
+The format can also be a Go template, which is executed for each file, followed by a newline.
+The template is given an object with the fields .Path, .ExternalPath, .Root, .Import, .Package,
+and .Edition, with the same meaning as above, and .Syntax, which is one of proto2, proto3, or
+editions. For example:
+
+  buf ls-files --input image.bin --format '{{.Path}} {{.Package}}{{if .Import}} (import){{end}}'
+
+Source files are parsed but not built to get their package, syntax, and edition.
+
+With --long, the root of each file and whether it is a target or an import are printed
+as additional columns. For sources, only target files are listed.`,
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container applog.Container) error {
 				return controller.Run(ctx, container)
@@ -53,7 +82,24 @@ func newController() *controller {
 type controller struct {
 	input                string
 	config               string
+	format               string
+	long                 bool
 	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+type externalFileInfo struct {
+	Path         string `json:"path,omitempty"`
+	ExternalPath string `json:"external_path,omitempty"`
+	Root         string `json:"root,omitempty"`
+	Import       bool   `json:"import,omitempty"`
+	Package      string `json:"package,omitempty"`
+	Syntax       string `json:"-"`
+	Edition      string `json:"edition,omitempty"`
 }
 
 func (c *controller) Bind(flagSet *pflag.FlagSet) {
@@ -72,14 +118,58 @@ func (c *controller) Bind(flagSet *pflag.FlagSet) {
 		"",
 		`The config file or data to use.`,
 	)
+	flagSet.StringVar(
+		&c.format,
+		formatFlagName,
+		"text",
+		`The format to print files as. Must be one of [text,json], or a Go template such as '{{.Path}}'.`,
+	)
+	flagSet.BoolVarP(
+		&c.long,
+		longFlagName,
+		"l",
+		false,
+		`Print the root of each file and whether it is a target or an import. Ignored with --format=json or a template.`,
+	)
 	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
 }
 
 func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
-	fileRefs, err := internal.NewBufwireEnvReader(
+	var tmpl *template.Template
+	var asJSON bool
+	if strings.Contains(c.format, "{{") {
+		var err error
+		tmpl, err = template.New(formatFlagName).Parse(c.format)
+		if err != nil {
+			return fmt.Errorf("--%s: %v", formatFlagName, err)
+		}
+	} else {
+		var err error
+		asJSON, err = internal.IsLsFormatJSON(c.format)
+		if err != nil {
+			return err
+		}
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	fileInfos, err := internal.NewBufwireEnvReader(
 		container.Logger(),
 		inputFlagName,
 		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
 	).ListFiles(
 		ctx,
 		container,
@@ -89,10 +179,70 @@ func (c *controller) Run(ctx context.Context, container applog.Container) (retEr
 	if err != nil {
 		return err
 	}
-	for _, fileRef := range fileRefs {
-		if _, err := fmt.Fprintln(container.Stdout(), fileRef.ExternalPath()); err != nil {
+	writer := container.Stdout()
+	if tmpl != nil {
+		for _, fileInfo := range fileInfos {
+			if err := tmpl.Execute(writer, newExternalFileInfo(fileInfo)); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(writer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	long := c.long && !asJSON
+	if long {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "PATH\tROOT\tROLE"); err != nil {
+			return err
+		}
+	}
+	for _, fileInfo := range fileInfos {
+		if err := printFileInfo(writer, fileInfo, asJSON, long); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+func printFileInfo(writer io.Writer, fileInfo bufwire.FileInfo, asJSON bool, long bool) error {
+	if asJSON {
+		data, err := json.Marshal(newExternalFileInfo(fileInfo))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	}
+	if long {
+		root := fileInfo.RootDirPath()
+		if root == "" {
+			root = "-"
+		}
+		role := "target"
+		if fileInfo.IsImport() {
+			role = "import"
+		}
+		_, err := fmt.Fprintf(writer, "%s\t%s\t%s\n", fileInfo.ExternalPath(), root, role)
+		return err
+	}
+	_, err := fmt.Fprintln(writer, fileInfo.ExternalPath())
+	return err
+}
+
+func newExternalFileInfo(fileInfo bufwire.FileInfo) *externalFileInfo {
+	return &externalFileInfo{
+		Path:         fileInfo.Path(),
+		ExternalPath: fileInfo.ExternalPath(),
+		Root:         fileInfo.RootDirPath(),
+		Import:       fileInfo.IsImport(),
+		Package:      fileInfo.Package(),
+		Syntax:       fileInfo.Syntax(),
+		Edition:      fileInfo.Edition(),
+	}
+}
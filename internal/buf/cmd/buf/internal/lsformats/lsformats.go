@@ -0,0 +1,118 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsformats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "List all supported input and output formats.",
+		Long: `The format of an input or output is inferred from its file extension, and can be
+overridden with the format and compression options, for example
+"-o image.out#format=json,compression=zstd". The level option sets the compression
+level of compressed outputs, for example "-o image.bin.gz#level=9".
+
+With --format=json, each format is printed as a JSON object on its own line, with the keys:
+
+  name          The name of the format, as used with the format option.
+  image         True for image formats. Omitted for source formats.
+  extensions    The file extensions the format is inferred from.
+  compressions  The values of the compression option the format accepts.`,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	format string
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	internal.BindLsFormat(flagSet, &c.format, "formats")
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
+	asJSON, err := internal.IsLsFormatJSON(c.format)
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = container.Stdout()
+	if !asJSON {
+		tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+		defer func() {
+			retErr = multierr.Append(retErr, tabWriter.Flush())
+		}()
+		writer = tabWriter
+		if _, err := fmt.Fprintln(writer, "NAME\tTYPE\tEXTENSIONS\tCOMPRESSIONS"); err != nil {
+			return err
+		}
+	}
+	for _, formatInfo := range buffetch.GetFormatInfos() {
+		if err := printFormatInfo(writer, formatInfo, asJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printFormatInfo(writer io.Writer, formatInfo *buffetch.FormatInfo, asJSON bool) error {
+	if asJSON {
+		data, err := json.Marshal(formatInfo)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(writer, string(data))
+		return err
+	}
+	formatType := "source"
+	if formatInfo.Image {
+		formatType = "image"
+	}
+	_, err := fmt.Fprintf(
+		writer,
+		"%s\t%s\t%s\t%s\n",
+		formatInfo.Name,
+		formatType,
+		strings.Join(formatInfo.Extensions, ","),
+		strings.Join(formatInfo.Compressions, ","),
+	)
+	return err
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsp
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/internal/buf/buflsp"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/interrupt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	configFlagName = "config"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Run a language server for Protobuf files over stdin and stdout.",
+		Long: `The language server publishes compile errors and lint failures as diagnostics, and supports
+going to the definition of the types referenced by fields, extensions, and methods.
+
+The workspace is the root of the client, and is built from the files on disk, so diagnostics
+are updated when files are opened and saved. The language server runs until the client exits,
+and --timeout does not apply.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	config  string
+	noCache bool
+	offline bool
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use instead of the buf.yaml of the workspace.`,
+	)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	// the context of the command times out after --timeout, but the
+	// language server runs until the client exits or we are interrupted
+	ctx, cancel := interrupt.WithCancel(context.Background())
+	defer cancel()
+	return buflsp.NewServer(
+		container.Logger(),
+		container,
+		internal.NewBufwireEnvReader(
+			container.Logger(),
+			"",
+			configFlagName,
+			internal.FetchOptions{
+				NoCache: c.noCache,
+				Offline: c.offline,
+			},
+		),
+		internal.NewBuflintHandler(container.Logger()),
+		buflsp.ServerWithConfigOverride(c.config),
+	).Serve(ctx, container.Stdin(), container.Stdout())
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lsstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufstats"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	inputFlagName       = "input"
+	configFlagName      = "input-config"
+	formatFlagName      = "format"
+	errorFormatFlagName = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "List statistics for each package of the input location.",
+		Long: `Imports are not included. Nested messages and enums are counted the same as top-level
+ones, and map entries are not counted as messages.
+
+With --format=text, the number of options set in each package is printed as a total.
+
+With --format=json, each package is printed as a JSON object on its own line, with the keys
+package, files, messages, fields, extensions, oneofs, enums, enum_values, services, methods,
+and options. The package is omitted for files without a package. The options are an object
+of the number of times each option is set, keyed by option name, such as go_package. Custom
+options are keyed by their fully-qualified name in parentheses, such as (google.api.http).`,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	format               string
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image to list statistics for. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.format,
+		formatFlagName,
+		"text",
+		`The format to print statistics as. Must be one of [text,json].`,
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for build errors, printed to stderr. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
+	asJSON, err := internal.IsLsFormatJSON(c.format)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		true, // source info is not needed for statistics
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	packageStatsList := bufstats.GetPackageStats(env.Image())
+	if asJSON {
+		for _, packageStats := range packageStatsList {
+			data, err := json.Marshal(packageStats)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(container.Stdout(), string(data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tabWriter := tabwriter.NewWriter(container.Stdout(), 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(
+		tabWriter,
+		"PACKAGE\tFILES\tMESSAGES\tFIELDS\tEXTENSIONS\tONEOFS\tENUMS\tENUM_VALUES\tSERVICES\tMETHODS\tOPTIONS",
+	); err != nil {
+		return err
+	}
+	for _, packageStats := range packageStatsList {
+		pkg := packageStats.Package
+		if pkg == "" {
+			pkg = "-"
+		}
+		if _, err := fmt.Fprintf(
+			tabWriter,
+			"%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			pkg,
+			packageStats.Files,
+			packageStats.Messages,
+			packageStats.Fields,
+			packageStats.Extensions,
+			packageStats.Oneofs,
+			packageStats.Enums,
+			packageStats.EnumValues,
+			packageStats.Services,
+			packageStats.Methods,
+			packageStats.OptionCount(),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
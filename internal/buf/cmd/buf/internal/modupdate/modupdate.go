@@ -0,0 +1,125 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modupdate
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const dirFlagName = "dir"
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Update the buf.lock file to pin the current dependencies of the module.",
+		Long: `Dependencies are declared with deps in buf.yaml, for example:
+
+  deps:
+    - https://github.com/googleapis/googleapis.git#branch=master
+
+Each dependency is a remote source input, such as a git repository, an archive
+over https, or a module pushed to an OCI registry with buf push --module, such as
+oci://ghcr.io/acme/weather:v1, and is built with the build config in its own buf.yaml. The dependencies
+declared in the buf.yaml of each dependency are resolved as well. Run
+buf beta dep graph to check that the versions of these agree.
+
+This fetches each dependency and its transitive dependencies, and writes buf.lock
+next to buf.yaml, pinning each of them to the digest of its Protobuf files. Builds
+fetch the dependencies, add their files as imports, and fail if a digest does not
+match buf.lock.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	dir               string
+	allowInsecureHTTP bool
+	keepTemp          bool
+	noCache           bool
+	tlsFlags          internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.dir,
+		dirFlagName,
+		".",
+		`The directory of the module, containing the buf.yaml file.`,
+	)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	readBucket, err := storageos.NewReadWriteBucket(c.dir)
+	if err != nil {
+		return err
+	}
+	config, err := bufconfig.NewProvider(container.Logger()).GetConfig(ctx, readBucket)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	lock, err := internal.NewBufwireDependencyResolver(
+		container.Logger(),
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			TLSConfig:         tlsConfig,
+		},
+	).ResolveLock(
+		ctx,
+		container,
+		config,
+	)
+	if err != nil {
+		return err
+	}
+	data, err := bufmod.MarshalLock(lock)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(c.dir, bufmod.LockFilePath), data, 0644)
+}
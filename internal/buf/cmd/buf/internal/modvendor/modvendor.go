@@ -0,0 +1,127 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modvendor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const dirFlagName = "dir"
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Copy the dependencies of the module into the buf.vendor directory.",
+		Long: `Each dependency in buf.yaml is fetched, verified against the digest in buf.lock, and
+its .proto files and buf.yaml are written to buf.vendor/HEX next to buf.yaml, where HEX is
+the hex of its digest. A copy of buf.lock is written to buf.vendor/buf.lock. Any existing
+buf.vendor directory is replaced.
+
+If buf.vendor/buf.lock exists, builds use the vendored dependencies instead of fetching
+them, and fail if it does not match buf.lock or if a vendored dependency does not match
+its digest. Commit buf.vendor to build without access to the dependencies. Together with
+--offline, this allows modules with dependencies to be built without network access.
+The .proto files within buf.vendor are never part of the module itself.
+
+Run buf beta mod update first to create or update buf.lock.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	dir               string
+	allowInsecureHTTP bool
+	keepTemp          bool
+	noCache           bool
+	tlsFlags          internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.dir,
+		dirFlagName,
+		".",
+		`The directory of the module, containing the buf.yaml and buf.lock files.`,
+	)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	readWriteBucket, err := storageos.NewReadWriteBucket(c.dir)
+	if err != nil {
+		return err
+	}
+	config, err := bufconfig.NewProvider(container.Logger()).GetConfig(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	lock, err := bufmod.GetLockForBucket(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return fmt.Errorf("no %s, run buf beta mod update", bufmod.LockFilePath)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(c.dir, bufmod.VendorDirPath)); err != nil {
+		return err
+	}
+	return internal.NewBufwireDependencyResolver(
+		container.Logger(),
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			TLSConfig:         tlsConfig,
+		},
+	).Vendor(
+		ctx,
+		container,
+		config,
+		lock,
+		readWriteBucket,
+	)
+}
@@ -35,6 +35,7 @@ const (
 	pluginPathValuesFlagName      = "plugin"
 	errorFormatFlagName           = "error_format"
 	byDirFlagName                 = "by-dir"
+	manifestFlagName              = "manifest"
 
 	pluginFakeFlagName = "protoc_plugin_fake"
 
@@ -57,6 +58,7 @@ type flags struct {
 	Output                string
 	ErrorFormat           string
 	ByDir                 bool
+	Manifest              string
 }
 
 type env struct {
@@ -95,7 +97,7 @@ func (f *flagsBuilder) Bind(flagSet *pflag.FlagSet) {
 		// no way to differentiate between default and set for now
 		// perhaps we could rework pflag usage somehow
 		nil,
-		`The include directory paths. This is equivalent to roots in Buf.`,
+		`The include directory paths. This is equivalent to roots in Buf. Like protoc, paths may also be separated by the path list separator.`,
 	)
 	flagSet.BoolVar(
 		&f.IncludeImports,
@@ -149,6 +151,12 @@ func (f *flagsBuilder) Bind(flagSet *pflag.FlagSet) {
 		false,
 		`Execute parallel plugin calls for every directory containing .proto files.`,
 	)
+	flagSet.StringVar(
+		&f.Manifest,
+		manifestFlagName,
+		"",
+		`Write a JSON manifest listing the path, size, sha256, and plugin of every file produced by plugins to the given path.`,
+	)
 
 	// MUST be a StringArray instead of StringSlice so we do not split on commas
 	// Otherwise --go_out=foo=bar,baz=bat:out would be treated as --go_out=foo=bar --go_out=baz=bat:out
@@ -220,6 +228,7 @@ func (f *flagsBuilder) Build(args []string) (*env, error) {
 			return nil, newCannotSpecifyPathWithoutOutError(pluginName)
 		}
 	}
+	f.IncludeDirPaths = splitIncludeDirPaths(f.IncludeDirPaths)
 	if len(f.IncludeDirPaths) == 0 {
 		f.IncludeDirPaths = defaultIncludeDirPaths
 	}
@@ -236,6 +245,16 @@ func (f *flagsBuilder) Build(args []string) (*env, error) {
 	}, nil
 }
 
+// splitIncludeDirPaths splits the include directory paths on the path list
+// separator, as protoc accepts -I foo:bar for -I foo -I bar.
+func splitIncludeDirPaths(includeDirPaths []string) []string {
+	var splitIncludeDirPaths []string
+	for _, includeDirPath := range includeDirPaths {
+		splitIncludeDirPaths = append(splitIncludeDirPaths, filepath.SplitList(includeDirPath)...)
+	}
+	return splitIncludeDirPaths
+}
+
 func (f *flagsBuilder) pluginFakeParse(name string, suffix string, isOut bool) {
 	pluginName := strings.TrimSuffix(name, suffix)
 	pluginValue, ok := f.pluginNameToValue[pluginName]
@@ -329,6 +348,9 @@ func (f *flagsBuilder) merge(subFlagsBuilder *flagsBuilder) error {
 	if subFlagsBuilder.ByDir {
 		f.ByDir = true
 	}
+	if subFlagsBuilder.Manifest != "" {
+		f.Manifest = subFlagsBuilder.Manifest
+	}
 	f.PluginPathValues = append(f.PluginPathValues, subFlagsBuilder.PluginPathValues...)
 	if subFlagsBuilder.Encode != "" {
 		f.Encode = subFlagsBuilder.Encode
@@ -67,6 +67,28 @@ func TestParseFlags(t *testing.T) {
 				},
 			},
 		},
+		{
+			Args: []string{
+				"-I",
+				"proto" + string(filepath.ListSeparator) + "vendor",
+				"-I",
+				"other",
+				"foo.proto",
+			},
+			Expected: &env{
+				flags: flags{
+					IncludeDirPaths: []string{
+						"proto",
+						"vendor",
+						"other",
+					},
+					ErrorFormat: defaultErrorFormat,
+				},
+				FilePaths: []string{
+					"foo.proto",
+				},
+			},
+		},
 		{
 			Args: []string{
 				"-I",
@@ -0,0 +1,96 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// manifest is the JSON manifest of the files produced by plugins.
+type manifest struct {
+	Files []*manifestFile `json:"files"`
+}
+
+// manifestFile is a file produced by a plugin.
+type manifestFile struct {
+	// Path is the path of the file on disk, including the plugin output directory.
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	Plugin string `json:"plugin"`
+}
+
+func newManifestFile(out string, name string, data []byte, pluginName string) *manifestFile {
+	sum := sha256.Sum256(data)
+	return &manifestFile{
+		Path:   filepath.ToSlash(filepath.Join(out, name)),
+		Size:   len(data),
+		SHA256: hex.EncodeToString(sum[:]),
+		Plugin: pluginName,
+	}
+}
+
+// manifestBuilder collects manifestFiles from concurrent plugin executions.
+type manifestBuilder struct {
+	files []*manifestFile
+	lock  sync.Mutex
+}
+
+func newManifestBuilder() *manifestBuilder {
+	return &manifestBuilder{}
+}
+
+func (m *manifestBuilder) Add(files ...*manifestFile) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.files = append(m.files, files...)
+}
+
+// Build returns the manifest with the files sorted by path, and then by plugin.
+func (m *manifestBuilder) Build() *manifest {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	files := make([]*manifestFile, len(m.files))
+	copy(files, m.files)
+	sort.Slice(
+		files,
+		func(i int, j int) bool {
+			if files[i].Path == files[j].Path {
+				return files[i].Plugin < files[j].Plugin
+			}
+			return files[i].Path < files[j].Path
+		},
+	)
+	if files == nil {
+		files = []*manifestFile{}
+	}
+	return &manifest{
+		Files: files,
+	}
+}
+
+func writeManifest(filePath string, manifest *manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, append(data, '\n'), 0644)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestBuilder(t *testing.T) {
+	t.Parallel()
+	manifestBuilder := newManifestBuilder()
+	assert.Equal(t, &manifest{Files: []*manifestFile{}}, manifestBuilder.Build())
+	manifestBuilder.Add(
+		newManifestFile("gen/go", "b/b.pb.go", []byte("b"), "go"),
+		newManifestFile("gen/go", "a/a.pb.go", []byte(""), "go"),
+	)
+	manifestBuilder.Add(
+		newManifestFile("gen/go", "a/a.pb.go", []byte("foo"), "gogo"),
+	)
+	assert.Equal(
+		t,
+		&manifest{
+			Files: []*manifestFile{
+				{
+					Path:   "gen/go/a/a.pb.go",
+					Size:   0,
+					SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+					Plugin: "go",
+				},
+				{
+					Path:   "gen/go/a/a.pb.go",
+					Size:   3,
+					SHA256: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+					Plugin: "gogo",
+				},
+				{
+					Path:   "gen/go/b/b.pb.go",
+					Size:   1,
+					SHA256: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d",
+					Plugin: "go",
+				},
+			},
+		},
+		manifestBuilder.Build(),
+	)
+}
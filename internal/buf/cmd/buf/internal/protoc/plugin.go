@@ -51,6 +51,7 @@ func executePlugin(
 	images []bufcore.Image,
 	pluginName string,
 	pluginInfo *pluginInfo,
+	manifestBuilder *manifestBuilder,
 ) error {
 	handler, err := appprotoexec.NewHandler(logger, pluginName, "", pluginInfo.Path)
 	if err != nil {
@@ -67,6 +68,7 @@ func executePlugin(
 				pluginName,
 				pluginInfo,
 				handler,
+				manifestBuilder,
 			)
 		}
 	}
@@ -80,6 +82,7 @@ func executePluginForImage(
 	pluginName string,
 	pluginInfo *pluginInfo,
 	handler appproto.Handler,
+	manifestBuilder *manifestBuilder,
 ) error {
 	request := bufcore.ImageToCodeGeneratorRequest(image, strings.Join(pluginInfo.Opt, ","))
 	response, err := appproto.Execute(ctx, container, handler, request)
@@ -89,41 +92,46 @@ func executePluginForImage(
 	if errString := response.GetError(); errString != "" {
 		return fmt.Errorf("--%s_out: %s", pluginName, errString)
 	}
-	if err := writeResponseFiles(ctx, response.File, pluginInfo.Out); err != nil {
+	manifestFiles, err := writeResponseFiles(ctx, response.File, pluginName, pluginInfo.Out)
+	if err != nil {
 		return fmt.Errorf("--%s_out: %v", pluginName, err)
 	}
+	manifestBuilder.Add(manifestFiles...)
 	return nil
 }
 
 func writeResponseFiles(
 	ctx context.Context,
 	files []*pluginpb.CodeGeneratorResponse_File,
+	pluginName string,
 	out string,
-) error {
+) ([]*manifestFile, error) {
 	switch filepath.Ext(out) {
 	case ".jar":
-		return fmt.Errorf("jar output not supported but is coming soon: %q", out)
+		return nil, fmt.Errorf("jar output not supported but is coming soon: %q", out)
 	case ".zip":
-		return fmt.Errorf("zip output not supported but is coming soon: %q", out)
+		return nil, fmt.Errorf("zip output not supported but is coming soon: %q", out)
 	}
 	readWriteBucket, err := storageos.NewReadWriteBucket(out)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	manifestFiles := make([]*manifestFile, 0, len(files))
 	for _, file := range files {
 		if file.GetInsertionPoint() != "" {
-			return fmt.Errorf("insertion points not supported but are coming soon: %s", file.GetName())
+			return nil, fmt.Errorf("insertion points not supported but are coming soon: %s", file.GetName())
 		}
 		data := []byte(file.GetContent())
 		writeObjectCloser, err := readWriteBucket.Put(ctx, file.GetName(), uint32(len(data)))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		_, err = writeObjectCloser.Write(data)
 		err = multierr.Append(err, writeObjectCloser.Close())
 		if err != nil {
-			return err
+			return nil, err
 		}
+		manifestFiles = append(manifestFiles, newManifestFile(out, file.GetName(), data, pluginName))
 	}
-	return nil
+	return manifestFiles, nil
 }
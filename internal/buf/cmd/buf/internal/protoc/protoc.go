@@ -137,6 +137,7 @@ func run(ctx context.Context, container applog.Container, env *env) (retErr erro
 			}
 			timer.End()
 		}
+		manifestBuilder := newManifestBuilder()
 		for pluginName, pluginInfo := range env.PluginNameToPluginInfo {
 			if err := executePlugin(
 				ctx,
@@ -145,12 +146,19 @@ func run(ctx context.Context, container applog.Container, env *env) (retErr erro
 				images,
 				pluginName,
 				pluginInfo,
+				manifestBuilder,
 			); err != nil {
 				return err
 			}
 		}
+		if env.Manifest != "" {
+			return writeManifest(env.Manifest, manifestBuilder.Build())
+		}
 		return nil
 	}
+	if env.Manifest != "" {
+		return fmt.Errorf("cannot call --%s without plugins", manifestFlagName)
+	}
 	if env.Output == "" {
 		return fmt.Errorf("--%s is required", outputFlagName)
 	}
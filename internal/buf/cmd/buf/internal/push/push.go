@@ -0,0 +1,261 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+)
+
+const (
+	inputFlagName             = "input"
+	configFlagName            = "input-config"
+	excludeImportsFlagName    = "exclude-imports"
+	excludeSourceInfoFlagName = "exclude-source-info"
+	moduleFlagName            = "module"
+
+	ociPrefix = "oci://"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <oci://host/repository:tag>",
+		Short: "Push an image or module of the input location to an OCI registry.",
+		Long: `The image is built from the input, or read if the input is an image, and is pushed as an
+artifact with a single layer, for example:
+
+  buf push oci://ghcr.io/acme/protos:v1
+
+The digest of the pushed artifact is logged. Pushed images can be used as inputs by
+their oci:// reference, by tag or by digest, for example:
+
+  buf check breaking --against oci://ghcr.io/acme/protos:v1
+
+Images are binary unless #format=json is given.
+
+With --module, the input must be a source, and its .proto files, buf.yaml and buf.lock
+are pushed as a module instead, for example:
+
+  buf push --module oci://ghcr.io/acme/weather:v1
+
+The module is built before it is pushed. Pushed modules can be used as deps in buf.yaml
+by their oci:// reference, and as inputs with #format=tar,compression=gzip.
+
+Credentials are read from the Docker config, falling back to the netrc file, so log in
+with buf login or docker login first. Registries on localhost are accessed over plain http.`,
+		Args: cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	excludeImports       bool
+	excludeSourceInfo    bool
+	module               bool
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image to push. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.BoolVar(
+		&c.excludeImports,
+		excludeImportsFlagName,
+		false,
+		"Exclude imports from the pushed image.",
+	)
+	flagSet.BoolVar(
+		&c.excludeSourceInfo,
+		excludeSourceInfoFlagName,
+		false,
+		"Exclude source info from the pushed image.",
+	)
+	flagSet.BoolVar(
+		&c.module,
+		moduleFlagName,
+		false,
+		"Push the source of the input as a module instead of an image.",
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	reference := container.Arg(0)
+	if !strings.HasPrefix(reference, ociPrefix) {
+		return fmt.Errorf("%q must be an %s reference such as %shost/repository:tag", reference, ociPrefix, ociPrefix)
+	}
+	if c.module {
+		if c.config != "" {
+			return fmt.Errorf("cannot set --%s with --%s, the buf.yaml of the module is pushed", configFlagName, moduleFlagName)
+		}
+		if c.excludeImports || c.excludeSourceInfo {
+			return fmt.Errorf("cannot set --%s or --%s with --%s", excludeImportsFlagName, excludeSourceInfoFlagName, moduleFlagName)
+		}
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	fetchOptions := internal.FetchOptions{
+		AllowInsecureHTTP: c.allowInsecureHTTP,
+		KeepTemp:          c.keepTemp,
+		NoCache:           c.noCache,
+		TLSConfig:         tlsConfig,
+	}
+	var sourceRef buffetch.SourceRef
+	if c.module {
+		sourceRef, err = buffetch.NewRefParser(container.Logger()).GetSourceRef(ctx, c.input)
+		if err != nil {
+			return fmt.Errorf("--%s: %v", inputFlagName, err)
+		}
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		fetchOptions,
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		c.excludeSourceInfo,
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			"text",
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	var writerOptions []buffetch.WriterOption
+	if c.allowInsecureHTTP {
+		writerOptions = append(writerOptions, buffetch.WriterWithInsecureHTTP())
+	}
+	if c.module {
+		return pushModule(ctx, container, reference, sourceRef, fetchOptions, writerOptions)
+	}
+	return bufwire.NewImageWriter(
+		container.Logger(),
+		buffetch.NewImageRefParser(container.Logger()),
+		internal.NewBuffetchWriter(container.Logger(), writerOptions...),
+	).PutImage(
+		ctx,
+		container,
+		reference,
+		env.Image(),
+		false,
+		c.excludeImports,
+	)
+}
+
+func pushModule(
+	ctx context.Context,
+	container applog.Container,
+	reference string,
+	sourceRef buffetch.SourceRef,
+	fetchOptions internal.FetchOptions,
+	writerOptions []buffetch.WriterOption,
+) (retErr error) {
+	readBucketCloser, err := internal.NewBuffetchReader(
+		container.Logger(),
+		fetchOptions,
+	).GetSourceBucket(
+		ctx,
+		container,
+		sourceRef,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readBucketCloser.Close())
+	}()
+	_, err = internal.NewBuffetchWriter(
+		container.Logger(),
+		writerOptions...,
+	).PutModule(
+		ctx,
+		container,
+		reference,
+		storage.Map(
+			readBucketCloser,
+			storage.MatchOr(
+				storage.MatchPathExt(".proto"),
+				storage.MatchPathEqual(bufconfig.ConfigFilePath),
+				storage.MatchPathEqual(bufmod.LockFilePath),
+			),
+		),
+	)
+	return err
+}
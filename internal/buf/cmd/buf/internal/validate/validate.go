@@ -0,0 +1,462 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	inputFlagName         = "input"
+	configFlagName        = "input-config"
+	typeFlagName          = "type"
+	payloadFormatFlagName = "payload-format"
+	errorFormatFlagName   = "error-format"
+
+	payloadFormatBin  = "bin"
+	payloadFormatJSON = "json"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use + " <payload>",
+		Short: "Validate that a payload strictly conforms to a message in the input location.",
+		Long: `The payload is read from the given path, or from stdin if the path is "-".
+
+The payload is checked for unknown fields, type mismatches, invalid UTF-8 in string fields,
+missing required fields, and multiple set fields of the same oneof. For binary payloads, the
+last set field of a oneof wins when unmarshaling, so each field of a oneof that is set after
+another field of the same oneof is reported.`,
+		Args: cobra.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input                string
+	config               string
+	typeName             string
+	payloadFormat        string
+	errorFormat          string
+	experimentalGitClone bool
+	allowInsecureHTTP    bool
+	keepTemp             bool
+	noCache              bool
+	offline              bool
+	tlsFlags             internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&c.input,
+		inputFlagName,
+		".",
+		fmt.Sprintf(
+			`The source or image that contains the message type. Must be one of format %s.`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.config,
+		configFlagName,
+		"",
+		`The config file or data to use.`,
+	)
+	flagSet.StringVar(
+		&c.typeName,
+		typeFlagName,
+		"",
+		`Required. The fully-qualified name of the message to validate against, for example acme.v1.Order.`,
+	)
+	flagSet.StringVar(
+		&c.payloadFormat,
+		payloadFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format of the payload. Must be one of %s. If not set, this is inferred from the file extension, defaulting to %s.`,
+			strings.Join([]string{payloadFormatBin, payloadFormatJSON}, ","),
+			payloadFormatBin,
+		),
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"text",
+		`The format for validation errors, printed to stdout. Must be one of text,json.`,
+	)
+	internal.BindExperimentalGitClone(flagSet, &c.experimentalGitClone)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) (retErr error) {
+	if c.typeName == "" {
+		return fmt.Errorf("--%s is required", typeFlagName)
+	}
+	payloadPath := container.Arg(0)
+	payloadFormat, err := getPayloadFormat(c.payloadFormat, payloadPath)
+	if err != nil {
+		return err
+	}
+	if c.errorFormat != "text" && c.errorFormat != "json" {
+		return fmt.Errorf("--%s: unknown format: %q", errorFormatFlagName, c.errorFormat)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+		container.Logger(),
+		inputFlagName,
+		configFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).GetEnv(
+		ctx,
+		container,
+		c.input,
+		c.config,
+		nil,
+		false,
+		true, // no need for source info
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			"text",
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	resolver, err := protoencoding.NewResolver(
+		bufcore.ImageToFileDescriptorProtos(
+			env.Image(),
+		)...,
+	)
+	if err != nil {
+		return err
+	}
+	if resolver == nil {
+		return fmt.Errorf("--%s: %q not found in input", typeFlagName, c.typeName)
+	}
+	messageType, err := resolver.FindMessageByName(protoreflect.FullName(c.typeName))
+	if err != nil {
+		return fmt.Errorf("--%s: %q not found in input", typeFlagName, c.typeName)
+	}
+	readCloser, err := internal.OpenPayload(container, payloadPath, payloadFormat)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(readCloser)
+	if err := multierr.Append(err, readCloser.Close()); err != nil {
+		return err
+	}
+	violations := validate(resolver, messageType, payloadFormat, data)
+	if len(violations) == 0 {
+		return nil
+	}
+	if err := printViolations(container.Stdout(), violations, c.errorFormat); err != nil {
+		return err
+	}
+	return errors.New("")
+}
+
+func getPayloadFormat(payloadFormat string, payloadPath string) (string, error) {
+	switch payloadFormat {
+	case payloadFormatBin, payloadFormatJSON:
+		return payloadFormat, nil
+	case "":
+		if filepath.Ext(payloadPath) == ".json" {
+			return payloadFormatJSON, nil
+		}
+		return payloadFormatBin, nil
+	default:
+		return "", fmt.Errorf("--%s: unknown format: %q", payloadFormatFlagName, payloadFormat)
+	}
+}
+
+// violation is a single way in which a payload does not conform to a message.
+type violation struct {
+	// Path is the path to the offending field, for example items[2].name.
+	//
+	// Empty for the root message.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func printViolations(writer io.Writer, violations []*violation, errorFormat string) error {
+	for _, violation := range violations {
+		var data []byte
+		switch errorFormat {
+		case "json":
+			var err error
+			data, err = json.Marshal(violation)
+			if err != nil {
+				return err
+			}
+		default:
+			if violation.Path == "" {
+				data = []byte(violation.Message)
+			} else {
+				data = []byte(violation.Path + ": " + violation.Message)
+			}
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(
+	resolver protoencoding.Resolver,
+	messageType protoreflect.MessageType,
+	payloadFormat string,
+	data []byte,
+) []*violation {
+	var violations []*violation
+	message := dynamicpb.NewMessage(messageType.Descriptor())
+	var err error
+	switch payloadFormat {
+	case payloadFormatJSON:
+		// protojson errors on unknown fields, type mismatches, and duplicate oneof fields
+		err = protojson.UnmarshalOptions{
+			Resolver:     resolver,
+			AllowPartial: true,
+		}.Unmarshal(data, message)
+	default:
+		// the binary encoding allows multiple fields of the same oneof, so we
+		// check the wire data directly as this is lost on unmarshal
+		violations = scanOneofs(violations, "", messageType.Descriptor(), data)
+		err = proto.UnmarshalOptions{
+			Resolver:       resolver,
+			AllowPartial:   true,
+			DiscardUnknown: false,
+		}.Unmarshal(data, message)
+	}
+	if err != nil {
+		return append(
+			violations,
+			&violation{
+				Message: err.Error(),
+			},
+		)
+	}
+	return walkMessage(violations, "", message.ProtoReflect())
+}
+
+// scanOneofs adds a violation for each field of a oneof in the wire data that
+// is set after another field of the same oneof, for the message and all of its
+// nested messages.
+//
+// Malformed wire data is not reported, as unmarshaling reports it.
+func scanOneofs(violations []*violation, path string, messageDescriptor protoreflect.MessageDescriptor, data []byte) []*violation {
+	oneofToLastField := make(map[protoreflect.FullName]protoreflect.FieldDescriptor)
+	fieldToCount := make(map[protoreflect.FieldNumber]int)
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return violations
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return violations
+		}
+		value := data[:n]
+		data = data[n:]
+		field := messageDescriptor.Fields().ByNumber(number)
+		if field == nil {
+			continue
+		}
+		fieldPath := joinPath(path, fieldName(field))
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			if lastField, ok := oneofToLastField[oneof.FullName()]; ok && lastField != field {
+				violations = append(
+					violations,
+					&violation{
+						Path:    fieldPath,
+						Message: fmt.Sprintf("set after field %s of the same oneof %s", lastField.Name(), oneof.Name()),
+					},
+				)
+			}
+			oneofToLastField[oneof.FullName()] = field
+		}
+		switch {
+		case field.IsMap():
+			if wireType == protowire.BytesType {
+				violations = scanMapEntryOneofs(violations, fieldPath, field, value)
+			}
+		case field.Kind() == protoreflect.MessageKind && wireType == protowire.BytesType:
+			if field.IsList() {
+				fieldPath = fmt.Sprintf("%s[%d]", fieldPath, fieldToCount[number])
+				fieldToCount[number]++
+			}
+			nested, _ := protowire.ConsumeBytes(value)
+			violations = scanOneofs(violations, fieldPath, field.Message(), nested)
+		case field.Kind() == protoreflect.GroupKind && wireType == protowire.StartGroupType:
+			if field.IsList() {
+				fieldPath = fmt.Sprintf("%s[%d]", fieldPath, fieldToCount[number])
+				fieldToCount[number]++
+			}
+			nested, _ := protowire.ConsumeGroup(number, value)
+			violations = scanOneofs(violations, fieldPath, field.Message(), nested)
+		}
+	}
+	return violations
+}
+
+func scanMapEntryOneofs(violations []*violation, path string, field protoreflect.FieldDescriptor, value []byte) []*violation {
+	if field.MapValue().Kind() != protoreflect.MessageKind {
+		return violations
+	}
+	entryData, _ := protowire.ConsumeBytes(value)
+	entry := dynamicpb.NewMessage(field.Message())
+	if err := (proto.UnmarshalOptions{AllowPartial: true}).Unmarshal(entryData, entry); err != nil {
+		return violations
+	}
+	entryPath := fmt.Sprintf("%s[%s]", path, strconv.Quote(entry.Get(field.MapKey()).MapKey().String()))
+	// the value is the last occurrence of the value field of the entry
+	var valueData []byte
+	for len(entryData) > 0 {
+		number, wireType, n := protowire.ConsumeTag(entryData)
+		if n < 0 {
+			return violations
+		}
+		entryData = entryData[n:]
+		n = protowire.ConsumeFieldValue(number, wireType, entryData)
+		if n < 0 {
+			return violations
+		}
+		if number == field.MapValue().Number() && wireType == protowire.BytesType {
+			valueData, _ = protowire.ConsumeBytes(entryData[:n])
+		}
+		entryData = entryData[n:]
+	}
+	return scanOneofs(violations, entryPath, field.MapValue().Message(), valueData)
+}
+
+func walkMessage(violations []*violation, path string, message protoreflect.Message) []*violation {
+	if len(message.GetUnknown()) > 0 {
+		violations = append(violations, &violation{Path: path, Message: "unknown fields present"})
+	}
+	fields := message.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Cardinality() == protoreflect.Required && !message.Has(field) {
+			violations = append(violations, &violation{Path: joinPath(path, string(field.Name())), Message: "required field not set"})
+		}
+	}
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		violations = walkField(violations, joinPath(path, fieldName(field)), field, value)
+		return true
+	})
+	return violations
+}
+
+func walkField(violations []*violation, path string, field protoreflect.FieldDescriptor, value protoreflect.Value) []*violation {
+	switch {
+	case field.IsMap():
+		value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			violations = walkValue(violations, fmt.Sprintf("%s[%s]", path, strconv.Quote(key.String())), field.MapValue(), value)
+			return true
+		})
+	case field.IsList():
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			violations = walkValue(violations, fmt.Sprintf("%s[%d]", path, i), field, list.Get(i))
+		}
+	default:
+		violations = walkValue(violations, path, field, value)
+	}
+	return violations
+}
+
+func walkValue(violations []*violation, path string, field protoreflect.FieldDescriptor, value protoreflect.Value) []*violation {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return walkMessage(violations, path, value.Message())
+	case protoreflect.StringKind:
+		// proto3 strings are validated on unmarshal, but proto2 strings are not
+		if !utf8.ValidString(value.String()) {
+			return append(violations, &violation{Path: path, Message: "invalid UTF-8 in string field"})
+		}
+	}
+	return violations
+}
+
+func fieldName(field protoreflect.FieldDescriptor) string {
+	if field.IsExtension() {
+		return "[" + string(field.FullName()) + "]"
+	}
+	return string(field.Name())
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appflag"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	inputFlagName       = "input"
+	errorFormatFlagName = "error-format"
+)
+
+// NewCommand returns a new Command
+func NewCommand(use string, builder appflag.Builder) *appcmd.Command {
+	controller := newController()
+	return &appcmd.Command{
+		Use:   use,
+		Short: "Verify the internal consistency of an image or FileDescriptorSet.",
+		Long: `This is useful for images and FileDescriptorSets produced by other tools, which otherwise
+may fail to be read with errors that do not say which file or element is the problem.
+
+The following problems are reported, each with its own type:
+
+  INVALID_FILE              The file cannot be unmarshaled, or has an invalid path.
+  DUPLICATE_FILE            The file is contained more than once.
+  UNRESOLVED_IMPORT         An import of the file is not contained in the image.
+  UNRESOLVED_TYPE           A type referenced by a field or RPC is not defined in the file or its imports.
+  UNRESOLVED_OPTION         A custom option is set that is not defined by any extension in the image.
+  INVALID_SOURCE_CODE_INFO  A source code info location does not refer to an element of the file.
+  INVALID_IMAGE             Any other problem that prevents the image from being read.
+
+Problems are printed to stdout with the location from the source code info if available,
+and the exit code is 1 if there are any. Images built with --exclude-imports are expected
+to have unresolved imports and types.`,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container applog.Container) error {
+				return controller.Run(ctx, container)
+			},
+		),
+		BindFlags: controller.Bind,
+	}
+}
+
+func newController() *controller {
+	return &controller{}
+}
+
+type controller struct {
+	input             string
+	errorFormat       string
+	allowInsecureHTTP bool
+	keepTemp          bool
+	noCache           bool
+	offline           bool
+	tlsFlags          internal.TLSFlags
+}
+
+func (c *controller) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(
+		&c.input,
+		inputFlagName,
+		"i",
+		"",
+		fmt.Sprintf(
+			`Required. The image or FileDescriptorSet to verify. Must be one of format %s.`,
+			buffetch.ImageFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&c.errorFormat,
+		errorFormatFlagName,
+		"",
+		fmt.Sprintf(
+			`The format for problems, printed to stdout. Must be one of %s. %s`,
+			stringutil.SliceToString(bufanalysis.AllFormatStringsWithAliases),
+			internal.ErrorFormatDefaultUsage,
+		),
+	)
+	internal.BindAllowInsecureHTTP(flagSet, &c.allowInsecureHTTP)
+	internal.BindKeepTemp(flagSet, &c.keepTemp)
+	internal.BindNoCache(flagSet, &c.noCache)
+	internal.BindOffline(flagSet, &c.offline)
+	internal.BindTLS(flagSet, &c.tlsFlags)
+}
+
+func (c *controller) Run(ctx context.Context, container applog.Container) error {
+	if c.input == "" {
+		return fmt.Errorf("--%s is required", inputFlagName)
+	}
+	tlsConfig, err := internal.NewTLSConfig(container, c.tlsFlags)
+	if err != nil {
+		return err
+	}
+	fileAnnotations, err := internal.NewBufwireImageReader(
+		container.Logger(),
+		inputFlagName,
+		internal.FetchOptions{
+			AllowInsecureHTTP: c.allowInsecureHTTP,
+			KeepTemp:          c.keepTemp,
+			NoCache:           c.noCache,
+			Offline:           c.offline,
+			TLSConfig:         tlsConfig,
+		},
+	).VerifyImage(
+		ctx,
+		container,
+		c.input,
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stdout(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, c.errorFormat),
+		); err != nil {
+			return err
+		}
+		return errors.New("")
+	}
+	return nil
+}
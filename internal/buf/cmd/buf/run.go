@@ -15,33 +15,115 @@
 package buf
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/buffix"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/buf/bufwork"
 	"github.com/bufbuild/buf/internal/buf/cmd/internal"
+	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/diff"
+	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/interrupt"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/bufbuild/buf/internal/pkg/thread"
+	"github.com/bufbuild/buf/internal/pkg/watch"
+	"go.uber.org/zap"
+)
+
+const (
+	// clearScreen moves the cursor to the top left and clears the screen.
+	clearScreen = "\033[H\033[2J"
+	// maxLintFixPasses is the maximum number of times that lint failures are
+	// fixed with --fix before the remaining failures are printed.
+	maxLintFixPasses = 10
+
+	// defaultErrorExitCode is the exit code for errors that do not have
+	// a more specific exit code.
+	defaultErrorExitCode = 1
+	// configErrorExitCode is the exit code for errors reading or validating
+	// the configuration.
+	configErrorExitCode = 2
+	// fetchErrorExitCode is the exit code for errors fetching an input or
+	// its dependencies.
+	fetchErrorExitCode = 3
+	// compileErrorExitCode is the exit code if the input does not compile.
+	compileErrorExitCode = 4
+	// lintFailureExitCode is the exit code if there are lint failures.
+	lintFailureExitCode = 5
+	// breakingFailureExitCode is the exit code if there are breaking change failures.
+	breakingFailureExitCode = 6
 )
 
 func imageBuild(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
 	if flags.Output == "" {
 		return fmt.Errorf("--%s is required", imageBuildOutputFlagName)
 	}
+	if flags.ExcludeSourceInfo && flags.IncludeSourceInfo {
+		return errors.New("cannot set both --exclude-source-info and --include-source-info")
+	}
+	var envReaderOptions []bufwire.EnvReaderOption
+	if !flags.IncludeSourceInfo {
+		envReaderOptions = append(
+			envReaderOptions,
+			bufwire.EnvReaderWithConfigExcludeSourceCodeInfo(
+				func(config *bufconfig.Config) bool {
+					return config.SourceInfo.ExcludeForImageBuild
+				},
+			),
+		)
+	}
+	if flags.Partial {
+		envReaderOptions = append(envReaderOptions, bufwire.EnvReaderWithPartialBuild())
+	}
+	envReaderOptions = append(envReaderOptions, newBuildPhaseEnvReaderOptions(flags)...)
+	envReaderOptions = append(envReaderOptions, bufwire.EnvReaderWithExcludeExternalFilePaths(flags.ExcludePaths))
+	sourceArchive := bufwire.IsSourceArchiveValue(ctx, container.Logger(), flags.Output)
+	if sourceArchive {
+		if flags.AsFileDescriptorSet {
+			return fmt.Errorf("cannot set --as-file-descriptor-set when --%s is a tar archive", imageBuildOutputFlagName)
+		}
+		envReaderOptions = append(envReaderOptions, bufwire.EnvReaderWithSourceReadBucket())
+	}
+	configFlagName, config, err := getConfigOverride(flags, imageBuildConfigFlagName)
+	if err != nil {
+		return err
+	}
+	fetchOptions, err := newFetchOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	// must be source only
 	env, fileAnnotations, err := internal.NewBufwireEnvReader(
 		container.Logger(),
 		imageBuildInputFlagName,
-		imageBuildConfigFlagName,
-		// must be source only
+		configFlagName,
+		fetchOptions,
+		envReaderOptions...,
 	).GetSourceEnv(
 		ctx,
 		container,
 		flags.Input,
-		flags.Config,
+		config,
 		flags.Files,
 		false,
 		flags.ExcludeSourceInfo,
@@ -54,7 +136,7 @@ func imageBuild(ctx context.Context, container applog.Container, flags *flags) (
 		if err := bufanalysis.PrintFileAnnotations(
 			container.Stderr(),
 			fileAnnotations,
-			flags.ErrorFormat,
+			internal.GetErrorFormat(container, flags.ErrorFormat),
 		); err != nil {
 			return err
 		}
@@ -64,18 +146,51 @@ func imageBuild(ctx context.Context, container applog.Container, flags *flags) (
 		// we could put the FileAnnotations in this error, but in general with
 		// linting/breaking change detection we actually print them to stdout
 		// so doing this here is consistent with lint/breaking change detection
-		return errors.New("")
+		retErr = newFailureError(compileErrorExitCode)
+		// with a partial build, we still output the image of the files that compiled
+		if env == nil {
+			return retErr
+		}
 	}
-	return internal.NewBufwireImageWriter(
+	image := env.Image()
+	if len(flags.Types) > 0 {
+		image, err = bufcore.ImageWithOnlyTypes(image, flags.Types)
+		if err != nil {
+			return err
+		}
+	}
+	imageWriterOptions, err := newImageWriterOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	imageWriter := internal.NewBufwireImageWriter(
 		container.Logger(),
-	).PutImage(
+		imageWriterOptions...,
+	)
+	if sourceArchive {
+		if err := imageWriter.PutSourceArchive(
+			ctx,
+			container,
+			flags.Output,
+			image,
+			env.SourceReadBucket(),
+			flags.ExcludeImports,
+		); err != nil {
+			return err
+		}
+		return retErr
+	}
+	if err := imageWriter.PutImage(
 		ctx,
 		container,
 		flags.Output,
-		env.Image(),
+		image,
 		flags.AsFileDescriptorSet,
 		flags.ExcludeImports,
-	)
+	); err != nil {
+		return err
+	}
+	return retErr
 }
 
 func imageConvert(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
@@ -83,9 +198,30 @@ func imageConvert(ctx context.Context, container applog.Container, flags *flags)
 	if flags.Output == "" {
 		return fmt.Errorf("--%s is required", imageBuildOutputFlagName)
 	}
+	if flags.SourceInfoFrom != "" && flags.ExcludeSourceInfo {
+		return fmt.Errorf("cannot set both --%s and --exclude-source-info", imageConvertSourceInfoFromFlagName)
+	}
+	jsonUnmarshalerOptions, err := internal.NewJSONUnmarshalerOptions(flags.JSON)
+	if err != nil {
+		return err
+	}
+	fetchOptions, err := newFetchOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	imageReaderOptions := []bufwire.ImageReaderOption{
+		bufwire.ImageReaderWithJSONUnmarshalerOptions(jsonUnmarshalerOptions...),
+		bufwire.ImageReaderWithFetchTimeout(flags.FetchTimeout),
+		bufwire.ImageReaderWithExcludeExternalFilePaths(flags.ExcludePaths),
+	}
+	if flags.StrictResolution {
+		imageReaderOptions = append(imageReaderOptions, bufwire.ImageReaderWithStrictResolution())
+	}
 	image, err := internal.NewBufwireImageReader(
 		container.Logger(),
 		imageConvertInputFlagName,
+		fetchOptions,
+		imageReaderOptions...,
 	).GetImage(
 		ctx,
 		container,
@@ -97,8 +233,25 @@ func imageConvert(ctx context.Context, container applog.Container, flags *flags)
 	if err != nil {
 		return err
 	}
+	if flags.SourceInfoFrom != "" {
+		image, err = imageWithSourceInfoFrom(ctx, container, flags, fetchOptions, image)
+		if err != nil {
+			return err
+		}
+	}
+	if len(flags.Types) > 0 {
+		image, err = bufcore.ImageWithOnlyTypes(image, flags.Types)
+		if err != nil {
+			return err
+		}
+	}
+	imageWriterOptions, err := newImageWriterOptions(container, flags)
+	if err != nil {
+		return err
+	}
 	return internal.NewBufwireImageWriter(
 		container.Logger(),
+		imageWriterOptions...,
 	).PutImage(
 		ctx,
 		container,
@@ -109,137 +262,523 @@ func imageConvert(ctx context.Context, container applog.Container, flags *flags)
 	)
 }
 
-func checkLint(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
-	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+// imageWithSourceInfoFrom builds the source at --source-info-from and attaches
+// its SourceCodeInfo to the image.
+func imageWithSourceInfoFrom(
+	ctx context.Context,
+	container applog.Container,
+	flags *flags,
+	fetchOptions internal.FetchOptions,
+	image bufcore.Image,
+) (bufcore.Image, error) {
+	sourceEnv, fileAnnotations, err := internal.NewBufwireEnvReader(
 		container.Logger(),
-		checkLintInputFlagName,
-		checkLintConfigFlagName,
-	).GetEnv(
+		imageConvertSourceInfoFromFlagName,
+		"",
+		fetchOptions,
+		newBuildPhaseEnvReaderOptions(flags)...,
+	).GetSourceEnv(
 		ctx,
 		container,
-		flags.Input,
-		flags.Config,
-		flags.Files, // we filter checks for files
-		false,       // input files must exist
-		false,       // we must include source info for linting
+		flags.SourceInfoFrom,
+		"",
+		nil,
+		false,
+		false, // we need the source info
 	)
 	if err != nil {
+		return nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		// stderr since we do output to stdout potentially
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stderr(),
+			fileAnnotations,
+			"text",
+		); err != nil {
+			return nil, err
+		}
+		return nil, newFailureError(compileErrorExitCode)
+	}
+	image, err = bufcore.ImageWithSourceCodeInfo(image, sourceEnv.Image())
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %w", imageConvertSourceInfoFromFlagName, err)
+	}
+	return image, nil
+}
+
+func checkLint(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
+	files, ok, err := getCheckFiles(ctx, container, flags)
+	if err != nil || !ok {
 		return err
 	}
+	configFlagName, _, err := getConfigOverride(flags, checkLintConfigFlagName)
+	if err != nil {
+		return err
+	}
+	fetchOptions, err := newFetchOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	envReader := internal.NewBufwireEnvReader(
+		container.Logger(),
+		checkLintInputFlagName,
+		configFlagName,
+		fetchOptions,
+		append(
+			newBuildPhaseEnvReaderOptions(flags),
+			bufwire.EnvReaderWithExcludeExternalFilePaths(flags.ExcludePaths),
+		)...,
+	)
+	return runCheckLint(ctx, container, flags, envReader, files)
+}
+
+// runCheckLint runs the lint checks on the input read by envReader.
+func runCheckLint(
+	ctx context.Context,
+	container applog.Container,
+	flags *flags,
+	envReader bufwire.EnvReader,
+	files []string,
+) error {
+	if flags.DryRun && !flags.Fix {
+		return fmt.Errorf("cannot set --%s without --%s", checkLintDryRunFlagName, checkLintFixFlagName)
+	}
+	if flags.Fix {
+		if err := checkLintFixInput(ctx, container, flags); err != nil {
+			return err
+		}
+	}
+	var config *buflint.Config
+	var fileAnnotations []bufanalysis.FileAnnotation
+	// with --dry-run, the files are not edited, so the failures that would
+	// be fixed still fail the command
+	var dryRunFailed bool
+	// fixes can overlap, or only pass once other files are fixed, so we
+	// check again after each fix until there is nothing left to fix
+	for pass := 0; ; pass++ {
+		var err error
+		config, fileAnnotations, err = getLintFileAnnotations(ctx, container, flags, envReader, files)
+		if err != nil {
+			return err
+		}
+		if !flags.Fix || pass == maxLintFixPasses {
+			break
+		}
+		fixedFiles, unfixedFileAnnotations, err := buffix.Fix(fileAnnotations)
+		if err != nil {
+			return err
+		}
+		if len(fixedFiles) == 0 {
+			break
+		}
+		if flags.DryRun {
+			if err := printLintFixDiff(ctx, container, fixedFiles); err != nil {
+				return err
+			}
+			dryRunFailed = len(fileAnnotations) > 0
+			if !flags.WarningsAsErrors {
+				failureFileAnnotations, _ := buflint.SplitWarnings(config, fileAnnotations)
+				dryRunFailed = len(failureFileAnnotations) > 0
+			}
+			fileAnnotations = unfixedFileAnnotations
+			break
+		}
+		if err := writeLintFixes(fixedFiles); err != nil {
+			return err
+		}
+	}
+	fileAnnotations, err := getBaselineFileAnnotations(flags, fileAnnotations)
+	if err != nil {
+		return err
+	}
+	checkers, err := config.GetCheckers()
+	if err != nil {
+		return err
+	}
+	printOptions := append(
+		getPrintOptions(flags),
+		bufanalysis.PrintWithRules(getCheckerRules(checkers, buflint.GetDoc)...),
+	)
+	if !flags.WarningsAsErrors {
+		var warningFileAnnotations []bufanalysis.FileAnnotation
+		fileAnnotations, warningFileAnnotations = buflint.SplitWarnings(config, fileAnnotations)
+		// warnings are printed to stderr so that stdout only has the failures
+		if len(warningFileAnnotations) > 0 {
+			if err := buflint.PrintFileAnnotations(
+				container.Stderr(),
+				warningFileAnnotations,
+				internal.GetErrorFormat(container, flags.ErrorFormat),
+				printOptions...,
+			); err != nil {
+				return err
+			}
+		}
+	}
 	if len(fileAnnotations) > 0 {
-		formatString := flags.ErrorFormat
+		if err := buflint.PrintFileAnnotations(
+			container.Stdout(),
+			fileAnnotations,
+			internal.GetErrorFormat(container, flags.ErrorFormat),
+			printOptions...,
+		); err != nil {
+			return err
+		}
+		return newFailureError(lintFailureExitCode)
+	}
+	if dryRunFailed {
+		return newFailureError(lintFailureExitCode)
+	}
+	return nil
+}
+
+// getPrintOptions returns the PrintOptions for the check failures.
+func getPrintOptions(flags *flags) []bufanalysis.PrintOption {
+	var printOptions []bufanalysis.PrintOption
+	if flags.GroupByFile {
+		printOptions = append(printOptions, bufanalysis.PrintWithGroupByFile())
+	}
+	return printOptions
+}
+
+// getCheckerRules returns the rules for the checkers, with the docs returned
+// by getDoc as help, for the error formats that include rule metadata.
+func getCheckerRules(checkers []bufcheck.Checker, getDoc func(string) (*bufcheck.Doc, bool)) []bufanalysis.Rule {
+	rules := make([]bufanalysis.Rule, len(checkers))
+	for i, checker := range checkers {
+		rules[i] = bufanalysis.Rule{
+			ID:      checker.ID(),
+			Purpose: checker.Purpose(),
+		}
+		if doc, ok := getDoc(checker.ID()); ok {
+			rules[i].Help = getCheckerDocMarkdown(doc)
+		}
+	}
+	return rules
+}
+
+// getCheckerDocMarkdown returns the doc as Markdown, with the examples as
+// Protobuf code blocks.
+func getCheckerDocMarkdown(doc *bufcheck.Doc) string {
+	var builder strings.Builder
+	_, _ = builder.WriteString(doc.Rationale)
+	for _, example := range []struct {
+		name   string
+		source string
+	}{
+		{name: "Previous example", source: doc.PreviousExample},
+		{name: "Failing example", source: doc.FailingExample},
+		{name: "Passing example", source: doc.PassingExample},
+	} {
+		if example.source != "" {
+			_, _ = fmt.Fprintf(&builder, "\n\n%s:\n\n```proto\n%s\n```", example.name, example.source)
+		}
+	}
+	return builder.String()
+}
+
+// getLintFileAnnotations runs the lint checks on the input read by envReader,
+// and returns the lint config and the lint failures.
+//
+// If the input does not build, the build errors are printed, and an error
+// is returned.
+func getLintFileAnnotations(
+	ctx context.Context,
+	container applog.Container,
+	flags *flags,
+	envReader bufwire.EnvReader,
+	files []string,
+) (*buflint.Config, []bufanalysis.FileAnnotation, error) {
+	env, fileAnnotations, err := getCheckEnv(ctx, container, flags, envReader, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fileAnnotations) > 0 {
+		formatString := internal.GetErrorFormat(container, flags.ErrorFormat)
 		if formatString == "config-ignore-yaml" {
 			formatString = "text"
 		}
-		if err := bufanalysis.PrintFileAnnotations(container.Stdout(), fileAnnotations, formatString); err != nil {
-			return err
+		if err := bufanalysis.PrintFileAnnotations(container.Stdout(), fileAnnotations, formatString, getPrintOptions(flags)...); err != nil {
+			return nil, nil, err
 		}
-		return errors.New("")
+		return nil, nil, newFailureError(compileErrorExitCode)
 	}
+	checkCtx, cancel := withCheckTimeout(ctx, flags)
+	defer cancel()
 	fileAnnotations, err = internal.NewBuflintHandler(container.Logger()).Check(
-		ctx,
+		checkCtx,
 		env.Config().Lint,
 		bufcore.ImageWithoutImports(env.Image()),
 	)
+	if err != nil {
+		return nil, nil, newCheckTimeoutError(ctx, flags, err)
+	}
+	return env.Config().Lint, fileAnnotations, nil
+}
+
+// checkLintFixInput checks that the input is a local directory, as --fix
+// edits the files of the input.
+func checkLintFixInput(ctx context.Context, container applog.Container, flags *flags) error {
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, flags.Input)
 	if err != nil {
 		return err
 	}
-	if len(fileAnnotations) > 0 {
-		if err := buflint.PrintFileAnnotations(
-			container.Stdout(),
-			fileAnnotations,
-			flags.ErrorFormat,
-		); err != nil {
+	if sourceRef, ok := ref.(buffetch.SourceRef); !ok || sourceRef.LocalDirPath() == "" {
+		return fmt.Errorf("--%s requires the input to be a local directory", checkLintFixFlagName)
+	}
+	return nil
+}
+
+func printLintFixDiff(ctx context.Context, container applog.Container, fixedFiles []*buffix.File) error {
+	for _, fixedFile := range fixedFiles {
+		diffData, err := diff.Diff(
+			ctx,
+			fixedFile.Data,
+			fixedFile.FixedData,
+			fixedFile.ExternalPath+".orig",
+			fixedFile.ExternalPath,
+			false,
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := container.Stdout().Write(diffData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLintFixes(fixedFiles []*buffix.File) error {
+	for _, fixedFile := range fixedFiles {
+		fileInfo, err := os.Stat(fixedFile.ExternalPath)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fixedFile.ExternalPath, fixedFile.FixedData, fileInfo.Mode().Perm()); err != nil {
 			return err
 		}
-		return errors.New("")
 	}
 	return nil
 }
 
 func checkBreaking(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
-	if flags.AgainstInput == "" {
-		return fmt.Errorf("--%s is required", checkBreakingAgainstInputFlagName)
+	against, err := getCheckBreakingAgainst(flags)
+	if err != nil {
+		return err
 	}
-	env, fileAnnotations, err := internal.NewBufwireEnvReader(
+	files, ok, err := getCheckFiles(ctx, container, flags)
+	if err != nil || !ok {
+		return err
+	}
+	configFlagName, _, err := getConfigOverride(flags, checkBreakingConfigFlagName)
+	if err != nil {
+		return err
+	}
+	// shared so that the network limits apply across both inputs
+	fetchOptions, err := newFetchOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	envReader := internal.NewBufwireEnvReader(
 		container.Logger(),
 		checkBreakingInputFlagName,
-		checkBreakingConfigFlagName,
-	).GetEnv(
-		ctx,
-		container,
-		flags.Input,
-		flags.Config,
-		flags.Files, // we filter checks for files
-		false,       // files specified must exist on the main input
-		false,       // we must include source info for this side of the check
+		configFlagName,
+		fetchOptions,
+		append(
+			newBuildPhaseEnvReaderOptions(flags),
+			bufwire.EnvReaderWithExcludeExternalFilePaths(flags.ExcludePaths),
+			bufwire.EnvReaderWithConfigExcludeSourceCodeInfo(
+				func(config *bufconfig.Config) bool {
+					return config.SourceInfo.ExcludeForBreaking
+				},
+			),
+		)...,
 	)
-	if err != nil {
+	return runCheckBreaking(ctx, container, flags, envReader, fetchOptions, against, files)
+}
+
+// runCheckBreaking runs the breaking checks on the input read by envReader.
+func runCheckBreaking(
+	ctx context.Context,
+	container applog.Container,
+	flags *flags,
+	envReader bufwire.EnvReader,
+	fetchOptions internal.FetchOptions,
+	against *checkBreakingAgainst,
+	files []string,
+) error {
+	var env bufwire.Env
+	var fileAnnotations []bufanalysis.FileAnnotation
+	getEnv := func() error {
+		var err error
+		// we include source info for this side of the check unless the config excludes it
+		env, fileAnnotations, err = getCheckEnv(ctx, container, flags, envReader, files)
+		return err
+	}
+	againstEnvs := make([]bufwire.Env, len(against.values))
+	againstFileAnnotations := make([][]bufanalysis.FileAnnotation, len(against.values))
+	jobs := []func() error{getEnv}
+	for i, againstValue := range against.values {
+		i := i
+		againstValue := againstValue
+		jobs = append(
+			jobs,
+			func() error {
+				var err error
+				againstEnvs[i], againstFileAnnotations[i], err = internal.NewBufwireEnvReader(
+					container.Logger(),
+					against.flagName,
+					against.configFlagName,
+					fetchOptions,
+					// the excluded files are also excluded from the against input so
+					// that they are not reported as deleted
+					append(
+						newBuildPhaseEnvReaderOptions(flags),
+						bufwire.EnvReaderWithExcludeExternalFilePaths(flags.ExcludePaths),
+					)...,
+				).GetEnv(
+					ctx,
+					container,
+					againstValue,
+					against.config,
+					files, // we filter checks for files
+					true,  // files are allowed to not exist on the against input
+					true,  // no need to include source info for against
+				)
+				return err
+			},
+		)
+	}
+	// the inputs are independent, so fetch and build them concurrently,
+	// which matters most when some of them are remote
+	if err := thread.Parallelize(jobs...); err != nil {
 		return err
 	}
 	if len(fileAnnotations) > 0 {
 		if err := bufanalysis.PrintFileAnnotations(
 			container.Stdout(),
 			fileAnnotations,
-			flags.ErrorFormat,
+			internal.GetErrorFormat(container, flags.ErrorFormat),
+			getPrintOptions(flags)...,
 		); err != nil {
 			return err
 		}
-		return errors.New("")
+		return newFailureError(compileErrorExitCode)
 	}
 	image := env.Image()
 	if flags.ExcludeImports {
 		image = bufcore.ImageWithoutImports(image)
 	}
-
-	// TODO: this doesn't actually work because we're using the same file paths for both sides
-	// if the roots change, then we're torched
-	externalPaths := flags.Files
-	if flags.LimitToInputFiles {
-		files := image.Files()
-		// we know that the file descriptors have unique names from validation
-		externalPaths = make([]string, len(files))
-		for i, file := range files {
-			externalPaths[i] = file.ExternalPath()
+	for _, fileAnnotations := range againstFileAnnotations {
+		if len(fileAnnotations) > 0 {
+			if err := bufanalysis.PrintFileAnnotations(
+				container.Stdout(),
+				fileAnnotations,
+				internal.GetErrorFormat(container, flags.ErrorFormat),
+				getPrintOptions(flags)...,
+			); err != nil {
+				return err
+			}
+			return newFailureError(compileErrorExitCode)
 		}
 	}
-
-	againstEnv, fileAnnotations, err := internal.NewBufwireEnvReader(
-		container.Logger(),
-		checkBreakingAgainstInputFlagName,
-		checkBreakingAgainstConfigFlagName,
-	).GetEnv(
-		ctx,
-		container,
-		flags.AgainstInput,
-		flags.AgainstConfig,
-		externalPaths, // we filter checks for files
-		true,          // files are allowed to not exist on the against input
-		true,          // no need to include source info for against
-	)
+	checkCtx, cancel := withCheckTimeout(ctx, flags)
+	defer cancel()
+	againstToFileAnnotations := make([][]bufanalysis.FileAnnotation, len(againstEnvs))
+	for i, againstEnv := range againstEnvs {
+		againstImage := againstEnv.Image()
+		if flags.ExcludeImports {
+			againstImage = bufcore.ImageWithoutImports(againstImage)
+		}
+		if flags.LimitToInputFiles {
+			// the files are matched by root relative path, so that this works
+			// even if the two inputs have different layouts, and files that are
+			// not in the input, such as moved files, are not reported as deleted
+			files := image.Files()
+			paths := make([]string, len(files))
+			for i, file := range files {
+				paths[i] = file.Path()
+			}
+			var err error
+			againstImage, err = bufcore.ImageWithOnlyPathsAllowNotExist(againstImage, paths)
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		againstToFileAnnotations[i], err = internal.NewBufbreakingHandler(container.Logger()).Check(
+			checkCtx,
+			env.Config().Breaking,
+			againstImage,
+			image,
+		)
+		if err != nil {
+			return newCheckTimeoutError(ctx, flags, err)
+		}
+	}
+	fileAnnotations, fileAnnotationToAgainstValues := mergeCheckBreakingFileAnnotations(against.values, againstToFileAnnotations)
+	fileAnnotations, err := getBaselineFileAnnotations(flags, fileAnnotations)
 	if err != nil {
 		return err
 	}
+	if len(against.values) > 1 {
+		fileAnnotations = labelCheckBreakingFileAnnotations(fileAnnotations, fileAnnotationToAgainstValues)
+	}
 	if len(fileAnnotations) > 0 {
+		checkers, err := env.Config().Breaking.GetCheckers()
+		if err != nil {
+			return err
+		}
 		if err := bufanalysis.PrintFileAnnotations(
 			container.Stdout(),
 			fileAnnotations,
-			flags.ErrorFormat,
+			internal.GetErrorFormat(container, flags.ErrorFormat),
+			append(
+				getPrintOptions(flags),
+				bufanalysis.PrintWithRules(getCheckerRules(checkers, bufbreaking.GetDoc)...),
+			)...,
 		); err != nil {
 			return err
 		}
-		return errors.New("")
+		return newFailureError(breakingFailureExitCode)
 	}
-	againstImage := againstEnv.Image()
-	if flags.ExcludeImports {
-		againstImage = bufcore.ImageWithoutImports(againstImage)
+	return nil
+}
+
+func checkAll(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
+	against, err := getCheckBreakingAgainst(flags)
+	if err != nil {
+		return err
 	}
-	fileAnnotations, err = internal.NewBufbreakingHandler(container.Logger()).Check(
-		ctx,
-		env.Config().Breaking,
-		againstImage,
-		image,
+	files, ok, err := getCheckFiles(ctx, container, flags)
+	if err != nil || !ok {
+		return err
+	}
+	configFlagName, _, err := getConfigOverride(flags, checkAllConfigFlagName)
+	if err != nil {
+		return err
+	}
+	fetchOptions, err := newFetchOptions(container, flags)
+	if err != nil {
+		return err
+	}
+	// the input is built once, with source info as lint requires it, and
+	// the result is shared by the lint and breaking checks
+	envReader := bufwire.NewSharedEnvReader(
+		internal.NewBufwireEnvReader(
+			container.Logger(),
+			checkAllInputFlagName,
+			configFlagName,
+			fetchOptions,
+			append(
+				newBuildPhaseEnvReaderOptions(flags),
+				bufwire.EnvReaderWithExcludeExternalFilePaths(flags.ExcludePaths),
+			)...,
+		),
 	)
+	// build errors are printed once here instead of by each check
+	_, fileAnnotations, err := getCheckEnv(ctx, container, flags, envReader, files)
 	if err != nil {
 		return err
 	}
@@ -247,13 +786,219 @@ func checkBreaking(ctx context.Context, container applog.Container, flags *flags
 		if err := bufanalysis.PrintFileAnnotations(
 			container.Stdout(),
 			fileAnnotations,
-			flags.ErrorFormat,
+			internal.GetErrorFormat(container, flags.ErrorFormat),
+			getPrintOptions(flags)...,
 		); err != nil {
 			return err
 		}
-		return errors.New("")
+		return newFailureError(compileErrorExitCode)
+	}
+	// the breaking checks still run if there are lint failures, so that
+	// all failures are printed
+	lintErr := runCheckLint(ctx, container, flags, envReader, files)
+	if lintErr != nil && lintErr.Error() != "" {
+		return lintErr
+	}
+	if err := runCheckBreaking(ctx, container, flags, envReader, fetchOptions, against, files); err != nil {
+		return err
+	}
+	return lintErr
+}
+
+// getBaselineFileAnnotations returns the file annotations that are not in the
+// baseline file of --baseline.
+//
+// With --write-baseline, the file annotations are written to the baseline
+// file instead, and none are returned.
+func getBaselineFileAnnotations(
+	flags *flags,
+	fileAnnotations []bufanalysis.FileAnnotation,
+) ([]bufanalysis.FileAnnotation, error) {
+	if flags.WriteBaseline != "" {
+		if flags.Baseline != "" {
+			return nil, fmt.Errorf("cannot set both --%s and --%s", baselineFlagName, writeBaselineFlagName)
+		}
+		buffer := bytes.NewBuffer(nil)
+		if err := bufanalysis.WriteBaseline(buffer, fileAnnotations); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(flags.WriteBaseline, buffer.Bytes(), 0644); err != nil {
+			return nil, fmt.Errorf("--%s: %v", writeBaselineFlagName, err)
+		}
+		return nil, nil
+	}
+	if flags.Baseline == "" {
+		return fileAnnotations, nil
+	}
+	data, err := ioutil.ReadFile(flags.Baseline)
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", baselineFlagName, err)
+	}
+	baseline, err := bufanalysis.ReadBaseline(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("--%s: %v", baselineFlagName, err)
+	}
+	return baseline.Filter(fileAnnotations), nil
+}
+
+// getCheckEnv gets the Env of the input to check.
+//
+// All checks use the same arguments so that the Env can be shared
+// with a shared EnvReader.
+func getCheckEnv(
+	ctx context.Context,
+	container applog.Container,
+	flags *flags,
+	envReader bufwire.EnvReader,
+	files []string,
+) (bufwire.Env, []bufanalysis.FileAnnotation, error) {
+	// the config flags of all checks have the same name
+	_, config, err := getConfigOverride(flags, checkAllConfigFlagName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envReader.GetEnv(
+		ctx,
+		container,
+		flags.Input,
+		config,
+		files,                        // we filter checks for files
+		flags.PathsFromGitDiff != "", // changed files may be outside of the roots
+		false,                        // source info is only excluded if the EnvReader excludes it
+	)
+}
+
+// checkBreakingAgainst is the against inputs of the breaking checks.
+type checkBreakingAgainst struct {
+	flagName       string
+	values         []string
+	configFlagName string
+	config         string
+}
+
+// getCheckBreakingAgainst gets the against inputs from the flags.
+func getCheckBreakingAgainst(flags *flags) (*checkBreakingAgainst, error) {
+	againstFlagName := checkBreakingAgainstFlagName
+	var againstValues []string
+	for _, against := range flags.Against {
+		if against != "" {
+			againstValues = append(againstValues, against)
+		}
+	}
+	if flags.AgainstInput != "" {
+		if len(againstValues) > 0 {
+			return nil, fmt.Errorf("cannot set both --%s and --%s", checkBreakingAgainstFlagName, checkBreakingAgainstInputFlagName)
+		}
+		againstFlagName = checkBreakingAgainstInputFlagName
+		againstValues = []string{flags.AgainstInput}
+	}
+	if len(againstValues) == 0 {
+		return nil, fmt.Errorf("--%s is required", checkBreakingAgainstFlagName)
+	}
+	againstConfigFlagName, againstConfig, err := getAliasedFlag(
+		checkBreakingAgainstConfigFlagName,
+		flags.AgainstConfig,
+		checkBreakingAgainstInputConfigFlagName,
+		flags.AgainstInputConfig,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &checkBreakingAgainst{
+		flagName:       againstFlagName,
+		values:         againstValues,
+		configFlagName: againstConfigFlagName,
+		config:         againstConfig,
+	}, nil
+}
+
+// mergeCheckBreakingFileAnnotations merges the file annotations of the checks
+// against each of the against values, which are in the same order.
+//
+// Equal file annotations are merged into one, and the against values that
+// each merged file annotation was found for are returned.
+func mergeCheckBreakingFileAnnotations(
+	againstValues []string,
+	againstToFileAnnotations [][]bufanalysis.FileAnnotation,
+) ([]bufanalysis.FileAnnotation, map[bufanalysis.FileAnnotation][]string) {
+	if len(againstToFileAnnotations) == 1 {
+		return againstToFileAnnotations[0], nil
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	keyToFileAnnotation := make(map[checkBreakingFileAnnotationKey]bufanalysis.FileAnnotation)
+	fileAnnotationToAgainstValues := make(map[bufanalysis.FileAnnotation][]string)
+	for i, againstFileAnnotations := range againstToFileAnnotations {
+		for _, fileAnnotation := range againstFileAnnotations {
+			key := newCheckBreakingFileAnnotationKey(fileAnnotation)
+			mergedFileAnnotation, ok := keyToFileAnnotation[key]
+			if !ok {
+				mergedFileAnnotation = fileAnnotation
+				keyToFileAnnotation[key] = mergedFileAnnotation
+				fileAnnotations = append(fileAnnotations, mergedFileAnnotation)
+			}
+			mergedAgainstValues := fileAnnotationToAgainstValues[mergedFileAnnotation]
+			// the same against value is only added once if a check has equal file annotations
+			if len(mergedAgainstValues) == 0 || mergedAgainstValues[len(mergedAgainstValues)-1] != againstValues[i] {
+				fileAnnotationToAgainstValues[mergedFileAnnotation] = append(mergedAgainstValues, againstValues[i])
+			}
+		}
+	}
+	bufanalysis.SortFileAnnotations(fileAnnotations)
+	return fileAnnotations, fileAnnotationToAgainstValues
+}
+
+// labelCheckBreakingFileAnnotations adds the against values that each file
+// annotation was found for to its message.
+func labelCheckBreakingFileAnnotations(
+	fileAnnotations []bufanalysis.FileAnnotation,
+	fileAnnotationToAgainstValues map[bufanalysis.FileAnnotation][]string,
+) []bufanalysis.FileAnnotation {
+	labeledFileAnnotations := make([]bufanalysis.FileAnnotation, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		labeledFileAnnotations[i] = bufanalysis.NewFileAnnotation(
+			fileAnnotation.FileInfo(),
+			fileAnnotation.StartLine(),
+			fileAnnotation.StartColumn(),
+			fileAnnotation.EndLine(),
+			fileAnnotation.EndColumn(),
+			fileAnnotation.Type(),
+			fmt.Sprintf(
+				"%s (against %s)",
+				fileAnnotation.Message(),
+				strings.Join(fileAnnotationToAgainstValues[fileAnnotation], ", "),
+			),
+			bufanalysis.FileAnnotationWithEdits(fileAnnotation.Edits()...),
+		)
+	}
+	return labeledFileAnnotations
+}
+
+// checkBreakingFileAnnotationKey is the key of equal file annotations of
+// checks against different against values.
+type checkBreakingFileAnnotationKey struct {
+	path        string
+	startLine   int
+	startColumn int
+	endLine     int
+	endColumn   int
+	typeString  string
+	message     string
+}
+
+func newCheckBreakingFileAnnotationKey(fileAnnotation bufanalysis.FileAnnotation) checkBreakingFileAnnotationKey {
+	var path string
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.Path()
+	}
+	return checkBreakingFileAnnotationKey{
+		path:        path,
+		startLine:   fileAnnotation.StartLine(),
+		startColumn: fileAnnotation.StartColumn(),
+		endLine:     fileAnnotation.EndLine(),
+		endColumn:   fileAnnotation.EndColumn(),
+		typeString:  fileAnnotation.Type(),
+		message:     fileAnnotation.Message(),
 	}
-	return nil
 }
 
 func checkLsLintCheckers(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
@@ -269,6 +1014,7 @@ func checkLsLintCheckers(ctx context.Context, container applog.Container, flags
 			container.Logger(),
 			"",
 			checkLsCheckersConfigFlagName,
+			internal.FetchOptions{},
 		).GetConfig(
 			ctx,
 			flags.Config,
@@ -285,6 +1031,7 @@ func checkLsLintCheckers(ctx context.Context, container applog.Container, flags
 		container.Stdout(),
 		checkers,
 		flags.Format,
+		getCheckPrintOptions(flags)...,
 	)
 }
 
@@ -301,6 +1048,7 @@ func checkLsBreakingCheckers(ctx context.Context, container applog.Container, fl
 			container.Logger(),
 			"",
 			checkLsCheckersConfigFlagName,
+			internal.FetchOptions{},
 		).GetConfig(
 			ctx,
 			flags.Config,
@@ -317,5 +1065,483 @@ func checkLsBreakingCheckers(ctx context.Context, container applog.Container, fl
 		container.Stdout(),
 		checkers,
 		flags.Format,
+		getCheckPrintOptions(flags)...,
 	)
 }
+
+func checkLsRules(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
+	lintCheckers, err := buflint.GetAllCheckers()
+	if err != nil {
+		return err
+	}
+	breakingCheckers, err := bufbreaking.GetAllCheckers()
+	if err != nil {
+		return err
+	}
+	if len(flags.CheckerCategories) > 0 {
+		// categories are specific to either lint or breaking
+		lintCategoryMap := stringutil.SliceToMap(buflint.GetAllCategories())
+		breakingCategoryMap := stringutil.SliceToMap(bufbreaking.GetAllCategories())
+		var lintCategories []string
+		var breakingCategories []string
+		for _, category := range flags.CheckerCategories {
+			_, isLint := lintCategoryMap[category]
+			_, isBreaking := breakingCategoryMap[category]
+			if !isLint && !isBreaking {
+				return fmt.Errorf("%q is not a known category", category)
+			}
+			if isLint {
+				lintCategories = append(lintCategories, category)
+			}
+			if isBreaking {
+				breakingCategories = append(breakingCategories, category)
+			}
+		}
+		lintCheckers = nil
+		if len(lintCategories) > 0 {
+			lintCheckers, err = buflint.GetAllCheckers(lintCategories...)
+			if err != nil {
+				return err
+			}
+		}
+		breakingCheckers = nil
+		if len(breakingCategories) > 0 {
+			breakingCheckers, err = bufbreaking.GetAllCheckers(breakingCategories...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return bufcheck.PrintRules(
+		container.Stdout(),
+		[]bufcheck.RuleSet{
+			{
+				Type:           "lint",
+				ConfigVersions: buflint.AllConfigVersions,
+				Checkers:       lintCheckers,
+			},
+			{
+				Type:           "breaking",
+				ConfigVersions: bufbreaking.AllConfigVersions,
+				Checkers:       breakingCheckers,
+			},
+		},
+		flags.Format,
+		getCheckPrintOptions(flags)...,
+	)
+}
+
+func checkExplain(ctx context.Context, container applog.Container, flags *flags) (retErr error) {
+	id := container.Arg(0)
+	lintCheckers, err := buflint.GetAllCheckers()
+	if err != nil {
+		return err
+	}
+	breakingCheckers, err := bufbreaking.GetAllCheckers()
+	if err != nil {
+		return err
+	}
+	for _, checker := range append(lintCheckers, breakingCheckers...) {
+		if checker.ID() == id {
+			// PrintWithDocs prints only the purpose of checkers without a Doc,
+			// which is not an explanation
+			if _, ok := getCheckerDoc(id); !ok {
+				return fmt.Errorf("no explanation available for checker ID: %q", id)
+			}
+			return bufcheck.PrintCheckers(
+				container.Stdout(),
+				[]bufcheck.Checker{checker},
+				"text",
+				bufcheck.PrintWithDocs(getCheckerDoc),
+			)
+		}
+	}
+	return fmt.Errorf("unknown checker ID: %q", id)
+}
+
+// getAllCheckerIDs gets the IDs of all lint and breaking checkers.
+//
+// Returns nil on error, as this is only used for completion.
+func getAllCheckerIDs() []string {
+	lintCheckers, err := buflint.GetAllCheckers()
+	if err != nil {
+		return nil
+	}
+	breakingCheckers, err := bufbreaking.GetAllCheckers()
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, checker := range append(lintCheckers, breakingCheckers...) {
+		ids = append(ids, checker.ID())
+	}
+	return stringutil.SliceToUniqueSortedSlice(ids)
+}
+
+// getAllCheckerCategories gets the categories of all lint and breaking checkers.
+func getAllCheckerCategories() []string {
+	return stringutil.SliceToUniqueSortedSlice(
+		append(buflint.GetAllCategories(), bufbreaking.GetAllCategories()...),
+	)
+}
+
+// getCheckPrintOptions returns the options to print checkers with.
+func getCheckPrintOptions(flags *flags) []bufcheck.PrintOption {
+	if !flags.CheckerExplain {
+		return nil
+	}
+	return []bufcheck.PrintOption{bufcheck.PrintWithDocs(getCheckerDoc)}
+}
+
+// getCheckerDoc gets the Doc for the lint or breaking checker with the given ID.
+func getCheckerDoc(id string) (*bufcheck.Doc, bool) {
+	if doc, ok := buflint.GetDoc(id); ok {
+		return doc, true
+	}
+	return bufbreaking.GetDoc(id)
+}
+
+// getCheckFiles returns the files to limit checks to.
+//
+// If --paths-from-git-diff is set, these are the .proto files that differ
+// from the ref, and if --paths-from-git-staged is set, these are the staged
+// .proto files. In both cases, false is returned if there are none, as there
+// is nothing to check. Otherwise, these are the --file paths.
+func getCheckFiles(ctx context.Context, container applog.Container, flags *flags) ([]string, bool, error) {
+	if flags.PathsFromGitDiff == "" && !flags.PathsFromGitStaged {
+		return flags.Files, true, nil
+	}
+	flagName := pathsFromGitDiffFlagName
+	if flags.PathsFromGitStaged {
+		if flags.PathsFromGitDiff != "" {
+			return nil, false, fmt.Errorf("cannot set both --%s and --%s", pathsFromGitDiffFlagName, pathsFromGitStagedFlagName)
+		}
+		flagName = pathsFromGitStagedFlagName
+	}
+	if len(flags.Files) > 0 {
+		return nil, false, fmt.Errorf("cannot set both --file and --%s", flagName)
+	}
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, flags.Input)
+	if err != nil {
+		return nil, false, err
+	}
+	sourceRef, ok := ref.(buffetch.SourceRef)
+	if !ok || sourceRef.LocalDirPath() == "" {
+		return nil, false, fmt.Errorf("--%s requires the input to be a local directory", flagName)
+	}
+	dirPath := normalpath.Unnormalize(sourceRef.LocalDirPath())
+	var paths []string
+	if flags.PathsFromGitStaged {
+		paths, err = git.StagedFilePaths(ctx, container, dirPath)
+	} else {
+		paths, err = git.ChangedFilePaths(ctx, container, dirPath, flags.PathsFromGitDiff)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var files []string
+	for _, path := range paths {
+		if normalpath.Ext(path) == ".proto" {
+			files = append(files, filepath.Join(dirPath, normalpath.Unnormalize(path)))
+		}
+	}
+	if len(files) == 0 {
+		if flags.PathsFromGitStaged {
+			container.Logger().Info("no .proto files are staged")
+		} else {
+			container.Logger().Info("no .proto files differ", zap.String("ref", flags.PathsFromGitDiff))
+		}
+		return nil, false, nil
+	}
+	return files, true, nil
+}
+
+// getConfigOverride gets the config override from the given config flag or
+// --config, and the name of the flag it was set with.
+func getConfigOverride(flags *flags, configFlagName string) (string, string, error) {
+	return getAliasedFlag(
+		configFlagName,
+		flags.Config,
+		configOverrideFlagName,
+		flags.ConfigOverride,
+	)
+}
+
+// getAliasedFlag returns the name and value of whichever of the flag and its
+// alias was set, so that errors refer to the flag that was used.
+func getAliasedFlag(flagName string, value string, aliasFlagName string, aliasValue string) (string, string, error) {
+	if value != "" && aliasValue != "" {
+		return "", "", fmt.Errorf("cannot set both --%s and --%s", flagName, aliasFlagName)
+	}
+	if aliasValue != "" {
+		return aliasFlagName, aliasValue, nil
+	}
+	return flagName, value, nil
+}
+
+func newImageWriterOptions(container app.EnvStdioContainer, flags *flags) ([]bufwire.ImageWriterOption, error) {
+	jsonMarshalerOptions, err := internal.NewJSONMarshalerOptions(flags.JSON)
+	if err != nil {
+		return nil, err
+	}
+	imageWriterOptions := []bufwire.ImageWriterOption{
+		bufwire.ImageWriterWithJSONMarshalerOptions(jsonMarshalerOptions...),
+	}
+	if flags.CompactSourceInfo {
+		imageWriterOptions = append(imageWriterOptions, bufwire.ImageWriterWithCompactSourceCodeInfo())
+	}
+	var sourceCodeInfoFilterOptions []bufcore.SourceCodeInfoFilterOption
+	if flags.SourceInfoOnlyComments {
+		sourceCodeInfoFilterOptions = append(sourceCodeInfoFilterOptions, bufcore.SourceCodeInfoFilterWithOnlyComments())
+	}
+	if flags.SourceInfoExcludeSpans {
+		sourceCodeInfoFilterOptions = append(sourceCodeInfoFilterOptions, bufcore.SourceCodeInfoFilterWithoutSpans())
+	}
+	if flags.SourceInfoExcludeDetachedComments {
+		sourceCodeInfoFilterOptions = append(sourceCodeInfoFilterOptions, bufcore.SourceCodeInfoFilterWithoutDetachedComments())
+	}
+	if len(sourceCodeInfoFilterOptions) > 0 {
+		if flags.ExcludeSourceInfo {
+			return nil, errors.New("cannot filter source info with --exclude-source-info")
+		}
+		imageWriterOptions = append(imageWriterOptions, bufwire.ImageWriterWithSourceCodeInfoFilterOptions(sourceCodeInfoFilterOptions...))
+	}
+	if flags.Digest {
+		if isStdoutOutput(flags.Output) {
+			return nil, fmt.Errorf("cannot use --%s when writing the image to stdout", digestFlagName)
+		}
+		imageWriterOptions = append(
+			imageWriterOptions,
+			bufwire.ImageWriterWithDigestFunc(
+				func(digest string) error {
+					_, err := fmt.Fprintln(container.Stdout(), digest)
+					return err
+				},
+			),
+		)
+	}
+	if !flags.Yes {
+		imageWriterOptions = append(
+			imageWriterOptions,
+			bufwire.ImageWriterWithOverwriteConfirmer(
+				func(path string) (bool, error) {
+					return app.Confirm(container, fmt.Sprintf("Overwrite %s?", path))
+				},
+			),
+		)
+	}
+	return imageWriterOptions, nil
+}
+
+// isStdoutOutput returns true if the output value writes to stdout.
+func isStdoutOutput(output string) bool {
+	path := output
+	if index := strings.Index(output, "#"); index != -1 {
+		path = output[:index]
+	}
+	return path == "-" || app.IsDevStdout(path)
+}
+
+// newBuildPhaseEnvReaderOptions returns the EnvReaderOptions for the
+// fetch and build timeouts, the build parallelism, and the reading of the
+// input and its config.
+func newBuildPhaseEnvReaderOptions(flags *flags) []bufwire.EnvReaderOption {
+	options := []bufwire.EnvReaderOption{
+		bufwire.EnvReaderWithFetchTimeout(flags.FetchTimeout),
+		bufwire.EnvReaderWithBuildTimeout(flags.BuildTimeout),
+		bufwire.EnvReaderWithBuildParallelism(flags.Parallelism),
+	}
+	if flags.StrictResolution {
+		options = append(options, bufwire.EnvReaderWithStrictResolution())
+	}
+	if flags.ConfigName != "" {
+		options = append(options, bufwire.EnvReaderWithConfigName(flags.ConfigName))
+	}
+	if flags.AllowPlugins {
+		options = append(options, bufwire.EnvReaderWithAllowPlugins())
+	}
+	return options
+}
+
+// withCheckTimeout returns a context for running checks that times out
+// after the check timeout, if set.
+func withCheckTimeout(ctx context.Context, flags *flags) (context.Context, context.CancelFunc) {
+	if flags.CheckTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, flags.CheckTimeout)
+}
+
+// newCheckTimeoutError returns an error that the checks timed out if err
+// is due to the check timeout and not the timeout of ctx.
+func newCheckTimeoutError(ctx context.Context, flags *flags, err error) error {
+	if flags.CheckTimeout <= 0 || ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("check timed out after %v: %w", flags.CheckTimeout, err)
+}
+
+// newFetchOptions returns new FetchOptions for the fetch flags.
+func newFetchOptions(container applog.Container, flags *flags) (internal.FetchOptions, error) {
+	tlsConfig, err := internal.NewTLSConfig(container, flags.TLS)
+	if err != nil {
+		return internal.FetchOptions{}, err
+	}
+	return internal.FetchOptions{
+		AllowInsecureHTTP: flags.AllowInsecureHTTP,
+		KeepTemp:          flags.KeepTemp,
+		NoCache:           flags.NoCache,
+		Offline:           flags.Offline,
+		NetworkLimiter:    newNetworkLimiter(flags),
+		TLSConfig:         tlsConfig,
+	}, nil
+}
+
+// newNetworkLimiter returns a new netlimit.Limiter for the network limit flags.
+//
+// Returns nil if no limits are set.
+func newNetworkLimiter(flags *flags) netlimit.Limiter {
+	if flags.MaxConcurrentFetches <= 0 && flags.FetchHostRate <= 0 {
+		return nil
+	}
+	return netlimit.NewLimiter(
+		netlimit.LimiterWithMaxConcurrent(flags.MaxConcurrentFetches),
+		netlimit.LimiterWithHostRate(flags.FetchHostRate),
+	)
+}
+
+// withErrorExitCode returns a run function that sets the exit code of the
+// error returned by f, see getErrorExitCode.
+func withErrorExitCode(
+	f func(context.Context, applog.Container, *flags) error,
+) func(context.Context, applog.Container, *flags) error {
+	return func(ctx context.Context, container applog.Container, flags *flags) error {
+		if flags.ErrorExitCode < 0 || flags.ErrorExitCode > 255 {
+			return fmt.Errorf("--%s must be between 0 and 255 but was %d", errorExitCodeFlagName, flags.ErrorExitCode)
+		}
+		err := f(ctx, container, flags)
+		if err == nil {
+			return nil
+		}
+		if flags.ErrorExitCode != 0 {
+			return app.WrapError(flags.ErrorExitCode, err)
+		}
+		return app.WrapError(getErrorExitCode(err), err)
+	}
+}
+
+// getErrorExitCode returns the exit code for the error.
+//
+// Errors that already have an exit code, such as check failures, keep it.
+func getErrorExitCode(err error) int {
+	switch {
+	case bufwire.IsConfigError(err):
+		return configErrorExitCode
+	case bufwire.IsFetchError(err):
+		return fetchErrorExitCode
+	default:
+		return app.GetExitCode(err)
+	}
+}
+
+// newFailureError returns an error with the exit code for failures that were
+// already printed, so no message is printed on exit.
+func newFailureError(exitCode int) error {
+	return app.WrapError(exitCode, errors.New(""))
+}
+
+// withDefaultInput returns a run function that sets the input to the
+// default input if the input was not given, see internal.GetDefaultInput.
+func withDefaultInput(
+	f func(context.Context, applog.Container, *flags) error,
+) func(context.Context, applog.Container, *flags) error {
+	return func(ctx context.Context, container applog.Container, flags *flags) error {
+		if flags.Input == "" {
+			input, err := internal.GetDefaultInput(container.Logger(), container)
+			if err != nil {
+				return err
+			}
+			flags.Input = input
+		}
+		return f(ctx, container, flags)
+	}
+}
+
+// withWatch returns a function that runs f, or if --watch is set, runs f
+// again each time a file of the input changes.
+func withWatch(
+	f func(context.Context, applog.Container, *flags) error,
+) func(context.Context, applog.Container, *flags) error {
+	return func(ctx context.Context, container applog.Container, flags *flags) error {
+		if !flags.Watch {
+			if flags.WatchClear {
+				return fmt.Errorf("cannot set --%s without --%s", watchClearFlagName, watchFlagName)
+			}
+			return f(ctx, container, flags)
+		}
+		watchPath, err := getWatchPath(ctx, container, flags.Input)
+		if err != nil {
+			return err
+		}
+		// the context of the command times out after --timeout, so we
+		// watch until interrupted and apply the timeout to each run instead
+		var runTimeout time.Duration
+		if deadline, ok := ctx.Deadline(); ok {
+			runTimeout = time.Until(deadline)
+		}
+		watchCtx, cancel := interrupt.WithCancel(context.Background())
+		defer cancel()
+		container.Logger().Info("watching for changes, press Ctrl+C to stop", zap.String("path", watchPath))
+		return watch.Run(
+			watchCtx,
+			[]string{watchPath},
+			func(ctx context.Context) {
+				if flags.WatchClear {
+					_, _ = container.Stdout().Write([]byte(clearScreen))
+				}
+				if runTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, runTimeout)
+					defer cancel()
+				}
+				// errors are printed the same way as when the command exits,
+				// but we keep watching
+				if err := f(ctx, container, flags); err != nil && err.Error() != "" {
+					_, _ = fmt.Fprintln(container.Stderr(), err.Error())
+				}
+			},
+			watch.RunWithFileFilter(
+				func(path string) bool {
+					if path == watchPath {
+						return true
+					}
+					switch filepath.Base(path) {
+					case bufconfig.ConfigFilePath, bufmod.LockFilePath, bufwork.ExternalConfigFilePath:
+						return true
+					default:
+						return filepath.Ext(path) == ".proto"
+					}
+				},
+			),
+		)
+	}
+}
+
+// getWatchPath returns the local path to watch for the input.
+func getWatchPath(ctx context.Context, container applog.Container, input string) (string, error) {
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	var watchPath string
+	switch t := ref.(type) {
+	case buffetch.ImageRef:
+		watchPath = t.LocalPath()
+	case buffetch.SourceRef:
+		watchPath = t.LocalDirPath()
+	}
+	if watchPath == "" {
+		return "", fmt.Errorf("--%s requires the input to be a local directory or image file", watchFlagName)
+	}
+	return normalpath.Unnormalize(watchPath), nil
+}
@@ -0,0 +1,363 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// persistentWorkerFlagName is the flag Bazel passes to tools that run as
+// persistent workers.
+//
+// See https://docs.bazel.build/versions/master/persistent-workers.html.
+const persistentWorkerFlagName = "--persistent_worker"
+
+// maxWorkRequestSize is the maximum size of a WorkRequest in bytes, so that
+// a malformed size does not allocate an arbitrary amount of memory.
+const maxWorkRequestSize = 64 << 20
+
+// workRequest is a blaze.worker.WorkRequest.
+//
+// Only the fields used by buf are decoded.
+type workRequest struct {
+	arguments []string
+	requestID int32
+	cancel    bool
+}
+
+// workResponse is a blaze.worker.WorkResponse.
+type workResponse struct {
+	exitCode     int32
+	output       string
+	requestID    int32
+	wasCancelled bool
+}
+
+// isPersistentWorker returns true if the args request the persistent worker mode.
+func isPersistentWorker(args []string) bool {
+	for _, arg := range args {
+		if arg == persistentWorkerFlagName {
+			return true
+		}
+	}
+	return false
+}
+
+// runPersistentWorker implements the Bazel persistent worker protocol.
+//
+// WorkRequests are read from stdin, and WorkResponses are written to stdout,
+// each as length-delimited binary messages. Each request is run in-process
+// with a new root command, so that the process does not have to start up
+// again for every action. Unless --no-cache is given, built files are kept
+// in memory by the build cache across requests, so files that are unchanged
+// are not read from disk or built again. The
+// output of each request, both stdout and stderr, is returned in the output
+// of the WorkResponse.
+//
+// Any args other than --persistent_worker that the worker was started with
+// are prepended to the arguments of each request.
+//
+// Requests are run one at a time in the order they are received, while
+// further requests are read. A cancel request stops the request with the
+// same request ID if it is running, or removes it if it is waiting to run,
+// and the request gets a WorkResponse with was_cancelled set. A cancel
+// request for a request that already has a WorkResponse is ignored, as
+// required by the protocol.
+func runPersistentWorker(
+	ctx context.Context,
+	container app.Container,
+	use string,
+	options ...RootCommandOption,
+) error {
+	var startupArgs []string
+	for _, arg := range app.Args(container)[1:] {
+		if arg != persistentWorkerFlagName {
+			startupArgs = append(startupArgs, arg)
+		}
+	}
+	doneC := make(chan struct{})
+	defer close(doneC)
+	requestC := make(chan *workRequest)
+	readErrC := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(container.Stdin())
+		for {
+			request, err := readWorkRequest(reader)
+			if err != nil {
+				readErrC <- err
+				return
+			}
+			select {
+			case requestC <- request:
+			case <-doneC:
+				return
+			}
+		}
+	}()
+
+	var readErr error
+	var queue []*workRequest
+	var running *runningWorkRequest
+	defer func() {
+		if running != nil {
+			running.cancel()
+		}
+	}()
+	// buffered so that a running request can complete if we return early
+	responseC := make(chan *workResponse, 1)
+	for {
+		if running == nil && len(queue) > 0 {
+			running = startWorkRequest(ctx, container, use, startupArgs, queue[0], responseC, options...)
+			queue = queue[1:]
+		}
+		if running == nil && readErrC == nil {
+			if readErr == io.EOF {
+				return nil
+			}
+			return readErr
+		}
+		select {
+		case request := <-requestC:
+			if !request.cancel {
+				queue = append(queue, request)
+				continue
+			}
+			if running != nil && running.requestID == request.requestID {
+				running.cancelled = true
+				running.cancel()
+				continue
+			}
+			for i, queued := range queue {
+				if queued.requestID == request.requestID {
+					queue = append(queue[:i], queue[i+1:]...)
+					if err := writeWorkResponse(
+						container.Stdout(),
+						&workResponse{
+							requestID:    request.requestID,
+							wasCancelled: true,
+						},
+					); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		case response := <-responseC:
+			running.cancel()
+			if running.cancelled {
+				response = &workResponse{
+					requestID:    response.requestID,
+					wasCancelled: true,
+				}
+			}
+			running = nil
+			if err := writeWorkResponse(container.Stdout(), response); err != nil {
+				return err
+			}
+		case readErr = <-readErrC:
+			// the requests that were already read are still run
+			readErrC = nil
+		}
+	}
+}
+
+// runningWorkRequest is a workRequest that has been started with startWorkRequest.
+type runningWorkRequest struct {
+	requestID int32
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// startWorkRequest runs the request in the background, and sends its
+// response to responseC when done.
+func startWorkRequest(
+	ctx context.Context,
+	container app.Container,
+	use string,
+	startupArgs []string,
+	request *workRequest,
+	responseC chan<- *workResponse,
+	options ...RootCommandOption,
+) *runningWorkRequest {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		responseC <- runWorkRequest(ctx, container, use, startupArgs, request, options...)
+	}()
+	return &runningWorkRequest{
+		requestID: request.requestID,
+		cancel:    cancel,
+	}
+}
+
+func runWorkRequest(
+	ctx context.Context,
+	container app.Container,
+	use string,
+	startupArgs []string,
+	request *workRequest,
+	options ...RootCommandOption,
+) *workResponse {
+	response := &workResponse{
+		requestID: request.requestID,
+	}
+	requestArgs, err := expandWorkRequestArgs(request.arguments)
+	if err != nil {
+		response.exitCode = 1
+		response.output = err.Error() + "\n"
+		return response
+	}
+	args := append([]string{use}, startupArgs...)
+	args = append(args, requestArgs...)
+	output := bytes.NewBuffer(nil)
+	runErr := appcmd.Run(
+		ctx,
+		app.NewContainer(
+			app.EnvironMap(container),
+			// stdin is used for the worker protocol, so it is never available to requests.
+			bytes.NewReader(nil),
+			output,
+			output,
+			args...,
+		),
+		newRootCommand(use, options...),
+	)
+	response.exitCode = int32(app.GetExitCode(runErr))
+	response.output = output.String()
+	return response
+}
+
+// expandWorkRequestArgs expands the @flagfile arguments Bazel uses for
+// params files, which contain one argument per line.
+func expandWorkRequestArgs(arguments []string) ([]string, error) {
+	var args []string
+	for _, argument := range arguments {
+		if !strings.HasPrefix(argument, "@") || strings.HasPrefix(argument, "@@") {
+			args = append(args, argument)
+			continue
+		}
+		data, err := ioutil.ReadFile(strings.TrimPrefix(argument, "@"))
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSuffix(line, "\r"); line != "" {
+				args = append(args, line)
+			}
+		}
+	}
+	return args, nil
+}
+
+func readWorkRequest(reader *bufio.Reader) (*workRequest, error) {
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxWorkRequestSize {
+		return nil, fmt.Errorf("WorkRequest of %d bytes exceeds the maximum of %d bytes", size, maxWorkRequestSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return unmarshalWorkRequest(data)
+}
+
+func unmarshalWorkRequest(data []byte) (*workRequest, error) {
+	request := &workRequest{}
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, newMalformedWorkRequestError(protowire.ParseError(n))
+		}
+		data = data[n:]
+		switch {
+		case number == 1 && wireType == protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, newMalformedWorkRequestError(protowire.ParseError(n))
+			}
+			request.arguments = append(request.arguments, string(value))
+			data = data[n:]
+		case number == 3 && wireType == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, newMalformedWorkRequestError(protowire.ParseError(n))
+			}
+			request.requestID = int32(value)
+			data = data[n:]
+		case number == 4 && wireType == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, newMalformedWorkRequestError(protowire.ParseError(n))
+			}
+			request.cancel = protowire.DecodeBool(value)
+			data = data[n:]
+		default:
+			// inputs, verbosity, sandbox_dir, and unknown fields
+			n := protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return nil, newMalformedWorkRequestError(protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return request, nil
+}
+
+func writeWorkResponse(writer io.Writer, response *workResponse) error {
+	data := marshalWorkResponse(response)
+	_, err := writer.Write(append(protowire.AppendVarint(nil, uint64(len(data))), data...))
+	return err
+}
+
+func marshalWorkResponse(response *workResponse) []byte {
+	var data []byte
+	if response.exitCode != 0 {
+		data = protowire.AppendTag(data, 1, protowire.VarintType)
+		data = protowire.AppendVarint(data, uint64(response.exitCode))
+	}
+	if response.output != "" {
+		data = protowire.AppendTag(data, 2, protowire.BytesType)
+		data = protowire.AppendString(data, response.output)
+	}
+	if response.requestID != 0 {
+		data = protowire.AppendTag(data, 3, protowire.VarintType)
+		data = protowire.AppendVarint(data, uint64(response.requestID))
+	}
+	if response.wasCancelled {
+		data = protowire.AppendTag(data, 4, protowire.VarintType)
+		data = protowire.AppendVarint(data, protowire.EncodeBool(true))
+	}
+	return data
+}
+
+func newMalformedWorkRequestError(err error) error {
+	return fmt.Errorf("malformed WorkRequest: %w", err)
+}
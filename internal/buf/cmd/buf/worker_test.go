@@ -0,0 +1,215 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPersistentWorker(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	paramsFilePath := filepath.Join(tempDirPath, "params")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			paramsFilePath,
+			[]byte("--input\n"+filepath.Join("testdata", "fail")+"\n"),
+			0644,
+		),
+	)
+	stdin := bytes.NewBuffer(nil)
+	writeTestWorkRequest(t, stdin, 1, "check", "lint", "--input", filepath.Join("testdata", "success"))
+	writeTestWorkRequest(t, stdin, 2, "check", "lint", "@"+paramsFilePath)
+	// unknown fields such as inputs are skipped
+	writeTestWorkRequest(t, stdin, 3, "check", "lint", "--input", filepath.Join("testdata", "success"), "--foo")
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		runPersistentWorker(
+			context.Background(),
+			app.NewContainer(nil, stdin, stdout, ioutil.Discard, "test", persistentWorkerFlagName),
+			"test",
+		),
+	)
+	responses := readTestWorkResponses(t, stdout.Bytes())
+	require.Len(t, responses, 3)
+	assert.Equal(t, &workResponse{requestID: 1}, responses[0])
+	assert.Equal(t, int32(2), responses[1].requestID)
+	assert.Equal(t, int32(5), responses[1].exitCode)
+	assert.Contains(t, responses[1].output, `Field name "oneTwo" should be lower_snake_case`)
+	assert.Equal(t, int32(3), responses[2].requestID)
+	assert.Equal(t, int32(1), responses[2].exitCode)
+	assert.Contains(t, responses[2].output, "unknown flag: --foo")
+}
+
+func TestPersistentWorkerCancel(t *testing.T) {
+	t.Parallel()
+	homeDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(homeDirPath)) }()
+	startedC := make(chan struct{}, 1)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				// blocks until the request is cancelled
+				startedC <- struct{}{}
+				<-request.Context().Done()
+			},
+		),
+	)
+	defer server.Close()
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	errC := make(chan error, 1)
+	go func() {
+		errC <- runPersistentWorker(
+			context.Background(),
+			// the netrc authenticator requires $HOME to be set
+			app.NewContainer(map[string]string{"HOME": homeDirPath}, stdinReader, stdoutWriter, ioutil.Discard, "test", persistentWorkerFlagName),
+			"test",
+		)
+		_ = stdoutWriter.Close()
+	}()
+	stdout := bufio.NewReader(stdoutReader)
+
+	writeTestWorkRequest(t, stdinWriter, 1, "experimental", "image", "convert", "-i", server.URL+"/image.bin", "-o", app.DevNullFilePath, "--allow-insecure-http")
+	writeTestWorkRequest(t, stdinWriter, 2, "check", "lint", "--input", filepath.Join("testdata", "success"))
+	<-startedC
+	// request 2 is waiting for request 1, so it is removed
+	writeTestWorkCancelRequest(t, stdinWriter, 2)
+	assert.Equal(t, &workResponse{requestID: 2, wasCancelled: true}, readTestWorkResponse(t, stdout))
+	// request 1 is running, so it is stopped
+	writeTestWorkCancelRequest(t, stdinWriter, 1)
+	assert.Equal(t, &workResponse{requestID: 1, wasCancelled: true}, readTestWorkResponse(t, stdout))
+	writeTestWorkRequest(t, stdinWriter, 3, "check", "lint", "--input", filepath.Join("testdata", "success"))
+	assert.Equal(t, &workResponse{requestID: 3}, readTestWorkResponse(t, stdout))
+	// request 3 already has a response, so the cancel request is ignored
+	writeTestWorkCancelRequest(t, stdinWriter, 3)
+	require.NoError(t, stdinWriter.Close())
+	require.NoError(t, <-errC)
+	data, err := ioutil.ReadAll(stdout)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestPersistentWorkerMaxWorkRequestSize(t *testing.T) {
+	t.Parallel()
+	stdin := bytes.NewBuffer(protowire.AppendVarint(nil, maxWorkRequestSize+1))
+	err := runPersistentWorker(
+		context.Background(),
+		app.NewContainer(nil, stdin, ioutil.Discard, ioutil.Discard, "test", persistentWorkerFlagName),
+		"test",
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum")
+}
+
+func writeTestWorkRequest(t *testing.T, writer io.Writer, requestID int32, arguments ...string) {
+	var data []byte
+	for _, argument := range arguments {
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendString(data, argument)
+	}
+	data = protowire.AppendTag(data, 2, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte("input"))
+	data = protowire.AppendTag(data, 3, protowire.VarintType)
+	data = protowire.AppendVarint(data, uint64(requestID))
+	data = protowire.AppendBytes(nil, data)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+}
+
+func writeTestWorkCancelRequest(t *testing.T, writer io.Writer, requestID int32) {
+	var data []byte
+	data = protowire.AppendTag(data, 3, protowire.VarintType)
+	data = protowire.AppendVarint(data, uint64(requestID))
+	data = protowire.AppendTag(data, 4, protowire.VarintType)
+	data = protowire.AppendVarint(data, protowire.EncodeBool(true))
+	data = protowire.AppendBytes(nil, data)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+}
+
+func readTestWorkResponse(t *testing.T, reader *bufio.Reader) *workResponse {
+	size, err := binary.ReadUvarint(reader)
+	require.NoError(t, err)
+	data := make([]byte, size)
+	_, err = io.ReadFull(reader, data)
+	require.NoError(t, err)
+	responses := readTestWorkResponses(t, protowire.AppendBytes(nil, data))
+	require.Len(t, responses, 1)
+	return responses[0]
+}
+
+func readTestWorkResponses(t *testing.T, data []byte) []*workResponse {
+	var responses []*workResponse
+	for len(data) > 0 {
+		message, n := protowire.ConsumeBytes(data)
+		require.True(t, n > 0)
+		data = data[n:]
+		response := &workResponse{}
+		for len(message) > 0 {
+			number, wireType, n := protowire.ConsumeTag(message)
+			require.True(t, n > 0)
+			message = message[n:]
+			switch number {
+			case 1:
+				value, n := protowire.ConsumeVarint(message)
+				require.True(t, n > 0)
+				response.exitCode = int32(value)
+				message = message[n:]
+			case 2:
+				value, n := protowire.ConsumeString(message)
+				require.True(t, n > 0)
+				response.output = value
+				message = message[n:]
+			case 3:
+				value, n := protowire.ConsumeVarint(message)
+				require.True(t, n > 0)
+				response.requestID = int32(value)
+				message = message[n:]
+			case 4:
+				value, n := protowire.ConsumeVarint(message)
+				require.True(t, n > 0)
+				response.wasCancelled = protowire.DecodeBool(value)
+				message = message[n:]
+			default:
+				n := protowire.ConsumeFieldValue(number, wireType, message)
+				require.True(t, n > 0)
+				message = message[n:]
+			}
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
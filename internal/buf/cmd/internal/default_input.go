@@ -0,0 +1,84 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/zap"
+)
+
+const inputEnvKey = "BUF_INPUT"
+
+// GetDefaultInput gets the input to use if no input is given.
+//
+// This is the value of BUF_INPUT if set. Otherwise, this is the closest
+// directory to the current directory that contains a buf.yaml, or the
+// default_input of this buf.yaml relative to its directory if set.
+// If no directory contains a buf.yaml, this is the current directory.
+func GetDefaultInput(logger *zap.Logger, envContainer app.EnvContainer) (string, error) {
+	if input := envContainer.Env(inputEnvKey); input != "" {
+		return input, nil
+	}
+	dirPath, err := getClosestConfigDirPath(".")
+	if err != nil {
+		return "", err
+	}
+	if dirPath == "" {
+		return ".", nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, bufconfig.ConfigFilePath))
+	if err != nil {
+		return "", err
+	}
+	config, err := bufconfig.NewProvider(logger).GetConfigForData(data)
+	if err != nil {
+		return "", err
+	}
+	if config.DefaultInput != "" {
+		return filepath.Join(dirPath, filepath.FromSlash(config.DefaultInput)), nil
+	}
+	return dirPath, nil
+}
+
+// getClosestConfigDirPath returns the closest directory to dirPath,
+// including dirPath, that contains a buf.yaml.
+//
+// The returned path is dirPath joined with zero or more "..", so that
+// relative paths stay relative. Returns empty if no directory contains
+// a buf.yaml.
+func getClosestConfigDirPath(dirPath string) (string, error) {
+	absDirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dirPath, bufconfig.ConfigFilePath)); err == nil {
+			return dirPath, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parentAbsDirPath := filepath.Dir(absDirPath)
+		if parentAbsDirPath == absDirPath {
+			return "", nil
+		}
+		dirPath = filepath.Join(dirPath, "..")
+		absDirPath = parentAbsDirPath
+	}
+}
@@ -15,8 +15,13 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/buf/bufbuild"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
@@ -25,26 +30,52 @@ import (
 	"github.com/bufbuild/buf/internal/buf/buffetch"
 	"github.com/bufbuild/buf/internal/buf/bufmod"
 	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
 	"github.com/bufbuild/buf/internal/pkg/git"
 	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
 )
 
 const (
 	experimentalGitCloneFlagName  = "experimental-git-clone"
+	allowInsecureHTTPFlagName     = "allow-insecure-http"
+	keepTempFlagName              = "keep-temp"
+	noCacheFlagName               = "no-cache"
+	offlineFlagName               = "offline"
+	yesFlagName                   = "yes"
+	forceFlagName                 = "force"
+	lsFormatFlagName              = "format"
 	inputHTTPSUsernameEnvKey      = "BUF_INPUT_HTTPS_USERNAME"
 	inputHTTPSPasswordEnvKey      = "BUF_INPUT_HTTPS_PASSWORD"
+	inputHTTPSTokenEnvKey         = "BUF_INPUT_HTTPS_TOKEN"
+	inputHTTPSTokenHostsEnvKey    = "BUF_INPUT_HTTPS_TOKEN_HOSTS"
 	inputSSHKeyFileEnvKey         = "BUF_INPUT_SSH_KEY_FILE"
 	inputSSHKnownHostsFilesEnvKey = "BUF_INPUT_SSH_KNOWN_HOSTS_FILES"
 )
 
 var (
+	// AllLsFormatStrings are all format strings for ls commands.
+	AllLsFormatStrings = []string{
+		"text",
+		"json",
+	}
+
 	// Timeout should be set through context for calls to EnvReader, not through http.Client
-	defaultHTTPClient        = &http.Client{}
+	defaultHTTPClient = &http.Client{}
+	// the most specific credentials are used first, that is credentials in the
+	// url, then credentials for the host from netrc or the token, and then
+	// credentials for all hosts
 	defaultHTTPAuthenticator = httpauth.NewMultiAuthenticator(
+		httpauth.NewURLAuthenticator(),
 		httpauth.NewNetrcAuthenticator(),
+		httpauth.NewBearerTokenEnvAuthenticator(
+			inputHTTPSTokenEnvKey,
+			inputHTTPSTokenHostsEnvKey,
+		),
 		// must keep this for legacy purposes
 		httpauth.NewEnvAuthenticator(
 			inputHTTPSPasswordEnvKey,
@@ -59,28 +90,64 @@ var (
 	}
 )
 
+// FetchOptions are options for fetching inputs.
+//
+// The zero value is the default.
+type FetchOptions struct {
+	// AllowInsecureHTTP allows inputs to be read over plain http.
+	AllowInsecureHTTP bool
+	// KeepTemp keeps extracted archives and git clones on disk.
+	KeepTemp bool
+	// NoCache does not use the cache of files read over http, git clones,
+	// and built files.
+	NoCache bool
+	// Offline fails to read inputs and dependencies that require network
+	// access.
+	Offline bool
+	// NetworkLimiter limits remote fetches if not nil.
+	NetworkLimiter netlimit.Limiter
+	// TLSConfig configures https fetches if not nil.
+	TLSConfig *TLSConfig
+}
+
 // NewBufwireEnvReader returns a new EnvReader.
 func NewBufwireEnvReader(
 	logger *zap.Logger,
 	inputFlagName string,
 	configOverrideFlagName string,
+	fetchOptions FetchOptions,
+	options ...bufwire.EnvReaderOption,
 ) bufwire.EnvReader {
+	if !fetchOptions.NoCache {
+		options = append(options, bufwire.EnvReaderWithBuildCache())
+	}
 	return bufwire.NewEnvReader(
 		logger,
 		buffetch.NewRefParser(
 			logger,
 		),
-		buffetch.NewReader(
-			logger,
-			defaultHTTPClient,
-			defaultHTTPAuthenticator,
-			git.NewCloner(logger, defaultGitClonerOptions),
-		),
+		newBuffetchReader(logger, fetchOptions),
 		bufconfig.NewProvider(logger),
 		bufmod.NewBucketBuilder(logger),
 		bufbuild.NewBuilder(logger),
 		inputFlagName,
 		configOverrideFlagName,
+		options...,
+	)
+}
+
+// NewBufwireDependencyResolver returns a new DependencyResolver.
+func NewBufwireDependencyResolver(
+	logger *zap.Logger,
+	fetchOptions FetchOptions,
+) bufwire.DependencyResolver {
+	return bufwire.NewDependencyResolver(
+		logger,
+		buffetch.NewRefParser(
+			logger,
+		),
+		newBuffetchReader(logger, fetchOptions),
+		bufconfig.NewProvider(logger),
 	)
 }
 
@@ -88,25 +155,24 @@ func NewBufwireEnvReader(
 func NewBufwireImageReader(
 	logger *zap.Logger,
 	imageFlagName string,
+	fetchOptions FetchOptions,
+	options ...bufwire.ImageReaderOption,
 ) bufwire.ImageReader {
 	return bufwire.NewImageReader(
 		logger,
 		buffetch.NewImageRefParser(
 			logger,
 		),
-		buffetch.NewReader(
-			logger,
-			defaultHTTPClient,
-			defaultHTTPAuthenticator,
-			git.NewCloner(logger, defaultGitClonerOptions),
-		),
+		newBuffetchReader(logger, fetchOptions),
 		imageFlagName,
+		options...,
 	)
 }
 
 // NewBufwireImageWriter returns a new ImageWriter.
 func NewBufwireImageWriter(
 	logger *zap.Logger,
+	options ...bufwire.ImageWriterOption,
 ) bufwire.ImageWriter {
 	return bufwire.NewImageWriter(
 		logger,
@@ -115,7 +181,29 @@ func NewBufwireImageWriter(
 		),
 		buffetch.NewWriter(
 			logger,
+			defaultHTTPClient,
 		),
+		options...,
+	)
+}
+
+// NewBuffetchReader returns a new buffetch.Reader.
+func NewBuffetchReader(
+	logger *zap.Logger,
+	fetchOptions FetchOptions,
+) buffetch.Reader {
+	return newBuffetchReader(logger, fetchOptions)
+}
+
+// NewBuffetchWriter returns a new buffetch.Writer.
+func NewBuffetchWriter(
+	logger *zap.Logger,
+	options ...buffetch.WriterOption,
+) buffetch.Writer {
+	return buffetch.NewWriter(
+		logger,
+		defaultHTTPClient,
+		options...,
 	)
 }
 
@@ -159,3 +247,189 @@ func BindExperimentalGitClone(flagSet *pflag.FlagSet, value *bool) {
 		),
 	)
 }
+
+// BindAllowInsecureHTTP binds the allow-insecure-http flag.
+func BindAllowInsecureHTTP(flagSet *pflag.FlagSet, value *bool) {
+	flagSet.BoolVar(
+		value,
+		allowInsecureHTTPFlagName,
+		false,
+		"Allow reading inputs and requesting registry tokens over plain http. Only https is allowed by default.",
+	)
+}
+
+// BindKeepTemp binds the keep-temp flag.
+func BindKeepTemp(flagSet *pflag.FlagSet, value *bool) {
+	flagSet.BoolVar(
+		value,
+		keepTempFlagName,
+		false,
+		"Keep the temporary directories that archives are extracted to and git repositories are cloned to, and print their paths. Used for debugging remote inputs.",
+	)
+}
+
+// BindNoCache binds the no-cache flag.
+func BindNoCache(flagSet *pflag.FlagSet, value *bool) {
+	flagSet.BoolVar(
+		value,
+		noCacheFlagName,
+		false,
+		"Do not read from or write to the cache of remote inputs and built files. Files read over https, git clones, and built files are cached by default.",
+	)
+}
+
+// BindOffline binds the offline flag.
+func BindOffline(flagSet *pflag.FlagSet, value *bool) {
+	flagSet.BoolVar(
+		value,
+		offlineFlagName,
+		false,
+		"Fail instead of reading inputs or dependencies over the network, even if they are cached. Use vendored dependencies with buf beta mod vendor.",
+	)
+}
+
+// BindYes binds the yes flag, and the force flag as a hidden alias of it.
+func BindYes(flagSet *pflag.FlagSet, value *bool) {
+	flagSet.BoolVar(
+		value,
+		yesFlagName,
+		false,
+		"Do not prompt for confirmation before overwriting or removing files. Prompts are only shown when stdin is a terminal.",
+	)
+	flagSet.BoolVar(
+		value,
+		forceFlagName,
+		false,
+		fmt.Sprintf("Alias of --%s.", yesFlagName),
+	)
+	_ = flagSet.MarkHidden(forceFlagName)
+}
+
+// BindLsFormat binds the format flag for ls commands.
+//
+// The name is what is being listed, for example "files".
+func BindLsFormat(flagSet *pflag.FlagSet, value *string, name string) {
+	flagSet.StringVar(
+		value,
+		lsFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format to print %s as. Must be one of %s.",
+			name,
+			stringutil.SliceToString(AllLsFormatStrings),
+		),
+	)
+}
+
+// IsLsFormatJSON returns true if the format flag value for an ls command is json.
+//
+// With json, ls commands print one JSON object per line.
+func IsLsFormatJSON(format string) (bool, error) {
+	switch s := strings.ToLower(strings.TrimSpace(format)); s {
+	case "", "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, fmt.Errorf("--%s: unknown format: %q", lsFormatFlagName, s)
+	}
+}
+
+// ErrorFormatDefaultUsage is the usage of the default of error format flags
+// whose value is given to GetErrorFormat.
+const ErrorFormatDefaultUsage = `If not set, this is github-actions when run in GitHub Actions, and text otherwise.`
+
+// GetErrorFormat returns the error format for the value of an error format flag.
+//
+// If the flag is not set, this is github-actions when run in GitHub Actions,
+// so that errors are annotated on the workflow run and pull request, and text
+// otherwise.
+func GetErrorFormat(envContainer app.EnvContainer, errorFormat string) string {
+	if errorFormat != "" {
+		return errorFormat
+	}
+	if app.IsGitHubActions(envContainer) {
+		return "github-actions"
+	}
+	return "text"
+}
+
+// OpenPayload opens the message payload at the path for reading.
+//
+// The path "-" or /dev/stdin reads from stdin, which is an error if stdin is
+// an interactive terminal. The format is only used for the error message.
+func OpenPayload(container app.StdinContainer, path string, format string) (io.ReadCloser, error) {
+	if path == "-" || app.IsDevStdin(path) {
+		if app.IsTerminal(container.Stdin()) {
+			return nil, fmt.Errorf(
+				`payload is stdin, but stdin is an interactive terminal, pipe or redirect %s data to stdin instead, such as with "cat payload.%s |" or "< payload.%s"`,
+				format,
+				format,
+				format,
+			)
+		}
+		return ioutil.NopCloser(container.Stdin()), nil
+	}
+	return os.Open(path)
+}
+
+func newBuffetchReader(logger *zap.Logger, fetchOptions FetchOptions) buffetch.Reader {
+	options := []buffetch.ReaderOption{
+		// local images can be hundreds of megabytes, and are only read
+		// once, so we map them instead of reading them into memory
+		buffetch.ReaderWithMmap(),
+	}
+	if fetchOptions.AllowInsecureHTTP {
+		options = append(options, buffetch.ReaderWithInsecureHTTP())
+	}
+	if fetchOptions.KeepTemp {
+		options = append(options, buffetch.ReaderWithKeepTemp())
+	}
+	if fetchOptions.NoCache {
+		options = append(options, buffetch.ReaderWithoutCache())
+	}
+	if fetchOptions.Offline {
+		options = append(options, buffetch.ReaderWithOffline())
+	}
+	if fetchOptions.NetworkLimiter != nil {
+		options = append(options, buffetch.ReaderWithNetworkLimiter(fetchOptions.NetworkLimiter))
+	}
+	httpClient := defaultHTTPClient
+	gitClonerOptions := defaultGitClonerOptions
+	if tlsConfig := fetchOptions.TLSConfig; tlsConfig != nil {
+		httpClient = tlsConfig.newHTTPClient()
+		gitClonerOptions = tlsConfig.applyToGitClonerOptions(gitClonerOptions)
+	}
+	return buffetch.NewReader(
+		logger,
+		withInsecureRedirectCheck(httpClient, fetchOptions.AllowInsecureHTTP),
+		defaultHTTPAuthenticator,
+		git.NewCloner(logger, gitClonerOptions),
+		options...,
+	)
+}
+
+// withInsecureRedirectCheck returns a copy of the http.Client that fails
+// redirects to plain http, unless allowInsecureHTTP is set.
+//
+// Otherwise an https input could be redirected to plain http, bypassing
+// --allow-insecure-http.
+func withInsecureRedirectCheck(httpClient *http.Client, allowInsecureHTTP bool) *http.Client {
+	if allowInsecureHTTP {
+		return httpClient
+	}
+	checkingHTTPClient := *httpClient
+	checkingHTTPClient.CheckRedirect = checkRedirectNotInsecure
+	return &checkingHTTPClient
+}
+
+func checkRedirectNotInsecure(request *http.Request, via []*http.Request) error {
+	// this is the limit of the default policy of http.Client
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if request.URL.Scheme == "http" {
+		return fmt.Errorf("redirect to plain http at %s disabled, set --%s to allow insecure http", request.URL.Host, allowInsecureHTTPFlagName)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithInsecureRedirectCheck(t *testing.T) {
+	t.Parallel()
+	httpServer := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write([]byte("insecure"))
+			},
+		),
+	)
+	defer httpServer.Close()
+	httpsServer := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				switch request.URL.Path {
+				case "/secure":
+					_, _ = responseWriter.Write([]byte("secure"))
+				case "/redirect-secure":
+					http.Redirect(responseWriter, request, "/secure", http.StatusFound)
+				default:
+					http.Redirect(responseWriter, request, httpServer.URL, http.StatusFound)
+				}
+			},
+		),
+	)
+	defer httpsServer.Close()
+
+	// redirects within https are followed
+	body, err := testGet(withInsecureRedirectCheck(httpsServer.Client(), false), httpsServer.URL+"/redirect-secure")
+	require.NoError(t, err)
+	assert.Equal(t, "secure", body)
+	// redirects from https to plain http are not followed
+	_, err = testGet(withInsecureRedirectCheck(httpsServer.Client(), false), httpsServer.URL+"/redirect-insecure")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "redirect to plain http")
+	assert.Contains(t, err.Error(), "--"+allowInsecureHTTPFlagName)
+	// unless insecure http is allowed
+	body, err = testGet(withInsecureRedirectCheck(httpsServer.Client(), true), httpsServer.URL+"/redirect-insecure")
+	require.NoError(t, err)
+	assert.Equal(t, "insecure", body)
+}
+
+func testGet(httpClient *http.Client, url string) (string, error) {
+	response, err := httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = response.Body.Close() }()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	jsonIndentFlagName          = "json-indent"
+	jsonUseProtoNamesFlagName   = "json-use-proto-names"
+	jsonEmitUnpopulatedFlagName = "json-emit-unpopulated"
+	jsonEnumAsIntFlagName       = "json-enum-as-int"
+	jsonCanonicalFlagName       = "json-canonical"
+	jsonAnyFallbackFlagName     = "json-any-fallback"
+)
+
+// JSONFlags are the flags for reading and writing JSON.
+type JSONFlags struct {
+	Indent          int
+	UseProtoNames   bool
+	EmitUnpopulated bool
+	EnumAsInt       bool
+	Canonical       bool
+	AnyFallback     string
+}
+
+// BindJSON binds the JSON flags.
+func BindJSON(flagSet *pflag.FlagSet, jsonFlags *JSONFlags) {
+	flagSet.IntVar(
+		&jsonFlags.Indent,
+		jsonIndentFlagName,
+		0,
+		`The number of spaces to indent JSON output with. If 0, JSON output is compact.`,
+	)
+	flagSet.BoolVar(
+		&jsonFlags.UseProtoNames,
+		jsonUseProtoNamesFlagName,
+		false,
+		`Use the proto field names for keys in JSON output instead of the lowerCamelCase JSON names.`,
+	)
+	flagSet.BoolVar(
+		&jsonFlags.EmitUnpopulated,
+		jsonEmitUnpopulatedFlagName,
+		false,
+		`Emit unpopulated fields with their default values in JSON output.`,
+	)
+	flagSet.BoolVar(
+		&jsonFlags.EnumAsInt,
+		jsonEnumAsIntFlagName,
+		false,
+		`Emit enum values as numbers instead of names in JSON output.`,
+	)
+	flagSet.BoolVar(
+		&jsonFlags.Canonical,
+		jsonCanonicalFlagName,
+		false,
+		`Output canonical JSON with all object keys sorted.
+
+Semantically identical inputs result in byte-for-byte identical JSON output.`,
+	)
+	flagSet.StringVar(
+		&jsonFlags.AnyFallback,
+		jsonAnyFallbackFlagName,
+		"error",
+		fmt.Sprintf(
+			`What to do with google.protobuf.Any values in JSON whose type cannot be resolved from the input. Must be one of %s.
+
+"global-types" falls back to the well-known types, "discard" additionally discards the contents of Any values whose type is still unknown.`,
+			stringutil.SliceToString(protoencoding.AllAnyFallbackStrings),
+		),
+	)
+}
+
+// NewJSONMarshalerOptions returns the JSONMarshalerOptions for the JSON flags.
+func NewJSONMarshalerOptions(jsonFlags JSONFlags) ([]protoencoding.JSONMarshalerOption, error) {
+	if jsonFlags.Indent < 0 {
+		return nil, fmt.Errorf("--%s must be non-negative", jsonIndentFlagName)
+	}
+	anyFallback, err := parseJSONAnyFallback(jsonFlags)
+	if err != nil {
+		return nil, err
+	}
+	jsonMarshalerOptions := []protoencoding.JSONMarshalerOption{
+		protoencoding.JSONMarshalerWithAnyFallback(anyFallback),
+	}
+	if jsonFlags.Indent > 0 {
+		jsonMarshalerOptions = append(
+			jsonMarshalerOptions,
+			protoencoding.JSONMarshalerWithIndent(strings.Repeat(" ", jsonFlags.Indent)),
+		)
+	}
+	if jsonFlags.UseProtoNames {
+		jsonMarshalerOptions = append(jsonMarshalerOptions, protoencoding.JSONMarshalerWithUseProtoNames())
+	}
+	if jsonFlags.EmitUnpopulated {
+		jsonMarshalerOptions = append(jsonMarshalerOptions, protoencoding.JSONMarshalerWithEmitUnpopulated())
+	}
+	if jsonFlags.EnumAsInt {
+		jsonMarshalerOptions = append(jsonMarshalerOptions, protoencoding.JSONMarshalerWithUseEnumNumbers())
+	}
+	if jsonFlags.Canonical {
+		jsonMarshalerOptions = append(jsonMarshalerOptions, protoencoding.JSONMarshalerWithCanonical())
+	}
+	return jsonMarshalerOptions, nil
+}
+
+// NewJSONUnmarshalerOptions returns the JSONUnmarshalerOptions for the JSON flags.
+func NewJSONUnmarshalerOptions(jsonFlags JSONFlags) ([]protoencoding.JSONUnmarshalerOption, error) {
+	anyFallback, err := parseJSONAnyFallback(jsonFlags)
+	if err != nil {
+		return nil, err
+	}
+	return []protoencoding.JSONUnmarshalerOption{
+		protoencoding.JSONUnmarshalerWithAnyFallback(anyFallback),
+	}, nil
+}
+
+func parseJSONAnyFallback(jsonFlags JSONFlags) (protoencoding.AnyFallback, error) {
+	anyFallback, err := protoencoding.ParseAnyFallback(jsonFlags.AnyFallback)
+	if err != nil {
+		return 0, fmt.Errorf("--%s: %w", jsonAnyFallbackFlagName, err)
+	}
+	return anyFallback, nil
+}
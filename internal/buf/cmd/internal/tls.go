@@ -0,0 +1,480 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/encoding"
+	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	caCertFlagName             = "cacert"
+	insecureSkipVerifyFlagName = "insecure-skip-verify"
+	minTLSVersionFlagName      = "min-tls-version"
+	clientCertFlagName         = "client-cert"
+	clientKeyFlagName          = "client-key"
+	clientCertConfigFlagName   = "client-cert-config"
+	httpsProxyFlagName         = "https-proxy"
+
+	caCertEnvKey     = "BUF_INPUT_CACERT"
+	clientCertEnvKey = "BUF_INPUT_CLIENT_CERT"
+	clientKeyEnvKey  = "BUF_INPUT_CLIENT_KEY"
+	httpsProxyEnvKey = "HTTPS_PROXY"
+	noProxyEnvKey    = "NO_PROXY"
+)
+
+var (
+	allTLSVersionStrings = []string{
+		"1.0",
+		"1.1",
+		"1.2",
+		"1.3",
+	}
+	tlsVersionStringToVersion = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+)
+
+// TLSFlags are the flags for TLS and proxy configuration of https fetches.
+type TLSFlags struct {
+	CACert             string
+	InsecureSkipVerify bool
+	MinTLSVersion      string
+	ClientCert         string
+	ClientKey          string
+	ClientCertConfig   string
+	HTTPSProxy         string
+}
+
+// BindTLS binds the TLS flags.
+func BindTLS(flagSet *pflag.FlagSet, tlsFlags *TLSFlags) {
+	flagSet.StringVar(
+		&tlsFlags.CACert,
+		caCertFlagName,
+		"",
+		fmt.Sprintf(
+			"The path to a PEM bundle of CA certificates to verify https inputs with instead of the system CA certificates. Defaults to $%s.",
+			caCertEnvKey,
+		),
+	)
+	flagSet.BoolVar(
+		&tlsFlags.InsecureSkipVerify,
+		insecureSkipVerifyFlagName,
+		false,
+		"Do not verify the certificates of https inputs. This is insecure and should only be used for testing.",
+	)
+	flagSet.StringVar(
+		&tlsFlags.MinTLSVersion,
+		minTLSVersionFlagName,
+		"",
+		fmt.Sprintf(
+			"The minimum TLS version for https inputs. Must be one of %s.",
+			stringutil.SliceToString(allTLSVersionStrings),
+		),
+	)
+	flagSet.StringVar(
+		&tlsFlags.ClientCert,
+		clientCertFlagName,
+		"",
+		fmt.Sprintf(
+			"The path to a PEM client certificate to present to https inputs. Requires --%s. Defaults to $%s.",
+			clientKeyFlagName,
+			clientCertEnvKey,
+		),
+	)
+	flagSet.StringVar(
+		&tlsFlags.ClientKey,
+		clientKeyFlagName,
+		"",
+		fmt.Sprintf(
+			"The path to the PEM private key of --%s. Defaults to $%s.",
+			clientCertFlagName,
+			clientKeyEnvKey,
+		),
+	)
+	flagSet.StringVar(
+		&tlsFlags.ClientCertConfig,
+		clientCertConfigFlagName,
+		"",
+		fmt.Sprintf(
+			`The path to a YAML or JSON file of client certificates to present to specific hosts, such as:
+
+hosts:
+  artifacts.example.com:
+    cert: artifacts.crt
+    key: artifacts.key
+
+Relative paths are relative to the directory of the file. Hosts without a client certificate
+in the file use --%s, if set.`,
+			clientCertFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&tlsFlags.HTTPSProxy,
+		httpsProxyFlagName,
+		"",
+		fmt.Sprintf(
+			"The URL of the proxy for https inputs, including git clones over https. Defaults to $%s. Hosts in $%s are not proxied.",
+			httpsProxyEnvKey,
+			noProxyEnvKey,
+		),
+	)
+}
+
+// TLSConfig is a TLS configuration for https fetches.
+type TLSConfig struct {
+	caCertFilePath     string
+	insecureSkipVerify bool
+	minTLSVersion      string
+	// the client certificate for all hosts, if any, has an empty host
+	clientCertificates []git.HTTPSClientCertificate
+	tlsConfig          *tls.Config
+	// does not include the client certificate for all hosts, which is in tlsConfig
+	hostToTLSConfig map[string]*tls.Config
+	// nil if https requests are not proxied
+	httpsProxyURL *url.URL
+	// empty if the proxy is not set with a flag, in which case git uses the
+	// proxy from the environment
+	gitHTTPSProxy string
+	// the entries of NO_PROXY
+	noProxyEntries []string
+}
+
+// NewTLSConfig returns a new TLSConfig for the TLS flags.
+//
+// The CA certificates and client certificate default to the values of their
+// environment variables, so that they can be set once for all commands.
+// The https proxy defaults to HTTPS_PROXY, and hosts in NO_PROXY are not
+// proxied, as with http.ProxyFromEnvironment.
+// Returns nil if no TLS flags are set, in which case the defaults are used.
+// This warns if InsecureSkipVerify is set.
+func NewTLSConfig(container applog.Container, tlsFlags TLSFlags) (*TLSConfig, error) {
+	logger := container.Logger()
+	if tlsFlags.CACert == "" {
+		tlsFlags.CACert = container.Env(caCertEnvKey)
+	}
+	if tlsFlags.ClientCert == "" && tlsFlags.ClientKey == "" {
+		tlsFlags.ClientCert = container.Env(clientCertEnvKey)
+		tlsFlags.ClientKey = container.Env(clientKeyEnvKey)
+	}
+	if tlsFlags == (TLSFlags{}) {
+		return nil, nil
+	}
+	t := &TLSConfig{
+		insecureSkipVerify: tlsFlags.InsecureSkipVerify,
+		minTLSVersion:      tlsFlags.MinTLSVersion,
+		tlsConfig:          &tls.Config{},
+		hostToTLSConfig:    make(map[string]*tls.Config),
+	}
+	if tlsFlags.CACert != "" {
+		// git runs in other directories, so the path must be absolute
+		caCertFilePath, err := filepath.Abs(tlsFlags.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", caCertFlagName, err)
+		}
+		data, err := ioutil.ReadFile(caCertFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", caCertFlagName, err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("--%s: no PEM certificates found in %q", caCertFlagName, caCertFilePath)
+		}
+		t.caCertFilePath = caCertFilePath
+		t.tlsConfig.RootCAs = certPool
+	}
+	if tlsFlags.InsecureSkipVerify {
+		logger.Warn(
+			fmt.Sprintf(
+				"--%s is set, the certificates of https inputs will NOT be verified. This is insecure and should only be used for testing.",
+				insecureSkipVerifyFlagName,
+			),
+		)
+		t.tlsConfig.InsecureSkipVerify = true
+	}
+	if tlsFlags.MinTLSVersion != "" {
+		version, ok := tlsVersionStringToVersion[tlsFlags.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("--%s: unknown TLS version: %q", minTLSVersionFlagName, tlsFlags.MinTLSVersion)
+		}
+		t.tlsConfig.MinVersion = version
+	}
+	if tlsFlags.ClientCert != "" || tlsFlags.ClientKey != "" {
+		if tlsFlags.ClientCert == "" || tlsFlags.ClientKey == "" {
+			return nil, fmt.Errorf("--%s and --%s must be set together", clientCertFlagName, clientKeyFlagName)
+		}
+		clientCertificate, certificate, err := loadClientCertificate("", tlsFlags.ClientCert, tlsFlags.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", clientCertFlagName, err)
+		}
+		t.clientCertificates = append(t.clientCertificates, clientCertificate)
+		t.tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	if tlsFlags.HTTPSProxy != "" {
+		httpsProxyURL, err := url.Parse(tlsFlags.HTTPSProxy)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %v", httpsProxyFlagName, err)
+		}
+		if httpsProxyURL.Scheme == "" || httpsProxyURL.Host == "" {
+			return nil, fmt.Errorf("--%s: must be a URL with a scheme and host, such as http://proxy.example.com:3128: %q", httpsProxyFlagName, tlsFlags.HTTPSProxy)
+		}
+		t.httpsProxyURL = httpsProxyURL
+		t.gitHTTPSProxy = httpsProxyURL.String()
+	} else if httpsProxy := getProxyEnv(container, httpsProxyEnvKey); httpsProxy != "" {
+		// as with http.ProxyFromEnvironment, the scheme defaults to http
+		if !strings.Contains(httpsProxy, "://") {
+			httpsProxy = "http://" + httpsProxy
+		}
+		httpsProxyURL, err := url.Parse(httpsProxy)
+		if err != nil {
+			return nil, fmt.Errorf("$%s: %v", httpsProxyEnvKey, err)
+		}
+		t.httpsProxyURL = httpsProxyURL
+	}
+	for _, noProxyEntry := range strings.Split(getProxyEnv(container, noProxyEnvKey), ",") {
+		if noProxyEntry = strings.ToLower(strings.TrimSpace(noProxyEntry)); noProxyEntry != "" {
+			t.noProxyEntries = append(t.noProxyEntries, noProxyEntry)
+		}
+	}
+	if tlsFlags.ClientCertConfig != "" {
+		if err := t.addClientCertConfig(tlsFlags.ClientCertConfig); err != nil {
+			return nil, fmt.Errorf("--%s: %v", clientCertConfigFlagName, err)
+		}
+	}
+	return t, nil
+}
+
+func (t *TLSConfig) addClientCertConfig(configFilePath string) error {
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	externalConfig := &externalClientCertConfig{}
+	if err := encoding.UnmarshalJSONOrYAMLStrict(data, externalConfig); err != nil {
+		return err
+	}
+	configDirPath := filepath.Dir(configFilePath)
+	hosts := make([]string, 0, len(externalConfig.Hosts))
+	for host := range externalConfig.Hosts {
+		hosts = append(hosts, host)
+	}
+	// sort for deterministic git config
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		externalHost := externalConfig.Hosts[host]
+		if host == "" {
+			return fmt.Errorf("empty host")
+		}
+		if externalHost.Cert == "" || externalHost.Key == "" {
+			return fmt.Errorf("host %q: cert and key must be set", host)
+		}
+		certFilePath := externalHost.Cert
+		if !filepath.IsAbs(certFilePath) {
+			certFilePath = filepath.Join(configDirPath, certFilePath)
+		}
+		keyFilePath := externalHost.Key
+		if !filepath.IsAbs(keyFilePath) {
+			keyFilePath = filepath.Join(configDirPath, keyFilePath)
+		}
+		clientCertificate, certificate, err := loadClientCertificate(host, certFilePath, keyFilePath)
+		if err != nil {
+			return fmt.Errorf("host %q: %v", host, err)
+		}
+		t.clientCertificates = append(t.clientCertificates, clientCertificate)
+		hostTLSConfig := t.tlsConfig.Clone()
+		hostTLSConfig.Certificates = []tls.Certificate{certificate}
+		t.hostToTLSConfig[host] = hostTLSConfig
+	}
+	return nil
+}
+
+func (t *TLSConfig) newHTTPClient() *http.Client {
+	transport := newTLSTransport(t.tlsConfig, t.proxy)
+	if len(t.hostToTLSConfig) == 0 {
+		return &http.Client{
+			Transport: transport,
+		}
+	}
+	hostToTransport := make(map[string]http.RoundTripper, len(t.hostToTLSConfig))
+	for host, hostTLSConfig := range t.hostToTLSConfig {
+		hostToTransport[host] = newTLSTransport(hostTLSConfig, t.proxy)
+	}
+	return &http.Client{
+		Transport: &hostRoundTripper{
+			defaultRoundTripper: transport,
+			hostToRoundTripper:  hostToTransport,
+		},
+	}
+}
+
+func (t *TLSConfig) applyToGitClonerOptions(gitClonerOptions git.ClonerOptions) git.ClonerOptions {
+	gitClonerOptions.HTTPSCACertFilePath = t.caCertFilePath
+	gitClonerOptions.HTTPSInsecureSkipVerify = t.insecureSkipVerify
+	if t.minTLSVersion != "" {
+		gitClonerOptions.HTTPSMinTLSVersion = "tlsv" + t.minTLSVersion
+	}
+	gitClonerOptions.HTTPSClientCertificates = t.clientCertificates
+	gitClonerOptions.HTTPSProxy = t.gitHTTPSProxy
+	return gitClonerOptions
+}
+
+// hostRoundTripper uses a separate http.RoundTripper per host, as the client
+// certificate cannot be selected by host within a single tls.Config.
+type hostRoundTripper struct {
+	defaultRoundTripper http.RoundTripper
+	// keys are either a host with a port, or a hostname
+	hostToRoundTripper map[string]http.RoundTripper
+}
+
+func (h *hostRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if roundTripper, ok := h.hostToRoundTripper[request.URL.Host]; ok {
+		return roundTripper.RoundTrip(request)
+	}
+	if roundTripper, ok := h.hostToRoundTripper[request.URL.Hostname()]; ok {
+		return roundTripper.RoundTrip(request)
+	}
+	return h.defaultRoundTripper.RoundTrip(request)
+}
+
+// proxy returns the proxy for the request.
+//
+// Plain http requests use the proxy from the environment as with
+// http.DefaultTransport.
+func (t *TLSConfig) proxy(request *http.Request) (*url.URL, error) {
+	if request.URL.Scheme != "https" {
+		return http.ProxyFromEnvironment(request)
+	}
+	if t.httpsProxyURL == nil || isNoProxyHost(t.noProxyEntries, request.URL) {
+		return nil, nil
+	}
+	return t.httpsProxyURL, nil
+}
+
+// newTLSTransport returns a new http.Transport with the TLS configuration
+// and proxy.
+func newTLSTransport(
+	tlsConfig *tls.Config,
+	proxy func(*http.Request) (*url.URL, error),
+) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	return transport
+}
+
+// isNoProxyHost returns true if the host of the url matches an entry of
+// NO_PROXY.
+//
+// As with http.ProxyFromEnvironment, the entries are hosts with an optional
+// port, which also match their subdomains unless they start with a ".", IP
+// addresses or CIDR ranges, or * to match all hosts.
+func isNoProxyHost(noProxyEntries []string, u *url.URL) bool {
+	hostname := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	ip := net.ParseIP(hostname)
+	for _, noProxyEntry := range noProxyEntries {
+		if noProxyEntry == "*" {
+			return true
+		}
+		if _, ipNet, err := net.ParseCIDR(noProxyEntry); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHostname := noProxyEntry
+		if splitHostname, splitPort, err := net.SplitHostPort(noProxyEntry); err == nil {
+			if splitPort != port {
+				continue
+			}
+			entryHostname = splitHostname
+		}
+		entryHostname = strings.TrimPrefix(entryHostname, "*")
+		if strings.HasPrefix(entryHostname, ".") {
+			if strings.HasSuffix(hostname, entryHostname) {
+				return true
+			}
+			continue
+		}
+		if hostname == entryHostname || strings.HasSuffix(hostname, "."+entryHostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// getProxyEnv returns the value of the proxy environment variable, falling
+// back to its lowercase name.
+func getProxyEnv(container applog.Container, key string) string {
+	if value := container.Env(key); value != "" {
+		return value
+	}
+	return container.Env(strings.ToLower(key))
+}
+
+// loadClientCertificate loads the client certificate, making the paths absolute
+// for git.
+func loadClientCertificate(
+	host string,
+	certFilePath string,
+	keyFilePath string,
+) (git.HTTPSClientCertificate, tls.Certificate, error) {
+	absCertFilePath, err := filepath.Abs(certFilePath)
+	if err != nil {
+		return git.HTTPSClientCertificate{}, tls.Certificate{}, err
+	}
+	absKeyFilePath, err := filepath.Abs(keyFilePath)
+	if err != nil {
+		return git.HTTPSClientCertificate{}, tls.Certificate{}, err
+	}
+	certificate, err := tls.LoadX509KeyPair(absCertFilePath, absKeyFilePath)
+	if err != nil {
+		return git.HTTPSClientCertificate{}, tls.Certificate{}, err
+	}
+	return git.HTTPSClientCertificate{
+		Host:         host,
+		CertFilePath: absCertFilePath,
+		KeyFilePath:  absKeyFilePath,
+	}, certificate, nil
+}
+
+type externalClientCertConfig struct {
+	Hosts map[string]externalClientCertConfigHost `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+}
+
+type externalClientCertConfigHost struct {
+	Cert string `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Key  string `json:"key,omitempty" yaml:"key,omitempty"`
+}
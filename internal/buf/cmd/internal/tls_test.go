@@ -0,0 +1,625 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewTLSConfigEmpty(t *testing.T) {
+	t.Parallel()
+	tlsConfig, err := NewTLSConfig(newTestContainer(nil), TLSFlags{})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestNewTLSConfigCACert(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	trustedCA := newTestCertificateAuthority(t)
+	untrustedCA := newTestCertificateAuthority(t)
+	trustedCACertFilePath := filepath.Join(tempDirPath, "trusted.crt")
+	require.NoError(t, ioutil.WriteFile(trustedCACertFilePath, trustedCA.certPEM, 0600))
+	untrustedCACertFilePath := filepath.Join(tempDirPath, "untrusted.crt")
+	require.NoError(t, ioutil.WriteFile(untrustedCACertFilePath, untrustedCA.certPEM, 0600))
+	server := newTestTLSServer(t, trustedCA, &tls.Config{})
+	defer server.Close()
+
+	tlsConfig, err := NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: trustedCACertFilePath})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.NoError(t, err)
+	gitClonerOptions := tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions)
+	assert.Equal(t, trustedCACertFilePath, gitClonerOptions.HTTPSCACertFilePath)
+	assert.False(t, gitClonerOptions.HTTPSInsecureSkipVerify)
+
+	tlsConfig, err = NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: untrustedCACertFilePath})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.Error(t, err)
+
+	tlsConfig, err = NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: untrustedCACertFilePath, InsecureSkipVerify: true})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.NoError(t, err)
+	assert.True(t, tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions).HTTPSInsecureSkipVerify)
+
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: filepath.Join(tempDirPath, "missing.crt")})
+	assert.Error(t, err)
+	emptyFilePath := filepath.Join(tempDirPath, "empty.crt")
+	require.NoError(t, ioutil.WriteFile(emptyFilePath, nil, 0600))
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: emptyFilePath})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no PEM certificates found")
+}
+
+func TestNewTLSConfigMinTLSVersion(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	ca := newTestCertificateAuthority(t)
+	caCertFilePath := filepath.Join(tempDirPath, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caCertFilePath, ca.certPEM, 0600))
+	server := newTestTLSServer(t, ca, &tls.Config{MaxVersion: tls.VersionTLS12})
+	defer server.Close()
+
+	tlsConfig, err := NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: caCertFilePath, MinTLSVersion: "1.2"})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "tlsv1.2", tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions).HTTPSMinTLSVersion)
+
+	// the server does not support TLS 1.3
+	tlsConfig, err = NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: caCertFilePath, MinTLSVersion: "1.3"})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.Error(t, err)
+
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{MinTLSVersion: "1.4"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--"+minTLSVersionFlagName)
+}
+
+func TestNewTLSConfigClientCert(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	ca := newTestCertificateAuthority(t)
+	caCertFilePath := filepath.Join(tempDirPath, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caCertFilePath, ca.certPEM, 0600))
+	clientCertFilePath, clientKeyFilePath := writeTestClientCertificate(t, ca, tempDirPath, "client")
+	server := newTestTLSServer(t, ca, newTestClientAuthTLSConfig(ca, tls.RequireAndVerifyClientCert))
+	defer server.Close()
+
+	tlsConfig, err := NewTLSConfig(newTestContainer(nil), TLSFlags{CACert: caCertFilePath})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.Error(t, err)
+
+	tlsConfig, err = NewTLSConfig(
+		newTestContainer(nil),
+		TLSFlags{
+			CACert:     caCertFilePath,
+			ClientCert: clientCertFilePath,
+			ClientKey:  clientKeyFilePath,
+		},
+	)
+	require.NoError(t, err)
+	body, err := testGet(tlsConfig.newHTTPClient(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "client", body)
+	assert.Equal(
+		t,
+		[]git.HTTPSClientCertificate{
+			{
+				CertFilePath: clientCertFilePath,
+				KeyFilePath:  clientKeyFilePath,
+			},
+		},
+		tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions).HTTPSClientCertificates,
+	)
+
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{ClientCert: clientCertFilePath})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be set together")
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{ClientCert: clientKeyFilePath, ClientKey: clientKeyFilePath})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--"+clientCertFlagName)
+}
+
+func TestNewTLSConfigClientCertConfig(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	ca := newTestCertificateAuthority(t)
+	caCertFilePath := filepath.Join(tempDirPath, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caCertFilePath, ca.certPEM, 0600))
+	defaultCertFilePath, defaultKeyFilePath := writeTestClientCertificate(t, ca, tempDirPath, "default")
+	writeTestClientCertificate(t, ca, tempDirPath, "a")
+	// both servers request a client certificate, but only verify it if given
+	serverA := newTestTLSServer(t, ca, newTestClientAuthTLSConfig(ca, tls.VerifyClientCertIfGiven))
+	defer serverA.Close()
+	serverB := newTestTLSServer(t, ca, newTestClientAuthTLSConfig(ca, tls.VerifyClientCertIfGiven))
+	defer serverB.Close()
+	hostA := serverA.Listener.Addr().String()
+	configFilePath := filepath.Join(tempDirPath, "client_certs.yaml")
+	// relative paths are relative to the directory of the file
+	require.NoError(t, ioutil.WriteFile(configFilePath, []byte("hosts:\n  "+hostA+":\n    cert: a.crt\n    key: a.key\n"), 0600))
+
+	tlsConfig, err := NewTLSConfig(
+		newTestContainer(nil),
+		TLSFlags{
+			CACert:           caCertFilePath,
+			ClientCertConfig: configFilePath,
+		},
+	)
+	require.NoError(t, err)
+	httpClient := tlsConfig.newHTTPClient()
+	body, err := testGet(httpClient, serverA.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "a", body)
+	// the certificate for host A is never sent to host B
+	body, err = testGet(httpClient, serverB.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "none", body)
+
+	tlsConfig, err = NewTLSConfig(
+		newTestContainer(nil),
+		TLSFlags{
+			CACert:           caCertFilePath,
+			ClientCert:       defaultCertFilePath,
+			ClientKey:        defaultKeyFilePath,
+			ClientCertConfig: configFilePath,
+		},
+	)
+	require.NoError(t, err)
+	httpClient = tlsConfig.newHTTPClient()
+	body, err = testGet(httpClient, serverA.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "a", body)
+	// hosts without a client certificate in the file use --client-cert
+	body, err = testGet(httpClient, serverB.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "default", body)
+	clientCertificates := tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions).HTTPSClientCertificates
+	require.Len(t, clientCertificates, 2)
+	assert.Equal(t, "", clientCertificates[0].Host)
+	assert.Equal(t, hostA, clientCertificates[1].Host)
+	assert.Equal(t, filepath.Join(tempDirPath, "a.crt"), clientCertificates[1].CertFilePath)
+
+	require.NoError(t, ioutil.WriteFile(configFilePath, []byte("hosts:\n  "+hostA+":\n    cert: a.crt\n"), 0600))
+	_, err = NewTLSConfig(newTestContainer(nil), TLSFlags{ClientCertConfig: configFilePath})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cert and key must be set")
+}
+
+func TestHostRoundTripper(t *testing.T) {
+	t.Parallel()
+	var hosts []string
+	newRoundTripper := func(name string) http.RoundTripper {
+		return testRoundTripperFunc(
+			func(request *http.Request) (*http.Response, error) {
+				hosts = append(hosts, name)
+				return nil, errors.New("test")
+			},
+		)
+	}
+	roundTripper := &hostRoundTripper{
+		defaultRoundTripper: newRoundTripper("default"),
+		hostToRoundTripper: map[string]http.RoundTripper{
+			"a.example.com:8443": newRoundTripper("a_port"),
+			"a.example.com":      newRoundTripper("a"),
+		},
+	}
+	for _, url := range []string{
+		"https://a.example.com:8443/foo",
+		"https://a.example.com/foo",
+		"https://a.example.com:9443/foo",
+		"https://b.example.com/foo",
+		"https://b.example.com:8443/foo",
+	} {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		_, _ = roundTripper.RoundTrip(request)
+	}
+	assert.Equal(t, []string{"a_port", "a", "a", "default", "default"}, hosts)
+}
+
+type testRoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f testRoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestNewTLSConfigEnv(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	trustedCA := newTestCertificateAuthority(t)
+	untrustedCA := newTestCertificateAuthority(t)
+	trustedCACertFilePath := filepath.Join(tempDirPath, "trusted.crt")
+	require.NoError(t, ioutil.WriteFile(trustedCACertFilePath, trustedCA.certPEM, 0600))
+	untrustedCACertFilePath := filepath.Join(tempDirPath, "untrusted.crt")
+	require.NoError(t, ioutil.WriteFile(untrustedCACertFilePath, untrustedCA.certPEM, 0600))
+	clientCertFilePath, clientKeyFilePath := writeTestClientCertificate(t, trustedCA, tempDirPath, "client")
+	server := newTestTLSServer(t, trustedCA, newTestClientAuthTLSConfig(trustedCA, tls.RequireAndVerifyClientCert))
+	defer server.Close()
+
+	env := map[string]string{
+		caCertEnvKey:     trustedCACertFilePath,
+		clientCertEnvKey: clientCertFilePath,
+		clientKeyEnvKey:  clientKeyFilePath,
+	}
+	tlsConfig, err := NewTLSConfig(newTestContainer(env), TLSFlags{})
+	require.NoError(t, err)
+	body, err := testGet(tlsConfig.newHTTPClient(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "client", body)
+
+	// flags override the environment
+	tlsConfig, err = NewTLSConfig(newTestContainer(env), TLSFlags{CACert: untrustedCACertFilePath})
+	require.NoError(t, err)
+	_, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	assert.Error(t, err)
+	env[caCertEnvKey] = untrustedCACertFilePath
+	tlsConfig, err = NewTLSConfig(newTestContainer(env), TLSFlags{CACert: trustedCACertFilePath})
+	require.NoError(t, err)
+	body, err = testGet(tlsConfig.newHTTPClient(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "client", body)
+}
+
+func TestNewTLSConfigHTTPSProxy(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	ca := newTestCertificateAuthority(t)
+	caCertFilePath := filepath.Join(tempDirPath, "ca.crt")
+	require.NoError(t, ioutil.WriteFile(caCertFilePath, ca.certPEM, 0600))
+	server := newTestTLSServer(t, ca, &tls.Config{})
+	defer server.Close()
+	serverHost := server.Listener.Addr().String()
+	proxy := newTestProxy()
+	defer proxy.Close()
+
+	testCases := []struct {
+		name              string
+		env               map[string]string
+		httpsProxy        string
+		expectedProxied   bool
+		expectedGitProxy  string
+		expectedErrString string
+	}{
+		{
+			name:             "flag",
+			httpsProxy:       proxy.URL,
+			expectedProxied:  true,
+			expectedGitProxy: proxy.URL,
+		},
+		{
+			name:            "env",
+			env:             map[string]string{httpsProxyEnvKey: proxy.URL},
+			expectedProxied: true,
+		},
+		{
+			name:            "env_lowercase",
+			env:             map[string]string{"https_proxy": proxy.URL},
+			expectedProxied: true,
+		},
+		{
+			// nothing listens on port 1, so this fails if the env is used
+			name:             "flag_overrides_env",
+			env:              map[string]string{httpsProxyEnvKey: "http://127.0.0.1:1"},
+			httpsProxy:       proxy.URL,
+			expectedProxied:  true,
+			expectedGitProxy: proxy.URL,
+		},
+		{
+			name:             "no_proxy",
+			env:              map[string]string{noProxyEnvKey: "example.com,127.0.0.1"},
+			httpsProxy:       proxy.URL,
+			expectedGitProxy: proxy.URL,
+		},
+		{
+			name:             "no_proxy_port",
+			env:              map[string]string{noProxyEnvKey: serverHost},
+			httpsProxy:       proxy.URL,
+			expectedGitProxy: proxy.URL,
+		},
+		{
+			name:             "no_proxy_other_port",
+			env:              map[string]string{noProxyEnvKey: "127.0.0.1:1"},
+			httpsProxy:       proxy.URL,
+			expectedProxied:  true,
+			expectedGitProxy: proxy.URL,
+		},
+		{
+			name:            "no_proxy_env",
+			env:             map[string]string{httpsProxyEnvKey: proxy.URL, "no_proxy": "127.0.0.0/8"},
+			expectedProxied: false,
+		},
+		{
+			name:              "flag_invalid",
+			httpsProxy:        "proxy.example.com:3128",
+			expectedErrString: "--" + httpsProxyFlagName,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			tlsConfig, err := NewTLSConfig(
+				newTestContainer(testCase.env),
+				TLSFlags{
+					CACert:     caCertFilePath,
+					HTTPSProxy: testCase.httpsProxy,
+				},
+			)
+			if testCase.expectedErrString != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			proxiedHostsBefore := len(proxy.ConnectHosts())
+			httpClient := tlsConfig.newHTTPClient()
+			defer httpClient.CloseIdleConnections()
+			_, err = testGet(httpClient, server.URL)
+			require.NoError(t, err)
+			proxiedHosts := proxy.ConnectHosts()[proxiedHostsBefore:]
+			if testCase.expectedProxied {
+				assert.Equal(t, []string{serverHost}, proxiedHosts)
+			} else {
+				assert.Empty(t, proxiedHosts)
+			}
+			assert.Equal(t, testCase.expectedGitProxy, tlsConfig.applyToGitClonerOptions(defaultGitClonerOptions).HTTPSProxy)
+		})
+	}
+}
+
+func TestIsNoProxyHost(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		noProxyEntries []string
+		url            string
+		expected       bool
+	}{
+		{nil, "https://example.com", false},
+		{[]string{"*"}, "https://example.com", true},
+		{[]string{"example.com"}, "https://example.com", true},
+		{[]string{"example.com"}, "https://EXAMPLE.com", true},
+		{[]string{"example.com"}, "https://foo.example.com", true},
+		{[]string{"example.com"}, "https://fooexample.com", false},
+		{[]string{".example.com"}, "https://foo.example.com", true},
+		{[]string{".example.com"}, "https://example.com", false},
+		{[]string{"*.example.com"}, "https://foo.example.com", true},
+		{[]string{"example.com:443"}, "https://example.com", true},
+		{[]string{"example.com:443"}, "https://example.com:8443", false},
+		{[]string{"example.com:8443"}, "https://example.com:8443", true},
+		{[]string{"10.0.0.1"}, "https://10.0.0.1:8443", true},
+		{[]string{"10.0.0.0/8"}, "https://10.1.2.3", true},
+		{[]string{"10.0.0.0/8"}, "https://11.1.2.3", false},
+		{[]string{"10.0.0.0/8"}, "https://example.com", false},
+		{[]string{"foo.com", "example.com"}, "https://example.com", true},
+	}
+	for _, testCase := range testCases {
+		u, err := url.Parse(testCase.url)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, isNoProxyHost(testCase.noProxyEntries, u), "%v %s", testCase.noProxyEntries, testCase.url)
+	}
+}
+
+type testCertificateAuthority struct {
+	certificate *x509.Certificate
+	privateKey  *ecdsa.PrivateKey
+	certPEM     []byte
+}
+
+func newTestCertificateAuthority(t *testing.T) *testCertificateAuthority {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+	return &testCertificateAuthority{
+		certificate: certificate,
+		privateKey:  privateKey,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+	}
+}
+
+// newCertificate returns a new certificate and its private key in PEM, signed
+// by the certificate authority.
+//
+// Server certificates are valid for 127.0.0.1 and localhost.
+func (c *testCertificateAuthority) newCertificate(
+	t *testing.T,
+	commonName string,
+	extKeyUsage x509.ExtKeyUsage,
+) ([]byte, []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+	if extKeyUsage == x509.ExtKeyUsageServerAuth {
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+		template.DNSNames = []string{"localhost"}
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, c.certificate, &privateKey.PublicKey, c.privateKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newTestTLSServer returns a started server with a certificate signed by the
+// certificate authority and the rest of the given tls.Config.
+//
+// The server responds with the common name of the client certificate, or
+// none if there is no client certificate.
+func newTestTLSServer(t *testing.T, ca *testCertificateAuthority, tlsConfig *tls.Config) *httptest.Server {
+	certPEM, keyPEM := ca.newCertificate(t, "server", x509.ExtKeyUsageServerAuth)
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				// the common name of the client certificate, if any
+				commonName := "none"
+				if peerCertificates := request.TLS.PeerCertificates; len(peerCertificates) > 0 {
+					commonName = peerCertificates[0].Subject.CommonName
+				}
+				_, _ = responseWriter.Write([]byte(commonName))
+			},
+		),
+	)
+	server.TLS = tlsConfig
+	server.TLS.Certificates = []tls.Certificate{certificate}
+	server.StartTLS()
+	return server
+}
+
+// writeTestClientCertificate writes a client certificate with the common name
+// and its private key to NAME.crt and NAME.key in the directory.
+func writeTestClientCertificate(
+	t *testing.T,
+	ca *testCertificateAuthority,
+	dirPath string,
+	name string,
+) (string, string) {
+	certPEM, keyPEM := ca.newCertificate(t, name, x509.ExtKeyUsageClientAuth)
+	certFilePath := filepath.Join(dirPath, name+".crt")
+	require.NoError(t, ioutil.WriteFile(certFilePath, certPEM, 0600))
+	keyFilePath := filepath.Join(dirPath, name+".key")
+	require.NoError(t, ioutil.WriteFile(keyFilePath, keyPEM, 0600))
+	return certFilePath, keyFilePath
+}
+
+// newTestClientAuthTLSConfig returns a new server tls.Config that verifies
+// client certificates signed by the certificate authority.
+func newTestClientAuthTLSConfig(ca *testCertificateAuthority, clientAuth tls.ClientAuthType) *tls.Config {
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.certificate)
+	return &tls.Config{
+		ClientAuth: clientAuth,
+		ClientCAs:  clientCAs,
+	}
+}
+
+// testProxy is an https proxy that records the hosts of CONNECT requests.
+type testProxy struct {
+	*httptest.Server
+
+	lock         sync.Mutex
+	connectHosts []string
+}
+
+func newTestProxy() *testProxy {
+	proxy := &testProxy{}
+	proxy.Server = httptest.NewServer(http.HandlerFunc(proxy.serveHTTP))
+	return proxy
+}
+
+func (p *testProxy) ConnectHosts() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string{}, p.connectHosts...)
+}
+
+func (p *testProxy) serveHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodConnect {
+		http.Error(responseWriter, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	p.lock.Lock()
+	p.connectHosts = append(p.connectHosts, request.Host)
+	p.lock.Unlock()
+	targetConn, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadGateway)
+		return
+	}
+	clientConn, _, err := responseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		_ = targetConn.Close()
+		return
+	}
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		_ = targetConn.Close()
+		_ = clientConn.Close()
+		return
+	}
+	go func() {
+		_, _ = io.Copy(targetConn, clientConn)
+		_ = targetConn.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(clientConn, targetConn)
+		_ = clientConn.Close()
+	}()
+}
+
+func newTestContainer(env map[string]string) applog.Container {
+	return applog.NewContainer(app.NewContainer(env, nil, nil, nil), zap.NewNop())
+}
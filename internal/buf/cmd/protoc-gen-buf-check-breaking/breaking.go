@@ -63,22 +63,29 @@ func handle(
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	logger, err := applog.NewLogger(container.Stderr(), externalConfig.LogLevel, externalConfig.LogFormat)
+	logger, err := applog.NewLogger(
+		container.Stderr(),
+		externalConfig.LogLevel,
+		applog.GetFormat(container, container.Stderr(), externalConfig.LogFormat, app.ColorModeAuto),
+	)
 	if err != nil {
 		return err
 	}
 
-	files := request.FileToGenerate
-	if !externalConfig.LimitToInputFiles {
-		files = nil
-	}
-	envReader := internal.NewBufwireEnvReader(logger, "against_input", "against_input_config")
+	envReader := internal.NewBufwireEnvReader(
+		logger,
+		"against_input",
+		"against_input_config",
+		internal.FetchOptions{
+			AllowInsecureHTTP: externalConfig.AllowInsecureHTTP,
+		},
+	)
 	againstEnv, err := envReader.GetImageEnv(
 		ctx,
 		newContainer(container),
 		externalConfig.AgainstInput,
 		encoding.GetJSONStringOrStringValue(externalConfig.AgainstInputConfig),
-		files, // limit to the input files if specified
+		nil,   // the files to generate are root relative, so are filtered below
 		true,  // allow files in the against input to not exist
 		false, // keep for now
 	)
@@ -89,7 +96,13 @@ func handle(
 	if externalConfig.ExcludeImports {
 		againstImage = bufcore.ImageWithoutImports(againstImage)
 	}
-	envReader = internal.NewBufwireEnvReader(logger, "", "input_config")
+	if externalConfig.LimitToInputFiles {
+		againstImage, err = bufcore.ImageWithOnlyPathsAllowNotExist(againstImage, request.FileToGenerate)
+		if err != nil {
+			return err
+		}
+	}
+	envReader = internal.NewBufwireEnvReader(logger, "", "input_config", internal.FetchOptions{})
 	config, err := envReader.GetConfig(
 		ctx,
 		encoding.GetJSONStringOrStringValue(externalConfig.InputConfig),
@@ -126,6 +139,7 @@ type externalConfig struct {
 	InputConfig        json.RawMessage `json:"input_config,omitempty" yaml:"input_config,omitempty"`
 	LimitToInputFiles  bool            `json:"limit_to_input_files,omitempty" yaml:"limit_to_input_files,omitempty"`
 	ExcludeImports     bool            `json:"exclude_imports,omitempty" yaml:"exclude_imports,omitempty"`
+	AllowInsecureHTTP  bool            `json:"allow_insecure_http,omitempty" yaml:"allow_insecure_http,omitempty"`
 	LogLevel           string          `json:"log_level,omitempty" yaml:"log_level,omitempty"`
 	LogFormat          string          `json:"log_format,omitempty" yaml:"log_format,omitempty"`
 	ErrorFormat        string          `json:"error_format,omitempty" yaml:"error_format,omitempty"`
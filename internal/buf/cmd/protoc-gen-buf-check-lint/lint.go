@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
 	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
 	"github.com/bufbuild/buf/internal/buf/bufcore"
 	"github.com/bufbuild/buf/internal/buf/cmd/internal"
@@ -59,11 +60,15 @@ func handle(
 	}
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	logger, err := applog.NewLogger(container.Stderr(), externalConfig.LogLevel, externalConfig.LogFormat)
+	logger, err := applog.NewLogger(
+		container.Stderr(),
+		externalConfig.LogLevel,
+		applog.GetFormat(container, container.Stderr(), externalConfig.LogFormat, app.ColorModeAuto),
+	)
 	if err != nil {
 		return err
 	}
-	envReader := internal.NewBufwireEnvReader(logger, "", "input_config")
+	envReader := internal.NewBufwireEnvReader(logger, "", "input_config", internal.FetchOptions{})
 	config, err := envReader.GetConfig(
 		ctx,
 		encoding.GetJSONStringOrStringValue(externalConfig.InputConfig),
@@ -84,6 +89,15 @@ func handle(
 	if err != nil {
 		return err
 	}
+	if !externalConfig.WarningsAsErrors {
+		var warningFileAnnotations []bufanalysis.FileAnnotation
+		fileAnnotations, warningFileAnnotations = buflint.SplitWarnings(config.Lint, fileAnnotations)
+		if len(warningFileAnnotations) > 0 {
+			if err := buflint.PrintFileAnnotations(container.Stderr(), warningFileAnnotations, externalConfig.ErrorFormat); err != nil {
+				return err
+			}
+		}
+	}
 	if len(fileAnnotations) > 0 {
 		buffer := bytes.NewBuffer(nil)
 		if err := buflint.PrintFileAnnotations(buffer, fileAnnotations, externalConfig.ErrorFormat); err != nil {
@@ -100,4 +114,6 @@ type externalConfig struct {
 	LogFormat   string          `json:"log_format,omitempty" yaml:"log_format,omitempty"`
 	ErrorFormat string          `json:"error_format,omitempty" yaml:"error_format,omitempty"`
 	Timeout     time.Duration   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// WarningsAsErrors fails on the checkers configured as warnings in lint.warn
+	WarningsAsErrors bool `json:"warnings_as_errors,omitempty" yaml:"warnings_as_errors,omitempty"`
 }
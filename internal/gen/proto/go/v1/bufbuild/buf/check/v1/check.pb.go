@@ -0,0 +1,424 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0
+// 	protoc        v3.12.3
+// source: bufbuild/buf/check/v1/check.proto
+
+package checkv1
+
+import (
+	v1 "github.com/bufbuild/buf/internal/gen/proto/go/v1/bufbuild/buf/image/v1"
+	proto "github.com/golang/protobuf/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+// CheckRequest is the request written to the stdin of a check plugin.
+//
+// Check plugins are analogous to protoc plugins. A plugin is an executable
+// that reads a CheckRequest from stdin, and writes a CheckResponse to stdout.
+type CheckRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// image is the Image to check.
+	//
+	// Files that are not imports are the files to check.
+	Image *v1.Image `protobuf:"bytes,1,opt,name=image" json:"image,omitempty"`
+	// against_image is the Image to check against.
+	//
+	// This is only set for breaking change plugins.
+	AgainstImage *v1.Image `protobuf:"bytes,2,opt,name=against_image,json=againstImage" json:"against_image,omitempty"`
+	// parameter is the parameter configured for the plugin, if any.
+	Parameter *string `protobuf:"bytes,3,opt,name=parameter" json:"parameter,omitempty"`
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_bufbuild_buf_check_v1_check_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckRequest) GetImage() *v1.Image {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetAgainstImage() *v1.Image {
+	if x != nil {
+		return x.AgainstImage
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetParameter() string {
+	if x != nil && x.Parameter != nil {
+		return *x.Parameter
+	}
+	return ""
+}
+
+// CheckResponse is the response written to the stdout of a check plugin.
+type CheckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// file_annotations are the failures found by the plugin.
+	FileAnnotations []*FileAnnotation `protobuf:"bytes,1,rep,name=file_annotations,json=fileAnnotations" json:"file_annotations,omitempty"`
+	// error is set if the plugin could not run the check.
+	//
+	// This should not be set for check failures, which should instead be
+	// reported as file_annotations.
+	Error *string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_bufbuild_buf_check_v1_check_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckResponse) GetFileAnnotations() []*FileAnnotation {
+	if x != nil {
+		return x.FileAnnotations
+	}
+	return nil
+}
+
+func (x *CheckResponse) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+// FileAnnotation is a failure found by a check plugin.
+type FileAnnotation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// path is the path of the file within the image.
+	//
+	// If not set, the failure is not specific to a file.
+	Path *string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+	// start_line is the 1-indexed start line, or 0 if unknown.
+	StartLine *uint32 `protobuf:"varint,2,opt,name=start_line,json=startLine" json:"start_line,omitempty"`
+	// start_column is the 1-indexed start column, or 0 if unknown.
+	StartColumn *uint32 `protobuf:"varint,3,opt,name=start_column,json=startColumn" json:"start_column,omitempty"`
+	// end_line is the 1-indexed end line, or 0 if unknown.
+	EndLine *uint32 `protobuf:"varint,4,opt,name=end_line,json=endLine" json:"end_line,omitempty"`
+	// end_column is the 1-indexed end column, or 0 if unknown.
+	EndColumn *uint32 `protobuf:"varint,5,opt,name=end_column,json=endColumn" json:"end_column,omitempty"`
+	// type is the type of failure, such as "ENUM_VALUE_PREFIX".
+	//
+	// This field must be set.
+	Type *string `protobuf:"bytes,6,opt,name=type" json:"type,omitempty"`
+	// message is a human-readable description of the failure.
+	Message *string `protobuf:"bytes,7,opt,name=message" json:"message,omitempty"`
+}
+
+func (x *FileAnnotation) Reset() {
+	*x = FileAnnotation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileAnnotation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileAnnotation) ProtoMessage() {}
+
+func (x *FileAnnotation) ProtoReflect() protoreflect.Message {
+	mi := &file_bufbuild_buf_check_v1_check_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileAnnotation.ProtoReflect.Descriptor instead.
+func (*FileAnnotation) Descriptor() ([]byte, []int) {
+	return file_bufbuild_buf_check_v1_check_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FileAnnotation) GetPath() string {
+	if x != nil && x.Path != nil {
+		return *x.Path
+	}
+	return ""
+}
+
+func (x *FileAnnotation) GetStartLine() uint32 {
+	if x != nil && x.StartLine != nil {
+		return *x.StartLine
+	}
+	return 0
+}
+
+func (x *FileAnnotation) GetStartColumn() uint32 {
+	if x != nil && x.StartColumn != nil {
+		return *x.StartColumn
+	}
+	return 0
+}
+
+func (x *FileAnnotation) GetEndLine() uint32 {
+	if x != nil && x.EndLine != nil {
+		return *x.EndLine
+	}
+	return 0
+}
+
+func (x *FileAnnotation) GetEndColumn() uint32 {
+	if x != nil && x.EndColumn != nil {
+		return *x.EndColumn
+	}
+	return 0
+}
+
+func (x *FileAnnotation) GetType() string {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return ""
+}
+
+func (x *FileAnnotation) GetMessage() string {
+	if x != nil && x.Message != nil {
+		return *x.Message
+	}
+	return ""
+}
+
+var File_bufbuild_buf_check_v1_check_proto protoreflect.FileDescriptor
+
+var file_bufbuild_buf_check_v1_check_proto_rawDesc = []byte{
+	0x0a, 0x21, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x2f, 0x76, 0x31, 0x2f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x15, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2e, 0x62, 0x75,
+	0x66, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2e, 0x76, 0x31, 0x1a, 0x21, 0x62, 0x75, 0x66, 0x62,
+	0x75, 0x69, 0x6c, 0x64, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x2f, 0x76,
+	0x31, 0x2f, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xa3, 0x01,
+	0x0a, 0x0c, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x32,
+	0x0a, 0x05, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
+	0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x05, 0x69, 0x6d, 0x61,
+	0x67, 0x65, 0x12, 0x41, 0x0a, 0x0d, 0x61, 0x67, 0x61, 0x69, 0x6e, 0x73, 0x74, 0x5f, 0x69, 0x6d,
+	0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x62, 0x75, 0x66, 0x62,
+	0x75, 0x69, 0x6c, 0x64, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x0c, 0x61, 0x67, 0x61, 0x69, 0x6e, 0x73, 0x74,
+	0x49, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65,
+	0x74, 0x65, 0x72, 0x22, 0x77, 0x0a, 0x0d, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x50, 0x0a, 0x10, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x61, 0x6e, 0x6e,
+	0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25,
+	0x2e, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2e, 0x62, 0x75, 0x66, 0x2e, 0x63, 0x68,
+	0x65, 0x63, 0x6b, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x41, 0x6e, 0x6e, 0x6f, 0x74,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0f, 0x66, 0x69, 0x6c, 0x65, 0x41, 0x6e, 0x6e, 0x6f, 0x74,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xce, 0x01, 0x0a,
+	0x0e, 0x46, 0x69, 0x6c, 0x65, 0x41, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x6c, 0x69, 0x6e,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x4c, 0x69,
+	0x6e, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x63, 0x6f, 0x6c, 0x75,
+	0x6d, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x43,
+	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x19, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x69, 0x6e,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x4c, 0x69, 0x6e, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x6e, 0x64, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x65, 0x6e, 0x64, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x42, 0x55, 0x48,
+	0x01, 0x5a, 0x4e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x62, 0x75,
+	0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x62, 0x75, 0x66, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x6f,
+	0x2f, 0x76, 0x31, 0x2f, 0x62, 0x75, 0x66, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x2f, 0x62, 0x75, 0x66,
+	0x2f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x2f, 0x76, 0x31, 0x3b, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x76,
+	0x31, 0xf8, 0x01, 0x01,
+}
+
+var (
+	file_bufbuild_buf_check_v1_check_proto_rawDescOnce sync.Once
+	file_bufbuild_buf_check_v1_check_proto_rawDescData = file_bufbuild_buf_check_v1_check_proto_rawDesc
+)
+
+func file_bufbuild_buf_check_v1_check_proto_rawDescGZIP() []byte {
+	file_bufbuild_buf_check_v1_check_proto_rawDescOnce.Do(func() {
+		file_bufbuild_buf_check_v1_check_proto_rawDescData = protoimpl.X.CompressGZIP(file_bufbuild_buf_check_v1_check_proto_rawDescData)
+	})
+	return file_bufbuild_buf_check_v1_check_proto_rawDescData
+}
+
+var file_bufbuild_buf_check_v1_check_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_bufbuild_buf_check_v1_check_proto_goTypes = []interface{}{
+	(*CheckRequest)(nil),   // 0: bufbuild.buf.check.v1.CheckRequest
+	(*CheckResponse)(nil),  // 1: bufbuild.buf.check.v1.CheckResponse
+	(*FileAnnotation)(nil), // 2: bufbuild.buf.check.v1.FileAnnotation
+	(*v1.Image)(nil),       // 3: bufbuild.buf.image.v1.Image
+}
+var file_bufbuild_buf_check_v1_check_proto_depIdxs = []int32{
+	3, // 0: bufbuild.buf.check.v1.CheckRequest.image:type_name -> bufbuild.buf.image.v1.Image
+	3, // 1: bufbuild.buf.check.v1.CheckRequest.against_image:type_name -> bufbuild.buf.image.v1.Image
+	2, // 2: bufbuild.buf.check.v1.CheckResponse.file_annotations:type_name -> bufbuild.buf.check.v1.FileAnnotation
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_bufbuild_buf_check_v1_check_proto_init() }
+func file_bufbuild_buf_check_v1_check_proto_init() {
+	if File_bufbuild_buf_check_v1_check_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_bufbuild_buf_check_v1_check_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bufbuild_buf_check_v1_check_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bufbuild_buf_check_v1_check_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileAnnotation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_bufbuild_buf_check_v1_check_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_bufbuild_buf_check_v1_check_proto_goTypes,
+		DependencyIndexes: file_bufbuild_buf_check_v1_check_proto_depIdxs,
+		MessageInfos:      file_bufbuild_buf_check_v1_check_proto_msgTypes,
+	}.Build()
+	File_bufbuild_buf_check_v1_check_proto = out.File
+	file_bufbuild_buf_check_v1_check_proto_rawDesc = nil
+	file_bufbuild_buf_check_v1_check_proto_goTypes = nil
+	file_bufbuild_buf_check_v1_check_proto_depIdxs = nil
+}
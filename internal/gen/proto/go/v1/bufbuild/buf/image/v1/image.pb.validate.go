@@ -162,6 +162,8 @@ func (m *ImageExtension) Validate() error {
 
 	}
 
+	// no validation rules for CompactSourceCodeInfo
+
 	return nil
 }
 
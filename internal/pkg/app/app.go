@@ -17,6 +17,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -316,16 +317,30 @@ func NewErrorf(exitCode int, format string, args ...interface{}) error {
 	return newAppError(exitCode, fmt.Sprintf(format, args...))
 }
 
+// WrapError returns a new error that contains an exit code and wraps err.
+//
+// The message of the returned error is the message of err, so if the message
+// of err is empty, no message is printed on exit.
+//
+// The exit code cannot be 0.
+func WrapError(exitCode int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return newWrappedAppError(exitCode, err)
+}
+
 // GetExitCode gets the exit code.
 //
 // If err == nil, this returns 0.
-// If err was created by this package, this returns the exit code from the error.
+// If err or an error it wraps was created by this package, this returns the exit code from the error.
 // Otherwise, this returns 1.
 func GetExitCode(err error) int {
 	if err == nil {
 		return 0
 	}
-	if appError, ok := err.(*appError); ok {
+	var appError *appError
+	if errors.As(err, &appError) {
 		return appError.exitCode
 	}
 	return 1
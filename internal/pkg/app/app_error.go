@@ -22,6 +22,7 @@ import (
 type appError struct {
 	exitCode int
 	message  string
+	err      error
 }
 
 func newAppError(exitCode int, message string) *appError {
@@ -39,13 +40,30 @@ func newAppError(exitCode int, message string) *appError {
 	}
 }
 
+func newWrappedAppError(exitCode int, err error) *appError {
+	if exitCode == 0 {
+		return newAppError(exitCode, err.Error())
+	}
+	return &appError{
+		exitCode: exitCode,
+		err:      err,
+	}
+}
+
 func (e *appError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
 	if e.message != "" {
 		return e.message
 	}
 	return "exit status " + strconv.Itoa(e.exitCode)
 }
 
+func (e *appError) Unwrap() error {
+	return e.err
+}
+
 func printError(container StderrContainer, err error) {
 	if errString := err.Error(); errString != "" {
 		_, _ = fmt.Fprintln(container.Stderr(), errString)
@@ -15,6 +15,11 @@
 package app
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -134,3 +139,73 @@ func TestIsDev(t *testing.T) {
 	assert.False(t, IsDevStderr("foo"))
 	assert.False(t, IsDevNull("foo"))
 }
+
+func TestConfirmNotTerminal(t *testing.T) {
+	stderr := bytes.NewBuffer(nil)
+	container := NewContainer(nil, strings.NewReader("n\n"), nil, stderr)
+	assert.False(t, IsTerminal(container.Stdin()))
+	// no prompt is shown and the answer is not read if stdin is not a terminal
+	confirmed, err := Confirm(container, "Overwrite?")
+	require.NoError(t, err)
+	assert.True(t, confirmed)
+	assert.Empty(t, stderr.String())
+}
+
+func TestIsTerminalDevNull(t *testing.T) {
+	file, err := os.Open(DevNullFilePath)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, file.Close()) }()
+	// /dev/null is a character device, but not a terminal
+	assert.False(t, IsTerminal(file))
+	assert.False(t, IsTerminal(NewContainer(nil, file, nil, nil).Stdin()))
+	assert.False(t, IsColorEnabled(NewEnvContainer(nil), file, ColorModeAuto))
+}
+
+func TestIsCI(t *testing.T) {
+	assert.False(t, IsCI(NewEnvContainer(nil)))
+	assert.True(t, IsCI(NewEnvContainer(map[string]string{"CI": "true"})))
+	assert.True(t, IsCI(NewEnvContainer(map[string]string{"GITHUB_ACTIONS": "true"})))
+	assert.True(t, IsCI(NewEnvContainer(map[string]string{"GITLAB_CI": "true"})))
+	assert.False(t, IsCI(NewEnvContainer(map[string]string{"CI": "false", "GITHUB_ACTIONS": "true"})))
+}
+
+func TestIsGitHubActions(t *testing.T) {
+	assert.False(t, IsGitHubActions(NewEnvContainer(nil)))
+	assert.False(t, IsGitHubActions(NewEnvContainer(map[string]string{"CI": "true"})))
+	assert.True(t, IsGitHubActions(NewEnvContainer(map[string]string{"GITHUB_ACTIONS": "true"})))
+	assert.False(t, IsGitHubActions(NewEnvContainer(map[string]string{"CI": "false", "GITHUB_ACTIONS": "true"})))
+}
+
+func TestIsColorEnabled(t *testing.T) {
+	stderr := bytes.NewBuffer(nil)
+	envContainer := NewEnvContainer(map[string]string{"NO_COLOR": "1"})
+	assert.True(t, IsColorEnabled(envContainer, stderr, ColorModeAlways))
+	assert.False(t, IsColorEnabled(envContainer, stderr, ColorModeNever))
+	assert.False(t, IsColorEnabled(envContainer, stderr, ColorModeAuto))
+	// not a terminal
+	assert.False(t, IsColorEnabled(NewEnvContainer(nil), stderr, ColorModeAuto))
+
+	colorMode, err := ParseColorMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ColorModeAuto, colorMode)
+	colorMode, err = ParseColorMode("Never")
+	require.NoError(t, err)
+	assert.Equal(t, ColorModeNever, colorMode)
+	_, err = ParseColorMode("sometimes")
+	assert.Error(t, err)
+}
+
+func TestGetExitCode(t *testing.T) {
+	assert.Equal(t, 0, GetExitCode(nil))
+	assert.Equal(t, 1, GetExitCode(errors.New("foo")))
+	assert.Equal(t, 2, GetExitCode(NewError(2, "foo")))
+	assert.Equal(t, 3, GetExitCode(fmt.Errorf("bar: %w", NewError(3, "foo"))))
+
+	err := WrapError(4, errors.New("foo"))
+	assert.Equal(t, 4, GetExitCode(err))
+	assert.Equal(t, "foo", err.Error())
+	assert.Equal(t, "", WrapError(5, errors.New("")).Error())
+	assert.Equal(t, 6, GetExitCode(WrapError(6, NewError(2, "foo"))))
+	assert.Equal(t, 1, GetExitCode(WrapError(0, errors.New("foo"))))
+	assert.NoError(t, WrapError(1, nil))
+}
@@ -58,8 +58,87 @@ type Command struct {
 	// SubCommands are the sub-commands. Optional.
 	// Must be unset if there is a run function.
 	SubCommands []*Command
+	// Interceptors intercept the Run functions of this command and all of its
+	// sub-commands. Optional.
+	//
+	// Interceptors of a command are called before the interceptors of its
+	// sub-commands, and are called in order.
+	Interceptors []Interceptor
+	// FlagCompletions are the shell completions of the values of flags of
+	// this command, by flag name. Optional.
+	//
+	// The values of flags without a Completion are completed to file paths.
+	FlagCompletions map[string]*Completion
+	// ArgCompletions are the shell completions of the arguments of this
+	// command, by position. Optional.
+	//
+	// If not set, arguments are completed to file paths. If set, arguments
+	// after the last Completion are not completed.
+	ArgCompletions []*Completion
+}
+
+// Completion is a shell completion of the value of a flag.
+type Completion struct {
+	// Values returns the values that start with toComplete.
+	//
+	// If nil, the value is completed to a file path.
+	Values func(toComplete string) []string
+	// FileExtensions are the extensions of the files to complete to, without
+	// the leading ".", such as "proto". If empty, all files are completed.
+	//
+	// Only used if Values is nil.
+	FileExtensions []string
+}
+
+// CompleteValues returns a new Completion that completes to the given values.
+func CompleteValues(values ...string) *Completion {
+	return CompleteValuesFunc(
+		func() []string {
+			return values
+		},
+	)
+}
+
+// CompleteValuesFunc returns a new Completion that completes to the values
+// returned by getValues.
+//
+// This is useful if the values are expensive to get, as getValues is only
+// called when completing.
+func CompleteValuesFunc(getValues func() []string) *Completion {
+	return &Completion{
+		Values: func(toComplete string) []string {
+			var completions []string
+			for _, value := range getValues() {
+				if strings.HasPrefix(value, toComplete) {
+					completions = append(completions, value)
+				}
+			}
+			return completions
+		},
+	}
 }
 
+// CompleteFileExtensions returns a new Completion that completes to the files
+// with the given extensions, and to directories.
+func CompleteFileExtensions(fileExtensions ...string) *Completion {
+	return &Completion{
+		FileExtensions: fileExtensions,
+	}
+}
+
+// completionShells are the shells that completion scripts can be printed for.
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// RunFunc is a function that runs a command.
+type RunFunc func(context.Context, app.Container) error
+
+// Interceptor intercepts the run of a command.
+//
+// The commandPath is the full path of the command being run, such as "buf check lint".
+// The Interceptor must call next to run the command, and can pass a different
+// context or container to next, such as a container with different arguments.
+type Interceptor func(ctx context.Context, container app.Container, commandPath string, next RunFunc) error
+
 // Main runs the application using the OS container and calling os.Exit on the return value of Run.
 func Main(ctx context.Context, command *Command) {
 	app.Main(ctx, newRunFunc(command))
@@ -92,14 +171,20 @@ func run(
 ) error {
 	var runErr error
 
-	cobraCommand, err := commandToCobra(ctx, container, command, &runErr)
+	args := app.Args(container)[1:]
+	// cobra registers flag completions globally, so we only register them
+	// when they are used, that is when completing or generating a completion
+	// script, and not for every run
+	completing := isCompletionArgs(args)
+	cobraCommand, err := commandToCobra(ctx, container, command, nil, completing, &runErr)
 	if err != nil {
 		return err
 	}
 
-	// If the root command is not the only command, add hidden bash-completion
-	// and zsh-completion commands.
+	// If the root command is not the only command, add the completion command,
+	// and the hidden bash-completion and zsh-completion commands that predate it.
 	if len(command.SubCommands) > 0 {
+		cobraCommand.AddCommand(newCompletionCommand(cobraCommand, container, &runErr))
 		cobraCommand.AddCommand(&cobra.Command{
 			Use:    "bash-completion",
 			Args:   cobra.NoArgs,
@@ -118,8 +203,13 @@ func run(
 		})
 	}
 
-	cobraCommand.SetArgs(app.Args(container)[1:])
-	cobraCommand.SetOut(container.Stderr())
+	cobraCommand.SetArgs(args)
+	if completing {
+		// completions are read from stdout by the completion scripts
+		cobraCommand.SetOut(container.Stdout())
+	} else {
+		cobraCommand.SetOut(container.Stderr())
+	}
 	cobraCommand.SetErr(container.Stderr())
 
 	if err := cobraCommand.Execute(); err != nil {
@@ -132,6 +222,8 @@ func commandToCobra(
 	ctx context.Context,
 	container app.Container,
 	command *Command,
+	parentInterceptors []Interceptor,
+	completing bool,
 	runErrAddr *error,
 ) (*cobra.Command, error) {
 	if err := commandValidate(command); err != nil {
@@ -157,9 +249,24 @@ func commandToCobra(
 	if command.NormalizePersistentFlag != nil {
 		cobraCommand.PersistentFlags().SetNormalizeFunc(normalizeFunc(command.NormalizePersistentFlag))
 	}
+	if len(command.ArgCompletions) > 0 {
+		cobraCommand.ValidArgsFunction = argCompletionsToCobraFunc(command.ArgCompletions)
+	}
+	if completing {
+		for flagName, completion := range command.FlagCompletions {
+			if err := cobraCommand.RegisterFlagCompletionFunc(flagName, completion.cobraFunc()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	interceptors := append(append([]Interceptor{}, parentInterceptors...), command.Interceptors...)
 	if command.Run != nil {
-		cobraCommand.Run = func(_ *cobra.Command, args []string) {
-			*runErrAddr = command.Run(ctx, app.NewContainerForArgs(container, args...))
+		cobraCommand.Run = func(cobraCommand *cobra.Command, args []string) {
+			*runErrAddr = chainInterceptors(
+				command.Run,
+				interceptors,
+				cobraCommand.CommandPath(),
+			)(ctx, app.NewContainerForArgs(container, args...))
 		}
 	}
 	if command.Version != "" {
@@ -167,7 +274,7 @@ func commandToCobra(
 		cobraCommand.Version = command.Version
 	}
 	for _, subCommand := range command.SubCommands {
-		subCobraCommand, err := commandToCobra(ctx, container, subCommand, runErrAddr)
+		subCobraCommand, err := commandToCobra(ctx, container, subCommand, interceptors, completing, runErrAddr)
 		if err != nil {
 			return nil, err
 		}
@@ -192,6 +299,92 @@ func commandValidate(command *Command) error {
 	return nil
 }
 
+func newCompletionCommand(rootCobraCommand *cobra.Command, container app.Container, runErrAddr *error) *cobra.Command {
+	name := rootCobraCommand.Name()
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Print the shell completion script for the given shell.",
+		Long: `Print the shell completion script for the given shell.
+
+To load the completions for the current bash session:
+
+  source <(` + name + ` completion bash)
+
+To load the completions for each zsh session, with compinit enabled:
+
+  ` + name + ` completion zsh > "${fpath[1]}/_` + name + `"
+
+To load the completions for each fish session:
+
+  ` + name + ` completion fish > ~/.config/fish/completions/` + name + `.fish
+
+To load the completions for the current PowerShell session:
+
+  ` + name + ` completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs: completionShells,
+		Args:      cobra.ExactValidArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			switch args[0] {
+			case "bash":
+				*runErrAddr = rootCobraCommand.GenBashCompletion(container.Stdout())
+			case "zsh":
+				*runErrAddr = rootCobraCommand.GenZshCompletion(container.Stdout())
+			case "fish":
+				*runErrAddr = rootCobraCommand.GenFishCompletion(container.Stdout(), true)
+			case "powershell":
+				*runErrAddr = rootCobraCommand.GenPowerShellCompletion(container.Stdout())
+			}
+		},
+	}
+}
+
+// isCompletionArgs returns true if the args complete a command line, or
+// generate a completion script.
+func isCompletionArgs(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd, "completion", "bash-completion", "zsh-completion":
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Completion) cobraFunc() func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if c.Values != nil {
+			return c.Values(toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(c.FileExtensions) > 0 {
+			return c.FileExtensions, cobra.ShellCompDirectiveFilterFileExt
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
+
+func argCompletionsToCobraFunc(argCompletions []*Completion) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cobraCommand *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(argCompletions) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return argCompletions[len(args)].cobraFunc()(cobraCommand, args, toComplete)
+	}
+}
+
+// chainInterceptors returns a RunFunc that calls the interceptors in order before run.
+func chainInterceptors(run RunFunc, interceptors []Interceptor, commandPath string) RunFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := run
+		run = func(ctx context.Context, container app.Container) error {
+			return interceptor(ctx, container, commandPath, next)
+		}
+	}
+	return run
+}
+
 func normalizeFunc(f func(*pflag.FlagSet, string) string) func(*pflag.FlagSet, string) pflag.NormalizedName {
 	return func(flagSet *pflag.FlagSet, name string) pflag.NormalizedName {
 		return pflag.NormalizedName(f(flagSet, name))
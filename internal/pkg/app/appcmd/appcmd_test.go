@@ -15,7 +15,9 @@
 package appcmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -106,3 +108,128 @@ func TestError(t *testing.T) {
 	)
 	require.Equal(t, app.NewError(5, "bar"), Run(context.Background(), container, rootCommand))
 }
+
+func TestInterceptors(t *testing.T) {
+	var calls []string
+	newInterceptor := func(name string) Interceptor {
+		return func(ctx context.Context, container app.Container, commandPath string, next RunFunc) error {
+			calls = append(calls, name+":"+commandPath)
+			if name == "sub" {
+				// rewrite the arguments
+				return next(ctx, app.NewContainerForArgs(container, "rewritten"))
+			}
+			return next(ctx, container)
+		}
+	}
+	var actualArgs []string
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				Run: func(ctx context.Context, container app.Container) error {
+					calls = append(calls, "run")
+					actualArgs = app.Args(container)
+					return nil
+				},
+				Interceptors: []Interceptor{
+					newInterceptor("sub"),
+				},
+			},
+		},
+		Interceptors: []Interceptor{
+			newInterceptor("one"),
+			newInterceptor("two"),
+		},
+	}
+	container := app.NewContainer(
+		nil,
+		nil,
+		nil,
+		nil,
+		"test",
+		"sub",
+		"arg",
+	)
+	require.NoError(t, Run(context.Background(), container, rootCommand))
+	assert.Equal(t, []string{"one:test sub", "two:test sub", "sub:test sub", "run"}, calls)
+	assert.Equal(t, []string{"rewritten"}, actualArgs)
+}
+
+func TestInterceptorError(t *testing.T) {
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				Run: func(ctx context.Context, container app.Container) error {
+					return errors.New("should not run")
+				},
+			},
+		},
+		Interceptors: []Interceptor{
+			func(ctx context.Context, container app.Container, commandPath string, next RunFunc) error {
+				return app.NewError(5, "denied")
+			},
+		},
+	}
+	container := app.NewContainer(
+		nil,
+		nil,
+		nil,
+		nil,
+		"test",
+		"sub",
+	)
+	require.Equal(t, app.NewError(5, "denied"), Run(context.Background(), container, rootCommand))
+}
+
+func TestCompletion(t *testing.T) {
+	var format string
+	rootCommand := &Command{
+		Use: "test",
+		SubCommands: []*Command{
+			{
+				Use: "sub",
+				BindFlags: func(flagSet *pflag.FlagSet) {
+					flagSet.StringVar(&format, "format", "", "Format.")
+					flagSet.StringSlice("file", nil, "Files.")
+				},
+				Run: func(ctx context.Context, container app.Container) error {
+					return errors.New("should not run")
+				},
+				FlagCompletions: map[string]*Completion{
+					"format": CompleteValues("json", "text", "junit"),
+					"file":   CompleteFileExtensions("proto"),
+				},
+				ArgCompletions: []*Completion{
+					CompleteValues("one", "two"),
+				},
+			},
+		},
+	}
+	assert.Equal(t, "json\njunit\n:4\n", testRunCompletion(t, rootCommand, "__complete", "sub", "--format", "j"))
+	assert.Equal(t, "proto\n:8\n", testRunCompletion(t, rootCommand, "__complete", "sub", "--file", ""))
+	assert.Equal(t, "two\n:4\n", testRunCompletion(t, rootCommand, "__complete", "sub", "t"))
+	// only the first argument is completed
+	assert.Equal(t, ":4\n", testRunCompletion(t, rootCommand, "__complete", "sub", "one", ""))
+	assert.Contains(t, testRunCompletion(t, rootCommand, "completion", "bash"), "__test_handle_go_custom_completion")
+	for _, shell := range []string{"zsh", "fish", "powershell"} {
+		assert.NotEmpty(t, testRunCompletion(t, rootCommand, "completion", shell))
+	}
+	container := app.NewContainer(nil, nil, nil, nil, "test", "completion", "tcsh")
+	assert.Error(t, Run(context.Background(), container, rootCommand))
+}
+
+func testRunCompletion(t *testing.T, rootCommand *Command, args ...string) string {
+	stdout := bytes.NewBuffer(nil)
+	container := app.NewContainer(
+		nil,
+		nil,
+		stdout,
+		ioutil.Discard,
+		append([]string{"test"}, args...)...,
+	)
+	require.NoError(t, Run(context.Background(), container, rootCommand))
+	return stdout.String()
+}
@@ -12,6 +12,10 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package appcmdtesting provides a harness for testing appcmd Commands.
+//
+// Commands are run with an in-memory environment, stdin, stdout, and stderr,
+// and the exit code and output can be compared to the expected values.
 package appcmdtesting
 
 import (
@@ -65,6 +69,25 @@ func RunCommandSuccess(
 	RunCommandExitCode(t, newCommand, 0, env, stdin, stdout, args...)
 }
 
+// RunCommandExitCodeStdoutStderr runs the command and compares the exit code, stdout output, and stderr output.
+func RunCommandExitCodeStdoutStderr(
+	t *testing.T,
+	newCommand func(string) *appcmd.Command,
+	expectedExitCode int,
+	expectedStdout string,
+	expectedStderr string,
+	env map[string]string,
+	stdin io.Reader,
+	args ...string,
+) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := RunCommand(context.Background(), newCommand, env, stdin, stdout, stderr, args...)
+	require.Equal(t, expectedExitCode, exitCode, stringutil.TrimLines(stderr.String()))
+	require.Equal(t, stringutil.TrimLines(expectedStdout), stringutil.TrimLines(stdout.String()))
+	require.Equal(t, stringutil.TrimLines(expectedStderr), stringutil.TrimLines(stderr.String()))
+}
+
 // RunCommandExitCode runs the command and compares the exit code.
 func RunCommandExitCode(
 	t *testing.T,
@@ -76,9 +99,30 @@ func RunCommandExitCode(
 	args ...string,
 ) {
 	stderr := bytes.NewBuffer(nil)
-	exitCode := app.GetExitCode(
+	exitCode := RunCommand(context.Background(), newCommand, env, stdin, stdout, stderr, args...)
+	require.Equal(t, expectedExitCode, exitCode, stringutil.TrimLines(stderr.String()))
+}
+
+// RunCommand runs the command and returns the exit code.
+//
+// The command is created with newCommand("test") and run with the given
+// environment, stdin, stdout, stderr, and args, where args do not include
+// the name of the command. Any of env, stdin, stdout, and stderr can be nil.
+//
+// This does not depend on the testing package, so it can be used with any
+// test framework.
+func RunCommand(
+	ctx context.Context,
+	newCommand func(string) *appcmd.Command,
+	env map[string]string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	args ...string,
+) int {
+	return app.GetExitCode(
 		appcmd.Run(
-			context.Background(),
+			ctx,
 			app.NewContainer(
 				env,
 				stdin,
@@ -89,5 +133,4 @@ func RunCommandExitCode(
 			newCommand("test"),
 		),
 	)
-	require.Equal(t, expectedExitCode, exitCode, stringutil.TrimLines(stderr.String()))
 }
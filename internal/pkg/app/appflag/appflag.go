@@ -21,6 +21,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/spf13/pflag"
 )
 
@@ -44,3 +45,21 @@ func BuilderWithTimeout(defaultTimeout time.Duration) BuilderOption {
 		builder.defaultTimeout = defaultTimeout
 	}
 }
+
+// BuilderWithName returns a new BuilderOption that sets the name of the app,
+// which is the service name of the metrics written by --metrics-out.
+func BuilderWithName(name string) BuilderOption {
+	return func(builder *builder) {
+		builder.name = name
+	}
+}
+
+// BuilderWithHook returns a new BuilderOption that calls the Hook with the
+// Span of every instrument.Timer logged by the run functions.
+//
+// This can be used to export the timings of each phase to other systems.
+func BuilderWithHook(hook instrument.Hook) BuilderOption {
+	return func(builder *builder) {
+		builder.hooks = append(builder.hooks, hook)
+	}
+}
@@ -15,21 +15,31 @@
 package appflag
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/app/applog"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/pkg/profile"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
 type builder struct {
 	logLevel  string
 	logFormat string
+	color     string
+	verbose   bool
+	timing    bool
+
+	metricsOut    string
+	metricsFormat string
 
 	profile           bool
 	profilePath       string
@@ -40,6 +50,8 @@ type builder struct {
 	timeout time.Duration
 
 	defaultTimeout time.Duration
+	hooks          []instrument.Hook
+	name           string
 }
 
 func newBuilder(options ...BuilderOption) *builder {
@@ -52,9 +64,31 @@ func newBuilder(options ...BuilderOption) *builder {
 
 func (b *builder) BindRoot(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&b.logLevel, "log-level", "info", "The log level [debug,info,warn,error].")
-	flagSet.StringVar(&b.logFormat, "log-format", "color", "The log format [text,color,json].")
+	flagSet.StringVar(&b.logFormat, "log-format", "", "The log format [text,color,json]. Defaults to color if color is enabled, and text otherwise.")
+	flagSet.StringVar(
+		&b.color,
+		"color",
+		"auto",
+		fmt.Sprintf(
+			"When to use color [%s]. With auto, color is used if stderr is a terminal, NO_COLOR is not set, and not running in CI.",
+			strings.Join(app.AllColorModeStrings, ","),
+		),
+	)
+	flagSet.BoolVar(&b.verbose, "verbose", false, "Print progress to stderr, such as when fetching inputs, compiling files, and running checks.")
+	flagSet.BoolVar(&b.timing, "timing", false, "Print a table of the time spent in each phase to stderr when done.")
+	flagSet.StringVar(&b.metricsOut, "metrics-out", "", `Write the timing of each phase and check, and the number of failures of each check, to this file when done.
+Each phase and check is written as a span, which can be collected across runs for dashboards.`)
+	flagSet.StringVar(
+		&b.metricsFormat,
+		"metrics-format",
+		"json",
+		fmt.Sprintf(
+			"The format of --metrics-out [%s]. With otlp, the spans are written as an OpenTelemetry OTLP/JSON export request.",
+			strings.Join(instrument.AllMetricsFormatStrings, ","),
+		),
+	)
 	if b.defaultTimeout > 0 {
-		flagSet.DurationVar(&b.timeout, "timeout", b.defaultTimeout, `The duration until timing out.`)
+		flagSet.DurationVar(&b.timeout, "timeout", b.defaultTimeout, `The duration until timing out, including fetching and building inputs. Set to 0 for no timeout.`)
 	}
 
 	flagSet.BoolVar(&b.profile, "profile", false, "Run profiling.")
@@ -81,8 +115,39 @@ func (b *builder) run(
 	ctx context.Context,
 	appContainer app.Container,
 	f func(context.Context, applog.Container) error,
-) error {
-	logger, err := applog.NewLogger(appContainer.Stderr(), b.logLevel, b.logFormat)
+) (retErr error) {
+	colorMode, err := app.ParseColorMode(b.color)
+	if err != nil {
+		return err
+	}
+	var loggerOptions []applog.LoggerOption
+	if b.verbose {
+		loggerOptions = append(loggerOptions, applog.LoggerWithProgress())
+	}
+	var timings instrument.Timings
+	if b.timing {
+		timings = instrument.NewTimings()
+		loggerOptions = append(loggerOptions, applog.LoggerWithCore(timings.Core()))
+	}
+	var metrics instrument.Metrics
+	var metricsFormat instrument.MetricsFormat
+	if b.metricsOut != "" {
+		metricsFormat, err = instrument.ParseMetricsFormat(b.metricsFormat)
+		if err != nil {
+			return err
+		}
+		metrics = instrument.NewMetrics()
+		loggerOptions = append(loggerOptions, applog.LoggerWithCore(instrument.NewHookCore(metrics)))
+	}
+	for _, hook := range b.hooks {
+		loggerOptions = append(loggerOptions, applog.LoggerWithCore(instrument.NewHookCore(hook)))
+	}
+	logger, err := applog.NewLogger(
+		appContainer.Stderr(),
+		b.logLevel,
+		applog.GetFormat(appContainer, appContainer.Stderr(), b.logFormat, colorMode),
+		loggerOptions...,
+	)
 	if err != nil {
 		return err
 	}
@@ -91,10 +156,23 @@ func (b *builder) run(
 	defer func() {
 		logger.Debug("end", zap.Duration("duration", time.Since(start)))
 	}()
+	if timings != nil {
+		// printed even if f fails, as that is often when the timings matter
+		defer func() {
+			retErr = multierr.Append(retErr, timings.Print(appContainer.Stderr()))
+		}()
+	}
+	if metrics != nil {
+		// also written even if f fails
+		defer func() {
+			retErr = multierr.Append(retErr, writeMetrics(metrics, metricsFormat, b.name, b.metricsOut))
+		}()
+	}
 
 	var cancel context.CancelFunc
 	if !b.profile && b.timeout != 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), b.timeout)
+		// derived from ctx so that the run is still stopped on interrupt signal
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
 		defer cancel()
 	}
 
@@ -113,6 +191,23 @@ func (b *builder) run(
 	)
 }
 
+// writeMetrics writes the metrics to the file path.
+func writeMetrics(
+	metrics instrument.Metrics,
+	metricsFormat instrument.MetricsFormat,
+	serviceName string,
+	filePath string,
+) error {
+	if serviceName == "" {
+		serviceName = "app"
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := metrics.Print(buffer, metricsFormat, serviceName); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, buffer.Bytes(), 0644)
+}
+
 // runProfile profiles the function.
 func runProfile(
 	logger *zap.Logger,
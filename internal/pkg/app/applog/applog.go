@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/bufbuild/buf/internal/pkg/zaputil"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -42,7 +43,11 @@ func NewContainer(appContainer app.Container, logger *zap.Logger) Container {
 //
 // The level can be [debug,info,warn,error]. The default is info.
 // The format can be [text,color,json]. The default is color.
-func NewLogger(writer io.Writer, levelString string, format string) (*zap.Logger, error) {
+func NewLogger(writer io.Writer, levelString string, format string, options ...LoggerOption) (*zap.Logger, error) {
+	loggerOptions := newLoggerOptions()
+	for _, option := range options {
+		option(loggerOptions)
+	}
 	level, err := getZapLevel(levelString)
 	if err != nil {
 		return nil, err
@@ -51,7 +56,72 @@ func NewLogger(writer io.Writer, levelString string, format string) (*zap.Logger
 	if err != nil {
 		return nil, err
 	}
-	return zaputil.NewLogger(writer, level, encoder), nil
+	logger := zaputil.NewLogger(writer, level, encoder)
+	cores := loggerOptions.cores
+	if loggerOptions.progress {
+		progressEncoder, err := getZapEncoder(format)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, instrument.NewProgressCore(progressEncoder, zapcore.Lock(zapcore.AddSync(writer))))
+	}
+	if len(cores) == 0 {
+		return logger, nil
+	}
+	return logger.WithOptions(
+		zap.WrapCore(
+			func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+			},
+		),
+	), nil
+}
+
+// LoggerOption is an option for a new Logger.
+type LoggerOption func(*loggerOptions)
+
+// LoggerWithProgress returns a new LoggerOption that also writes progress
+// logged with instrument.Progress, regardless of the level.
+func LoggerWithProgress() LoggerOption {
+	return func(loggerOptions *loggerOptions) {
+		loggerOptions.progress = true
+	}
+}
+
+// LoggerWithCore returns a new LoggerOption that also logs to the core.
+func LoggerWithCore(core zapcore.Core) LoggerOption {
+	return func(loggerOptions *loggerOptions) {
+		loggerOptions.cores = append(loggerOptions.cores, core)
+	}
+}
+
+// GetFormat returns the log format to use for logs written to the writer.
+//
+// If format is empty, this is color if color is enabled for the writer, and
+// text otherwise. An explicit color format is replaced with text only if
+// colorMode is app.ColorModeNever.
+func GetFormat(envContainer app.EnvContainer, writer io.Writer, format string, colorMode app.ColorMode) string {
+	switch strings.TrimSpace(strings.ToLower(format)) {
+	case "":
+		if app.IsColorEnabled(envContainer, writer, colorMode) {
+			return "color"
+		}
+		return "text"
+	case "color":
+		if colorMode == app.ColorModeNever {
+			return "text"
+		}
+	}
+	return format
+}
+
+type loggerOptions struct {
+	progress bool
+	cores    []zapcore.Core
+}
+
+func newLoggerOptions() *loggerOptions {
+	return &loggerOptions{}
 }
 
 func getZapLevel(level string) (zapcore.Level, error) {
@@ -47,6 +47,13 @@ var (
 		"python": {},
 		"ruby":   {},
 	}
+
+	// pluginNameToInstallCommand are the commands to install well-known plugins
+	// that are not builtin to protoc, used to diagnose missing plugins.
+	pluginNameToInstallCommand = map[string]string{
+		"go":      "go install google.golang.org/protobuf/cmd/protoc-gen-go@latest",
+		"go-grpc": "go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest",
+	}
 )
 
 // NewHandler returns a new Handler based on the plugin name and optional path.
@@ -57,7 +64,7 @@ var (
 // - If the plugin path is unset, this does exec.LookPath for a binary named protoc-gen-pluginName,
 //   and if one is found, a new binary handler is returned for this.
 // - Else, if the name is in ProtocProxyPluginNames, this returns a new protoc proxy handler.
-// - Else, this returns error.
+// - Else, this returns error, which describes how to install well-known plugins.
 func NewHandler(
 	logger *zap.Logger,
 	pluginName string,
@@ -75,9 +82,31 @@ func NewHandler(
 		if protocPath == "" {
 			protocPath = "protoc"
 		}
-		return NewProtocProxyHandler(logger, protocPath, pluginName)
+		handler, err := NewProtocProxyHandler(logger, protocPath, pluginName)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not find protoc plugin for name %s: %s is builtin to protoc, but %s was not found: %v",
+				pluginName,
+				pluginName,
+				protocPath,
+				err,
+			)
+		}
+		return handler, nil
 	}
-	return nil, fmt.Errorf("could not find protoc plugin for name %s", pluginName)
+	if installCommand, ok := pluginNameToInstallCommand[pluginName]; ok {
+		return nil, fmt.Errorf(
+			"could not find protoc plugin for name %s: protoc-gen-%s was not found on the PATH, install it with %q or set the plugin path",
+			pluginName,
+			pluginName,
+			installCommand,
+		)
+	}
+	return nil, fmt.Errorf(
+		"could not find protoc plugin for name %s: protoc-gen-%s was not found on the PATH, install it or set the plugin path",
+		pluginName,
+		pluginName,
+	)
 }
 
 // NewBinaryHandler returns a new Handler for the given plugin path.
@@ -87,11 +116,11 @@ func NewBinaryHandler(
 	logger *zap.Logger,
 	pluginPath string,
 ) (appproto.Handler, error) {
-	pluginPath, err := exec.LookPath(pluginPath)
+	resolvedPluginPath, err := exec.LookPath(pluginPath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not find protoc plugin at path %s: %v", pluginPath, err)
 	}
-	return newBinaryHandler(logger, pluginPath), nil
+	return newBinaryHandler(logger, resolvedPluginPath), nil
 }
 
 // NewProtocProxyHandler returns a new Handler that proxies through protoc.
@@ -0,0 +1,37 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appprotoexec
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewHandlerNotFound(t *testing.T) {
+	t.Parallel()
+	_, err := NewHandler(zap.NewNop(), "buf-test-not-found", "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protoc-gen-buf-test-not-found was not found on the PATH")
+	_, err = NewHandler(zap.NewNop(), "java", filepath.Join("testdata", "not-found", "protoc"), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "java is builtin to protoc")
+	_, err = NewHandler(zap.NewNop(), "go", "", filepath.Join("testdata", "not-found", "protoc-gen-go"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not find protoc plugin at path")
+}
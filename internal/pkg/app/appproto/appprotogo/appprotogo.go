@@ -0,0 +1,81 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package appprotogo provides an appproto.Handler for protoc-gen-go that runs in-process.
+package appprotogo
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/app/appproto"
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// PluginName is the name of the plugin, as in --go_out.
+const PluginName = "go"
+
+// NewHandler returns a new Handler that generates Go code with the version of
+// protoc-gen-go that buf is built with.
+//
+// The options are the same as for protoc-gen-go, such as paths=source_relative.
+func NewHandler() appproto.Handler {
+	return appproto.HandlerFunc(handle)
+}
+
+func handle(
+	ctx context.Context,
+	container app.EnvStderrContainer,
+	responseWriter appproto.ResponseWriter,
+	request *pluginpb.CodeGeneratorRequest,
+) error {
+	var flags flag.FlagSet
+	plugins := flags.String("plugins", "", "deprecated option")
+	importPrefix := flags.String("import_prefix", "", "deprecated option")
+	plugin, err := protogen.Options{
+		ParamFunc: flags.Set,
+	}.New(request)
+	if err != nil {
+		return err
+	}
+	if *plugins != "" {
+		return errors.New("protoc-gen-go: plugins are not supported; use 'protoc --go-grpc_out=...' to generate gRPC")
+	}
+	if *importPrefix != "" {
+		return errors.New("protoc-gen-go: import_prefix is not supported")
+	}
+	for _, file := range plugin.Files {
+		if file.Generate {
+			gengo.GenerateFile(plugin, file)
+		}
+	}
+	plugin.SupportedFeatures = gengo.SupportedFeatures
+	response := plugin.Response()
+	if response.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) != 0 {
+		responseWriter.SetFeatureProto3Optional()
+	}
+	for _, file := range response.File {
+		if err := responseWriter.Add(file); err != nil {
+			return err
+		}
+	}
+	if response.Error != nil {
+		return responseWriter.AddError(response.GetError())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"strings"
+)
+
+// ciEnvKeys are environment variables that are set by common CI providers.
+//
+// CI is set by most providers, including GitHub Actions, GitLab CI, CircleCI,
+// Travis CI, and Buildkite. The others cover providers that do not set CI.
+var ciEnvKeys = []string{
+	"CI",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"CIRCLECI",
+	"TRAVIS",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+}
+
+// IsCI returns true if the environment is a continuous integration environment.
+//
+// Setting CI to "false" or "0" overrides the detection.
+func IsCI(envContainer EnvContainer) bool {
+	switch strings.ToLower(envContainer.Env("CI")) {
+	case "false", "0":
+		return false
+	}
+	for _, key := range ciEnvKeys {
+		if envContainer.Env(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGitHubActions returns true if the environment is GitHub Actions.
+//
+// Setting CI to "false" or "0" overrides the detection, as with IsCI.
+func IsGitHubActions(envContainer EnvContainer) bool {
+	return IsCI(envContainer) && envContainer.Env("GITHUB_ACTIONS") != ""
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ColorModeAuto enables color if the output is a terminal and color
+	// is not otherwise disabled.
+	ColorModeAuto ColorMode = iota + 1
+	// ColorModeAlways always enables color.
+	ColorModeAlways
+	// ColorModeNever never enables color.
+	ColorModeNever
+)
+
+var (
+	// AllColorModeStrings are all color mode strings.
+	AllColorModeStrings = []string{
+		"auto",
+		"always",
+		"never",
+	}
+
+	colorModeToString = map[ColorMode]string{
+		ColorModeAuto:   "auto",
+		ColorModeAlways: "always",
+		ColorModeNever:  "never",
+	}
+	stringToColorMode = map[string]ColorMode{
+		"auto":   ColorModeAuto,
+		"always": ColorModeAlways,
+		"never":  ColorModeNever,
+	}
+)
+
+// ColorMode is a color mode.
+type ColorMode int
+
+// String implements fmt.Stringer.
+func (c ColorMode) String() string {
+	s, ok := colorModeToString[c]
+	if !ok {
+		return strconv.Itoa(int(c))
+	}
+	return s
+}
+
+// ParseColorMode parses the ColorMode.
+//
+// The empty string defaults to ColorModeAuto.
+func ParseColorMode(s string) (ColorMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ColorModeAuto, nil
+	}
+	c, ok := stringToColorMode[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown color mode: %q", s)
+	}
+	return c, nil
+}
+
+// IsColorEnabled returns true if colored output should be written to the writer.
+//
+// This is the color policy for all colored output. With ColorModeAuto, color is
+// disabled if NO_COLOR is set, TERM is dumb, this is running in CI, or the
+// writer is not a terminal.
+func IsColorEnabled(envContainer EnvContainer, writer io.Writer, colorMode ColorMode) bool {
+	switch colorMode {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	}
+	if envContainer.Env("NO_COLOR") != "" {
+		return false
+	}
+	if envContainer.Env("TERM") == "dumb" {
+		return false
+	}
+	if IsCI(envContainer) {
+		return false
+	}
+	return IsTerminal(writer)
+}
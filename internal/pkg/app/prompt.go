@@ -0,0 +1,70 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+)
+
+// IsTerminal returns true if the value is a file that is a terminal.
+//
+// Character devices that are not terminals, such as /dev/null, are not
+// terminals.
+//
+// This is used to check if the stdin, stdout, or stderr of a container is attached to a TTY.
+func IsTerminal(value interface{}) bool {
+	if writer, ok := value.(io.Writer); ok {
+		value = ioutilextended.UnwrapWriter(writer)
+	}
+	file, ok := value.(*os.File)
+	if !ok {
+		return false
+	}
+	return isTerminal(file)
+}
+
+// Confirm prompts the user to confirm the message on stderr, and reads the
+// answer from stdin.
+//
+// Only "y" and "yes" are treated as confirmation, case-insensitively.
+//
+// If stdin is not a terminal, or this is running in CI, this does not prompt
+// and returns true, so that non-interactive use is not blocked. Callers should
+// skip calling Confirm altogether if the user has already confirmed, for
+// example with --yes.
+func Confirm(container EnvStdioContainer, message string) (bool, error) {
+	if IsCI(container) || !IsTerminal(container.Stdin()) {
+		return true, nil
+	}
+	if _, err := fmt.Fprintf(container.Stderr(), "%s [y/N] ", message); err != nil {
+		return false, err
+	}
+	answer, err := bufio.NewReader(container.Stdin()).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
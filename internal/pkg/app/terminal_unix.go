@@ -0,0 +1,38 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build darwin linux
+
+package app
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal returns true if the file is a terminal, that is if its terminal
+// attributes can be read.
+//
+// Other character devices such as /dev/null are not terminals.
+func isTerminal(file *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		file.Fd(),
+		ioctlReadTermios,
+		uintptr(unsafe.Pointer(&termios)),
+	)
+	return errno == 0
+}
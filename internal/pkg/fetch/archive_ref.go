@@ -14,17 +14,23 @@
 
 package fetch
 
+import (
+	"github.com/bufbuild/buf/internal/pkg/storage"
+)
+
 var (
 	_ ParsedArchiveRef = &archiveRef{}
 )
 
 type archiveRef struct {
-	format          string
-	path            string
-	fileScheme      FileScheme
-	archiveType     ArchiveType
-	compressionType CompressionType
-	stripComponents uint32
+	format           string
+	path             string
+	fileScheme       FileScheme
+	archiveType      ArchiveType
+	compressionType  CompressionType
+	compressionLevel int
+	stripComponents  uint32
+	symlinkPolicy    storage.SymlinkPolicy
 }
 
 func newArchiveRef(
@@ -32,6 +38,7 @@ func newArchiveRef(
 	path string,
 	archiveType ArchiveType,
 	compressionType CompressionType,
+	compressionLevel int,
 	stripComponents uint32,
 ) (*archiveRef, error) {
 	if archiveType == ArchiveTypeZip && compressionType != CompressionTypeNone {
@@ -41,6 +48,7 @@ func newArchiveRef(
 		format,
 		path,
 		compressionType,
+		compressionLevel,
 	)
 	if err != nil {
 		return nil, err
@@ -51,6 +59,7 @@ func newArchiveRef(
 		singleRef.FileScheme(),
 		archiveType,
 		compressionType,
+		compressionLevel,
 		stripComponents,
 	), nil
 }
@@ -61,15 +70,17 @@ func buildArchiveRef(
 	fileScheme FileScheme,
 	archiveType ArchiveType,
 	compressionType CompressionType,
+	compressionLevel int,
 	stripComponents uint32,
 ) *archiveRef {
 	return &archiveRef{
-		format:          format,
-		path:            path,
-		fileScheme:      fileScheme,
-		archiveType:     archiveType,
-		compressionType: compressionType,
-		stripComponents: stripComponents,
+		format:           format,
+		path:             path,
+		fileScheme:       fileScheme,
+		archiveType:      archiveType,
+		compressionType:  compressionType,
+		compressionLevel: compressionLevel,
+		stripComponents:  stripComponents,
 	}
 }
 
@@ -93,10 +104,21 @@ func (r *archiveRef) CompressionType() CompressionType {
 	return r.compressionType
 }
 
+func (r *archiveRef) CompressionLevel() int {
+	return r.compressionLevel
+}
+
 func (r *archiveRef) StripComponents() uint32 {
 	return r.stripComponents
 }
 
+func (r *archiveRef) SymlinkPolicy() storage.SymlinkPolicy {
+	if r.symlinkPolicy == 0 {
+		return storage.SymlinkPolicySkip
+	}
+	return r.symlinkPolicy
+}
+
 func (*archiveRef) ref()        {}
 func (*archiveRef) fileRef()    {}
 func (*archiveRef) bucketRef()  {}
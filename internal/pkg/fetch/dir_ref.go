@@ -19,6 +19,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 )
 
 var (
@@ -26,8 +27,9 @@ var (
 )
 
 type dirRef struct {
-	format string
-	path   string
+	format        string
+	path          string
+	symlinkPolicy storage.SymlinkPolicy
 }
 
 func newDirRef(
@@ -70,6 +72,13 @@ func (r *dirRef) Path() string {
 	return r.path
 }
 
+func (r *dirRef) SymlinkPolicy() storage.SymlinkPolicy {
+	if r.symlinkPolicy == 0 {
+		return storage.SymlinkPolicySkip
+	}
+	return r.symlinkPolicy
+}
+
 func (*dirRef) ref()       {}
 func (*dirRef) bucketRef() {}
 func (*dirRef) dirRef()    {}
@@ -48,6 +48,10 @@ func newCannotSpecifyGitBranchAndTagError() error {
 	return fmt.Errorf(`must specify only one of "branch", "tag"`)
 }
 
+func newCannotSpecifyRecurseSubmodulesAndSubmodulesError() error {
+	return fmt.Errorf(`must specify only one of "recurse_submodules", "submodules"`)
+}
+
 func newCannotSpecifyTagWithRefError() error {
 	return fmt.Errorf(`cannot specify "tag" with "ref"`)
 }
@@ -68,10 +72,22 @@ func newCompressionUnknownError(compression string, valid ...string) error {
 	return fmt.Errorf("unknown compression: %q (valid values are %q)", compression, strings.Join(valid, ","))
 }
 
+func newSymlinksUnknownError(symlinks string, valid ...string) error {
+	return fmt.Errorf("unknown symlinks: %q (valid values are %q)", symlinks, strings.Join(valid, ","))
+}
+
 func newCannotSpecifyCompressionForZipError() error {
 	return errors.New("cannot specify compression type for zip files")
 }
 
+func newCompressionLevelWithoutCompressionError() error {
+	return errors.New("cannot specify compression level without compression")
+}
+
+func newCompressionLevelOutOfRangeError(compression string, level int, min int, max int) error {
+	return fmt.Errorf("compression level %d for %s must be between %d and %d", level, compression, min, max)
+}
+
 func newNoPathError() error {
 	return errors.New("value has no path once processed")
 }
@@ -96,8 +112,12 @@ func newOptionsCouldNotParseStripComponentsError(s string) error {
 	return fmt.Errorf("could not parse strip_components value %q", s)
 }
 
-func newOptionsCouldNotParseRecurseSubmodulesError(s string) error {
-	return fmt.Errorf("could not parse recurse_submodules value %q", s)
+func newOptionsCouldNotParseCompressionLevelError(s string) error {
+	return fmt.Errorf("could not parse level value %q", s)
+}
+
+func newOptionsCouldNotParseRecurseSubmodulesError(key string, s string) error {
+	return fmt.Errorf("could not parse %s value %q", key, s)
 }
 
 func newFormatOverrideNotAllowedForDevNullError(devNull string) error {
@@ -128,6 +148,38 @@ func newReadHTTPDisabledError() error {
 	return newReadDisabledError("http")
 }
 
+func newReadInsecureHTTPDisabledError() error {
+	return errors.New("reading assets from plain http disabled, use https or explicitly allow insecure http")
+}
+
+func newReadS3DisabledError() error {
+	return newReadDisabledError("s3")
+}
+
+func newReadGCSDisabledError() error {
+	return newReadDisabledError("gs")
+}
+
+func newReadOCIDisabledError() error {
+	return newReadDisabledError("oci")
+}
+
+func newReadGRPCDisabledError() error {
+	return newReadDisabledError("grpc")
+}
+
+func newReadCustomSchemeDisabledError(scheme string) error {
+	return fmt.Errorf("reading assets from %s disabled, no reader is registered for the scheme", scheme)
+}
+
+func newReadOfflineError(rawURL string) error {
+	return fmt.Errorf("cannot read %s, network access is disabled in offline mode", rawURL)
+}
+
+func newInvalidBucketPathError(scheme string, path string) error {
+	return fmt.Errorf("invalid %s path, must be of the form %s://bucket/path: %q", scheme, scheme, path)
+}
+
 func newReadGitDisabledError() error {
 	return newReadDisabledError("git")
 }
@@ -140,6 +192,21 @@ func newReadStdioDisabledError() error {
 	return newReadDisabledError("stdin")
 }
 
+func newReadStdinTerminalError(fileRef FileRef) error {
+	data := "the input"
+	example := "input"
+	if parsedRef, ok := fileRef.(HasFormat); ok && parsedRef.Format() != "" {
+		data = parsedRef.Format() + " data"
+		example = "input." + parsedRef.Format()
+	}
+	return fmt.Errorf(
+		`stdin is an interactive terminal, pipe or redirect %s to stdin instead, such as with "cat %s |" or "< %s"`,
+		data,
+		example,
+		example,
+	)
+}
+
 func newWriteDisabledError(scheme string) error {
 	return fmt.Errorf("writing assets to %s disabled", scheme)
 }
@@ -148,6 +215,10 @@ func newWriteHTTPDisabledError() error {
 	return newWriteDisabledError("http")
 }
 
+func newWriteOCIDisabledError() error {
+	return newWriteDisabledError("oci")
+}
+
 func newWriteLocalDisabledError() error {
 	return newWriteDisabledError("local")
 }
@@ -18,10 +18,13 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/git"
 	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
+	"github.com/bufbuild/buf/internal/pkg/oci"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
@@ -44,6 +47,27 @@ const (
 	FileSchemeStdout
 	// FileSchemeNull is the null file scheme.
 	FileSchemeNull
+	// FileSchemeS3 is the s3 file scheme.
+	FileSchemeS3
+	// FileSchemeGCS is the gs file scheme.
+	FileSchemeGCS
+	// FileSchemeOCI is the oci file scheme.
+	//
+	// The path is a reference to an artifact in an OCI registry, see oci.ParseReference.
+	FileSchemeOCI
+	// FileSchemeGRPC is the grpc file scheme.
+	//
+	// The path is the host:port of a gRPC server. Readers of this package cannot
+	// read these references, callers are expected to handle them, see
+	// FileRef.FileScheme.
+	FileSchemeGRPC
+	// FileSchemeCustom is a file scheme that is not known to this package,
+	// such as artifact://.
+	//
+	// The path is the full URL, including the scheme. Readers can only read
+	// these references if a FileOpener is registered for the scheme, see
+	// WithReaderFileOpener.
+	FileSchemeCustom
 
 	// GitSchemeHTTP is the http git scheme.
 	GitSchemeHTTP GitScheme = iota + 1
@@ -83,7 +107,8 @@ type CompressionType int
 type Ref interface {
 	// Path is the path to.
 	//
-	// This will be the non-empty path minus the scheme for http and https files.
+	// This will be the non-empty path minus the scheme for http, https, s3, gs, and oci files.
+	// This will be the non-empty path including the scheme for custom files.
 	// This will be the non-empty normalized file path for local files.
 	// This will be empty for stdio and null files.
 	// This will be the non-empty normalized directory path for directories.
@@ -98,6 +123,10 @@ type FileRef interface {
 	Ref
 	FileScheme() FileScheme
 	CompressionType() CompressionType
+	// CompressionLevel is the compression level to use when writing.
+	//
+	// Will be 0 if the default level for the CompressionType should be used.
+	CompressionLevel() int
 	fileRef()
 }
 
@@ -115,7 +144,7 @@ type SingleRef interface {
 
 // NewSingleRef returns a new SingleRef.
 func NewSingleRef(path string, compressionType CompressionType) (SingleRef, error) {
-	return newSingleRef("", path, compressionType)
+	return newSingleRef("", path, compressionType, 0)
 }
 
 // ArchiveRef is an archive reference.
@@ -127,6 +156,8 @@ type ArchiveRef interface {
 	BucketRef
 	ArchiveType() ArchiveType
 	StripComponents() uint32
+	// Will always be set
+	SymlinkPolicy() storage.SymlinkPolicy
 	archiveRef()
 }
 
@@ -137,12 +168,14 @@ func NewArchiveRef(
 	compressionType CompressionType,
 	stripComponents uint32,
 ) (ArchiveRef, error) {
-	return newArchiveRef("", path, archiveType, compressionType, stripComponents)
+	return newArchiveRef("", path, archiveType, compressionType, 0, stripComponents)
 }
 
 // DirRef is a local directory reference.
 type DirRef interface {
 	BucketRef
+	// Will always be set
+	SymlinkPolicy() storage.SymlinkPolicy
 	dirRef()
 }
 
@@ -304,6 +337,10 @@ type RawRef struct {
 	// Only set for single, archive formats
 	// Cannot be set for zip archives
 	CompressionType CompressionType
+	// Only set for single, archive formats
+	// Only allowed if CompressionType is set
+	// 0 means the default level for the CompressionType
+	CompressionLevel int
 	// Only set for git formats
 	// Only one of GitBranch and GitTag will be set
 	GitBranch string
@@ -323,6 +360,9 @@ type RawRef struct {
 	GitDepth uint32
 	// Only set for archive formats
 	ArchiveStripComponents uint32
+	// Only set for dir, archive formats
+	// 0 means the default, which is storage.SymlinkPolicySkip
+	SymlinkPolicy storage.SymlinkPolicy
 }
 
 // RefParserOption is an RefParser option.
@@ -446,7 +486,97 @@ func WithReaderHTTP(httpClient *http.Client, httpAuthenticator httpauth.Authenti
 	}
 }
 
+// WithReaderInsecureHTTP enables reading over plain, non-TLS http.
+//
+// This has no effect unless HTTP is enabled with WithReaderHTTP. Without
+// this option, http:// file and git references are rejected.
+func WithReaderInsecureHTTP() ReaderOption {
+	return func(reader *reader) {
+		reader.insecureHTTPEnabled = true
+	}
+}
+
+// WithReaderHTTPCache enables caching of files read over HTTP.
+//
+// Responses are cached in the given directory name within the cache
+// directory of the user, see app.CacheDirPath, and are revalidated with
+// the server on every read using the ETag and Last-Modified headers.
+// Responses without either header are not cached.
+//
+// This has no effect unless HTTP is enabled with WithReaderHTTP.
+func WithReaderHTTPCache(dirName string) ReaderOption {
+	return func(reader *reader) {
+		reader.httpCacheDirName = dirName
+	}
+}
+
+// WithReaderS3 enables reading files from s3:// URLs.
+//
+// Files are read over HTTP with the S3 REST API, so this has no effect
+// unless HTTP is enabled with WithReaderHTTP. The given authenticator is
+// used instead of the authenticator given to WithReaderHTTP.
+//
+// The region is read from AWS_REGION or AWS_DEFAULT_REGION. If
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL is set, requests are made to that
+// endpoint with path-style URLs, which allows S3-compatible servers.
+func WithReaderS3(authenticator httpauth.Authenticator) ReaderOption {
+	return func(reader *reader) {
+		reader.s3Authenticator = authenticator
+	}
+}
+
+// WithReaderGCS enables reading files from gs:// URLs.
+//
+// Files are read over HTTP with the Cloud Storage JSON API, so this has no
+// effect unless HTTP is enabled with WithReaderHTTP. The given authenticator
+// is used instead of the authenticator given to WithReaderHTTP.
+//
+// If STORAGE_EMULATOR_HOST is set, requests are made to that host.
+func WithReaderGCS(authenticator httpauth.Authenticator) ReaderOption {
+	return func(reader *reader) {
+		reader.gcsAuthenticator = authenticator
+	}
+}
+
+// WithReaderOCI enables reading files from oci:// references.
+//
+// The file is the single layer of the artifact at the reference.
+func WithReaderOCI(ociClient oci.Client) ReaderOption {
+	return func(reader *reader) {
+		reader.ociClient = ociClient
+	}
+}
+
+// FileOpener opens files for a custom file scheme.
+type FileOpener interface {
+	// OpenFile opens the file at the URL, which includes the scheme.
+	//
+	// The file may be compressed, in which case it is decompressed according
+	// to the compression of the FileRef.
+	OpenFile(ctx context.Context, container app.EnvContainer, url string) (io.ReadCloser, error)
+}
+
+// WithReaderFileOpener enables reading files from references with the given
+// custom scheme, such as "artifact" for artifact://path/to/file.tar, with
+// the given FileOpener.
+//
+// The scheme cannot be one of the schemes known to this package, as those
+// are always parsed as their FileScheme.
+func WithReaderFileOpener(scheme string, fileOpener FileOpener) ReaderOption {
+	return func(reader *reader) {
+		if reader.schemeToFileOpener == nil {
+			reader.schemeToFileOpener = make(map[string]FileOpener)
+		}
+		reader.schemeToFileOpener[strings.ToLower(scheme)] = fileOpener
+	}
+}
+
 // WithReaderGit enables Git.
+//
+// If a local path ending in .git does not exist, the repository that
+// contains its directory is cloned instead, and the bucket is the directory
+// within the clone. That is, .git#branch=main reads the current directory
+// on the main branch from any directory within a repository.
 func WithReaderGit(gitCloner git.Cloner) ReaderOption {
 	return func(reader *reader) {
 		reader.gitEnabled = true
@@ -454,6 +584,49 @@ func WithReaderGit(gitCloner git.Cloner) ReaderOption {
 	}
 }
 
+// WithReaderGitCache enables caching of git clones.
+//
+// Clones are cached in the given directory name within the cache directory
+// of the user, see app.CacheDirPath, and are updated with a fetch on every
+// read, so that only new objects are fetched.
+//
+// This has no effect unless Git is enabled with WithReaderGit.
+func WithReaderGitCache(dirName string) ReaderOption {
+	return func(reader *reader) {
+		reader.gitCacheDirName = dirName
+	}
+}
+
+// WithReaderKeepTemp keeps the directories that archives are extracted to
+// and git repositories are checked out to instead of holding them in memory.
+//
+// The directories are created within os.TempDir() and are never deleted.
+// The path of each directory is logged at the info level so that the
+// contents can be inspected after the command completes.
+func WithReaderKeepTemp() ReaderOption {
+	return func(reader *reader) {
+		reader.keepTemp = true
+	}
+}
+
+// WithReaderNetworkLimiter limits HTTP requests and non-local git clones
+// with the given Limiter.
+//
+// HTTP requests are limited until the response body is closed.
+func WithReaderNetworkLimiter(networkLimiter netlimit.Limiter) ReaderOption {
+	return func(reader *reader) {
+		reader.networkLimiter = networkLimiter
+	}
+}
+
+// WithReaderOffline fails all HTTP requests and non-local git clones,
+// including those that could be served from the cache.
+func WithReaderOffline() ReaderOption {
+	return func(reader *reader) {
+		reader.offline = true
+	}
+}
+
 // WithReaderLocal enables local.
 func WithReaderLocal() ReaderOption {
 	return func(reader *reader) {
@@ -485,6 +658,16 @@ func WithWriterStdio() WriterOption {
 	}
 }
 
+// WithWriterOCI enables writing files to oci:// references.
+//
+// The file is buffered in memory and pushed as the single layer of an
+// artifact when the returned WriteCloser is closed.
+func WithWriterOCI(ociClient oci.Client) WriterOption {
+	return func(writer *writer) {
+		writer.ociClient = ociClient
+	}
+}
+
 // GetParsedRefOption is a GetParsedRef option
 type GetParsedRefOption func(*getParsedRefOptions)
 
@@ -507,6 +690,18 @@ func WithGetFileKeepFileCompression() GetFileOption {
 	}
 }
 
+// WithGetFileMmap says to memory-map local files that are not compressed
+// instead of reading them from the file.
+//
+// The returned io.ReadCloser then has a Bytes() []byte method that returns
+// the mapped data, which must not be used after the io.ReadCloser is closed.
+// This has no effect on platforms that do not support memory-mapping files.
+func WithGetFileMmap() GetFileOption {
+	return func(getFileOptions *getFileOptions) {
+		getFileOptions.mmap = true
+	}
+}
+
 // GetBucketOption is a GetBucket option
 type GetBucketOption func(*getBucketOptions)
 
@@ -15,12 +15,25 @@
 package fetch
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/internal/pkg/tmp"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -56,6 +69,417 @@ func TestRoundTripBinZst(t *testing.T) {
 	)
 }
 
+func TestReadHTTPCache(t *testing.T) {
+	t.Parallel()
+
+	var fullResponseCount int32
+	var notModifiedResponseCount int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.Header.Get("If-None-Match") == `"one"` {
+					atomic.AddInt32(&notModifiedResponseCount, 1)
+					responseWriter.WriteHeader(http.StatusNotModified)
+					return
+				}
+				atomic.AddInt32(&fullResponseCount, 1)
+				responseWriter.Header().Set("ETag", `"one"`)
+				_, _ = responseWriter.Write([]byte("one"))
+			},
+		),
+	)
+	defer server.Close()
+
+	logger := zap.NewNop()
+	refParser := testNewRefParser(logger)
+	reader := NewReader(
+		logger,
+		WithReaderHTTP(server.Client(), httpauth.NewNopAuthenticator()),
+		WithReaderInsecureHTTP(),
+		WithReaderHTTPCache("test"),
+	)
+
+	ctx := context.Background()
+	tmpDir, err := tmp.NewDir("")
+	require.NoError(t, err)
+	container := app.NewContainer(
+		map[string]string{
+			"XDG_CACHE_HOME": tmpDir.AbsPath(),
+		},
+		nil,
+		nil,
+		nil,
+	)
+
+	parsedRef, err := refParser.GetParsedRef(ctx, server.URL+"/file.bin")
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		readCloser, err := reader.GetFile(ctx, container, fileRef)
+		require.NoError(t, err)
+		actualData, err := ioutil.ReadAll(readCloser)
+		require.NoError(t, err)
+		require.NoError(t, readCloser.Close())
+		require.Equal(t, "one", string(actualData))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&fullResponseCount))
+	require.Equal(t, int32(2), atomic.LoadInt32(&notModifiedResponseCount))
+
+	require.NoError(t, tmpDir.Close())
+}
+
+func TestReadHTTPCacheURLCredentials(t *testing.T) {
+	t.Parallel()
+
+	var fullResponseCount int32
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.Header.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+					responseWriter.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if request.Header.Get("If-None-Match") == `"one"` {
+					responseWriter.WriteHeader(http.StatusNotModified)
+					return
+				}
+				atomic.AddInt32(&fullResponseCount, 1)
+				responseWriter.Header().Set("ETag", `"one"`)
+				_, _ = responseWriter.Write([]byte("one"))
+			},
+		),
+	)
+	defer server.Close()
+
+	reader := NewReader(
+		zap.NewNop(),
+		WithReaderHTTP(server.Client(), httpauth.NewURLAuthenticator()),
+		WithReaderHTTPCache("test"),
+	)
+	tmpDir, err := tmp.NewDir("")
+	require.NoError(t, err)
+	env := map[string]string{
+		"XDG_CACHE_HOME": tmpDir.AbsPath(),
+	}
+	value := strings.Replace(server.URL, "https://", "https://user:pass@", 1) + "/file.bin"
+	for i := 0; i < 3; i++ {
+		testReadBucketFile(t, reader, env, value, "one")
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&fullResponseCount))
+	// the credentials are never written to the cache
+	require.NoError(
+		t,
+		filepath.Walk(
+			tmpDir.AbsPath(),
+			func(path string, fileInfo os.FileInfo, err error) error {
+				if err != nil || fileInfo.IsDir() {
+					return err
+				}
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				require.NotContains(t, string(data), "pass", path)
+				return nil
+			},
+		),
+	)
+
+	require.NoError(t, tmpDir.Close())
+}
+
+func TestReadS3(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL.Path != "/bucket/path/to/file.bin" {
+					responseWriter.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if !strings.HasPrefix(request.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+					responseWriter.WriteHeader(http.StatusForbidden)
+					return
+				}
+				_, _ = responseWriter.Write([]byte("one"))
+			},
+		),
+	)
+	defer server.Close()
+
+	testReadBucketFile(
+		t,
+		NewReader(
+			zap.NewNop(),
+			WithReaderHTTP(server.Client(), httpauth.NewNopAuthenticator()),
+			WithReaderS3(httpauth.NewAWSAuthenticator(server.Client())),
+		),
+		map[string]string{
+			"AWS_ENDPOINT_URL":      server.URL,
+			"AWS_ACCESS_KEY_ID":     "id",
+			"AWS_SECRET_ACCESS_KEY": "secret",
+		},
+		"s3://bucket/path/to/file.bin",
+		"one",
+	)
+}
+
+func TestReadGCS(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL.EscapedPath() != "/storage/v1/b/bucket/o/path%2Fto%2Ffile.bin" ||
+					request.URL.Query().Get("alt") != "media" {
+					responseWriter.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = responseWriter.Write([]byte("one"))
+			},
+		),
+	)
+	defer server.Close()
+
+	testReadBucketFile(
+		t,
+		NewReader(
+			zap.NewNop(),
+			WithReaderHTTP(server.Client(), httpauth.NewNopAuthenticator()),
+			WithReaderGCS(httpauth.NewNopAuthenticator()),
+		),
+		map[string]string{
+			"STORAGE_EMULATOR_HOST": strings.TrimPrefix(server.URL, "http://"),
+		},
+		"gs://bucket/path/to/file.bin",
+		"one",
+	)
+}
+
+func TestReadHTTPAuth(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				switch request.Header.Get("Authorization") {
+				case "Bearer token":
+					_, _ = responseWriter.Write([]byte("token"))
+				case "Basic dXNlcjpwYXNz":
+					_, _ = responseWriter.Write([]byte("basic"))
+				default:
+					responseWriter.WriteHeader(http.StatusUnauthorized)
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	reader := NewReader(
+		zap.NewNop(),
+		WithReaderHTTP(
+			server.Client(),
+			httpauth.NewMultiAuthenticator(
+				httpauth.NewURLAuthenticator(),
+				httpauth.NewBearerTokenEnvAuthenticator("TOKEN", "TOKEN_HOSTS"),
+			),
+		),
+	)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	env := map[string]string{"TOKEN": "token", "TOKEN_HOSTS": "example.com, " + serverURL.Host}
+	testReadBucketFile(t, reader, env, server.URL+"/file.bin", "token")
+	// the token is only sent to the configured hosts
+	parsedRef, err := testNewRefParser(zap.NewNop()).GetParsedRef(context.Background(), server.URL+"/file.bin")
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+	_, err = reader.GetFile(
+		context.Background(),
+		app.NewContainer(map[string]string{"TOKEN": "token", "TOKEN_HOSTS": "example.com"}, nil, nil, nil),
+		fileRef,
+	)
+	require.Error(t, err)
+	// credentials in the url take precedence
+	testReadBucketFile(t, reader, env, strings.Replace(server.URL, "https://", "https://user:pass@", 1)+"/file.bin", "basic")
+}
+
+func TestReadHTTPSZipArchive(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	readBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"bar-main/proto/foo.proto": []byte("foo"),
+		},
+	)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, storagearchive.Zip(ctx, readBucket, buffer))
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL.Path != "/foo/bar/archive/refs/heads/main.zip" {
+					responseWriter.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = responseWriter.Write(buffer.Bytes())
+			},
+		),
+	)
+	defer server.Close()
+
+	refParser := testNewRefParser(zap.NewNop())
+	reader := NewReader(
+		zap.NewNop(),
+		WithReaderHTTP(server.Client(), httpauth.NewNopAuthenticator()),
+	)
+	parsedRef, err := refParser.GetParsedRef(ctx, server.URL+"/foo/bar/archive/refs/heads/main.zip#strip_components=1")
+	require.NoError(t, err)
+	archiveRef, ok := parsedRef.(ArchiveRef)
+	require.True(t, ok)
+	require.Equal(t, ArchiveTypeZip, archiveRef.ArchiveType())
+	readBucketCloser, err := reader.GetBucket(ctx, app.NewContainer(nil, nil, nil, nil), archiveRef)
+	require.NoError(t, err)
+	data, err := storage.ReadPath(ctx, readBucketCloser, "proto/foo.proto")
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(data))
+	require.NoError(t, readBucketCloser.Close())
+}
+
+func TestIsGitHubReleaseAssetURL(t *testing.T) {
+	t.Parallel()
+	for rawURL, expected := range map[string]bool{
+		"https://api.github.com/repos/owner/repo/releases/assets/1234":         true,
+		"https://api.github.com/repos/owner/repo/releases/assets/1234/":        true,
+		"https://api.github.com/repos/owner/repo/releases/1234":                false,
+		"https://github.com/repos/owner/repo/releases/assets/1234":             false,
+		"https://github.com/owner/repo/releases/download/v1.0.0/protos.tar.gz": false,
+	} {
+		parsedURL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		require.Equal(t, expected, isGitHubReleaseAssetURL(parsedURL), rawURL)
+	}
+}
+
+func TestReadCustomScheme(t *testing.T) {
+	t.Parallel()
+
+	testReadBucketFile(
+		t,
+		NewReader(
+			zap.NewNop(),
+			WithReaderFileOpener("artifact", testFileOpener{"artifact://path/to/file.bin": "one"}),
+		),
+		nil,
+		"artifact://path/to/file.bin",
+		"one",
+	)
+
+	ctx := context.Background()
+	parsedRef, err := testNewRefParser(zap.NewNop()).GetParsedRef(ctx, "other://path/to/file.bin")
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+	_, err = NewReader(
+		zap.NewNop(),
+		WithReaderFileOpener("artifact", testFileOpener{}),
+	).GetFile(ctx, app.NewContainer(nil, nil, nil, nil), fileRef)
+	require.Equal(t, newReadCustomSchemeDisabledError("other"), err)
+}
+
+func TestReadS3Disabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	parsedRef, err := testNewRefParser(zap.NewNop()).GetParsedRef(ctx, "s3://bucket/file.bin")
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+	_, err = testNewReader(zap.NewNop()).GetFile(ctx, app.NewContainer(nil, nil, nil, nil), fileRef)
+	require.Equal(t, newReadS3DisabledError(), err)
+}
+
+func TestReadOffline(t *testing.T) {
+	t.Parallel()
+
+	var requestCount int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				atomic.AddInt32(&requestCount, 1)
+				_, _ = responseWriter.Write([]byte("one"))
+			},
+		),
+	)
+	defer server.Close()
+
+	logger := zap.NewNop()
+	ctx := context.Background()
+	parsedRef, err := testNewRefParser(logger).GetParsedRef(ctx, server.URL+"/file.bin")
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+	_, err = NewReader(
+		logger,
+		WithReaderHTTP(server.Client(), httpauth.NewNopAuthenticator()),
+		WithReaderInsecureHTTP(),
+		WithReaderOffline(),
+	).GetFile(ctx, app.NewContainer(nil, nil, nil, nil), fileRef)
+	require.Equal(t, newReadOfflineError(server.URL+"/file.bin"), err)
+	require.Equal(t, int32(0), atomic.LoadInt32(&requestCount))
+}
+
+func TestGetLocalGitRootRef(t *testing.T) {
+	t.Parallel()
+
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(tempDirPath))
+	}()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDirPath, ".git"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDirPath, "a", "b"), 0755))
+
+	gitRef, err := NewGitRef(filepath.Join(tempDirPath, "a", "b", ".git"), nil, 1, false)
+	require.NoError(t, err)
+	rootGitRef, subDirPath, err := getLocalGitRootRef(gitRef)
+	require.NoError(t, err)
+	require.Equal(t, "a/b", subDirPath)
+	require.Equal(t, filepath.ToSlash(filepath.Join(tempDirPath, ".git")), rootGitRef.Path())
+	require.Equal(t, uint32(1), rootGitRef.Depth())
+
+	gitRef, err = NewGitRef(filepath.Join(tempDirPath, ".git"), nil, 1, false)
+	require.NoError(t, err)
+	rootGitRef, subDirPath, err = getLocalGitRootRef(gitRef)
+	require.NoError(t, err)
+	require.Equal(t, "", subDirPath)
+	require.Equal(t, gitRef, rootGitRef)
+}
+
+func testReadBucketFile(
+	t *testing.T,
+	reader Reader,
+	env map[string]string,
+	value string,
+	expectedData string,
+) {
+	ctx := context.Background()
+	parsedRef, err := testNewRefParser(zap.NewNop()).GetParsedRef(ctx, value)
+	require.NoError(t, err)
+	fileRef, ok := parsedRef.(FileRef)
+	require.True(t, ok)
+	readCloser, err := reader.GetFile(ctx, app.NewContainer(env, nil, nil, nil), fileRef)
+	require.NoError(t, err)
+	actualData, err := ioutil.ReadAll(readCloser)
+	require.NoError(t, err)
+	require.NoError(t, readCloser.Close())
+	require.Equal(t, expectedData, string(actualData))
+}
+
 func testRoundTripLocalFile(
 	t *testing.T,
 	filename string,
@@ -98,6 +522,19 @@ func testRoundTripLocalFile(
 
 	require.Equal(t, string(expectedData), string(actualData))
 
+	readCloser, err = reader.GetFile(ctx, container, fileRef, WithGetFileMmap())
+	require.NoError(t, err)
+	if expectedCompressionType == CompressionTypeNone && runtime.GOOS != "windows" {
+		bytesReader, ok := readCloser.(interface{ Bytes() []byte })
+		require.True(t, ok)
+		require.Equal(t, string(expectedData), string(bytesReader.Bytes()))
+	}
+	actualData, err = ioutil.ReadAll(readCloser)
+	require.NoError(t, err)
+	require.NoError(t, readCloser.Close())
+
+	require.Equal(t, string(expectedData), string(actualData))
+
 	require.NoError(t, tmpDir.Close())
 }
 
@@ -114,3 +551,14 @@ func testNewWriter(logger *zap.Logger) Writer {
 		WithWriterLocal(),
 	)
 }
+
+// testFileOpener is a FileOpener from URL to data.
+type testFileOpener map[string]string
+
+func (f testFileOpener) OpenFile(ctx context.Context, container app.EnvContainer, url string) (io.ReadCloser, error) {
+	data, ok := f[url]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(strings.NewReader(data)), nil
+}
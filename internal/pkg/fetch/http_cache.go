@@ -0,0 +1,166 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+	"go.uber.org/multierr"
+)
+
+const (
+	httpCacheDataFileSuffix     = ".data"
+	httpCacheMetadataFileSuffix = ".json"
+	httpCacheTempFilePrefix     = "tmp"
+)
+
+// httpCache caches the responses of http requests on disk, keyed by URL.
+//
+// Any username and password in the URL are removed from the key, and are
+// never written to disk.
+//
+// Cached responses are revalidated with the server on every request using
+// the ETag and Last-Modified headers of the cached response, and are served
+// from disk if the server responds with 304 Not Modified.
+type httpCache struct {
+	dirPath string
+}
+
+// httpCacheMetadata is the metadata stored alongside the cached data.
+type httpCacheMetadata struct {
+	URL          string `json:"url,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func newHTTPCache(dirPath string) *httpCache {
+	return &httpCache{
+		dirPath: dirPath,
+	}
+}
+
+// setConditionalHeaders sets the If-None-Match and If-Modified-Since headers
+// on the request if there is a cached response for the URL of the request.
+//
+// Returns false if there is no cached response.
+func (h *httpCache) setConditionalHeaders(request *http.Request) bool {
+	metadata, err := h.getMetadata(getHTTPCacheKey(request.URL))
+	if err != nil {
+		return false
+	}
+	if metadata.ETag != "" {
+		request.Header.Set("If-None-Match", metadata.ETag)
+	}
+	if metadata.LastModified != "" {
+		request.Header.Set("If-Modified-Since", metadata.LastModified)
+	}
+	return true
+}
+
+// getData returns the cached data for the URL.
+func (h *httpCache) getData(u *url.URL) (io.ReadCloser, int64, error) {
+	return h.getDataForKey(getHTTPCacheKey(u))
+}
+
+func (h *httpCache) getDataForKey(key string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(h.getFilePathPrefix(key) + httpCacheDataFileSuffix)
+	if err != nil {
+		return nil, -1, err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, -1, multierr.Append(err, file.Close())
+	}
+	return file, fileInfo.Size(), nil
+}
+
+// putResponse reads the response body into the cache and returns the cached data.
+//
+// The response body is always closed.
+func (h *httpCache) putResponse(u *url.URL, response *http.Response) (_ io.ReadCloser, _ int64, retErr error) {
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	key := getHTTPCacheKey(u)
+	filePathPrefix := h.getFilePathPrefix(key)
+	if err := ioutilextended.WriteFileAtomic(filePathPrefix+httpCacheDataFileSuffix, response.Body, httpCacheTempFilePrefix); err != nil {
+		return nil, -1, err
+	}
+	data, err := json.Marshal(
+		&httpCacheMetadata{
+			URL:          key,
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+		},
+	)
+	if err != nil {
+		return nil, -1, err
+	}
+	if err := ioutilextended.WriteFileAtomic(filePathPrefix+httpCacheMetadataFileSuffix, bytes.NewReader(data), httpCacheTempFilePrefix); err != nil {
+		return nil, -1, err
+	}
+	return h.getDataForKey(key)
+}
+
+func (h *httpCache) getMetadata(key string) (*httpCacheMetadata, error) {
+	filePathPrefix := h.getFilePathPrefix(key)
+	data, err := ioutil.ReadFile(filePathPrefix + httpCacheMetadataFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &httpCacheMetadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, err
+	}
+	// guard against hash collisions and partially-written caches
+	if metadata.URL != key {
+		return nil, os.ErrNotExist
+	}
+	if _, err := os.Stat(filePathPrefix + httpCacheDataFileSuffix); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (h *httpCache) getFilePathPrefix(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(h.dirPath, hex.EncodeToString(hash[:]))
+}
+
+// getHTTPCacheKey returns the URL without any username and password.
+func getHTTPCacheKey(u *url.URL) string {
+	keyURL := *u
+	keyURL.User = nil
+	return keyURL.String()
+}
+
+// isHTTPResponseCacheable returns true if the response can be revalidated later.
+func isHTTPResponseCacheable(response *http.Response) bool {
+	if strings.Contains(strings.ToLower(response.Header.Get("Cache-Control")), "no-store") {
+		return false
+	}
+	return response.Header.Get("ETag") != "" || response.Header.Get("Last-Modified") != ""
+}
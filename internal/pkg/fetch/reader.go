@@ -22,15 +22,20 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/git"
 	"github.com/bufbuild/buf/internal/pkg/httpauth"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
 	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+	"github.com/bufbuild/buf/internal/pkg/mmap"
+	"github.com/bufbuild/buf/internal/pkg/netlimit"
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/oci"
 	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
@@ -47,12 +52,29 @@ type reader struct {
 	localEnabled bool
 	stdioEnabled bool
 
-	httpEnabled       bool
-	httpClient        *http.Client
-	httpAuthenticator httpauth.Authenticator
+	httpEnabled         bool
+	httpClient          *http.Client
+	httpAuthenticator   httpauth.Authenticator
+	insecureHTTPEnabled bool
+	httpCacheDirName    string
 
-	gitEnabled bool
-	gitCloner  git.Cloner
+	// nil if disabled
+	s3Authenticator  httpauth.Authenticator
+	gcsAuthenticator httpauth.Authenticator
+	ociClient        oci.Client
+
+	// custom scheme to FileOpener
+	schemeToFileOpener map[string]FileOpener
+
+	gitEnabled      bool
+	gitCloner       git.Cloner
+	gitCacheDirName string
+
+	keepTemp bool
+	offline  bool
+
+	// may be nil
+	networkLimiter netlimit.Limiter
 }
 
 func newReader(
@@ -85,6 +107,7 @@ func (r *reader) GetFile(
 			container,
 			t,
 			getFileOptions.keepFileCompression,
+			getFileOptions.mmap,
 		)
 	case ArchiveRef:
 		return r.getArchiveFile(
@@ -141,7 +164,19 @@ func (r *reader) getSingle(
 	container app.EnvStdinContainer,
 	singleRef SingleRef,
 	keepFileCompression bool,
+	mmapLocal bool,
 ) (io.ReadCloser, error) {
+	if mmapLocal &&
+		r.localEnabled &&
+		singleRef.FileScheme() == FileSchemeLocal &&
+		singleRef.CompressionType() == CompressionTypeNone {
+		readCloser, err := mmap.Open(singleRef.Path())
+		if !errors.Is(err, mmap.ErrUnsupported) && !errors.Is(err, mmap.ErrNotRegularFile) {
+			return readCloser, err
+		}
+		// fall back to streaming the file as usual, such as for pipes
+		// whose size is not known until they are read
+	}
 	readCloser, _, err := r.getFileReadCloserAndSize(ctx, container, singleRef, keepFileCompression)
 	return readCloser, err
 }
@@ -169,7 +204,10 @@ func (r *reader) getArchiveBucket(
 	defer func() {
 		retErr = multierr.Append(retErr, readCloser.Close())
 	}()
-	readBucketBuilder := storagemem.NewReadBucketBuilder()
+	readBucketBuilder, err := r.newReadBucketBuilder("archive", archiveRef.Path())
+	if err != nil {
+		return nil, err
+	}
 	defer instrument.Start(r.logger, "unarchive").End()
 	switch archiveType := archiveRef.ArchiveType(); archiveType {
 	case ArchiveTypeTar:
@@ -179,6 +217,7 @@ func (r *reader) getArchiveBucket(
 			readBucketBuilder,
 			mapper,
 			archiveRef.StripComponents(),
+			storagearchive.UnarchiveWithSymlinkPolicy(archiveRef.SymlinkPolicy()),
 		); err != nil {
 			return nil, err
 		}
@@ -204,6 +243,7 @@ func (r *reader) getArchiveBucket(
 			readBucketBuilder,
 			mapper,
 			archiveRef.StripComponents(),
+			storagearchive.UnarchiveWithSymlinkPolicy(archiveRef.SymlinkPolicy()),
 		); err != nil {
 			return nil, err
 		}
@@ -226,7 +266,10 @@ func (r *reader) getDirBucket(
 	if !r.localEnabled {
 		return nil, newReadLocalDisabledError()
 	}
-	readWriteBucket, err := storageos.NewReadWriteBucket(dirRef.Path())
+	readWriteBucket, err := storageos.NewReadWriteBucket(
+		dirRef.Path(),
+		storageos.BucketWithSymlinkPolicy(dirRef.SymlinkPolicy()),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -243,11 +286,44 @@ func (r *reader) getGitBucket(
 	if !r.gitEnabled {
 		return nil, newReadGitDisabledError()
 	}
+	if gitRef.GitScheme() == GitSchemeHTTP && !r.insecureHTTPEnabled {
+		return nil, newReadInsecureHTTPDisabledError()
+	}
+	if gitRef.GitScheme() == GitSchemeLocal {
+		rootGitRef, subDirPath, err := getLocalGitRootRef(gitRef)
+		if err != nil {
+			return nil, err
+		}
+		if subDirPath != "" {
+			r.logger.Debug(
+				"found_git_root",
+				zap.String("path", rootGitRef.Path()),
+				zap.String("subdir", subDirPath),
+			)
+			gitRef = rootGitRef
+			if mapper != nil {
+				mapper = storage.MapChain(storage.MapOnPrefix(subDirPath), mapper)
+			} else {
+				mapper = storage.MapOnPrefix(subDirPath)
+			}
+		}
+	}
 	gitURL, err := getGitURL(gitRef)
 	if err != nil {
 		return nil, err
 	}
-	readBucketBuilder := storagemem.NewReadBucketBuilder()
+	readBucketBuilder, err := r.newReadBucketBuilder("git", gitURL)
+	if err != nil {
+		return nil, err
+	}
+	if gitRef.GitScheme() != GitSchemeLocal {
+		release, err := r.acquireNetwork(ctx, gitURL)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	instrument.Progress(r.logger, "cloning", zap.String("url", getRedactedURL(gitURL)))
 	if err := r.gitCloner.CloneToBucket(
 		ctx,
 		container,
@@ -258,6 +334,7 @@ func (r *reader) getGitBucket(
 			Name:              gitRef.GitName(),
 			RecurseSubmodules: gitRef.RecurseSubmodules(),
 			Mapper:            mapper,
+			CacheDirPath:      r.getGitCacheDirPath(container),
 		},
 	); err != nil {
 		return nil, fmt.Errorf("could not clone %s: %v", gitURL, err)
@@ -269,6 +346,31 @@ func (r *reader) getGitBucket(
 	return storage.NopReadBucketCloser(readBucket), nil
 }
 
+// newReadBucketBuilder returns a new ReadBucketBuilder to extract or clone to.
+//
+// If keepTemp is set, this is backed by a new temporary directory that is not
+// deleted, otherwise this is in-memory. The name and source are only used to
+// log the path of the temporary directory.
+func (r *reader) newReadBucketBuilder(name string, source string) (storagemem.ReadBucketBuilder, error) {
+	if !r.keepTemp {
+		return storagemem.NewReadBucketBuilder(), nil
+	}
+	dirPath, err := ioutil.TempDir("", "buf-"+name+"-")
+	if err != nil {
+		return nil, err
+	}
+	readWriteBucket, err := storageos.NewReadWriteBucket(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Info(
+		"keeping temporary directory",
+		zap.String("source", source),
+		zap.String("path", dirPath),
+	)
+	return newReadWriteBucketReadBucketBuilder(readWriteBucket), nil
+}
+
 func (r *reader) getFileReadCloserAndSize(
 	ctx context.Context,
 	container app.EnvStdinContainer,
@@ -331,12 +433,51 @@ func (r *reader) getFileReadCloserAndSizePotentiallyCompressed(
 		if !r.httpEnabled {
 			return nil, -1, newReadHTTPDisabledError()
 		}
-		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, "http://"+fileRef.Path())
+		if !r.insecureHTTPEnabled {
+			return nil, -1, newReadInsecureHTTPDisabledError()
+		}
+		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, "http://"+fileRef.Path(), r.httpAuthenticator)
 	case FileSchemeHTTPS:
 		if !r.httpEnabled {
 			return nil, -1, newReadHTTPDisabledError()
 		}
-		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, "https://"+fileRef.Path())
+		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, "https://"+fileRef.Path(), r.httpAuthenticator)
+	case FileSchemeS3:
+		if !r.httpEnabled || r.s3Authenticator == nil {
+			return nil, -1, newReadS3DisabledError()
+		}
+		s3URL, err := getS3URL(container, fileRef.Path())
+		if err != nil {
+			return nil, -1, err
+		}
+		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, s3URL, r.s3Authenticator)
+	case FileSchemeGCS:
+		if !r.httpEnabled || r.gcsAuthenticator == nil {
+			return nil, -1, newReadGCSDisabledError()
+		}
+		gcsURL, err := getGCSURL(container, fileRef.Path())
+		if err != nil {
+			return nil, -1, err
+		}
+		return r.getFileReadCloserAndSizePotentiallyCompressedHTTP(ctx, container, gcsURL, r.gcsAuthenticator)
+	case FileSchemeOCI:
+		if r.ociClient == nil {
+			return nil, -1, newReadOCIDisabledError()
+		}
+		return r.getFileReadCloserAndSizePotentiallyCompressedOCI(ctx, container, fileRef.Path())
+	case FileSchemeGRPC:
+		return nil, -1, newReadGRPCDisabledError()
+	case FileSchemeCustom:
+		scheme := getCustomScheme(fileRef.Path())
+		fileOpener, ok := r.schemeToFileOpener[scheme]
+		if !ok {
+			return nil, -1, newReadCustomSchemeDisabledError(scheme)
+		}
+		readCloser, err := fileOpener.OpenFile(ctx, container, fileRef.Path())
+		if err != nil {
+			return nil, -1, err
+		}
+		return readCloser, -1, nil
 	case FileSchemeLocal:
 		if !r.localEnabled {
 			return nil, -1, newReadLocalDisabledError()
@@ -354,6 +495,11 @@ func (r *reader) getFileReadCloserAndSizePotentiallyCompressed(
 		if !r.stdioEnabled {
 			return nil, -1, newReadStdioDisabledError()
 		}
+		// reading from an interactive terminal would block until the user
+		// enters EOF, which is never what the user intended
+		if app.IsTerminal(container.Stdin()) {
+			return nil, -1, newReadStdinTerminalError(fileRef)
+		}
 		return ioutil.NopCloser(container.Stdin()), -1, nil
 	case FileSchemeStdout:
 		return nil, -1, errors.New("cannot read from stdout")
@@ -369,18 +515,63 @@ func (r *reader) getFileReadCloserAndSizePotentiallyCompressedHTTP(
 	ctx context.Context,
 	container app.EnvStdinContainer,
 	httpPath string,
+	authenticator httpauth.Authenticator,
+) (io.ReadCloser, int64, error) {
+	release, err := r.acquireNetwork(ctx, httpPath)
+	if err != nil {
+		return nil, -1, err
+	}
+	readCloser, size, err := r.getFileReadCloserAndSizePotentiallyCompressedHTTPUnlimited(ctx, container, httpPath, authenticator)
+	if err != nil {
+		release()
+		return nil, -1, err
+	}
+	return ioutilextended.CompositeReadCloser(
+		readCloser,
+		ioutilextended.ChainCloser(
+			readCloser,
+			closerFunc(release),
+		),
+	), size, nil
+}
+
+func (r *reader) getFileReadCloserAndSizePotentiallyCompressedHTTPUnlimited(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	httpPath string,
+	authenticator httpauth.Authenticator,
 ) (io.ReadCloser, int64, error) {
 	request, err := http.NewRequestWithContext(ctx, "GET", httpPath, nil)
 	if err != nil {
 		return nil, -1, err
 	}
-	if _, err := r.httpAuthenticator.SetAuth(container, request); err != nil {
+	if _, err := authenticator.SetAuth(container, request); err != nil {
 		return nil, -1, err
 	}
+	if isGitHubReleaseAssetURL(request.URL) {
+		// the API returns the asset metadata as JSON unless the asset
+		// itself is requested, in which case it redirects to the download
+		request.Header.Set("Accept", "application/octet-stream")
+	}
+	httpCache := r.getHTTPCache(container)
+	cached := false
+	if httpCache != nil {
+		cached = httpCache.setConditionalHeaders(request)
+	}
+	instrument.Progress(r.logger, "fetching", zap.String("url", getRedactedURL(request.URL.String())))
 	response, err := r.httpClient.Do(request)
 	if err != nil {
 		return nil, -1, err
 	}
+	if response.StatusCode == http.StatusNotModified && cached {
+		r.logger.Debug("http_cache_hit", zap.String("url", request.URL.String()))
+		if response.Body != nil {
+			if err := response.Body.Close(); err != nil {
+				return nil, -1, err
+			}
+		}
+		return httpCache.getData(request.URL)
+	}
 	if response.StatusCode != http.StatusOK {
 		err := fmt.Errorf("got HTTP status code %d", response.StatusCode)
 		if response.Body != nil {
@@ -388,10 +579,134 @@ func (r *reader) getFileReadCloserAndSizePotentiallyCompressedHTTP(
 		}
 		return nil, -1, err
 	}
+	if httpCache != nil && isHTTPResponseCacheable(response) {
+		r.logger.Debug("http_cache_put", zap.String("url", request.URL.String()))
+		return httpCache.putResponse(request.URL, response)
+	}
 	// ContentLength is -1 if unknown, which is what we want
 	return response.Body, response.ContentLength, nil
 }
 
+func (r *reader) getFileReadCloserAndSizePotentiallyCompressedOCI(
+	ctx context.Context,
+	container app.EnvStdinContainer,
+	ociPath string,
+) (io.ReadCloser, int64, error) {
+	reference, err := oci.ParseReference(ociPath)
+	if err != nil {
+		return nil, -1, err
+	}
+	release, err := r.acquireNetwork(ctx, "oci://"+ociPath)
+	if err != nil {
+		return nil, -1, err
+	}
+	readCloser, size, err := r.ociClient.Pull(ctx, container, reference)
+	if err != nil {
+		release()
+		return nil, -1, err
+	}
+	return ioutilextended.CompositeReadCloser(
+		readCloser,
+		ioutilextended.ChainCloser(
+			readCloser,
+			closerFunc(release),
+		),
+	), size, nil
+}
+
+// acquireNetwork acquires the network limiter for the host of the URL.
+//
+// Returns an error if the reader is offline.
+// The returned function must be called when the network operation completes.
+func (r *reader) acquireNetwork(ctx context.Context, rawURL string) (func(), error) {
+	if r.offline {
+		return nil, newReadOfflineError(getRedactedURL(rawURL))
+	}
+	if r.networkLimiter == nil {
+		return func() {}, nil
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return r.networkLimiter.Acquire(ctx, parsedURL.Host)
+}
+
+// getHTTPCache returns nil if the http cache is disabled or the cache
+// directory is not available, in which case requests are not cached.
+func (r *reader) getHTTPCache(container app.EnvContainer) *httpCache {
+	if r.httpCacheDirName == "" {
+		return nil
+	}
+	cacheDirPath, err := app.CacheDirPath(container)
+	if err != nil {
+		r.logger.Debug("http_cache_disabled", zap.Error(err))
+		return nil
+	}
+	httpCacheDirPath := filepath.Join(cacheDirPath, r.httpCacheDirName)
+	if err := os.MkdirAll(httpCacheDirPath, 0755); err != nil {
+		r.logger.Debug("http_cache_disabled", zap.Error(err))
+		return nil
+	}
+	return newHTTPCache(httpCacheDirPath)
+}
+
+// getGitCacheDirPath returns empty if the git cache is disabled or the cache
+// directory is not available, in which case clones are not cached.
+func (r *reader) getGitCacheDirPath(container app.EnvContainer) string {
+	if r.gitCacheDirName == "" {
+		return ""
+	}
+	cacheDirPath, err := app.CacheDirPath(container)
+	if err != nil {
+		r.logger.Debug("git_cache_disabled", zap.Error(err))
+		return ""
+	}
+	return filepath.Join(cacheDirPath, r.gitCacheDirName)
+}
+
+// getLocalGitRootRef finds the repository that contains the directory of a
+// local .git path that does not exist, so that .git can be used from any
+// directory within a repository.
+//
+// Returns a GitRef for the .git directory of the repository and the path of
+// the directory relative to the root of the repository. The returned path is
+// empty if the .git path exists, does not end in .git, or no repository was
+// found, in which case the GitRef is used as-is.
+func getLocalGitRootRef(gitRef GitRef) (GitRef, string, error) {
+	gitDirPath := normalpath.Unnormalize(gitRef.Path())
+	if filepath.Base(gitDirPath) != ".git" {
+		return gitRef, "", nil
+	}
+	if _, err := os.Stat(gitDirPath); !os.IsNotExist(err) {
+		return gitRef, "", nil
+	}
+	dirPath, err := filepath.Abs(filepath.Dir(gitDirPath))
+	if err != nil {
+		return nil, "", err
+	}
+	for rootDirPath := filepath.Dir(dirPath); ; rootDirPath = filepath.Dir(rootDirPath) {
+		rootGitDirPath := filepath.Join(rootDirPath, ".git")
+		if _, err := os.Stat(rootGitDirPath); err == nil {
+			subDirPath, err := filepath.Rel(rootDirPath, dirPath)
+			if err != nil {
+				return nil, "", err
+			}
+			return buildGitRef(
+				"",
+				normalpath.Normalize(rootGitDirPath),
+				GitSchemeLocal,
+				gitRef.GitName(),
+				gitRef.RecurseSubmodules(),
+				gitRef.Depth(),
+			), normalpath.Normalize(subDirPath), nil
+		}
+		if rootDirPath == filepath.Dir(rootDirPath) {
+			return gitRef, "", nil
+		}
+	}
+}
+
 func getGitURL(gitRef GitRef) (string, error) {
 	switch gitScheme := gitRef.GitScheme(); gitScheme {
 	case GitSchemeHTTP:
@@ -411,8 +726,74 @@ func getGitURL(gitRef GitRef) (string, error) {
 	}
 }
 
+// getS3URL returns the URL of the S3 REST API to get the object at the path,
+// which is the bucket followed by the key.
+func getS3URL(envContainer app.EnvContainer, path string) (string, error) {
+	bucket, key, err := splitBucketPath("s3", path)
+	if err != nil {
+		return "", err
+	}
+	if endpoint := getS3Endpoint(envContainer); endpoint != "" {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil {
+			return "", fmt.Errorf("invalid S3 endpoint %q: %v", endpoint, err)
+		}
+		endpointURL.Path = strings.TrimSuffix(endpointURL.Path, "/") + "/" + bucket + "/" + key
+		return endpointURL.String(), nil
+	}
+	region := httpauth.AWSRegion(envContainer)
+	s3URL := &url.URL{
+		Scheme: "https",
+		Host:   bucket + ".s3." + region + ".amazonaws.com",
+		Path:   "/" + key,
+	}
+	// virtual-hosted-style URLs do not work with TLS for bucket names with
+	// dots, as the wildcard certificate only matches one label
+	if strings.Contains(bucket, ".") {
+		s3URL.Host = "s3." + region + ".amazonaws.com"
+		s3URL.Path = "/" + bucket + "/" + key
+	}
+	return s3URL.String(), nil
+}
+
+func getS3Endpoint(envContainer app.EnvContainer) string {
+	if endpoint := envContainer.Env("AWS_ENDPOINT_URL_S3"); endpoint != "" {
+		return endpoint
+	}
+	return envContainer.Env("AWS_ENDPOINT_URL")
+}
+
+// getGCSURL returns the URL of the Cloud Storage JSON API to get the object
+// at the path, which is the bucket followed by the object name.
+func getGCSURL(envContainer app.EnvContainer, path string) (string, error) {
+	bucket, object, err := splitBucketPath("gs", path)
+	if err != nil {
+		return "", err
+	}
+	baseURL := "https://storage.googleapis.com"
+	if emulatorHost := envContainer.Env("STORAGE_EMULATOR_HOST"); emulatorHost != "" {
+		baseURL = emulatorHost
+		if !strings.Contains(baseURL, "://") {
+			baseURL = "http://" + baseURL
+		}
+	}
+	return strings.TrimSuffix(baseURL, "/") +
+		"/storage/v1/b/" + url.PathEscape(bucket) +
+		"/o/" + url.PathEscape(object) +
+		"?alt=media", nil
+}
+
+func splitBucketPath(scheme string, path string) (string, string, error) {
+	split := strings.SplitN(path, "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", "", newInvalidBucketPathError(scheme, path)
+	}
+	return split[0], split[1], nil
+}
+
 type getFileOptions struct {
 	keepFileCompression bool
+	mmap                bool
 }
 
 func newGetFileOptions() *getFileOptions {
@@ -426,3 +807,38 @@ type getBucketOptions struct {
 func newGetBucketOptions() *getBucketOptions {
 	return &getBucketOptions{}
 }
+
+// readWriteBucketReadBucketBuilder is a storagemem.ReadBucketBuilder backed by
+// a ReadWriteBucket, used when keeping temporary directories.
+type readWriteBucketReadBucketBuilder struct {
+	storage.ReadWriteBucket
+}
+
+func newReadWriteBucketReadBucketBuilder(readWriteBucket storage.ReadWriteBucket) *readWriteBucketReadBucketBuilder {
+	return &readWriteBucketReadBucketBuilder{
+		ReadWriteBucket: readWriteBucket,
+	}
+}
+
+func (r *readWriteBucketReadBucketBuilder) ToReadBucket(...storagemem.ReadBucketOption) (storage.ReadBucket, error) {
+	return r.ReadWriteBucket, nil
+}
+
+type closerFunc func()
+
+func (c closerFunc) Close() error {
+	c()
+	return nil
+}
+
+// isGitHubReleaseAssetURL returns true if the URL is for a release asset in
+// the GitHub API, that is https://api.github.com/repos/owner/repo/releases/assets/id.
+//
+// This is the only URL that private release assets can be downloaded from with a token.
+func isGitHubReleaseAssetURL(u *url.URL) bool {
+	if u.Host != "api.github.com" {
+		return false
+	}
+	split := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return len(split) == 6 && split[0] == "repos" && split[3] == "releases" && split[4] == "assets"
+}
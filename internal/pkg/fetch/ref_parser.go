@@ -15,21 +15,33 @@
 package fetch
 
 import (
+	"compress/gzip"
 	"context"
 	"strconv"
 	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 	"go.uber.org/zap"
 )
 
+// zstdMaxCompressionLevel is the maximum zstd compression level.
+//
+// Levels are mapped to the closest matching level of the encoder.
+const zstdMaxCompressionLevel = 22
+
 var (
 	knownCompressionTypeStrings = []string{
 		"none",
 		"gzip",
 		"zstd",
 	}
+	knownSymlinkPolicyStrings = []string{
+		"skip",
+		"follow",
+		"error",
+	}
 )
 
 type refParser struct {
@@ -119,6 +131,11 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 			return nil, err
 		}
 	}
+	_, hasRecurseSubmodules := options["recurse_submodules"]
+	_, hasSubmodules := options["submodules"]
+	if hasRecurseSubmodules && hasSubmodules {
+		return nil, newCannotSpecifyRecurseSubmodulesAndSubmodulesError()
+	}
 	for key, value := range options {
 		switch key {
 		case "format":
@@ -137,6 +154,12 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 			default:
 				return nil, newCompressionUnknownError(value, knownCompressionTypeStrings...)
 			}
+		case "level":
+			level, err := strconv.Atoi(value)
+			if err != nil || level < 1 {
+				return nil, newOptionsCouldNotParseCompressionLevelError(value)
+			}
+			rawRef.CompressionLevel = level
 		case "branch":
 			if rawRef.GitBranch != "" || rawRef.GitTag != "" {
 				return nil, newCannotSpecifyGitBranchAndTagError()
@@ -158,7 +181,7 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 				return nil, newDepthZeroError()
 			}
 			rawRef.GitDepth = uint32(depth)
-		case "recurse_submodules":
+		case "recurse_submodules", "submodules":
 			// TODO: need to refactor to make sure this is not set for any non-git input
 			// ie right now recurse_submodules=false will not error
 			switch value {
@@ -166,7 +189,7 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 				rawRef.GitRecurseSubmodules = true
 			case "false":
 			default:
-				return nil, newOptionsCouldNotParseRecurseSubmodulesError(value)
+				return nil, newOptionsCouldNotParseRecurseSubmodulesError(key, value)
 			}
 		case "strip_components":
 			// TODO: need to refactor to make sure this is not set for any non-tarball
@@ -176,6 +199,17 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 				return nil, newOptionsCouldNotParseStripComponentsError(value)
 			}
 			rawRef.ArchiveStripComponents = uint32(stripComponents)
+		case "symlinks":
+			switch value {
+			case "skip":
+				rawRef.SymlinkPolicy = storage.SymlinkPolicySkip
+			case "follow":
+				rawRef.SymlinkPolicy = storage.SymlinkPolicyFollow
+			case "error":
+				rawRef.SymlinkPolicy = storage.SymlinkPolicyError
+			default:
+				return nil, newSymlinksUnknownError(value, knownSymlinkPolicyStrings...)
+			}
 		default:
 			return nil, newOptionsInvalidKeyError(key)
 		}
@@ -188,6 +222,12 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 	_, gitOK := a.gitFormatToInfo[rawRef.Format]
 	archiveFormatInfo, archiveOK := a.archiveFormatToInfo[rawRef.Format]
 	_, singleOK := a.singleFormatToInfo[rawRef.Format]
+	_, dirOK := a.dirFormatToInfo[rawRef.Format]
+	if !dirOK && !archiveOK {
+		if rawRef.SymlinkPolicy != 0 {
+			return nil, newOptionsInvalidForFormatError(rawRef.Format, value)
+		}
+	}
 	if gitOK {
 		if rawRef.GitRef != "" && rawRef.GitTag != "" {
 			return nil, newCannotSpecifyTagWithRefError()
@@ -216,7 +256,7 @@ func (a *refParser) getRawRef(value string) (*RawRef, error) {
 		}
 	}
 	if !singleOK && !archiveOK {
-		if rawRef.CompressionType != 0 {
+		if rawRef.CompressionType != 0 || rawRef.CompressionLevel != 0 {
 			return nil, newOptionsInvalidForFormatError(rawRef.Format, value)
 		}
 	}
@@ -272,10 +312,14 @@ func getSingleRef(
 	if compressionType == 0 {
 		compressionType = defaultCompressionType
 	}
+	if err := validateCompressionLevel(compressionType, rawRef.CompressionLevel); err != nil {
+		return nil, err
+	}
 	return newSingleRef(
 		rawRef.Format,
 		rawRef.Path,
 		compressionType,
+		rawRef.CompressionLevel,
 	)
 }
 
@@ -288,22 +332,55 @@ func getArchiveRef(
 	if compressionType == 0 {
 		compressionType = defaultCompressionType
 	}
-	return newArchiveRef(
+	if err := validateCompressionLevel(compressionType, rawRef.CompressionLevel); err != nil {
+		return nil, err
+	}
+	archiveRef, err := newArchiveRef(
 		rawRef.Format,
 		rawRef.Path,
 		archiveType,
 		compressionType,
+		rawRef.CompressionLevel,
 		rawRef.ArchiveStripComponents,
 	)
+	if err != nil {
+		return nil, err
+	}
+	archiveRef.symlinkPolicy = rawRef.SymlinkPolicy
+	return archiveRef, nil
+}
+
+func validateCompressionLevel(compressionType CompressionType, compressionLevel int) error {
+	if compressionLevel == 0 {
+		return nil
+	}
+	switch compressionType {
+	case CompressionTypeGzip:
+		if compressionLevel > gzip.BestCompression {
+			return newCompressionLevelOutOfRangeError("gzip", compressionLevel, gzip.BestSpeed, gzip.BestCompression)
+		}
+	case CompressionTypeZstd:
+		if compressionLevel > zstdMaxCompressionLevel {
+			return newCompressionLevelOutOfRangeError("zstd", compressionLevel, 1, zstdMaxCompressionLevel)
+		}
+	default:
+		return newCompressionLevelWithoutCompressionError()
+	}
+	return nil
 }
 
 func getDirRef(
 	rawRef *RawRef,
 ) (ParsedDirRef, error) {
-	return newDirRef(
+	dirRef, err := newDirRef(
 		rawRef.Format,
 		rawRef.Path,
 	)
+	if err != nil {
+		return nil, err
+	}
+	dirRef.symlinkPolicy = rawRef.SymlinkPolicy
+	return dirRef, nil
 }
 
 func getGitRef(
@@ -23,6 +23,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/storage"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -113,6 +114,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"path/to/file.tar",
 	)
@@ -125,6 +127,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"file:///path/to/file.tar",
 	)
@@ -136,6 +139,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeNone,
+			0,
 			1,
 		),
 		"path/to/file.tar#strip_components=1",
@@ -149,6 +153,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeGzip,
 			0,
+			0,
 		),
 		"path/to/file.tar.gz",
 	)
@@ -160,6 +165,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeGzip,
+			0,
 			1,
 		),
 		"path/to/file.tar.gz#strip_components=1",
@@ -173,6 +179,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeGzip,
 			0,
+			0,
 		),
 		"path/to/file.tgz",
 	)
@@ -184,6 +191,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeGzip,
+			0,
 			1,
 		),
 		"path/to/file.tgz#strip_components=1",
@@ -197,6 +205,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"http://path/to/file.tar",
 	)
@@ -209,9 +218,49 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"https://path/to/file.tar",
 	)
+	testGetParsedRefSuccess(
+		t,
+		buildArchiveRef(
+			testFormatZip,
+			"github.com/foo/bar/archive/refs/heads/main.zip",
+			FileSchemeHTTPS,
+			ArchiveTypeZip,
+			CompressionTypeNone,
+			0,
+			1,
+		),
+		"https://github.com/foo/bar/archive/refs/heads/main.zip#strip_components=1",
+	)
+	testGetParsedRefSuccess(
+		t,
+		buildArchiveRef(
+			testFormatTar,
+			"bucket/path/to/file.tar",
+			FileSchemeS3,
+			ArchiveTypeTar,
+			CompressionTypeNone,
+			0,
+			0,
+		),
+		"s3://bucket/path/to/file.tar",
+	)
+	testGetParsedRefSuccess(
+		t,
+		buildArchiveRef(
+			testFormatTar,
+			"artifact://path/to/file.tar",
+			FileSchemeCustom,
+			ArchiveTypeTar,
+			CompressionTypeNone,
+			0,
+			0,
+		),
+		"artifact://path/to/file.tar",
+	)
 	testGetParsedRefSuccess(
 		t,
 		buildArchiveRef(
@@ -221,6 +270,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeZip,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"path/to/file.zip",
 	)
@@ -233,6 +283,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeZip,
 			CompressionTypeNone,
 			0,
+			0,
 		),
 		"file:///path/to/file.zip",
 	)
@@ -244,10 +295,45 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeZip,
 			CompressionTypeNone,
+			0,
 			1,
 		),
 		"path/to/file.zip#strip_components=1",
 	)
+	testGetParsedRefSuccess(
+		t,
+		&dirRef{
+			format:        testFormatDir,
+			path:          "path/to/dir",
+			symlinkPolicy: storage.SymlinkPolicyFollow,
+		},
+		"path/to/dir#symlinks=follow",
+	)
+	testGetParsedRefSuccess(
+		t,
+		&archiveRef{
+			format:          testFormatTar,
+			path:            "path/to/file.tar",
+			fileScheme:      FileSchemeLocal,
+			archiveType:     ArchiveTypeTar,
+			compressionType: CompressionTypeNone,
+			symlinkPolicy:   storage.SymlinkPolicyError,
+		},
+		"path/to/file.tar#symlinks=error",
+	)
+	testGetParsedRefSuccess(
+		t,
+		&archiveRef{
+			format:          testFormatZip,
+			path:            "path/to/file.zip",
+			fileScheme:      FileSchemeLocal,
+			archiveType:     ArchiveTypeZip,
+			compressionType: CompressionTypeNone,
+			stripComponents: 1,
+			symlinkPolicy:   storage.SymlinkPolicySkip,
+		},
+		"path/to/file.zip#strip_components=1,symlinks=skip",
+	)
 	testGetParsedRefSuccess(
 		t,
 		buildGitRef(
@@ -332,6 +418,18 @@ func TestGetParsedRefSuccess(t *testing.T) {
 		),
 		"https://hello.com/path/to/dir.git#branch=master",
 	)
+	testGetParsedRefSuccess(
+		t,
+		buildGitRef(
+			testFormatGit,
+			"hello.com/path/to/dir.git",
+			GitSchemeHTTPS,
+			git.NewBranchName("main"),
+			true,
+			1,
+		),
+		"https://hello.com/path/to/dir.git#branch=main,submodules=true",
+	)
 	testGetParsedRefSuccess(
 		t,
 		buildGitRef(
@@ -399,6 +497,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.bin",
 			FileSchemeLocal,
 			CompressionTypeNone,
+			0,
 		),
 		"path/to/file.bin",
 	)
@@ -409,9 +508,32 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.bin.gz",
 			FileSchemeLocal,
 			CompressionTypeGzip,
+			0,
 		),
 		"path/to/file.bin.gz",
 	)
+	testGetParsedRefSuccess(
+		t,
+		buildSingleRef(
+			testFormatBin,
+			"bucket/path/to/file.bin",
+			FileSchemeGCS,
+			CompressionTypeNone,
+			0,
+		),
+		"gs://bucket/path/to/file.bin",
+	)
+	testGetParsedRefSuccess(
+		t,
+		buildSingleRef(
+			testFormatBin,
+			"localhost:8080",
+			FileSchemeGRPC,
+			CompressionTypeNone,
+			0,
+		),
+		"grpc://localhost:8080#format=bin",
+	)
 	testGetParsedRefSuccess(
 		t,
 		buildSingleRef(
@@ -419,6 +541,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.json",
 			FileSchemeLocal,
 			CompressionTypeNone,
+			0,
 		),
 		"path/to/file.json",
 	)
@@ -429,6 +552,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.json.gz",
 			FileSchemeLocal,
 			CompressionTypeGzip,
+			0,
 		),
 		"path/to/file.json.gz",
 	)
@@ -439,6 +563,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.json.gz",
 			FileSchemeLocal,
 			CompressionTypeNone,
+			0,
 		),
 		"path/to/file.json.gz#compression=none",
 	)
@@ -449,6 +574,29 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.json.gz",
 			FileSchemeLocal,
 			CompressionTypeGzip,
+			9,
+		),
+		"path/to/file.json.gz#level=9",
+	)
+	testGetParsedRefSuccess(
+		t,
+		buildSingleRef(
+			testFormatBin,
+			"path/to/file.bin.zst",
+			FileSchemeLocal,
+			CompressionTypeZstd,
+			19,
+		),
+		"path/to/file.bin.zst#level=19",
+	)
+	testGetParsedRefSuccess(
+		t,
+		buildSingleRef(
+			testFormatJSON,
+			"path/to/file.json.gz",
+			FileSchemeLocal,
+			CompressionTypeGzip,
+			0,
 		),
 		"path/to/file.json.gz#compression=gzip",
 	)
@@ -459,6 +607,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"",
 			FileSchemeStdio,
 			CompressionTypeNone,
+			0,
 		),
 		"-",
 	)
@@ -469,6 +618,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"",
 			FileSchemeStdio,
 			CompressionTypeNone,
+			0,
 		),
 		"-#format=json",
 	)
@@ -479,6 +629,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"",
 			FileSchemeNull,
 			CompressionTypeNone,
+			0,
 		),
 		app.DevNullFilePath,
 	)
@@ -490,6 +641,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"",
 			FileSchemeStdin,
 			CompressionTypeNone,
+			0,
 		),
 		app.DevStdinFilePath,
 	)
@@ -501,6 +653,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"",
 			FileSchemeStdout,
 			CompressionTypeNone,
+			0,
 		),
 		app.DevStdoutFilePath,
 	)
@@ -511,6 +664,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/dir",
 			FileSchemeLocal,
 			CompressionTypeNone,
+			0,
 		),
 		"path/to/dir#format=bin",
 	)
@@ -521,6 +675,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/dir",
 			FileSchemeLocal,
 			CompressionTypeNone,
+			0,
 		),
 		"path/to/dir#format=bin,compression=none",
 	)
@@ -531,6 +686,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/dir",
 			FileSchemeLocal,
 			CompressionTypeGzip,
+			0,
 		),
 		"path/to/dir#format=bin,compression=gzip",
 	)
@@ -638,6 +794,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeGzip,
+			0,
 			1,
 		),
 		"path/to/file#format=targz,strip_components=1",
@@ -650,6 +807,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeNone,
+			0,
 			1,
 		),
 		"path/to/file#format=tar,strip_components=1",
@@ -662,6 +820,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeNone,
+			0,
 			1,
 		),
 		"path/to/file#format=tar,strip_components=1,compression=none",
@@ -674,6 +833,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeGzip,
+			0,
 			1,
 		),
 		"path/to/file#format=tar,strip_components=1,compression=gzip",
@@ -686,6 +846,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeZip,
 			CompressionTypeNone,
+			0,
 			1,
 		),
 		"path/to/file#format=zip,strip_components=1",
@@ -699,6 +860,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			ArchiveTypeTar,
 			CompressionTypeZstd,
 			0,
+			0,
 		),
 		"path/to/file.tar.zst",
 	)
@@ -710,6 +872,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeZstd,
+			0,
 			1,
 		),
 		"path/to/file.tar.zst#strip_components=1",
@@ -722,6 +885,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			FileSchemeLocal,
 			ArchiveTypeTar,
 			CompressionTypeZstd,
+			0,
 			1,
 		),
 		"path/to/file#format=tar,strip_components=1,compression=zstd",
@@ -733,6 +897,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file",
 			FileSchemeLocal,
 			CompressionTypeZstd,
+			0,
 		),
 		"path/to/file#format=bin,compression=zstd",
 	)
@@ -743,6 +908,7 @@ func TestGetParsedRefSuccess(t *testing.T) {
 			"path/to/file.bin.zst",
 			FileSchemeLocal,
 			CompressionTypeZstd,
+			0,
 		),
 		"path/to/file.bin.zst",
 	)
@@ -849,6 +1015,16 @@ func TestGetParsedRefError(t *testing.T) {
 		newFormatUnknownError("bar"),
 		"path/to/foo#format=bar",
 	)
+	testGetParsedRefError(
+		t,
+		newOptionsCouldNotParseRecurseSubmodulesError("submodules", "foo"),
+		"path/to/foo.git#submodules=foo",
+	)
+	testGetParsedRefError(
+		t,
+		newCannotSpecifyRecurseSubmodulesAndSubmodulesError(),
+		"path/to/foo.git#recurse_submodules=true,submodules=true",
+	)
 	testGetParsedRefError(
 		t,
 		newOptionsCouldNotParseStripComponentsError("foo"),
@@ -889,6 +1065,21 @@ func TestGetParsedRefError(t *testing.T) {
 		newCannotSpecifyCompressionForZipError(),
 		"path/to/foo.zip#compression=none",
 	)
+	testGetParsedRefError(
+		t,
+		newSymlinksUnknownError("foo", knownSymlinkPolicyStrings...),
+		"path/to/foo#symlinks=foo",
+	)
+	testGetParsedRefError(
+		t,
+		newOptionsInvalidForFormatError(testFormatBin, "path/to/foo.bin#symlinks=follow"),
+		"path/to/foo.bin#symlinks=follow",
+	)
+	testGetParsedRefError(
+		t,
+		newOptionsInvalidForFormatError(testFormatGit, "path/to/foo.git#symlinks=follow"),
+		"path/to/foo.git#symlinks=follow",
+	)
 	testGetParsedRefError(
 		t,
 		newCannotSpecifyCompressionForZipError(),
@@ -904,6 +1095,41 @@ func TestGetParsedRefError(t *testing.T) {
 		newCannotSpecifyCompressionForZipError(),
 		"path/to/foo#format=zip,compression=gzip",
 	)
+	testGetParsedRefError(
+		t,
+		newOptionsCouldNotParseCompressionLevelError("foo"),
+		"path/to/foo.bin.gz#level=foo",
+	)
+	testGetParsedRefError(
+		t,
+		newOptionsCouldNotParseCompressionLevelError("0"),
+		"path/to/foo.bin.gz#level=0",
+	)
+	testGetParsedRefError(
+		t,
+		newCompressionLevelOutOfRangeError("gzip", 10, 1, 9),
+		"path/to/foo.bin.gz#level=10",
+	)
+	testGetParsedRefError(
+		t,
+		newCompressionLevelWithoutCompressionError(),
+		"path/to/foo.bin#level=9",
+	)
+	testGetParsedRefError(
+		t,
+		newCompressionLevelWithoutCompressionError(),
+		"path/to/foo.zip#level=9",
+	)
+	testGetParsedRefError(
+		t,
+		newOptionsInvalidForFormatError(testFormatDir, "path/to/foo#format=dir,level=9"),
+		"path/to/foo#format=dir,level=9",
+	)
+	testGetParsedRefError(
+		t,
+		newInvalidFilePathError("1artifact://path/to/file.tar"),
+		"1artifact://path/to/file.tar",
+	)
 }
 
 func testGetParsedRefSuccess(
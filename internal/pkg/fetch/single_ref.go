@@ -28,20 +28,26 @@ var (
 		"http://":  FileSchemeHTTP,
 		"https://": FileSchemeHTTPS,
 		"file://":  FileSchemeLocal,
+		"s3://":    FileSchemeS3,
+		"gs://":    FileSchemeGCS,
+		"oci://":   FileSchemeOCI,
+		"grpc://":  FileSchemeGRPC,
 	}
 )
 
 type singleRef struct {
-	format          string
-	path            string
-	fileScheme      FileScheme
-	compressionType CompressionType
+	format           string
+	path             string
+	fileScheme       FileScheme
+	compressionType  CompressionType
+	compressionLevel int
 }
 
 func newSingleRef(
 	format string,
 	path string,
 	compressionType CompressionType,
+	compressionLevel int,
 ) (*singleRef, error) {
 	if path == "" {
 		return nil, newNoPathError()
@@ -55,6 +61,7 @@ func newSingleRef(
 			"",
 			FileSchemeStdio,
 			compressionType,
+			compressionLevel,
 		), nil
 	}
 	if app.IsDevStdin(path) {
@@ -63,6 +70,7 @@ func newSingleRef(
 			"",
 			FileSchemeStdin,
 			compressionType,
+			compressionLevel,
 		), nil
 	}
 	if app.IsDevStdout(path) {
@@ -71,6 +79,7 @@ func newSingleRef(
 			"",
 			FileSchemeStdout,
 			compressionType,
+			compressionLevel,
 		), nil
 	}
 	if app.IsDevNull(path) {
@@ -79,6 +88,7 @@ func newSingleRef(
 			"",
 			FileSchemeNull,
 			compressionType,
+			compressionLevel,
 		), nil
 	}
 	for prefix, fileScheme := range fileSchemePrefixToFileScheme {
@@ -95,17 +105,28 @@ func newSingleRef(
 				path,
 				fileScheme,
 				compressionType,
+				compressionLevel,
 			), nil
 		}
 	}
 	if strings.Contains(path, "://") {
-		return nil, newInvalidFilePathError(path)
+		if getCustomScheme(path) == "" {
+			return nil, newInvalidFilePathError(path)
+		}
+		return buildSingleRef(
+			format,
+			path,
+			FileSchemeCustom,
+			compressionType,
+			compressionLevel,
+		), nil
 	}
 	return buildSingleRef(
 		format,
 		normalpath.Normalize(path),
 		FileSchemeLocal,
 		compressionType,
+		compressionLevel,
 	), nil
 }
 
@@ -114,12 +135,14 @@ func buildSingleRef(
 	path string,
 	fileScheme FileScheme,
 	compressionType CompressionType,
+	compressionLevel int,
 ) *singleRef {
 	return &singleRef{
-		format:          format,
-		path:            path,
-		fileScheme:      fileScheme,
-		compressionType: compressionType,
+		format:           format,
+		path:             path,
+		fileScheme:       fileScheme,
+		compressionType:  compressionType,
+		compressionLevel: compressionLevel,
 	}
 }
 
@@ -139,6 +162,10 @@ func (r *singleRef) CompressionType() CompressionType {
 	return r.compressionType
 }
 
+func (r *singleRef) CompressionLevel() int {
+	return r.compressionLevel
+}
+
 func (*singleRef) ref()       {}
 func (*singleRef) fileRef()   {}
 func (*singleRef) singleRef() {}
@@ -15,6 +15,7 @@
 package fetch
 
 import (
+	"net/url"
 	"sort"
 	"strings"
 )
@@ -34,3 +35,34 @@ func formatsToString(formats map[string]struct{}) string {
 	sort.Strings(s)
 	return "[" + strings.Join(s, ",") + "]"
 }
+
+// getCustomScheme returns the lowercased scheme of a URL of the form
+// scheme://path, or empty if the value is not of this form.
+//
+// The scheme must be a valid URI scheme per RFC 3986.
+func getCustomScheme(value string) string {
+	index := strings.Index(value, "://")
+	if index < 1 {
+		return ""
+	}
+	scheme := value[:index]
+	for i, c := range scheme {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return ""
+		}
+	}
+	return strings.ToLower(scheme)
+}
+
+// getRedactedURL returns the url without any credentials, for logging.
+func getRedactedURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.User == nil {
+		return rawURL
+	}
+	parsedURL.User = nil
+	return parsedURL.String()
+}
@@ -15,6 +15,7 @@
 package fetch
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/ioutilextended"
+	"github.com/bufbuild/buf/internal/pkg/oci"
 	"github.com/klauspost/compress/zstd"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
@@ -36,6 +38,9 @@ type writer struct {
 	httpEnabled  bool
 	localEnabled bool
 	stdioEnabled bool
+
+	// nil if disabled
+	ociClient oci.Client
 }
 
 func newWriter(
@@ -121,7 +126,14 @@ func (w *writer) putFileWriteCloser(
 	case CompressionTypeNone:
 		return writeCloser, nil
 	case CompressionTypeGzip:
-		gzipWriteCloser := gzip.NewWriter(writeCloser)
+		compressionLevel := fileRef.CompressionLevel()
+		if compressionLevel == 0 {
+			compressionLevel = gzip.DefaultCompression
+		}
+		gzipWriteCloser, err := gzip.NewWriterLevel(writeCloser, compressionLevel)
+		if err != nil {
+			return nil, err
+		}
 		return ioutilextended.CompositeWriteCloser(
 			gzipWriteCloser,
 			ioutilextended.ChainCloser(
@@ -130,7 +142,14 @@ func (w *writer) putFileWriteCloser(
 			),
 		), nil
 	case CompressionTypeZstd:
-		zstdWriteCloser, err := zstd.NewWriter(writeCloser)
+		var zstdOptions []zstd.EOption
+		if compressionLevel := fileRef.CompressionLevel(); compressionLevel != 0 {
+			zstdOptions = append(
+				zstdOptions,
+				zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(compressionLevel)),
+			)
+		}
+		zstdWriteCloser, err := zstd.NewWriter(writeCloser, zstdOptions...)
 		if err != nil {
 			return nil, err
 		}
@@ -162,6 +181,28 @@ func (w *writer) putFileWriteCloserPotentiallyUncompressed(
 			return nil, newWriteHTTPDisabledError()
 		}
 		return nil, fmt.Errorf("https not supported for writes: %v", fileRef.Path())
+	case FileSchemeS3:
+		return nil, fmt.Errorf("s3 not supported for writes: %v", fileRef.Path())
+	case FileSchemeGCS:
+		return nil, fmt.Errorf("gs not supported for writes: %v", fileRef.Path())
+	case FileSchemeGRPC:
+		return nil, fmt.Errorf("grpc not supported for writes: %v", fileRef.Path())
+	case FileSchemeCustom:
+		return nil, fmt.Errorf("%s not supported for writes: %v", getCustomScheme(fileRef.Path()), fileRef.Path())
+	case FileSchemeOCI:
+		if w.ociClient == nil {
+			return nil, newWriteOCIDisabledError()
+		}
+		reference, err := oci.ParseReference(fileRef.Path())
+		if err != nil {
+			return nil, err
+		}
+		return newOCIWriteCloser(
+			func(data []byte) error {
+				_, err := w.ociClient.Push(ctx, container, reference, data)
+				return err
+			},
+		), nil
 	case FileSchemeLocal:
 		if !w.localEnabled {
 			return nil, newWriteLocalDisabledError()
@@ -181,6 +222,27 @@ func (w *writer) putFileWriteCloserPotentiallyUncompressed(
 	}
 }
 
+// ociWriteCloser buffers the data and pushes it on Close.
+type ociWriteCloser struct {
+	buffer *bytes.Buffer
+	push   func([]byte) error
+}
+
+func newOCIWriteCloser(push func([]byte) error) *ociWriteCloser {
+	return &ociWriteCloser{
+		buffer: bytes.NewBuffer(nil),
+		push:   push,
+	}
+}
+
+func (o *ociWriteCloser) Write(p []byte) (int, error) {
+	return o.buffer.Write(p)
+}
+
+func (o *ociWriteCloser) Close() error {
+	return o.push(o.buffer.Bytes())
+}
+
 type putFileOptions struct {
 	noFileCompression bool
 }
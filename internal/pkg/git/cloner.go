@@ -17,11 +17,16 @@ package git
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/instrument"
@@ -32,6 +37,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// gitCacheLockStaleDuration is the duration after which the lock of a cached
+// clone is considered to be left by a process that did not exit cleanly.
+const gitCacheLockStaleDuration = time.Hour
+
 type cloner struct {
 	logger  *zap.Logger
 	options ClonerOptions
@@ -69,29 +78,19 @@ func (c *cloner) CloneToBucket(
 	}
 
 	depthArg := strconv.Itoa(int(depth))
-	args := []string{"clone", "--depth", depthArg}
-
+	var cloneBranch string
 	if options.Name != nil {
-		if cloneBranch := options.Name.cloneBranch(); cloneBranch != "" {
-			args = append(args, "--branch", cloneBranch, "--single-branch")
-		}
-	}
-
-	tmpDir, err := tmp.NewDir("")
-	if err != nil {
-		return err
+		cloneBranch = options.Name.cloneBranch()
 	}
-	defer func() {
-		retErr = multierr.Append(retErr, tmpDir.Close())
-	}()
-	args = append(args, url, tmpDir.AbsPath())
 
+	var configArgs []string
 	if strings.HasPrefix(url, "https://") {
 		extraArgs, err := c.getArgsForHTTPSCommand(envContainer)
 		if err != nil {
 			return err
 		}
-		args = append(args, extraArgs...)
+		configArgs = append(configArgs, extraArgs...)
+		configArgs = append(configArgs, c.getArgsForHTTPSTLS()...)
 	}
 	if strings.HasPrefix(url, "ssh://") {
 		envContainer, err = c.getEnvContainerWithGitSSHCommand(envContainer)
@@ -100,52 +99,63 @@ func (c *cloner) CloneToBucket(
 		}
 	}
 
-	buffer := bytes.NewBuffer(nil)
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Env = app.Environ(envContainer)
-	cmd.Stderr = buffer
-	if err := cmd.Run(); err != nil {
-		// Suppress printing of temp path
-		return fmt.Errorf("%v\n%v", err, strings.Replace(buffer.String(), tmpDir.AbsPath(), "", -1))
+	var dirPath string
+	if options.CacheDirPath != "" {
+		cacheDirPath, release, err := c.updateCachedClone(
+			ctx,
+			envContainer,
+			url,
+			depthArg,
+			cloneBranch,
+			configArgs,
+			options.CacheDirPath,
+		)
+		if err != nil {
+			return err
+		}
+		if release != nil {
+			defer release()
+			dirPath = cacheDirPath
+		}
+	}
+	if dirPath == "" {
+		tmpDir, err := tmp.NewDir("")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = multierr.Append(retErr, tmpDir.Close())
+		}()
+		dirPath = tmpDir.AbsPath()
+		if err := runGit(ctx, envContainer, "", dirPath, getCloneArgs(url, dirPath, depthArg, cloneBranch, configArgs)...); err != nil {
+			return err
+		}
 	}
 
 	if options.Name != nil && options.Name.checkout() != "" {
-		args = []string{
-			"checkout",
-			options.Name.checkout(),
-		}
-		buffer.Reset()
-		cmd = exec.CommandContext(ctx, "git", args...)
-		cmd.Env = app.Environ(envContainer)
-		cmd.Dir = tmpDir.AbsPath()
-		cmd.Stderr = buffer
-		if err := cmd.Run(); err != nil {
-			// Suppress printing of temp path
-			return fmt.Errorf("%v\n%v", err, strings.Replace(buffer.String(), tmpDir.AbsPath(), "", -1))
+		if err := runGit(ctx, envContainer, dirPath, dirPath, "checkout", options.Name.checkout()); err != nil {
+			return err
 		}
 	}
 
 	if options.RecurseSubmodules {
-		args = []string{
+		if err := runGit(
+			ctx,
+			envContainer,
+			dirPath,
+			dirPath,
 			"submodule",
 			"update",
 			"--init",
 			"--recursive",
 			"--depth",
 			depthArg,
-		}
-		buffer.Reset()
-		cmd = exec.CommandContext(ctx, "git", args...)
-		cmd.Env = app.Environ(envContainer)
-		cmd.Dir = tmpDir.AbsPath()
-		cmd.Stderr = buffer
-		if err := cmd.Run(); err != nil {
-			// Suppress printing of temp path
-			return fmt.Errorf("%v\n%v", err, strings.Replace(buffer.String(), tmpDir.AbsPath(), "", -1))
+		); err != nil {
+			return err
 		}
 	}
 
-	tmpReadWriteBucket, err := storageos.NewReadWriteBucket(tmpDir.AbsPath())
+	tmpReadWriteBucket, err := storageos.NewReadWriteBucket(dirPath)
 	if err != nil {
 		return err
 	}
@@ -159,6 +169,203 @@ func (c *cloner) CloneToBucket(
 	return err
 }
 
+// updateCachedClone clones the url to the cache directory, or updates the
+// existing clone within the cache directory with a fetch.
+//
+// The returned function releases the cached clone, and must be called once
+// the clone is no longer used. If the cached clone is in use by another
+// process, or the cache directory cannot be used, the returned function is
+// nil, and the url should be cloned without the cache.
+func (c *cloner) updateCachedClone(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	url string,
+	depthArg string,
+	cloneBranch string,
+	configArgs []string,
+	cacheDirPath string,
+) (string, func(), error) {
+	if err := os.MkdirAll(cacheDirPath, 0755); err != nil {
+		c.logger.Debug("git_cache_disabled", zap.Error(err))
+		return "", nil, nil
+	}
+	// clones with and without a branch are configured to fetch different
+	// refs, so they are cached separately
+	hash := sha256.Sum256([]byte(url + "\n" + cloneBranch))
+	dirPath := filepath.Join(cacheDirPath, hex.EncodeToString(hash[:]))
+	release, err := acquireGitCacheLock(dirPath + ".lock")
+	if err != nil {
+		c.logger.Debug("git_cache_disabled", zap.Error(err))
+		return "", nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(dirPath, ".git")); err != nil {
+		c.logger.Debug("git_cache_miss", zap.String("url", url))
+		// remove anything left by an interrupted clone
+		if err := os.RemoveAll(dirPath); err != nil {
+			release()
+			return "", nil, err
+		}
+		if err := runGit(ctx, envContainer, "", dirPath, getCloneArgs(url, dirPath, depthArg, cloneBranch, configArgs)...); err != nil {
+			release()
+			return "", nil, multierr.Append(err, os.RemoveAll(dirPath))
+		}
+		return dirPath, release, nil
+	}
+	c.logger.Debug("git_cache_hit", zap.String("url", url))
+	// --config is only accepted by clone, the equivalent for other commands
+	// is -c before the command
+	var fetchArgs []string
+	for i := 0; i+1 < len(configArgs); i += 2 {
+		fetchArgs = append(fetchArgs, "-c", configArgs[i+1])
+	}
+	fetchArgs = append(fetchArgs, "fetch", "--depth", depthArg, "origin")
+	// without a branch, all branches are fetched so that any of them can be
+	// checked out, and the default branch is checked out as with a clone
+	checkoutTarget := "refs/remotes/origin/HEAD"
+	if cloneBranch != "" {
+		fetchArgs = append(fetchArgs, cloneBranch)
+		checkoutTarget = "FETCH_HEAD"
+	}
+	for _, args := range [][]string{
+		fetchArgs,
+		{"checkout", "--force", "--detach", checkoutTarget},
+		{"clean", "-ffdx"},
+	} {
+		if err := runGit(ctx, envContainer, dirPath, dirPath, args...); err != nil {
+			release()
+			return "", nil, err
+		}
+	}
+	return dirPath, release, nil
+}
+
+// acquireGitCacheLock creates the lock file at lockFilePath, failing if the
+// lock file already exists and is not stale.
+//
+// The returned function removes the lock file.
+func acquireGitCacheLock(lockFilePath string) (func(), error) {
+	if fileInfo, err := os.Stat(lockFilePath); err == nil && time.Since(fileInfo.ModTime()) > gitCacheLockStaleDuration {
+		// left by a process that did not exit cleanly
+		_ = os.Remove(lockFilePath)
+	}
+	file, err := os.OpenFile(lockFilePath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, multierr.Append(err, os.Remove(lockFilePath))
+	}
+	return func() {
+		_ = os.Remove(lockFilePath)
+	}, nil
+}
+
+func getCloneArgs(url string, dirPath string, depthArg string, cloneBranch string, configArgs []string) []string {
+	args := []string{"clone", "--depth", depthArg}
+	if cloneBranch != "" {
+		args = append(args, "--branch", cloneBranch, "--single-branch")
+	}
+	args = append(args, url, dirPath)
+	return append(args, configArgs...)
+}
+
+// runGit runs git with the args in the directory dirPath.
+//
+// If dirPath is empty, git is run in the current directory. The path
+// suppressPath is removed from any error output.
+//
+// Unless GIT_TERMINAL_PROMPT is set, git is run with terminal prompts
+// disabled, as git is not run in the process group of the terminal, see
+// runCommand. Credentials must be given with the configured environment
+// variables, a credential helper, or an ssh agent.
+func runGit(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+	suppressPath string,
+	args ...string,
+) error {
+	if envContainer.Env("GIT_TERMINAL_PROMPT") == "" {
+		envContainer = app.NewEnvContainerWithOverrides(
+			envContainer,
+			map[string]string{
+				"GIT_TERMINAL_PROMPT": "0",
+			},
+		)
+	}
+	buffer := bytes.NewBuffer(nil)
+	cmd := exec.Command("git", args...)
+	cmd.Env = app.Environ(envContainer)
+	cmd.Dir = dirPath
+	cmd.Stderr = buffer
+	if err := runCommand(ctx, cmd); err != nil {
+		// Suppress printing of temp path
+		return fmt.Errorf("%v\n%v", err, strings.Replace(buffer.String(), suppressPath, "", -1))
+	}
+	return nil
+}
+
+// runCommand runs the command, killing the command along with the processes
+// it started if the context is done before the command completes.
+//
+// exec.CommandContext only kills the command itself, which leaves the processes
+// started by git, such as git-remote-https and ssh, running. These also hold the
+// output of the command open, so the command would not complete until they exit.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	doneC := make(chan struct{})
+	defer close(doneC)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+		case <-doneC:
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
+// getArgsForHTTPSTLS returns the config args for the TLS and proxy options.
+//
+// These are set as config on the clone, so they also apply to submodule updates.
+func (c *cloner) getArgsForHTTPSTLS() []string {
+	var args []string
+	if c.options.HTTPSCACertFilePath != "" {
+		args = append(args, "--config", "http.sslCAInfo="+c.options.HTTPSCACertFilePath)
+	}
+	if c.options.HTTPSInsecureSkipVerify {
+		args = append(args, "--config", "http.sslVerify=false")
+	}
+	if c.options.HTTPSMinTLSVersion != "" {
+		args = append(args, "--config", "http.sslVersion="+c.options.HTTPSMinTLSVersion)
+	}
+	if c.options.HTTPSProxy != "" {
+		args = append(args, "--config", "http.proxy="+c.options.HTTPSProxy)
+	}
+	for _, clientCertificate := range c.options.HTTPSClientCertificates {
+		// git applies http.<url>.* config to matching urls over http.*
+		configPrefix := "http."
+		if clientCertificate.Host != "" {
+			configPrefix = "http.https://" + clientCertificate.Host + "/."
+		}
+		args = append(
+			args,
+			"--config", configPrefix+"sslCert="+clientCertificate.CertFilePath,
+			"--config", configPrefix+"sslKey="+clientCertificate.KeyFilePath,
+		)
+	}
+	return args
+}
+
 func (c *cloner) getArgsForHTTPSCommand(envContainer app.EnvContainer) ([]string, error) {
 	if c.options.HTTPSUsernameEnvKey == "" || c.options.HTTPSPasswordEnvKey == "" {
 		return nil, nil
@@ -0,0 +1,113 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+)
+
+// ChangedFilePaths returns the paths of the files that differ between the
+// ref and the working tree of the repository that contains dirPath.
+//
+// The paths are relative to dirPath, and only files within dirPath are
+// returned. Deleted files are not returned.
+func ChangedFilePaths(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+	ref string,
+) ([]string, error) {
+	paths, err := diffFilePaths(ctx, envContainer, dirPath, ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not diff against %q: %v", ref, err)
+	}
+	return paths, nil
+}
+
+// StagedFilePaths returns the paths of the files that are staged to be
+// committed in the repository that contains dirPath.
+//
+// The paths are relative to dirPath, and only files within dirPath are
+// returned. Deleted files are not returned.
+func StagedFilePaths(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+) ([]string, error) {
+	paths, err := diffFilePaths(ctx, envContainer, dirPath, "--cached")
+	if err != nil {
+		return nil, fmt.Errorf("could not get staged files: %v", err)
+	}
+	return paths, nil
+}
+
+// diffFilePaths returns the paths of the files in the output of git diff
+// with the given argument, which is either a ref or a flag.
+func diffFilePaths(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+	arg string,
+) ([]string, error) {
+	output, err := runGitOutput(
+		ctx,
+		envContainer,
+		dirPath,
+		"diff",
+		"--name-only",
+		"--relative",
+		"--diff-filter=d",
+		"-z",
+		arg,
+		"--",
+	)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range strings.Split(output, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// runGitOutput runs git with the args in the directory dirPath and returns
+// its stdout.
+func runGitOutput(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+	args ...string,
+) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.Command("git", args...)
+	cmd.Env = app.Environ(envContainer)
+	cmd.Dir = dirPath
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runCommand(ctx, cmd); err != nil {
+		return "", fmt.Errorf("%v\n%v", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
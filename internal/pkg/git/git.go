@@ -71,6 +71,13 @@ type CloneToBucketOptions struct {
 	Mapper            storage.Mapper
 	Name              Name
 	RecurseSubmodules bool
+	// CacheDirPath is the directory to cache clones in.
+	//
+	// If set, the clone of each url is kept within this directory and is
+	// updated with a fetch on subsequent clones, so that only new objects are
+	// fetched. If the cached clone is in use by another process, the
+	// repository is cloned without the cache.
+	CacheDirPath string
 }
 
 // NewCloner returns a new Cloner.
@@ -84,4 +91,33 @@ type ClonerOptions struct {
 	HTTPSPasswordEnvKey      string
 	SSHKeyFileEnvKey         string
 	SSHKnownHostsFilesEnvKey string
+
+	// HTTPSCACertFilePath is the path to a PEM bundle of CA certificates
+	// to verify https servers with instead of the system CA certificates.
+	HTTPSCACertFilePath string
+	// HTTPSInsecureSkipVerify disables verification of https servers.
+	HTTPSInsecureSkipVerify bool
+	// HTTPSMinTLSVersion is the minimum TLS version for https, in the
+	// format of the git http.sslVersion config, such as "tlsv1.2".
+	HTTPSMinTLSVersion string
+	// HTTPSClientCertificates are the client certificates to present to
+	// https servers.
+	HTTPSClientCertificates []HTTPSClientCertificate
+	// HTTPSProxy is the URL of the proxy for https.
+	//
+	// If empty, git uses the proxy in the environment, such as HTTPS_PROXY.
+	HTTPSProxy string
+}
+
+// HTTPSClientCertificate is a client certificate to present to https servers.
+type HTTPSClientCertificate struct {
+	// Host is the host to present the certificate to, optionally with a port.
+	//
+	// If empty, the certificate is presented to all hosts that do not have
+	// their own certificate.
+	Host string
+	// CertFilePath is the path to the PEM certificate.
+	CertFilePath string
+	// KeyFilePath is the path to the PEM private key.
+	KeyFilePath string
 }
@@ -17,11 +17,14 @@ package git
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
 	"github.com/bufbuild/buf/internal/pkg/storage"
@@ -208,6 +211,262 @@ func TestCloneDefault(t *testing.T) {
 	assert.True(t, storage.IsNotExist(err))
 }
 
+func TestCloneWithCache(t *testing.T) {
+	t.Parallel()
+	repoDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(repoDirPath))
+	}()
+	cacheDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(cacheDirPath))
+	}()
+	testRunGit(t, repoDirPath, "init", "--quiet")
+	testRunGit(t, repoDirPath, "checkout", "--quiet", "-b", "main")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "a.proto"), []byte(`syntax = "proto3";`), 0644))
+	testRunGit(t, repoDirPath, "add", ".")
+	testRunGit(t, repoDirPath, "commit", "--quiet", "-m", "first")
+
+	cloner := NewCloner(zap.NewNop(), ClonerOptions{})
+	envContainer, err := app.NewEnvContainerForOS()
+	require.NoError(t, err)
+	testClone := func(name Name) storage.ReadBucket {
+		readBucketBuilder := storagemem.NewReadBucketBuilder()
+		require.NoError(
+			t,
+			cloner.CloneToBucket(
+				context.Background(),
+				envContainer,
+				"file://"+repoDirPath,
+				1,
+				readBucketBuilder,
+				CloneToBucketOptions{
+					Name:         name,
+					CacheDirPath: cacheDirPath,
+				},
+			),
+		)
+		readBucket, err := readBucketBuilder.ToReadBucket()
+		require.NoError(t, err)
+		return readBucket
+	}
+
+	for _, name := range []Name{nil, NewBranchName("main")} {
+		readBucket := testClone(name)
+		_, err = readBucket.Stat(context.Background(), "a.proto")
+		assert.NoError(t, err)
+		_, err = readBucket.Stat(context.Background(), "b.proto")
+		assert.True(t, storage.IsNotExist(err))
+	}
+	fileInfos, err := ioutil.ReadDir(cacheDirPath)
+	require.NoError(t, err)
+	// one clone with and one without the branch, and no lock files
+	assert.Len(t, fileInfos, 2)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "b.proto"), []byte(`syntax = "proto3";`), 0644))
+	testRunGit(t, repoDirPath, "rm", "--quiet", "a.proto")
+	testRunGit(t, repoDirPath, "add", ".")
+	testRunGit(t, repoDirPath, "commit", "--quiet", "-m", "second")
+
+	for _, name := range []Name{nil, NewBranchName("main")} {
+		readBucket := testClone(name)
+		_, err = readBucket.Stat(context.Background(), "a.proto")
+		assert.True(t, storage.IsNotExist(err))
+		_, err = readBucket.Stat(context.Background(), "b.proto")
+		assert.NoError(t, err)
+	}
+	fileInfos, err = ioutil.ReadDir(cacheDirPath)
+	require.NoError(t, err)
+	assert.Len(t, fileInfos, 2)
+}
+
+func TestGetArgsForHTTPSTLS(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name         string
+		options      ClonerOptions
+		expectedArgs []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "ca_cert",
+			options: ClonerOptions{
+				HTTPSCACertFilePath: "/etc/ca.crt",
+			},
+			expectedArgs: []string{"--config", "http.sslCAInfo=/etc/ca.crt"},
+		},
+		{
+			name: "insecure_skip_verify",
+			options: ClonerOptions{
+				HTTPSInsecureSkipVerify: true,
+			},
+			expectedArgs: []string{"--config", "http.sslVerify=false"},
+		},
+		{
+			name: "min_tls_version",
+			options: ClonerOptions{
+				HTTPSMinTLSVersion: "tlsv1.2",
+			},
+			expectedArgs: []string{"--config", "http.sslVersion=tlsv1.2"},
+		},
+		{
+			name: "proxy",
+			options: ClonerOptions{
+				HTTPSProxy: "http://proxy.example.com:3128",
+			},
+			expectedArgs: []string{"--config", "http.proxy=http://proxy.example.com:3128"},
+		},
+		{
+			name: "client_certificates",
+			options: ClonerOptions{
+				HTTPSClientCertificates: []HTTPSClientCertificate{
+					{
+						CertFilePath: "/etc/default.crt",
+						KeyFilePath:  "/etc/default.key",
+					},
+					{
+						Host:         "a.example.com:8443",
+						CertFilePath: "/etc/a.crt",
+						KeyFilePath:  "/etc/a.key",
+					},
+				},
+			},
+			expectedArgs: []string{
+				"--config", "http.sslCert=/etc/default.crt",
+				"--config", "http.sslKey=/etc/default.key",
+				"--config", "http.https://a.example.com:8443/.sslCert=/etc/a.crt",
+				"--config", "http.https://a.example.com:8443/.sslKey=/etc/a.key",
+			},
+		},
+		{
+			name: "all",
+			options: ClonerOptions{
+				HTTPSCACertFilePath:     "/etc/ca.crt",
+				HTTPSInsecureSkipVerify: true,
+				HTTPSMinTLSVersion:      "tlsv1.3",
+			},
+			expectedArgs: []string{
+				"--config", "http.sslCAInfo=/etc/ca.crt",
+				"--config", "http.sslVerify=false",
+				"--config", "http.sslVersion=tlsv1.3",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expectedArgs, newCloner(zap.NewNop(), testCase.options).getArgsForHTTPSTLS())
+		})
+	}
+}
+
+func TestRunCommandKillsProcessGroup(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("processes started by the command are not killed on windows")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the background sleep holds stderr open after the shell is killed
+	cmd := exec.Command("sh", "-c", "sleep 60 & sleep 60")
+	cmd.Stderr = bytes.NewBuffer(nil)
+	start := time.Now()
+	err := runCommand(ctx, cmd)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 30*time.Second)
+}
+
+func TestChangedFilePaths(t *testing.T) {
+	t.Parallel()
+	repoDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(repoDirPath))
+	}()
+	protoDirPath := filepath.Join(repoDirPath, "proto")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	testRunGit(t, repoDirPath, "init", "--quiet")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "README.md"), []byte(`readme`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte(`syntax = "proto3";`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "b.proto"), []byte(`syntax = "proto3";`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "c.proto"), []byte(`syntax = "proto3";`), 0644))
+	testRunGit(t, repoDirPath, "add", ".")
+	testRunGit(t, repoDirPath, "commit", "--quiet", "-m", "first")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "README.md"), []byte(`changed`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte(`syntax = "proto2";`), 0644))
+	require.NoError(t, os.Remove(filepath.Join(protoDirPath, "b.proto")))
+
+	envContainer, err := app.NewEnvContainerForOS()
+	require.NoError(t, err)
+	paths, err := ChangedFilePaths(context.Background(), envContainer, protoDirPath, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto"}, paths)
+	paths, err = ChangedFilePaths(context.Background(), envContainer, repoDirPath, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "proto/a.proto"}, paths)
+	_, err = ChangedFilePaths(context.Background(), envContainer, repoDirPath, "nonexistent")
+	assert.Error(t, err)
+}
+
+func TestStagedFilePaths(t *testing.T) {
+	t.Parallel()
+	repoDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(repoDirPath))
+	}()
+	protoDirPath := filepath.Join(repoDirPath, "proto")
+	require.NoError(t, os.MkdirAll(protoDirPath, 0755))
+	testRunGit(t, repoDirPath, "init", "--quiet")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoDirPath, "README.md"), []byte(`readme`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "a.proto"), []byte(`syntax = "proto3";`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoDirPath, "b.proto"), []byte(`syntax = "proto3";`), 0644))
+	testRunGit(t, repoDirPath, "add", "README.md", "proto/a.proto")
+
+	envContainer, err := app.NewEnvContainerForOS()
+	require.NoError(t, err)
+	paths, err := StagedFilePaths(context.Background(), envContainer, protoDirPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.proto"}, paths)
+	paths, err = StagedFilePaths(context.Background(), envContainer, repoDirPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README.md", "proto/a.proto"}, paths)
+
+	topLevelDirPath, err := TopLevelDirPath(context.Background(), envContainer, protoDirPath)
+	require.NoError(t, err)
+	expectedTopLevelDirPath, err := filepath.EvalSymlinks(repoDirPath)
+	require.NoError(t, err)
+	actualTopLevelDirPath, err := filepath.EvalSymlinks(topLevelDirPath)
+	require.NoError(t, err)
+	assert.Equal(t, expectedTopLevelDirPath, actualTopLevelDirPath)
+	hooksDirPath, err := HooksDirPath(context.Background(), envContainer, repoDirPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoDirPath, ".git", "hooks"), hooksDirPath)
+}
+
+func testRunGit(t *testing.T, dirPath string, args ...string) {
+	cmd := exec.Command(
+		"git",
+		append(
+			[]string{
+				"-c", "user.name=test",
+				"-c", "user.email=test@test.com",
+				"-c", "commit.gpgsign=false",
+			},
+			args...,
+		)...,
+	)
+	cmd.Dir = dirPath
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
 func testGetLastGitCommit(t *testing.T) string {
 	envContainer, err := app.NewEnvContainerForOS()
 	require.NoError(t, err)
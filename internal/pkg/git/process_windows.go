@@ -0,0 +1,30 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package git
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+// killProcessGroup kills the started command.
+//
+// Processes started by the command are not killed on Windows.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
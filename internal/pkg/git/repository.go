@@ -0,0 +1,59 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+)
+
+// TopLevelDirPath returns the absolute path of the top-level directory of
+// the working tree of the repository that contains dirPath.
+func TopLevelDirPath(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+) (string, error) {
+	output, err := runGitOutput(ctx, envContainer, dirPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("could not get the git repository of %s: %v", dirPath, err)
+	}
+	return filepath.Clean(strings.TrimSpace(output)), nil
+}
+
+// HooksDirPath returns the path of the hooks directory of the repository
+// that contains dirPath.
+//
+// The path is relative if dirPath is relative and the hooks directory is
+// within the repository.
+func HooksDirPath(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	dirPath string,
+) (string, error) {
+	output, err := runGitOutput(ctx, envContainer, dirPath, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("could not get the git hooks directory of %s: %v", dirPath, err)
+	}
+	hooksDirPath := filepath.FromSlash(strings.TrimSpace(output))
+	if !filepath.IsAbs(hooksDirPath) {
+		hooksDirPath = filepath.Join(dirPath, hooksDirPath)
+	}
+	return hooksDirPath, nil
+}
@@ -0,0 +1,448 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcreflect
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.uber.org/multierr"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// the v1alpha service is used if the server does not have the v1 service
+	reflectionV1Path      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	reflectionV1AlphaPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+
+	// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+	grpcStatusOK            = "0"
+	grpcStatusUnimplemented = "12"
+
+	// the maximum size of a response message
+	maxMessageSize = 64 << 20
+)
+
+// the fields of ServerReflectionRequest and ServerReflectionResponse
+//
+// https://github.com/grpc/grpc/blob/master/src/proto/grpc/reflection/v1/reflection.proto
+const (
+	requestFileByFilenameFieldNumber       protowire.Number = 3
+	requestFileContainingSymbolFieldNumber protowire.Number = 4
+	requestListServicesFieldNumber         protowire.Number = 7
+
+	responseFileDescriptorResponseFieldNumber protowire.Number = 4
+	responseListServicesResponseFieldNumber   protowire.Number = 6
+	responseErrorResponseFieldNumber          protowire.Number = 7
+
+	fileDescriptorResponseFileDescriptorProtoFieldNumber protowire.Number = 1
+	listServiceResponseServiceFieldNumber                protowire.Number = 1
+	serviceResponseNameFieldNumber                       protowire.Number = 1
+	errorResponseErrorCodeFieldNumber                    protowire.Number = 1
+	errorResponseErrorMessageFieldNumber                 protowire.Number = 2
+)
+
+type client struct {
+	httpClient *http.Client
+}
+
+func newClient(httpClient *http.Client) *client {
+	return &client{
+		httpClient: httpClient,
+	}
+}
+
+func (c *client) GetFileDescriptorProtos(
+	ctx context.Context,
+	address string,
+) ([]*descriptorpb.FileDescriptorProto, []string, error) {
+	session := &session{
+		client:  c,
+		baseURL: "https://" + address,
+		path:    reflectionV1Path,
+	}
+	services, err := session.listServices(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(services) == 0 {
+		return nil, nil, fmt.Errorf("%s has no services", address)
+	}
+	nameToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto)
+	serviceFilePathMap := make(map[string]struct{})
+	for _, service := range services {
+		fileDescriptorProtos, err := session.getFileDescriptorProtos(ctx, requestFileContainingSymbolFieldNumber, service)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get file of service %s: %v", service, err)
+		}
+		serviceFilePath := ""
+		for _, fileDescriptorProto := range fileDescriptorProtos {
+			nameToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+			if fileHasService(fileDescriptorProto, service) {
+				serviceFilePath = fileDescriptorProto.GetName()
+			}
+		}
+		if serviceFilePath == "" {
+			return nil, nil, fmt.Errorf("server did not return the file of service %s", service)
+		}
+		serviceFilePathMap[serviceFilePath] = struct{}{}
+	}
+	// servers may only return the dependencies they have not yet returned on
+	// the stream, and each request is a new stream, but this is not guaranteed
+	for {
+		missingDependency := getMissingDependency(nameToFileDescriptorProto)
+		if missingDependency == "" {
+			break
+		}
+		fileDescriptorProtos, err := session.getFileDescriptorProtos(ctx, requestFileByFilenameFieldNumber, missingDependency)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get file %s: %v", missingDependency, err)
+		}
+		if _, ok := getFileDescriptorProtoMap(fileDescriptorProtos)[missingDependency]; !ok {
+			return nil, nil, fmt.Errorf("server did not return file %s", missingDependency)
+		}
+		for _, fileDescriptorProto := range fileDescriptorProtos {
+			nameToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+		}
+	}
+	serviceFilePaths := make([]string, 0, len(serviceFilePathMap))
+	for serviceFilePath := range serviceFilePathMap {
+		serviceFilePaths = append(serviceFilePaths, serviceFilePath)
+	}
+	sort.Strings(serviceFilePaths)
+	return orderFileDescriptorProtos(nameToFileDescriptorProto), serviceFilePaths, nil
+}
+
+// session makes requests to the reflection service of a single server.
+type session struct {
+	client  *client
+	baseURL string
+	// set to reflectionV1AlphaPath if the server does not have the v1 service
+	path string
+}
+
+func (s *session) listServices(ctx context.Context) ([]string, error) {
+	response, err := s.call(ctx, protowire.AppendString(protowire.AppendTag(nil, requestListServicesFieldNumber, protowire.BytesType), ""))
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	if err := rangeFields(
+		response.listServicesResponse,
+		func(number protowire.Number, value []byte) error {
+			if number != listServiceResponseServiceFieldNumber {
+				return nil
+			}
+			return rangeFields(
+				value,
+				func(number protowire.Number, value []byte) error {
+					if number == serviceResponseNameFieldNumber {
+						if name := string(value); !strings.HasPrefix(name, "grpc.") {
+							services = append(services, name)
+						}
+					}
+					return nil
+				},
+			)
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+func (s *session) getFileDescriptorProtos(
+	ctx context.Context,
+	requestFieldNumber protowire.Number,
+	value string,
+) ([]*descriptorpb.FileDescriptorProto, error) {
+	response, err := s.call(ctx, protowire.AppendString(protowire.AppendTag(nil, requestFieldNumber, protowire.BytesType), value))
+	if err != nil {
+		return nil, err
+	}
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	if err := rangeFields(
+		response.fileDescriptorResponse,
+		func(number protowire.Number, value []byte) error {
+			if number != fileDescriptorResponseFileDescriptorProtoFieldNumber {
+				return nil
+			}
+			fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(value, fileDescriptorProto); err != nil {
+				return err
+			}
+			fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return fileDescriptorProtos, nil
+}
+
+type reflectionResponse struct {
+	fileDescriptorResponse []byte
+	listServicesResponse   []byte
+}
+
+// call makes a request on a new stream and reads the single response.
+func (s *session) call(ctx context.Context, request []byte) (*reflectionResponse, error) {
+	data, grpcStatus, grpcMessage, err := s.callOnce(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if grpcStatus == grpcStatusUnimplemented && s.path == reflectionV1Path {
+		s.path = reflectionV1AlphaPath
+		data, grpcStatus, grpcMessage, err = s.callOnce(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if grpcStatus == grpcStatusUnimplemented {
+			return nil, errors.New("server does not have the reflection service")
+		}
+	}
+	if grpcStatus != grpcStatusOK {
+		return nil, fmt.Errorf("reflection failed with gRPC status %s: %s", grpcStatus, grpcMessage)
+	}
+	response := &reflectionResponse{}
+	if err := rangeFields(
+		data,
+		func(number protowire.Number, value []byte) error {
+			switch number {
+			case responseFileDescriptorResponseFieldNumber:
+				response.fileDescriptorResponse = value
+			case responseListServicesResponseFieldNumber:
+				response.listServicesResponse = value
+			case responseErrorResponseFieldNumber:
+				return newErrorResponseError(value)
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// callOnce returns the response message and the gRPC status and message.
+func (s *session) callOnce(ctx context.Context, request []byte) (_ []byte, _ string, _ string, retErr error) {
+	body := make([]byte, 5, 5+len(request))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	body = append(body, request...)
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+s.path, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", "", err
+	}
+	httpRequest.Header.Set("Content-Type", "application/grpc")
+	httpRequest.Header.Set("TE", "trailers")
+	httpResponse, err := s.client.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, httpResponse.Body.Close())
+	}()
+	if httpResponse.ProtoMajor != 2 {
+		return nil, "", "", errors.New("server does not support HTTP/2")
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("got HTTP status code %d", httpResponse.StatusCode)
+	}
+	// trailers-only responses have the status in the headers
+	if grpcStatus := httpResponse.Header.Get("Grpc-Status"); grpcStatus != "" {
+		return nil, grpcStatus, getGRPCMessage(httpResponse.Header), nil
+	}
+	data, err := readMessage(httpResponse.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	// the trailers are only populated once the body is read to EOF
+	if _, err := io.Copy(ioutil.Discard, httpResponse.Body); err != nil {
+		return nil, "", "", err
+	}
+	grpcStatus := httpResponse.Trailer.Get("Grpc-Status")
+	if grpcStatus == "" {
+		return nil, "", "", errors.New("server did not return a gRPC status")
+	}
+	return data, grpcStatus, getGRPCMessage(httpResponse.Trailer), nil
+}
+
+// readMessage reads a single length-prefixed message.
+func readMessage(reader io.Reader) ([]byte, error) {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(reader, prefix); err != nil {
+		if err == io.EOF {
+			// the status is in the trailers
+			return nil, nil
+		}
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("server returned a compressed message")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("server returned a message of %d bytes, which is larger than the maximum of %d", size, maxMessageSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func getGRPCMessage(header http.Header) string {
+	// the message is percent-encoded
+	message, err := url.PathUnescape(header.Get("Grpc-Message"))
+	if err != nil {
+		return header.Get("Grpc-Message")
+	}
+	return message
+}
+
+func newErrorResponseError(value []byte) error {
+	var errorCode uint64
+	var errorMessage string
+	if err := rangeFields(
+		value,
+		func(number protowire.Number, value []byte) error {
+			switch number {
+			case errorResponseErrorCodeFieldNumber:
+				errorCode, _ = protowire.ConsumeVarint(value)
+			case errorResponseErrorMessageFieldNumber:
+				errorMessage = string(value)
+			}
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+	return fmt.Errorf("reflection failed with gRPC status %d: %s", errorCode, errorMessage)
+}
+
+// rangeFields calls f for each field of the message.
+//
+// For length-delimited fields, the value is the content of the field. For
+// varint fields, the value is the encoded varint. Other fields are skipped.
+func rangeFields(data []byte, f func(protowire.Number, []byte) error) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		var value []byte
+		switch wireType {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		case protowire.VarintType:
+			_, n = protowire.ConsumeVarint(data)
+			if n >= 0 {
+				value = data[:n]
+			}
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if value != nil {
+			if err := f(number, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fileHasService(fileDescriptorProto *descriptorpb.FileDescriptorProto, service string) bool {
+	for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		fullName := serviceDescriptorProto.GetName()
+		if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+			fullName = pkg + "." + fullName
+		}
+		if fullName == service {
+			return true
+		}
+	}
+	return false
+}
+
+// getMissingDependency returns the first dependency, in sorted order, that
+// is not in the map, or empty if there are none.
+func getMissingDependency(nameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto) string {
+	var missingDependencies []string
+	for _, fileDescriptorProto := range nameToFileDescriptorProto {
+		for _, dependency := range fileDescriptorProto.GetDependency() {
+			if _, ok := nameToFileDescriptorProto[dependency]; !ok {
+				missingDependencies = append(missingDependencies, dependency)
+			}
+		}
+	}
+	if len(missingDependencies) == 0 {
+		return ""
+	}
+	sort.Strings(missingDependencies)
+	return missingDependencies[0]
+}
+
+func getFileDescriptorProtoMap(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) map[string]struct{} {
+	m := make(map[string]struct{}, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		m[fileDescriptorProto.GetName()] = struct{}{}
+	}
+	return m
+}
+
+// orderFileDescriptorProtos orders the FileDescriptorProtos in DAG order,
+// visiting files in sorted order so that the order is deterministic.
+func orderFileDescriptorProtos(nameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	names := make([]string, 0, len(nameToFileDescriptorProto))
+	for name := range nameToFileDescriptorProto {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ordered := make([]*descriptorpb.FileDescriptorProto, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	var visit func(string)
+	visit = func(name string) {
+		if _, ok := seen[name]; ok {
+			return
+		}
+		seen[name] = struct{}{}
+		fileDescriptorProto := nameToFileDescriptorProto[name]
+		for _, dependency := range fileDescriptorProto.GetDependency() {
+			visit(dependency)
+		}
+		ordered = append(ordered, fileDescriptorProto)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return ordered
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcreflect gets the FileDescriptorProtos of gRPC servers with the
+// server reflection service.
+//
+// https://github.com/grpc/grpc/blob/master/doc/server-reflection.md
+package grpcreflect
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Client gets FileDescriptorProtos from gRPC servers.
+type Client interface {
+	// GetFileDescriptorProtos gets the FileDescriptorProtos of all services
+	// of the server at the address, including their dependencies.
+	//
+	// The address is of the form host:port. The FileDescriptorProtos are
+	// returned in DAG order. Also returns the paths of the files that
+	// contain the services.
+	//
+	// Services within the grpc package, such as the reflection service
+	// itself, are ignored.
+	GetFileDescriptorProtos(
+		ctx context.Context,
+		address string,
+	) ([]*descriptorpb.FileDescriptorProto, []string, error)
+}
+
+// NewClient returns a new Client.
+//
+// Servers are connected to with HTTP/2 over TLS with the httpClient, so the
+// httpClient must support HTTP/2. Servers that only accept plaintext
+// connections are not supported.
+func NewClient(httpClient *http.Client) Client {
+	return newClient(httpClient)
+}
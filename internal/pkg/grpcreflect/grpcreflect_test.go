@@ -0,0 +1,190 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcreflect
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGetFileDescriptorProtos(t *testing.T) {
+	t.Parallel()
+	testGetFileDescriptorProtos(t, reflectionV1Path)
+}
+
+func TestGetFileDescriptorProtosV1Alpha(t *testing.T) {
+	t.Parallel()
+	testGetFileDescriptorProtos(t, reflectionV1AlphaPath)
+}
+
+func TestGetFileDescriptorProtosNoReflection(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, "", nil)
+	defer server.Close()
+	_, _, err := NewClient(server.Client()).GetFileDescriptorProtos(
+		context.Background(),
+		strings.TrimPrefix(server.URL, "https://"),
+	)
+	assert.Error(t, err)
+}
+
+func testGetFileDescriptorProtos(t *testing.T, path string) {
+	fileDescriptorProtos := []*descriptorpb.FileDescriptorProto{
+		{
+			Name:    proto.String("a/v1/a.proto"),
+			Package: proto.String("a.v1"),
+			Dependency: []string{
+				"b/v1/b.proto",
+			},
+			Service: []*descriptorpb.ServiceDescriptorProto{
+				{
+					Name: proto.String("AService"),
+				},
+			},
+		},
+		{
+			Name:    proto.String("a/v1/a_messages.proto"),
+			Package: proto.String("a.v1"),
+		},
+		{
+			Name:    proto.String("b/v1/b.proto"),
+			Package: proto.String("b.v1"),
+			Dependency: []string{
+				"c/v1/c.proto",
+			},
+		},
+		{
+			Name:    proto.String("c/v1/c.proto"),
+			Package: proto.String("c.v1"),
+		},
+		{
+			Name:    proto.String("grpc/health/v1/health.proto"),
+			Package: proto.String("grpc.health.v1"),
+			Service: []*descriptorpb.ServiceDescriptorProto{
+				{
+					Name: proto.String("Health"),
+				},
+			},
+		},
+	}
+	server := newTestServer(t, path, fileDescriptorProtos)
+	defer server.Close()
+	actualFileDescriptorProtos, serviceFilePaths, err := NewClient(server.Client()).GetFileDescriptorProtos(
+		context.Background(),
+		strings.TrimPrefix(server.URL, "https://"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/v1/a.proto"}, serviceFilePaths)
+	var names []string
+	for _, fileDescriptorProto := range actualFileDescriptorProtos {
+		names = append(names, fileDescriptorProto.GetName())
+	}
+	assert.Equal(t, []string{"c/v1/c.proto", "b/v1/b.proto", "a/v1/a.proto"}, names)
+}
+
+// newTestServer returns a server with the reflection service at path that
+// serves the FileDescriptorProtos.
+//
+// Requests for files only return the file itself without its dependencies.
+func newTestServer(
+	t *testing.T,
+	path string,
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+) *httptest.Server {
+	server := httptest.NewUnstartedServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL.Path != path {
+					responseWriter.Header().Set("Content-Type", "application/grpc")
+					responseWriter.Header().Set("Grpc-Status", grpcStatusUnimplemented)
+					return
+				}
+				prefix := make([]byte, 5)
+				_, err := io.ReadFull(request.Body, prefix)
+				require.NoError(t, err)
+				data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+				_, err = io.ReadFull(request.Body, data)
+				require.NoError(t, err)
+				var response []byte
+				require.NoError(
+					t,
+					rangeFields(
+						data,
+						func(number protowire.Number, value []byte) error {
+							switch number {
+							case requestListServicesFieldNumber:
+								var listServiceResponse []byte
+								for _, fileDescriptorProto := range fileDescriptorProtos {
+									for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+										var serviceResponse []byte
+										serviceResponse = protowire.AppendTag(serviceResponse, serviceResponseNameFieldNumber, protowire.BytesType)
+										serviceResponse = protowire.AppendString(serviceResponse, fileDescriptorProto.GetPackage()+"."+serviceDescriptorProto.GetName())
+										listServiceResponse = protowire.AppendTag(listServiceResponse, listServiceResponseServiceFieldNumber, protowire.BytesType)
+										listServiceResponse = protowire.AppendBytes(listServiceResponse, serviceResponse)
+									}
+								}
+								response = protowire.AppendTag(response, responseListServicesResponseFieldNumber, protowire.BytesType)
+								response = protowire.AppendBytes(response, listServiceResponse)
+							case requestFileByFilenameFieldNumber, requestFileContainingSymbolFieldNumber:
+								for _, fileDescriptorProto := range fileDescriptorProtos {
+									if (number == requestFileByFilenameFieldNumber && fileDescriptorProto.GetName() == string(value)) ||
+										(number == requestFileContainingSymbolFieldNumber && fileHasService(fileDescriptorProto, string(value))) {
+										fileDescriptorProtoData, err := proto.Marshal(fileDescriptorProto)
+										if err != nil {
+											return err
+										}
+										var fileDescriptorResponse []byte
+										fileDescriptorResponse = protowire.AppendTag(fileDescriptorResponse, fileDescriptorResponseFileDescriptorProtoFieldNumber, protowire.BytesType)
+										fileDescriptorResponse = protowire.AppendBytes(fileDescriptorResponse, fileDescriptorProtoData)
+										response = protowire.AppendTag(response, responseFileDescriptorResponseFieldNumber, protowire.BytesType)
+										response = protowire.AppendBytes(response, fileDescriptorResponse)
+										return nil
+									}
+								}
+								var errorResponse []byte
+								errorResponse = protowire.AppendTag(errorResponse, errorResponseErrorCodeFieldNumber, protowire.VarintType)
+								errorResponse = protowire.AppendVarint(errorResponse, 5)
+								errorResponse = protowire.AppendTag(errorResponse, errorResponseErrorMessageFieldNumber, protowire.BytesType)
+								errorResponse = protowire.AppendString(errorResponse, "not found")
+								response = protowire.AppendTag(response, responseErrorResponseFieldNumber, protowire.BytesType)
+								response = protowire.AppendBytes(response, errorResponse)
+							}
+							return nil
+						},
+					),
+				)
+				responseWriter.Header().Set("Content-Type", "application/grpc")
+				responseWriter.Header().Set("Trailer", "Grpc-Status")
+				binary.BigEndian.PutUint32(prefix[1:], uint32(len(response)))
+				_, _ = responseWriter.Write(append(prefix, response...))
+				responseWriter.Header().Set("Grpc-Status", grpcStatusOK)
+			},
+		),
+	)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	return server
+}
@@ -0,0 +1,407 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpauth
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/multierr"
+)
+
+const (
+	awsSigningAlgorithm = "AWS4-HMAC-SHA256"
+	awsS3ServiceName    = "s3"
+	awsDefaultRegion    = "us-east-1"
+	awsDefaultProfile   = "default"
+	// the hex-encoded SHA256 of an empty payload
+	awsEmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// S3 accepts requests that do not sign the payload
+	awsUnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	awsECSCredentialsHost         = "http://169.254.170.2"
+	awsEC2MetadataDefaultEndpoint = "http://169.254.169.254"
+	// the metadata endpoints are only reachable from within AWS, and
+	// requests to them otherwise hang until the connection times out
+	awsMetadataTimeout = time.Second
+)
+
+type awsAuthenticator struct {
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func newAWSAuthenticator(httpClient *http.Client) *awsAuthenticator {
+	return &awsAuthenticator{
+		httpClient: httpClient,
+		now:        time.Now,
+	}
+}
+
+func (a *awsAuthenticator) SetAuth(envContainer app.EnvContainer, request *http.Request) (bool, error) {
+	credentials, err := a.getCredentials(request.Context(), envContainer)
+	if err != nil {
+		return false, err
+	}
+	if credentials == nil {
+		return false, nil
+	}
+	signAWSRequest(request, credentials, AWSRegion(envContainer), awsS3ServiceName, a.now())
+	return true, nil
+}
+
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId,omitempty"`
+	SecretAccessKey string `json:"SecretAccessKey,omitempty"`
+	Token           string `json:"Token,omitempty"`
+}
+
+// getCredentials gets the credentials from the environment, the shared
+// credentials file, the ECS container credentials endpoint, and the EC2
+// instance metadata service, in that order.
+//
+// Returns nil if there are no credentials.
+func (a *awsAuthenticator) getCredentials(ctx context.Context, envContainer app.EnvContainer) (*awsCredentials, error) {
+	accessKeyID := envContainer.Env("AWS_ACCESS_KEY_ID")
+	secretAccessKey := envContainer.Env("AWS_SECRET_ACCESS_KEY")
+	switch {
+	case accessKeyID != "" && secretAccessKey != "":
+		return &awsCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			Token:           envContainer.Env("AWS_SESSION_TOKEN"),
+		}, nil
+	case accessKeyID != "":
+		return nil, fmt.Errorf("%s set but %s not set", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY")
+	case secretAccessKey != "":
+		return nil, fmt.Errorf("%s set but %s not set", "AWS_SECRET_ACCESS_KEY", "AWS_ACCESS_KEY_ID")
+	}
+	credentials, err := getAWSSharedCredentials(envContainer)
+	if err != nil || credentials != nil {
+		return credentials, err
+	}
+	if relativeURI := envContainer.Env("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relativeURI != "" {
+		return a.getAWSMetadataCredentials(ctx, awsECSCredentialsHost+relativeURI, nil)
+	}
+	if fullURI := envContainer.Env("AWS_CONTAINER_CREDENTIALS_FULL_URI"); fullURI != "" {
+		var header http.Header
+		if authorizationToken := envContainer.Env("AWS_CONTAINER_AUTHORIZATION_TOKEN"); authorizationToken != "" {
+			header = http.Header{"Authorization": []string{authorizationToken}}
+		}
+		return a.getAWSMetadataCredentials(ctx, fullURI, header)
+	}
+	if strings.EqualFold(envContainer.Env("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, nil
+	}
+	return a.getAWSEC2MetadataCredentials(ctx, envContainer)
+}
+
+// getAWSSharedCredentials gets the credentials of the profile from the
+// shared credentials file.
+//
+// Returns nil if there is no shared credentials file or the profile is not
+// in the file.
+func getAWSSharedCredentials(envContainer app.EnvContainer) (_ *awsCredentials, retErr error) {
+	filePath := envContainer.Env("AWS_SHARED_CREDENTIALS_FILE")
+	if filePath == "" {
+		homeDirPath, err := app.HomeDirPath(envContainer)
+		if err != nil {
+			return nil, nil
+		}
+		filePath = filepath.Join(homeDirPath, ".aws", "credentials")
+	}
+	profile := envContainer.Env("AWS_PROFILE")
+	if profile == "" {
+		profile = awsDefaultProfile
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	var credentials *awsCredentials
+	inProfile := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			if inProfile && credentials == nil {
+				credentials = &awsCredentials{}
+			}
+			continue
+		}
+		if !inProfile {
+			continue
+		}
+		split := strings.SplitN(line, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(split[1])
+		switch strings.ToLower(strings.TrimSpace(split[0])) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.Token = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return nil, nil
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%s: profile %s must set both aws_access_key_id and aws_secret_access_key", filePath, profile)
+	}
+	return credentials, nil
+}
+
+// getAWSEC2MetadataCredentials gets the credentials of the instance role from
+// the EC2 instance metadata service.
+//
+// Returns nil if the instance metadata service is not available.
+func (a *awsAuthenticator) getAWSEC2MetadataCredentials(ctx context.Context, envContainer app.EnvContainer) (*awsCredentials, error) {
+	endpoint := envContainer.Env("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = awsEC2MetadataDefaultEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	ctx, cancel := context.WithTimeout(ctx, awsMetadataTimeout)
+	defer cancel()
+	tokenRequest, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	tokenRequest.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := a.doMetadataRequest(tokenRequest)
+	if err != nil {
+		// not running on EC2
+		return nil, nil
+	}
+	header := http.Header{"X-aws-ec2-metadata-token": []string{string(token)}}
+	roleRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/latest/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return nil, err
+	}
+	roleRequest.Header = header
+	role, err := a.doMetadataRequest(roleRequest)
+	if err != nil {
+		// no instance role
+		return nil, nil
+	}
+	return a.getAWSMetadataCredentials(
+		ctx,
+		endpoint+"/latest/meta-data/iam/security-credentials/"+strings.TrimSpace(string(role)),
+		header,
+	)
+}
+
+// getAWSMetadataCredentials gets the credentials from a container credentials
+// or instance metadata endpoint, which both return the credentials as JSON.
+func (a *awsAuthenticator) getAWSMetadataCredentials(ctx context.Context, credentialsURL string, header http.Header) (*awsCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, awsMetadataTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, credentialsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		request.Header = header
+	}
+	data, err := a.doMetadataRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not get AWS credentials: %v", err)
+	}
+	credentials := &awsCredentials{}
+	if err := json.Unmarshal(data, credentials); err != nil {
+		return nil, fmt.Errorf("could not get AWS credentials: %v", err)
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("could not get AWS credentials: no credentials returned from %s", credentialsURL)
+	}
+	return credentials, nil
+}
+
+func (a *awsAuthenticator) doMetadataRequest(request *http.Request) (_ []byte, retErr error) {
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d", response.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(response.Body, 1<<20))
+}
+
+// signAWSRequest signs the request with AWS Signature Version 4.
+//
+// All headers set on the request are signed, so headers that may be changed
+// by the transport must be set after signing.
+//
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func signAWSRequest(
+	request *http.Request,
+	credentials *awsCredentials,
+	region string,
+	serviceName string,
+	now time.Time,
+) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], region, serviceName, "aws4_request"}, "/")
+	payloadHash := awsEmptyPayloadHash
+	if request.Body != nil && request.Body != http.NoBody {
+		payloadHash = awsUnsignedPayload
+	}
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.Token != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.Token)
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+	headerNameToValue := map[string]string{
+		"host": host,
+	}
+	for key, values := range request.Header {
+		trimmedValues := make([]string, len(values))
+		for i, value := range values {
+			trimmedValues[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headerNameToValue[strings.ToLower(key)] = strings.Join(trimmedValues, ",")
+	}
+	headerNames := make([]string, 0, len(headerNameToValue))
+	for headerName := range headerNameToValue {
+		headerNames = append(headerNames, headerName)
+	}
+	sort.Strings(headerNames)
+	canonicalHeaders := &strings.Builder{}
+	for _, headerName := range headerNames {
+		_, _ = canonicalHeaders.WriteString(headerName + ":" + headerNameToValue[headerName] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	// the path sent must be encoded the same as the signed path
+	canonicalURI := awsURIEncode(request.URL.Path, false)
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	request.URL.RawPath = canonicalURI
+
+	canonicalRequest := strings.Join(
+		[]string{
+			request.Method,
+			canonicalURI,
+			getAWSCanonicalQueryString(request.URL.Query()),
+			canonicalHeaders.String(),
+			signedHeaders,
+			payloadHash,
+		},
+		"\n",
+	)
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join(
+		[]string{
+			awsSigningAlgorithm,
+			amzDate,
+			scope,
+			hex.EncodeToString(canonicalRequestHash[:]),
+		},
+		"\n",
+	)
+	signingKey := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), amzDate[:8])
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, serviceName)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	request.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			awsSigningAlgorithm,
+			credentials.AccessKeyID,
+			scope,
+			signedHeaders,
+			signature,
+		),
+	)
+}
+
+func getAWSCanonicalQueryString(values url.Values) string {
+	pairs := make([]string, 0, len(values))
+	for key, keyValues := range values {
+		for _, value := range keyValues {
+			pairs = append(pairs, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode encodes every byte except the unreserved characters, as
+// required for signing.
+func awsURIEncode(s string, encodeSlash bool) string {
+	builder := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			_ = builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			_ = builder.WriteByte(c)
+		default:
+			_, _ = fmt.Fprintf(builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+	_, _ = hash.Write([]byte(data))
+	return hash.Sum(nil)
+}
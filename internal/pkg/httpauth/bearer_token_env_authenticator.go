@@ -0,0 +1,62 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+)
+
+type bearerTokenEnvAuthenticator struct {
+	tokenKey string
+	hostsKey string
+}
+
+func newBearerTokenEnvAuthenticator(tokenKey string, hostsKey string) *bearerTokenEnvAuthenticator {
+	return &bearerTokenEnvAuthenticator{
+		tokenKey: tokenKey,
+		hostsKey: hostsKey,
+	}
+}
+
+func (a *bearerTokenEnvAuthenticator) SetAuth(envContainer app.EnvContainer, request *http.Request) (bool, error) {
+	if request.URL == nil {
+		return false, errors.New("malformed request: no url")
+	}
+	if request.URL.Scheme != "https" {
+		return false, nil
+	}
+	if !a.isHostConfigured(envContainer, request.URL.Host) {
+		return false, nil
+	}
+	token := envContainer.Env(a.tokenKey)
+	if token == "" {
+		return false, nil
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	return true, nil
+}
+
+func (a *bearerTokenEnvAuthenticator) isHostConfigured(envContainer app.EnvContainer, host string) bool {
+	for _, configuredHost := range strings.Split(envContainer.Env(a.hostsKey), ",") {
+		if host != "" && strings.TrimSpace(configuredHost) == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,367 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/multierr"
+)
+
+const (
+	googleStorageReadOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+	googleDefaultTokenURL      = "https://oauth2.googleapis.com/token"
+	googleJWTBearerGrantType   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	googleMetadataDefaultHost  = "metadata.google.internal"
+	// the metadata server is only reachable from within Google Cloud, and
+	// requests to it otherwise hang until the connection times out
+	googleMetadataTimeout = time.Second
+	// tokens are refreshed this long before they expire so that they do
+	// not expire while in use
+	googleTokenExpiryDelta = time.Minute
+
+	googleCredentialsTypeServiceAccount = "service_account"
+	googleCredentialsTypeAuthorizedUser = "authorized_user"
+)
+
+type googleAuthenticator struct {
+	httpClient *http.Client
+	now        func() time.Time
+
+	// cached tokens by the source of the credentials
+	sourceToToken map[string]*googleToken
+	lock          sync.Mutex
+}
+
+func newGoogleAuthenticator(httpClient *http.Client) *googleAuthenticator {
+	return &googleAuthenticator{
+		httpClient:    httpClient,
+		now:           time.Now,
+		sourceToToken: make(map[string]*googleToken),
+	}
+}
+
+func (a *googleAuthenticator) SetAuth(envContainer app.EnvContainer, request *http.Request) (bool, error) {
+	accessToken, err := a.getAccessToken(request.Context(), envContainer)
+	if err != nil {
+		return false, err
+	}
+	if accessToken == "" {
+		return false, nil
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	return true, nil
+}
+
+type googleToken struct {
+	accessToken string
+	expiry      time.Time
+}
+
+type googleCredentials struct {
+	Type string `json:"type,omitempty"`
+	// service_account
+	ClientEmail  string `json:"client_email,omitempty"`
+	PrivateKeyID string `json:"private_key_id,omitempty"`
+	PrivateKey   string `json:"private_key,omitempty"`
+	TokenURI     string `json:"token_uri,omitempty"`
+	// authorized_user
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type googleTokenResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// getAccessToken gets an access token with the credentials file at
+// GOOGLE_APPLICATION_CREDENTIALS, the gcloud application default credentials
+// file, and the GCE metadata server, in that order.
+//
+// Returns empty if there are no credentials.
+func (a *googleAuthenticator) getAccessToken(ctx context.Context, envContainer app.EnvContainer) (string, error) {
+	credentialsFilePath := envContainer.Env("GOOGLE_APPLICATION_CREDENTIALS")
+	if credentialsFilePath == "" {
+		credentialsFilePath = getGoogleWellKnownCredentialsFilePath(envContainer)
+		if credentialsFilePath != "" {
+			if _, err := os.Stat(credentialsFilePath); err != nil {
+				credentialsFilePath = ""
+			}
+		}
+	}
+	if credentialsFilePath != "" {
+		return a.getCachedAccessToken(credentialsFilePath, func() (*googleToken, error) {
+			return a.getCredentialsFileToken(ctx, credentialsFilePath)
+		})
+	}
+	metadataHost := envContainer.Env("GCE_METADATA_HOST")
+	if metadataHost == "" {
+		metadataHost = googleMetadataDefaultHost
+	}
+	return a.getCachedAccessToken(metadataHost, func() (*googleToken, error) {
+		return a.getMetadataToken(ctx, metadataHost)
+	})
+}
+
+// getCachedAccessToken returns the cached access token for the source if it
+// has not expired, otherwise it gets a new token with getToken.
+//
+// getToken returns nil if there are no credentials.
+func (a *googleAuthenticator) getCachedAccessToken(source string, getToken func() (*googleToken, error)) (string, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if token, ok := a.sourceToToken[source]; ok && a.now().Add(googleTokenExpiryDelta).Before(token.expiry) {
+		return token.accessToken, nil
+	}
+	token, err := getToken()
+	if err != nil {
+		return "", err
+	}
+	if token == nil {
+		return "", nil
+	}
+	a.sourceToToken[source] = token
+	return token.accessToken, nil
+}
+
+func (a *googleAuthenticator) getCredentialsFileToken(ctx context.Context, credentialsFilePath string) (*googleToken, error) {
+	data, err := ioutil.ReadFile(credentialsFilePath)
+	if err != nil {
+		return nil, err
+	}
+	credentials := &googleCredentials{}
+	if err := json.Unmarshal(data, credentials); err != nil {
+		return nil, fmt.Errorf("could not parse Google credentials file %s: %v", credentialsFilePath, err)
+	}
+	var token *googleToken
+	switch credentials.Type {
+	case googleCredentialsTypeServiceAccount:
+		token, err = a.getServiceAccountToken(ctx, credentials)
+	case googleCredentialsTypeAuthorizedUser:
+		token, err = a.getAuthorizedUserToken(ctx, credentials)
+	default:
+		return nil, fmt.Errorf("unsupported Google credentials type %q in %s", credentials.Type, credentialsFilePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get Google access token with %s: %v", credentialsFilePath, err)
+	}
+	return token, nil
+}
+
+// getServiceAccountToken exchanges a JWT signed with the private key of the
+// service account for an access token.
+//
+// https://developers.google.com/identity/protocols/oauth2/service-account#httprest
+func (a *googleAuthenticator) getServiceAccountToken(ctx context.Context, credentials *googleCredentials) (*googleToken, error) {
+	if credentials.ClientEmail == "" || credentials.PrivateKey == "" {
+		return nil, errors.New("service account credentials must have client_email and private_key")
+	}
+	tokenURL := credentials.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleDefaultTokenURL
+	}
+	privateKey, err := parseGoogleRSAPrivateKey(credentials.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	now := a.now()
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	}
+	if credentials.PrivateKeyID != "" {
+		header["kid"] = credentials.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   credentials.ClientEmail,
+		"scope": googleStorageReadOnlyScope,
+		"aud":   tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	assertion, err := signGoogleJWT(privateKey, header, claims)
+	if err != nil {
+		return nil, err
+	}
+	return a.postTokenRequest(
+		ctx,
+		tokenURL,
+		url.Values{
+			"grant_type": []string{googleJWTBearerGrantType},
+			"assertion":  []string{assertion},
+		},
+	)
+}
+
+// getAuthorizedUserToken exchanges the refresh token of the user for an
+// access token.
+func (a *googleAuthenticator) getAuthorizedUserToken(ctx context.Context, credentials *googleCredentials) (*googleToken, error) {
+	if credentials.ClientID == "" || credentials.ClientSecret == "" || credentials.RefreshToken == "" {
+		return nil, errors.New("authorized user credentials must have client_id, client_secret, and refresh_token")
+	}
+	return a.postTokenRequest(
+		ctx,
+		googleDefaultTokenURL,
+		url.Values{
+			"grant_type":    []string{"refresh_token"},
+			"client_id":     []string{credentials.ClientID},
+			"client_secret": []string{credentials.ClientSecret},
+			"refresh_token": []string{credentials.RefreshToken},
+		},
+	)
+}
+
+func (a *googleAuthenticator) postTokenRequest(ctx context.Context, tokenURL string, values url.Values) (*googleToken, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return a.doTokenRequest(request)
+}
+
+// getMetadataToken gets an access token for the default service account from
+// the GCE metadata server.
+//
+// Returns nil if the metadata server is not available.
+func (a *googleAuthenticator) getMetadataToken(ctx context.Context, metadataHost string) (*googleToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, googleMetadataTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		"http://"+metadataHost+"/computeMetadata/v1/instance/service-accounts/default/token",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	token, err := a.doTokenRequest(request)
+	if err != nil {
+		// not running on Google Cloud
+		return nil, nil
+	}
+	return token, nil
+}
+
+func (a *googleAuthenticator) doTokenRequest(request *http.Request) (_ *googleToken, retErr error) {
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, response.Body.Close())
+	}()
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got HTTP status code %d: %s", response.StatusCode, strings.TrimSpace(string(data)))
+	}
+	tokenResponse := &googleTokenResponse{}
+	if err := json.Unmarshal(data, tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, errors.New("no access token returned")
+	}
+	return &googleToken{
+		accessToken: tokenResponse.AccessToken,
+		expiry:      a.now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// getGoogleWellKnownCredentialsFilePath returns the path of the application
+// default credentials file written by gcloud.
+//
+// Returns empty if the path cannot be determined.
+func getGoogleWellKnownCredentialsFilePath(envContainer app.EnvContainer) string {
+	configDirPath := envContainer.Env("CLOUDSDK_CONFIG")
+	if configDirPath == "" {
+		if runtime.GOOS == "windows" {
+			appDataDirPath, err := app.ConfigDirPath(envContainer)
+			if err != nil {
+				return ""
+			}
+			configDirPath = filepath.Join(appDataDirPath, "gcloud")
+		} else {
+			// gcloud does not respect XDG_CONFIG_HOME
+			homeDirPath, err := app.HomeDirPath(envContainer)
+			if err != nil {
+				return ""
+			}
+			configDirPath = filepath.Join(homeDirPath, ".config", "gcloud")
+		}
+	}
+	return filepath.Join(configDirPath, "application_default_credentials.json")
+}
+
+func parseGoogleRSAPrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("private_key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private_key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func signGoogleJWT(privateKey *rsa.PrivateKey, header map[string]string, claims map[string]interface{}) (string, error) {
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerData) + "." + base64.RawURLEncoding.EncodeToString(claimsData)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
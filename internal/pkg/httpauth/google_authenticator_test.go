@@ -0,0 +1,90 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleServiceAccount(t *testing.T) {
+	t.Parallel()
+	var tokenRequestCount int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.FormValue("grant_type") != googleJWTBearerGrantType || request.FormValue("assertion") == "" {
+					responseWriter.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				atomic.AddInt32(&tokenRequestCount, 1)
+				_, _ = responseWriter.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKeyData, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	credentialsData, err := json.Marshal(
+		&googleCredentials{
+			Type:        googleCredentialsTypeServiceAccount,
+			ClientEmail: "test@test.iam.gserviceaccount.com",
+			PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyData})),
+			TokenURI:    server.URL,
+		},
+	)
+	require.NoError(t, err)
+	tmpDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDirPath))
+	}()
+	credentialsFilePath := filepath.Join(tmpDirPath, "credentials.json")
+	require.NoError(t, ioutil.WriteFile(credentialsFilePath, credentialsData, 0600))
+
+	envContainer := app.NewEnvContainer(
+		map[string]string{
+			"GOOGLE_APPLICATION_CREDENTIALS": credentialsFilePath,
+		},
+	)
+	authenticator := NewGoogleAuthenticator(server.Client())
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest(http.MethodGet, "https://storage.googleapis.com/storage/v1/b/bucket/o/object", nil)
+		require.NoError(t, err)
+		ok, err := authenticator.SetAuth(envContainer, request)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "Bearer token", request.Header.Get("Authorization"))
+	}
+	// the token is cached until it expires
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenRequestCount))
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+)
+
+type urlAuthenticator struct{}
+
+func newURLAuthenticator() *urlAuthenticator {
+	return &urlAuthenticator{}
+}
+
+func (a *urlAuthenticator) SetAuth(envContainer app.EnvContainer, request *http.Request) (bool, error) {
+	if request.URL == nil {
+		return false, errors.New("malformed request: no url")
+	}
+	if request.URL.User == nil {
+		return false, nil
+	}
+	username := request.URL.User.Username()
+	password, _ := request.URL.User.Password()
+	// the credentials are removed from the URL either way, so that they are
+	// never sent over plain http and do not end up in logs and errors
+	request.URL.User = nil
+	return setBasicAuth(
+		request,
+		username,
+		password,
+		"username in url",
+		"password in url",
+	)
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Span is the span of a Timer, from when it was started to when it ended.
+type Span struct {
+	// Name is the name of the logger and the message of the Timer,
+	// separated by a period, such as bufbuild.build.
+	Name string
+	// Start is when the Timer was started.
+	Start time.Time
+	// End is when the Timer ended.
+	End time.Time
+	// Attributes are the fields of the Timer, other than the duration.
+	Attributes map[string]interface{}
+}
+
+// Duration returns the duration of the Span.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Hook is called with the Span of every ended Timer.
+//
+// Hooks must be safe for concurrent use, as Timers can end concurrently.
+type Hook interface {
+	OnSpan(span Span)
+}
+
+// HookFunc is a function that is a Hook.
+type HookFunc func(Span)
+
+// OnSpan implements Hook.
+func (f HookFunc) OnSpan(span Span) {
+	f(span)
+}
+
+// NewHookCore returns a new zapcore.Core that calls the Hook with the Span of
+// every Timer logged to it. The Core does not write anything.
+func NewHookCore(hook Hook) zapcore.Core {
+	return newHookCore(hook, nil)
+}
+
+type hookCore struct {
+	hook   Hook
+	fields []zapcore.Field
+}
+
+func newHookCore(hook Hook, fields []zapcore.Field) *hookCore {
+	return &hookCore{
+		hook:   hook,
+		fields: fields,
+	}
+}
+
+func (h *hookCore) Enabled(level zapcore.Level) bool {
+	return level == zapcore.DebugLevel
+}
+
+func (h *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return newHookCore(h.hook, append(append([]zapcore.Field{}, h.fields...), fields...))
+}
+
+func (h *hookCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if h.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, h)
+	}
+	return checkedEntry
+}
+
+func (h *hookCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	// only entries with a duration are from Timers
+	var duration time.Duration
+	var ok bool
+	for _, field := range fields {
+		if field.Key == durationKey && field.Type == zapcore.DurationType {
+			duration = time.Duration(field.Integer)
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return nil
+	}
+	name := entry.Message
+	if entry.LoggerName != "" {
+		name = entry.LoggerName + "." + name
+	}
+	mapObjectEncoder := zapcore.NewMapObjectEncoder()
+	for _, field := range h.fields {
+		field.AddTo(mapObjectEncoder)
+	}
+	for _, field := range fields {
+		if field.Key != durationKey {
+			field.AddTo(mapObjectEncoder)
+		}
+	}
+	h.hook.OnSpan(
+		Span{
+			Name:       name,
+			Start:      entry.Time.Add(-duration),
+			End:        entry.Time,
+			Attributes: mapObjectEncoder.Fields,
+		},
+	)
+	return nil
+}
+
+func (*hookCore) Sync() error {
+	return nil
+}
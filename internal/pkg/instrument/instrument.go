@@ -16,12 +16,16 @@
 package instrument
 
 import (
+	"io"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// durationKey is the key of the duration field logged by Timers.
+const durationKey = "duration"
+
 // Timer logs a duration to a logger.
 type Timer interface {
 	End(...zap.Field)
@@ -35,6 +39,21 @@ func Start(logger *zap.Logger, message string, fields ...zap.Field) Timer {
 	return nopTimer{}
 }
 
+// Timings records the durations logged by Timers.
+type Timings interface {
+	// Core returns a zapcore.Core that records the duration of every Timer
+	// logged to it, by logger name and message. The Core does not write anything.
+	Core() zapcore.Core
+	// Print prints a table of the count and total duration of each Timer
+	// to the writer, with the longest total duration first.
+	Print(writer io.Writer) error
+}
+
+// NewTimings returns a new Timings.
+func NewTimings() Timings {
+	return newTimings()
+}
+
 type timer struct {
 	checkedEntry *zapcore.CheckedEntry
 	fields       []zap.Field
@@ -55,7 +74,7 @@ func (t *timer) End(extraFields ...zap.Field) {
 			t.fields,
 			append(
 				extraFields,
-				zap.Duration("duration", time.Since(t.start)),
+				zap.Duration(durationKey, time.Since(t.start)),
 			)...,
 		)...,
 	)
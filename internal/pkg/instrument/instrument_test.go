@@ -0,0 +1,119 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/zaputil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	logger := zap.New(
+		zapcore.NewTee(
+			zapcore.NewCore(zaputil.NewTextEncoder(), zapcore.AddSync(buffer), zapcore.DebugLevel),
+			NewProgressCore(zaputil.NewTextEncoder(), zapcore.AddSync(buffer)),
+		),
+	)
+	Progress(logger, "compiling", zap.Int("num_files", 2))
+	logger.Debug("debug")
+	require.Equal(
+		t,
+		"INFO\tcompiling\t{\"num_files\": 2}\nDEBUG\tdebug\n",
+		buffer.String(),
+	)
+	buffer.Reset()
+	// progress is not written without a progress core, even at debug level
+	Progress(zaputil.NewLogger(buffer, zapcore.DebugLevel, zaputil.NewTextEncoder()), "compiling")
+	require.Empty(t, buffer.String())
+}
+
+func TestTimings(t *testing.T) {
+	t.Parallel()
+	timings := NewTimings()
+	logger := zap.New(timings.Core()).Named("test")
+	Start(logger, "one").End()
+	Start(logger, "two").End()
+	Start(logger, "two").End()
+	// only entries with a duration are recorded
+	logger.Debug("three")
+	Progress(logger, "four")
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, timings.Print(buffer))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"NAME", "COUNT", "TOTAL"}, strings.Fields(lines[0]))
+	nameToCount := make(map[string]string)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		require.Len(t, fields, 3)
+		nameToCount[fields[0]] = fields[1]
+	}
+	require.Equal(t, map[string]string{"test.one": "1", "test.two": "2"}, nameToCount)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	metrics := NewMetrics()
+	var hookSpans []Span
+	logger := zap.New(
+		zapcore.NewTee(
+			NewHookCore(metrics),
+			NewHookCore(HookFunc(func(span Span) { hookSpans = append(hookSpans, span) })),
+		),
+	).Named("test")
+	Start(logger, "one", zap.String("checker", "FOO")).End(zap.Int("num_failures", 2))
+	// only entries with a duration are spans
+	logger.Debug("two")
+	require.Len(t, hookSpans, 1)
+	require.Equal(t, "test.one", hookSpans[0].Name)
+	require.Equal(t, map[string]interface{}{"checker": "FOO", "num_failures": int64(2)}, hookSpans[0].Attributes)
+	require.False(t, hookSpans[0].End.Before(hookSpans[0].Start))
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, metrics.Print(buffer, MetricsFormatJSON, "test"))
+	var externalSpan map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &externalSpan))
+	require.Equal(t, "test.one", externalSpan["name"])
+	require.Equal(t, map[string]interface{}{"checker": "FOO", "num_failures": float64(2)}, externalSpan["attributes"])
+
+	buffer.Reset()
+	require.NoError(t, metrics.Print(buffer, MetricsFormatOTLP, "test"))
+	var request externalOTLPRequest
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &request))
+	require.Len(t, request.ResourceSpans, 1)
+	require.Len(t, request.ResourceSpans[0].ScopeSpans, 1)
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	require.Equal(t, "test.one", spans[0].Name)
+	require.Len(t, spans[0].TraceID, 32)
+	require.Len(t, spans[0].SpanID, 16)
+	require.Len(t, spans[0].Attributes, 2)
+	require.Equal(t, "checker", spans[0].Attributes[0].Key)
+	require.Equal(t, "FOO", *spans[0].Attributes[0].Value.StringValue)
+	require.Equal(t, "num_failures", spans[0].Attributes[1].Key)
+	require.Equal(t, "2", *spans[0].Attributes[1].Value.IntValue)
+
+	_, err := ParseMetricsFormat("foo")
+	require.Error(t, err)
+}
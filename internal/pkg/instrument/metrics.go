@@ -0,0 +1,316 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetricsFormatJSON is the JSON metrics format.
+	//
+	// Each Span is printed as a JSON object on its own line.
+	MetricsFormatJSON MetricsFormat = iota + 1
+	// MetricsFormatOTLP is the OpenTelemetry metrics format.
+	//
+	// The Spans are printed as a single OTLP/JSON trace export request on one
+	// line, which can be read by the OpenTelemetry Collector.
+	MetricsFormatOTLP
+)
+
+var (
+	// AllMetricsFormatStrings are all metrics format strings.
+	AllMetricsFormatStrings = []string{
+		"json",
+		"otlp",
+	}
+
+	metricsFormatToString = map[MetricsFormat]string{
+		MetricsFormatJSON: "json",
+		MetricsFormatOTLP: "otlp",
+	}
+	stringToMetricsFormat = map[string]MetricsFormat{
+		"json": MetricsFormatJSON,
+		"otlp": MetricsFormatOTLP,
+	}
+)
+
+// MetricsFormat is a metrics format.
+type MetricsFormat int
+
+// String implements fmt.Stringer.
+func (m MetricsFormat) String() string {
+	s, ok := metricsFormatToString[m]
+	if !ok {
+		return strconv.Itoa(int(m))
+	}
+	return s
+}
+
+// ParseMetricsFormat parses the MetricsFormat.
+//
+// The empty string defaults to MetricsFormatJSON.
+func ParseMetricsFormat(s string) (MetricsFormat, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return MetricsFormatJSON, nil
+	}
+	m, ok := stringToMetricsFormat[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown metrics format: %q", s)
+	}
+	return m, nil
+}
+
+// Metrics records the Spans of Timers.
+type Metrics interface {
+	Hook
+	// Print prints the recorded Spans to the writer, in the order they started.
+	//
+	// serviceName is the name of the service for MetricsFormatOTLP.
+	Print(writer io.Writer, format MetricsFormat, serviceName string) error
+}
+
+// NewMetrics returns a new Metrics.
+func NewMetrics() Metrics {
+	return newMetrics()
+}
+
+type metrics struct {
+	lock  sync.Mutex
+	spans []Span
+}
+
+func newMetrics() *metrics {
+	return &metrics{}
+}
+
+func (m *metrics) OnSpan(span Span) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.spans = append(m.spans, span)
+}
+
+func (m *metrics) Print(writer io.Writer, format MetricsFormat, serviceName string) error {
+	m.lock.Lock()
+	spans := make([]Span, len(m.spans))
+	copy(spans, m.spans)
+	m.lock.Unlock()
+	sort.SliceStable(
+		spans,
+		func(i int, j int) bool {
+			return spans[i].Start.Before(spans[j].Start)
+		},
+	)
+	switch format {
+	case MetricsFormatJSON:
+		return printMetricsJSON(writer, spans)
+	case MetricsFormatOTLP:
+		return printMetricsOTLP(writer, spans, serviceName)
+	default:
+		return fmt.Errorf("unknown metrics format: %v", format)
+	}
+}
+
+func printMetricsJSON(writer io.Writer, spans []Span) error {
+	for _, span := range spans {
+		data, err := json.Marshal(
+			externalSpan{
+				Name:       span.Name,
+				StartTime:  span.Start.UTC().Format(time.RFC3339Nano),
+				DurationNS: span.Duration().Nanoseconds(),
+				Attributes: span.Attributes,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printMetricsOTLP(writer io.Writer, spans []Span, serviceName string) error {
+	// all Spans are from the same invocation, so they share a trace
+	traceID, err := newOTLPID(16)
+	if err != nil {
+		return err
+	}
+	otlpSpans := make([]externalOTLPSpan, len(spans))
+	for i, span := range spans {
+		spanID, err := newOTLPID(8)
+		if err != nil {
+			return err
+		}
+		otlpSpans[i] = externalOTLPSpan{
+			TraceID:           traceID,
+			SpanID:            spanID,
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        newExternalOTLPAttributes(span.Attributes),
+		}
+	}
+	data, err := json.Marshal(
+		externalOTLPRequest{
+			ResourceSpans: []externalOTLPResourceSpans{
+				{
+					Resource: externalOTLPResource{
+						Attributes: newExternalOTLPAttributes(
+							map[string]interface{}{
+								"service.name": serviceName,
+							},
+						),
+					},
+					ScopeSpans: []externalOTLPScopeSpans{
+						{
+							Scope: externalOTLPScope{
+								Name: serviceName,
+							},
+							Spans: otlpSpans,
+						},
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL.
+const otlpSpanKindInternal = 1
+
+// newOTLPID returns a new random trace or span ID of the given number of
+// bytes, hex-encoded as OTLP/JSON requires.
+func newOTLPID(numBytes int) (string, error) {
+	data := make([]byte, numBytes)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+func newExternalOTLPAttributes(attributes map[string]interface{}) []externalOTLPAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	otlpAttributes := make([]externalOTLPAttribute, len(keys))
+	for i, key := range keys {
+		otlpAttributes[i] = externalOTLPAttribute{
+			Key:   key,
+			Value: newExternalOTLPValue(attributes[key]),
+		}
+	}
+	return otlpAttributes
+}
+
+func newExternalOTLPValue(value interface{}) externalOTLPValue {
+	// 64-bit integers are strings in OTLP/JSON
+	switch t := value.(type) {
+	case string:
+		return externalOTLPValue{StringValue: &t}
+	case bool:
+		return externalOTLPValue{BoolValue: &t}
+	case int:
+		s := strconv.FormatInt(int64(t), 10)
+		return externalOTLPValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(t, 10)
+		return externalOTLPValue{IntValue: &s}
+	case int32:
+		s := strconv.FormatInt(int64(t), 10)
+		return externalOTLPValue{IntValue: &s}
+	case uint64:
+		s := strconv.FormatUint(t, 10)
+		return externalOTLPValue{IntValue: &s}
+	case time.Duration:
+		s := strconv.FormatInt(t.Nanoseconds(), 10)
+		return externalOTLPValue{IntValue: &s}
+	case float64:
+		return externalOTLPValue{DoubleValue: &t}
+	default:
+		s := fmt.Sprint(t)
+		return externalOTLPValue{StringValue: &s}
+	}
+}
+
+type externalSpan struct {
+	Name       string                 `json:"name"`
+	StartTime  string                 `json:"start_time"`
+	DurationNS int64                  `json:"duration_ns"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type externalOTLPRequest struct {
+	ResourceSpans []externalOTLPResourceSpans `json:"resourceSpans"`
+}
+
+type externalOTLPResourceSpans struct {
+	Resource   externalOTLPResource     `json:"resource"`
+	ScopeSpans []externalOTLPScopeSpans `json:"scopeSpans"`
+}
+
+type externalOTLPResource struct {
+	Attributes []externalOTLPAttribute `json:"attributes"`
+}
+
+type externalOTLPScopeSpans struct {
+	Scope externalOTLPScope  `json:"scope"`
+	Spans []externalOTLPSpan `json:"spans"`
+}
+
+type externalOTLPScope struct {
+	Name string `json:"name"`
+}
+
+type externalOTLPSpan struct {
+	TraceID           string                  `json:"traceId"`
+	SpanID            string                  `json:"spanId"`
+	Name              string                  `json:"name"`
+	Kind              int                     `json:"kind"`
+	StartTimeUnixNano string                  `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string                  `json:"endTimeUnixNano"`
+	Attributes        []externalOTLPAttribute `json:"attributes,omitempty"`
+}
+
+type externalOTLPAttribute struct {
+	Key   string            `json:"key"`
+	Value externalOTLPValue `json:"value"`
+}
+
+type externalOTLPValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
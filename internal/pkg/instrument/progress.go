@@ -0,0 +1,77 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ProgressLevel is the level that progress is logged at.
+//
+// This is below zapcore.DebugLevel, so that progress is only written by
+// cores returned from NewProgressCore, even when debug logging is enabled.
+const ProgressLevel = zapcore.DebugLevel - 1
+
+// Progress logs the progress of a long-running operation, such as
+// fetching, compiling, or checking.
+func Progress(logger *zap.Logger, message string, fields ...zap.Field) {
+	if checkedEntry := logger.Check(ProgressLevel, message); checkedEntry != nil {
+		checkedEntry.Write(fields...)
+	}
+}
+
+// NewProgressCore returns a new zapcore.Core that only writes progress.
+//
+// Progress is written at info level.
+func NewProgressCore(encoder zapcore.Encoder, writeSyncer zapcore.WriteSyncer) zapcore.Core {
+	return newProgressCore(
+		zapcore.NewCore(
+			encoder,
+			writeSyncer,
+			zap.LevelEnablerFunc(
+				func(level zapcore.Level) bool {
+					return level == ProgressLevel
+				},
+			),
+		),
+	)
+}
+
+type progressCore struct {
+	zapcore.Core
+}
+
+func newProgressCore(core zapcore.Core) *progressCore {
+	return &progressCore{
+		Core: core,
+	}
+}
+
+func (p *progressCore) With(fields []zapcore.Field) zapcore.Core {
+	return newProgressCore(p.Core.With(fields))
+}
+
+func (p *progressCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if p.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, p)
+	}
+	return checkedEntry
+}
+
+func (p *progressCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Level = zapcore.InfoLevel
+	return p.Core.Write(entry, fields)
+}
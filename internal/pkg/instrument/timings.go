@@ -0,0 +1,97 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instrument
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+type timings struct {
+	lock           sync.Mutex
+	nameToTiming   map[string]*timing
+	orderedTimings []*timing
+}
+
+type timing struct {
+	name  string
+	count int
+	total time.Duration
+}
+
+func newTimings() *timings {
+	return &timings{
+		nameToTiming: make(map[string]*timing),
+	}
+}
+
+func (t *timings) Core() zapcore.Core {
+	return NewHookCore(HookFunc(t.record))
+}
+
+func (t *timings) Print(writer io.Writer) (retErr error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	sortedTimings := make([]*timing, len(t.orderedTimings))
+	copy(sortedTimings, t.orderedTimings)
+	// the longest first, and otherwise in the order they were first recorded
+	sort.SliceStable(
+		sortedTimings,
+		func(i int, j int) bool {
+			return sortedTimings[i].total > sortedTimings[j].total
+		},
+	)
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = multierr.Append(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, "NAME\tCOUNT\tTOTAL"); err != nil {
+		return err
+	}
+	for _, timing := range sortedTimings {
+		if _, err := fmt.Fprintf(
+			tabWriter,
+			"%s\t%d\t%v\n",
+			timing.name,
+			timing.count,
+			timing.total.Round(time.Microsecond),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *timings) record(span Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	timingForName, ok := t.nameToTiming[span.Name]
+	if !ok {
+		timingForName = &timing{
+			name: span.Name,
+		}
+		t.nameToTiming[span.Name] = timingForName
+		t.orderedTimings = append(t.orderedTimings, timingForName)
+	}
+	timingForName.count++
+	timingForName.total += span.Duration()
+}
@@ -25,7 +25,12 @@ func WithCancel(ctx context.Context) (context.Context, context.CancelFunc) {
 	signalC, closer := NewSignalChannel()
 	ctx, cancel := context.WithCancel(ctx)
 	go func() {
-		<-signalC
+		// stop listening for signals after the first signal, so that a second
+		// signal terminates the process, or once the context is done
+		select {
+		case <-signalC:
+		case <-ctx.Done():
+		}
 		closer()
 		cancel()
 	}()
@@ -19,6 +19,8 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"go.uber.org/multierr"
@@ -43,6 +45,19 @@ func LockedWriter(writer io.Writer) io.Writer {
 	return &lockedWriter{writer: writer}
 }
 
+// UnwrapWriter returns the Writer wrapped by a Writer created with LockedWriter.
+//
+// Other Writers are returned as-is.
+func UnwrapWriter(writer io.Writer) io.Writer {
+	for {
+		lockedWriter, ok := writer.(*lockedWriter)
+		if !ok {
+			return writer
+		}
+		writer = lockedWriter.writer
+	}
+}
+
 // CompositeReadCloser returns a io.ReadCloser that is a composite of the Reader and Closer.
 func CompositeReadCloser(reader io.Reader, closer io.Closer) io.ReadCloser {
 	return compositeReadCloser{Reader: reader, Closer: closer}
@@ -70,6 +85,30 @@ func ReaderAtForReader(reader io.Reader) (io.ReaderAt, error) {
 	return bytes.NewReader(data), nil
 }
 
+// WriteFileAtomic writes the contents of the reader to a temporary file in the
+// directory of filePath and then renames it to filePath, so that readers never
+// see a partially-written file.
+//
+// The name of the temporary file starts with tempFilePrefix.
+func WriteFileAtomic(filePath string, reader io.Reader, tempFilePrefix string) (retErr error) {
+	file, err := ioutil.TempFile(filepath.Dir(filePath), tempFilePrefix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			retErr = multierr.Append(retErr, os.Remove(file.Name()))
+		}
+	}()
+	if _, err := io.Copy(file, reader); err != nil {
+		return multierr.Append(err, file.Close())
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filePath)
+}
+
 type discardReader struct{}
 
 func (discardReader) Read([]byte) (int, error) {
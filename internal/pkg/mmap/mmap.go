@@ -0,0 +1,130 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mmap provides read-only memory-mapped files.
+package mmap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrUnsupported is returned from Open if memory-mapping files is not
+// supported on the platform.
+var ErrUnsupported = errors.New("memory-mapping files is not supported on this platform")
+
+// ErrNotRegularFile is returned from Open if the file is not a regular file,
+// such as a pipe or a device, in which case the file should be read as usual.
+var ErrNotRegularFile = errors.New("not a regular file")
+
+// ReadCloser is a memory-mapped file.
+//
+// Reading from the ReadCloser reads from the mapped data, which is paged in
+// from the file as needed instead of being copied into the heap.
+//
+// Close unmaps the data and closes the file.
+type ReadCloser interface {
+	io.ReadCloser
+
+	// Bytes returns the mapped data.
+	//
+	// The data must not be modified, and must not be used after Close is
+	// called, including any slices of it. Copy what is still needed first.
+	Bytes() []byte
+}
+
+// Open memory-maps the file at the path for reading.
+//
+// If the file is modified while it is mapped, the contents of the mapped
+// data are undefined, and reading past the end of a truncated file may crash
+// the program.
+//
+// Returns ErrUnsupported if memory-mapping files is not supported on the
+// platform, in which case the file should be read as usual.
+//
+// Returns an error wrapping ErrNotRegularFile if the file is not a regular
+// file. The file is not opened in this case, as opening a pipe for reading
+// would consume what is written to it.
+func Open(path string) (ReadCloser, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotRegularFile)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err = file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	// the file may have been replaced since it was checked
+	if !fileInfo.Mode().IsRegular() {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: %w", path, ErrNotRegularFile)
+	}
+	size := fileInfo.Size()
+	// the size is only too large for an int on 32-bit platforms
+	if int64(int(size)) != size {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s: file of size %d is too large to be memory-mapped", path, size)
+	}
+	var data []byte
+	// an empty file cannot be mapped, and there is nothing to map
+	if size > 0 {
+		data, err = mapFile(file, int(size))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	return &readCloser{
+		Reader: bytes.NewReader(data),
+		file:   file,
+		data:   data,
+	}, nil
+}
+
+type readCloser struct {
+	*bytes.Reader
+
+	file *os.File
+	data []byte
+	once sync.Once
+	err  error
+}
+
+func (r *readCloser) Bytes() []byte {
+	return r.data
+}
+
+func (r *readCloser) Close() error {
+	r.once.Do(func() {
+		if r.data != nil {
+			r.err = unmapFile(r.data)
+		}
+		if err := r.file.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	})
+	return r.err
+}
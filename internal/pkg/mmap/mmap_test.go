@@ -0,0 +1,64 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	filePath := filepath.Join(tempDirPath, "foo")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("foo"), 0600))
+	emptyFilePath := filepath.Join(tempDirPath, "empty")
+	require.NoError(t, ioutil.WriteFile(emptyFilePath, nil, 0600))
+
+	readCloser, err := Open(filePath)
+	if err == ErrUnsupported {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), readCloser.Bytes())
+	data, err := ioutil.ReadAll(readCloser)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), data)
+	assert.NoError(t, readCloser.Close())
+	// closing again is a no-op
+	assert.NoError(t, readCloser.Close())
+
+	readCloser, err = Open(emptyFilePath)
+	require.NoError(t, err)
+	assert.Empty(t, readCloser.Bytes())
+	data, err = ioutil.ReadAll(readCloser)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	assert.NoError(t, readCloser.Close())
+
+	_, err = Open(filepath.Join(tempDirPath, "bar"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = Open(tempDirPath)
+	assert.True(t, errors.Is(err, ErrNotRegularFile))
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netlimit limits network operations.
+package netlimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits network operations.
+type Limiter interface {
+	// Acquire blocks until a network operation for the host can start.
+	//
+	// The returned function must be called when the operation completes.
+	// Returns an error if the context is done before the operation can start.
+	Acquire(ctx context.Context, host string) (func(), error)
+}
+
+// NewLimiter returns a new Limiter.
+//
+// By default, the Limiter does not limit network operations.
+func NewLimiter(options ...LimiterOption) Limiter {
+	return newLimiter(options...)
+}
+
+// LimiterOption is an option for a new Limiter.
+type LimiterOption func(*limiter)
+
+// LimiterWithMaxConcurrent returns a new LimiterOption that limits the number
+// of network operations that run at once across all hosts.
+//
+// If maxConcurrent is 0, the number of network operations is not limited.
+func LimiterWithMaxConcurrent(maxConcurrent int) LimiterOption {
+	return func(limiter *limiter) {
+		limiter.maxConcurrent = maxConcurrent
+	}
+}
+
+// LimiterWithHostRate returns a new LimiterOption that limits the number of
+// network operations started per second for each host.
+//
+// If hostRate is 0, the rate of network operations is not limited.
+func LimiterWithHostRate(hostRate float64) LimiterOption {
+	return func(limiter *limiter) {
+		limiter.hostRate = hostRate
+	}
+}
+
+type limiter struct {
+	maxConcurrent int
+	hostRate      float64
+
+	// nil if maxConcurrent is 0
+	semaphoreC chan struct{}
+	// 0 if hostRate is 0
+	hostInterval time.Duration
+	// the time the next operation can start for each host
+	hostToNextTime map[string]time.Time
+	lock           sync.Mutex
+}
+
+func newLimiter(options ...LimiterOption) *limiter {
+	limiter := &limiter{
+		hostToNextTime: make(map[string]time.Time),
+	}
+	for _, option := range options {
+		option(limiter)
+	}
+	if limiter.maxConcurrent > 0 {
+		limiter.semaphoreC = make(chan struct{}, limiter.maxConcurrent)
+	}
+	if limiter.hostRate > 0 {
+		limiter.hostInterval = time.Duration(float64(time.Second) / limiter.hostRate)
+	}
+	return limiter
+}
+
+func (l *limiter) Acquire(ctx context.Context, host string) (func(), error) {
+	if l.semaphoreC != nil {
+		select {
+		case l.semaphoreC <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.semaphoreC != nil {
+			<-l.semaphoreC
+		}
+	}
+	if wait := l.reserve(host); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// reserve reserves the next start time for the host and returns the
+// duration to wait until then.
+func (l *limiter) reserve(host string) time.Duration {
+	if l.hostInterval == 0 {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	startTime := l.hostToNextTime[host]
+	if startTime.Before(now) {
+		startTime = now
+	}
+	l.hostToNextTime[host] = startTime.Add(l.hostInterval)
+	return startTime.Sub(now)
+}
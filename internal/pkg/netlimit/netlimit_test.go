@@ -0,0 +1,66 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netlimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterMaxConcurrent(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter(LimiterWithMaxConcurrent(1))
+	release, err := limiter.Acquire(context.Background(), "foo.com")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "bar.com")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	release()
+	// releasing twice must not release another operation
+	release()
+	release, err = limiter.Acquire(context.Background(), "bar.com")
+	require.NoError(t, err)
+	release()
+}
+
+func TestLimiterHostRate(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter(LimiterWithHostRate(1))
+	release, err := limiter.Acquire(context.Background(), "foo.com")
+	require.NoError(t, err)
+	release()
+	// other hosts are not limited
+	release, err = limiter.Acquire(context.Background(), "bar.com")
+	require.NoError(t, err)
+	release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "foo.com")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestLimiterNoLimits(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter()
+	for i := 0; i < 10; i++ {
+		_, err := limiter.Acquire(context.Background(), "foo.com")
+		require.NoError(t, err)
+	}
+}
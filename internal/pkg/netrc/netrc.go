@@ -16,6 +16,9 @@
 package netrc
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -33,6 +36,16 @@ type Machine interface {
 	Account() string
 }
 
+// NewMachine returns a new Machine.
+func NewMachine(
+	name string,
+	login string,
+	password string,
+	account string,
+) Machine {
+	return newMachine(name, login, password, account)
+}
+
 // GetMachineForName returns the Machine for the given name.
 //
 // Returns nil if no such Machine.
@@ -44,6 +57,19 @@ func GetMachineForName(envContainer app.EnvContainer, name string) (_ Machine, r
 	return getMachineForNameAndFilePath(name, filePath)
 }
 
+// PutMachine adds the Machine to the netrc file, replacing any existing
+// machine with the same name.
+//
+// The file is created with 0600 permissions if it does not exist. The name
+// of the Machine must not be empty.
+func PutMachine(envContainer app.EnvContainer, machine Machine) error {
+	filePath, err := getFilePath(envContainer)
+	if err != nil {
+		return err
+	}
+	return putMachineForFilePath(machine, filePath)
+}
+
 func getFilePath(envContainer app.EnvContainer) (string, error) {
 	if netrcFilePath := envContainer.Env("NETRC"); netrcFilePath != "" {
 		return netrcFilePath, nil
@@ -81,3 +107,34 @@ func getMachineForNameAndFilePath(name string, filePath string) (_ Machine, retE
 		netrcMachine.Account,
 	), nil
 }
+
+func putMachineForFilePath(machine Machine, filePath string) error {
+	if machine.Name() == "" {
+		return errors.New("cannot put the default machine")
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	netrc, err := netrc.Parse(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	// updating in place does not work for machines that are missing
+	// tokens, so the machine is always re-added
+	netrc.RemoveMachine(machine.Name())
+	netrc.NewMachine(
+		machine.Name(),
+		machine.Login(),
+		machine.Password(),
+		machine.Account(),
+	)
+	data, err = netrc.MarshalText()
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	return ioutil.WriteFile(filePath, data, 0600)
+}
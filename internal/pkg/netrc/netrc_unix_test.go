@@ -17,6 +17,9 @@
 package netrc
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bufbuild/buf/internal/pkg/app"
@@ -88,6 +91,40 @@ func TestGetMachineForName(t *testing.T) {
 	)
 }
 
+func TestPutMachine(t *testing.T) {
+	homeDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(homeDirPath))
+	}()
+	data, err := ioutil.ReadFile("testdata/unix/home1/.netrc")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(homeDirPath, ".netrc"), data, 0600))
+	envContainer := app.NewEnvContainer(map[string]string{"HOME": homeDirPath})
+
+	require.NoError(t, PutMachine(envContainer, NewMachine("api.foo.com", "", "token", "")))
+	require.NoError(t, PutMachine(envContainer, NewMachine("foo.com", "bar2", "baz2", "")))
+	testGetMachineForNameSuccess(t, "api.foo.com", homeDirPath, "api.foo.com", "", "token", "")
+	testGetMachineForNameSuccess(t, "foo.com", homeDirPath, "foo.com", "bar2", "baz2", "")
+	testGetMachineForNameNil(t, "bar.com", homeDirPath)
+
+	emptyHomeDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(emptyHomeDirPath))
+	}()
+	require.NoError(t, PutMachine(
+		app.NewEnvContainer(map[string]string{"HOME": emptyHomeDirPath}),
+		NewMachine("foo.com", "bar", "baz", ""),
+	))
+	fileInfo, err := os.Stat(filepath.Join(emptyHomeDirPath, ".netrc"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+	testGetMachineForNameSuccess(t, "foo.com", emptyHomeDirPath, "foo.com", "bar", "baz", "")
+
+	assert.Error(t, PutMachine(envContainer, NewMachine("", "bar", "baz", "")))
+}
+
 func testGetMachineForNameSuccess(
 	t *testing.T,
 	name string,
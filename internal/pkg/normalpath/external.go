@@ -0,0 +1,185 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalpath
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	windowsExtendedLengthPrefix    = "//?/"
+	windowsExtendedLengthUNCPrefix = "//?/UNC/"
+	windowsUNCPrefix               = "//"
+)
+
+// NormalizeExternal normalizes the given external path, that is a path on the
+// local file system as given by a user or the operating system.
+//
+// This is the same as Normalize, except on Windows, where this also:
+//
+//   - Upper-cases drive letters, so that c:\a and C:\a are the same path.
+//   - Strips the \\?\ prefix of extended-length paths, and replaces the
+//     \\?\UNC\ prefix with \\.
+//   - Keeps the volume of UNC paths such as \\server\share\a, which is
+//     normalized to //server/share/a.
+func NormalizeExternal(externalPath string) string {
+	return normalizeExternal(externalPath, isWindows())
+}
+
+// NormalizeExternalAndAbsolute calls NormalizeExternal on the path and makes
+// it absolute.
+func NormalizeExternalAndAbsolute(externalPath string) (string, error) {
+	windows := isWindows()
+	externalPath = normalizeExternal(externalPath, windows)
+	if isAbsExternal(externalPath, windows) {
+		return externalPath, nil
+	}
+	absExternalPath, err := filepath.Abs(Unnormalize(externalPath))
+	if err != nil {
+		return "", err
+	}
+	return normalizeExternal(absExternalPath, windows), nil
+}
+
+// RelExternal returns the normalized path of the external path targetExternalPath
+// relative to the external path baseExternalPath.
+//
+// This is equivalent to filepath.Rel, except that the paths are normalized with
+// NormalizeExternal first, and on Windows, the paths are compared without regard
+// to case, as the file systems of Windows are case-insensitive.
+//
+// Returns error if one path is absolute and the other is not, or if the paths
+// are on different volumes.
+func RelExternal(baseExternalPath string, targetExternalPath string) (string, error) {
+	return relExternal(baseExternalPath, targetExternalPath, isWindows())
+}
+
+func normalizeExternal(externalPath string, windows bool) string {
+	if !windows {
+		return Normalize(externalPath)
+	}
+	externalPath = strings.ReplaceAll(externalPath, `\`, "/")
+	switch {
+	case hasPrefixFold(externalPath, windowsExtendedLengthUNCPrefix):
+		externalPath = windowsUNCPrefix + externalPath[len(windowsExtendedLengthUNCPrefix):]
+	case strings.HasPrefix(externalPath, windowsExtendedLengthPrefix):
+		externalPath = externalPath[len(windowsExtendedLengthPrefix):]
+	}
+	volume, rest := splitExternalVolume(externalPath, true)
+	if rest == "" && strings.HasPrefix(volume, windowsUNCPrefix) {
+		return volume
+	}
+	return volume + path.Clean(rest)
+}
+
+func isAbsExternal(externalPath string, windows bool) bool {
+	volume, rest := splitExternalVolume(externalPath, windows)
+	if strings.HasPrefix(volume, windowsUNCPrefix) {
+		return true
+	}
+	if windows && volume == "" {
+		// rooted paths without a drive letter are relative to the current drive
+		return false
+	}
+	return strings.HasPrefix(rest, "/")
+}
+
+// relExternal expects neither path to be empty.
+func relExternal(baseExternalPath string, targetExternalPath string, windows bool) (string, error) {
+	baseExternalPath = normalizeExternal(baseExternalPath, windows)
+	targetExternalPath = normalizeExternal(targetExternalPath, windows)
+	baseVolume, baseRest := splitExternalVolume(baseExternalPath, windows)
+	targetVolume, targetRest := splitExternalVolume(targetExternalPath, windows)
+	if !equalExternal(baseVolume, targetVolume, windows) ||
+		strings.HasPrefix(baseRest, "/") != strings.HasPrefix(targetRest, "/") {
+		return "", newRelExternalError(baseExternalPath, targetExternalPath)
+	}
+	baseComponents := externalComponents(baseRest)
+	targetComponents := externalComponents(targetRest)
+	i := 0
+	for i < len(baseComponents) && i < len(targetComponents) && equalExternal(baseComponents[i], targetComponents[i], windows) {
+		i++
+	}
+	relComponents := make([]string, 0, len(baseComponents)-i+len(targetComponents)-i)
+	for _, baseComponent := range baseComponents[i:] {
+		if baseComponent == ".." {
+			return "", newRelExternalError(baseExternalPath, targetExternalPath)
+		}
+		relComponents = append(relComponents, "..")
+	}
+	relComponents = append(relComponents, targetComponents[i:]...)
+	if len(relComponents) == 0 {
+		return ".", nil
+	}
+	return strings.Join(relComponents, "/"), nil
+}
+
+// splitExternalVolume splits the normalized external path into its volume and
+// the rest of the path.
+//
+// The volume is always empty if windows is false.
+func splitExternalVolume(externalPath string, windows bool) (string, string) {
+	if !windows {
+		return "", externalPath
+	}
+	if len(externalPath) >= 2 && externalPath[1] == ':' && isASCIILetter(externalPath[0]) {
+		return strings.ToUpper(externalPath[:1]) + ":", externalPath[2:]
+	}
+	if strings.HasPrefix(externalPath, windowsUNCPrefix) && !strings.HasPrefix(externalPath, windowsUNCPrefix+"/") {
+		// the volume of \\server\share\a is \\server\share
+		split := strings.SplitN(externalPath[len(windowsUNCPrefix):], "/", 3)
+		if len(split) < 2 || split[0] == "" || split[1] == "" {
+			return "", externalPath
+		}
+		volume := windowsUNCPrefix + split[0] + "/" + split[1]
+		return volume, externalPath[len(volume):]
+	}
+	return "", externalPath
+}
+
+func externalComponents(rest string) []string {
+	rest = strings.TrimPrefix(rest, "/")
+	if rest == "" || rest == "." {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
+
+func equalExternal(one string, two string, windows bool) bool {
+	if windows {
+		return strings.EqualFold(one, two)
+	}
+	return one == two
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isWindows() bool {
+	return runtime.GOOS == "windows"
+}
+
+func newRelExternalError(baseExternalPath string, targetExternalPath string) error {
+	return fmt.Errorf("cannot make %s relative to %s", targetExternalPath, baseExternalPath)
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeExternal(t *testing.T) {
+	t.Parallel()
+	testNormalizeExternal(t, false, ".", "")
+	testNormalizeExternal(t, false, "foo/bar", "./foo/bar/")
+	testNormalizeExternal(t, false, "/foo", "/foo/../foo")
+	// backslashes are valid in file names outside of Windows
+	testNormalizeExternal(t, false, `foo\bar`, `foo\bar`)
+
+	testNormalizeExternal(t, true, ".", "")
+	testNormalizeExternal(t, true, "foo/bar", `.\foo\bar\`)
+	testNormalizeExternal(t, true, "foo/bar", `foo/baz\..\bar`)
+	testNormalizeExternal(t, true, "/foo", `\foo`)
+	testNormalizeExternal(t, true, "C:/foo/bar", `c:\foo\bar`)
+	testNormalizeExternal(t, true, "C:/", `C:\`)
+	testNormalizeExternal(t, true, "C:foo", `c:foo`)
+	testNormalizeExternal(t, true, "C:/foo", `\\?\C:\foo`)
+	testNormalizeExternal(t, true, "//server/share/foo", `\\server\share\foo\`)
+	testNormalizeExternal(t, true, "//server/share", `\\server\share`)
+	testNormalizeExternal(t, true, "//server/share/", `\\server\share\..`)
+	testNormalizeExternal(t, true, "//server/share/foo", `\\?\UNC\server\share\foo`)
+}
+
+func TestIsAbsExternal(t *testing.T) {
+	t.Parallel()
+	assert.True(t, isAbsExternal("/foo", false))
+	assert.False(t, isAbsExternal("foo", false))
+	assert.False(t, isAbsExternal("C:/foo", false))
+
+	assert.True(t, isAbsExternal("C:/foo", true))
+	assert.True(t, isAbsExternal("//server/share/foo", true))
+	assert.True(t, isAbsExternal("//server/share", true))
+	assert.False(t, isAbsExternal("/foo", true))
+	assert.False(t, isAbsExternal("C:foo", true))
+	assert.False(t, isAbsExternal("foo", true))
+}
+
+func TestRelExternal(t *testing.T) {
+	t.Parallel()
+	testRelExternal(t, false, "bar/baz.proto", "/foo", "/foo/bar/baz.proto")
+	testRelExternal(t, false, ".", "/foo", "/foo")
+	testRelExternal(t, false, "../bar", "/foo", "/bar")
+	testRelExternal(t, false, "a", "../x", "../x/a")
+	testRelExternal(t, false, "bar", ".", "bar")
+	testRelExternalError(t, false, "/foo", "foo")
+	testRelExternalError(t, false, "../x", "a")
+	// case-sensitive outside of Windows
+	testRelExternal(t, false, "../Foo/bar", "/foo", "/Foo/bar")
+
+	testRelExternal(t, true, "proto/foo/bar.proto", `C:\src`, `c:\src\proto\foo\bar.proto`)
+	testRelExternal(t, true, "proto/foo/bar.proto", `C:\Src`, `C:\src\proto/foo\bar.proto`)
+	testRelExternal(t, true, "foo/bar.proto", `\\server\share\src`, `\\SERVER\share\src\foo\bar.proto`)
+	testRelExternal(t, true, "foo/bar.proto", `\\server\share\src`, `\\?\UNC\server\share\src\foo\bar.proto`)
+	testRelExternal(t, true, "foo/bar.proto", `C:\src`, `\\?\C:\src\foo\bar.proto`)
+	testRelExternal(t, true, "../other", `C:\src`, `C:\other`)
+	testRelExternalError(t, true, `C:\src`, `D:\src\foo`)
+	testRelExternalError(t, true, `C:\src`, `\\server\share\src\foo`)
+	testRelExternalError(t, true, `\\server\share\src`, `\\server\other\src\foo`)
+	testRelExternalError(t, true, `C:\src`, `foo`)
+}
+
+func testNormalizeExternal(t *testing.T, windows bool, expected string, externalPath string) {
+	assert.Equal(t, expected, normalizeExternal(externalPath, windows), externalPath)
+}
+
+func testRelExternal(t *testing.T, windows bool, expected string, baseExternalPath string, targetExternalPath string) {
+	rel, err := relExternal(baseExternalPath, targetExternalPath, windows)
+	require.NoError(t, err)
+	assert.Equal(t, expected, rel)
+}
+
+func testRelExternalError(t *testing.T, windows bool, baseExternalPath string, targetExternalPath string) {
+	_, err := relExternal(baseExternalPath, targetExternalPath, windows)
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalpath
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const globDoubleStar = "**"
+
+// IsGlob returns true if the path contains any of the glob metacharacters
+// "*", "?", and "[".
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// ValidateGlob validates the syntax of the glob pattern.
+//
+// The pattern is expected to be normalized.
+func ValidateGlob(pattern string) error {
+	for _, component := range strings.Split(pattern, "/") {
+		if component == globDoubleStar {
+			continue
+		}
+		if strings.Contains(component, globDoubleStar) {
+			return fmt.Errorf("invalid glob %q: %q must be an entire path component", pattern, globDoubleStar)
+		}
+		if _, err := path.Match(component, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchGlob returns true if the path or any of its parent directories
+// matches the glob pattern.
+//
+// The path and pattern are expected to be normalized and validated.
+//
+// Each component of the pattern is matched with path.Match, except "**",
+// which matches zero or more components. A pattern without a "/" matches
+// a component at any depth, for example "*_internal.proto" matches
+// "foo/bar_internal.proto".
+func MatchGlob(pattern string, path string) bool {
+	patternComponents := strings.Split(pattern, "/")
+	if len(patternComponents) == 1 {
+		patternComponents = []string{globDoubleStar, pattern}
+	}
+	return matchGlobComponents(patternComponents, strings.Split(path, "/"))
+}
+
+// matchGlobComponents returns true if the pattern components match the
+// path components or a prefix of them.
+func matchGlobComponents(patternComponents []string, pathComponents []string) bool {
+	if len(patternComponents) == 0 {
+		return true
+	}
+	if patternComponents[0] == globDoubleStar {
+		for i := 0; i <= len(pathComponents); i++ {
+			if matchGlobComponents(patternComponents[1:], pathComponents[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pathComponents) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(patternComponents[0], pathComponents[0]); !matched {
+		return false
+	}
+	return matchGlobComponents(patternComponents[1:], pathComponents[1:])
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package normalpath
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsGlob(t *testing.T) {
+	assert.False(t, IsGlob("a/b.proto"))
+	assert.True(t, IsGlob("a/*.proto"))
+	assert.True(t, IsGlob("a/?.proto"))
+	assert.True(t, IsGlob("a/[bc].proto"))
+	assert.True(t, IsGlob("**/vendor"))
+}
+
+func TestValidateGlob(t *testing.T) {
+	assert.NoError(t, ValidateGlob("**/vendor/**"))
+	assert.NoError(t, ValidateGlob("a/*_internal.proto"))
+	assert.NoError(t, ValidateGlob("a/[bc].proto"))
+	assert.Error(t, ValidateGlob("a/[b.proto"))
+	assert.Error(t, ValidateGlob("a/**.proto"))
+}
+
+func TestMatchGlob(t *testing.T) {
+	testMatchGlob(t, true, "*.proto", "a.proto")
+	testMatchGlob(t, true, "*.proto", "a/b.proto")
+	testMatchGlob(t, true, "*_internal.proto", "a/b/c_internal.proto")
+	testMatchGlob(t, false, "*_internal.proto", "a/b/c.proto")
+	testMatchGlob(t, true, "a/*.proto", "a/b.proto")
+	testMatchGlob(t, false, "a/*.proto", "b/a/b.proto")
+	testMatchGlob(t, false, "a/*.proto", "a/b/c.proto")
+	testMatchGlob(t, true, "a/*", "a/b/c.proto")
+	testMatchGlob(t, true, "**/vendor/**", "vendor/a.proto")
+	testMatchGlob(t, true, "**/vendor/**", "a/vendor/b/c.proto")
+	testMatchGlob(t, true, "**/vendor", "a/vendor/b/c.proto")
+	testMatchGlob(t, false, "**/vendor/**", "a/vendored/b.proto")
+	testMatchGlob(t, true, "a/**/c.proto", "a/c.proto")
+	testMatchGlob(t, true, "a/**/c.proto", "a/b/b/c.proto")
+	testMatchGlob(t, false, "a/**/c.proto", "b/c.proto")
+	testMatchGlob(t, true, "a/?.proto", "a/b.proto")
+	testMatchGlob(t, false, "a/?.proto", "a/bc.proto")
+	testMatchGlob(t, true, "a/[bc].proto", "a/c.proto")
+	testMatchGlob(t, false, "a/[bc].proto", "a/d.proto")
+}
+
+func testMatchGlob(t *testing.T, expected bool, pattern string, path string) {
+	assert.Equal(t, expected, MatchGlob(pattern, path), fmt.Sprintf("%s %s", pattern, path))
+}
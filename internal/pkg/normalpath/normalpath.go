@@ -112,7 +112,9 @@ func ErrorEquals(err error, target error) bool {
 // The error message is safe to pass to users.
 func NormalizeAndValidate(path string) (string, error) {
 	path = Normalize(path)
-	if filepath.IsAbs(path) {
+	// on Windows, rooted paths such as /foo and paths with a drive letter
+	// such as C:foo are not absolute, but are not relative either
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") || filepath.VolumeName(path) != "" {
 		return "", NewError(path, errNotRelative)
 	}
 	// https://github.com/bufbuild/buf/issues/51
@@ -0,0 +1,577 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+const (
+	dockerHubHost    = "docker.io"
+	dockerHubAPIHost = "registry-1.docker.io"
+
+	// the config of pushed artifacts, as artifacts do not have a config
+	emptyConfig = "{}"
+	// the maximum size of manifests and error responses that are read
+	maxMetadataSize = 4 << 20
+)
+
+type client struct {
+	logger     *zap.Logger
+	httpClient *http.Client
+	options    ClientOptions
+
+	// bearer tokens by host and scope
+	keyToToken map[string]string
+	lock       sync.RWMutex
+}
+
+func newClient(logger *zap.Logger, httpClient *http.Client, options ClientOptions) *client {
+	return &client{
+		logger:     logger.Named("oci"),
+		httpClient: httpClient,
+		options:    options,
+		keyToToken: make(map[string]string),
+	}
+}
+
+type manifest struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	MediaType     string        `json:"mediaType,omitempty"`
+	Config        *descriptor   `json:"config,omitempty"`
+	Layers        []*descriptor `json:"layers,omitempty"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+type errorResponse struct {
+	Errors []*errorResponseError `json:"errors,omitempty"`
+}
+
+type errorResponseError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func (c *client) Pull(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	reference Reference,
+) (_ io.ReadCloser, _ int64, retErr error) {
+	session := c.newSession(envContainer, reference, "pull")
+	manifestReference := reference.Tag
+	if reference.Digest != "" {
+		manifestReference = reference.Digest
+	}
+	response, err := session.do(
+		ctx,
+		http.MethodGet,
+		"/manifests/"+manifestReference,
+		http.Header{"Accept": []string{ManifestMediaType + ", " + DockerManifestMediaType}},
+		nil,
+		http.StatusOK,
+	)
+	if err != nil {
+		return nil, -1, fmt.Errorf("could not get manifest of %s: %v", reference, err)
+	}
+	manifestData, err := readAllAndClose(response.Body)
+	if err != nil {
+		return nil, -1, err
+	}
+	if reference.Digest != "" {
+		if actualDigest := getDigest(manifestData); actualDigest != reference.Digest {
+			return nil, -1, fmt.Errorf("manifest of %s had digest %s", reference, actualDigest)
+		}
+	}
+	manifest := &manifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, -1, fmt.Errorf("could not parse manifest of %s: %v", reference, err)
+	}
+	layer, err := c.getLayer(manifest)
+	if err != nil {
+		return nil, -1, fmt.Errorf("%s: %v", reference, err)
+	}
+	response, err = session.do(
+		ctx,
+		http.MethodGet,
+		"/blobs/"+layer.Digest,
+		nil,
+		nil,
+		http.StatusOK,
+	)
+	if err != nil {
+		return nil, -1, fmt.Errorf("could not get layer of %s: %v", reference, err)
+	}
+	return newVerifyingReadCloser(response.Body, layer.Digest), layer.Size, nil
+}
+
+func (c *client) Push(
+	ctx context.Context,
+	envContainer app.EnvContainer,
+	reference Reference,
+	data []byte,
+) (string, error) {
+	if reference.Tag == "" {
+		return "", fmt.Errorf("cannot push to %s, a tag is required", reference)
+	}
+	session := c.newSession(envContainer, reference, "pull,push")
+	configData := []byte(emptyConfig)
+	config := &descriptor{
+		MediaType: c.options.ConfigMediaType,
+		Digest:    getDigest(configData),
+		Size:      int64(len(configData)),
+	}
+	layer := &descriptor{
+		MediaType: c.options.LayerMediaType,
+		Digest:    getDigest(data),
+		Size:      int64(len(data)),
+	}
+	if err := session.putBlob(ctx, config.Digest, configData); err != nil {
+		return "", fmt.Errorf("could not push config to %s: %v", reference, err)
+	}
+	if err := session.putBlob(ctx, layer.Digest, data); err != nil {
+		return "", fmt.Errorf("could not push layer to %s: %v", reference, err)
+	}
+	manifestData, err := json.Marshal(
+		&manifest{
+			SchemaVersion: 2,
+			MediaType:     ManifestMediaType,
+			Config:        config,
+			Layers:        []*descriptor{layer},
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	response, err := session.do(
+		ctx,
+		http.MethodPut,
+		"/manifests/"+reference.Tag,
+		http.Header{"Content-Type": []string{ManifestMediaType}},
+		manifestData,
+		http.StatusCreated,
+	)
+	if err != nil {
+		return "", fmt.Errorf("could not push manifest to %s: %v", reference, err)
+	}
+	if err := response.Body.Close(); err != nil {
+		return "", err
+	}
+	digest := getDigest(manifestData)
+	c.logger.Info(
+		"pushed",
+		zap.String("reference", reference.String()),
+		zap.String("digest", digest),
+	)
+	return digest, nil
+}
+
+// getLayer gets the layer with the LayerMediaType, or the only layer.
+func (c *client) getLayer(manifest *manifest) (*descriptor, error) {
+	if len(manifest.Layers) == 1 {
+		return manifest.Layers[0], nil
+	}
+	for _, layer := range manifest.Layers {
+		if c.options.LayerMediaType != "" && layer.MediaType == c.options.LayerMediaType {
+			return layer, nil
+		}
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, errors.New("artifact has no layers")
+	}
+	return nil, fmt.Errorf("artifact has %d layers and none have media type %q", len(manifest.Layers), c.options.LayerMediaType)
+}
+
+func (c *client) getToken(key string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.keyToToken[key]
+}
+
+func (c *client) setToken(key string, token string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if token == "" {
+		delete(c.keyToToken, key)
+		return
+	}
+	c.keyToToken[key] = token
+}
+
+// session makes requests to a single repository with a single scope.
+type session struct {
+	client       *client
+	envContainer app.EnvContainer
+	baseURL      string
+	host         string
+	scope        string
+}
+
+func (c *client) newSession(envContainer app.EnvContainer, reference Reference, actions string) *session {
+	host := reference.Host
+	repository := reference.Repository
+	if host == dockerHubHost {
+		host = dockerHubAPIHost
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	scheme := "https"
+	if isLocalhost(host) {
+		scheme = "http"
+	}
+	return &session{
+		client:       c,
+		envContainer: envContainer,
+		baseURL:      scheme + "://" + host + "/v2/" + repository,
+		host:         host,
+		scope:        "repository:" + repository + ":" + actions,
+	}
+}
+
+// putBlob uploads the blob with a monolithic upload if it does not exist.
+func (s *session) putBlob(ctx context.Context, digest string, data []byte) error {
+	response, err := s.do(ctx, http.MethodHead, "/blobs/"+digest, nil, nil, http.StatusOK)
+	if err == nil {
+		s.client.logger.Debug("blob_exists", zap.String("digest", digest))
+		return response.Body.Close()
+	}
+	response, err = s.do(ctx, http.MethodPost, "/blobs/uploads/", nil, nil, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	if err := response.Body.Close(); err != nil {
+		return err
+	}
+	location, err := response.Request.URL.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %v", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	response, err = s.doURL(
+		ctx,
+		http.MethodPut,
+		location.String(),
+		http.Header{"Content-Type": []string{"application/octet-stream"}},
+		data,
+		http.StatusCreated,
+	)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// do makes a request to the path within the repository.
+//
+// Returns an error if the response does not have the expected status code.
+// The body of the response must be closed if there is no error.
+func (s *session) do(
+	ctx context.Context,
+	method string,
+	path string,
+	header http.Header,
+	body []byte,
+	expectedStatusCode int,
+) (*http.Response, error) {
+	return s.doURL(ctx, method, s.baseURL+path, header, body, expectedStatusCode)
+}
+
+func (s *session) doURL(
+	ctx context.Context,
+	method string,
+	requestURL string,
+	header http.Header,
+	body []byte,
+	expectedStatusCode int,
+) (*http.Response, error) {
+	tokenKey := s.host + " " + s.scope
+	response, err := s.doOnce(ctx, method, requestURL, header, body, s.client.getToken(tokenKey), nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusUnauthorized {
+		challenge := response.Header.Get("WWW-Authenticate")
+		if err := drainAndClose(response.Body); err != nil {
+			return nil, err
+		}
+		credentials, err := getCredentials(ctx, s.envContainer, s.host)
+		if err != nil {
+			return nil, err
+		}
+		scheme, params := parseChallenge(challenge)
+		switch scheme {
+		case "bearer":
+			token, err := s.getBearerToken(ctx, params, credentials)
+			if err != nil {
+				return nil, err
+			}
+			s.client.setToken(tokenKey, token)
+			response, err = s.doOnce(ctx, method, requestURL, header, body, token, nil)
+		case "basic":
+			if credentials == nil {
+				return nil, fmt.Errorf("no credentials for %s, log in with buf login or docker login", s.host)
+			}
+			response, err = s.doOnce(ctx, method, requestURL, header, body, "", credentials)
+		default:
+			return nil, fmt.Errorf("unsupported authentication challenge %q", challenge)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if response.StatusCode != expectedStatusCode {
+		return nil, newStatusError(response)
+	}
+	return response, nil
+}
+
+func (s *session) doOnce(
+	ctx context.Context,
+	method string,
+	requestURL string,
+	header http.Header,
+	body []byte,
+	token string,
+	credentials *credentials,
+) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if credentials != nil {
+		request.SetBasicAuth(credentials.username, credentials.password)
+	}
+	return s.client.httpClient.Do(request)
+}
+
+// getBearerToken gets a token from the realm of the challenge.
+//
+// https://docs.docker.com/registry/spec/auth/token/
+func (s *session) getBearerToken(ctx context.Context, params map[string]string, credentials *credentials) (_ string, retErr error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.New("bearer authentication challenge has no realm")
+	}
+	realmURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid bearer authentication realm %q: %v", realm, err)
+	}
+	switch realmURL.Scheme {
+	case "https":
+	case "http":
+		if !isLocalhost(realmURL.Host) && !s.client.options.AllowInsecureHTTP {
+			return "", fmt.Errorf("bearer authentication realm %s is plain http, use https or explicitly allow insecure http", realmURL.Host)
+		}
+	default:
+		return "", fmt.Errorf("invalid bearer authentication realm %q: scheme must be https", realm)
+	}
+	query := realmURL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", s.scope)
+	realmURL.RawQuery = query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, realmURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials != nil {
+		request.SetBasicAuth(credentials.username, credentials.password)
+	}
+	response, err := s.client.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		err := newStatusError(response)
+		if credentials == nil {
+			err = fmt.Errorf("%v, no credentials for %s, log in with buf login or docker login", err, s.host)
+		}
+		return "", fmt.Errorf("could not get token from %s: %v", realmURL.Host, err)
+	}
+	data, err := readAllAndClose(response.Body)
+	if err != nil {
+		return "", err
+	}
+	tokenResponse := struct {
+		Token       string `json:"token,omitempty"`
+		AccessToken string `json:"access_token,omitempty"`
+	}{}
+	if err := json.Unmarshal(data, &tokenResponse); err != nil {
+		return "", fmt.Errorf("could not parse token from %s: %v", realmURL.Host, err)
+	}
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	if tokenResponse.AccessToken != "" {
+		return tokenResponse.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned from %s", realmURL.Host)
+}
+
+// parseChallenge parses the WWW-Authenticate header into the lowercase scheme
+// and the parameters, such as Bearer realm="https://host/token",service="host".
+func parseChallenge(challenge string) (string, map[string]string) {
+	challenge = strings.TrimSpace(challenge)
+	index := strings.Index(challenge, " ")
+	if index == -1 {
+		return strings.ToLower(challenge), nil
+	}
+	scheme := strings.ToLower(challenge[:index])
+	params := make(map[string]string)
+	rest := challenge[index+1:]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		equalsIndex := strings.Index(rest, "=")
+		if equalsIndex == -1 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:equalsIndex]))
+		rest = rest[equalsIndex+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			endIndex := strings.Index(rest[1:], `"`)
+			if endIndex == -1 {
+				value = rest[1:]
+				rest = ""
+			} else {
+				value = rest[1 : endIndex+1]
+				rest = rest[endIndex+2:]
+			}
+		} else {
+			endIndex := strings.Index(rest, ",")
+			if endIndex == -1 {
+				value = rest
+				rest = ""
+			} else {
+				value = rest[:endIndex]
+				rest = rest[endIndex+1:]
+			}
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return scheme, params
+}
+
+func newStatusError(response *http.Response) error {
+	data, err := readAllAndClose(response.Body)
+	if err != nil {
+		return err
+	}
+	statusErr := fmt.Errorf("got HTTP status code %d", response.StatusCode)
+	errorResponse := &errorResponse{}
+	if json.Unmarshal(data, errorResponse) == nil && len(errorResponse.Errors) > 0 {
+		messages := make([]string, len(errorResponse.Errors))
+		for i, responseError := range errorResponse.Errors {
+			messages[i] = responseError.Code + ": " + responseError.Message
+		}
+		return fmt.Errorf("%v: %s", statusErr, strings.Join(messages, ", "))
+	}
+	return statusErr
+}
+
+// isLocalhost returns true if the host, optionally with a port, is a loopback host.
+func isLocalhost(host string) bool {
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func getDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func readAllAndClose(readCloser io.ReadCloser) (_ []byte, retErr error) {
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	return ioutil.ReadAll(io.LimitReader(readCloser, maxMetadataSize))
+}
+
+func drainAndClose(readCloser io.ReadCloser) error {
+	_, err := io.Copy(ioutil.Discard, io.LimitReader(readCloser, maxMetadataSize))
+	return multierr.Append(err, readCloser.Close())
+}
+
+// verifyingReadCloser verifies the content against the digest once it has
+// all been read.
+type verifyingReadCloser struct {
+	readCloser io.ReadCloser
+	hash       hash.Hash
+	digest     string
+}
+
+func newVerifyingReadCloser(readCloser io.ReadCloser, digest string) *verifyingReadCloser {
+	return &verifyingReadCloser{
+		readCloser: readCloser,
+		hash:       sha256.New(),
+		digest:     digest,
+	}
+}
+
+func (v *verifyingReadCloser) Read(p []byte) (int, error) {
+	n, err := v.readCloser.Read(p)
+	_, _ = v.hash.Write(p[:n])
+	if err == io.EOF {
+		if actualDigest := "sha256:" + hex.EncodeToString(v.hash.Sum(nil)); actualDigest != v.digest {
+			return n, fmt.Errorf("layer had digest %s but expected %s", actualDigest, v.digest)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReadCloser) Close() error {
+	return v.readCloser.Close()
+}
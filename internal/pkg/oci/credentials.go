@@ -0,0 +1,191 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/netrc"
+)
+
+// the key that Docker Hub credentials are stored under
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+type credentials struct {
+	username string
+	password string
+}
+
+type dockerConfig struct {
+	Auths       map[string]dockerConfigAuth `json:"auths,omitempty"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+}
+
+type dockerConfigAuth struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type dockerCredentialHelperOutput struct {
+	Username string `json:"Username,omitempty"`
+	Secret   string `json:"Secret,omitempty"`
+}
+
+// getCredentials gets the credentials for the host from the Docker config
+// file, falling back to the netrc file, such as written by buf login.
+//
+// Returns nil if there are no credentials for the host.
+func getCredentials(ctx context.Context, envContainer app.EnvContainer, host string) (*credentials, error) {
+	credentials, err := getDockerCredentials(ctx, envContainer, host)
+	if err != nil || credentials != nil {
+		return credentials, err
+	}
+	return getNetrcCredentials(envContainer, host)
+}
+
+// getDockerCredentials gets the credentials for the host from the Docker
+// config file, including from credential helpers.
+//
+// Returns nil if there are no credentials for the host.
+func getDockerCredentials(ctx context.Context, envContainer app.EnvContainer, host string) (*credentials, error) {
+	configFilePath, err := getDockerConfigFilePath(envContainer)
+	if err != nil {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	config := &dockerConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", configFilePath, err)
+	}
+	authKey := host
+	if host == dockerHubHost || host == dockerHubAPIHost {
+		authKey = dockerHubAuthKey
+	}
+	if helper, ok := config.CredHelpers[authKey]; ok {
+		return getCredentialHelperCredentials(ctx, helper, authKey)
+	}
+	for key, auth := range config.Auths {
+		if key != authKey && normalizeAuthKey(key) != authKey {
+			continue
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("could not decode auth for %s in %s: %v", key, configFilePath, err)
+			}
+			split := strings.SplitN(string(decoded), ":", 2)
+			if len(split) != 2 {
+				return nil, fmt.Errorf("invalid auth for %s in %s", key, configFilePath)
+			}
+			return &credentials{username: split[0], password: split[1]}, nil
+		}
+		if auth.Username != "" {
+			return &credentials{username: auth.Username, password: auth.Password}, nil
+		}
+	}
+	if config.CredsStore != "" {
+		return getCredentialHelperCredentials(ctx, config.CredsStore, authKey)
+	}
+	return nil, nil
+}
+
+// getNetrcCredentials gets the credentials for the host from the netrc file.
+//
+// Returns nil if there is no machine for the host. The default machine is
+// not used, as it is not specific to any registry.
+func getNetrcCredentials(envContainer app.EnvContainer, host string) (*credentials, error) {
+	if envContainer.Env("NETRC") == "" {
+		// as with the Docker config, no home directory means no credentials
+		if _, err := app.HomeDirPath(envContainer); err != nil {
+			return nil, nil
+		}
+	}
+	machine, err := netrc.GetMachineForName(envContainer, host)
+	if err != nil {
+		return nil, err
+	}
+	if machine == nil || machine.Name() == "" {
+		return nil, nil
+	}
+	return &credentials{username: machine.Login(), password: machine.Password()}, nil
+}
+
+// getCredentialHelperCredentials gets the credentials from the
+// docker-credential-helper program.
+//
+// https://github.com/docker/docker-credential-helpers
+func getCredentialHelperCredentials(ctx context.Context, helper string, authKey string) (*credentials, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(authKey)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		// the helper prints this to stdout if there are no credentials
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("docker-credential-%s get failed: %v: %s", helper, err, strings.TrimSpace(stderr.String()))
+	}
+	output := &dockerCredentialHelperOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, fmt.Errorf("could not parse output of docker-credential-%s: %v", helper, err)
+	}
+	if output.Username == "" && output.Secret == "" {
+		return nil, nil
+	}
+	return &credentials{username: output.Username, password: output.Secret}, nil
+}
+
+func getDockerConfigFilePath(envContainer app.EnvContainer) (string, error) {
+	if dockerConfigDirPath := envContainer.Env("DOCKER_CONFIG"); dockerConfigDirPath != "" {
+		return filepath.Join(dockerConfigDirPath, "config.json"), nil
+	}
+	homeDirPath, err := app.HomeDirPath(envContainer)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDirPath, ".docker", "config.json"), nil
+}
+
+// normalizeAuthKey strips the scheme and path from keys such as
+// https://ghcr.io/v1/, which older versions of Docker wrote.
+func normalizeAuthKey(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	if index := strings.Index(key, "/"); index != -1 {
+		key = key[:index]
+	}
+	return key
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci pushes and pulls single-layer artifacts to and from OCI registries.
+package oci
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"go.uber.org/zap"
+)
+
+const (
+	// ManifestMediaType is the media type of OCI image manifests.
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// DockerManifestMediaType is the media type of Docker image manifests.
+	//
+	// These are accepted when pulling, as some registries convert manifests.
+	DockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	// DefaultTag is the tag used if a Reference has neither a tag nor a digest.
+	DefaultTag = "latest"
+)
+
+// Reference is a reference to an artifact in a registry.
+//
+// References are of the form host/repository:tag or host/repository@digest.
+type Reference struct {
+	// Host is the host of the registry, optionally with a port.
+	Host string
+	// Repository is the repository within the registry.
+	Repository string
+	// Tag is the tag of the artifact.
+	//
+	// Empty if Digest is set.
+	Tag string
+	// Digest is the digest of the manifest of the artifact, such as sha256:HEX.
+	//
+	// Empty if Tag is set.
+	Digest string
+}
+
+// ParseReference parses the Reference.
+//
+// If neither a tag nor a digest is given, DefaultTag is used.
+func ParseReference(value string) (Reference, error) {
+	return parseReference(value)
+}
+
+// String returns the Reference in the form host/repository:tag or host/repository@digest.
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return r.Host + "/" + r.Repository + "@" + r.Digest
+	}
+	return r.Host + "/" + r.Repository + ":" + r.Tag
+}
+
+// Client pushes and pulls single-layer artifacts.
+type Client interface {
+	// Pull pulls the content of the layer of the artifact.
+	//
+	// The content is verified against the digest of the layer as it is read,
+	// and an error is returned from Read if the content does not match.
+	// Returns the size of the content.
+	Pull(
+		ctx context.Context,
+		envContainer app.EnvContainer,
+		reference Reference,
+	) (io.ReadCloser, int64, error)
+	// Push pushes data as the single layer of an artifact, and tags the
+	// artifact with the tag of the reference.
+	//
+	// The reference must have a tag.
+	// Returns the digest of the manifest of the artifact.
+	Push(
+		ctx context.Context,
+		envContainer app.EnvContainer,
+		reference Reference,
+		data []byte,
+	) (string, error)
+}
+
+// NewClient returns a new Client.
+//
+// Requests are made over https, except for registries on localhost, which
+// are accessed over plain http, as is done by Docker.
+//
+// Credentials are read from the Docker config file at $DOCKER_CONFIG/config.json,
+// falling back to $HOME/.docker/config.json, including from credential helpers.
+// If the Docker config has no credentials for a registry, the machine for the
+// host of the registry in the netrc file is used.
+// Registries that require token authentication are supported.
+func NewClient(logger *zap.Logger, httpClient *http.Client, options ClientOptions) Client {
+	return newClient(logger, httpClient, options)
+}
+
+// ClientOptions are options for a new Client.
+type ClientOptions struct {
+	// ConfigMediaType is the media type of the config of pushed artifacts.
+	//
+	// The config is always the empty JSON object.
+	ConfigMediaType string
+	// LayerMediaType is the media type of the layer of pushed artifacts.
+	//
+	// When pulling, the layer with this media type is used if the artifact
+	// has multiple layers.
+	LayerMediaType string
+	// AllowInsecureHTTP allows bearer tokens to be requested from realms
+	// over plain, non-TLS http.
+	//
+	// By default, credentials are only sent to https realms, or http realms
+	// on localhost.
+	AllowInsecureHTTP bool
+}
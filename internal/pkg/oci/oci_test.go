@@ -0,0 +1,289 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Parallel()
+	testParseReference(t, "ghcr.io/org/protos:v1", Reference{Host: "ghcr.io", Repository: "org/protos", Tag: "v1"})
+	testParseReference(t, "ghcr.io/org/protos", Reference{Host: "ghcr.io", Repository: "org/protos", Tag: DefaultTag})
+	testParseReference(t, "localhost:5000/protos:v1", Reference{Host: "localhost:5000", Repository: "protos", Tag: "v1"})
+	testParseReference(t, "localhost:5000/protos", Reference{Host: "localhost:5000", Repository: "protos", Tag: DefaultTag})
+	digest := "sha256:" + strings.Repeat("a", 64)
+	testParseReference(t, "ghcr.io/org/protos@"+digest, Reference{Host: "ghcr.io", Repository: "org/protos", Digest: digest})
+	testParseReferenceError(t, "ghcr.io")
+	testParseReferenceError(t, "ghcr.io/")
+	testParseReferenceError(t, "ghcr.io/Org/protos:v1")
+	testParseReferenceError(t, "ghcr.io/org/protos:v1:v2")
+	testParseReferenceError(t, "ghcr.io/org/protos@sha256:abc")
+}
+
+func TestPushPull(t *testing.T) {
+	t.Parallel()
+	registry := newTestRegistry("user", "password")
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	registry.realm = server.URL + "/token"
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	dockerConfigDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dockerConfigDirPath))
+	}()
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(dockerConfigDirPath, "config.json"),
+			[]byte(`{"auths":{"`+host+`":{"auth":"`+base64.StdEncoding.EncodeToString([]byte("user:password"))+`"}}}`),
+			0600,
+		),
+	)
+	envContainer := app.NewEnvContainer(map[string]string{"DOCKER_CONFIG": dockerConfigDirPath})
+	client := NewClient(
+		zap.NewNop(),
+		server.Client(),
+		ClientOptions{
+			ConfigMediaType: "application/vnd.test.config.v1+json",
+			LayerMediaType:  "application/vnd.test.v1",
+		},
+	)
+	ctx := context.Background()
+
+	reference, err := ParseReference(host + "/org/test:v1")
+	require.NoError(t, err)
+	digest, err := client.Push(ctx, envContainer, reference, []byte("one"))
+	require.NoError(t, err)
+	// pushing again does not upload existing blobs
+	_, err = client.Push(ctx, envContainer, reference, []byte("one"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, registry.uploadCount)
+
+	testPull(t, client, envContainer, reference, "one")
+	digestReference, err := ParseReference(host + "/org/test@" + digest)
+	require.NoError(t, err)
+	testPull(t, client, envContainer, digestReference, "one")
+
+	// without credentials, the token request is rejected
+	_, _, err = NewClient(zap.NewNop(), server.Client(), ClientOptions{}).Pull(
+		ctx,
+		app.NewEnvContainer(map[string]string{"DOCKER_CONFIG": filepath.Join(dockerConfigDirPath, "missing")}),
+		reference,
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "docker login")
+
+	// credentials are read from the netrc file if not in the Docker config
+	netrcFilePath := filepath.Join(dockerConfigDirPath, "netrc")
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			netrcFilePath,
+			[]byte("machine "+host+"\nlogin user\npassword password\n"),
+			0600,
+		),
+	)
+	testPull(
+		t,
+		NewClient(zap.NewNop(), server.Client(), ClientOptions{}),
+		app.NewEnvContainer(
+			map[string]string{
+				"DOCKER_CONFIG": filepath.Join(dockerConfigDirPath, "missing"),
+				"NETRC":         netrcFilePath,
+			},
+		),
+		reference,
+		"one",
+	)
+}
+
+func TestPullInsecureRealm(t *testing.T) {
+	t.Parallel()
+	registry := newTestRegistry("user", "password")
+	server := httptest.NewServer(registry)
+	defer server.Close()
+	// the realm is not on localhost, the transport sends it to the server
+	registry.realm = "http://registry.test/token"
+	host := strings.TrimPrefix(server.URL, "http://")
+	httpClient := &http.Client{
+		Transport: roundTripperFunc(
+			func(request *http.Request) (*http.Response, error) {
+				if request.URL.Host == "registry.test" {
+					request.URL.Host = host
+				}
+				return http.DefaultTransport.RoundTrip(request)
+			},
+		),
+	}
+
+	dockerConfigDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(dockerConfigDirPath))
+	}()
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(dockerConfigDirPath, "config.json"),
+			[]byte(`{"auths":{"`+host+`":{"auth":"`+base64.StdEncoding.EncodeToString([]byte("user:password"))+`"}}}`),
+			0600,
+		),
+	)
+	envContainer := app.NewEnvContainer(map[string]string{"DOCKER_CONFIG": dockerConfigDirPath})
+	ctx := context.Background()
+	reference, err := ParseReference(host + "/org/test:v1")
+	require.NoError(t, err)
+
+	// credentials are not sent to a plain http realm
+	_, err = NewClient(zap.NewNop(), httpClient, ClientOptions{}).Push(ctx, envContainer, reference, []byte("one"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "plain http")
+	assert.Equal(t, 0, registry.tokenCount)
+
+	client := NewClient(zap.NewNop(), httpClient, ClientOptions{AllowInsecureHTTP: true})
+	_, err = client.Push(ctx, envContainer, reference, []byte("one"))
+	require.NoError(t, err)
+	testPull(t, client, envContainer, reference, "one")
+}
+
+func testParseReference(t *testing.T, value string, expectedReference Reference) {
+	reference, err := ParseReference(value)
+	require.NoError(t, err)
+	assert.Equal(t, expectedReference, reference)
+	assert.Equal(t, expectedReference, mustParseReference(t, reference.String()))
+}
+
+func mustParseReference(t *testing.T, value string) Reference {
+	reference, err := ParseReference(value)
+	require.NoError(t, err)
+	return reference
+}
+
+func testParseReferenceError(t *testing.T, value string) {
+	_, err := ParseReference(value)
+	assert.Error(t, err, value)
+}
+
+func testPull(t *testing.T, client Client, envContainer app.EnvContainer, reference Reference, expectedData string) {
+	readCloser, size, err := client.Pull(context.Background(), envContainer, reference)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(readCloser)
+	require.NoError(t, err)
+	require.NoError(t, readCloser.Close())
+	assert.Equal(t, expectedData, string(data))
+	assert.Equal(t, int64(len(expectedData)), size)
+}
+
+// testRegistry is an in-memory registry that requires bearer tokens.
+type testRegistry struct {
+	username string
+	password string
+	realm    string
+
+	blobs       map[string][]byte
+	manifests   map[string][]byte
+	uploadCount int
+	tokenCount  int
+	lock        sync.Mutex
+}
+
+func newTestRegistry(username string, password string) *testRegistry {
+	return &testRegistry{
+		username:  username,
+		password:  password,
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string][]byte),
+	}
+}
+
+func (r *testRegistry) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if request.URL.Path == "/token" {
+		r.tokenCount++
+		if username, password, ok := request.BasicAuth(); !ok || username != r.username || password != r.password {
+			responseWriter.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = responseWriter.Write([]byte(`{"token":"` + request.URL.Query().Get("scope") + `"}`))
+		return
+	}
+	if !strings.HasPrefix(request.Header.Get("Authorization"), "Bearer repository:org/test:") {
+		responseWriter.Header().Set("WWW-Authenticate", `Bearer realm="`+r.realm+`",service="test"`)
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(request.URL.Path, "/v2/org/test")
+	switch {
+	case request.Method == http.MethodPost && path == "/blobs/uploads/":
+		responseWriter.Header().Set("Location", "/v2/org/test/blobs/uploads/1?state=test")
+		responseWriter.WriteHeader(http.StatusAccepted)
+	case request.Method == http.MethodPut && path == "/blobs/uploads/1":
+		if request.URL.Query().Get("state") != "test" {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(request.Body)
+		r.blobs[request.URL.Query().Get("digest")] = data
+		r.uploadCount++
+		responseWriter.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/blobs/"):
+		data, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = responseWriter.Write(data)
+	case request.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		data, _ := ioutil.ReadAll(request.Body)
+		r.manifests[strings.TrimPrefix(path, "/manifests/")] = data
+		r.manifests[getDigest(data)] = data
+		responseWriter.WriteHeader(http.StatusCreated)
+	case request.Method == http.MethodGet && strings.HasPrefix(path, "/manifests/"):
+		data, ok := r.manifests[strings.TrimPrefix(path, "/manifests/")]
+		if !ok {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			_, _ = responseWriter.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+			return
+		}
+		responseWriter.Header().Set("Content-Type", ManifestMediaType)
+		_, _ = responseWriter.Write(data)
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#pulling-manifests
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+func parseReference(value string) (Reference, error) {
+	split := strings.SplitN(value, "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return Reference{}, newInvalidReferenceError(value, "must be of the form host/repository:tag or host/repository@digest")
+	}
+	reference := Reference{
+		Host:       split[0],
+		Repository: split[1],
+	}
+	if index := strings.Index(reference.Repository, "@"); index != -1 {
+		reference.Digest = reference.Repository[index+1:]
+		reference.Repository = reference.Repository[:index]
+		if !digestRegexp.MatchString(reference.Digest) {
+			return Reference{}, newInvalidReferenceError(value, fmt.Sprintf("invalid digest %q, must be of the form sha256:HEX", reference.Digest))
+		}
+	} else if index := strings.LastIndex(reference.Repository, ":"); index != -1 && !strings.Contains(reference.Repository[index:], "/") {
+		reference.Tag = reference.Repository[index+1:]
+		reference.Repository = reference.Repository[:index]
+		if !tagRegexp.MatchString(reference.Tag) {
+			return Reference{}, newInvalidReferenceError(value, fmt.Sprintf("invalid tag %q", reference.Tag))
+		}
+	} else {
+		reference.Tag = DefaultTag
+	}
+	if !repositoryRegexp.MatchString(reference.Repository) {
+		return Reference{}, newInvalidReferenceError(value, fmt.Sprintf("invalid repository %q, must be lowercase", reference.Repository))
+	}
+	return reference, nil
+}
+
+func newInvalidReferenceError(value string, message string) error {
+	return fmt.Errorf("invalid OCI reference %q: %s", value, message)
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protodescriptor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The editions fields are newer than the vendored descriptor.proto, so they
+// are read from the unknown fields, which are preserved when images are read
+// and written.
+const (
+	// fileDescriptorProtoEditionStringFieldNumber is the field number of the
+	// edition string of the first experimental versions of editions.
+	fileDescriptorProtoEditionStringFieldNumber protowire.Number = 13
+	// fileDescriptorProtoEditionFieldNumber is the field number of the
+	// Edition enum.
+	fileDescriptorProtoEditionFieldNumber protowire.Number = 14
+	// optionsFeaturesFieldNumber is the field number of the FeatureSet in
+	// all options messages.
+	optionsFeaturesFieldNumber protowire.Number = 50
+)
+
+// SyntaxEditions is the value of the syntax field of files that use editions.
+const SyntaxEditions = "editions"
+
+var (
+	editionToString = map[uint64]string{
+		998:  "proto2",
+		999:  "proto3",
+		1000: "2023",
+		1001: "2024",
+	}
+	// featureDefinitions are the file-level features, in the order of their
+	// field numbers in FeatureSet.
+	featureDefinitions = []*featureDefinition{
+		{
+			name:   "field_presence",
+			values: map[uint64]string{1: "EXPLICIT", 2: "IMPLICIT", 3: "LEGACY_REQUIRED"},
+		},
+		{
+			name:   "enum_type",
+			values: map[uint64]string{1: "OPEN", 2: "CLOSED"},
+		},
+		{
+			name:   "repeated_field_encoding",
+			values: map[uint64]string{1: "PACKED", 2: "EXPANDED"},
+		},
+		{
+			name:   "utf8_validation",
+			values: map[uint64]string{2: "VERIFY", 3: "NONE"},
+		},
+		{
+			name:   "message_encoding",
+			values: map[uint64]string{1: "LENGTH_PREFIXED", 2: "DELIMITED"},
+		},
+		{
+			name:   "json_format",
+			values: map[uint64]string{1: "ALLOW", 2: "LEGACY_BEST_EFFORT"},
+		},
+	}
+	// the defaults are in the order of featureDefinitions
+	proto2FeatureDefaults   = []string{"EXPLICIT", "CLOSED", "EXPANDED", "NONE", "LENGTH_PREFIXED", "LEGACY_BEST_EFFORT"}
+	proto3FeatureDefaults   = []string{"IMPLICIT", "OPEN", "PACKED", "VERIFY", "LENGTH_PREFIXED", "ALLOW"}
+	editionsFeatureDefaults = []string{"EXPLICIT", "OPEN", "PACKED", "VERIFY", "LENGTH_PREFIXED", "ALLOW"}
+)
+
+// Feature is a resolved file-level feature, such as field_presence.
+type Feature struct {
+	// Name is the name of the feature field in FeatureSet, such as field_presence.
+	Name string
+	// Value is the name of the feature value, such as EXPLICIT.
+	Value string
+}
+
+// GetFileDescriptorProtoEdition returns the edition of the FileDescriptorProto,
+// such as 2023.
+//
+// Returns empty if the file does not use editions.
+// Returns error if the edition field is malformed.
+func GetFileDescriptorProtoEdition(fileDescriptorProto *descriptorpb.FileDescriptorProto) (string, error) {
+	if fileDescriptorProto.GetSyntax() != SyntaxEditions {
+		return "", nil
+	}
+	var edition string
+	if err := rangeUnknownFields(
+		fileDescriptorProto.ProtoReflect().GetUnknown(),
+		func(number protowire.Number, wireType protowire.Type, value []byte) error {
+			switch {
+			case number == fileDescriptorProtoEditionFieldNumber && wireType == protowire.VarintType:
+				v, n := protowire.ConsumeVarint(value)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				if s, ok := editionToString[v]; ok {
+					edition = s
+				} else {
+					edition = strconv.FormatUint(v, 10)
+				}
+			case number == fileDescriptorProtoEditionStringFieldNumber && wireType == protowire.BytesType:
+				v, n := protowire.ConsumeBytes(value)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				edition = string(v)
+			}
+			return nil
+		},
+	); err != nil {
+		return "", fmt.Errorf("malformed edition for %q: %v", fileDescriptorProto.GetName(), err)
+	}
+	if edition == "" {
+		return "", errors.New("file with editions syntax has no edition: " + fileDescriptorProto.GetName())
+	}
+	return edition, nil
+}
+
+// GetFileDescriptorProtoFeatures returns the resolved file-level features of
+// the FileDescriptorProto.
+//
+// These are the defaults of the syntax or edition of the file, overridden by
+// the features file option. Proto2 and proto3 files have the features that
+// match their behavior, so that files can be compared across syntaxes and
+// editions. The features are returned in the order of their field numbers.
+func GetFileDescriptorProtoFeatures(fileDescriptorProto *descriptorpb.FileDescriptorProto) ([]Feature, error) {
+	var defaults []string
+	switch syntax := fileDescriptorProto.GetSyntax(); syntax {
+	case "", "proto2":
+		defaults = proto2FeatureDefaults
+	case "proto3":
+		defaults = proto3FeatureDefaults
+	case SyntaxEditions:
+		defaults = editionsFeatureDefaults
+	default:
+		return nil, fmt.Errorf("unknown syntax: %q", syntax)
+	}
+	features := make([]Feature, len(featureDefinitions))
+	for i, featureDefinition := range featureDefinitions {
+		features[i] = Feature{
+			Name:  featureDefinition.name,
+			Value: defaults[i],
+		}
+	}
+	if fileDescriptorProto.GetSyntax() != SyntaxEditions || fileDescriptorProto.Options == nil {
+		return features, nil
+	}
+	if err := rangeUnknownFields(
+		fileDescriptorProto.GetOptions().ProtoReflect().GetUnknown(),
+		func(number protowire.Number, wireType protowire.Type, value []byte) error {
+			if number != optionsFeaturesFieldNumber || wireType != protowire.BytesType {
+				return nil
+			}
+			featureSet, n := protowire.ConsumeBytes(value)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			return rangeUnknownFields(
+				featureSet,
+				func(number protowire.Number, wireType protowire.Type, value []byte) error {
+					index := int(number) - 1
+					if index < 0 || index >= len(featureDefinitions) || wireType != protowire.VarintType {
+						// features that are not file-level behavior, such as
+						// language-specific features, are not resolved
+						return nil
+					}
+					v, n := protowire.ConsumeVarint(value)
+					if n < 0 {
+						return protowire.ParseError(n)
+					}
+					featureDefinition := featureDefinitions[index]
+					if s, ok := featureDefinition.values[v]; ok {
+						features[index].Value = s
+					} else {
+						features[index].Value = strconv.FormatUint(v, 10)
+					}
+					return nil
+				},
+			)
+		},
+	); err != nil {
+		return nil, fmt.Errorf("malformed features for %q: %v", fileDescriptorProto.GetName(), err)
+	}
+	return features, nil
+}
+
+// GetResolvableFileDescriptorProtos returns the FileDescriptorProtos with
+// any files that use editions replaced by copies with the proto2 syntax.
+//
+// The vendored protodesc rejects the editions syntax, but the descriptors of
+// editions files are otherwise valid, and proto2 has explicit presence like
+// the editions defaults. The given FileDescriptorProtos are not modified, so
+// the copies should only be used to build descriptors for resolution.
+func GetResolvableFileDescriptorProtos(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) []*descriptorpb.FileDescriptorProto {
+	var resolvableFileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		if fileDescriptorProto.GetSyntax() != SyntaxEditions {
+			if resolvableFileDescriptorProtos != nil {
+				resolvableFileDescriptorProtos[i] = fileDescriptorProto
+			}
+			continue
+		}
+		if resolvableFileDescriptorProtos == nil {
+			resolvableFileDescriptorProtos = make([]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+			copy(resolvableFileDescriptorProtos, fileDescriptorProtos[:i])
+		}
+		resolvableFileDescriptorProto := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+		resolvableFileDescriptorProto.Syntax = proto.String("proto2")
+		resolvableFileDescriptorProtos[i] = resolvableFileDescriptorProto
+	}
+	if resolvableFileDescriptorProtos == nil {
+		return fileDescriptorProtos
+	}
+	return resolvableFileDescriptorProtos
+}
+
+type featureDefinition struct {
+	name   string
+	values map[uint64]string
+}
+
+// rangeUnknownFields calls f for each field in the raw fields, with the
+// value starting after the tag.
+func rangeUnknownFields(
+	rawFields protoreflect.RawFields,
+	f func(protowire.Number, protowire.Type, []byte) error,
+) error {
+	data := []byte(rawFields)
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := f(number, wireType, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
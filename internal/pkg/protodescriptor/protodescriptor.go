@@ -37,6 +37,25 @@ func ValidateFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescripto
 	return nil
 }
 
+// InternMinFileDescriptorProtos is the number of FileDescriptorProtos at which
+// interning their strings is worth the extra pass.
+//
+// Smaller sets do not repeat enough strings to make up for the map of interned strings.
+const InternMinFileDescriptorProtos = 256
+
+// InternFileDescriptorProtoStrings deduplicates the strings that are commonly repeated
+// across the FileDescriptorProtos, such as dependencies, packages, field names, JSON names,
+// and type names, so that equal strings share the same backing memory.
+//
+// This modifies the FileDescriptorProtos in place, but does not change their values.
+// This only reduces the memory retained by large Images.
+func InternFileDescriptorProtoStrings(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) {
+	interner := newStringInterner()
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		interner.internFileDescriptorProto(fileDescriptorProto)
+	}
+}
+
 // ValidateFileDescriptorProtos validates the FileDescriptorProtos.
 func ValidateFileDescriptorProtos(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) error {
 	for _, fileDescriptorProto := range fileDescriptorProtos {
@@ -119,3 +138,107 @@ func ValidateProtoPaths(name string, paths []string) error {
 	}
 	return nil
 }
+
+type stringInterner struct {
+	values map[string]string
+}
+
+func newStringInterner() *stringInterner {
+	return &stringInterner{
+		values: make(map[string]string),
+	}
+}
+
+// intern replaces the value of the string pointer with an equal string that shares
+// backing memory with all other equal interned strings.
+//
+// The pointer itself is not shared, so that the messages remain independent.
+func (s *stringInterner) intern(value *string) {
+	if value == nil {
+		return
+	}
+	if interned, ok := s.values[*value]; ok {
+		*value = interned
+		return
+	}
+	s.values[*value] = *value
+}
+
+func (s *stringInterner) internSlice(values []string) {
+	for i := range values {
+		s.intern(&values[i])
+	}
+}
+
+func (s *stringInterner) internFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	if fileDescriptorProto == nil {
+		return
+	}
+	s.intern(fileDescriptorProto.Name)
+	s.intern(fileDescriptorProto.Package)
+	s.intern(fileDescriptorProto.Syntax)
+	s.internSlice(fileDescriptorProto.Dependency)
+	if options := fileDescriptorProto.Options; options != nil {
+		s.intern(options.GoPackage)
+		s.intern(options.JavaPackage)
+		s.intern(options.CsharpNamespace)
+		s.intern(options.ObjcClassPrefix)
+		s.intern(options.PhpNamespace)
+		s.intern(options.PhpMetadataNamespace)
+		s.intern(options.RubyPackage)
+		s.intern(options.SwiftPrefix)
+	}
+	for _, descriptorProto := range fileDescriptorProto.MessageType {
+		s.internDescriptorProto(descriptorProto)
+	}
+	for _, enumDescriptorProto := range fileDescriptorProto.EnumType {
+		s.internEnumDescriptorProto(enumDescriptorProto)
+	}
+	for _, fieldDescriptorProto := range fileDescriptorProto.Extension {
+		s.internFieldDescriptorProto(fieldDescriptorProto)
+	}
+	for _, serviceDescriptorProto := range fileDescriptorProto.Service {
+		s.intern(serviceDescriptorProto.Name)
+		for _, methodDescriptorProto := range serviceDescriptorProto.Method {
+			s.intern(methodDescriptorProto.Name)
+			s.intern(methodDescriptorProto.InputType)
+			s.intern(methodDescriptorProto.OutputType)
+		}
+	}
+}
+
+func (s *stringInterner) internDescriptorProto(descriptorProto *descriptorpb.DescriptorProto) {
+	s.intern(descriptorProto.Name)
+	for _, fieldDescriptorProto := range descriptorProto.Field {
+		s.internFieldDescriptorProto(fieldDescriptorProto)
+	}
+	for _, fieldDescriptorProto := range descriptorProto.Extension {
+		s.internFieldDescriptorProto(fieldDescriptorProto)
+	}
+	for _, nestedDescriptorProto := range descriptorProto.NestedType {
+		s.internDescriptorProto(nestedDescriptorProto)
+	}
+	for _, enumDescriptorProto := range descriptorProto.EnumType {
+		s.internEnumDescriptorProto(enumDescriptorProto)
+	}
+	for _, oneofDescriptorProto := range descriptorProto.OneofDecl {
+		s.intern(oneofDescriptorProto.Name)
+	}
+	s.internSlice(descriptorProto.ReservedName)
+}
+
+func (s *stringInterner) internFieldDescriptorProto(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) {
+	s.intern(fieldDescriptorProto.Name)
+	s.intern(fieldDescriptorProto.JsonName)
+	s.intern(fieldDescriptorProto.TypeName)
+	s.intern(fieldDescriptorProto.Extendee)
+	s.intern(fieldDescriptorProto.DefaultValue)
+}
+
+func (s *stringInterner) internEnumDescriptorProto(enumDescriptorProto *descriptorpb.EnumDescriptorProto) {
+	s.intern(enumDescriptorProto.Name)
+	for _, enumValueDescriptorProto := range enumDescriptorProto.Value {
+		s.intern(enumValueDescriptorProto.Name)
+	}
+	s.internSlice(enumDescriptorProto.ReservedName)
+}
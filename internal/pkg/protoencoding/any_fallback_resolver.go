@@ -0,0 +1,126 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type anyFallbackResolver struct {
+	delegate    Resolver
+	anyFallback AnyFallback
+
+	// full name to message type for AnyFallbackDiscard
+	emptyMessageTypes map[protoreflect.FullName]protoreflect.MessageType
+	lock              sync.Mutex
+}
+
+// newAnyFallbackResolver returns a new Resolver that handles type URLs that
+// cannot be resolved by the delegate according to the AnyFallback.
+//
+// delegate can be nil, in which case the global types are used, as is done
+// by protojson when no resolver is given.
+func newAnyFallbackResolver(delegate Resolver, anyFallback AnyFallback) Resolver {
+	if delegate == nil {
+		delegate = protoregistry.GlobalTypes
+	}
+	switch anyFallback {
+	case 0, AnyFallbackError:
+		return delegate
+	default:
+		return &anyFallbackResolver{
+			delegate:          delegate,
+			anyFallback:       anyFallback,
+			emptyMessageTypes: make(map[protoreflect.FullName]protoreflect.MessageType),
+		}
+	}
+}
+
+func (r *anyFallbackResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	return r.delegate.FindExtensionByName(field)
+}
+
+func (r *anyFallbackResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	return r.delegate.FindExtensionByNumber(message, field)
+}
+
+func (r *anyFallbackResolver) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	return r.delegate.FindMessageByName(message)
+}
+
+func (r *anyFallbackResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	messageType, err := r.delegate.FindMessageByURL(url)
+	if !errors.Is(err, protoregistry.NotFound) {
+		return messageType, err
+	}
+	if r.delegate != protoregistry.GlobalTypes {
+		messageType, err = protoregistry.GlobalTypes.FindMessageByURL(url)
+		if !errors.Is(err, protoregistry.NotFound) {
+			return messageType, err
+		}
+	}
+	switch r.anyFallback {
+	case AnyFallbackGlobalTypes:
+		return nil, err
+	case AnyFallbackDiscard:
+		return r.getEmptyMessageType(url)
+	default:
+		return nil, fmt.Errorf("unknown AnyFallback: %v", r.anyFallback)
+	}
+}
+
+func (r *anyFallbackResolver) getEmptyMessageType(url string) (protoreflect.MessageType, error) {
+	fullName := protoreflect.FullName(url)
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		fullName = fullName[i+1:]
+	}
+	if !fullName.IsValid() {
+		return nil, fmt.Errorf("invalid type URL: %q", url)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if messageType, ok := r.emptyMessageTypes[fullName]; ok {
+		return messageType, nil
+	}
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String(strings.ReplaceAll(string(fullName), ".", "/") + ".proto"),
+		Syntax: proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String(string(fullName.Name())),
+			},
+		},
+	}
+	if parent := fullName.Parent(); parent != "" {
+		fileDescriptorProto.Package = proto.String(string(parent))
+	}
+	fileDescriptor, err := protodesc.NewFile(fileDescriptorProto, nil)
+	if err != nil {
+		return nil, err
+	}
+	messageType := dynamicpb.NewMessageType(fileDescriptor.Messages().Get(0))
+	r.emptyMessageTypes[fullName] = messageType
+	return messageType, nil
+}
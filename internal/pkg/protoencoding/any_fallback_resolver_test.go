@@ -0,0 +1,93 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONMarshalAnyFallback(t *testing.T) {
+	t.Parallel()
+	resolver := testNewResolver(t)
+
+	unknownAny := &anypb.Any{
+		TypeUrl: "type.googleapis.com/foo.v1.Bar",
+		Value:   []byte{0x08, 0x01},
+	}
+	_, err := NewJSONMarshaler(resolver).Marshal(unknownAny)
+	require.Error(t, err)
+	_, err = NewJSONMarshaler(resolver, JSONMarshalerWithAnyFallback(AnyFallbackGlobalTypes)).Marshal(unknownAny)
+	require.Error(t, err)
+	data, err := NewJSONMarshaler(resolver, JSONMarshalerWithAnyFallback(AnyFallbackDiscard)).Marshal(unknownAny)
+	require.NoError(t, err)
+	require.Equal(t, `{"@type":"type.googleapis.com/foo.v1.Bar"}`, string(data))
+
+	wrapperAny, err := anypb.New(wrapperspb.String("hello"))
+	require.NoError(t, err)
+	_, err = NewJSONMarshaler(resolver).Marshal(wrapperAny)
+	require.Error(t, err)
+	data, err = NewJSONMarshaler(resolver, JSONMarshalerWithAnyFallback(AnyFallbackGlobalTypes)).Marshal(wrapperAny)
+	require.NoError(t, err)
+	require.Equal(t, `{"@type":"type.googleapis.com/google.protobuf.StringValue","value":"hello"}`, string(data))
+}
+
+func TestJSONUnmarshalAnyFallback(t *testing.T) {
+	t.Parallel()
+	resolver := testNewResolver(t)
+
+	data := []byte(`{"@type":"type.googleapis.com/foo.v1.Bar","baz":1}`)
+	require.Error(t, NewJSONUnmarshaler(resolver).Unmarshal(data, &anypb.Any{}))
+	unknownAny := &anypb.Any{}
+	require.NoError(t, NewJSONUnmarshaler(resolver, JSONUnmarshalerWithAnyFallback(AnyFallbackDiscard)).Unmarshal(data, unknownAny))
+	require.Equal(t, "type.googleapis.com/foo.v1.Bar", unknownAny.GetTypeUrl())
+	require.Empty(t, unknownAny.GetValue())
+}
+
+func TestParseAnyFallback(t *testing.T) {
+	t.Parallel()
+	for _, s := range AllAnyFallbackStrings {
+		anyFallback, err := ParseAnyFallback(s)
+		require.NoError(t, err)
+		require.Equal(t, s, anyFallback.String())
+	}
+	anyFallback, err := ParseAnyFallback("")
+	require.NoError(t, err)
+	require.Equal(t, AnyFallbackError, anyFallback)
+	_, err = ParseAnyFallback("foo")
+	require.Error(t, err)
+}
+
+func testNewResolver(t *testing.T) Resolver {
+	resolver, err := NewResolver(
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("a.proto"),
+			Package: proto.String("a"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("A"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	return resolver
+}
@@ -24,17 +24,23 @@ import (
 )
 
 type jsonMarshaler struct {
-	resolver      Resolver
-	indent        string
-	useProtoNames bool
+	resolver        Resolver
+	indent          string
+	useProtoNames   bool
+	emitUnpopulated bool
+	useEnumNumbers  bool
+	canonical       bool
+	anyFallback     AnyFallback
 }
 
-func newJSONMarshaler(resolver Resolver, indent string, useProtoNames bool) Marshaler {
-	return &jsonMarshaler{
-		resolver:      resolver,
-		indent:        indent,
-		useProtoNames: useProtoNames,
+func newJSONMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshaler {
+	jsonMarshaler := &jsonMarshaler{
+		resolver: resolver,
 	}
+	for _, option := range options {
+		option(jsonMarshaler)
+	}
+	return jsonMarshaler
 }
 
 func (m *jsonMarshaler) Marshal(message proto.Message) ([]byte, error) {
@@ -42,14 +48,21 @@ func (m *jsonMarshaler) Marshal(message proto.Message) ([]byte, error) {
 		return nil, err
 	}
 	options := protojson.MarshalOptions{
-		Resolver:      m.resolver,
-		Indent:        m.indent,
-		UseProtoNames: m.useProtoNames,
+		Resolver:        newAnyFallbackResolver(m.resolver, m.anyFallback),
+		UseProtoNames:   m.useProtoNames,
+		EmitUnpopulated: m.emitUnpopulated,
+		UseEnumNumbers:  m.useEnumNumbers,
 	}
 	data, err := options.Marshal(message)
 	if err != nil {
 		return nil, err
 	}
+	if m.canonical {
+		data, err = canonicalizeJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	}
 	// This is needed due to the instability of protojson output.
 	//
 	// https://github.com/golang/protobuf/issues/1121
@@ -58,13 +71,44 @@ func (m *jsonMarshaler) Marshal(message proto.Message) ([]byte, error) {
 	//
 	// We may need to do a full encoding/json encode/decode in the future if protojson
 	// produces non-deterministic output.
+	//
+	// We do the indenting ourselves for the same reason, as protojson will
+	// randomly add spaces when indenting as well.
 	buffer := bytes.NewBuffer(nil)
+	if m.indent != "" {
+		if err := json.Indent(buffer, data, "", m.indent); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
 	if err := json.Compact(buffer, data); err != nil {
 		return nil, err
 	}
 	return buffer.Bytes(), nil
 }
 
+// canonicalizeJSON re-encodes the JSON data with all object keys sorted.
+//
+// Numbers are kept as the literal protojson produced, which is already
+// the shortest round-trip representation for floats and quoted for 64-bit
+// integers, and HTML characters are not escaped so that strings are not rewritten.
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	// encoding/json always sorts map keys
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
 func reparseUnrecognized(resolver Resolver, reflectMessage protoreflect.Message) error {
 	if resolver == nil {
 		return nil
@@ -20,18 +20,23 @@ import (
 )
 
 type jsonUnmarshaler struct {
-	resolver Resolver
+	resolver    Resolver
+	anyFallback AnyFallback
 }
 
-func newJSONUnmarshaler(resolver Resolver) Unmarshaler {
-	return &jsonUnmarshaler{
+func newJSONUnmarshaler(resolver Resolver, options ...JSONUnmarshalerOption) Unmarshaler {
+	jsonUnmarshaler := &jsonUnmarshaler{
 		resolver: resolver,
 	}
+	for _, option := range options {
+		option(jsonUnmarshaler)
+	}
+	return jsonUnmarshaler
 }
 
 func (m *jsonUnmarshaler) Unmarshal(data []byte, message proto.Message) error {
 	options := protojson.UnmarshalOptions{
-		Resolver: m.resolver,
+		Resolver: newAnyFallbackResolver(m.resolver, m.anyFallback),
 		// TODO: make this an option
 		DiscardUnknown: true,
 	}
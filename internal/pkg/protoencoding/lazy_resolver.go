@@ -0,0 +1,139 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type lazyResolver struct {
+	getFileDescriptorProtos func() []*descriptorpb.FileDescriptorProto
+
+	files *protoregistry.Files
+	types *protoregistry.Types
+	// the number of FileDescriptorProtos added to files and types
+	numAdded int
+	// the lookups that did not find a type
+	notFoundFinds []func() error
+	lock          sync.Mutex
+}
+
+func newLazyResolver(getFileDescriptorProtos func() []*descriptorpb.FileDescriptorProto) *lazyResolver {
+	return &lazyResolver{
+		getFileDescriptorProtos: getFileDescriptorProtos,
+		files:                   &protoregistry.Files{},
+		types:                   &protoregistry.Types{},
+	}
+}
+
+func (r *lazyResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	var extensionType protoreflect.ExtensionType
+	err := r.find(func() error {
+		var err error
+		extensionType, err = r.types.FindExtensionByName(field)
+		return err
+	})
+	return extensionType, err
+}
+
+func (r *lazyResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	var extensionType protoreflect.ExtensionType
+	err := r.find(func() error {
+		var err error
+		extensionType, err = r.types.FindExtensionByNumber(message, field)
+		return err
+	})
+	return extensionType, err
+}
+
+func (r *lazyResolver) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	var messageType protoreflect.MessageType
+	err := r.find(func() error {
+		var err error
+		messageType, err = r.types.FindMessageByName(message)
+		return err
+	})
+	return messageType, err
+}
+
+func (r *lazyResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	var messageType protoreflect.MessageType
+	err := r.find(func() error {
+		var err error
+		messageType, err = r.types.FindMessageByURL(url)
+		return err
+	})
+	return messageType, err
+}
+
+func (r *lazyResolver) Incomplete() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.notFoundFinds) == 0 {
+		return false
+	}
+	if err := r.add(); err != nil {
+		// redoing with a Resolver for all FileDescriptorProtos will
+		// return the error
+		return true
+	}
+	for _, find := range r.notFoundFinds {
+		if err := find(); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// find adds the available FileDescriptorProtos and then calls f, recording
+// if the type was not found.
+func (r *lazyResolver) find(f func() error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.add(); err != nil {
+		return err
+	}
+	err := f()
+	if errors.Is(err, protoregistry.NotFound) {
+		r.notFoundFinds = append(r.notFoundFinds, f)
+	}
+	return err
+}
+
+func (r *lazyResolver) add() error {
+	fileDescriptorProtos := r.getFileDescriptorProtos()
+	for ; r.numAdded < len(fileDescriptorProtos); r.numAdded++ {
+		fileDescriptor, err := protodesc.FileOptions{
+			AllowUnresolvable: true,
+		}.New(protodescriptor.GetResolvableFileDescriptorProtos(fileDescriptorProtos[r.numAdded])[0], r.files)
+		if err != nil {
+			return err
+		}
+		if err := r.files.RegisterFile(fileDescriptor); err != nil {
+			return err
+		}
+		if err := addFileToTypes(r.types, fileDescriptor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
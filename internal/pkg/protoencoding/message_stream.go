@@ -0,0 +1,147 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type jsonLinesMessageReader struct {
+	reader      *bufio.Reader
+	unmarshaler Unmarshaler
+}
+
+func newJSONLinesMessageReader(reader io.Reader, resolver Resolver, options ...JSONUnmarshalerOption) *jsonLinesMessageReader {
+	return &jsonLinesMessageReader{
+		reader:      bufio.NewReader(reader),
+		unmarshaler: newJSONUnmarshaler(resolver, options...),
+	}
+}
+
+func (r *jsonLinesMessageReader) Read(message proto.Message) error {
+	for {
+		// we do not use a bufio.Scanner as it has a maximum token size
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			return r.unmarshaler.Unmarshal(trimmed, message)
+		}
+		if err != nil {
+			// io.EOF with no remaining data
+			return err
+		}
+	}
+}
+
+type jsonLinesMessageWriter struct {
+	writer    io.Writer
+	marshaler Marshaler
+}
+
+func newJSONLinesMessageWriter(writer io.Writer, resolver Resolver, options ...JSONMarshalerOption) *jsonLinesMessageWriter {
+	return &jsonLinesMessageWriter{
+		writer: writer,
+		// each message must be on a single line, so we override any indent
+		marshaler: newJSONMarshaler(resolver, append(options, JSONMarshalerWithIndent(""))...),
+	}
+}
+
+func (w *jsonLinesMessageWriter) Write(message proto.Message) error {
+	data, err := w.marshaler.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(append(data, '\n'))
+	return err
+}
+
+type lengthPrefixedMessageReader struct {
+	reader      *bufio.Reader
+	unmarshaler Unmarshaler
+	buffer      []byte
+}
+
+func newLengthPrefixedMessageReader(reader io.Reader, resolver Resolver) *lengthPrefixedMessageReader {
+	return &lengthPrefixedMessageReader{
+		reader:      bufio.NewReader(reader),
+		unmarshaler: newWireUnmarshaler(resolver),
+	}
+}
+
+func (r *lengthPrefixedMessageReader) Read(message proto.Message) error {
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			// clean end of stream
+			return io.EOF
+		}
+		return fmt.Errorf("could not read length prefix: %w", err)
+	}
+	if size > math.MaxInt32 {
+		return fmt.Errorf("length prefix %d exceeds maximum message size", size)
+	}
+	if uint64(cap(r.buffer)) < size {
+		r.buffer = make([]byte, size)
+	}
+	data := r.buffer[:size]
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return fmt.Errorf("could not read message of length %d: %w", size, unexpectedEOF(err))
+	}
+	return r.unmarshaler.Unmarshal(data, message)
+}
+
+type lengthPrefixedMessageWriter struct {
+	writer    io.Writer
+	marshaler Marshaler
+	prefix    []byte
+}
+
+func newLengthPrefixedMessageWriter(writer io.Writer) *lengthPrefixedMessageWriter {
+	return &lengthPrefixedMessageWriter{
+		writer:    writer,
+		marshaler: newWireMarshaler(),
+		prefix:    make([]byte, binary.MaxVarintLen64),
+	}
+}
+
+func (w *lengthPrefixedMessageWriter) Write(message proto.Message) error {
+	data, err := w.marshaler.Marshal(message)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(w.prefix, uint64(len(data)))
+	if _, err := w.writer.Write(w.prefix[:n]); err != nil {
+		return err
+	}
+	_, err = w.writer.Write(data)
+	return err
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONLinesMessageStream(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	writer := NewJSONLinesMessageWriter(buffer, nil, JSONMarshalerWithIndent("  "))
+	for _, value := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, writer.Write(wrapperspb.String(value)))
+	}
+	require.Equal(t, "\"foo\"\n\"bar\"\n\"baz\"\n", buffer.String())
+	// blank lines are skipped and the final newline is optional
+	reader := NewJSONLinesMessageReader(strings.NewReader(strings.TrimSuffix(buffer.String(), "\n")+"\n\n\"qux\""), nil)
+	require.Equal(t, []string{"foo", "bar", "baz", "qux"}, testReadStringValues(t, reader))
+}
+
+func TestLengthPrefixedMessageStream(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	writer := NewLengthPrefixedMessageWriter(buffer)
+	values := []string{"foo", "", strings.Repeat("a", 300)}
+	for _, value := range values {
+		require.NoError(t, writer.Write(wrapperspb.String(value)))
+	}
+	data := buffer.Bytes()
+	require.Equal(t, values, testReadStringValues(t, NewLengthPrefixedMessageReader(bytes.NewReader(data), nil)))
+
+	reader := NewLengthPrefixedMessageReader(bytes.NewReader(data[:len(data)-1]), nil)
+	stringValue := &wrapperspb.StringValue{}
+	require.NoError(t, reader.Read(stringValue))
+	require.NoError(t, reader.Read(stringValue))
+	require.True(t, errors.Is(reader.Read(stringValue), io.ErrUnexpectedEOF))
+}
+
+func testReadStringValues(t *testing.T, reader MessageReader) []string {
+	var values []string
+	for {
+		stringValue := &wrapperspb.StringValue{}
+		err := reader.Read(stringValue)
+		if err == io.EOF {
+			return values
+		}
+		require.NoError(t, err)
+		values = append(values, stringValue.GetValue())
+	}
+}
@@ -15,6 +15,10 @@
 package protoencoding
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -33,8 +37,127 @@ type Resolver interface {
 // If the input slice is empty, this returns nil
 // The given FileDescriptorProtos must be self-contained, that is they must contain all imports.
 // This can NOT be guaranteed for FileDescriptorSets given over the wire, and can only be guaranteed from builds.
+//
+// Imports and types that are not contained in the FileDescriptorProtos are
+// allowed, and references to them are left unresolved.
 func NewResolver(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) (Resolver, error) {
-	return newResolver(fileDescriptorProtos...)
+	return newResolver(false, fileDescriptorProtos...)
+}
+
+// NewStrictResolver creates a new Resolver that returns an error if the
+// given FileDescriptorProtos are not self-contained.
+//
+// The error lists all imports and types that could not be resolved.
+// If the input slice is empty, this returns nil.
+func NewStrictResolver(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) (Resolver, error) {
+	return newResolver(true, fileDescriptorProtos...)
+}
+
+// LazyResolver is a Resolver that adds the types of FileDescriptorProtos as
+// they become available.
+type LazyResolver interface {
+	Resolver
+
+	// Incomplete returns true if a type was not found at the time it was
+	// looked up, but is contained in a FileDescriptorProto that became
+	// available later.
+	//
+	// If so, whatever used the LazyResolver should be redone with a Resolver
+	// for all of the FileDescriptorProtos.
+	Incomplete() bool
+}
+
+// NewLazyResolver returns a new LazyResolver.
+//
+// On each lookup, the FileDescriptorProtos returned by getFileDescriptorProtos
+// that have not been added yet are added. getFileDescriptorProtos must only
+// append to what it returned previously. This allows a message containing
+// FileDescriptorProtos, such as an Image, to be unmarshaled in a single pass
+// while resolving extensions and types in the files already unmarshaled, as
+// long as files come after the files they import.
+//
+// Unresolvable imports and types are allowed, as with NewResolver.
+func NewLazyResolver(getFileDescriptorProtos func() []*descriptorpb.FileDescriptorProto) LazyResolver {
+	return newLazyResolver(getFileDescriptorProtos)
+}
+
+// AnyFallback says what to do with google.protobuf.Any values whose type URL
+// cannot be resolved.
+type AnyFallback int
+
+const (
+	// AnyFallbackError results in an error for Any values with unknown types.
+	//
+	// This is the default.
+	AnyFallbackError AnyFallback = iota + 1
+	// AnyFallbackGlobalTypes falls back to the types compiled into this binary,
+	// such as the well-known types, and errors if the type is still not found.
+	AnyFallbackGlobalTypes
+	// AnyFallbackDiscard falls back to the types compiled into this binary,
+	// and if the type is still not found, discards the contents of the Any,
+	// keeping only the type URL.
+	AnyFallbackDiscard
+)
+
+var (
+	// AllAnyFallbackStrings are all AnyFallback strings.
+	AllAnyFallbackStrings = []string{
+		"error",
+		"global-types",
+		"discard",
+	}
+
+	anyFallbackToString = map[AnyFallback]string{
+		AnyFallbackError:       "error",
+		AnyFallbackGlobalTypes: "global-types",
+		AnyFallbackDiscard:     "discard",
+	}
+	stringToAnyFallback = map[string]AnyFallback{
+		"error":        AnyFallbackError,
+		"global-types": AnyFallbackGlobalTypes,
+		"discard":      AnyFallbackDiscard,
+	}
+)
+
+// String implements fmt.Stringer.
+func (a AnyFallback) String() string {
+	s, ok := anyFallbackToString[a]
+	if !ok {
+		return fmt.Sprintf("%d", a)
+	}
+	return s
+}
+
+// ParseAnyFallback parses the AnyFallback.
+//
+// The empty string defaults to AnyFallbackError.
+func ParseAnyFallback(s string) (AnyFallback, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return AnyFallbackError, nil
+	}
+	anyFallback, ok := stringToAnyFallback[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown any fallback: %q", s)
+	}
+	return anyFallback, nil
+}
+
+// ResolveUnknownFields re-parses the unknown fields of the message and all
+// of its nested messages using the resolver, in place.
+//
+// This allows a message to be unmarshaled once without a resolver, and then
+// have the extensions it contains resolved after the fact, for example once
+// the FileDescriptorProtos that define custom options have been read.
+// Unknown fields that still cannot be resolved are kept as unknown fields.
+func ResolveUnknownFields(resolver Resolver, message proto.Message) error {
+	return resolveUnknownFields(
+		proto.UnmarshalOptions{
+			Merge:    true,
+			Resolver: resolver,
+		},
+		message.ProtoReflect(),
+	)
 }
 
 // Marshaler marshals Messages.
@@ -54,8 +177,8 @@ func NewWireMarshaler() Marshaler {
 //
 // This has the potential to be unstable over time.
 // resolver can be nil if unknown and are only needed for extensions.
-func NewJSONMarshaler(resolver Resolver) Marshaler {
-	return newJSONMarshaler(resolver, "", false)
+func NewJSONMarshaler(resolver Resolver, options ...JSONMarshalerOption) Marshaler {
+	return newJSONMarshaler(resolver, options...)
 }
 
 // NewJSONMarshalerIndent returns a new Marshaler for JSON with indents.
@@ -63,7 +186,7 @@ func NewJSONMarshaler(resolver Resolver) Marshaler {
 // This has the potential to be unstable over time.
 // resolver can be nil if unknown and are only needed for extensions.
 func NewJSONMarshalerIndent(resolver Resolver) Marshaler {
-	return newJSONMarshaler(resolver, "  ", false)
+	return newJSONMarshaler(resolver, JSONMarshalerWithIndent("  "))
 }
 
 // NewJSONMarshalerUseProtoNames returns a new Marshaler for JSON using the proto names for keys.
@@ -71,7 +194,91 @@ func NewJSONMarshalerIndent(resolver Resolver) Marshaler {
 // This has the potential to be unstable over time.
 // resolver can be nil if unknown and are only needed for extensions.
 func NewJSONMarshalerUseProtoNames(resolver Resolver) Marshaler {
-	return newJSONMarshaler(resolver, "", true)
+	return newJSONMarshaler(resolver, JSONMarshalerWithUseProtoNames())
+}
+
+// JSONMarshalerOption is an option for a new JSON Marshaler.
+type JSONMarshalerOption func(*jsonMarshaler)
+
+// JSONMarshalerWithIndent returns a new JSONMarshalerOption that indents the
+// output with the given indent.
+//
+// The default is to output compact JSON.
+func JSONMarshalerWithIndent(indent string) JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.indent = indent
+	}
+}
+
+// JSONMarshalerWithUseProtoNames returns a new JSONMarshalerOption that uses
+// the proto field names for keys instead of the lowerCamelCase JSON names.
+func JSONMarshalerWithUseProtoNames() JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.useProtoNames = true
+	}
+}
+
+// JSONMarshalerWithEmitUnpopulated returns a new JSONMarshalerOption that
+// emits fields that are not populated with their default values.
+func JSONMarshalerWithEmitUnpopulated() JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.emitUnpopulated = true
+	}
+}
+
+// JSONMarshalerWithUseEnumNumbers returns a new JSONMarshalerOption that
+// emits enum values as numbers instead of as their string names.
+func JSONMarshalerWithUseEnumNumbers() JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.useEnumNumbers = true
+	}
+}
+
+// JSONMarshalerWithCanonical returns a new JSONMarshalerOption that outputs
+// canonical JSON, with all object keys sorted.
+//
+// Two semantically identical messages marshaled with the same options will
+// result in byte-for-byte identical output, including messages with Any
+// fields and extensions.
+func JSONMarshalerWithCanonical() JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.canonical = true
+	}
+}
+
+// JSONMarshalerWithAnyFallback returns a new JSONMarshalerOption that uses the
+// given AnyFallback for google.protobuf.Any values whose type URL cannot be
+// resolved.
+//
+// The default is AnyFallbackError.
+func JSONMarshalerWithAnyFallback(anyFallback AnyFallback) JSONMarshalerOption {
+	return func(jsonMarshaler *jsonMarshaler) {
+		jsonMarshaler.anyFallback = anyFallback
+	}
+}
+
+// NewTextMarshaler returns a new Marshaler for the protobuf text format.
+//
+// The output is multi-line and indented.
+// This has the potential to be unstable over time, as prototext deliberately
+// randomizes whitespace.
+// resolver can be nil if unknown and are only needed for extensions.
+func NewTextMarshaler(resolver Resolver, options ...TextMarshalerOption) Marshaler {
+	return newTextMarshaler(resolver, options...)
+}
+
+// TextMarshalerOption is an option for a new text Marshaler.
+type TextMarshalerOption func(*textMarshaler)
+
+// TextMarshalerWithAnyFallback returns a new TextMarshalerOption that uses the
+// given AnyFallback for google.protobuf.Any values whose type URL cannot be
+// resolved.
+//
+// The default is AnyFallbackError.
+func TextMarshalerWithAnyFallback(anyFallback AnyFallback) TextMarshalerOption {
+	return func(textMarshaler *textMarshaler) {
+		textMarshaler.anyFallback = anyFallback
+	}
 }
 
 // Unmarshaler unmarshals Messages.
@@ -89,6 +296,87 @@ func NewWireUnmarshaler(resolver Resolver) Unmarshaler {
 // NewJSONUnmarshaler returns a new Unmarshaler for json.
 //
 // resolver can be nil if unknown and are only needed for extensions.
-func NewJSONUnmarshaler(resolver Resolver) Unmarshaler {
-	return newJSONUnmarshaler(resolver)
+func NewJSONUnmarshaler(resolver Resolver, options ...JSONUnmarshalerOption) Unmarshaler {
+	return newJSONUnmarshaler(resolver, options...)
+}
+
+// JSONUnmarshalerOption is an option for a new JSON Unmarshaler.
+type JSONUnmarshalerOption func(*jsonUnmarshaler)
+
+// JSONUnmarshalerWithAnyFallback returns a new JSONUnmarshalerOption that uses
+// the given AnyFallback for google.protobuf.Any values whose type URL cannot be
+// resolved.
+//
+// The default is AnyFallbackError.
+func JSONUnmarshalerWithAnyFallback(anyFallback AnyFallback) JSONUnmarshalerOption {
+	return func(jsonUnmarshaler *jsonUnmarshaler) {
+		jsonUnmarshaler.anyFallback = anyFallback
+	}
+}
+
+// NewTextUnmarshaler returns a new Unmarshaler for the protobuf text format.
+//
+// resolver can be nil if unknown and are only needed for extensions.
+func NewTextUnmarshaler(resolver Resolver, options ...TextUnmarshalerOption) Unmarshaler {
+	return newTextUnmarshaler(resolver, options...)
+}
+
+// TextUnmarshalerOption is an option for a new text Unmarshaler.
+type TextUnmarshalerOption func(*textUnmarshaler)
+
+// TextUnmarshalerWithAnyFallback returns a new TextUnmarshalerOption that uses
+// the given AnyFallback for google.protobuf.Any values whose type URL cannot be
+// resolved.
+//
+// The default is AnyFallbackError.
+func TextUnmarshalerWithAnyFallback(anyFallback AnyFallback) TextUnmarshalerOption {
+	return func(textUnmarshaler *textUnmarshaler) {
+		textUnmarshaler.anyFallback = anyFallback
+	}
+}
+
+// MessageReader reads a stream of Messages.
+type MessageReader interface {
+	// Read reads the next Message in the stream into message.
+	//
+	// Returns io.EOF when there are no more Messages.
+	Read(message proto.Message) error
+}
+
+// NewJSONLinesMessageReader returns a new MessageReader for newline-delimited JSON.
+//
+// Each non-empty line is a single JSON Message.
+// resolver can be nil if unknown and are only needed for extensions.
+func NewJSONLinesMessageReader(reader io.Reader, resolver Resolver, options ...JSONUnmarshalerOption) MessageReader {
+	return newJSONLinesMessageReader(reader, resolver, options...)
+}
+
+// NewLengthPrefixedMessageReader returns a new MessageReader for length-prefixed wire records.
+//
+// Each record is a varint length followed by a wire-encoded Message of that length.
+// This is the same format as writeDelimitedTo in the Java and C++ protobuf libraries.
+// resolver can be nil if unknown and are only needed for extensions.
+func NewLengthPrefixedMessageReader(reader io.Reader, resolver Resolver) MessageReader {
+	return newLengthPrefixedMessageReader(reader, resolver)
+}
+
+// MessageWriter writes a stream of Messages.
+type MessageWriter interface {
+	// Write writes the Message to the stream.
+	Write(message proto.Message) error
+}
+
+// NewJSONLinesMessageWriter returns a new MessageWriter for newline-delimited JSON.
+//
+// Any indent given with the options is ignored, as each Message must be on a single line.
+// resolver can be nil if unknown and are only needed for extensions.
+func NewJSONLinesMessageWriter(writer io.Writer, resolver Resolver, options ...JSONMarshalerOption) MessageWriter {
+	return newJSONLinesMessageWriter(writer, resolver, options...)
+}
+
+// NewLengthPrefixedMessageWriter returns a new MessageWriter for length-prefixed wire records.
+//
+// See NewLengthPrefixedMessageReader for a description of the format.
+func NewLengthPrefixedMessageWriter(writer io.Writer) MessageWriter {
+	return newLengthPrefixedMessageWriter(writer)
 }
@@ -15,6 +15,11 @@
 package protoencoding
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -22,35 +27,34 @@ import (
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
-func newResolver(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) (Resolver, error) {
+func newResolver(strict bool, fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) (Resolver, error) {
 	if len(fileDescriptorProtos) == 0 {
 		return nil, nil
 	}
-	// TODO: handle if resolvable
+	// we always allow unresolvable references when building the files, as
+	// protodesc fails on the first one, and strict resolution reports all of them
 	files, err := protodesc.FileOptions{
 		AllowUnresolvable: true,
 	}.NewFiles(
 		&descriptorpb.FileDescriptorSet{
-			File: fileDescriptorProtos,
+			File: protodescriptor.GetResolvableFileDescriptorProtos(fileDescriptorProtos...),
 		},
 	)
 	if err != nil {
 		return nil, err
 	}
+	if strict {
+		if err := checkResolved(files); err != nil {
+			return nil, err
+		}
+	}
 	types := &protoregistry.Types{}
 	var rangeErr error
 	files.RangeFiles(func(fileDescriptor protoreflect.FileDescriptor) bool {
-		if err := addMessagesToTypes(types, fileDescriptor.Messages()); err != nil {
-			rangeErr = err
-			return false
-		}
-		if err := addExtensionsToTypes(types, fileDescriptor.Extensions()); err != nil {
+		if err := addFileToTypes(types, fileDescriptor); err != nil {
 			rangeErr = err
 			return false
 		}
-		// There is no way to do register enum, and it is not used
-		// https://github.com/golang/protobuf/issues/1065
-		// https://godoc.org/google.golang.org/protobuf/types/dynamicpb does not have NewEnumType
 		return true
 	})
 	if rangeErr != nil {
@@ -59,6 +63,16 @@ func newResolver(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) (Res
 	return types, nil
 }
 
+func addFileToTypes(types *protoregistry.Types, fileDescriptor protoreflect.FileDescriptor) error {
+	if err := addMessagesToTypes(types, fileDescriptor.Messages()); err != nil {
+		return err
+	}
+	// There is no way to do register enum, and it is not used
+	// https://github.com/golang/protobuf/issues/1065
+	// https://godoc.org/google.golang.org/protobuf/types/dynamicpb does not have NewEnumType
+	return addExtensionsToTypes(types, fileDescriptor.Extensions())
+}
+
 func addMessagesToTypes(types *protoregistry.Types, messageDescriptors protoreflect.MessageDescriptors) error {
 	messagesLen := messageDescriptors.Len()
 	for i := 0; i < messagesLen; i++ {
@@ -86,3 +100,79 @@ func addExtensionsToTypes(types *protoregistry.Types, extensionDescriptors proto
 	}
 	return nil
 }
+
+// checkResolved returns an error listing the imports and types that are
+// referenced by the files but are not contained in them.
+func checkResolved(files *protoregistry.Files) error {
+	unresolvedImports := make(map[string]struct{})
+	unresolvedTypes := make(map[string]struct{})
+	addDescriptor := func(descriptor protoreflect.Descriptor) {
+		if descriptor != nil && descriptor.IsPlaceholder() {
+			unresolvedTypes[string(descriptor.FullName())] = struct{}{}
+		}
+	}
+	addField := func(fieldDescriptor protoreflect.FieldDescriptor) {
+		addDescriptor(fieldDescriptor.Message())
+		addDescriptor(fieldDescriptor.Enum())
+		if fieldDescriptor.IsExtension() {
+			addDescriptor(fieldDescriptor.ContainingMessage())
+		}
+	}
+	var addMessages func(protoreflect.MessageDescriptors)
+	addMessages = func(messageDescriptors protoreflect.MessageDescriptors) {
+		for i := 0; i < messageDescriptors.Len(); i++ {
+			messageDescriptor := messageDescriptors.Get(i)
+			fieldDescriptors := messageDescriptor.Fields()
+			for j := 0; j < fieldDescriptors.Len(); j++ {
+				addField(fieldDescriptors.Get(j))
+			}
+			extensionDescriptors := messageDescriptor.Extensions()
+			for j := 0; j < extensionDescriptors.Len(); j++ {
+				addField(extensionDescriptors.Get(j))
+			}
+			addMessages(messageDescriptor.Messages())
+		}
+	}
+	files.RangeFiles(func(fileDescriptor protoreflect.FileDescriptor) bool {
+		imports := fileDescriptor.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			if fileImport := imports.Get(i); fileImport.IsPlaceholder() {
+				unresolvedImports[fileImport.Path()] = struct{}{}
+			}
+		}
+		addMessages(fileDescriptor.Messages())
+		extensionDescriptors := fileDescriptor.Extensions()
+		for i := 0; i < extensionDescriptors.Len(); i++ {
+			addField(extensionDescriptors.Get(i))
+		}
+		serviceDescriptors := fileDescriptor.Services()
+		for i := 0; i < serviceDescriptors.Len(); i++ {
+			methodDescriptors := serviceDescriptors.Get(i).Methods()
+			for j := 0; j < methodDescriptors.Len(); j++ {
+				addDescriptor(methodDescriptors.Get(j).Input())
+				addDescriptor(methodDescriptors.Get(j).Output())
+			}
+		}
+		return true
+	})
+	var unresolved []string
+	for _, fileImport := range sortedKeys(unresolvedImports) {
+		unresolved = append(unresolved, fmt.Sprintf("import %q", fileImport))
+	}
+	for _, typeName := range sortedKeys(unresolvedTypes) {
+		unresolved = append(unresolved, fmt.Sprintf("type %s", typeName))
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("could not resolve %s", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewStrictResolver(t *testing.T) {
+	t.Parallel()
+	fileDescriptorProtos := []*descriptorpb.FileDescriptorProto{
+		{
+			Name:       proto.String("a.proto"),
+			Package:    proto.String("a"),
+			Dependency: []string{"b.proto", "c.proto"},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Foo"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:     proto.String("bar"),
+							Number:   proto.Int32(1),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+							TypeName: proto.String(".b.Bar"),
+						},
+						{
+							Name:     proto.String("baz"),
+							Number:   proto.Int32(2),
+							Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+							TypeName: proto.String(".c.Baz"),
+						},
+					},
+				},
+			},
+			Service: []*descriptorpb.ServiceDescriptorProto{
+				{
+					Name: proto.String("FooService"),
+					Method: []*descriptorpb.MethodDescriptorProto{
+						{
+							Name:       proto.String("Foo"),
+							InputType:  proto.String(".a.Foo"),
+							OutputType: proto.String(".c.Response"),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    proto.String("b.proto"),
+			Package: proto.String("b"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Bar"),
+				},
+			},
+		},
+	}
+	resolver, err := NewResolver(fileDescriptorProtos...)
+	require.NoError(t, err)
+	require.NotNil(t, resolver)
+	_, err = NewStrictResolver(fileDescriptorProtos...)
+	assert.EqualError(t, err, `could not resolve import "c.proto", type c.Baz, type c.Response`)
+	resolver, err = NewStrictResolver(fileDescriptorProtos[1])
+	require.NoError(t, err)
+	require.NotNil(t, resolver)
+}
+
+func TestLazyResolver(t *testing.T) {
+	t.Parallel()
+	descriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	extFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("ext.proto"),
+		Package:    proto.String("ext"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("foo"),
+				Number:   proto.Int32(50000),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Extendee: proto.String(".google.protobuf.MessageOptions"),
+			},
+		},
+	}
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	resolver := NewLazyResolver(
+		func() []*descriptorpb.FileDescriptorProto {
+			return fileDescriptorProtos
+		},
+	)
+	_, err := resolver.FindExtensionByName("ext.foo")
+	assert.True(t, errors.Is(err, protoregistry.NotFound))
+	_, err = resolver.FindExtensionByName("ext.bar")
+	assert.True(t, errors.Is(err, protoregistry.NotFound))
+	// nothing that was not found is available yet
+	assert.False(t, resolver.Incomplete())
+
+	fileDescriptorProtos = append(fileDescriptorProtos, descriptorFileDescriptorProto, extFileDescriptorProto)
+	// ext.foo is now available, but was not found when it was looked up
+	assert.True(t, resolver.Incomplete())
+	extensionType, err := resolver.FindExtensionByNumber("google.protobuf.MessageOptions", 50000)
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("ext.foo"), extensionType.TypeDescriptor().FullName())
+	messageType, err := resolver.FindMessageByURL("type.googleapis.com/google.protobuf.FileOptions")
+	require.NoError(t, err)
+	assert.Equal(t, protoreflect.FullName("google.protobuf.FileOptions"), messageType.Descriptor().FullName())
+
+	resolver = NewLazyResolver(
+		func() []*descriptorpb.FileDescriptorProto {
+			return fileDescriptorProtos
+		},
+	)
+	_, err = resolver.FindExtensionByName("ext.bar")
+	assert.True(t, errors.Is(err, protoregistry.NotFound))
+	// ext.bar is not contained in any of the files
+	assert.False(t, resolver.Incomplete())
+}
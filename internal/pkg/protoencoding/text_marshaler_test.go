@@ -0,0 +1,72 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestTextRoundtrip(t *testing.T) {
+	t.Parallel()
+	resolver, err := NewResolver(
+		protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("ext.proto"),
+			Package:    proto.String("ext"),
+			Dependency: []string{"google/protobuf/descriptor.proto"},
+			Extension: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("foo"),
+					Number:   proto.Int32(50000),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Extendee: proto.String(".google.protobuf.MessageOptions"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	data := []byte(`
+name: "a.proto"
+message_type: {
+  name: "A"
+  options: {
+    [ext.foo]: "bar"
+  }
+}
+`)
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, NewTextUnmarshaler(resolver).Unmarshal(data, fileDescriptorProto))
+	require.Equal(t, "A", fileDescriptorProto.GetMessageType()[0].GetName())
+	require.Empty(t, fileDescriptorProto.GetMessageType()[0].GetOptions().ProtoReflect().GetUnknown())
+
+	// without a resolver, the extension is discarded
+	discardedFileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, NewTextUnmarshaler(nil).Unmarshal(data, discardedFileDescriptorProto))
+	require.Equal(t, "A", discardedFileDescriptorProto.GetMessageType()[0].GetName())
+
+	marshaledData, err := NewTextMarshaler(resolver).Marshal(fileDescriptorProto)
+	require.NoError(t, err)
+	require.Contains(t, string(marshaledData), "[ext.foo]")
+	roundtripFileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, NewTextUnmarshaler(resolver).Unmarshal(marshaledData, roundtripFileDescriptorProto))
+	require.True(t, proto.Equal(fileDescriptorProto, roundtripFileDescriptorProto))
+}
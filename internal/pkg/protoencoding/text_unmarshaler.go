@@ -0,0 +1,45 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+type textUnmarshaler struct {
+	resolver    Resolver
+	anyFallback AnyFallback
+}
+
+func newTextUnmarshaler(resolver Resolver, options ...TextUnmarshalerOption) Unmarshaler {
+	textUnmarshaler := &textUnmarshaler{
+		resolver: resolver,
+	}
+	for _, option := range options {
+		option(textUnmarshaler)
+	}
+	return textUnmarshaler
+}
+
+func (m *textUnmarshaler) Unmarshal(data []byte, message proto.Message) error {
+	options := prototext.UnmarshalOptions{
+		Resolver: newAnyFallbackResolver(m.resolver, m.anyFallback),
+		// this matches the JSON Unmarshaler, so that extensions can be
+		// skipped on a first pass without a resolver
+		DiscardUnknown: true,
+	}
+	return options.Unmarshal(data, message)
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func resolveUnknownFields(unmarshalOptions proto.UnmarshalOptions, message protoreflect.Message) error {
+	if unknown := message.GetUnknown(); len(unknown) > 0 {
+		message.SetUnknown(nil)
+		if err := unmarshalOptions.Unmarshal(unknown, message.Interface()); err != nil {
+			return err
+		}
+	}
+	var rangeErr error
+	message.Range(func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		rangeErr = resolveUnknownFieldsForValue(unmarshalOptions, fieldDescriptor, value)
+		return rangeErr == nil
+	})
+	return rangeErr
+}
+
+func resolveUnknownFieldsForValue(
+	unmarshalOptions proto.UnmarshalOptions,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	value protoreflect.Value,
+) error {
+	switch {
+	case fieldDescriptor.IsMap():
+		if fieldDescriptor.MapValue().Message() == nil {
+			return nil
+		}
+		var rangeErr error
+		value.Map().Range(func(_ protoreflect.MapKey, mapValue protoreflect.Value) bool {
+			rangeErr = resolveUnknownFields(unmarshalOptions, mapValue.Message())
+			return rangeErr == nil
+		})
+		return rangeErr
+	case fieldDescriptor.Message() == nil:
+		return nil
+	case fieldDescriptor.IsList():
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			if err := resolveUnknownFields(unmarshalOptions, list.Get(i).Message()); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return resolveUnknownFields(unmarshalOptions, value.Message())
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoencoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestResolveUnknownFields(t *testing.T) {
+	t.Parallel()
+	resolver, err := NewResolver(
+		protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("ext.proto"),
+			Package:    proto.String("ext"),
+			Dependency: []string{"google/protobuf/descriptor.proto"},
+			Extension: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("foo"),
+					Number:   proto.Int32(50000),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Extendee: proto.String(".google.protobuf.MessageOptions"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	var unknown []byte
+	unknown = protowire.AppendTag(unknown, 50000, protowire.BytesType)
+	unknown = protowire.AppendString(unknown, "bar")
+	var unresolvable []byte
+	unresolvable = protowire.AppendTag(unresolvable, 50001, protowire.VarintType)
+	unresolvable = protowire.AppendVarint(unresolvable, 1)
+	messageOptions := &descriptorpb.MessageOptions{}
+	messageOptions.ProtoReflect().SetUnknown(append(unknown, unresolvable...))
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("a.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:    proto.String("A"),
+				Options: messageOptions,
+			},
+		},
+	}
+
+	require.NoError(t, ResolveUnknownFields(resolver, fileDescriptorProto))
+	var extensionValues []string
+	messageOptions.ProtoReflect().Range(func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		require.True(t, fieldDescriptor.IsExtension())
+		require.Equal(t, protoreflect.FullName("ext.foo"), fieldDescriptor.FullName())
+		extensionValues = append(extensionValues, value.String())
+		return true
+	})
+	require.Equal(t, []string{"bar"}, extensionValues)
+	require.Equal(t, protoreflect.RawFields(unresolvable), messageOptions.ProtoReflect().GetUnknown())
+
+	// the resolved message marshals to the same bytes as the original
+	data, err := proto.Marshal(fileDescriptorProto)
+	require.NoError(t, err)
+	roundTrip := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, NewWireUnmarshaler(resolver).Unmarshal(data, roundTrip))
+	require.True(t, proto.Equal(fileDescriptorProto, roundTrip))
+}
@@ -22,17 +22,20 @@ type enum struct {
 	allowAliasPath     []int32
 	reservedEnumRanges []EnumRange
 	reservedNames      []ReservedName
+	deprecated         bool
 }
 
 func newEnum(
 	namedDescriptor namedDescriptor,
 	allowAlias bool,
 	allowAliasPath []int32,
+	deprecated bool,
 ) *enum {
 	return &enum{
 		namedDescriptor: namedDescriptor,
 		allowAlias:      allowAlias,
 		allowAliasPath:  allowAliasPath,
+		deprecated:      deprecated,
 	}
 }
 
@@ -75,3 +78,7 @@ func (e *enum) addReservedEnumRange(reservedEnumRange EnumRange) {
 func (e *enum) addReservedName(reservedName ReservedName) {
 	e.reservedNames = append(e.reservedNames, reservedName)
 }
+
+func (e *enum) Deprecated() bool {
+	return e.deprecated
+}
@@ -20,6 +20,7 @@ type enumValue struct {
 	enum       Enum
 	number     int
 	numberPath []int32
+	deprecated bool
 }
 
 func newEnumValue(
@@ -27,12 +28,14 @@ func newEnumValue(
 	enum Enum,
 	number int,
 	numberPath []int32,
+	deprecated bool,
 ) *enumValue {
 	return &enumValue{
 		namedDescriptor: namedDescriptor,
 		enum:            enum,
 		number:          number,
 		numberPath:      numberPath,
+		deprecated:      deprecated,
 	}
 }
 
@@ -47,3 +50,7 @@ func (e *enumValue) Number() int {
 func (e *enumValue) NumberLocation() Location {
 	return e.getLocation(e.numberPath)
 }
+
+func (e *enumValue) Deprecated() bool {
+	return e.deprecated
+}
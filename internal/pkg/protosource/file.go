@@ -17,6 +17,7 @@ package protosource
 import (
 	"fmt"
 
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -26,10 +27,13 @@ type file struct {
 
 	fileDescriptorProto *descriptorpb.FileDescriptorProto
 	syntax              Syntax
+	edition             string
+	features            []protodescriptor.Feature
 	fileImports         []FileImport
 	messages            []Message
 	enums               []Enum
 	services            []Service
+	extensions          []Field
 	optimizeMode        FileOptionsOptimizeMode
 }
 
@@ -37,6 +41,14 @@ func (f *file) Syntax() Syntax {
 	return f.syntax
 }
 
+func (f *file) Edition() string {
+	return f.edition
+}
+
+func (f *file) Features() []protodescriptor.Feature {
+	return f.features
+}
+
 func (f *file) Package() string {
 	return f.fileDescriptorProto.GetPackage()
 }
@@ -57,6 +69,10 @@ func (f *file) Services() []Service {
 	return f.services
 }
 
+func (f *file) Extensions() []Field {
+	return f.extensions
+}
+
 func (f *file) CsharpNamespace() string {
 	return f.fileDescriptorProto.GetOptions().GetCsharpNamespace()
 }
@@ -227,9 +243,21 @@ func newFile(inputFile InputFile) (*file, error) {
 		f.syntax = SyntaxProto2
 	} else if syntaxString == "proto3" {
 		f.syntax = SyntaxProto3
+	} else if syntaxString == protodescriptor.SyntaxEditions {
+		f.syntax = SyntaxEditions
 	} else {
 		return nil, fmt.Errorf("unknown syntax: %q", syntaxString)
 	}
+	edition, err := protodescriptor.GetFileDescriptorProtoEdition(f.fileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	f.edition = edition
+	features, err := protodescriptor.GetFileDescriptorProtoFeatures(f.fileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	f.features = features
 
 	for dependencyIndex, dependency := range f.fileDescriptorProto.GetDependency() {
 		fileImport, err := newFileImport(
@@ -297,6 +325,16 @@ func newFile(inputFile InputFile) (*file, error) {
 		}
 		f.services = append(f.services, service)
 	}
+	for extensionIndex, fieldDescriptorProto := range f.fileDescriptorProto.GetExtension() {
+		extension, err := f.populateExtension(
+			fieldDescriptorProto,
+			extensionIndex,
+		)
+		if err != nil {
+			return nil, err
+		}
+		f.extensions = append(f.extensions, extension)
+	}
 	optimizeMode, err := getFileOptionsOptimizeMode(f.fileDescriptorProto.GetOptions().GetOptimizeFor())
 	if err != nil {
 		return nil, err
@@ -330,6 +368,7 @@ func (f *file) populateEnum(
 		enumNamedDescriptor,
 		enumDescriptorProto.GetOptions().GetAllowAlias(),
 		getEnumAllowAliasPath(enumIndex, nestedMessageIndexes...),
+		enumDescriptorProto.GetOptions().GetDeprecated(),
 	)
 
 	for enumValueIndex, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
@@ -350,6 +389,7 @@ func (f *file) populateEnum(
 			enum,
 			int(enumValueDescriptorProto.GetNumber()),
 			getEnumValueNumberPath(enumIndex, enumValueIndex, nestedMessageIndexes...),
+			enumValueDescriptorProto.GetOptions().GetDeprecated(),
 		)
 		enum.addValue(enumValue)
 	}
@@ -408,12 +448,18 @@ func (f *file) populateMessage(
 	}
 	message := newMessage(
 		messageNamedDescriptor,
+		newOptionExtensionDescriptor(
+			f.descriptor,
+			descriptorProto.GetOptions(),
+			getMessageOptionsPath(topLevelMessageIndex, nestedMessageIndexes...),
+		),
 		parent,
 		descriptorProto.GetOptions().GetMapEntry(),
 		descriptorProto.GetOptions().GetMessageSetWireFormat(),
 		descriptorProto.GetOptions().GetNoStandardDescriptorAccessor(),
 		getMessageMessageSetWireFormatPath(topLevelMessageIndex, nestedMessageIndexes...),
 		getMessageNoStandardDescriptorAccessorPath(topLevelMessageIndex, nestedMessageIndexes...),
+		descriptorProto.GetOptions().GetDeprecated(),
 	)
 	for fieldIndex, fieldDescriptorProto := range descriptorProto.GetField() {
 		// TODO: not working for map entries
@@ -451,12 +497,19 @@ func (f *file) populateMessage(
 		}
 		field := newField(
 			fieldNamedDescriptor,
+			newOptionExtensionDescriptor(
+				f.descriptor,
+				fieldDescriptorProto.GetOptions(),
+				getMessageFieldOptionsPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+			),
 			message,
 			int(fieldDescriptorProto.GetNumber()),
 			label,
 			typ,
 			fieldDescriptorProto.GetTypeName(),
-			fieldDescriptorProto.OneofIndex,
+			fieldDescriptorProto.GetExtendee(),
+			getFieldOneofIndex(fieldDescriptorProto),
+			fieldDescriptorProto.GetProto3Optional(),
 			fieldDescriptorProto.GetJsonName(),
 			jsType,
 			cType,
@@ -468,6 +521,7 @@ func (f *file) populateMessage(
 			getMessageFieldJSTypePath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
 			getMessageFieldCTypePath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
 			getMessageFieldPackedPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+			fieldDescriptorProto.GetOptions().GetDeprecated(),
 		)
 		message.addField(field)
 	}
@@ -507,12 +561,19 @@ func (f *file) populateMessage(
 		}
 		field := newField(
 			fieldNamedDescriptor,
+			newOptionExtensionDescriptor(
+				f.descriptor,
+				fieldDescriptorProto.GetOptions(),
+				getMessageExtensionOptionsPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+			),
 			message,
 			int(fieldDescriptorProto.GetNumber()),
 			label,
 			typ,
 			fieldDescriptorProto.GetTypeName(),
-			fieldDescriptorProto.OneofIndex,
+			fieldDescriptorProto.GetExtendee(),
+			getFieldOneofIndex(fieldDescriptorProto),
+			fieldDescriptorProto.GetProto3Optional(),
 			fieldDescriptorProto.GetJsonName(),
 			jsType,
 			cType,
@@ -524,10 +585,17 @@ func (f *file) populateMessage(
 			getMessageExtensionJSTypePath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
 			getMessageExtensionCTypePath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
 			getMessageExtensionPackedPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...),
+			fieldDescriptorProto.GetOptions().GetDeprecated(),
 		)
 		message.addExtension(field)
 	}
+	syntheticOneofIndexes := getSyntheticOneofIndexes(descriptorProto)
 	for oneofIndex, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+		// synthetic oneofs are always after the real oneofs, so skipping them
+		// does not change the indexes of the real oneofs
+		if _, ok := syntheticOneofIndexes[int32(oneofIndex)]; ok {
+			continue
+		}
 		oneofNamedDescriptor, err := newNamedDescriptor(
 			newLocationDescriptor(
 				f.descriptor,
@@ -616,6 +684,72 @@ func (f *file) populateMessage(
 	return message, nil
 }
 
+func (f *file) populateExtension(
+	fieldDescriptorProto *descriptorpb.FieldDescriptorProto,
+	extensionIndex int,
+) (Field, error) {
+	fieldNamedDescriptor, err := newNamedDescriptor(
+		newLocationDescriptor(
+			f.descriptor,
+			getFileExtensionPath(extensionIndex),
+		),
+		fieldDescriptorProto.GetName(),
+		getFileExtensionNamePath(extensionIndex),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var packed *bool
+	if fieldDescriptorProto.Options != nil {
+		packed = fieldDescriptorProto.GetOptions().Packed
+	}
+	label, err := getFieldDescriptorProtoLabel(fieldDescriptorProto.GetLabel())
+	if err != nil {
+		return nil, err
+	}
+	typ, err := getFieldDescriptorProtoType(fieldDescriptorProto.GetType())
+	if err != nil {
+		return nil, err
+	}
+	jsType, err := getFieldOptionsJSType(fieldDescriptorProto.GetOptions().GetJstype())
+	if err != nil {
+		return nil, err
+	}
+	cType, err := getFieldOptionsCType(fieldDescriptorProto.GetOptions().GetCtype())
+	if err != nil {
+		return nil, err
+	}
+	return newField(
+		fieldNamedDescriptor,
+		newOptionExtensionDescriptor(
+			f.descriptor,
+			fieldDescriptorProto.GetOptions(),
+			getFileExtensionOptionsPath(extensionIndex),
+		),
+		nil,
+		int(fieldDescriptorProto.GetNumber()),
+		label,
+		typ,
+		fieldDescriptorProto.GetTypeName(),
+		fieldDescriptorProto.GetExtendee(),
+		getFieldOneofIndex(fieldDescriptorProto),
+		fieldDescriptorProto.GetProto3Optional(),
+		fieldDescriptorProto.GetJsonName(),
+		jsType,
+		cType,
+		packed,
+		getFileExtensionNumberPath(extensionIndex),
+		getFileExtensionTypePath(extensionIndex),
+		getFileExtensionTypeNamePath(extensionIndex),
+		getFileExtensionJSONNamePath(extensionIndex),
+		getFileExtensionJSTypePath(extensionIndex),
+		getFileExtensionCTypePath(extensionIndex),
+		getFileExtensionPackedPath(extensionIndex),
+		fieldDescriptorProto.GetOptions().GetDeprecated(),
+	), nil
+}
+
 func (f *file) populateService(
 	serviceDescriptorProto *descriptorpb.ServiceDescriptorProto,
 	serviceIndex int,
@@ -634,6 +768,12 @@ func (f *file) populateService(
 	}
 	service := newService(
 		serviceNamedDescriptor,
+		newOptionExtensionDescriptor(
+			f.descriptor,
+			serviceDescriptorProto.GetOptions(),
+			getServiceOptionsPath(serviceIndex),
+		),
+		serviceDescriptorProto.GetOptions().GetDeprecated(),
 	)
 	for methodIndex, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
 		methodNamedDescriptor, err := newNamedDescriptor(
@@ -654,6 +794,11 @@ func (f *file) populateService(
 		}
 		method, err := newMethod(
 			methodNamedDescriptor,
+			newOptionExtensionDescriptor(
+				f.descriptor,
+				methodDescriptorProto.GetOptions(),
+				getMethodOptionsPath(serviceIndex, methodIndex),
+			),
 			service,
 			methodDescriptorProto.GetInputType(),
 			methodDescriptorProto.GetOutputType(),
@@ -662,7 +807,9 @@ func (f *file) populateService(
 			getMethodInputTypePath(serviceIndex, methodIndex),
 			getMethodOutputTypePath(serviceIndex, methodIndex),
 			idempotencyLevel,
+			methodDescriptorProto.GetOptions() != nil && methodDescriptorProto.GetOptions().IdempotencyLevel != nil,
 			getMethodIdempotencyLevelPath(serviceIndex, methodIndex),
+			methodDescriptorProto.GetOptions().GetDeprecated(),
 		)
 		if err != nil {
 			return nil, err
@@ -671,3 +818,24 @@ func (f *file) populateService(
 	}
 	return service, nil
 }
+
+// getFieldOneofIndex returns the oneof index of the field, which is nil for
+// proto3 optional fields, as their synthetic oneofs are not real oneofs.
+func getFieldOneofIndex(fieldDescriptorProto *descriptorpb.FieldDescriptorProto) *int32 {
+	if fieldDescriptorProto.GetProto3Optional() {
+		return nil
+	}
+	return fieldDescriptorProto.OneofIndex
+}
+
+// getSyntheticOneofIndexes returns the indexes of the synthetic oneofs of
+// the proto3 optional fields of the message.
+func getSyntheticOneofIndexes(descriptorProto *descriptorpb.DescriptorProto) map[int32]struct{} {
+	syntheticOneofIndexes := make(map[int32]struct{})
+	for _, fieldDescriptorProto := range descriptorProto.GetField() {
+		if fieldDescriptorProto.GetProto3Optional() && fieldDescriptorProto.OneofIndex != nil {
+			syntheticOneofIndexes[fieldDescriptorProto.GetOneofIndex()] = struct{}{}
+		}
+	}
+	return syntheticOneofIndexes
+}
@@ -16,6 +16,7 @@ package protosource
 
 type message struct {
 	namedDescriptor
+	optionExtensionDescriptor
 
 	fields                           []Field
 	extensions                       []Field
@@ -31,24 +32,30 @@ type message struct {
 	noStandardDescriptorAccessor     bool
 	messageSetWireFormatPath         []int32
 	noStandardDescriptorAccessorPath []int32
+	deprecated                       bool
 }
 
 func newMessage(
 	namedDescriptor namedDescriptor,
+	optionExtensionDescriptor optionExtensionDescriptor,
 	parent Message,
 	isMapEntry bool,
 	messageSetWireFormat bool,
 	noStandardDescriptorAccessor bool,
 	messageSetWireFormatPath []int32,
 	noStandardDescriptorAccessorPath []int32,
+	deprecated bool,
 ) *message {
 	return &message{
 		namedDescriptor:                  namedDescriptor,
+		optionExtensionDescriptor:        optionExtensionDescriptor,
+		parent:                           parent,
 		isMapEntry:                       isMapEntry,
 		messageSetWireFormat:             messageSetWireFormat,
 		noStandardDescriptorAccessor:     noStandardDescriptorAccessor,
 		messageSetWireFormatPath:         messageSetWireFormatPath,
 		noStandardDescriptorAccessorPath: noStandardDescriptorAccessorPath,
+		deprecated:                       deprecated,
 	}
 }
 
@@ -147,3 +154,7 @@ func (m *message) addReservedName(reservedName ReservedName) {
 func (m *message) addExtensionMessageRange(extensionMessageRange MessageRange) {
 	m.extensionMessageRanges = append(m.extensionMessageRanges, extensionMessageRange)
 }
+
+func (m *message) Deprecated() bool {
+	return m.deprecated
+}
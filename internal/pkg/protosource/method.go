@@ -18,20 +18,24 @@ import "fmt"
 
 type method struct {
 	namedDescriptor
+	optionExtensionDescriptor
 
-	service              Service
-	inputTypeName        string
-	outputTypeName       string
-	clientStreaming      bool
-	serverStreaming      bool
-	inputTypePath        []int32
-	outputTypePath       []int32
-	idempotencyLevel     MethodOptionsIdempotencyLevel
-	idempotencyLevelPath []int32
+	service               Service
+	inputTypeName         string
+	outputTypeName        string
+	clientStreaming       bool
+	serverStreaming       bool
+	inputTypePath         []int32
+	outputTypePath        []int32
+	idempotencyLevel      MethodOptionsIdempotencyLevel
+	idempotencyLevelIsSet bool
+	idempotencyLevelPath  []int32
+	deprecated            bool
 }
 
 func newMethod(
 	namedDescriptor namedDescriptor,
+	optionExtensionDescriptor optionExtensionDescriptor,
 	service Service,
 	inputTypeName string,
 	outputTypeName string,
@@ -40,7 +44,9 @@ func newMethod(
 	inputTypePath []int32,
 	outputTypePath []int32,
 	idempotencyLevel MethodOptionsIdempotencyLevel,
+	idempotencyLevelIsSet bool,
 	idempotencyLevelPath []int32,
+	deprecated bool,
 ) (*method, error) {
 	if inputTypeName == "" {
 		return nil, fmt.Errorf("no inputTypeName on %q", namedDescriptor.name)
@@ -49,16 +55,19 @@ func newMethod(
 		return nil, fmt.Errorf("no outputTypeName on %q", namedDescriptor.name)
 	}
 	return &method{
-		namedDescriptor:      namedDescriptor,
-		service:              service,
-		inputTypeName:        inputTypeName,
-		outputTypeName:       outputTypeName,
-		clientStreaming:      clientStreaming,
-		serverStreaming:      serverStreaming,
-		inputTypePath:        inputTypePath,
-		outputTypePath:       outputTypePath,
-		idempotencyLevel:     idempotencyLevel,
-		idempotencyLevelPath: idempotencyLevelPath,
+		namedDescriptor:           namedDescriptor,
+		optionExtensionDescriptor: optionExtensionDescriptor,
+		service:                   service,
+		inputTypeName:             inputTypeName,
+		outputTypeName:            outputTypeName,
+		clientStreaming:           clientStreaming,
+		serverStreaming:           serverStreaming,
+		inputTypePath:             inputTypePath,
+		outputTypePath:            outputTypePath,
+		idempotencyLevel:          idempotencyLevel,
+		idempotencyLevelIsSet:     idempotencyLevelIsSet,
+		idempotencyLevelPath:      idempotencyLevelPath,
+		deprecated:                deprecated,
 	}, nil
 }
 
@@ -94,6 +103,14 @@ func (m *method) IdempotencyLevel() MethodOptionsIdempotencyLevel {
 	return m.idempotencyLevel
 }
 
+func (m *method) IdempotencyLevelIsSet() bool {
+	return m.idempotencyLevelIsSet
+}
+
 func (m *method) IdempotencyLevelLocation() Location {
 	return m.getLocation(m.idempotencyLevelPath)
 }
+
+func (m *method) Deprecated() bool {
+	return m.deprecated
+}
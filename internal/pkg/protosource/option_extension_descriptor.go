@@ -0,0 +1,87 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protosource
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+type optionExtensionDescriptor struct {
+	descriptor
+
+	options     proto.Message
+	optionsPath []int32
+}
+
+func newOptionExtensionDescriptor(
+	descriptor descriptor,
+	options proto.Message,
+	optionsPath []int32,
+) optionExtensionDescriptor {
+	return optionExtensionDescriptor{
+		descriptor:  descriptor,
+		options:     options,
+		optionsPath: optionsPath,
+	}
+}
+
+func (o *optionExtensionDescriptor) OptionExtension(fieldNumber int32) ([]byte, bool) {
+	if o.options == nil || !o.options.ProtoReflect().IsValid() {
+		return nil, false
+	}
+	// Extensions may either be resolved or be unknown fields depending on how
+	// the options were parsed, so we go through the wire format to handle both.
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(o.options)
+	if err != nil {
+		return nil, false
+	}
+	var value []byte
+	found := false
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+		n = protowire.ConsumeFieldValue(number, typ, data)
+		if n < 0 {
+			return nil, false
+		}
+		if int32(number) == fieldNumber {
+			found = true
+			if typ == protowire.BytesType {
+				// multiple occurrences of a message field are merged,
+				// which is equivalent to concatenating their contents
+				fieldValue, _ := protowire.ConsumeBytes(data[:n])
+				value = append(value, fieldValue...)
+			} else {
+				// the last occurrence of a scalar wins
+				value = append([]byte(nil), data[:n]...)
+			}
+		}
+		data = data[n:]
+	}
+	return value, found
+}
+
+func (o *optionExtensionDescriptor) OptionExtensionLocation(fieldNumber int32) Location {
+	if len(o.optionsPath) == 0 {
+		return nil
+	}
+	path := make([]int32, len(o.optionsPath), len(o.optionsPath)+1)
+	copy(path, o.optionsPath)
+	return o.getLocation(append(path, fieldNumber))
+}
@@ -53,6 +53,10 @@ func getMessageNamePath(messageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 1)
 }
 
+func getMessageOptionsPath(messageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 7)
+}
+
 func getMessageMessageSetWireFormatPath(messageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessagePath(messageIndex, nestedMessageIndexes...), 7, 1)
 }
@@ -69,6 +73,10 @@ func getMessageFieldNamePath(fieldIndex int, topLevelMessageIndex int, nestedMes
 	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 1)
 }
 
+func getMessageFieldOptionsPath(fieldIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 8)
+}
+
 func getMessageFieldNumberPath(fieldIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessageFieldPath(fieldIndex, topLevelMessageIndex, nestedMessageIndexes...), 3)
 }
@@ -105,6 +113,10 @@ func getMessageExtensionNamePath(extensionIndex int, topLevelMessageIndex int, n
 	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 1)
 }
 
+func getMessageExtensionOptionsPath(extensionIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
+	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 8)
+}
+
 func getMessageExtensionNumberPath(extensionIndex int, topLevelMessageIndex int, nestedMessageIndexes ...int) []int32 {
 	return append(getMessageExtensionPath(extensionIndex, topLevelMessageIndex, nestedMessageIndexes...), 3)
 }
@@ -191,6 +203,46 @@ func getEnumReservedNamePath(enumIndex int, reservedNameIndex int, nestedMessage
 	return append(getEnumPath(enumIndex, nestedMessageIndexes...), 5, int32(reservedNameIndex))
 }
 
+func getFileExtensionPath(extensionIndex int) []int32 {
+	return []int32{7, int32(extensionIndex)}
+}
+
+func getFileExtensionNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 1)
+}
+
+func getFileExtensionOptionsPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8)
+}
+
+func getFileExtensionNumberPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 3)
+}
+
+func getFileExtensionTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 5)
+}
+
+func getFileExtensionTypeNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 6)
+}
+
+func getFileExtensionJSONNamePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 10)
+}
+
+func getFileExtensionJSTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 6)
+}
+
+func getFileExtensionCTypePath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 1)
+}
+
+func getFileExtensionPackedPath(extensionIndex int) []int32 {
+	return append(getFileExtensionPath(extensionIndex), 8, 2)
+}
+
 func getServicePath(serviceIndex int) []int32 {
 	return []int32{6, int32(serviceIndex)}
 }
@@ -199,6 +251,10 @@ func getServiceNamePath(serviceIndex int) []int32 {
 	return append(getServicePath(serviceIndex), 1)
 }
 
+func getServiceOptionsPath(serviceIndex int) []int32 {
+	return append(getServicePath(serviceIndex), 3)
+}
+
 func getMethodPath(serviceIndex int, methodIndex int) []int32 {
 	return []int32{6, int32(serviceIndex), 2, int32(methodIndex)}
 }
@@ -215,6 +271,10 @@ func getMethodOutputTypePath(serviceIndex int, methodIndex int) []int32 {
 	return append(getMethodPath(serviceIndex, methodIndex), 3)
 }
 
+func getMethodOptionsPath(serviceIndex int, methodIndex int) []int32 {
+	return append(getMethodPath(serviceIndex, methodIndex), 4)
+}
+
 func getMethodIdempotencyLevelPath(serviceIndex int, methodIndex int) []int32 {
 	return append(getMethodPath(serviceIndex, methodIndex), 4, 34)
 }
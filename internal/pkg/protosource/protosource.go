@@ -31,6 +31,7 @@ import (
 	"strings"
 
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/protodescriptor"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -39,6 +40,8 @@ const (
 	SyntaxProto2 Syntax = iota + 1
 	// SyntaxProto3 represents the proto3 syntax.
 	SyntaxProto3
+	// SyntaxEditions represents files that use editions.
+	SyntaxEditions
 )
 
 // Syntax is the syntax of a file.
@@ -51,6 +54,8 @@ func (s Syntax) String() string {
 		return "proto2"
 	case SyntaxProto3:
 		return "proto3"
+	case SyntaxEditions:
+		return "editions"
 	default:
 		return strconv.Itoa(int(s))
 	}
@@ -103,6 +108,22 @@ type NamedDescriptor interface {
 	NameLocation() Location
 }
 
+// OptionExtensionDescriptor is a descriptor whose options can have extensions set.
+type OptionExtensionDescriptor interface {
+	// OptionExtension returns the wire-encoded value of the options extension with
+	// the given field number, and whether the extension was set.
+	//
+	// For length-delimited extensions such as messages, this is the contents of
+	// the field without the tag and length prefix, with all occurrences merged.
+	// For all other extensions, this is the raw value of the last occurrence.
+	OptionExtension(fieldNumber int32) ([]byte, bool)
+	// OptionExtensionLocation returns the location of the options extension with
+	// the given field number.
+	//
+	// Can return nil.
+	OptionExtensionLocation(fieldNumber int32) Location
+}
+
 // ContainerDescriptor contains Enums and Messages.
 type ContainerDescriptor interface {
 	Enums() []Enum
@@ -154,9 +175,20 @@ type File interface {
 	ContainerDescriptor
 
 	Syntax() Syntax
+	// Edition returns the edition of the file, such as 2023.
+	//
+	// Empty if the syntax is not SyntaxEditions.
+	Edition() string
+	// Features returns the resolved file-level features of the file.
+	//
+	// Proto2 and proto3 files have the features that match their behavior,
+	// so these can be compared across syntaxes and editions.
+	Features() []protodescriptor.Feature
 	Package() string
 	FileImports() []FileImport
 	Services() []Service
+	// Top-level only.
+	Extensions() []Field
 
 	CsharpNamespace() string
 	GoPackage() string
@@ -260,6 +292,7 @@ type Enum interface {
 
 	AllowAlias() bool
 	AllowAliasLocation() Location
+	Deprecated() bool
 }
 
 // EnumValue is an enum value descriptor.
@@ -268,6 +301,7 @@ type EnumValue interface {
 
 	Enum() Enum
 	Number() int
+	Deprecated() bool
 
 	NumberLocation() Location
 }
@@ -275,6 +309,7 @@ type EnumValue interface {
 // Message is a message descriptor.
 type Message interface {
 	NamedDescriptor
+	OptionExtensionDescriptor
 	// Only those directly nested under this message.
 	ContainerDescriptor
 	ReservedDescriptor
@@ -282,6 +317,7 @@ type Message interface {
 	// Includes fields in oneofs.
 	Fields() []Field
 	Extensions() []Field
+	// Does not include the synthetic oneofs of proto3 optional fields.
 	Oneofs() []Oneof
 	ExtensionMessageRanges() []MessageRange
 	ReservedMessageRanges() []MessageRange
@@ -289,6 +325,7 @@ type Message interface {
 	// Will return nil if this is a top-level message
 	Parent() Message
 	IsMapEntry() bool
+	Deprecated() bool
 
 	MessageSetWireFormat() bool
 	NoStandardDescriptorAccessor() bool
@@ -299,19 +336,32 @@ type Message interface {
 // Field is a field descriptor.
 type Field interface {
 	NamedDescriptor
+	OptionExtensionDescriptor
 
+	// Will return nil if this is a top-level extension.
 	Message() Message
 	Number() int
 	Label() FieldDescriptorProtoLabel
 	Type() FieldDescriptorProtoType
 	TypeName() string
+	// Extendee returns the fully-qualified name of the extended message, with
+	// a leading period.
+	//
+	// Empty if this is not an extension.
+	Extendee() string
+	// Returns false for proto3 optional fields, as their synthetic oneofs
+	// are not real oneofs.
 	OneofIndex() (int, bool)
+	// Proto3Optional returns true if this is a proto3 field with the
+	// optional label, which has explicit presence.
+	Proto3Optional() bool
 	JSONName() string
 	JSType() FieldOptionsJSType
 	CType() FieldOptionsCType
 	// Set vs unset matters for packed
 	// See the comments on descriptor.proto
 	Packed() *bool
+	Deprecated() bool
 
 	NumberLocation() Location
 	TypeLocation() Location
@@ -332,23 +382,30 @@ type Oneof interface {
 // Service is a service descriptor.
 type Service interface {
 	NamedDescriptor
+	OptionExtensionDescriptor
 
 	Methods() []Method
+	Deprecated() bool
 }
 
 // Method is a method descriptor.
 type Method interface {
 	NamedDescriptor
+	OptionExtensionDescriptor
 
 	Service() Service
 	InputTypeName() string
 	OutputTypeName() string
 	ClientStreaming() bool
 	ServerStreaming() bool
+	Deprecated() bool
 	InputTypeLocation() Location
 	OutputTypeLocation() Location
 
 	IdempotencyLevel() MethodOptionsIdempotencyLevel
+	// IdempotencyLevelIsSet returns true if the idempotency_level option is
+	// explicitly set, including if it is set to IDEMPOTENCY_UNKNOWN.
+	IdempotencyLevelIsSet() bool
 	IdempotencyLevelLocation() Location
 }
 
@@ -799,6 +856,81 @@ func FullNameToMethod(files ...File) (map[string]Method, error) {
 	return fullNameToMethod, nil
 }
 
+// FullNameToNamedDescriptor maps the NamedDescriptors in the Files to a map from
+// full name to NamedDescriptor.
+//
+// This includes Enums, EnumValues, Messages, Fields, Extensions declared within
+// Messages, Oneofs, Services, and Methods.
+//
+// Returns error if NamedDescriptors do not have unique full names within the Files,
+// which should generally never happen for properly-formed Files.
+func FullNameToNamedDescriptor(files ...File) (map[string]NamedDescriptor, error) {
+	fullNameToNamedDescriptor := make(map[string]NamedDescriptor)
+	add := func(namedDescriptor NamedDescriptor) error {
+		fullName := namedDescriptor.FullName()
+		if _, ok := fullNameToNamedDescriptor[fullName]; ok {
+			return fmt.Errorf("duplicate descriptor: %q", fullName)
+		}
+		fullNameToNamedDescriptor[fullName] = namedDescriptor
+		return nil
+	}
+	for _, file := range files {
+		if err := ForEachEnum(
+			func(enum Enum) error {
+				if err := add(enum); err != nil {
+					return err
+				}
+				for _, enumValue := range enum.Values() {
+					if err := add(enumValue); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := ForEachMessage(
+			func(message Message) error {
+				if err := add(message); err != nil {
+					return err
+				}
+				for _, field := range message.Fields() {
+					if err := add(field); err != nil {
+						return err
+					}
+				}
+				for _, extension := range message.Extensions() {
+					if err := add(extension); err != nil {
+						return err
+					}
+				}
+				for _, oneof := range message.Oneofs() {
+					if err := add(oneof); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		for _, service := range file.Services() {
+			if err := add(service); err != nil {
+				return nil, err
+			}
+			for _, method := range service.Methods() {
+				if err := add(method); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return fullNameToNamedDescriptor, nil
+}
+
 // StringToReservedTagRange maps the ReservedTagRanges in the ReservedDescriptor to a map
 // from string string to reserved TagRange.
 //
@@ -851,6 +983,34 @@ func FieldOneof(field Field) (Oneof, error) {
 	return oneofs[oneofIndex], nil
 }
 
+// FieldTypeString returns the type of the field as written in Protobuf source.
+//
+// This is the fully-qualified name without a leading dot for messages, enums,
+// and groups, and map<K, V> for map fields, where K and V are the types of the
+// key and value. fullNameToMessage is used to find map entries, as returned by
+// FullNameToMessage. Map fields whose entry is not in fullNameToMessage are
+// returned as the name of the entry.
+func FieldTypeString(field Field, fullNameToMessage map[string]Message) string {
+	switch field.Type() {
+	case FieldDescriptorProtoTypeMessage,
+		FieldDescriptorProtoTypeEnum,
+		FieldDescriptorProtoTypeGroup:
+		typeName := strings.TrimPrefix(field.TypeName(), ".")
+		if message, ok := fullNameToMessage[typeName]; ok && message.IsMapEntry() {
+			if fields := message.Fields(); len(fields) == 2 {
+				return fmt.Sprintf(
+					"map<%s, %s>",
+					FieldTypeString(fields[0], fullNameToMessage),
+					FieldTypeString(fields[1], fullNameToMessage),
+				)
+			}
+		}
+		return typeName
+	default:
+		return field.Type().String()
+	}
+}
+
 // NumberInReservedRanges returns true if the number is in one of the Ranges.
 func NumberInReservedRanges(number int, reservedRanges ...TagRange) bool {
 	for _, reservedRange := range reservedRanges {
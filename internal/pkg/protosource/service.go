@@ -16,15 +16,21 @@ package protosource
 
 type service struct {
 	namedDescriptor
+	optionExtensionDescriptor
 
-	methods []Method
+	methods    []Method
+	deprecated bool
 }
 
 func newService(
 	namedDescriptor namedDescriptor,
+	optionExtensionDescriptor optionExtensionDescriptor,
+	deprecated bool,
 ) *service {
 	return &service{
-		namedDescriptor: namedDescriptor,
+		namedDescriptor:           namedDescriptor,
+		optionExtensionDescriptor: optionExtensionDescriptor,
+		deprecated:                deprecated,
 	}
 }
 
@@ -35,3 +41,7 @@ func (m *service) Methods() []Method {
 func (m *service) addMethod(method Method) {
 	m.methods = append(m.methods, method)
 }
+
+func (m *service) Deprecated() bool {
+	return m.deprecated
+}
@@ -31,6 +31,10 @@ var (
 
 	// errNotExist is the error returned if a path does not exist.
 	errNotExist = errors.New("does not exist")
+	// errSymlink is the error returned for a symlink with SymlinkPolicyError.
+	errSymlink = errors.New("is a symlink, which is not allowed by the symlink policy")
+	// errSymlinkCycle is the error returned for a symlink cycle with SymlinkPolicyFollow.
+	errSymlinkCycle = errors.New("symlink cycle")
 )
 
 // NewErrNotExist returns a new error for a path not existing.
@@ -43,6 +47,21 @@ func IsNotExist(err error) bool {
 	return normalpath.ErrorEquals(err, errNotExist)
 }
 
+// NewErrSymlink returns a new error for a symlink not allowed by SymlinkPolicyError.
+func NewErrSymlink(path string) error {
+	return normalpath.NewError(path, errSymlink)
+}
+
+// IsSymlink returns true for an error that is for a symlink not allowed by SymlinkPolicyError.
+func IsSymlink(err error) bool {
+	return normalpath.ErrorEquals(err, errSymlink)
+}
+
+// NewErrSymlinkCycle returns a new error for a symlink cycle.
+func NewErrSymlinkCycle(path string) error {
+	return normalpath.NewError(path, errSymlinkCycle)
+}
+
 // NewErrExistsMultipleLocations returns a new error if a path exists in multiple locations.
 func NewErrExistsMultipleLocations(path string, externalPaths ...string) error {
 	return &errorExistsMultipleLocations{
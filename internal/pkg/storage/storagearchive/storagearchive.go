@@ -17,10 +17,15 @@ package storagearchive
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
 
 	"github.com/bufbuild/buf/internal/pkg/normalpath"
 	"github.com/bufbuild/buf/internal/pkg/storage"
@@ -29,10 +34,22 @@ import (
 	"go.uber.org/multierr"
 )
 
+const archiveFileMode = 0644
+
+var (
+	// archiveModTime is the fixed modification time for all archive entries.
+	//
+	// This is the earliest time that can be represented in a zip archive.
+	archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
 // Tar tars the given bucket to the writer.
 //
 // Only regular files are added to the writer.
 // All files are written as 0644.
+//
+// The output is deterministic: entries are sorted by path, and all
+// modification times and ownership are fixed.
 func Tar(
 	ctx context.Context,
 	readBucket storage.ReadBucket,
@@ -42,18 +59,18 @@ func Tar(
 	defer func() {
 		retErr = multierr.Append(retErr, tarWriter.Close())
 	}()
-	return storage.WalkReadObjects(
+	return walkReadObjectsSorted(
 		ctx,
 		readBucket,
-		"",
 		func(readObject storage.ReadObject) error {
 			if err := tarWriter.WriteHeader(
 				&tar.Header{
 					Typeflag: tar.TypeReg,
 					Name:     readObject.Path(),
 					Size:     int64(readObject.Size()),
-					// If we ever use this outside of testing, we will want to do something about this
-					Mode: 0644,
+					Mode:     archiveFileMode,
+					ModTime:  archiveModTime,
+					Format:   tar.FormatPAX,
 				},
 			); err != nil {
 				return err
@@ -66,7 +83,8 @@ func Tar(
 
 // Untar untars the given tar archive from the reader into the bucket.
 //
-// Only regular files are added to the bucket.
+// Only regular files are added to the bucket, unless symlinks are followed
+// with UnarchiveWithSymlinkPolicy.
 //
 // Paths from the tar archive will be mapped before adding to the bucket.
 // Mapper can be nil.
@@ -77,7 +95,13 @@ func Untar(
 	writeBucket storage.WriteBucket,
 	mapper storage.Mapper,
 	stripComponentCount uint32,
+	options ...UnarchiveOption,
 ) error {
+	unarchiveOptions := newUnarchiveOptions(options...)
+	var archiveSymlinks *archiveSymlinks
+	if unarchiveOptions.symlinkPolicy == storage.SymlinkPolicyFollow {
+		archiveSymlinks = newArchiveSymlinks()
+	}
 	tarReader := tar.NewReader(reader)
 	walkChecker := internal.NewWalkChecker()
 	for tarHeader, err := tarReader.Next(); err != io.EOF; tarHeader, err = tarReader.Next() {
@@ -91,30 +115,69 @@ func Untar(
 		if err != nil {
 			return err
 		}
-		if !ok {
+		if tarHeader.Typeflag == tar.TypeSymlink {
+			if err := handleArchiveSymlink(
+				unarchiveOptions.symlinkPolicy,
+				archiveSymlinks,
+				tarHeader.Name,
+				tarHeader.Linkname,
+				ok,
+			); err != nil {
+				return err
+			}
 			continue
 		}
-		if tarHeader.FileInfo().Mode().IsRegular() {
-			if tarHeader.Size < 0 {
-				return fmt.Errorf("invalid size for tar file %s: %d", tarHeader.Name, tarHeader.Size)
-			}
-			writeObjectCloser, err := writeBucket.Put(ctx, path, uint32(tarHeader.Size))
+		if !tarHeader.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		if tarHeader.Size < 0 {
+			return fmt.Errorf("invalid size for tar file %s: %d", tarHeader.Name, tarHeader.Size)
+		}
+		var fileReader io.Reader = tarReader
+		if archiveSymlinks != nil {
+			// the targets of symlinks are only known once the entire archive
+			// is read, so the file has to be kept regardless of the mapping
+			data, err := ioutil.ReadAll(tarReader)
 			if err != nil {
 				return err
 			}
-			_, err = io.Copy(writeObjectCloser, tarReader)
-			err = multierr.Append(err, writeObjectCloser.Close())
-			if err != nil {
+			if err := archiveSymlinks.addFile(
+				tarHeader.Name,
+				uint64(len(data)),
+				func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(data)), nil
+				},
+			); err != nil {
 				return err
 			}
+			fileReader = bytes.NewReader(data)
+		}
+		if !ok {
+			continue
+		}
+		writeObjectCloser, err := writeBucket.Put(ctx, path, uint32(tarHeader.Size))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writeObjectCloser, fileReader)
+		err = multierr.Append(err, writeObjectCloser.Close())
+		if err != nil {
+			return err
 		}
 	}
+	if archiveSymlinks != nil {
+		return archiveSymlinks.writeToBucket(ctx, writeBucket, mapper, stripComponentCount)
+	}
 	return nil
 }
 
 // Zip zips the given bucket to the writer.
 //
 // Only regular files are added to the writer.
+// All files are written as 0644.
+//
+// The output is deterministic: entries are sorted by path, and all
+// modification times are fixed.
 func Zip(
 	ctx context.Context,
 	readBucket storage.ReadBucket,
@@ -124,12 +187,17 @@ func Zip(
 	defer func() {
 		retErr = multierr.Append(retErr, zipWriter.Close())
 	}()
-	return storage.WalkReadObjects(
+	return walkReadObjectsSorted(
 		ctx,
 		readBucket,
-		"",
 		func(readObject storage.ReadObject) error {
-			writer, err := zipWriter.Create(readObject.Path())
+			zipFileHeader := &zip.FileHeader{
+				Name:     readObject.Path(),
+				Method:   zip.Deflate,
+				Modified: archiveModTime,
+			}
+			zipFileHeader.SetMode(archiveFileMode)
+			writer, err := zipWriter.CreateHeader(zipFileHeader)
 			if err != nil {
 				return err
 			}
@@ -141,7 +209,8 @@ func Zip(
 
 // Unzip unzips the given zip archive from the reader into the bucket.
 //
-// Only regular files are added to the bucket.
+// Only regular files are added to the bucket, unless symlinks are followed
+// with UnarchiveWithSymlinkPolicy.
 //
 // Paths from the zip archive will be mapped before adding to the bucket.
 // Mapper can be nil.
@@ -153,7 +222,9 @@ func Unzip(
 	writeBucket storage.WriteBucket,
 	mapper storage.Mapper,
 	stripComponentCount uint32,
+	options ...UnarchiveOption,
 ) error {
+	unarchiveOptions := newUnarchiveOptions(options...)
 	if size < 0 {
 		return fmt.Errorf("unknown size to unzip: %d", int(size))
 	}
@@ -164,6 +235,10 @@ func Unzip(
 	if err != nil {
 		return err
 	}
+	var archiveSymlinks *archiveSymlinks
+	if unarchiveOptions.symlinkPolicy == storage.SymlinkPolicyFollow {
+		archiveSymlinks = newArchiveSymlinks()
+	}
 	walkChecker := internal.NewWalkChecker()
 	// reads can be done concurrently in the future
 	for _, zipFile := range zipReader.File {
@@ -174,28 +249,154 @@ func Unzip(
 		if err != nil {
 			return err
 		}
-		if !ok {
-			continue
-		}
-		if zipFile.FileInfo().Mode().IsRegular() {
-			readCloser, err := zipFile.Open()
+		if zipFile.Mode()&os.ModeSymlink != 0 {
+			// the content of a zip symlink entry is the target of the symlink
+			linkname, err := readZipFile(zipFile)
 			if err != nil {
 				return err
 			}
-			writeObjectCloser, err := writeBucket.Put(ctx, path, uint32(zipFile.UncompressedSize64))
-			if err != nil {
-				return multierr.Append(err, readCloser.Close())
+			if err := handleArchiveSymlink(
+				unarchiveOptions.symlinkPolicy,
+				archiveSymlinks,
+				zipFile.Name,
+				string(linkname),
+				ok,
+			); err != nil {
+				return err
 			}
-			_, err = io.Copy(writeObjectCloser, readCloser)
-			err = multierr.Combine(err, writeObjectCloser.Close(), readCloser.Close())
-			if err != nil {
+			continue
+		}
+		if !zipFile.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		if archiveSymlinks != nil {
+			if err := archiveSymlinks.addFile(
+				zipFile.Name,
+				zipFile.UncompressedSize64,
+				zipFile.Open,
+			); err != nil {
 				return err
 			}
 		}
+		if !ok {
+			continue
+		}
+		if err := writeArchiveFile(ctx, writeBucket, path, zipFile.UncompressedSize64, zipFile.Open); err != nil {
+			return err
+		}
+	}
+	if archiveSymlinks != nil {
+		return archiveSymlinks.writeToBucket(ctx, writeBucket, mapper, stripComponentCount)
+	}
+	return nil
+}
+
+// UnarchiveOption is an option for Untar and Unzip.
+type UnarchiveOption func(*unarchiveOptions)
+
+// UnarchiveWithSymlinkPolicy sets the policy for symlinks within the archive.
+//
+// With storage.SymlinkPolicyFollow, the files that a symlink points to are
+// added to the bucket at the path of the symlink. Symlinks must point to
+// files or directories within the archive.
+//
+// The default is storage.SymlinkPolicySkip.
+func UnarchiveWithSymlinkPolicy(symlinkPolicy storage.SymlinkPolicy) UnarchiveOption {
+	return func(unarchiveOptions *unarchiveOptions) {
+		unarchiveOptions.symlinkPolicy = symlinkPolicy
+	}
+}
+
+// walkReadObjectsSorted walks the bucket in sorted path order.
+//
+// Bucket walk order is not guaranteed, so archives would not be reproducible otherwise.
+func walkReadObjectsSorted(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	f func(storage.ReadObject) error,
+) error {
+	paths, err := storage.AllPaths(ctx, readBucket, "")
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		readObjectCloser, err := readBucket.Get(ctx, path)
+		if err != nil {
+			return err
+		}
+		if err := multierr.Append(f(readObjectCloser), readObjectCloser.Close()); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func handleArchiveSymlink(
+	symlinkPolicy storage.SymlinkPolicy,
+	archiveSymlinks *archiveSymlinks,
+	archivePath string,
+	linkname string,
+	mapped bool,
+) error {
+	switch symlinkPolicy {
+	case storage.SymlinkPolicyError:
+		if mapped {
+			return storage.NewErrSymlink(archivePath)
+		}
+		return nil
+	case storage.SymlinkPolicyFollow:
+		return archiveSymlinks.addSymlink(archivePath, linkname)
+	default:
+		return nil
+	}
+}
+
+// writeArchiveFile writes the file opened by open to the path in the bucket.
+func writeArchiveFile(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	path string,
+	size uint64,
+	open func() (io.ReadCloser, error),
+) error {
+	readCloser, err := open()
+	if err != nil {
+		return err
+	}
+	writeObjectCloser, err := writeBucket.Put(ctx, path, uint32(size))
+	if err != nil {
+		return multierr.Append(err, readCloser.Close())
+	}
+	_, err = io.Copy(writeObjectCloser, readCloser)
+	return multierr.Combine(err, writeObjectCloser.Close(), readCloser.Close())
+}
+
+func readZipFile(zipFile *zip.File) (_ []byte, retErr error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readCloser.Close())
+	}()
+	return ioutil.ReadAll(readCloser)
+}
+
+type unarchiveOptions struct {
+	symlinkPolicy storage.SymlinkPolicy
+}
+
+func newUnarchiveOptions(options ...UnarchiveOption) *unarchiveOptions {
+	unarchiveOptions := &unarchiveOptions{
+		symlinkPolicy: storage.SymlinkPolicySkip,
+	}
+	for _, option := range options {
+		option(unarchiveOptions)
+	}
+	return unarchiveOptions
+}
+
 func unmapArchivePath(
 	archivePath string,
 	mapper storage.Mapper,
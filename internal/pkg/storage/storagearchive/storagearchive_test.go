@@ -0,0 +1,252 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagearchive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/pkg/storage"
+	"github.com/bufbuild/buf/internal/pkg/storage/storagemem"
+	"github.com/klauspost/compress/zip"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarDeterministic(t *testing.T) {
+	t.Parallel()
+	testDeterministic(t, Tar)
+}
+
+func TestZipDeterministic(t *testing.T) {
+	t.Parallel()
+	testDeterministic(t, Zip)
+}
+
+func testDeterministic(
+	t *testing.T,
+	archive func(context.Context, storage.ReadBucket, io.Writer) error,
+) {
+	pathToData := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		pathToData[fmt.Sprintf("a/b%d/c%d.proto", i%5, i)] = []byte(fmt.Sprintf("data%d", i))
+	}
+	var expected []byte
+	for i := 0; i < 5; i++ {
+		// storagemem walks its map, so each bucket may walk in a different order
+		readBucket, err := storagemem.NewReadBucket(pathToData)
+		require.NoError(t, err)
+		buffer := bytes.NewBuffer(nil)
+		require.NoError(t, archive(context.Background(), readBucket, buffer))
+		if expected == nil {
+			expected = buffer.Bytes()
+			continue
+		}
+		require.Equal(t, expected, buffer.Bytes())
+	}
+}
+
+func TestUntarSymlinkPolicy(t *testing.T) {
+	t.Parallel()
+	testUnarchiveSymlinkPolicy(t, newTestTar, untarBytes)
+}
+
+func TestUnzipSymlinkPolicy(t *testing.T) {
+	t.Parallel()
+	testUnarchiveSymlinkPolicy(t, newTestZip, unzipBytes)
+}
+
+type testArchiveEntry struct {
+	path     string
+	data     string
+	linkname string
+}
+
+func testUnarchiveSymlinkPolicy(
+	t *testing.T,
+	newArchive func(*testing.T, []testArchiveEntry) []byte,
+	unarchive func(context.Context, []byte, storage.WriteBucket, uint32, ...UnarchiveOption) error,
+) {
+	data := newArchive(
+		t,
+		[]testArchiveEntry{
+			{path: "root/proto/a.proto", data: "a"},
+			{path: "root/proto/vendor", linkname: "../third_party"},
+			{path: "root/proto/link.proto", linkname: "a.proto"},
+			{path: "root/third_party/b.proto", data: "b"},
+			{path: "root/third_party/sub/c.proto", data: "c"},
+		},
+	)
+	for _, testCase := range []struct {
+		symlinkPolicy storage.SymlinkPolicy
+		expected      map[string]string
+	}{
+		{
+			symlinkPolicy: storage.SymlinkPolicySkip,
+			expected: map[string]string{
+				"proto/a.proto":           "a",
+				"third_party/b.proto":     "b",
+				"third_party/sub/c.proto": "c",
+			},
+		},
+		{
+			symlinkPolicy: storage.SymlinkPolicyFollow,
+			expected: map[string]string{
+				"proto/a.proto":            "a",
+				"proto/link.proto":         "a",
+				"proto/vendor/b.proto":     "b",
+				"proto/vendor/sub/c.proto": "c",
+				"third_party/b.proto":      "b",
+				"third_party/sub/c.proto":  "c",
+			},
+		},
+	} {
+		readBucketBuilder := storagemem.NewReadBucketBuilder()
+		require.NoError(
+			t,
+			unarchive(
+				context.Background(),
+				data,
+				readBucketBuilder,
+				1,
+				UnarchiveWithSymlinkPolicy(testCase.symlinkPolicy),
+			),
+			testCase.symlinkPolicy.String(),
+		)
+		readBucket, err := readBucketBuilder.ToReadBucket()
+		require.NoError(t, err)
+		actual := make(map[string]string)
+		require.NoError(
+			t,
+			readBucket.Walk(
+				context.Background(),
+				"",
+				func(objectInfo storage.ObjectInfo) error {
+					data, err := storage.ReadPath(context.Background(), readBucket, objectInfo.Path())
+					if err != nil {
+						return err
+					}
+					actual[objectInfo.Path()] = string(data)
+					return nil
+				},
+			),
+		)
+		require.Equal(t, testCase.expected, actual, testCase.symlinkPolicy.String())
+	}
+
+	err := unarchive(
+		context.Background(),
+		data,
+		storagemem.NewReadBucketBuilder(),
+		1,
+		UnarchiveWithSymlinkPolicy(storage.SymlinkPolicyError),
+	)
+	require.True(t, storage.IsSymlink(err), err)
+
+	for _, entries := range [][]testArchiveEntry{
+		{
+			{path: "root/link.proto", linkname: "../../etc/passwd"},
+		},
+		{
+			{path: "root/link.proto", linkname: "/etc/passwd"},
+		},
+		{
+			{path: "root/link.proto", linkname: "missing.proto"},
+		},
+		{
+			{path: "root/a/b.proto", data: "b"},
+			{path: "root/a/loop", linkname: ".."},
+		},
+	} {
+		err := unarchive(
+			context.Background(),
+			newArchive(t, entries),
+			storagemem.NewReadBucketBuilder(),
+			1,
+			UnarchiveWithSymlinkPolicy(storage.SymlinkPolicyFollow),
+		)
+		require.Error(t, err, entries[len(entries)-1].linkname)
+	}
+}
+
+func newTestTar(t *testing.T, entries []testArchiveEntry) []byte {
+	buffer := bytes.NewBuffer(nil)
+	tarWriter := tar.NewWriter(buffer)
+	for _, entry := range entries {
+		tarHeader := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.path,
+			Size:     int64(len(entry.data)),
+			Mode:     archiveFileMode,
+		}
+		if entry.linkname != "" {
+			tarHeader.Typeflag = tar.TypeSymlink
+			tarHeader.Linkname = entry.linkname
+			tarHeader.Size = 0
+		}
+		require.NoError(t, tarWriter.WriteHeader(tarHeader))
+		_, err := tarWriter.Write([]byte(entry.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	return buffer.Bytes()
+}
+
+func newTestZip(t *testing.T, entries []testArchiveEntry) []byte {
+	buffer := bytes.NewBuffer(nil)
+	zipWriter := zip.NewWriter(buffer)
+	for _, entry := range entries {
+		zipFileHeader := &zip.FileHeader{
+			Name:   entry.path,
+			Method: zip.Deflate,
+		}
+		data := entry.data
+		zipFileHeader.SetMode(archiveFileMode)
+		if entry.linkname != "" {
+			zipFileHeader.SetMode(os.ModeSymlink | 0777)
+			data = entry.linkname
+		}
+		writer, err := zipWriter.CreateHeader(zipFileHeader)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+	return buffer.Bytes()
+}
+
+func untarBytes(
+	ctx context.Context,
+	data []byte,
+	writeBucket storage.WriteBucket,
+	stripComponentCount uint32,
+	options ...UnarchiveOption,
+) error {
+	return Untar(ctx, bytes.NewReader(data), writeBucket, nil, stripComponentCount, options...)
+}
+
+func unzipBytes(
+	ctx context.Context,
+	data []byte,
+	writeBucket storage.WriteBucket,
+	stripComponentCount uint32,
+	options ...UnarchiveOption,
+) error {
+	return Unzip(ctx, bytes.NewReader(data), int64(len(data)), writeBucket, nil, stripComponentCount, options...)
+}
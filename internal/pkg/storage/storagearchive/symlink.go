@@ -0,0 +1,181 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagearchive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/pkg/normalpath"
+	"github.com/bufbuild/buf/internal/pkg/storage"
+)
+
+// maxArchiveSymlinkDepth is the maximum number of symlinks followed when
+// resolving a single symlink, after which the symlink is considered a cycle.
+const maxArchiveSymlinkDepth = 40
+
+// archiveSymlinks collects the regular files and symlinks of an archive
+// so that symlinks can be resolved once the entire archive is read.
+//
+// All paths are full paths within the archive, that is before any
+// components are stripped and before mapping.
+type archiveSymlinks struct {
+	pathToFile      map[string]*archiveFile
+	symlinkToTarget map[string]string
+}
+
+type archiveFile struct {
+	size uint64
+	open func() (io.ReadCloser, error)
+}
+
+func newArchiveSymlinks() *archiveSymlinks {
+	return &archiveSymlinks{
+		pathToFile:      make(map[string]*archiveFile),
+		symlinkToTarget: make(map[string]string),
+	}
+}
+
+func (a *archiveSymlinks) addFile(archivePath string, size uint64, open func() (io.ReadCloser, error)) error {
+	path, err := normalpath.NormalizeAndValidate(archivePath)
+	if err != nil {
+		return err
+	}
+	a.pathToFile[path] = &archiveFile{
+		size: size,
+		open: open,
+	}
+	return nil
+}
+
+func (a *archiveSymlinks) addSymlink(archivePath string, linkname string) error {
+	path, err := normalpath.NormalizeAndValidate(archivePath)
+	if err != nil {
+		return err
+	}
+	if linkname == "" {
+		return fmt.Errorf("%s: empty symlink target", archivePath)
+	}
+	target, err := normalpath.NormalizeAndValidate(normalpath.Join(normalpath.Dir(path), linkname))
+	if err != nil || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("%s: symlink target %s is outside of the archive", archivePath, linkname)
+	}
+	a.symlinkToTarget[path] = target
+	return nil
+}
+
+// writeToBucket writes the files that all symlinks resolve to into the bucket.
+func (a *archiveSymlinks) writeToBucket(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	mapper storage.Mapper,
+	stripComponentCount uint32,
+) error {
+	for _, symlink := range a.sortedSymlinks() {
+		if err := a.resolve(
+			symlink,
+			symlink,
+			a.symlinkToTarget[symlink],
+			0,
+			func(archivePath string, archiveFile *archiveFile) error {
+				path, ok, err := unmapArchivePath(archivePath, mapper, stripComponentCount)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return nil
+				}
+				return writeArchiveFile(ctx, writeBucket, path, archiveFile.size, archiveFile.open)
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve calls f for every file that the target resolves to, with the file's
+// path as if the target was located at linkPath.
+//
+// The symlink is the symlink that resolution started from, and is used for errors.
+func (a *archiveSymlinks) resolve(
+	symlink string,
+	linkPath string,
+	target string,
+	depth int,
+	f func(string, *archiveFile) error,
+) error {
+	if depth >= maxArchiveSymlinkDepth {
+		return storage.NewErrSymlinkCycle(symlink)
+	}
+	if nextTarget, ok := a.symlinkToTarget[target]; ok {
+		return a.resolve(symlink, linkPath, nextTarget, depth+1, f)
+	}
+	if archiveFile, ok := a.pathToFile[target]; ok {
+		return f(linkPath, archiveFile)
+	}
+	prefix := target + "/"
+	if target == "." {
+		prefix = ""
+	}
+	found := false
+	for _, path := range a.sortedPaths() {
+		if strings.HasPrefix(path, prefix) {
+			found = true
+			if err := f(normalpath.Join(linkPath, strings.TrimPrefix(path, prefix)), a.pathToFile[path]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, childSymlink := range a.sortedSymlinks() {
+		if strings.HasPrefix(childSymlink, prefix) {
+			found = true
+			if err := a.resolve(
+				symlink,
+				normalpath.Join(linkPath, strings.TrimPrefix(childSymlink, prefix)),
+				a.symlinkToTarget[childSymlink],
+				depth+1,
+				f,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: symlink target %s does not exist in the archive", symlink, target)
+	}
+	return nil
+}
+
+func (a *archiveSymlinks) sortedPaths() []string {
+	paths := make([]string, 0, len(a.pathToFile))
+	for path := range a.pathToFile {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (a *archiveSymlinks) sortedSymlinks() []string {
+	symlinks := make([]string, 0, len(a.symlinkToTarget))
+	for symlink := range a.symlinkToTarget {
+		symlinks = append(symlinks, symlink)
+	}
+	sort.Strings(symlinks)
+	return symlinks
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
@@ -32,10 +33,11 @@ import (
 var errNotDir = errors.New("not a directory")
 
 type bucket struct {
-	rootPath string
+	rootPath      string
+	symlinkPolicy storage.SymlinkPolicy
 }
 
-func newBucket(rootPath string) (*bucket, error) {
+func newBucket(rootPath string, options ...BucketOption) (*bucket, error) {
 	rootPath = normalpath.Unnormalize(rootPath)
 	fileInfo, err := os.Stat(rootPath)
 	if err != nil {
@@ -49,10 +51,15 @@ func newBucket(rootPath string) (*bucket, error) {
 	}
 	// do not validate - allow anything with OS buckets including
 	// absolute paths and jumping context
-	rootPath = normalpath.Normalize(rootPath)
-	return &bucket{
-		rootPath: rootPath,
-	}, nil
+	rootPath = normalpath.NormalizeExternal(rootPath)
+	bucket := &bucket{
+		rootPath:      rootPath,
+		symlinkPolicy: storage.SymlinkPolicySkip,
+	}
+	for _, option := range options {
+		option(bucket)
+	}
+	return bucket, nil
 }
 
 func (b *bucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
@@ -96,6 +103,13 @@ func (b *bucket) Walk(
 		return err
 	}
 	walkChecker := internal.NewWalkChecker()
+	if b.symlinkPolicy == storage.SymlinkPolicyFollow {
+		fileInfo, err := os.Stat(externalPrefix)
+		if err != nil {
+			return err
+		}
+		return b.walkFollow(ctx, walkChecker, externalPrefix, fileInfo, nil, f)
+	}
 	// Walk does not follow symlinks
 	return filepath.Walk(
 		externalPrefix,
@@ -106,35 +120,99 @@ func (b *bucket) Walk(
 			if err := walkChecker.Check(ctx); err != nil {
 				return err
 			}
-			if fileInfo.Mode().IsRegular() {
-				size, err := getFileInfoSize(fileInfo)
+			if fileInfo.Mode()&os.ModeSymlink != 0 && b.symlinkPolicy == storage.SymlinkPolicyError {
+				path, err := b.getPath(externalPath)
 				if err != nil {
 					return err
 				}
-				path, err := normalpath.Rel(b.rootPath, normalpath.Normalize(externalPath))
-				if err != nil {
-					return err
-				}
-				// just in case
-				path, err = normalpath.NormalizeAndValidate(path)
-				if err != nil {
-					return err
-				}
-				if err := f(
-					internal.NewObjectInfo(
-						size,
-						path,
-						externalPath,
-					),
-				); err != nil {
-					return err
-				}
+				return storage.NewErrSymlink(path)
+			}
+			if fileInfo.Mode().IsRegular() {
+				return b.walkRegularFile(externalPath, fileInfo, f)
 			}
 			return nil
 		},
 	)
 }
 
+// walkFollow walks the external path, following symlinks.
+//
+// Symlinked files and directories are walked at the path of the symlink.
+// resolvedDirPaths are the resolved paths of the directories currently
+// being walked, and are used to detect symlink cycles.
+func (b *bucket) walkFollow(
+	ctx context.Context,
+	walkChecker internal.WalkChecker,
+	externalPath string,
+	fileInfo os.FileInfo,
+	resolvedDirPaths []string,
+	f func(storage.ObjectInfo) error,
+) error {
+	if err := walkChecker.Check(ctx); err != nil {
+		return err
+	}
+	if fileInfo.Mode().IsRegular() {
+		return b.walkRegularFile(externalPath, fileInfo, f)
+	}
+	if !fileInfo.IsDir() {
+		return nil
+	}
+	resolvedDirPath, err := filepath.EvalSymlinks(externalPath)
+	if err != nil {
+		return err
+	}
+	for _, ancestorResolvedDirPath := range resolvedDirPaths {
+		if resolvedDirPath == ancestorResolvedDirPath {
+			path, err := b.getPath(externalPath)
+			if err != nil {
+				return err
+			}
+			return storage.NewErrSymlinkCycle(path)
+		}
+	}
+	resolvedDirPaths = append(resolvedDirPaths[:len(resolvedDirPaths):len(resolvedDirPaths)], resolvedDirPath)
+	fileInfos, err := ioutil.ReadDir(externalPath)
+	if err != nil {
+		return err
+	}
+	for _, fileInfo := range fileInfos {
+		childExternalPath := filepath.Join(externalPath, fileInfo.Name())
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			// os.Stat follows the symlink, and errors for dangling symlinks
+			fileInfo, err = os.Stat(childExternalPath)
+			if err != nil {
+				return err
+			}
+		}
+		if err := b.walkFollow(ctx, walkChecker, childExternalPath, fileInfo, resolvedDirPaths, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bucket) walkRegularFile(
+	externalPath string,
+	fileInfo os.FileInfo,
+	f func(storage.ObjectInfo) error,
+) error {
+	size, err := getFileInfoSize(fileInfo)
+	if err != nil {
+		return err
+	}
+	path, err := b.getPath(externalPath)
+	if err != nil {
+		return err
+	}
+	return f(
+		internal.NewObjectInfo(
+			size,
+			path,
+			externalPath,
+		),
+	)
+}
+
 func (b *bucket) Put(ctx context.Context, path string, size uint32) (storage.WriteObjectCloser, error) {
 	externalPath, err := b.getExternalPath(path)
 	if err != nil {
@@ -197,6 +275,15 @@ func (b *bucket) getExternalPathAndSize(path string) (string, uint32, error) {
 	return externalPath, size, nil
 }
 
+func (b *bucket) getPath(externalPath string) (string, error) {
+	path, err := normalpath.RelExternal(b.rootPath, externalPath)
+	if err != nil {
+		return "", err
+	}
+	// just in case
+	return normalpath.NormalizeAndValidate(path)
+}
+
 func (b *bucket) getExternalPath(path string) (string, error) {
 	path, err := internal.ValidatePath(path)
 	if err != nil {
@@ -29,6 +29,22 @@ import (
 // can be absolute or jump context.
 //
 // Not thread-safe.
-func NewReadWriteBucket(rootPath string) (storage.ReadWriteBucket, error) {
-	return newBucket(rootPath)
+func NewReadWriteBucket(rootPath string, options ...BucketOption) (storage.ReadWriteBucket, error) {
+	return newBucket(rootPath, options...)
+}
+
+// BucketOption is an option for a new bucket.
+type BucketOption func(*bucket)
+
+// BucketWithSymlinkPolicy sets the policy for symlinks encountered by Walk.
+//
+// With storage.SymlinkPolicyFollow, symlinks to files and directories are
+// walked as if they were regular files and directories at the path of the
+// symlink, including symlinks that point outside of the root path.
+//
+// The default is storage.SymlinkPolicySkip.
+func BucketWithSymlinkPolicy(symlinkPolicy storage.SymlinkPolicy) BucketOption {
+	return func(bucket *bucket) {
+		bucket.symlinkPolicy = symlinkPolicy
+	}
 }
@@ -15,7 +15,11 @@
 package storageos_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/bufbuild/buf/internal/pkg/storage"
@@ -57,3 +61,60 @@ func testWriteBucketToReadBucket(t *testing.T, writeBucket storage.WriteBucket)
 	require.True(t, ok)
 	return readWriteBucket
 }
+
+func TestWalkSymlinkPolicy(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := tmp.NewDir("tmp")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, tmpDir.Close())
+	}()
+	rootDirPath := filepath.Join(tmpDir.AbsPath(), "root")
+	vendorDirPath := filepath.Join(tmpDir.AbsPath(), "vendor")
+	require.NoError(t, os.MkdirAll(rootDirPath, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(vendorDirPath, "sub"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootDirPath, "a.proto"), []byte("a"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendorDirPath, "b.proto"), []byte("b"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendorDirPath, "sub", "c.proto"), []byte("c"), 0644))
+	require.NoError(t, os.Symlink(vendorDirPath, filepath.Join(rootDirPath, "vendor")))
+	require.NoError(t, os.Symlink("a.proto", filepath.Join(rootDirPath, "link.proto")))
+
+	ctx := context.Background()
+	for _, testCase := range []struct {
+		symlinkPolicy storage.SymlinkPolicy
+		expectedPaths []string
+	}{
+		{
+			symlinkPolicy: storage.SymlinkPolicySkip,
+			expectedPaths: []string{"a.proto"},
+		},
+		{
+			symlinkPolicy: storage.SymlinkPolicyFollow,
+			expectedPaths: []string{"a.proto", "link.proto", "vendor/b.proto", "vendor/sub/c.proto"},
+		},
+	} {
+		readBucket, err := storageos.NewReadWriteBucket(rootDirPath, storageos.BucketWithSymlinkPolicy(testCase.symlinkPolicy))
+		require.NoError(t, err)
+		paths, err := storage.AllPaths(ctx, readBucket, "")
+		require.NoError(t, err)
+		sort.Strings(paths)
+		require.Equal(t, testCase.expectedPaths, paths, testCase.symlinkPolicy.String())
+		for _, path := range paths {
+			data, err := storage.ReadPath(ctx, readBucket, path)
+			require.NoError(t, err)
+			require.NotEmpty(t, data)
+		}
+	}
+
+	readBucket, err := storageos.NewReadWriteBucket(rootDirPath, storageos.BucketWithSymlinkPolicy(storage.SymlinkPolicyError))
+	require.NoError(t, err)
+	_, err = storage.AllPaths(ctx, readBucket, "")
+	require.True(t, storage.IsSymlink(err), err)
+
+	require.NoError(t, os.Symlink(rootDirPath, filepath.Join(vendorDirPath, "sub", "root")))
+	readBucket, err = storageos.NewReadWriteBucket(rootDirPath, storageos.BucketWithSymlinkPolicy(storage.SymlinkPolicyFollow))
+	require.NoError(t, err)
+	_, err = storage.AllPaths(ctx, readBucket, "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "symlink cycle")
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SymlinkPolicySkip skips symlinks.
+	//
+	// This is the default.
+	SymlinkPolicySkip SymlinkPolicy = iota + 1
+	// SymlinkPolicyFollow follows symlinks.
+	SymlinkPolicyFollow
+	// SymlinkPolicyError returns an error for symlinks.
+	SymlinkPolicyError
+)
+
+var (
+	symlinkPolicyToString = map[SymlinkPolicy]string{
+		SymlinkPolicySkip:   "skip",
+		SymlinkPolicyFollow: "follow",
+		SymlinkPolicyError:  "error",
+	}
+	stringToSymlinkPolicy = map[string]SymlinkPolicy{
+		"skip":   SymlinkPolicySkip,
+		"follow": SymlinkPolicyFollow,
+		"error":  SymlinkPolicyError,
+	}
+)
+
+// SymlinkPolicy is the policy for handling symlinks when reading
+// directories or extracting archives.
+type SymlinkPolicy int
+
+// String implements fmt.Stringer.
+func (s SymlinkPolicy) String() string {
+	str, ok := symlinkPolicyToString[s]
+	if !ok {
+		return strconv.Itoa(int(s))
+	}
+	return str
+}
+
+// ParseSymlinkPolicy parses the SymlinkPolicy.
+//
+// The empty string defaults to SymlinkPolicySkip.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return SymlinkPolicySkip, nil
+	}
+	symlinkPolicy, ok := stringToSymlinkPolicy[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown symlink policy: %q", s)
+	}
+	return symlinkPolicy, nil
+}
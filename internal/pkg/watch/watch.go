@@ -0,0 +1,188 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watch runs functions when files change.
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInterval = 500 * time.Millisecond
+	defaultDebounce = 200 * time.Millisecond
+)
+
+// Run runs f, and then runs f again each time a file within the paths is
+// created, modified, or deleted, until the context is done.
+//
+// Paths may be files or directories. Directories are walked recursively,
+// skipping hidden directories. Changes are detected by polling the modification
+// times and sizes of the files, and runs are debounced so that a burst of changes
+// results in a single run once the files have stopped changing.
+//
+// Returns nil once the context is done.
+func Run(ctx context.Context, paths []string, f func(context.Context), options ...RunOption) error {
+	runOptions := newRunOptions()
+	for _, option := range options {
+		option(runOptions)
+	}
+	last, err := getSnapshot(paths, runOptions.fileFilter)
+	if err != nil {
+		return err
+	}
+	f(ctx)
+	ticker := time.NewTicker(runOptions.interval)
+	defer ticker.Stop()
+	var changeTime time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current, err := getSnapshot(paths, runOptions.fileFilter)
+		if err != nil {
+			return err
+		}
+		if !current.equal(last) {
+			last = current
+			changeTime = time.Now()
+			continue
+		}
+		if !changeTime.IsZero() && time.Since(changeTime) >= runOptions.debounce {
+			changeTime = time.Time{}
+			f(ctx)
+		}
+	}
+}
+
+// RunOption is an option for Run.
+type RunOption func(*runOptions)
+
+// RunWithInterval returns a new RunOption that polls the files at the interval.
+//
+// The default is to poll every 500ms.
+func RunWithInterval(interval time.Duration) RunOption {
+	return func(runOptions *runOptions) {
+		runOptions.interval = interval
+	}
+}
+
+// RunWithDebounce returns a new RunOption that waits until the files have not
+// changed for the duration before running again.
+//
+// The default is 200ms.
+func RunWithDebounce(debounce time.Duration) RunOption {
+	return func(runOptions *runOptions) {
+		runOptions.debounce = debounce
+	}
+}
+
+// RunWithFileFilter returns a new RunOption that only watches the files for
+// which fileFilter returns true.
+//
+// The default is to watch all files.
+func RunWithFileFilter(fileFilter func(path string) bool) RunOption {
+	return func(runOptions *runOptions) {
+		runOptions.fileFilter = fileFilter
+	}
+}
+
+type runOptions struct {
+	interval   time.Duration
+	debounce   time.Duration
+	fileFilter func(string) bool
+}
+
+func newRunOptions() *runOptions {
+	return &runOptions{
+		interval: defaultInterval,
+		debounce: defaultDebounce,
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot is a map from file path to the state of the file.
+type snapshot map[string]fileState
+
+func (s snapshot) equal(other snapshot) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for path, state := range s {
+		otherState, ok := other[path]
+		if !ok || !state.modTime.Equal(otherState.modTime) || state.size != otherState.size {
+			return false
+		}
+	}
+	return true
+}
+
+func getSnapshot(paths []string, fileFilter func(string) bool) (snapshot, error) {
+	s := make(snapshot)
+	add := func(path string, fileInfo os.FileInfo) {
+		if fileFilter == nil || fileFilter(path) {
+			s[path] = fileState{
+				modTime: fileInfo.ModTime(),
+				size:    fileInfo.Size(),
+			}
+		}
+	}
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			// a deleted path is a change, not an error
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !fileInfo.IsDir() {
+			add(path, fileInfo)
+			continue
+		}
+		if err := filepath.Walk(
+			path,
+			func(walkPath string, walkFileInfo os.FileInfo, err error) error {
+				if err != nil {
+					// files may be deleted while we walk
+					if os.IsNotExist(err) {
+						return nil
+					}
+					return err
+				}
+				if walkFileInfo.IsDir() {
+					if walkPath != path && strings.HasPrefix(walkFileInfo.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				add(walkPath, walkFileInfo)
+				return nil
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
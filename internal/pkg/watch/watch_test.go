@@ -0,0 +1,82 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	tempDirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tempDirPath)) }()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDirPath, ".hidden"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.proto"), []byte("a"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runC := make(chan struct{}, 16)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- Run(
+			ctx,
+			[]string{tempDirPath},
+			func(context.Context) { runC <- struct{}{} },
+			RunWithInterval(5*time.Millisecond),
+			RunWithDebounce(10*time.Millisecond),
+			RunWithFileFilter(func(path string) bool { return filepath.Ext(path) == ".proto" }),
+		)
+	}()
+	testReceive(t, runC)
+
+	// filtered files and files in hidden directories are not watched
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, ".hidden", "b.proto"), []byte("b"), 0644))
+	testNotReceive(t, runC)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDirPath, "a.proto"), []byte("aa"), 0644))
+	testReceive(t, runC)
+	require.NoError(t, os.Remove(filepath.Join(tempDirPath, "a.proto")))
+	testReceive(t, runC)
+	testNotReceive(t, runC)
+
+	cancel()
+	assert.NoError(t, <-errC)
+}
+
+func testReceive(t *testing.T, runC <-chan struct{}) {
+	select {
+	case <-runC:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for run")
+	}
+}
+
+func testNotReceive(t *testing.T, runC <-chan struct{}) {
+	select {
+	case <-runC:
+		require.Fail(t, "unexpected run")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufimage is the supported Go API for building Images, and for
+// linting and checking them for breaking changes.
+//
+// Programs should use this package instead of the packages under internal,
+// which may change at any time. The types of this package only reference this
+// package, the standard library, and the Protobuf runtime, so that callers
+// never have to name an internal type.
+package bufimage
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Image is a built Image.
+type Image interface {
+	// FileDescriptorSet returns the files of the Image, including any imports,
+	// in topological order.
+	FileDescriptorSet() *descriptorpb.FileDescriptorSet
+	// Marshal returns the binary encoding of the Image.
+	//
+	// This is wire compatible with the FileDescriptorSet.
+	Marshal() ([]byte, error)
+}
+
+// FileAnnotation is a compile error, lint failure, or breaking change.
+type FileAnnotation struct {
+	// Path is the path of the file as given by the input, or empty if the
+	// annotation is not for a specific file.
+	Path string
+	// StartLine is the starting line, or 0 if not known.
+	StartLine int
+	// StartColumn is the starting column, or 0 if not known.
+	StartColumn int
+	// EndLine is the ending line, or 0 if not known.
+	EndLine int
+	// EndColumn is the ending column, or 0 if not known.
+	EndColumn int
+	// Type is the type of annotation, for example the ID of a lint checker.
+	//
+	// This is "COMPILE" for compile errors.
+	Type string
+	// Message is the message of the annotation.
+	Message string
+}
+
+// String returns the annotation in the same format as the CLI, that is
+// path:line:column:message.
+func (f *FileAnnotation) String() string {
+	return fileAnnotationString(f)
+}
+
+// Builder builds Images from inputs, and lints and checks them for breaking changes.
+//
+// Inputs are the same as the inputs of the CLI, for example a directory, an
+// archive, a git repository, or an Image, with options given as in
+// "foo.tar.gz#strip_components=1". Inputs are read according to their
+// buf.yaml, as they are with the CLI.
+type Builder interface {
+	// Build builds the input into an Image.
+	//
+	// If the input does not compile, the compile errors are returned as
+	// FileAnnotations and the Image is nil.
+	// If an error is returned, it is a system error.
+	Build(ctx context.Context, input string, options ...BuildOption) (Image, []*FileAnnotation, error)
+	// Lint builds and lints the input.
+	//
+	// Imports are not linted. If the input does not compile, the compile
+	// errors are returned instead.
+	// If an error is returned, it is a system error.
+	Lint(ctx context.Context, input string, options ...BuildOption) ([]*FileAnnotation, error)
+	// Breaking builds the input and againstInput, and checks the input for
+	// breaking changes against againstInput.
+	//
+	// The breaking configuration of the input is used. If either input does
+	// not compile, the compile errors are returned instead.
+	// If an error is returned, it is a system error.
+	Breaking(ctx context.Context, input string, againstInput string, options ...BuildOption) ([]*FileAnnotation, error)
+}
+
+// NewBuilder returns a new Builder.
+func NewBuilder(options ...BuilderOption) Builder {
+	return newBuilder(options...)
+}
+
+// BuilderOption is an option for a new Builder.
+type BuilderOption func(*builder)
+
+// Logger is a logger, such as a *log.Logger.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// BuilderWithLogger returns a new BuilderOption that logs to the given logger.
+//
+// All messages are logged, including debug messages, with one line per call
+// to Printf. The default is to not log.
+func BuilderWithLogger(logger Logger) BuilderOption {
+	return func(builder *builder) {
+		builder.logger = newZapLogger(logger)
+	}
+}
+
+// BuilderWithHTTPClient returns a new BuilderOption that uses the given
+// http.Client to read remote inputs.
+//
+// The default is http.DefaultClient.
+func BuilderWithHTTPClient(httpClient *http.Client) BuilderOption {
+	return func(builder *builder) {
+		builder.httpClient = httpClient
+	}
+}
+
+// FileOpener opens files for a custom scheme.
+type FileOpener interface {
+	// OpenFile opens the file at the URL, which includes the scheme.
+	//
+	// The file may be compressed, in which case it is decompressed according
+	// to the extension of the URL or the compression option of the input.
+	OpenFile(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// BuilderWithFileOpener returns a new BuilderOption that reads inputs with
+// the given custom scheme, such as "artifact" for artifact://path/to/file.tar,
+// with the given FileOpener.
+//
+// The built-in schemes, such as https and s3, cannot be overridden.
+func BuilderWithFileOpener(scheme string, fileOpener FileOpener) BuilderOption {
+	return func(builder *builder) {
+		builder.readerOptions = append(
+			builder.readerOptions,
+			buffetch.ReaderWithFileOpener(scheme, newFileOpener(fileOpener)),
+		)
+	}
+}
+
+// BuildOption is an option for Build, Lint, and Breaking.
+type BuildOption func(*buildOptions)
+
+// WithConfig returns a new BuildOption that uses the given config file or
+// data instead of the buf.yaml of the input.
+//
+// For Breaking, this only applies to the input, and not to againstInput.
+func WithConfig(config string) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.config = config
+	}
+}
+
+// WithPaths returns a new BuildOption that limits the files to the given
+// paths, which are relative to the current directory as with --file.
+//
+// For Breaking, paths that do not exist in againstInput are allowed.
+func WithPaths(paths ...string) BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.paths = append(buildOptions.paths, paths...)
+	}
+}
+
+// WithExcludeSourceCodeInfo returns a new BuildOption that excludes source
+// code info from the Image.
+//
+// This only applies to Build, as source code info is needed to report the
+// location of lint failures and breaking changes.
+func WithExcludeSourceCodeInfo() BuildOption {
+	return func(buildOptions *buildOptions) {
+		buildOptions.excludeSourceCodeInfo = true
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+
+	image, fileAnnotations, err := NewBuilder().Build(context.Background(), dirPath)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	fileDescriptorSet := image.FileDescriptorSet()
+	require.Len(t, fileDescriptorSet.File, 1)
+	require.Equal(t, "a/v1/a.proto", fileDescriptorSet.File[0].GetName())
+	data, err := image.Marshal()
+	require.NoError(t, err)
+	// the Image is wire compatible with the FileDescriptorSet
+	unmarshaledFileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(data, unmarshaledFileDescriptorSet))
+	require.Equal(t, "a/v1/a.proto", unmarshaledFileDescriptorSet.File[0].GetName())
+
+	compileErrorDirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { Bar one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(compileErrorDirPath)) }()
+	image, fileAnnotations, err = NewBuilder().Build(context.Background(), compileErrorDirPath)
+	require.NoError(t, err)
+	require.Nil(t, image)
+	require.Len(t, fileAnnotations, 1)
+	require.Equal(t, "COMPILE", fileAnnotations[0].Type)
+}
+
+func TestBuildFileOpener(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	image, _, err := NewBuilder().Build(context.Background(), dirPath)
+	require.NoError(t, err)
+	data, err := image.Marshal()
+	require.NoError(t, err)
+
+	builder := NewBuilder(
+		BuilderWithFileOpener(
+			"artifact",
+			testFileOpener{"artifact://images/a.bin": data},
+		),
+	)
+	image, fileAnnotations, err := builder.Build(context.Background(), "artifact://images/a.bin")
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	require.Equal(t, "a/v1/a.proto", image.FileDescriptorSet().File[0].GetName())
+	_, _, err = builder.Build(context.Background(), "other://images/a.bin")
+	require.Error(t, err)
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message foo { string one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+
+	fileAnnotations, err := NewBuilder().Lint(
+		context.Background(),
+		dirPath,
+		WithConfig(`{"lint":{"use":["MESSAGE_PASCAL_CASE"]}}`),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileAnnotations, 1)
+	require.Equal(t, "MESSAGE_PASCAL_CASE", fileAnnotations[0].Type)
+	require.Equal(t, filepath.Join(dirPath, "a", "v1", "a.proto"), fileAnnotations[0].Path)
+}
+
+func TestBreaking(t *testing.T) {
+	t.Parallel()
+	againstDirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(againstDirPath)) }()
+	dirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { int64 one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+
+	fileAnnotations, err := NewBuilder().Breaking(
+		context.Background(),
+		dirPath,
+		againstDirPath,
+		WithConfig(`{"breaking":{"use":["FIELD_SAME_TYPE"]}}`),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileAnnotations, 1)
+	require.Equal(t, "FIELD_SAME_TYPE", fileAnnotations[0].Type)
+
+	fileAnnotations, err = NewBuilder().Breaking(context.Background(), againstDirPath, againstDirPath)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+}
+
+func TestBuilderWithLogger(t *testing.T) {
+	t.Parallel()
+	dirPath := testWriteDir(t, `syntax = "proto3"; package a.v1; message Foo { string one = 1; }`)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+
+	buffer := bytes.NewBuffer(nil)
+	_, fileAnnotations, err := NewBuilder(
+		BuilderWithLogger(log.New(buffer, "", 0)),
+	).Build(context.Background(), dirPath)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	assert.NotEmpty(t, buffer.String())
+}
+
+func testWriteDir(t *testing.T, content string) string {
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "a", "v1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a", "v1", "a.proto"), []byte(content), 0644))
+	return dirPath
+}
+
+// testFileOpener is a FileOpener from URL to data.
+type testFileOpener map[string][]byte
+
+func (f testFileOpener) OpenFile(ctx context.Context, url string) (io.ReadCloser, error) {
+	data, ok := f[url]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
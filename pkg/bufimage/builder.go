@@ -0,0 +1,290 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/internal/buf/bufanalysis"
+	"github.com/bufbuild/buf/internal/buf/bufbuild"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/internal/buf/bufcheck/buflint"
+	"github.com/bufbuild/buf/internal/buf/bufconfig"
+	"github.com/bufbuild/buf/internal/buf/bufcore"
+	"github.com/bufbuild/buf/internal/buf/buffetch"
+	"github.com/bufbuild/buf/internal/buf/bufmod"
+	"github.com/bufbuild/buf/internal/buf/bufwire"
+	"github.com/bufbuild/buf/internal/pkg/app"
+	"github.com/bufbuild/buf/internal/pkg/git"
+	"github.com/bufbuild/buf/internal/pkg/httpauth"
+	"github.com/bufbuild/buf/internal/pkg/protoencoding"
+	"github.com/bufbuild/buf/internal/pkg/zaputil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// the flag names are only used in error messages
+	inputName  = "input"
+	configName = "config"
+)
+
+type builder struct {
+	logger        *zap.Logger
+	httpClient    *http.Client
+	readerOptions []buffetch.ReaderOption
+}
+
+func newBuilder(options ...BuilderOption) *builder {
+	builder := &builder{
+		logger:     zap.NewNop(),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(builder)
+	}
+	return builder
+}
+
+func (b *builder) Build(ctx context.Context, input string, options ...BuildOption) (Image, []*FileAnnotation, error) {
+	buildOptions := newBuildOptions(options...)
+	env, fileAnnotations, err := b.getEnv(ctx, input, buildOptions, false, buildOptions.excludeSourceCodeInfo)
+	if err != nil || len(fileAnnotations) > 0 {
+		return nil, fileAnnotations, err
+	}
+	return newImage(env.Image()), nil, nil
+}
+
+func (b *builder) Lint(ctx context.Context, input string, options ...BuildOption) ([]*FileAnnotation, error) {
+	buildOptions := newBuildOptions(options...)
+	env, fileAnnotations, err := b.getEnv(ctx, input, buildOptions, false, false)
+	if err != nil || len(fileAnnotations) > 0 {
+		return fileAnnotations, err
+	}
+	lintFileAnnotations, err := buflint.NewHandler(b.logger).Check(
+		ctx,
+		env.Config().Lint,
+		bufcore.ImageWithoutImports(env.Image()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newFileAnnotations(lintFileAnnotations), nil
+}
+
+func (b *builder) Breaking(ctx context.Context, input string, againstInput string, options ...BuildOption) ([]*FileAnnotation, error) {
+	buildOptions := newBuildOptions(options...)
+	env, fileAnnotations, err := b.getEnv(ctx, input, buildOptions, false, false)
+	if err != nil || len(fileAnnotations) > 0 {
+		return fileAnnotations, err
+	}
+	// the config only applies to the input
+	againstBuildOptions := newBuildOptions(WithPaths(buildOptions.paths...))
+	againstEnv, fileAnnotations, err := b.getEnv(ctx, againstInput, againstBuildOptions, true, true)
+	if err != nil || len(fileAnnotations) > 0 {
+		return fileAnnotations, err
+	}
+	breakingFileAnnotations, err := bufbreaking.NewHandler(b.logger).Check(
+		ctx,
+		env.Config().Breaking,
+		againstEnv.Image(),
+		env.Image(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newFileAnnotations(breakingFileAnnotations), nil
+}
+
+func (b *builder) getEnv(
+	ctx context.Context,
+	input string,
+	buildOptions *buildOptions,
+	pathsAllowNotExist bool,
+	excludeSourceCodeInfo bool,
+) (bufwire.Env, []*FileAnnotation, error) {
+	envContainer, err := app.NewEnvContainerForOS()
+	if err != nil {
+		return nil, nil, err
+	}
+	env, fileAnnotations, err := bufwire.NewEnvReader(
+		b.logger,
+		buffetch.NewRefParser(b.logger),
+		buffetch.NewReader(
+			b.logger,
+			b.httpClient,
+			httpauth.NewMultiAuthenticator(
+				httpauth.NewURLAuthenticator(),
+				httpauth.NewNetrcAuthenticator(),
+			),
+			git.NewCloner(b.logger, git.ClonerOptions{}),
+			b.readerOptions...,
+		),
+		bufconfig.NewProvider(b.logger),
+		bufmod.NewBucketBuilder(b.logger),
+		bufbuild.NewBuilder(b.logger),
+		inputName,
+		configName,
+	).GetEnv(
+		ctx,
+		newEnvStdinContainer(envContainer),
+		input,
+		buildOptions.config,
+		buildOptions.paths,
+		pathsAllowNotExist,
+		excludeSourceCodeInfo,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return env, newFileAnnotations(fileAnnotations), nil
+}
+
+type buildOptions struct {
+	config                string
+	paths                 []string
+	excludeSourceCodeInfo bool
+}
+
+func newBuildOptions(options ...BuildOption) *buildOptions {
+	buildOptions := &buildOptions{}
+	for _, option := range options {
+		option(buildOptions)
+	}
+	return buildOptions
+}
+
+type image struct {
+	image bufcore.Image
+}
+
+func newImage(bufcoreImage bufcore.Image) *image {
+	return &image{
+		image: bufcoreImage,
+	}
+}
+
+func (i *image) FileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	return bufcore.ImageToFileDescriptorSet(i.image)
+}
+
+func (i *image) Marshal() ([]byte, error) {
+	return protoencoding.NewWireMarshaler().Marshal(bufcore.ImageToProtoImage(i.image))
+}
+
+type fileOpener struct {
+	delegate FileOpener
+}
+
+func newFileOpener(delegate FileOpener) *fileOpener {
+	return &fileOpener{
+		delegate: delegate,
+	}
+}
+
+func (f *fileOpener) OpenFile(ctx context.Context, _ app.EnvContainer, url string) (io.ReadCloser, error) {
+	return f.delegate.OpenFile(ctx, url)
+}
+
+type envStdinContainer struct {
+	app.EnvContainer
+	app.StdinContainer
+}
+
+func newEnvStdinContainer(envContainer app.EnvContainer) *envStdinContainer {
+	return &envStdinContainer{
+		EnvContainer:   envContainer,
+		StdinContainer: app.NewStdinContainer(os.Stdin),
+	}
+}
+
+func newFileAnnotations(fileAnnotations []bufanalysis.FileAnnotation) []*FileAnnotation {
+	if len(fileAnnotations) == 0 {
+		return nil
+	}
+	publicFileAnnotations := make([]*FileAnnotation, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		var path string
+		if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+			path = fileInfo.ExternalPath()
+		}
+		publicFileAnnotations[i] = &FileAnnotation{
+			Path:        path,
+			StartLine:   fileAnnotation.StartLine(),
+			StartColumn: fileAnnotation.StartColumn(),
+			EndLine:     fileAnnotation.EndLine(),
+			EndColumn:   fileAnnotation.EndColumn(),
+			Type:        fileAnnotation.Type(),
+			Message:     fileAnnotation.Message(),
+		}
+	}
+	return publicFileAnnotations
+}
+
+func fileAnnotationString(fileAnnotation *FileAnnotation) string {
+	if fileAnnotation == nil {
+		return ""
+	}
+	path := fileAnnotation.Path
+	if path == "" {
+		path = "<input>"
+	}
+	line := fileAnnotation.StartLine
+	if line == 0 {
+		line = 1
+	}
+	column := fileAnnotation.StartColumn
+	if column == 0 {
+		column = 1
+	}
+	message := fileAnnotation.Message
+	if message == "" {
+		message = fileAnnotation.Type
+	}
+	return strings.Join(
+		[]string{
+			path,
+			strconv.Itoa(line),
+			strconv.Itoa(column),
+			message,
+		},
+		":",
+	)
+}
+
+// newZapLogger returns a new zap.Logger that logs to the Logger.
+func newZapLogger(logger Logger) *zap.Logger {
+	return zaputil.NewLogger(
+		&printfWriter{logger: logger},
+		zapcore.DebugLevel,
+		zaputil.NewTextEncoder(),
+	)
+}
+
+// printfWriter writes each line to the Logger.
+type printfWriter struct {
+	logger Logger
+}
+
+func (w *printfWriter) Write(p []byte) (int, error) {
+	w.logger.Printf("%s", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
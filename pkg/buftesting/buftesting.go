@@ -0,0 +1,105 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buftesting is the supported harness for testing buf commands.
+//
+// Commands are run in-process with an in-memory environment, stdin, stdout,
+// and stderr, and the exit code and output can be compared to the expected
+// values. Args do not include the name of the buf binary, for example
+// "check", "lint", "--input", "proto".
+//
+// Programs should use this package instead of the test helpers under
+// internal, which may change at any time. The functions of this package only
+// reference the standard library.
+package buftesting
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/bufbuild/buf/internal/buf/cmd/buf"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd"
+	"github.com/bufbuild/buf/internal/pkg/app/appcmd/appcmdtesting"
+)
+
+// RunCommand runs the buf command with the args and returns the exit code.
+//
+// The command is run with the given environment, stdin, stdout, and stderr,
+// and not with those of the current process. Any of env, stdin, stdout, and
+// stderr can be nil.
+//
+// This does not depend on the testing package, so it can be used with any
+// test framework.
+func RunCommand(
+	ctx context.Context,
+	env map[string]string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	args ...string,
+) int {
+	return appcmdtesting.RunCommand(ctx, newRootCommand, env, stdin, stdout, stderr, args...)
+}
+
+// RunCommandExitCode runs the buf command and compares the exit code.
+//
+// stderr is included in the failure message if the exit code differs.
+func RunCommandExitCode(
+	t *testing.T,
+	expectedExitCode int,
+	env map[string]string,
+	stdin io.Reader,
+	stdout io.Writer,
+	args ...string,
+) {
+	appcmdtesting.RunCommandExitCode(t, newRootCommand, expectedExitCode, env, stdin, stdout, args...)
+}
+
+// RunCommandExitCodeStdout runs the buf command and compares the exit code
+// and stdout output.
+//
+// Leading and trailing whitespace is trimmed from each line of the expected
+// and actual output before they are compared, so that the expected output
+// can be indented with the test.
+func RunCommandExitCodeStdout(
+	t *testing.T,
+	expectedExitCode int,
+	expectedStdout string,
+	env map[string]string,
+	stdin io.Reader,
+	args ...string,
+) {
+	appcmdtesting.RunCommandExitCodeStdout(t, newRootCommand, expectedExitCode, expectedStdout, env, stdin, args...)
+}
+
+// RunCommandExitCodeStdoutStderr runs the buf command and compares the exit
+// code, stdout output, and stderr output.
+//
+// The output is compared as with RunCommandExitCodeStdout.
+func RunCommandExitCodeStdoutStderr(
+	t *testing.T,
+	expectedExitCode int,
+	expectedStdout string,
+	expectedStderr string,
+	env map[string]string,
+	stdin io.Reader,
+	args ...string,
+) {
+	appcmdtesting.RunCommandExitCodeStdoutStderr(t, newRootCommand, expectedExitCode, expectedStdout, expectedStderr, env, stdin, args...)
+}
+
+func newRootCommand(use string) *appcmd.Command {
+	return buf.NewRootCommand(use)
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buftesting
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	t.Parallel()
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dirPath)) }()
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "a", "v1"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a", "v1", "a.proto"), []byte(`syntax = "proto3"; package a.v1; message Foo { string oneTwo = 1; }`), 0644))
+	filePath := filepath.Join(dirPath, "a", "v1", "a.proto")
+
+	RunCommandExitCodeStdout(
+		t,
+		5,
+		filePath+`:1:55:Field name "oneTwo" should be lower_snake_case, such as "one_two".`,
+		nil,
+		nil,
+		"check",
+		"lint",
+		"--input",
+		dirPath,
+		"--input-config",
+		`{"lint":{"use":["FIELD_LOWER_SNAKE_CASE"]}}`,
+	)
+	RunCommandExitCodeStdoutStderr(
+		t,
+		0,
+		filePath,
+		``,
+		nil,
+		nil,
+		"ls-files",
+		"--input",
+		dirPath,
+	)
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	exitCode := RunCommand(context.Background(), nil, strings.NewReader(""), stdout, stderr, "image", "build", "--source", dirPath, "-o", "-#format=json")
+	require.Equal(t, 0, exitCode, stderr.String())
+	require.Contains(t, stdout.String(), `"name":"a/v1/a.proto"`)
+}